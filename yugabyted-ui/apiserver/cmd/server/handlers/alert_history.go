@@ -0,0 +1,110 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "time"
+)
+
+// Events of the alert history
+const ALERT_EVENT_FIRED = "fired"
+const ALERT_EVENT_RESOLVED = "resolved"
+
+func getAlertHistoryEntry(event string, alert models.Alert, now int64) models.AlertHistoryEntry {
+    return models.AlertHistoryEntry{
+        Event: event,
+        Time: now,
+        RuleId: alert.RuleId,
+        RuleName: alert.RuleName,
+        Metric: alert.Metric,
+        Severity: alert.Severity,
+        Node: alert.Node,
+        Zone: alert.Zone,
+        Value: alert.Value,
+        Threshold: alert.Threshold,
+        Since: alert.Since,
+    }
+}
+
+// Compares the alerts raised before and after an evaluation. An alert that is raised again
+// since a different time, as its rule moved to another metric, is resolved and fired again.
+func getAlertHistoryEntries(previous map[alertKey]models.Alert, current map[alertKey]models.Alert,
+    now int64) []models.AlertHistoryEntry {
+    history := []models.AlertHistoryEntry{}
+    for key, alert := range previous {
+        if raised, ok := current[key]; !ok || raised.Since != alert.Since {
+            history = append(history, getAlertHistoryEntry(ALERT_EVENT_RESOLVED, alert, now))
+        }
+    }
+    for key, alert := range current {
+        if raised, ok := previous[key]; !ok || raised.Since != alert.Since {
+            history = append(history, getAlertHistoryEntry(ALERT_EVENT_FIRED, alert, now))
+        }
+    }
+    sort.SliceStable(history, func(i, j int) bool {
+        if history[i].Event != history[j].Event {
+            return history[i].Event == ALERT_EVENT_RESOLVED
+        }
+        if history[i].Node != history[j].Node {
+            return history[i].Node < history[j].Node
+        }
+        return history[i].RuleId < history[j].RuleId
+    })
+    return history
+}
+
+// Keeps the entries in the alert history of the local store, dropping the oldest entries beyond
+// alerts.max_history_entries. Entries of nodes under maintenance are marked as suppressed, as
+// they were not shown. Keys start with the time in nanoseconds, so that they sort by time.
+func (evaluator *alertEvaluator) record(history []models.AlertHistoryEntry,
+    maintenance *maintenanceWindowStore, now int64) {
+    maxEntries := helpers.GetConfig().Alerts.MaxHistoryEntries
+    if maxEntries == 0 || len(history) == 0 {
+        return
+    }
+    nanos := time.Now().UnixNano()
+    for index, entry := range history {
+        entry.Suppressed = maintenance.covers(entry.Node, entry.Zone, now)
+        data, err := json.Marshal(entry)
+        if err == nil {
+            err = evaluator.local.Put(STORE_BUCKET_ALERT_HISTORY,
+                fmt.Sprintf("%019d-%04d", nanos, index), data)
+        }
+        if err != nil {
+            evaluator.logger.Errorf("failed to keep the alert history: %s", err.Error())
+            return
+        }
+    }
+    if err := evaluator.local.Trim(STORE_BUCKET_ALERT_HISTORY, maxEntries); err != nil {
+        evaluator.logger.Errorf("failed to trim the alert history: %s", err.Error())
+    }
+}
+
+// Gets the entries of the alert history between two times, newest first. Empty filters match
+// every entry.
+func (evaluator *alertEvaluator) history(from int64, to int64, severity string,
+    ruleId string) ([]models.AlertHistoryEntry, error) {
+    entries, err := evaluator.local.List(STORE_BUCKET_ALERT_HISTORY)
+    if err != nil {
+        return nil, err
+    }
+    history := []models.AlertHistoryEntry{}
+    for index := len(entries) - 1; index >= 0; index-- {
+        entry := models.AlertHistoryEntry{}
+        if err := json.Unmarshal(entries[index].Value, &entry); err != nil {
+            evaluator.logger.Errorf("failed to read alert history entry %s: %s",
+                entries[index].Key, err.Error())
+            continue
+        }
+        if entry.Time < from || entry.Time > to ||
+            (severity != "" && entry.Severity != severity) ||
+            (ruleId != "" && entry.RuleId != ruleId) {
+            continue
+        }
+        history = append(history, entry)
+    }
+    return history, nil
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// The path of the v2 API of Alertmanager that alerts are posted to
+const ALERTMANAGER_ALERTS_PATH = "/api/v2/alerts"
+
+// How many evaluation intervals a firing alert is valid for. The alerts that are still raised
+// are sent again after every evaluation, so Alertmanager only resolves an alert on its own
+// once the server stops sending it, e.g. as it was stopped.
+const ALERTMANAGER_VALIDITY_INTERVALS = 4
+
+// An alert in the layout of the v2 API of Alertmanager
+type alertmanagerAlert struct {
+    Labels map[string]string `json:"labels"`
+    Annotations map[string]string `json:"annotations"`
+    StartsAt string `json:"startsAt"`
+    EndsAt string `json:"endsAt"`
+}
+
+// Gets an alert in the layout of Alertmanager. Alerts are told apart by their labels, so the
+// value of the metric is an annotation, and the alert ends at endsAt.
+func getAlertmanagerAlert(ruleId string, ruleName string, metric string, severity string,
+    node string, zone string, value float64, threshold float64, since int64,
+    endsAt time.Time) alertmanagerAlert {
+    labels := map[string]string{}
+    for name, labelValue := range helpers.GetConfig().Alerts.Alertmanager.ExternalLabels {
+        labels[name] = labelValue
+    }
+    labels["alertname"] = ruleName
+    labels["rule_id"] = ruleId
+    labels["metric"] = metric
+    labels["severity"] = severity
+    labels["node"] = node
+    // Alertmanager drops empty labels anyway
+    if zone != "" {
+        labels["zone"] = zone
+    }
+    return alertmanagerAlert{
+        Labels: labels,
+        Annotations: map[string]string{
+            "summary": fmt.Sprintf("%s of node %s is %g, above %g", metric, node, value,
+                threshold),
+            "value": fmt.Sprintf("%g", value),
+            "threshold": fmt.Sprintf("%g", threshold),
+        },
+        StartsAt: time.Unix(since, 0).UTC().Format(time.RFC3339),
+        EndsAt: endsAt.UTC().Format(time.RFC3339),
+    }
+}
+
+// Gets the alerts to send after an evaluation: the raised alerts that are not suppressed, valid
+// for a few evaluation intervals, and the alerts that were resolved, ending now. Alerts of nodes
+// that went under maintenance are not sent anymore, and end once they are no longer valid.
+func getAlertmanagerAlerts(alerts []models.Alert, history []models.AlertHistoryEntry,
+    now time.Time) []alertmanagerAlert {
+    validUntil := now.Add(ALERTMANAGER_VALIDITY_INTERVALS *
+        helpers.GetConfig().Alerts.EvaluationInterval)
+    alertmanagerAlerts := []alertmanagerAlert{}
+    for _, entry := range history {
+        if entry.Event != ALERT_EVENT_RESOLVED {
+            continue
+        }
+        alertmanagerAlerts = append(alertmanagerAlerts, getAlertmanagerAlert(entry.RuleId,
+            entry.RuleName, entry.Metric, entry.Severity, entry.Node, entry.Zone, entry.Value,
+            entry.Threshold, entry.Since, now))
+    }
+    for _, alert := range alerts {
+        alertmanagerAlerts = append(alertmanagerAlerts, getAlertmanagerAlert(alert.RuleId,
+            alert.RuleName, alert.Metric, alert.Severity, alert.Node, alert.Zone, alert.Value,
+            alert.Threshold, alert.Since, validUntil))
+    }
+    return alertmanagerAlerts
+}
+
+// Posts the alerts to every Alertmanager of alerts.alertmanager.urls. Alertmanagers that
+// cannot be reached are logged, as the alerts are sent again after the next evaluation.
+func (evaluator *alertEvaluator) sendToAlertmanagers(alerts []alertmanagerAlert) {
+    config := helpers.GetConfig().Alerts.Alertmanager
+    if len(config.Urls) == 0 || len(alerts) == 0 {
+        return
+    }
+    body, err := json.Marshal(alerts)
+    if err != nil {
+        evaluator.logger.Errorf("failed to encode the alerts for Alertmanager: %s", err.Error())
+        return
+    }
+    fanOut := newFanOutLimiter()
+    futures := map[string]chan error{}
+    for _, alertmanagerUrl := range config.Urls {
+        alertmanagerUrl := alertmanagerUrl
+        future := make(chan error, 1)
+        futures[alertmanagerUrl] = future
+        fanOut.goCall(func() {
+            future <- postAlertmanagerAlerts(alertmanagerUrl, body, config)
+        })
+    }
+    for alertmanagerUrl, future := range futures {
+        if err := <-future; err != nil {
+            evaluator.logger.Errorf("failed to send the alerts to Alertmanager %s: %s",
+                alertmanagerUrl, err.Error())
+        }
+    }
+}
+
+// Posts the body of the alerts to an Alertmanager. Responses other than 2xx are errors.
+func postAlertmanagerAlerts(alertmanagerUrl string, body []byte,
+    config helpers.AlertmanagerConfig) error {
+    request, err := http.NewRequest(http.MethodPost,
+        strings.TrimSuffix(alertmanagerUrl, "/")+ALERTMANAGER_ALERTS_PATH, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    request.Header.Set("Content-Type", "application/json")
+    if config.Username != "" {
+        request.SetBasicAuth(config.Username, config.Password)
+    }
+    response, err := helpers.NewHttpClientWithTimeout(config.Timeout).Do(request)
+    if err != nil {
+        return err
+    }
+    defer response.Body.Close()
+    if response.StatusCode < 200 || response.StatusCode >= 300 {
+        return fmt.Errorf("Alertmanager returned %s", response.Status)
+    }
+    return nil
+}
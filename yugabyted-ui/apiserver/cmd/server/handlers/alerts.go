@@ -0,0 +1,588 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "bytes"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// The metrics of the nodes that rules can be on, each in the unit of its name
+const ALERT_METRIC_DISK_USAGE_PERCENT = "disk_usage_percent"
+const ALERT_METRIC_CPU_USAGE_PERCENT = "cpu_usage_percent"
+const ALERT_METRIC_REPLICATION_LAG_SECONDS = "replication_lag_seconds"
+const ALERT_METRIC_CLOCK_SKEW_MS = "clock_skew_ms"
+
+var ALERT_METRICS = []string{ALERT_METRIC_DISK_USAGE_PERCENT, ALERT_METRIC_CPU_USAGE_PERCENT,
+    ALERT_METRIC_REPLICATION_LAG_SECONDS, ALERT_METRIC_CLOCK_SKEW_MS}
+
+const ALERT_SEVERITY_WARNING = "warning"
+const ALERT_SEVERITY_SEVERE = "severe"
+
+var ALERT_SEVERITIES = []string{ALERT_SEVERITY_WARNING, ALERT_SEVERITY_SEVERE}
+
+const ALERT_RULE_MAX_NAME_LENGTH = 100
+
+// How far the changes applied on each xCluster consumer tablet of a tserver are behind the
+// producer, in microseconds
+const XCLUSTER_LAG_METRIC = "async_replication_committed_lag_micros"
+
+// The skew between the clocks of a tserver and the servers it hears from, in microseconds
+const CLOCK_SKEW_METRIC = "hybrid_clock_skew"
+
+// A rule as written to the store
+type storedAlertRule struct {
+    Id string `json:"id"`
+    Name string `json:"name"`
+    Metric string `json:"metric"`
+    Severity string `json:"severity"`
+    Threshold float64 `json:"threshold"`
+    Enabled bool `json:"enabled"`
+    UpdatedBy string `json:"updated_by"`
+    UpdatedAt int64 `json:"updated_at"`
+}
+
+// The rules used until the rules are first changed
+var DEFAULT_ALERT_RULES = []storedAlertRule{
+    {
+        Id: "disk-usage-warning",
+        Name: "Disk usage is high",
+        Metric: ALERT_METRIC_DISK_USAGE_PERCENT,
+        Severity: ALERT_SEVERITY_WARNING,
+        Threshold: 80,
+        Enabled: true,
+    },
+    {
+        Id: "disk-usage-severe",
+        Name: "Disk is almost full",
+        Metric: ALERT_METRIC_DISK_USAGE_PERCENT,
+        Severity: ALERT_SEVERITY_SEVERE,
+        Threshold: 90,
+        Enabled: true,
+    },
+    {
+        Id: "cpu-usage-warning",
+        Name: "CPU usage is high",
+        Metric: ALERT_METRIC_CPU_USAGE_PERCENT,
+        Severity: ALERT_SEVERITY_WARNING,
+        Threshold: 90,
+        Enabled: true,
+    },
+    {
+        Id: "replication-lag-warning",
+        Name: "xCluster replication lags behind",
+        Metric: ALERT_METRIC_REPLICATION_LAG_SECONDS,
+        Severity: ALERT_SEVERITY_WARNING,
+        Threshold: 60,
+        Enabled: true,
+    },
+    // Half of the default --max_clock_skew_usec, beyond which tservers refuse to serve reads
+    {
+        Id: "clock-skew-warning",
+        Name: "Clock skew is high",
+        Metric: ALERT_METRIC_CLOCK_SKEW_MS,
+        Severity: ALERT_SEVERITY_WARNING,
+        Threshold: 250,
+        Enabled: true,
+    },
+}
+
+type alertRulesFile struct {
+    Rules []storedAlertRule `json:"rules"`
+}
+
+// Keeps the alert rules in the local store, so that changes to them outlive the server. The
+// built-in rules are used until the rules are first written.
+type alertRuleStore struct {
+    mutex sync.Mutex
+    rules map[string]storedAlertRule
+    local localstore.Store
+    logger logger.Logger
+}
+
+func newAlertRuleStore(log logger.Logger, local localstore.Store) *alertRuleStore {
+    store := &alertRuleStore{
+        rules: map[string]storedAlertRule{},
+        local: local,
+        logger: log,
+    }
+    store.load()
+    return store
+}
+
+// Reads the rules from the local store
+func (store *alertRuleStore) load() {
+    rules := map[string]storedAlertRule{}
+    for _, rule := range DEFAULT_ALERT_RULES {
+        rules[rule.Id] = rule
+    }
+    if rulesFile, ok, err := store.read(); err != nil {
+        store.logger.Errorf("failed to read the alert rules: %s", err.Error())
+    } else if ok {
+        // The store holds every rule, so that built-in rules that were deleted stay deleted
+        rules = map[string]storedAlertRule{}
+        for _, rule := range rulesFile.Rules {
+            rules[rule.Id] = rule
+        }
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    store.rules = rules
+}
+
+func (store *alertRuleStore) read() (alertRulesFile, bool, error) {
+    rulesFile := alertRulesFile{}
+    data, ok, err := store.local.Get(STORE_BUCKET_ALERT_RULES, STORE_ALERT_RULES_KEY)
+    if err != nil || !ok {
+        return rulesFile, false, err
+    }
+    if err := json.Unmarshal(data, &rulesFile); err != nil {
+        return rulesFile, false, err
+    }
+    return rulesFile, true, nil
+}
+
+// Orders rules by metric and threshold, so that the rules on a metric are listed together
+func sortAlertRules(rules []models.AlertRule) {
+    sort.Slice(rules, func(i, j int) bool {
+        if rules[i].Metric != rules[j].Metric {
+            return rules[i].Metric < rules[j].Metric
+        }
+        if rules[i].Threshold != rules[j].Threshold {
+            return rules[i].Threshold < rules[j].Threshold
+        }
+        return rules[i].Id < rules[j].Id
+    })
+}
+
+// Writes the rules to the local store. Must be called with the mutex held.
+func (store *alertRuleStore) saveLocked() error {
+    rulesFile := alertRulesFile{Rules: []storedAlertRule{}}
+    for _, rule := range store.rules {
+        rulesFile.Rules = append(rulesFile.Rules, rule)
+    }
+    sort.Slice(rulesFile.Rules, func(i, j int) bool {
+        return rulesFile.Rules[i].Id < rulesFile.Rules[j].Id
+    })
+    data, err := json.MarshalIndent(rulesFile, "", "  ")
+    if err != nil {
+        return err
+    }
+    return store.local.Put(STORE_BUCKET_ALERT_RULES, STORE_ALERT_RULES_KEY, data)
+}
+
+func getAlertRuleModel(rule storedAlertRule) models.AlertRule {
+    return models.AlertRule{
+        Id: rule.Id,
+        Name: rule.Name,
+        Metric: rule.Metric,
+        Severity: rule.Severity,
+        Threshold: rule.Threshold,
+        Enabled: rule.Enabled,
+        UpdatedBy: rule.UpdatedBy,
+        UpdatedAt: rule.UpdatedAt,
+    }
+}
+
+func getStoredAlertRule(id string, spec models.AlertRuleSpec,
+    updatedBy string) storedAlertRule {
+    return storedAlertRule{
+        Id: id,
+        Name: spec.Name,
+        Metric: spec.Metric,
+        Severity: spec.Severity,
+        Threshold: spec.Threshold,
+        Enabled: spec.Enabled == nil || *spec.Enabled,
+        UpdatedBy: updatedBy,
+        UpdatedAt: time.Now().Unix(),
+    }
+}
+
+func validateAlertRuleSpec(spec models.AlertRuleSpec) error {
+    if spec.Name == "" {
+        return errors.New("name must not be empty")
+    }
+    if err := validateMetadataText("name", spec.Name, ALERT_RULE_MAX_NAME_LENGTH); err != nil {
+        return err
+    }
+    if !containsString(ALERT_METRICS, spec.Metric) {
+        return fmt.Errorf("metric must be one of %s, got %q", strings.Join(ALERT_METRICS, ", "),
+            spec.Metric)
+    }
+    if !containsString(ALERT_SEVERITIES, spec.Severity) {
+        return fmt.Errorf("severity must be one of %s, got %q",
+            strings.Join(ALERT_SEVERITIES, ", "), spec.Severity)
+    }
+    if spec.Threshold < 0 {
+        return errors.New("threshold must not be negative")
+    }
+    if strings.HasSuffix(spec.Metric, "_percent") && spec.Threshold >= 100 {
+        return errors.New("threshold of a percentage must be below 100")
+    }
+    return nil
+}
+
+// Gets the rules, grouped by metric
+func (store *alertRuleStore) list() []models.AlertRule {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    rules := []models.AlertRule{}
+    for _, rule := range store.rules {
+        rules = append(rules, getAlertRuleModel(rule))
+    }
+    sortAlertRules(rules)
+    return rules
+}
+
+// Gets the rules that are checked
+func (store *alertRuleStore) enabled() []storedAlertRule {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    rules := []storedAlertRule{}
+    for _, rule := range store.rules {
+        if rule.Enabled {
+            rules = append(rules, rule)
+        }
+    }
+    return rules
+}
+
+func (store *alertRuleStore) create(spec models.AlertRuleSpec,
+    updatedBy string) (models.AlertRule, error) {
+    idBytes := make([]byte, 8)
+    if _, err := rand.Read(idBytes); err != nil {
+        return models.AlertRule{}, err
+    }
+    rule := getStoredAlertRule(hex.EncodeToString(idBytes), spec, updatedBy)
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    store.rules[rule.Id] = rule
+    if err := store.saveLocked(); err != nil {
+        delete(store.rules, rule.Id)
+        return models.AlertRule{}, err
+    }
+    return getAlertRuleModel(rule), nil
+}
+
+// Replaces a rule. Returns false if there is no such rule.
+func (store *alertRuleStore) update(id string, spec models.AlertRuleSpec,
+    updatedBy string) (models.AlertRule, bool, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    previous, ok := store.rules[id]
+    if !ok {
+        return models.AlertRule{}, false, nil
+    }
+    rule := getStoredAlertRule(id, spec, updatedBy)
+    store.rules[id] = rule
+    if err := store.saveLocked(); err != nil {
+        store.rules[id] = previous
+        return models.AlertRule{}, true, err
+    }
+    return getAlertRuleModel(rule), true, nil
+}
+
+// Deletes a rule. Returns false if there is no such rule.
+func (store *alertRuleStore) delete(id string) (bool, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    rule, ok := store.rules[id]
+    if !ok {
+        return false, nil
+    }
+    delete(store.rules, id)
+    if err := store.saveLocked(); err != nil {
+        store.rules[id] = rule
+        return true, err
+    }
+    return true, nil
+}
+
+// The values of the metrics of the nodes, by alert metric and node host, along with the zones
+// of the nodes as cloud.region.zone
+type alertValues struct {
+    metrics map[string]map[string]float64
+    zones map[string]string
+}
+
+type alertKey struct {
+    ruleId string
+    node string
+}
+
+// Checks the enabled rules against the metrics of the nodes at every evaluation interval, and
+// at once when the rules change. The raised alerts are kept in the local store, so that they
+// keep the time they were raised at across restarts.
+type alertEvaluator struct {
+    mutex sync.Mutex
+    alerts map[alertKey]models.Alert
+    wake chan struct{}
+    local localstore.Store
+    // The raised alerts as last written to the store
+    saved []byte
+    logger logger.Logger
+}
+
+func newAlertEvaluator(log logger.Logger, local localstore.Store) *alertEvaluator {
+    evaluator := &alertEvaluator{
+        alerts: map[alertKey]models.Alert{},
+        wake: make(chan struct{}, 1),
+        local: local,
+        logger: log,
+    }
+    evaluator.load()
+    return evaluator
+}
+
+// Reads the raised alerts from the local store
+func (evaluator *alertEvaluator) load() {
+    alerts := map[alertKey]models.Alert{}
+    data, ok, err := evaluator.local.Get(STORE_BUCKET_ALERTS, STORE_ALERTS_KEY)
+    if err == nil && ok {
+        storedAlerts := []models.Alert{}
+        if err = json.Unmarshal(data, &storedAlerts); err == nil {
+            for _, alert := range storedAlerts {
+                alerts[alertKey{alert.RuleId, alert.Node}] = alert
+            }
+        }
+    }
+    if err != nil {
+        evaluator.logger.Errorf("failed to read the raised alerts: %s", err.Error())
+    }
+    evaluator.mutex.Lock()
+    defer evaluator.mutex.Unlock()
+    evaluator.alerts = alerts
+    evaluator.saved = data
+}
+
+// Writes the raised alerts to the local store if they changed since they were last written
+func (evaluator *alertEvaluator) save() {
+    evaluator.mutex.Lock()
+    defer evaluator.mutex.Unlock()
+    storedAlerts := []models.Alert{}
+    for _, alert := range evaluator.alerts {
+        storedAlerts = append(storedAlerts, alert)
+    }
+    sort.Slice(storedAlerts, func(i, j int) bool {
+        if storedAlerts[i].Node != storedAlerts[j].Node {
+            return storedAlerts[i].Node < storedAlerts[j].Node
+        }
+        return storedAlerts[i].RuleId < storedAlerts[j].RuleId
+    })
+    data, err := json.Marshal(storedAlerts)
+    if err == nil && bytes.Equal(data, evaluator.saved) {
+        return
+    }
+    if err == nil {
+        err = evaluator.local.Put(STORE_BUCKET_ALERTS, STORE_ALERTS_KEY, data)
+    }
+    if err != nil {
+        evaluator.logger.Errorf("failed to write the raised alerts: %s", err.Error())
+        return
+    }
+    evaluator.saved = data
+}
+
+// Makes the evaluator check the rules again without waiting for the interval
+func (evaluator *alertEvaluator) trigger() {
+    select {
+    case evaluator.wake <- struct{}{}:
+    default:
+    }
+}
+
+func (evaluator *alertEvaluator) run(rules *alertRuleStore,
+    maintenance *maintenanceWindowStore, getValues func() (alertValues, error)) {
+    for {
+        values, err := getValues()
+        if err != nil {
+            evaluator.logger.Debugf("failed to get the metrics that alerts are raised on: %s",
+                err.Error())
+            values = alertValues{}
+        }
+        now := time.Now()
+        history := evaluator.evaluate(rules.enabled(), values, now.Unix())
+        evaluator.save()
+        evaluator.record(history, maintenance, now.Unix())
+        evaluator.sendToAlertmanagers(getAlertmanagerAlerts(evaluator.list(maintenance, false),
+            history, now))
+        select {
+        case <-evaluator.wake:
+        case <-time.After(helpers.GetConfig().Alerts.EvaluationInterval):
+        }
+    }
+}
+
+// Raises an alert for each node above the threshold of a rule, and resolves the alerts of the
+// nodes that are not anymore. A node without a value, e.g. as it could not be reached, keeps
+// its alerts, and the alerts of rules that were deleted or disabled are dropped. Returns the
+// alerts that were raised and resolved, for the history.
+func (evaluator *alertEvaluator) evaluate(rules []storedAlertRule, values alertValues,
+    now int64) []models.AlertHistoryEntry {
+    evaluator.mutex.Lock()
+    defer evaluator.mutex.Unlock()
+    alerts := map[alertKey]models.Alert{}
+    for _, rule := range rules {
+        nodeValues := values.metrics[rule.Metric]
+        for key, alert := range evaluator.alerts {
+            if _, hasValue := nodeValues[key.node]; key.ruleId != rule.Id || hasValue ||
+                alert.Metric != rule.Metric {
+                continue
+            }
+            alert.RuleName = rule.Name
+            alert.Severity = rule.Severity
+            alert.Threshold = rule.Threshold
+            alerts[key] = alert
+        }
+        for node, value := range nodeValues {
+            if value <= rule.Threshold {
+                continue
+            }
+            key := alertKey{rule.Id, node}
+            since := now
+            if previous, ok := evaluator.alerts[key]; ok && previous.Metric == rule.Metric {
+                since = previous.Since
+            }
+            alerts[key] = models.Alert{
+                RuleId: rule.Id,
+                RuleName: rule.Name,
+                Metric: rule.Metric,
+                Severity: rule.Severity,
+                Node: node,
+                Zone: values.zones[node],
+                Value: value,
+                Threshold: rule.Threshold,
+                Since: since,
+            }
+        }
+    }
+    history := getAlertHistoryEntries(evaluator.alerts, alerts, now)
+    evaluator.alerts = alerts
+    return history
+}
+
+// Gets the raised alerts, severe alerts first. The alerts of nodes under maintenance are marked
+// as suppressed, and left out unless includeSuppressed is set.
+func (evaluator *alertEvaluator) list(maintenance *maintenanceWindowStore,
+    includeSuppressed bool) []models.Alert {
+    now := time.Now().Unix()
+    evaluator.mutex.Lock()
+    defer evaluator.mutex.Unlock()
+    alerts := []models.Alert{}
+    for _, alert := range evaluator.alerts {
+        alert.Suppressed = maintenance.covers(alert.Node, alert.Zone, now)
+        if alert.Suppressed && !includeSuppressed {
+            continue
+        }
+        alerts = append(alerts, alert)
+    }
+    sort.Slice(alerts, func(i, j int) bool {
+        if alerts[i].Severity != alerts[j].Severity {
+            return alerts[i].Severity == ALERT_SEVERITY_SEVERE
+        }
+        if alerts[i].Node != alerts[j].Node {
+            return alerts[i].Node < alerts[j].Node
+        }
+        return alerts[i].RuleId < alerts[j].RuleId
+    })
+    return alerts
+}
+
+// Gets the disk and cpu usage, replication lag and clock skew of the alive nodes. The cpu
+// usage is read like the charts read it, and is left out while it is unavailable.
+func (c *Container) getAlertValues() (alertValues, error) {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return alertValues{}, tabletServersResponse.Error
+    }
+    values := alertValues{
+        metrics: map[string]map[string]float64{},
+        zones: map[string]string{},
+    }
+    for _, metric := range ALERT_METRICS {
+        values.metrics[metric] = map[string]float64{}
+    }
+    fanOut := newFanOutLimiter()
+    tserverMetricsFutures := map[string]chan helpers.TserverMetricsFuture{}
+    for _, cluster := range tabletServersResponse.Tablets {
+        for address, tabletServer := range cluster {
+            if tabletServer.Status != "ALIVE" {
+                continue
+            }
+            host, err := helpers.GetHostFromAddress(address)
+            if err != nil {
+                continue
+            }
+            values.zones[host] = getMaintenanceZone(tabletServer)
+            totalDisk, usedDisk := float64(0), float64(0)
+            for _, pathMetrics := range tabletServer.PathMetrics {
+                totalDisk += float64(pathMetrics.TotalSpaceSize)
+                usedDisk += float64(pathMetrics.SpaceUsed)
+            }
+            if totalDisk > 0 {
+                values.metrics[ALERT_METRIC_DISK_USAGE_PERCENT][host] =
+                    100 * usedDisk / totalDisk
+            }
+            tserverMetricsFuture := make(chan helpers.TserverMetricsFuture, 1)
+            tserverMetricsFutures[host] = tserverMetricsFuture
+            fanOut.goCall(func() { helpers.GetTserverMetricsFuture(host, tserverMetricsFuture) })
+        }
+    }
+    for host, tserverMetricsFuture := range tserverMetricsFutures {
+        tserverMetrics := <-tserverMetricsFuture
+        if tserverMetrics.Error != nil {
+            continue
+        }
+        // Tservers without xCluster consumer tablets do not report the lag
+        if samples, ok := tserverMetrics.Metrics[XCLUSTER_LAG_METRIC]; ok {
+            lag := float64(0)
+            for _, sample := range samples {
+                lag = math.Max(lag, sample.Value)
+            }
+            values.metrics[ALERT_METRIC_REPLICATION_LAG_SECONDS][host] = lag / 1000000
+        }
+        if samples := tserverMetrics.Metrics[CLOCK_SKEW_METRIC]; len(samples) > 0 {
+            values.metrics[ALERT_METRIC_CLOCK_SKEW_MS][host] = math.Abs(samples[0].Value) / 1000
+        }
+    }
+    hostToUuid, err := c.hostToUuid.get()
+    if err != nil {
+        return values, nil
+    }
+    reader, err := c.getMetricsReader(hostToUuid)
+    if err != nil {
+        return values, nil
+    }
+    metricsConfig := helpers.GetConfig().Metrics
+    for host := range tserverMetricsFutures {
+        uuid, ok := hostToUuid.Get(host)
+        if !ok {
+            continue
+        }
+        usage, hasUsage := float64(0), false
+        for _, metric := range []string{metricsConfig.CpuUsageUserMetric,
+            metricsConfig.CpuUsageSystemMetric} {
+            if sample, err := reader.latestSample(metric, uuid, true); err == nil {
+                usage += sample[1]
+                hasUsage = true
+            }
+        }
+        if hasUsage {
+            values.metrics[ALERT_METRIC_CPU_USAGE_PERCENT][host] = 100 * usage
+        }
+    }
+    return values, nil
+}
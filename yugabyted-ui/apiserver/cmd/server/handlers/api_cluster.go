@@ -3,34 +3,35 @@ package handlers
 import (
         "apiserver/cmd/server/helpers"
         "apiserver/cmd/server/models"
+        "apiserver/cmd/server/tasks"
+        "context"
         "encoding/json"
         "fmt"
         "net/http"
-        "runtime"
         "sort"
+        "strconv"
+        "strings"
+        "sync"
         "time"
 
         "github.com/labstack/echo/v4"
 )
 
-const QUERY_LIMIT_ONE string = "select ts, value, details " +
-        "from %s where metric='%s' and node='%s' limit 1;"
-
 // GetCluster - Get a cluster
 func (c *Container) GetCluster(ctx echo.Context) error {
         // Perform all necessary http requests asynchronously
-        tabletServersFuture := make(chan helpers.TabletServersFuture)
-        mastersFuture := make(chan helpers.MastersFuture)
-        clusterConfigFuture := make(chan helpers.ClusterConfigFuture)
-        go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
-        go helpers.GetMastersFuture(helpers.HOST, mastersFuture)
-        go helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture)
+        fanOut := newFanOutLimiter()
+        tabletServersFuture := make(chan helpers.TabletServersFuture, 1)
+        mastersFuture := make(chan helpers.MastersFuture, 1)
+        clusterConfigFuture := make(chan helpers.ClusterConfigFuture, 1)
+        fanOut.goCall(func() { helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture) })
+        fanOut.goCall(func() { helpers.GetMastersFuture(helpers.HOST, mastersFuture) })
+        fanOut.goCall(func() { helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture) })
 
         // Get response from tabletServersFuture
         tabletServersResponse := <-tabletServersFuture
         if tabletServersResponse.Error != nil {
-                return ctx.String(http.StatusInternalServerError,
-                        tabletServersResponse.Error.Error())
+                return respondWithError(ctx, tabletServersResponse.Error)
         }
 
         // Now that we have tabletServersResponse, we can start doing
@@ -42,29 +43,41 @@ func (c *Container) GetCluster(ctx echo.Context) error {
         gFlagsMasterFutures := []chan helpers.GFlagsFuture{}
         versionInfoFutures := []chan helpers.VersionInfoFuture{}
         for _, nodeHost := range nodeList {
-                gFlagsTserverFuture := make(chan helpers.GFlagsFuture)
+                nodeHost := nodeHost
+                gFlagsTserverFuture := make(chan helpers.GFlagsFuture, 1)
                 gFlagsTserverFutures = append(gFlagsTserverFutures, gFlagsTserverFuture)
-                go helpers.GetGFlagsFuture(nodeHost, false, gFlagsTserverFuture)
-                gFlagsMasterFuture := make(chan helpers.GFlagsFuture)
+                fanOut.goCall(func() {
+                        helpers.GetGFlagsFuture(nodeHost, false, gFlagsTserverFuture)
+                })
+                gFlagsMasterFuture := make(chan helpers.GFlagsFuture, 1)
                 gFlagsMasterFutures = append(gFlagsMasterFutures, gFlagsMasterFuture)
-                go helpers.GetGFlagsFuture(nodeHost, true, gFlagsMasterFuture)
-                versionInfoFuture := make(chan helpers.VersionInfoFuture)
+                fanOut.goCall(func() {
+                        helpers.GetGFlagsFuture(nodeHost, true, gFlagsMasterFuture)
+                })
+                versionInfoFuture := make(chan helpers.VersionInfoFuture, 1)
                 versionInfoFutures = append(versionInfoFutures, versionInfoFuture)
-                go helpers.GetVersionFuture(nodeHost, versionInfoFuture)
+                fanOut.goCall(func() { helpers.GetVersionFuture(nodeHost, versionInfoFuture) })
         }
 
     // Getting relevant data from tabletServersResponse
-    regionsMap := map[string]int32{}
-    zonesMap := map[string]int32{}
+    // Regions and zones are keyed by cloud and region too, as their names need not be unique
+    type regionKey struct {
+        cloud string
+        region string
+    }
+    regionsMap := map[regionKey]int32{}
+    zonesMap := map[regionKey]map[string]int32{}
     numNodes := int32(0)
     ramUsageBytes := float64(0)
     for _, cluster := range tabletServersResponse.Tablets {
         for _, tablet := range cluster {
             numNodes++;
-            region := tablet.Region
+            region := regionKey{tablet.Cloud, tablet.Region}
             regionsMap[region]++
-            zone := tablet.Zone
-            zonesMap[zone]++
+            if _, ok := zonesMap[region]; !ok {
+                zonesMap[region] = map[string]int32{}
+            }
+            zonesMap[region][tablet.Zone]++
             ramUsageBytes += float64(tablet.RamUsedBytes)
         }
     }
@@ -74,25 +87,40 @@ func (c *Container) GetCluster(ctx echo.Context) error {
     provider := models.CLOUDENUM_MANUAL
     clusterRegionInfo := []models.ClusterRegionInfo{}
     for region, numNodesInRegion := range regionsMap {
+        zones := []models.ClusterZoneInfo{}
+        for zone, numNodesInZone := range zonesMap[region] {
+            zones = append(zones, models.ClusterZoneInfo{
+                Zone: zone,
+                NumNodes: numNodesInZone,
+            })
+        }
+        sort.Slice(zones, func(i, j int) bool {
+            return zones[i].Zone < zones[j].Zone
+        })
         clusterRegionInfo = append(clusterRegionInfo, models.ClusterRegionInfo{
+            Cloud: region.cloud,
             PlacementInfo: models.PlacementInfo{
                 CloudInfo: models.CloudInfo{
                     Code:   provider,
-                    Region: region,
+                    Region: region.region,
                 },
                 NumNodes: numNodesInRegion,
             },
+            Zones: zones,
         })
     }
     sort.Slice(clusterRegionInfo, func(i, j int) bool {
-        return clusterRegionInfo[i].PlacementInfo.CloudInfo.Region <
-               clusterRegionInfo[j].PlacementInfo.CloudInfo.Region
+        left, right := clusterRegionInfo[i], clusterRegionInfo[j]
+        if left.PlacementInfo.CloudInfo.Region != right.PlacementInfo.CloudInfo.Region {
+            return left.PlacementInfo.CloudInfo.Region < right.PlacementInfo.CloudInfo.Region
+        }
+        return left.Cloud < right.Cloud
     })
 
         // Getting response from mastersFuture
         mastersResponse := <-mastersFuture
         if mastersResponse.Error != nil {
-                return ctx.String(http.StatusInternalServerError, mastersResponse.Error.Error())
+                return respondWithError(ctx, mastersResponse.Error)
         }
 
         // Getting relevant data from mastersResponse
@@ -103,33 +131,29 @@ func (c *Container) GetCluster(ctx echo.Context) error {
                         timestamp = startTime
                 }
         }
-        createdOn := time.UnixMicro(timestamp).Format(time.RFC3339)
-        // Less than 3 replicas -> None
-        // In at least 3 different regions -> Region
-        // In at least 3 different zones but fewer than 3 regions -> Zone
-        // At least 3 replicas but in fewer than 3 zones -> Node
-        faultTolerance := models.CLUSTERFAULTTOLERANCE_NONE
-        if numNodes >= 3 {
-                if len(regionsMap) >= 3 {
-                        // regionsMap comes from parsing /tablet-servers endpoint
-                        faultTolerance = models.CLUSTERFAULTTOLERANCE_REGION
-                } else if len(zonesMap) >= 3 {
-                        // zonesMap comes from parsing /tablet-servers endpoint
-                        // assumes there cannot be two zones with the same name
-                        // but in different regions
-                        faultTolerance = models.CLUSTERFAULTTOLERANCE_ZONE
-                } else {
-                        faultTolerance = models.CLUSTERFAULTTOLERANCE_NODE
-                }
-        }
         // Determine if encryption at rest is enabled
         // Checks cluster-config response encryption_info.encryption_enabled
         clusterConfigResponse := <-clusterConfigFuture
         isEncryptionAtRestEnabled := false
+        metadata := models.ClusterMetadata{Tags: map[string]string{}}
+        replicationInfo := helpers.ReplicationInfoStruct{}
+        createdAt, updatedAt := timestamp/1000000, timestamp/1000000
         if clusterConfigResponse.Error == nil {
                 resultConfig := clusterConfigResponse.ClusterConfig
                 isEncryptionAtRestEnabled = resultConfig.EncryptionInfo.EncryptionEnabled
+                metadata = c.clusterMetadata.get(resultConfig.ClusterUuid)
+                replicationInfo = resultConfig.ReplicationInfo
+                // The masters start over after a full restart, so the cluster is created as of
+                // the oldest start time ever seen, kept along with the metadata of the cluster
+                createdAt, updatedAt = c.clusterMetadata.getLifecycle(resultConfig.ClusterUuid,
+                        createdAt, resultConfig.Version, time.Now().Unix())
         }
+        createdOn := time.Unix(createdAt, 0).UTC().Format(time.RFC3339)
+        updatedOn := time.Unix(updatedAt, 0).UTC().Format(time.RFC3339)
+        // The fault tolerance follows from the replication factor and the placement blocks of
+        // the cluster config, along with the nodes of each zone and region
+        faultTolerance, replicationFactor, failuresTolerated := getFaultTolerance(
+                tabletServersResponse.Tablets, replicationInfo)
         // Determine if encryption in transit is enabled
         // It is enabled if and only if each master and tserver has the flags:
         //   --use_node_to_node_encryption=true
@@ -162,70 +186,75 @@ func (c *Container) GetCluster(ctx echo.Context) error {
                 }
         }
 
-        // Use the session from the context.
-        session := c.Session
+        // Usage metrics are left out while they are unavailable
         averageCpu := float64(0)
         totalDiskGb := float64(0)
         freeDiskGb := float64(0)
-        hostToUuid, err := helpers.GetHostToUuidMap(helpers.HOST)
+        hostToUuid, err := c.hostToUuid.get()
+        var reader metricsReader
+        if err == nil {
+            reader, err = c.getMetricsReader(hostToUuid)
+        }
         if err == nil {
+            metricsConfig := helpers.GetConfig().Metrics
             sum := float64(0)
             for _, uuid := range hostToUuid {
-                query := fmt.Sprintf(QUERY_LIMIT_ONE, "system.metrics", "cpu_usage_user", uuid)
-                iter := session.Query(query).Iter()
-                var ts int64
-                var value int
-                var details string
-                iter.Scan(&ts, &value, &details)
-                detailObj := DetailObj{}
-                json.Unmarshal([]byte(details), &detailObj)
-                sum += detailObj.Value
-                if err := iter.Close(); err != nil {
-                    continue
+                if sample, err := reader.latestSample(metricsConfig.CpuUsageUserMetric, uuid,
+                    true); err == nil {
+                    sum += sample[1]
                 }
-                query = fmt.Sprintf(QUERY_LIMIT_ONE, "system.metrics", "cpu_usage_system", uuid)
-                iter = session.Query(query).Iter()
-                iter.Scan(&ts, &value, &details)
-                json.Unmarshal([]byte(details), &detailObj)
-                sum += detailObj.Value
-                if err := iter.Close(); err != nil {
-                    continue
+                if sample, err := reader.latestSample(metricsConfig.CpuUsageSystemMetric, uuid,
+                    true); err == nil {
+                    sum += sample[1]
                 }
             }
             averageCpu = (sum * 100) / float64(len(hostToUuid))
             // Get the disk usage as well. Assume every node reports the same metrics for disk space
-            query :=
-              fmt.Sprintf(QUERY_LIMIT_ONE, "system.metrics", "total_disk", hostToUuid[helpers.HOST])
-            iter := session.Query(query).Iter()
-            var ts int64
-            var value int
-            var details string
-            iter.Scan(&ts, &value, &details)
-            totalDiskGb = float64(value) / helpers.BYTES_IN_GB
-            query =
-              fmt.Sprintf(QUERY_LIMIT_ONE, "system.metrics", "free_disk", hostToUuid[helpers.HOST])
-            iter = session.Query(query).Iter()
-            iter.Scan(&ts, &value, &details)
-            freeDiskGb = float64(value) / helpers.BYTES_IN_GB
+            localUuid, _ := hostToUuid.Get(helpers.HOST)
+            if sample, err := reader.latestSample(metricsConfig.TotalDiskMetric, localUuid,
+                false); err == nil {
+                totalDiskGb = sample[1] / helpers.BYTES_IN_GB
+            }
+            if sample, err := reader.latestSample(metricsConfig.FreeDiskMetric, localUuid,
+                false); err == nil {
+                freeDiskGb = sample[1] / helpers.BYTES_IN_GB
+            }
         }
         // Get software version
         smallestVersion := helpers.GetSmallestVersion(versionInfoFutures)
+        // The cores and memory are those of the latest background poll of the node_exporter of
+        // the nodes, which leaves out the nodes it could not reach
+        resources := c.nodeResources.get()
+        numCores, totalCores, nodeCores := getClusterCores(resources)
+        nodeMemory, totalMemoryMb, usedMemoryMb := getClusterMemory(resources)
 
     response := models.ClusterResponse{
         Data: models.ClusterData{
             Spec: models.ClusterSpec{
+                Name: metadata.Name,
+                Description: metadata.Description,
+                Owner: metadata.Owner,
+                Tags: metadata.Tags,
                 CloudInfo: models.CloudInfo{
                     Code: provider,
                 },
                 ClusterInfo: models.ClusterInfo{
                     NumNodes:       numNodes,
                     FaultTolerance: faultTolerance,
+                    ReplicationFactor: int32(replicationFactor),
+                    FailuresTolerated: failuresTolerated,
                     NodeInfo: models.ClusterNodeInfo{
                         MemoryMb:       ramUsageMb,
                         DiskSizeGb:     totalDiskGb,
                         DiskSizeUsedGb: totalDiskGb - freeDiskGb,
                         CpuUsage:       averageCpu,
-                        NumCores:       int32(runtime.NumCPU()),
+                        NumCores:       numCores,
+                        TotalCores:     totalCores,
+                        NodeCores:      nodeCores,
+                        TotalMemoryMb:  totalMemoryMb,
+                        UsedMemoryMb:   usedMemoryMb,
+                        AvailableMemoryMb: totalMemoryMb - usedMemoryMb,
+                        NodeMemory:     nodeMemory,
                     },
                 },
                 ClusterRegionInfo: &clusterRegionInfo,
@@ -237,7 +266,7 @@ func (c *Container) GetCluster(ctx echo.Context) error {
             Info: models.ClusterDataInfo{
                 Metadata: models.EntityMetadata{
                     CreatedOn: &createdOn,
-                    UpdatedOn: &createdOn,
+                    UpdatedOn: &updatedOn,
                 },
                 SoftwareVersion: smallestVersion,
             },
@@ -245,3 +274,1093 @@ func (c *Container) GetCluster(ctx echo.Context) error {
     }
     return ctx.JSON(http.StatusOK, response)
 }
+
+// Gets the cores of each node, sorted by node, along with the fewest cores of any node and the
+// cores of all of them. Both are 0, as unknown, if no node reports its cores.
+func getClusterCores(resources map[string]nodeResources) (int32, int32,
+    []models.ClusterNodeCores) {
+    numCores, totalCores := int32(0), int32(0)
+    nodeCores := []models.ClusterNodeCores{}
+    for host, hostResources := range resources {
+        cores := int32(hostResources.cpuCores)
+        if cores == 0 {
+            continue
+        }
+        nodeCores = append(nodeCores, models.ClusterNodeCores{
+            Name: host,
+            NumCores: cores,
+        })
+        totalCores += cores
+        if numCores == 0 || cores < numCores {
+            numCores = cores
+        }
+    }
+    sort.Slice(nodeCores, func(i, j int) bool {
+        return nodeCores[i].Name < nodeCores[j].Name
+    })
+    return numCores, totalCores, nodeCores
+}
+
+// Gets the memory of each node, sorted by node, along with the memory and the memory in use of
+// all of them in MB. The memory in use is the memory that is not available to start new
+// processes with, which leaves out the page cache that the kernel can reclaim.
+func getClusterMemory(resources map[string]nodeResources) ([]models.ClusterNodeMemory, float64,
+    float64) {
+    nodeMemory := []models.ClusterNodeMemory{}
+    totalMb, usedMb := float64(0), float64(0)
+    for host, hostResources := range resources {
+        if hostResources.memoryBytes == 0 || hostResources.availableMemoryBytes == 0 {
+            continue
+        }
+        memory := models.ClusterNodeMemory{
+            Name: host,
+            TotalMb: hostResources.memoryBytes / helpers.BYTES_IN_MB,
+            AvailableMb: hostResources.availableMemoryBytes / helpers.BYTES_IN_MB,
+        }
+        memory.UsedMb = memory.TotalMb - memory.AvailableMb
+        nodeMemory = append(nodeMemory, memory)
+        totalMb += memory.TotalMb
+        usedMb += memory.UsedMb
+    }
+    sort.Slice(nodeMemory, func(i, j int) bool {
+        return nodeMemory[i].Name < nodeMemory[j].Name
+    })
+    return nodeMemory, totalMb, usedMb
+}
+
+// Gets the uuid of the cluster from the cluster config
+func getClusterUuid() (string, error) {
+    clusterConfigFuture := make(chan helpers.ClusterConfigFuture)
+    go helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture)
+    clusterConfig := <-clusterConfigFuture
+    if clusterConfig.Error != nil {
+        return "", clusterConfig.Error
+    }
+    return clusterConfig.ClusterConfig.ClusterUuid, nil
+}
+
+// GetClusterMetadata - Get the name and details of the cluster
+func (c *Container) GetClusterMetadata(ctx echo.Context) error {
+    clusterUuid, err := getClusterUuid()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.ClusterMetadataResponse{
+        Data: c.clusterMetadata.get(clusterUuid),
+    })
+}
+
+// UpdateClusterMetadata - Set the name and details of the cluster
+func (c *Container) UpdateClusterMetadata(ctx echo.Context) error {
+    metadataSpec := models.ClusterMetadataSpec{}
+    if err := ctx.Bind(&metadataSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if err := validateClusterMetadataSpec(metadataSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    clusterUuid, err := getClusterUuid()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    updatedBy := ""
+    if existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session); ok {
+        updatedBy = existing.username
+    }
+    metadata, err := c.clusterMetadata.set(clusterUuid, metadataSpec, updatedBy,
+        time.Now().Unix())
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "update_cluster_metadata", "cluster_uuid", clusterUuid,
+        "name", metadata.Name, "owner", metadata.Owner, "tags", metadata.Tags)
+    return ctx.JSON(http.StatusOK, models.ClusterMetadataResponse{
+        Data: metadata,
+    })
+}
+
+// GetGflagDoc - Get the documentation of a gflag
+func (c *Container) GetGflagDoc(ctx echo.Context) error {
+    name := ctx.Param("name")
+    if !GFLAG_NAME_REGEX.MatchString(name) {
+        return respondError(ctx, http.StatusBadRequest, "invalid name")
+    }
+    // Flags of both servers are looked up in the tserver first
+    serverTypes := []string{GFLAG_SERVER_TYPE_TSERVER, GFLAG_SERVER_TYPE_MASTER}
+    if serverType := ctx.QueryParam("server_type"); serverType != "" {
+        if serverType != GFLAG_SERVER_TYPE_TSERVER && serverType != GFLAG_SERVER_TYPE_MASTER {
+            return respondError(ctx, http.StatusBadRequest,
+                "server_type must be master or tserver")
+        }
+        serverTypes = []string{serverType}
+    }
+    for _, serverType := range serverTypes {
+        entry, err := c.gflagDocs.getForCluster(serverType)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        doc, ok := entry.docs[name]
+        if !ok {
+            continue
+        }
+        return ctx.JSON(http.StatusOK, models.GflagDocResponse{
+            Data: models.GflagDoc{
+                Name: doc.Name,
+                ServerType: serverType,
+                Description: doc.Meaning,
+                Type: doc.Type,
+                DefaultValue: doc.Default,
+                Tags: splitGflagTags(doc.Tags),
+                Impact: getGflagImpact(doc, true, ""),
+                Version: entry.binaryVersion,
+                RunningVersion: entry.runningVersion,
+            },
+        })
+    }
+    return respondError(ctx, http.StatusNotFound, fmt.Sprintf("gflag %s not found", name))
+}
+
+// GetFeatures - Get the capabilities available on the cluster
+func (c *Container) GetFeatures(ctx echo.Context) error {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return respondWithError(ctx, tabletServersResponse.Error)
+    }
+    fanOut := newFanOutLimiter()
+    versionInfoFutures := []chan helpers.VersionInfoFuture{}
+    gFlagsFutures := []chan helpers.GFlagsFuture{}
+    for _, nodeHost := range helpers.GetNodesList(tabletServersResponse) {
+        nodeHost := nodeHost
+        versionInfoFuture := make(chan helpers.VersionInfoFuture, 1)
+        versionInfoFutures = append(versionInfoFutures, versionInfoFuture)
+        fanOut.goCall(func() { helpers.GetVersionFuture(nodeHost, versionInfoFuture) })
+        gFlagsFuture := make(chan helpers.GFlagsFuture, 1)
+        gFlagsFutures = append(gFlagsFutures, gFlagsFuture)
+        fanOut.goCall(func() { helpers.GetGFlagsFuture(nodeHost, false, gFlagsFuture) })
+    }
+    version := helpers.GetSmallestVersion(versionInfoFutures)
+    // Tservers whose gflags cannot be read are left out rather than hiding the capabilities
+    // that need a gflag
+    tserverFlags := []map[string]string{}
+    for _, gFlagsFuture := range gFlagsFutures {
+        if gFlags := <-gFlagsFuture; gFlags.Error == nil {
+            tserverFlags = append(tserverFlags, gFlags.GFlags)
+        }
+    }
+    return ctx.JSON(http.StatusOK, models.ClusterFeaturesResponse{
+        Data: models.ClusterFeatures{
+            Version: version,
+            Cluster: getClusterFeatures(version, tserverFlags),
+            Server: getConfigFeatures(helpers.GetConfig().Features),
+        },
+    })
+}
+
+// GetEncryptionAtRest - Get the state of encryption at rest
+func (c *Container) GetEncryptionAtRest(ctx echo.Context) error {
+    clusterConfigFuture := make(chan helpers.ClusterConfigFuture)
+    go helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture)
+    clusterConfig := <-clusterConfigFuture
+    if clusterConfig.Error != nil {
+        return respondWithError(ctx, clusterConfig.Error)
+    }
+    encryptionInfo := clusterConfig.ClusterConfig.EncryptionInfo
+    return ctx.JSON(http.StatusOK, models.EncryptionAtRestStatusResponse{
+        Data: models.EncryptionAtRestStatus{
+            Enabled: encryptionInfo.EncryptionEnabled,
+            KeyId: encryptionInfo.LatestVersionId,
+            KeyInMemory: encryptionInfo.KeyInMemory,
+            Progress: c.encryptionAtRest.get(),
+        },
+    })
+}
+
+// EnableEncryptionAtRest - Encrypt the data of the cluster with a key, or rotate its key
+func (c *Container) EnableEncryptionAtRest(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.EncryptionAtRest {
+        return respondError(ctx, http.StatusForbidden, "encryption at rest is disabled")
+    }
+    encryptionSpec := models.EncryptionAtRestSpec{}
+    if err := ctx.Bind(&encryptionSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    key, err := getEncryptionKey(encryptionSpec)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    keyId := encryptionSpec.KeyId
+    if keyId == "" {
+        if keyId, err = helpers.Random128BitString(); err != nil {
+            return respondWithError(ctx, err)
+        }
+    }
+    clusterConfigFuture := make(chan helpers.ClusterConfigFuture)
+    go helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture)
+    clusterConfig := <-clusterConfigFuture
+    if clusterConfig.Error != nil {
+        return respondWithError(ctx, clusterConfig.Error)
+    }
+    description := fmt.Sprintf("enable encryption at rest with the key %s", keyId)
+    if clusterConfig.ClusterConfig.EncryptionInfo.EncryptionEnabled {
+        description = fmt.Sprintf("rotate the encryption at rest key to %s", keyId)
+    }
+    if encryptionSpec.EncryptExistingData {
+        description += ", and compact every table to encrypt the existing data"
+    }
+    if !c.confirmations.consume(encryptionSpec.ConfirmationToken, description) {
+        token, expiresAt, err := c.confirmations.issue(description)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        return ctx.JSON(http.StatusConflict, models.ConfirmationRequiredResponse{
+            Data: models.ConfirmationRequired{
+                ConfirmationToken: token,
+                ExpireTimestamp:   expiresAt.Unix(),
+                Description:       description,
+            },
+        })
+    }
+    if !c.encryptionAtRest.start() {
+        return respondError(ctx, http.StatusConflict,
+            "encryption at rest is already being enabled")
+    }
+    task, err := c.tasks.Submit("enable_encryption_at_rest", func(task *tasks.Task) error {
+        defer c.encryptionAtRest.finish()
+        c.encryptionAtRest.begin(task.Info().Id, keyId)
+        return c.enableEncryptionAtRest(task, keyId, key,
+            encryptionSpec.EncryptExistingData)
+    })
+    if err != nil {
+        c.encryptionAtRest.finish()
+        return respondWithError(ctx, err)
+    }
+    // The key itself is never logged
+    c.auditLog(ctx, "enable_encryption_at_rest", "key_id", keyId,
+        "key_file", encryptionSpec.KeyFile,
+        "encrypt_existing_data", encryptionSpec.EncryptExistingData, "task_id", task.Id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// GetWebhooks - Get list of webhooks
+func (c *Container) GetWebhooks(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, models.WebhookListResponse{
+        Data: c.webhooks.list(),
+    })
+}
+
+// CreateWebhook - Create a webhook
+func (c *Container) CreateWebhook(ctx echo.Context) error {
+    webhookSpec := models.WebhookSpec{}
+    if err := ctx.Bind(&webhookSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if err := validateWebhookSpec(webhookSpec, true); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    createdBy := ""
+    if existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session); ok {
+        createdBy = existing.username
+    }
+    webhook, err := c.webhooks.create(webhookSpec, createdBy)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "create_webhook", "webhook_id", webhook.Id, "url", webhook.Url,
+        "event_types", strings.Join(webhook.EventTypes, ","))
+    return ctx.JSON(http.StatusOK, models.WebhookResponse{
+        Data: webhook,
+    })
+}
+
+// UpdateWebhook - Change a webhook
+func (c *Container) UpdateWebhook(ctx echo.Context) error {
+    id := ctx.Param("id")
+    webhookSpec := models.WebhookSpec{}
+    if err := ctx.Bind(&webhookSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if err := validateWebhookSpec(webhookSpec, false); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    webhook, ok, err := c.webhooks.update(id, webhookSpec)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("webhook %s not found", id))
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "update_webhook", "webhook_id", id, "url", webhook.Url,
+        "event_types", strings.Join(webhook.EventTypes, ","),
+        "secret_changed", webhookSpec.Secret != "")
+    return ctx.JSON(http.StatusOK, models.WebhookResponse{
+        Data: webhook,
+    })
+}
+
+// DeleteWebhook - Delete a webhook
+func (c *Container) DeleteWebhook(ctx echo.Context) error {
+    id := ctx.Param("id")
+    ok, err := c.webhooks.delete(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("webhook %s not found", id))
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "delete_webhook", "webhook_id", id)
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// Number of events GetClusterEvents returns by default, and at most
+const CLUSTER_EVENTS_DEFAULT_LIMIT = 100
+const CLUSTER_EVENTS_MAX_LIMIT = 1000
+
+// GetDdlActivity - Get the DDL statements that ran on the nodes and the catalog version bumps
+func (c *Container) GetDdlActivity(ctx echo.Context) error {
+    to := time.Now().Unix()
+    if ctx.QueryParam("to") != "" {
+        parsed, err := strconv.ParseInt(ctx.QueryParam("to"), 10, 64)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "to must be a unix timestamp")
+        }
+        to = parsed
+    }
+    from := int64(0)
+    if ctx.QueryParam("from") != "" {
+        parsed, err := strconv.ParseInt(ctx.QueryParam("from"), 10, 64)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "from must be a unix timestamp")
+        }
+        from = parsed
+    }
+    if from > to {
+        return respondError(ctx, http.StatusBadRequest, "from must not be after to")
+    }
+    kind := ctx.QueryParam("kind")
+    if kind != "" && !containsString(DDL_ACTIVITY_KINDS, kind) {
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf(
+            "kind must be one of %s, got %q", strings.Join(DDL_ACTIVITY_KINDS, ", "), kind))
+    }
+    limit := DDL_ACTIVITY_DEFAULT_LIMIT
+    if limitParam := ctx.QueryParam("limit"); limitParam != "" {
+        parsed, err := strconv.Atoi(limitParam)
+        if err != nil || parsed < 1 || parsed > DDL_ACTIVITY_MAX_LIMIT {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("limit must be between 1 and %d", DDL_ACTIVITY_MAX_LIMIT))
+        }
+        limit = parsed
+    }
+    activity, nextCursor, err := c.ddlActivity.list(from, to, kind, ctx.QueryParam("node"),
+        ctx.QueryParam("cursor"), limit)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.DdlActivityListResponse{
+        Data: activity,
+        NextCursor: nextCursor,
+    })
+}
+
+// GetWorkloads - Get list of the running sample workloads
+func (c *Container) GetWorkloads(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, models.WorkloadListResponse{
+        Data: c.workloads.list(),
+    })
+}
+
+// StartWorkload - Start a sample workload
+func (c *Container) StartWorkload(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.Workloads {
+        return respondError(ctx, http.StatusForbidden, "workloads are disabled")
+    }
+    workloadSpec := models.WorkloadSpec{}
+    if err := ctx.Bind(&workloadSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if err := validateWorkloadSpec(workloadSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    workload := &runningWorkload{
+        spec: workloadSpec,
+        startedAt: time.Now(),
+    }
+    if existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session); ok {
+        workload.startedBy = existing.username
+    }
+    workload.ctx, workload.cancel = context.WithCancel(context.Background())
+    if !c.workloads.add(workload) {
+        workload.cancel()
+        return respondError(ctx, http.StatusConflict, fmt.Sprintf(
+            "%d workloads are already running", helpers.GetConfig().Workloads.MaxRunning))
+    }
+    // The task waits for its id to be set on the workload before it runs it
+    submitted := make(chan struct{})
+    task, err := c.tasks.Submit("run_workload", func(task *tasks.Task) error {
+        <-submitted
+        defer c.workloads.remove(workload)
+        defer workload.cancel()
+        return c.runWorkload(task, workload)
+    })
+    if err != nil {
+        c.workloads.remove(workload)
+        workload.cancel()
+        return respondWithError(ctx, err)
+    }
+    c.workloads.setTaskId(workload, task.Id)
+    close(submitted)
+    c.auditLog(ctx, "start_workload", "workload", workloadSpec.Workload,
+        "threads", workloadSpec.Threads, "duration_seconds", workloadSpec.DurationSeconds,
+        "read_percent", workloadSpec.ReadPercent, "task_id", task.Id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// StopWorkload - Stop a running sample workload
+func (c *Container) StopWorkload(ctx echo.Context) error {
+    id := ctx.Param("id")
+    if !c.workloads.stop(id) {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("no running workload has task %s", id))
+    }
+    c.auditLog(ctx, "stop_workload", "task_id", id)
+    task, _ := c.tasks.Get(id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// GetClusterEvents - Get the changes detected in the cluster
+func (c *Container) GetClusterEvents(ctx echo.Context) error {
+    to := time.Now().Unix()
+    if ctx.QueryParam("to") != "" {
+        parsed, err := strconv.ParseInt(ctx.QueryParam("to"), 10, 64)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "to must be a unix timestamp")
+        }
+        to = parsed
+    }
+    from := int64(0)
+    if ctx.QueryParam("from") != "" {
+        parsed, err := strconv.ParseInt(ctx.QueryParam("from"), 10, 64)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "from must be a unix timestamp")
+        }
+        from = parsed
+    }
+    if from > to {
+        return respondError(ctx, http.StatusBadRequest, "from must not be after to")
+    }
+    eventType := ctx.QueryParam("type")
+    if eventType != "" && !containsString(CLUSTER_EVENT_TYPES, eventType) {
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf(
+            "type must be one of %s, got %q", strings.Join(CLUSTER_EVENT_TYPES, ", "), eventType))
+    }
+    limit := CLUSTER_EVENTS_DEFAULT_LIMIT
+    if limitParam := ctx.QueryParam("limit"); limitParam != "" {
+        parsed, err := strconv.Atoi(limitParam)
+        if err != nil || parsed < 1 || parsed > CLUSTER_EVENTS_MAX_LIMIT {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("limit must be between 1 and %d", CLUSTER_EVENTS_MAX_LIMIT))
+        }
+        limit = parsed
+    }
+    events, nextCursor, err := c.clusterEvents.list(from, to, eventType,
+        ctx.QueryParam("cursor"), limit)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.ClusterEventListResponse{
+        Data: events,
+        NextCursor: nextCursor,
+    })
+}
+
+// How long GetClusterChanges waits for a change by default, and at most
+const CLUSTER_CHANGES_DEFAULT_WAIT = 30 * time.Second
+const CLUSTER_CHANGES_MAX_WAIT = 60 * time.Second
+
+// GetClusterChanges - Wait for changes of the cluster state
+func (c *Container) GetClusterChanges(ctx echo.Context) error {
+    wait := CLUSTER_CHANGES_DEFAULT_WAIT
+    if waitParam := ctx.QueryParam("wait"); waitParam != "" {
+        waitSeconds, err := strconv.Atoi(waitParam)
+        if err != nil || waitSeconds < 0 {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("invalid wait %q, expected a number of seconds", waitParam))
+        }
+        wait = time.Duration(waitSeconds) * time.Second
+        if wait > CLUSTER_CHANGES_MAX_WAIT {
+            wait = CLUSTER_CHANGES_MAX_WAIT
+        }
+    }
+    since := ctx.QueryParam("since")
+    c.clusterState.touch()
+    timeout := time.NewTimer(wait)
+    defer timeout.Stop()
+    for {
+        changes, hasChanges, changed := c.clusterState.getChanges(since)
+        if hasChanges {
+            return ctx.JSON(http.StatusOK, models.ClusterStateChangesResponse{
+                Data: changes,
+            })
+        }
+        select {
+        case <-changed:
+        case <-timeout.C:
+            // Nothing changed, the client asks again with the same cursor
+            return ctx.JSON(http.StatusOK, models.ClusterStateChangesResponse{
+                Data: changes,
+            })
+        case <-ctx.Request().Context().Done():
+            return nil
+        }
+    }
+}
+
+// Version of the layout of the cluster snapshot, incremented on incompatible changes
+const CLUSTER_SNAPSHOT_SCHEMA_VERSION = 1
+
+// Gets the current view of the cluster. Only the tservers are required, parts of the view that
+// cannot be fetched otherwise are listed in the errors of the snapshot.
+func getClusterSnapshot(timestamp time.Time) (models.ClusterSnapshot, error) {
+    fanOut := newFanOutLimiter()
+    tabletServersFuture := make(chan helpers.TabletServersFuture, 1)
+    mastersFuture := make(chan helpers.MastersFuture, 1)
+    healthCheckFuture := make(chan helpers.HealthCheckFuture, 1)
+    clusterConfigFuture := make(chan helpers.ClusterConfigFuture, 1)
+    fanOut.goCall(func() { helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture) })
+    fanOut.goCall(func() { helpers.GetMastersFuture(helpers.HOST, mastersFuture) })
+    fanOut.goCall(func() { helpers.GetHealthCheckFuture(helpers.HOST, healthCheckFuture) })
+    fanOut.goCall(func() { helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture) })
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return models.ClusterSnapshot{}, tabletServersResponse.Error
+    }
+    snapshot := models.ClusterSnapshot{
+        SchemaVersion: CLUSTER_SNAPSHOT_SCHEMA_VERSION,
+        Timestamp: timestamp.Unix(),
+        Placement: []models.ClusterSnapshotPlacementBlock{},
+        Nodes: []models.ClusterSnapshotNode{},
+        Masters: []models.ClusterStateMaster{},
+        Errors: []string{},
+    }
+    stateNodes := getClusterStateNodes(tabletServersResponse)
+    gFlagsTserverFutures := []chan helpers.GFlagsFuture{}
+    gFlagsMasterFutures := []chan helpers.GFlagsFuture{}
+    versionInfoFutures := []chan helpers.VersionInfoFuture{}
+    for _, node := range stateNodes {
+        name := node.Name
+        gFlagsTserverFuture := make(chan helpers.GFlagsFuture, 1)
+        gFlagsTserverFutures = append(gFlagsTserverFutures, gFlagsTserverFuture)
+        fanOut.goCall(func() { helpers.GetGFlagsFuture(name, false, gFlagsTserverFuture) })
+        gFlagsMasterFuture := make(chan helpers.GFlagsFuture, 1)
+        gFlagsMasterFutures = append(gFlagsMasterFutures, gFlagsMasterFuture)
+        fanOut.goCall(func() { helpers.GetGFlagsFuture(name, true, gFlagsMasterFuture) })
+        versionInfoFuture := make(chan helpers.VersionInfoFuture, 1)
+        versionInfoFutures = append(versionInfoFutures, versionInfoFuture)
+        fanOut.goCall(func() { helpers.GetVersionFuture(name, versionInfoFuture) })
+    }
+
+    masterHosts := map[string]bool{}
+    if mastersResponse := <-mastersFuture; mastersResponse.Error == nil {
+        snapshot.Masters = getClusterStateMasters(mastersResponse)
+        for _, master := range snapshot.Masters {
+            masterHosts[master.Host] = true
+        }
+    } else {
+        snapshot.Errors = append(snapshot.Errors,
+            fmt.Sprintf("masters: %s", mastersResponse.Error.Error()))
+    }
+    if healthCheckResponse := <-healthCheckFuture; healthCheckResponse.Error == nil {
+        health := getClusterStateHealth(healthCheckResponse)
+        snapshot.Health = &health
+    } else {
+        snapshot.Errors = append(snapshot.Errors,
+            fmt.Sprintf("health check: %s", healthCheckResponse.Error.Error()))
+    }
+    if clusterConfigResponse := <-clusterConfigFuture; clusterConfigResponse.Error == nil {
+        clusterConfig := getClusterStateConfig(clusterConfigResponse)
+        snapshot.ClusterConfig = &clusterConfig
+        liveReplicas := clusterConfigResponse.ClusterConfig.ReplicationInfo.LiveReplicas
+        for _, block := range liveReplicas.PlacementBlocks {
+            snapshot.Placement = append(snapshot.Placement, models.ClusterSnapshotPlacementBlock{
+                Cloud: block.CloudInfo.PlacementCloud,
+                Region: block.CloudInfo.PlacementRegion,
+                Zone: block.CloudInfo.PlacementZone,
+                MinNumReplicas: int32(block.MinNumReplicas),
+            })
+        }
+    } else {
+        snapshot.Errors = append(snapshot.Errors,
+            fmt.Sprintf("cluster config: %s", clusterConfigResponse.Error.Error()))
+    }
+
+    for i, node := range stateNodes {
+        snapshotNode := models.ClusterSnapshotNode{
+            Name: node.Name,
+            Status: node.Status,
+            Cloud: node.Cloud,
+            Region: node.Region,
+            Zone: node.Zone,
+            IsMaster: masterHosts[node.Name],
+            TserverGflags: map[string]string{},
+        }
+        if versionInfo := <-versionInfoFutures[i]; versionInfo.Error == nil {
+            snapshotNode.Version = versionInfo.VersionInfo.VersionNumber
+            snapshotNode.BuildNumber = versionInfo.VersionInfo.BuildNumber
+        } else {
+            snapshot.Errors = append(snapshot.Errors,
+                fmt.Sprintf("version of %s: %s", node.Name, versionInfo.Error.Error()))
+        }
+        if tserverFlags := <-gFlagsTserverFutures[i]; tserverFlags.Error == nil {
+            snapshotNode.TserverGflags = tserverFlags.GFlags
+        } else {
+            snapshot.Errors = append(snapshot.Errors,
+                fmt.Sprintf("tserver gflags of %s: %s", node.Name, tserverFlags.Error.Error()))
+        }
+        masterFlags := <-gFlagsMasterFutures[i]
+        if snapshotNode.IsMaster {
+            if masterFlags.Error == nil {
+                snapshotNode.MasterGflags = &masterFlags.GFlags
+            } else {
+                snapshot.Errors = append(snapshot.Errors,
+                    fmt.Sprintf("master gflags of %s: %s", node.Name, masterFlags.Error.Error()))
+            }
+        }
+        snapshot.Nodes = append(snapshot.Nodes, snapshotNode)
+    }
+    return snapshot, nil
+}
+
+// GetClusterSnapshot - Export the current view of the cluster
+func (c *Container) GetClusterSnapshot(ctx echo.Context) error {
+    timestamp := time.Now()
+    snapshot, err := getClusterSnapshot(timestamp)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    // Saved as a file by browsers, to be attached to support tickets and change records
+    ctx.Response().Header().Set(echo.HeaderContentDisposition,
+        fmt.Sprintf("attachment; filename=\"cluster-snapshot-%s.json\"",
+            timestamp.UTC().Format("20060102T150405Z")))
+    return ctx.JSON(http.StatusOK, models.ClusterSnapshotResponse{
+        Data: snapshot,
+    })
+}
+
+// DiffClusterSnapshots - Compare two cluster snapshots
+func (c *Container) DiffClusterSnapshots(ctx echo.Context) error {
+    snapshotDiffBody := struct {
+        Base json.RawMessage `json:"base"`
+        Target json.RawMessage `json:"target"`
+    }{}
+    if err := ctx.Bind(&snapshotDiffBody); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    snapshotDiffSpec := models.SnapshotDiffSpec{}
+    var err error
+    snapshotDiffSpec.Base, err = decodeClusterSnapshot(snapshotDiffBody.Base)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid base: "+err.Error())
+    }
+    if len(snapshotDiffBody.Target) > 0 && string(snapshotDiffBody.Target) != "null" {
+        target, err := decodeClusterSnapshot(snapshotDiffBody.Target)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "invalid target: "+err.Error())
+        }
+        snapshotDiffSpec.Target = &target
+    }
+    if snapshotDiffSpec.Base.SchemaVersion != CLUSTER_SNAPSHOT_SCHEMA_VERSION {
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("base must be a cluster snapshot with schema_version %d, got %d",
+                CLUSTER_SNAPSHOT_SCHEMA_VERSION, snapshotDiffSpec.Base.SchemaVersion))
+    }
+    targetIsLive := snapshotDiffSpec.Target == nil
+    var target models.ClusterSnapshot
+    if targetIsLive {
+        target, err = getClusterSnapshot(time.Now())
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+    } else {
+        target = *snapshotDiffSpec.Target
+        if target.SchemaVersion != CLUSTER_SNAPSHOT_SCHEMA_VERSION {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("target must be a cluster snapshot with schema_version %d, got %d",
+                    CLUSTER_SNAPSHOT_SCHEMA_VERSION, target.SchemaVersion))
+        }
+    }
+    return ctx.JSON(http.StatusOK, models.SnapshotDiffResponse{
+        Data: models.SnapshotDiff{
+            BaseTimestamp: snapshotDiffSpec.Base.Timestamp,
+            TargetTimestamp: target.Timestamp,
+            TargetIsLive: targetIsLive,
+            Changes: diffSnapshots(snapshotDiffSpec.Base, target),
+        },
+    })
+}
+
+// Decodes a cluster snapshot as the export endpoint returns it, with the snapshot in its data
+// field, or the bare snapshot
+func decodeClusterSnapshot(raw json.RawMessage) (models.ClusterSnapshot, error) {
+    exported := struct {
+        Data *models.ClusterSnapshot `json:"data"`
+    }{}
+    if err := json.Unmarshal(raw, &exported); err != nil {
+        return models.ClusterSnapshot{}, err
+    }
+    if exported.Data != nil {
+        return *exported.Data, nil
+    }
+    snapshot := models.ClusterSnapshot{}
+    err := json.Unmarshal(raw, &snapshot)
+    return snapshot, err
+}
+
+// Diagnostics are sent with all the sections up to this level
+var CALLHOME_COLLECTION_LEVELS = []string{"low", "medium", "high"}
+
+// Gets the diagnostics reporting settings of every master and tserver, from their gflags
+func getCallhomeServers() ([]models.CallhomeServer, error) {
+    fanOut := newFanOutLimiter()
+    tabletServersFuture := make(chan helpers.TabletServersFuture, 1)
+    mastersFuture := make(chan helpers.MastersFuture, 1)
+    fanOut.goCall(func() { helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture) })
+    fanOut.goCall(func() { helpers.GetMastersFuture(helpers.HOST, mastersFuture) })
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return nil, tabletServersResponse.Error
+    }
+    mastersResponse := <-mastersFuture
+    if mastersResponse.Error != nil {
+        return nil, mastersResponse.Error
+    }
+    servers := []models.CallhomeServer{}
+    for _, master := range mastersResponse.Masters {
+        if len(master.Registration.PrivateRpcAddresses) > 0 {
+            servers = append(servers, models.CallhomeServer{
+                Host: helpers.NormalizeHost(master.Registration.PrivateRpcAddresses[0].Host),
+                ServerType: "master",
+            })
+        }
+    }
+    for _, cluster := range tabletServersResponse.Tablets {
+        for address := range cluster {
+            if host, err := helpers.GetHostFromAddress(address); err == nil {
+                servers = append(servers, models.CallhomeServer{
+                    Host: host,
+                    ServerType: "tserver",
+                })
+            }
+        }
+    }
+    sort.Slice(servers, func(i, j int) bool {
+        if servers[i].Host != servers[j].Host {
+            return servers[i].Host < servers[j].Host
+        }
+        return servers[i].ServerType < servers[j].ServerType
+    })
+    gFlagsFutures := []chan helpers.GFlagsFuture{}
+    for _, server := range servers {
+        host, isMaster := server.Host, server.ServerType == "master"
+        gFlagsFuture := make(chan helpers.GFlagsFuture, 1)
+        gFlagsFutures = append(gFlagsFutures, gFlagsFuture)
+        fanOut.goCall(func() { helpers.GetGFlagsFuture(host, isMaster, gFlagsFuture) })
+    }
+    for index, gFlagsFuture := range gFlagsFutures {
+        gFlags := <-gFlagsFuture
+        if gFlags.Error != nil {
+            servers[index].Error = gFlags.Error.Error()
+            continue
+        }
+        servers[index].Enabled = gFlags.GFlags["callhome_enabled"] == "true"
+        servers[index].CollectionLevel = gFlags.GFlags["callhome_collection_level"]
+        servers[index].Url = gFlags.GFlags["callhome_url"]
+        intervalSecs, _ := strconv.Atoi(gFlags.GFlags["callhome_interval_secs"])
+        servers[index].IntervalSecs = int32(intervalSecs)
+    }
+    return servers, nil
+}
+
+func getCallhomeSettings(servers []models.CallhomeServer) models.CallhomeSettings {
+    settings := models.CallhomeSettings{
+        Servers: servers,
+    }
+    for _, server := range servers {
+        if server.Enabled {
+            settings.Enabled = true
+        }
+    }
+    return settings
+}
+
+// GetCallhome - Get the diagnostics reporting settings
+func (c *Container) GetCallhome(ctx echo.Context) error {
+    servers, err := getCallhomeServers()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.CallhomeSettingsResponse{
+        Data: getCallhomeSettings(servers),
+    })
+}
+
+// UpdateCallhome - Change the diagnostics reporting settings
+func (c *Container) UpdateCallhome(ctx echo.Context) error {
+    callhomeSpec := models.CallhomeSpec{}
+    if err := ctx.Bind(&callhomeSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if callhomeSpec.CollectionLevel != nil {
+        isValidLevel := false
+        for _, level := range CALLHOME_COLLECTION_LEVELS {
+            isValidLevel = isValidLevel || *callhomeSpec.CollectionLevel == level
+        }
+        if !isValidLevel {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("collection_level must be one of %s, got %s",
+                    strings.Join(CALLHOME_COLLECTION_LEVELS, ", "),
+                    *callhomeSpec.CollectionLevel))
+        }
+    }
+    servers, err := getCallhomeServers()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    flags := map[string]string{
+        "callhome_enabled": strconv.FormatBool(callhomeSpec.Enabled),
+    }
+    if callhomeSpec.CollectionLevel != nil {
+        flags["callhome_collection_level"] = *callhomeSpec.CollectionLevel
+    }
+    // A flag that is only read at start would be changed without any effect
+    names := []string{}
+    for name := range flags {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, serverType := range []string{GFLAG_SERVER_TYPE_MASTER, GFLAG_SERVER_TYPE_TSERVER} {
+        nonRuntime := c.gflagDocs.getNonRuntimeGflags(serverType, names)
+        for _, name := range names {
+            if impact, ok := nonRuntime[name]; ok {
+                return respondError(ctx, http.StatusBadRequest,
+                    fmt.Sprintf("gflag %s of the %s cannot be changed at runtime: %s",
+                        name, serverType, impact))
+            }
+        }
+    }
+    // The flags are set on every server in parallel, and the failures reported per server
+    setErrors := make([]error, len(servers))
+    fanOut := newFanOutLimiter()
+    var wait sync.WaitGroup
+    for index, server := range servers {
+        index, server := index, server
+        wait.Add(1)
+        fanOut.goCall(func() {
+            defer wait.Done()
+            for name, value := range flags {
+                err := helpers.SetGFlag(server.Host, server.ServerType == "master", name, value)
+                if err != nil {
+                    setErrors[index] = err
+                    return
+                }
+            }
+        })
+    }
+    wait.Wait()
+    c.auditLog(ctx, "update_callhome", "enabled", callhomeSpec.Enabled,
+        "collection_level", flags["callhome_collection_level"])
+    updatedServers, err := getCallhomeServers()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    failures := 0
+    for index, server := range servers {
+        if setErrors[index] == nil {
+            continue
+        }
+        failures++
+        for updatedIndex, updatedServer := range updatedServers {
+            if updatedServer.Host == server.Host && updatedServer.ServerType == server.ServerType {
+                updatedServers[updatedIndex].Error = setErrors[index].Error()
+            }
+        }
+    }
+    if len(servers) > 0 && failures == len(servers) {
+        return respondWithError(ctx,
+            fmt.Errorf("failed to change the settings of all servers: %w", setErrors[0]))
+    }
+    // The gflags are not written where the processes are started, e.g. the yugabyted
+    // configuration, so the processes start with their previous settings again
+    settings := getCallhomeSettings(updatedServers)
+    persisted := false
+    warning := "the settings were changed at runtime only, and are lost when a master or " +
+        "tserver restarts unless its gflags are changed where it is started too"
+    settings.Persisted = &persisted
+    settings.Warning = &warning
+    return ctx.JSON(http.StatusOK, models.CallhomeSettingsResponse{
+        Data: settings,
+    })
+}
+
+// GetCallhomePreview - Preview the diagnostics sent by the tserver of this host
+func (c *Container) GetCallhomePreview(ctx echo.Context) error {
+    gFlagsFuture := make(chan helpers.GFlagsFuture)
+    clusterConfigFuture := make(chan helpers.ClusterConfigFuture)
+    go helpers.GetGFlagsFuture(helpers.HOST, false, gFlagsFuture)
+    go helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture)
+    gFlags := <-gFlagsFuture
+    if gFlags.Error != nil {
+        return respondWithError(ctx, gFlags.Error)
+    }
+    clusterConfig := <-clusterConfigFuture
+    if clusterConfig.Error != nil {
+        return respondWithError(ctx, clusterConfig.Error)
+    }
+    collectionLevel := ctx.QueryParam("collection_level")
+    if collectionLevel == "" {
+        collectionLevel = gFlags.GFlags["callhome_collection_level"]
+    }
+    levelIndex := -1
+    for index, level := range CALLHOME_COLLECTION_LEVELS {
+        if collectionLevel == level {
+            levelIndex = index
+        }
+    }
+    if levelIndex < 0 {
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("collection_level must be one of %s, got %s",
+                strings.Join(CALLHOME_COLLECTION_LEVELS, ", "), collectionLevel))
+    }
+    hostToUuid, err := c.hostToUuid.get()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    nodeUuid, _ := hostToUuid.Get(helpers.HOST)
+    // Laid out like the collectors of the tserver, each of which sends its section from the
+    // collection level it is registered at
+    payload := map[string]interface{}{
+        "cluster_uuid": clusterConfig.ClusterConfig.ClusterUuid,
+        "node_uuid": nodeUuid,
+        "server_type": "tserver",
+        "timestamp": time.Now().Unix(),
+    }
+    if levelIndex >= 1 {
+        payload["gflags"] = gFlags.GFlags
+    }
+    if levelIndex >= 2 {
+        sectionPaths := map[string]string{"metrics": "/metrics", "rpcs": "/rpcz"}
+        for section, path := range sectionPaths {
+            documentFuture := make(chan helpers.JsonDocumentFuture)
+            go helpers.GetJsonDocumentFuture(helpers.HOST, false, path, documentFuture)
+            document := <-documentFuture
+            if document.Error != nil {
+                return respondWithError(ctx, document.Error)
+            }
+            payload[section] = document.Document
+        }
+    }
+    return ctx.JSON(http.StatusOK, models.CallhomePreviewResponse{
+        Data: models.CallhomePreview{
+            CollectionLevel: collectionLevel,
+            Url: gFlags.GFlags["callhome_url"],
+            Payload: payload,
+        },
+    })
+}
+
+// GetYbAdminCommands - Get list of the yb-admin commands that can be run
+func (c *Container) GetYbAdminCommands(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, models.YbAdminCommandListResponse{
+        Data: getYbAdminCommands(),
+    })
+}
+
+// RunYbAdminCommand - Run a read-only yb-admin command
+func (c *Container) RunYbAdminCommand(ctx echo.Context) error {
+    name := ctx.Param("command")
+    command, ok := YB_ADMIN_COMMANDS[name]
+    if !ok {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("yb-admin command %s cannot be run from the UI", name))
+    }
+    args := ctx.QueryParams()["arg"]
+    if args == nil {
+        args = []string{}
+    }
+    if err := command.validate(name, args); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    output, err := helpers.RunYbAdmin(append([]string{name}, args...)...)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    parsed, err := parseYbAdminOutput(name, args, output, command.output)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.YbAdminOutputResponse{
+        Data: parsed,
+    })
+}
+
+// GetRestorePreview - Get what restoring a snapshot or a snapshot schedule would do
+func (c *Container) GetRestorePreview(ctx echo.Context) error {
+    snapshotId := ctx.QueryParam("snapshot_id")
+    scheduleId := ctx.QueryParam("schedule_id")
+    if (snapshotId == "") == (scheduleId == "") {
+        return respondError(ctx, http.StatusBadRequest,
+            "exactly one of snapshot_id and schedule_id must be set")
+    }
+    restoreTime := int64(0)
+    namespaces := []string{}
+    snapshotTime := int64(0)
+    if scheduleId != "" {
+        if !YB_ADMIN_ID_REGEX.MatchString(scheduleId) {
+            return respondError(ctx, http.StatusBadRequest, "invalid schedule_id")
+        }
+        parsed, err := strconv.ParseInt(ctx.QueryParam("restore_time"), 10, 64)
+        if err != nil || parsed <= 0 || parsed > time.Now().Unix() {
+            return respondError(ctx, http.StatusBadRequest,
+                "restore_time must be a UNIX timestamp in the past")
+        }
+        restoreTime = parsed
+        snapshot, ok, err := getScheduleSnapshot(scheduleId, time.Unix(restoreTime, 0))
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        if !ok {
+            return respondError(ctx, http.StatusNotFound,
+                fmt.Sprintf("snapshot schedule %s not found", scheduleId))
+        }
+        if snapshot.snapshotId == "" {
+            return respondError(ctx, http.StatusBadRequest, fmt.Sprintf(
+                "snapshot schedule %s has no snapshot at or before restore_time",
+                scheduleId))
+        }
+        snapshotId = snapshot.snapshotId
+        namespaces = snapshot.namespaces
+        snapshotTime = snapshot.snapshotTime.Unix()
+    } else if !YB_ADMIN_ID_REGEX.MatchString(snapshotId) {
+        return respondError(ctx, http.StatusBadRequest, "invalid snapshot_id")
+    }
+    preview, ok, err := getRestorePreview(snapshotId, namespaces)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !ok {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("snapshot %s not found", snapshotId))
+    }
+    preview.ScheduleId = scheduleId
+    preview.RestoreTime = restoreTime
+    preview.SnapshotTime = snapshotTime
+    return ctx.JSON(http.StatusOK, models.RestorePreviewResponse{
+        Data: preview,
+    })
+}
@@ -4,19 +4,16 @@ import (
         "apiserver/cmd/server/helpers"
         "apiserver/cmd/server/models"
         "context"
-        "encoding/json"
         "fmt"
         "math"
-        "net"
         "net/http"
         "sort"
         "strconv"
         "strings"
         "time"
 
-        "github.com/jackc/pgx/v4"
+        "github.com/jackc/pgx/v4/pgxpool"
         "github.com/labstack/echo/v4"
-        "github.com/yugabyte/gocql"
 )
 
 const SLOW_QUERY_STATS_SQL string = "SELECT a.rolname, t.datname, t.queryid, " +
@@ -29,6 +26,10 @@ var EXCLUDED_QUERY_STATEMENTS = map[string]bool{
         SLOW_QUERY_STATS_SQL:         true,
 }
 
+// query for the latest value on one node
+const QUERY_LIMIT_ONE string = "select ts, value, details " +
+        "from %s where metric='%s' and node='%s' limit 1;"
+
 // query over one node
 const QUERY_FORMAT_NODE string = "select ts, value, details from " +
         "%s where metric = '%s' and node = '%s' and ts >= %d and ts < %d"
@@ -37,15 +38,18 @@ const QUERY_FORMAT_NODE string = "select ts, value, details from " +
 const QUERY_FORMAT string = "select ts, value, details from " +
         "%s where metric = '%s' and ts >= %d and ts < %d"
 
-// the count metrics count the total number of accumulated ops, and the sum metric
-// counts the total amount of time spent on ops.
-const READ_COUNT_METRIC = "handler_latency_yb_tserver_TabletServerService_Read_count"
-const WRITE_COUNT_METRIC = "handler_latency_yb_tserver_TabletServerService_Write_count"
-const READ_SUM_METRIC = "handler_latency_yb_tserver_TabletServerService_Read_sum"
-const WRITE_SUM_METRIC = "handler_latency_yb_tserver_TabletServerService_Write_sum"
 
 const GRANULARITY_NUM_INTERVALS = 120
 
+// yb_servers() is what smart drivers use to discover the servers they can connect to
+const YB_SERVERS_SQL string = "SELECT host, port, num_connections, node_type, cloud, region, " +
+        "zone, public_ip, uuid FROM yb_servers()"
+
+// Longest validity that can be requested for a client certificate
+const MAX_CLIENT_CERT_VALIDITY_DAYS = 3650
+
+const DEFAULT_CLIENT_CERT_VALIDITY_DAYS = 365
+
 type SlowQueriesFuture struct {
         Items []*models.SlowQueryResponseYsqlQueryItem
         Error error
@@ -67,7 +71,7 @@ func getNodes() ([]string, error) {
         // to get hostnames, get all second level keys and only keep them if net.SpliHostPort succeeds.
         for _, obj := range tabletServersResponse.Tablets {
                 for hostport := range obj {
-                        host, _, err := net.SplitHostPort(hostport)
+                        host, err := helpers.GetHostFromAddress(hostport)
                         if err == nil {
                                 hostNames = append(hostNames, host)
                         }
@@ -76,7 +80,7 @@ func getNodes() ([]string, error) {
         return hostNames, nil
 }
 
-func getSlowQueriesFuture(nodeHost string, conn *pgx.Conn, future chan SlowQueriesFuture) {
+func getSlowQueriesFuture(nodeHost string, conn *pgxpool.Pool, future chan SlowQueriesFuture) {
         slowQueries := SlowQueriesFuture{
                 Items: []*models.SlowQueryResponseYsqlQueryItem{},
                 Error: nil,
@@ -191,20 +195,20 @@ func calculateCombinedMetric(nodeValues [][][]float64, isAverage bool) [][]float
 }
 
 // Get metrics that are meant to be averaged over all nodes. detailsValue is true if the value of
-// the metric is in the details column instead of the value column in the system.metrics table.
+// the metric is in the details column instead of the value column in the metrics table.
 // Note: assumes values are percentages, and so all values are multiplied by 100
 func getAveragePercentageMetricData(
         metricColumnValue string,
         nodeList []string,
-        hostToUuid map[string]string,
+        hostToUuid helpers.HostToUuidMap,
         startTime int64,
         endTime int64,
-        session *gocql.Session,
+        reader metricsReader,
         detailsValue bool,
 ) ([][]float64, error) {
         metricValues := [][]float64{}
         rawMetricValues, err := getRawMetricsForAllNodes(metricColumnValue, nodeList, hostToUuid,
-                startTime, endTime, session, detailsValue)
+                startTime, endTime, reader, detailsValue)
         if err != nil {
                 return metricValues, err
         }
@@ -286,36 +290,21 @@ func reduceGranularityForAllNodes(
 func getRawMetricsForAllNodes(
         metricColumnValue string,
         nodeList []string,
-        hostToUuid map[string]string,
+        hostToUuid helpers.HostToUuidMap,
         startTime int64,
         endTime int64,
-        session *gocql.Session,
+        reader metricsReader,
         detailsValue bool,
 ) ([][][]float64, error) {
         nodeValues := [][][]float64{}
-        var ts int64
-        var value int
-        var details string
         for _, hostName := range nodeList {
-                query := fmt.Sprintf(QUERY_FORMAT_NODE, "system.metrics", metricColumnValue,
-                        hostToUuid[hostName], startTime*1000, endTime*1000)
-                iter := session.Query(query).Iter()
-                values := [][]float64{}
-                for iter.Scan(&ts, &value, &details) {
-                        if detailsValue {
-                                detailObj := DetailObj{}
-                                json.Unmarshal([]byte(details), &detailObj)
-                                values = append(values, []float64{float64(ts) / 1000, detailObj.Value})
-                        } else {
-                                values = append(values, []float64{float64(ts) / 1000, float64(value)})
-                        }
-                }
-                if err := iter.Close(); err != nil {
+                // Nodes without a uuid have no metrics, but keep their place in the result
+                uuid, _ := hostToUuid.Get(hostName)
+                values, err := reader.nodeValues(metricColumnValue, uuid, startTime, endTime,
+                        detailsValue)
+                if err != nil {
                         return nodeValues, err
                 }
-                sort.Slice(values, func(i, j int) bool {
-                        return values[i][0] < values[j][0]
-                })
                 nodeValues = append(nodeValues, values)
         }
         return nodeValues, nil
@@ -352,16 +341,19 @@ func (c *Container) GetClusterMetric(ctx echo.Context) error {
         metricsParam := strings.Split(ctx.QueryParam("metrics"), ",")
         nodeParam := ctx.QueryParam("node_name")
         nodeList := []string{nodeParam}
-        var err error = nil
+        format, err := getExportFormat(ctx)
+        if err != nil {
+                return respondError(ctx, http.StatusBadRequest, err.Error())
+        }
         if nodeParam == "" {
                 nodeList, err = getNodes()
                 if err != nil {
-                        return ctx.String(http.StatusInternalServerError, err.Error())
+                        return respondWithError(ctx, err)
                 }
         }
-        hostToUuid, err := helpers.GetHostToUuidMap(helpers.HOST)
+        hostToUuid, err := c.hostToUuid.get()
         if err != nil {
-                return ctx.String(http.StatusInternalServerError, err.Error())
+                return respondWithError(ctx, err)
         }
         // in case of errors parsing start/end time, set to defaults of start = 1 hour ago, end = now
         startTime, err := strconv.ParseInt(ctx.QueryParam("start_time"), 10, 64)
@@ -379,22 +371,29 @@ func (c *Container) GetClusterMetric(ctx echo.Context) error {
                 Data:           []models.MetricData{},
                 StartTimestamp: startTime,
                 EndTimestamp:   endTime,
+                Resolution:     METRICS_RESOLUTION_RAW,
         }
 
-        session := c.Session
+        reader, err := c.getMetricsReader(hostToUuid)
+        if err != nil {
+                return respondError(ctx, http.StatusServiceUnavailable, err.Error())
+        }
+        // Wide ranges are charted from the samples rolled up over longer intervals
+        if downsampledReader, resolution, ok := c.getDownsampledMetricsReader(startTime,
+                endTime); ok {
+                reader = downsampledReader
+                metricResponse.Resolution = resolution
+        }
 
+        metricsConfig := helpers.GetConfig().Metrics
         for _, metric := range metricsParam {
-                // Read from the table.
-                var ts int64
-                var value int
-                var details string
                 // need node uuid
                 switch metric {
                 case "READ_OPS_PER_SEC":
-                        rawMetricValues, err := getRawMetricsForAllNodes(READ_COUNT_METRIC, nodeList,
-                                hostToUuid, startTime, endTime, session, false)
+                        rawMetricValues, err := getRawMetricsForAllNodes(metricsConfig.ReadCountMetric,
+                                nodeList, hostToUuid, startTime, endTime, reader, false)
                         if err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                                return respondWithError(ctx, err)
                         }
                         rateMetrics := convertRawMetricsToRates(rawMetricValues)
                         nodeMetricValues := reduceGranularityForAllNodes(startTime, endTime, rateMetrics,
@@ -405,10 +404,10 @@ func (c *Container) GetClusterMetric(ctx echo.Context) error {
                                 Values: metricValues,
                         })
                 case "WRITE_OPS_PER_SEC":
-                        rawMetricValues, err := getRawMetricsForAllNodes(WRITE_COUNT_METRIC, nodeList,
-                                hostToUuid, startTime, endTime, session, false)
+                        rawMetricValues, err := getRawMetricsForAllNodes(metricsConfig.WriteCountMetric,
+                                nodeList, hostToUuid, startTime, endTime, reader, false)
                         if err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                                return respondWithError(ctx, err)
                         }
                         rateMetrics := convertRawMetricsToRates(rawMetricValues)
                         nodeMetricValues := reduceGranularityForAllNodes(startTime, endTime, rateMetrics,
@@ -418,21 +417,42 @@ func (c *Container) GetClusterMetric(ctx echo.Context) error {
                                 Name:   metric,
                                 Values: metricValues,
                         })
+                case "YSQL_READ_OPS_PER_SEC", "YSQL_WRITE_OPS_PER_SEC", "YCQL_READ_OPS_PER_SEC",
+                        "YCQL_WRITE_OPS_PER_SEC":
+                        // Only scraped into the fallback store, yugabyted does not record them
+                        if !metricsConfig.Fallback {
+                                return respondError(ctx, http.StatusServiceUnavailable,
+                                        fmt.Sprintf("%s needs metrics.fallback to be on", metric))
+                        }
+                        localUuid, _ := hostToUuid.Get(helpers.HOST)
+                        rateMetricValues, err := getRawMetricsForAllNodes(API_OPS_METRICS[metric],
+                                nodeList, hostToUuid, startTime, endTime,
+                                fallbackMetricsReader{c.fallbackMetrics.store, localUuid}, false)
+                        if err != nil {
+                                return respondWithError(ctx, err)
+                        }
+                        nodeMetricValues := reduceGranularityForAllNodes(startTime, endTime,
+                                rateMetricValues, GRANULARITY_NUM_INTERVALS, true)
+                        metricValues := calculateCombinedMetric(nodeMetricValues, false)
+                        metricResponse.Data = append(metricResponse.Data, models.MetricData{
+                                Name:   metric,
+                                Values: metricValues,
+                        })
                 case "CPU_USAGE_USER":
-                        metricValues, err := getAveragePercentageMetricData("cpu_usage_user", nodeList,
-                                hostToUuid, startTime, endTime, session, true)
+                        metricValues, err := getAveragePercentageMetricData(metricsConfig.CpuUsageUserMetric,
+                                nodeList, hostToUuid, startTime, endTime, reader, true)
                         if err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                                return respondWithError(ctx, err)
                         }
                         metricResponse.Data = append(metricResponse.Data, models.MetricData{
                                 Name:   metric,
                                 Values: metricValues,
                         })
                 case "CPU_USAGE_SYSTEM":
-                        metricValues, err := getAveragePercentageMetricData("cpu_usage_system", nodeList,
-                                hostToUuid, startTime, endTime, session, true)
+                        metricValues, err := getAveragePercentageMetricData(metricsConfig.CpuUsageSystemMetric,
+                                nodeList, hostToUuid, startTime, endTime, reader, true)
                         if err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                                return respondWithError(ctx, err)
                         }
                         metricResponse.Data = append(metricResponse.Data, models.MetricData{
                                 Name:   metric,
@@ -440,34 +460,18 @@ func (c *Container) GetClusterMetric(ctx echo.Context) error {
                         })
                 case "DISK_USAGE_GB":
                         // For disk usage, we assume every node reports the same metrics
-                        query := fmt.Sprintf(QUERY_FORMAT, "system.metrics", "total_disk", startTime*1000,
-                                endTime*1000)
-                        iter := session.Query(query).Iter()
-                        values := [][]float64{}
-                        for iter.Scan(&ts, &value, &details) {
-                                values = append(values,
-                                        []float64{float64(ts) / 1000, float64(value) / helpers.BYTES_IN_GB})
-                        }
-                        if err := iter.Close(); err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
-                        }
-                        sort.Slice(values, func(i, j int) bool {
-                                return values[i][0] < values[j][0]
-                        })
-                        query = fmt.Sprintf(QUERY_FORMAT, "system.metrics", "free_disk", startTime*1000,
-                                endTime*1000)
-                        iter = session.Query(query).Iter()
-                        freeValues := [][]float64{}
-                        for iter.Scan(&ts, &value, &details) {
-                                freeValues = append(freeValues,
-                                        []float64{float64(ts) / 1000, float64(value) / helpers.BYTES_IN_GB})
+                        values, err := reader.allNodeValues(metricsConfig.TotalDiskMetric, startTime,
+                                endTime)
+                        if err != nil {
+                                return respondWithError(ctx, err)
                         }
-                        if err := iter.Close(); err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                        divideMetricByConstant(values, helpers.BYTES_IN_GB)
+                        freeValues, err := reader.allNodeValues(metricsConfig.FreeDiskMetric, startTime,
+                                endTime)
+                        if err != nil {
+                                return respondWithError(ctx, err)
                         }
-                        sort.Slice(freeValues, func(i, j int) bool {
-                                return freeValues[i][0] < freeValues[j][0]
-                        })
+                        divideMetricByConstant(freeValues, helpers.BYTES_IN_GB)
 
                         // we assume the query results for free and total disk have the same timestamps
                         for index, pair := range freeValues {
@@ -482,36 +486,28 @@ func (c *Container) GetClusterMetric(ctx echo.Context) error {
                                         true),
                         })
                 case "PROVISIONED_DISK_SPACE_GB":
-                        query := fmt.Sprintf(QUERY_FORMAT, "system.metrics", "total_disk", startTime*1000,
-                                endTime*1000)
-                        iter := session.Query(query).Iter()
-                        values := [][]float64{}
-                        for iter.Scan(&ts, &value, &details) {
-                                values = append(values,
-                                        []float64{float64(ts) / 1000, float64(value) / helpers.BYTES_IN_GB})
-                        }
-                        if err := iter.Close(); err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                        values, err := reader.allNodeValues(metricsConfig.TotalDiskMetric, startTime,
+                                endTime)
+                        if err != nil {
+                                return respondWithError(ctx, err)
                         }
-                        sort.Slice(values, func(i, j int) bool {
-                                return values[i][0] < values[j][0]
-                        })
+                        divideMetricByConstant(values, helpers.BYTES_IN_GB)
                         metricResponse.Data = append(metricResponse.Data, models.MetricData{
                                 Name: metric,
                                 Values: reduceGranularity(startTime, endTime, values, GRANULARITY_NUM_INTERVALS,
                                         true),
                         })
                 case "AVERAGE_READ_LATENCY_MS":
-                        rawMetricValuesCount, err := getRawMetricsForAllNodes(READ_COUNT_METRIC, nodeList,
-                                hostToUuid, startTime, endTime, session, false)
+                        rawMetricValuesCount, err := getRawMetricsForAllNodes(metricsConfig.ReadCountMetric,
+                                nodeList, hostToUuid, startTime, endTime, reader, false)
                         if err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                                return respondWithError(ctx, err)
                         }
 
-                        rawMetricValuesSum, err := getRawMetricsForAllNodes(READ_SUM_METRIC, nodeList,
-                                hostToUuid, startTime, endTime, session, false)
+                        rawMetricValuesSum, err := getRawMetricsForAllNodes(metricsConfig.ReadSumMetric,
+                                nodeList, hostToUuid, startTime, endTime, reader, false)
                         if err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                                return respondWithError(ctx, err)
                         }
 
                         rateMetricsCount := convertRawMetricsToRates(rawMetricValuesCount)
@@ -537,16 +533,16 @@ func (c *Container) GetClusterMetric(ctx echo.Context) error {
                                 Values: metricValues,
                         })
                 case "AVERAGE_WRITE_LATENCY_MS":
-                        rawMetricValuesCount, err := getRawMetricsForAllNodes(WRITE_COUNT_METRIC, nodeList,
-                                hostToUuid, startTime, endTime, session, false)
+                        rawMetricValuesCount, err := getRawMetricsForAllNodes(metricsConfig.WriteCountMetric,
+                                nodeList, hostToUuid, startTime, endTime, reader, false)
                         if err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                                return respondWithError(ctx, err)
                         }
 
-                        rawMetricValuesSum, err := getRawMetricsForAllNodes(WRITE_SUM_METRIC, nodeList,
-                                hostToUuid, startTime, endTime, session, false)
+                        rawMetricValuesSum, err := getRawMetricsForAllNodes(metricsConfig.WriteSumMetric,
+                                nodeList, hostToUuid, startTime, endTime, reader, false)
                         if err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                                return respondWithError(ctx, err)
                         }
 
                         rateMetricsCount := convertRawMetricsToRates(rawMetricValuesCount)
@@ -572,10 +568,10 @@ func (c *Container) GetClusterMetric(ctx echo.Context) error {
                                 Values: metricValues,
                         })
                 case "TOTAL_LIVE_NODES":
-                        rawMetricValues, err := getRawMetricsForAllNodes("node_up", nodeList, hostToUuid,
-                                startTime, endTime, session, false)
+                        rawMetricValues, err := getRawMetricsForAllNodes(metricsConfig.NodeUpMetric,
+                                nodeList, hostToUuid, startTime, endTime, reader, false)
                         if err != nil {
-                                return ctx.String(http.StatusInternalServerError, err.Error())
+                                return respondWithError(ctx, err)
                         }
                         reducedMetric := reduceGranularityForAllNodes(startTime, endTime, rawMetricValues,
                                 GRANULARITY_NUM_INTERVALS, true)
@@ -592,9 +588,82 @@ func (c *Container) GetClusterMetric(ctx echo.Context) error {
                         })
                 }
         }
+        if format != EXPORT_FORMAT_JSON {
+                // One row per sample, so that the metrics can be filtered and pivoted as needed
+                stream := newCsvStream(ctx, format, http.StatusOK)
+                stream.row("metric", "timestamp", "value")
+                for _, metricData := range metricResponse.Data {
+                        for _, value := range metricData.Values {
+                                if len(value) < 2 {
+                                        continue
+                                }
+                                stream.row(metricData.Name, formatCsvFloat(value[0]),
+                                        formatCsvFloat(value[1]))
+                        }
+                }
+                return stream.close()
+        }
         return ctx.JSON(http.StatusOK, metricResponse)
 }
 
+// GetReplicationTraffic - Get the bytes replicated between regions or zones
+func (c *Container) GetReplicationTraffic(ctx echo.Context) error {
+        kinds := []string{RAFT_TRAFFIC, XCLUSTER_TRAFFIC}
+        if kind := ctx.QueryParam("kind"); kind != "" {
+                if kind != RAFT_TRAFFIC && kind != XCLUSTER_TRAFFIC {
+                        return respondError(ctx, http.StatusBadRequest,
+                                "kind must be raft or xcluster")
+                }
+                kinds = []string{kind}
+        }
+        level := TRAFFIC_LEVEL_ZONE
+        if ctx.QueryParam("level") != "" {
+                level = ctx.QueryParam("level")
+        }
+        if level != TRAFFIC_LEVEL_REGION && level != TRAFFIC_LEVEL_ZONE {
+                return respondError(ctx, http.StatusBadRequest, "level must be region or zone")
+        }
+        endTime := time.Now().Unix()
+        if ctx.QueryParam("end_time") != "" {
+                parsed, err := strconv.ParseInt(ctx.QueryParam("end_time"), 10, 64)
+                if err != nil {
+                        return respondError(ctx, http.StatusBadRequest,
+                                "end_time must be a unix timestamp")
+                }
+                endTime = parsed
+        }
+        startTime := endTime - 60*60
+        if ctx.QueryParam("start_time") != "" {
+                parsed, err := strconv.ParseInt(ctx.QueryParam("start_time"), 10, 64)
+                if err != nil {
+                        return respondError(ctx, http.StatusBadRequest,
+                                "start_time must be a unix timestamp")
+                }
+                startTime = parsed
+        }
+        if startTime >= endTime {
+                return respondError(ctx, http.StatusBadRequest,
+                        "start_time must be before end_time")
+        }
+        // Only scraped into the fallback store, yugabyted does not record it
+        metricsConfig := helpers.GetConfig().Metrics
+        if !metricsConfig.Fallback {
+                return respondError(ctx, http.StatusServiceUnavailable,
+                        "replication traffic needs metrics.fallback to be on")
+        }
+        response := models.ReplicationTrafficResponse{
+                Data: []models.ReplicationTraffic{},
+                StartTimestamp: startTime,
+                EndTimestamp: endTime,
+        }
+        scrapeIntervalSeconds := metricsConfig.FallbackScrapeInterval.Seconds()
+        for _, kind := range kinds {
+                response.Data = append(response.Data, c.fallbackMetrics.getReplicationTraffic(kind,
+                        level, startTime, endTime, scrapeIntervalSeconds)...)
+        }
+        return ctx.JSON(http.StatusOK, response)
+}
+
 // GetClusterNodes - Get the nodes for a cluster
 func (c *Container) GetClusterNodes(ctx echo.Context) error {
         response := models.ClusterNodesResponse{
@@ -604,18 +673,23 @@ func (c *Container) GetClusterNodes(ctx echo.Context) error {
         go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
         tabletServersResponse := <-tabletServersFuture
         if tabletServersResponse.Error != nil {
-                return ctx.String(http.StatusInternalServerError, tabletServersResponse.Error.Error())
+                return respondWithError(ctx, tabletServersResponse.Error)
         }
         nodeList := helpers.GetNodesList(tabletServersResponse)
+        // The rates of each API are left at 0 for nodes without a uuid
+        hostToUuid, _ := c.hostToUuid.get()
+        fanOut := newFanOutLimiter()
         versionInfoFutures := map[string]chan helpers.VersionInfoFuture{}
+        now := time.Now().Unix()
         for _, nodeHost := range nodeList {
-                versionInfoFuture := make(chan helpers.VersionInfoFuture)
+                nodeHost := nodeHost
+                versionInfoFuture := make(chan helpers.VersionInfoFuture, 1)
                 versionInfoFutures[nodeHost] = versionInfoFuture
-                go helpers.GetVersionFuture(nodeHost, versionInfoFuture)
+                fanOut.goCall(func() { helpers.GetVersionFuture(nodeHost, versionInfoFuture) })
         }
         for _, obj := range tabletServersResponse.Tablets {
                 for hostport, nodeData := range obj {
-                        host, _, err := net.SplitHostPort(hostport)
+                        host, err := helpers.GetHostFromAddress(hostport)
                         // If we can split hostport, just use host as name.
                         // Otherwise, use hostport as name.
                         // However, we can only get version information if we can get the host
@@ -628,6 +702,9 @@ func (c *Container) GetClusterNodes(ctx echo.Context) error {
                                         versionNumber = versionInfo.VersionInfo.VersionNumber
                                 }
                         }
+                        uuid, _ := hostToUuid.Get(hostName)
+                        maintenanceUntil := c.maintenance.coveredUntil(hostName,
+                                getMaintenanceZone(nodeData), now)
                         totalSstFileSizeBytes := int64(nodeData.TotalSstFileSizeBytes)
                         uncompressedSstFileSizeBytes :=
                                 int64(nodeData.UncompressedSstFileSizeBytes)
@@ -644,6 +721,14 @@ func (c *Container) GetClusterNodes(ctx echo.Context) error {
                                         UncompressedSstFileSizeBytes: &uncompressedSstFileSizeBytes,
                                         ReadOpsPerSec:                nodeData.ReadOpsPerSec,
                                         WriteOpsPerSec:               nodeData.WriteOpsPerSec,
+                                        YsqlReadOpsPerSec: c.fallbackMetrics.latestOpsRate(
+                                                YSQL_READ_OPS_METRIC, uuid),
+                                        YsqlWriteOpsPerSec: c.fallbackMetrics.latestOpsRate(
+                                                YSQL_WRITE_OPS_METRIC, uuid),
+                                        YcqlReadOpsPerSec: c.fallbackMetrics.latestOpsRate(
+                                                YCQL_READ_OPS_METRIC, uuid),
+                                        YcqlWriteOpsPerSec: c.fallbackMetrics.latestOpsRate(
+                                                YCQL_WRITE_OPS_METRIC, uuid),
                                 },
                                 CloudInfo: models.NodeDataCloudInfo{
                                         Cloud:  nodeData.Cloud,
@@ -651,6 +736,8 @@ func (c *Container) GetClusterNodes(ctx echo.Context) error {
                                         Zone:   nodeData.Zone,
                                 },
                                 SoftwareVersion: versionNumber,
+                                UnderMaintenance: maintenanceUntil > 0,
+                                MaintenanceUntil: maintenanceUntil,
                         })
                 }
         }
@@ -662,41 +749,31 @@ func (c *Container) GetClusterNodes(ctx echo.Context) error {
 
 // GetClusterTables - Get list of DB tables per YB API (YCQL/YSQL)
 func (c *Container) GetClusterTables(ctx echo.Context) error {
-        tableListResponse := models.ClusterTableListResponse{
-                Data: []models.ClusterTable{},
-        }
         tablesFuture := make(chan helpers.TablesFuture)
         go helpers.GetTablesFuture(helpers.HOST, tablesFuture)
         tablesList := <-tablesFuture
         if tablesList.Error != nil {
-                return ctx.String(http.StatusInternalServerError, tablesList.Error.Error())
+                return respondWithError(ctx, tablesList.Error)
         }
         api := ctx.QueryParam("api")
-        switch api {
-        case "YSQL":
-                for _, table := range tablesList.Tables {
-                        if table.IsYsql {
-                                tableListResponse.Data = append(tableListResponse.Data, models.ClusterTable{
-                                        Name:      table.Name,
-                                        Keyspace:  table.Keyspace,
-                                        Type:      models.YBAPIENUM_YSQL,
-                                        SizeBytes: table.SizeBytes,
-                                })
-                        }
-                }
-        case "YCQL":
+        // The tables are streamed as a ClusterTableListResponse, as there can be very many
+        stream := newJsonStream(ctx, http.StatusOK)
+        stream.open(`{"data":[`)
+        if api == "YSQL" || api == "YCQL" {
                 for _, table := range tablesList.Tables {
-                        if !table.IsYsql {
-                                tableListResponse.Data = append(tableListResponse.Data, models.ClusterTable{
-                                        Name:      table.Name,
-                                        Keyspace:  table.Keyspace,
-                                        Type:      models.YBAPIENUM_YCQL,
-                                        SizeBytes: table.SizeBytes,
-                                })
+                        if table.IsYsql != (api == "YSQL") {
+                                continue
                         }
+                        stream.item(models.ClusterTable{
+                                Uuid:      table.Uuid,
+                                Name:      table.Name,
+                                Keyspace:  table.Keyspace,
+                                Type:      models.YbApiEnum(api),
+                                SizeBytes: table.SizeBytes,
+                        })
                 }
         }
-        return ctx.JSON(http.StatusOK, tableListResponse)
+        return stream.close("]}")
 }
 
 // GetClusterHealthCheck - Get health information about the cluster
@@ -705,13 +782,102 @@ func (c *Container) GetClusterHealthCheck(ctx echo.Context) error {
     go helpers.GetHealthCheckFuture(helpers.HOST, future)
     result := <-future
     if result.Error != nil {
-        return ctx.String(http.StatusInternalServerError, result.Error.Error())
+        return respondWithError(ctx, result.Error)
+    }
+    // Failures of nodes under maintenance are expected, so they are not counted with the others
+    now := time.Now().Unix()
+    deadNodesUnderMaintenance, err := c.getNodesUnderMaintenance(result.HealthCheck.DeadNodes,
+        now)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    deadNodes := []string{}
+    for _, uuid := range result.HealthCheck.DeadNodes {
+        if !containsString(deadNodesUnderMaintenance, uuid) {
+            deadNodes = append(deadNodes, uuid)
+        }
     }
     return ctx.JSON(http.StatusOK, models.HealthCheckResponse{
         Data: models.HealthCheckInfo{
-            DeadNodes: result.HealthCheck.DeadNodes,
+            DeadNodes: deadNodes,
             MostRecentUptime: result.HealthCheck.MostRecentUptime,
             UnderReplicatedTablets: result.HealthCheck.UnderReplicatedTablets,
+            UnderMaintenance: c.maintenance.coversCluster(now),
+            DeadNodesUnderMaintenance: deadNodesUnderMaintenance,
+        },
+    })
+}
+
+// GetApiHealth - Probe the YSQL and YCQL layers of every node
+func (c *Container) GetApiHealth(ctx echo.Context) error {
+    apiHealth, err := c.getApiHealth()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.ApiHealthResponse{
+        Data: apiHealth,
+    })
+}
+
+// GetUptime - Get the availability of the APIs over the last day, week and month
+func (c *Container) GetUptime(ctx echo.Context) error {
+    uptime, err := c.getUptime(time.Now())
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.UptimeResponse{Data: uptime})
+}
+
+// Number of results GetProbes returns by default, and at most
+const PROBE_RESULTS_DEFAULT_LIMIT = 100
+const PROBE_RESULTS_MAX_LIMIT = 1000
+
+// GetProbes - Get the results of the synthetic probes
+func (c *Container) GetProbes(ctx echo.Context) error {
+    to := time.Now().Unix()
+    if ctx.QueryParam("to") != "" {
+        parsed, err := strconv.ParseInt(ctx.QueryParam("to"), 10, 64)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "to must be a unix timestamp")
+        }
+        to = parsed
+    }
+    from := to - 24*60*60
+    if ctx.QueryParam("from") != "" {
+        parsed, err := strconv.ParseInt(ctx.QueryParam("from"), 10, 64)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "from must be a unix timestamp")
+        }
+        from = parsed
+    }
+    if from > to {
+        return respondError(ctx, http.StatusBadRequest, "from must not be after to")
+    }
+    limit := PROBE_RESULTS_DEFAULT_LIMIT
+    if limitParam := ctx.QueryParam("limit"); limitParam != "" {
+        parsed, err := strconv.Atoi(limitParam)
+        if err != nil || parsed < 0 || parsed > PROBE_RESULTS_MAX_LIMIT {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("limit must be between 0 and %d", PROBE_RESULTS_MAX_LIMIT))
+        }
+        limit = parsed
+    }
+    results, err := c.prober.list(from, to, ctx.QueryParam("node"))
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    nodes, overall := getProbeSummaries(results)
+    latest := []models.ProbeResult{}
+    for index := len(results) - 1; index >= 0 && len(latest) < limit; index-- {
+        latest = append(latest, results[index])
+    }
+    return ctx.JSON(http.StatusOK, models.ProbeReportResponse{
+        Data: models.ProbeReport{
+            From: from,
+            To: to,
+            Overall: overall,
+            Nodes: nodes,
+            Results: latest,
         },
     })
 }
@@ -724,8 +890,9 @@ func (c *Container) GetLiveQueries(ctx echo.Context) error {
         }
         nodes, err := getNodes()
         if err != nil {
-                return ctx.String(http.StatusInternalServerError, err.Error())
+                return respondWithError(ctx, err)
         }
+        fanOut := newFanOutLimiter()
         if api == "YSQL" {
                 liveQueryResponse.Data.Ysql = models.LiveQueryResponseYsqlData{
                         ErrorCount: 0,
@@ -734,9 +901,10 @@ func (c *Container) GetLiveQueries(ctx echo.Context) error {
                 // Get live queries of all nodes in parallel
                 futures := []chan helpers.LiveQueriesYsqlFuture{}
                 for _, nodeHost := range nodes {
-                        future := make(chan helpers.LiveQueriesYsqlFuture)
+                        nodeHost := nodeHost
+                        future := make(chan helpers.LiveQueriesYsqlFuture, 1)
                         futures = append(futures, future)
-                        go helpers.GetLiveQueriesYsqlFuture(nodeHost, future)
+                        fanOut.goCall(func() { helpers.GetLiveQueriesYsqlFuture(nodeHost, future) })
                 }
                 for _, future := range futures {
                         items := <-future
@@ -758,9 +926,10 @@ func (c *Container) GetLiveQueries(ctx echo.Context) error {
                 // Get live queries of all nodes in parallel
                 futures := []chan helpers.LiveQueriesYcqlFuture{}
                 for _, nodeHost := range nodes {
-                        future := make(chan helpers.LiveQueriesYcqlFuture)
+                        nodeHost := nodeHost
+                        future := make(chan helpers.LiveQueriesYcqlFuture, 1)
                         futures = append(futures, future)
-                        go helpers.GetLiveQueriesYcqlFuture(nodeHost, future)
+                        fanOut.goCall(func() { helpers.GetLiveQueriesYcqlFuture(nodeHost, future) })
                 }
                 for _, future := range futures {
                         items := <-future
@@ -777,35 +946,29 @@ func (c *Container) GetLiveQueries(ctx echo.Context) error {
         return ctx.JSON(http.StatusOK, liveQueryResponse)
 }
 
-// GetSlowQueries - Get the slow queries in a cluster
-func (c *Container) GetSlowQueries(ctx echo.Context) error {
-        nodes, err := getNodes()
-        if err != nil {
-                return ctx.String(http.StatusInternalServerError, err.Error())
-        }
-        slowQueryResponse := models.SlowQueryResponseSchema{
-                Data: models.SlowQueryResponseData{
-                        Ysql: models.SlowQueryResponseYsqlData{
-                                ErrorCount: 0,
-                                Queries:    []models.SlowQueryResponseYsqlQueryItem{},
-                        },
-                },
-        }
+// Gets the slow queries of the nodes with their stats aggregated over the nodes, by query, and
+// the number of nodes that could not be queried
+func (c *Container) getAggregatedSlowQueries(
+        nodes []string,
+) (map[string]*models.SlowQueryResponseYsqlQueryItem, int32) {
+        errorCount := int32(0)
 
         // for each node, get slow queries and aggregate the stats.
         // do each node in parallel
+        fanOut := newFanOutLimiter()
         futures := []chan SlowQueriesFuture{}
         for _, nodeHost := range nodes {
-                future := make(chan SlowQueriesFuture)
+                nodeHost := nodeHost
+                future := make(chan SlowQueriesFuture, 1)
                 futures = append(futures, future)
-                go getSlowQueriesFuture(nodeHost, c.Conn, future)
+                fanOut.goCall(func() { getSlowQueriesFuture(nodeHost, c.Conn, future) })
         }
         // Keep track of stats for each query so we can aggregrate the states over all nodes
         queryMap := map[string]*models.SlowQueryResponseYsqlQueryItem{}
         for _, future := range futures {
                 items := <-future
                 if items.Error != nil {
-                        slowQueryResponse.Data.Ysql.ErrorCount++
+                        errorCount++
                         continue
                 }
                 for _, item := range items.Items {
@@ -859,34 +1022,474 @@ func (c *Container) GetSlowQueries(ctx echo.Context) error {
                         }
                 }
         }
-        // put queries into slice and return
+        return queryMap, errorCount
+}
+
+// Number of nodes whose slow queries could not be read, for CSV and TSV responses
+const SLOW_QUERIES_ERROR_COUNT_HEADER = "X-Error-Count"
+
+// GetSlowQueries - Get the slow queries in a cluster
+func (c *Container) GetSlowQueries(ctx echo.Context) error {
+        format, err := getExportFormat(ctx)
+        if err != nil {
+                return respondError(ctx, http.StatusBadRequest, err.Error())
+        }
+        nodes, err := getNodes()
+        if err != nil {
+                return respondWithError(ctx, err)
+        }
+        queryMap, errorCount := c.getAggregatedSlowQueries(nodes)
+        if format != EXPORT_FORMAT_JSON {
+                // The rows have no room for the error count, so it is sent as a header
+                ctx.Response().Header().Set(SLOW_QUERIES_ERROR_COUNT_HEADER,
+                        strconv.Itoa(int(errorCount)))
+                stream := newCsvStream(ctx, format, http.StatusOK)
+                stream.row("queryid", "query", "rolname", "datname", "calls", "local_blks_hit",
+                        "local_blks_written", "max_time", "mean_time", "min_time", "rows",
+                        "stddev_time", "total_time")
+                for _, value := range queryMap {
+                        stream.row(strconv.FormatInt(value.Queryid, 10), value.Query, value.Rolname,
+                                value.Datname, strconv.Itoa(int(value.Calls)),
+                                strconv.Itoa(int(value.LocalBlksHit)),
+                                strconv.Itoa(int(value.LocalBlksWritten)),
+                                formatCsvFloat(float64(value.MaxTime)),
+                                formatCsvFloat(float64(value.MeanTime)),
+                                formatCsvFloat(float64(value.MinTime)), strconv.Itoa(int(value.Rows)),
+                                formatCsvFloat(float64(value.StddevTime)),
+                                formatCsvFloat(float64(value.TotalTime)))
+                }
+                return stream.close()
+        }
+        // The queries are streamed as a SlowQueryResponseSchema, as there can be very many
+        stream := newJsonStream(ctx, http.StatusOK)
+        stream.open(fmt.Sprintf(`{"data":{"ysql":{"error_count":%d,"queries":[`, errorCount))
         for _, value := range queryMap {
-                slowQueryResponse.Data.Ysql.Queries = append(slowQueryResponse.Data.Ysql.Queries, *value)
+                stream.item(*value)
         }
-        return ctx.JSON(http.StatusOK, slowQueryResponse)
+        return stream.close("]}}}")
 }
 
-// GetLiveQueries - Get the live queries in a cluster
+// GetClusterTablets - Get list of tablets
 func (c *Container) GetClusterTablets(ctx echo.Context) error {
-    tabletListResponse := models.ClusterTabletListResponse{
-        Data: map[string]models.ClusterTablet{},
+    dumpEntitiesFuture := make(chan helpers.DumpEntitiesFuture)
+    go helpers.GetDumpEntitiesFuture(helpers.HOST, dumpEntitiesFuture)
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    dumpEntities := <-dumpEntitiesFuture
+    tabletServers := <-tabletServersFuture
+    if dumpEntities.Error != nil {
+        return respondWithError(ctx, dumpEntities.Error)
+    }
+    if tabletServers.Error != nil {
+        return respondWithError(ctx, tabletServers.Error)
+    }
+    node := ctx.QueryParam("node")
+    if node != "" {
+        node = helpers.NormalizeHost(node)
+    }
+    nodes := getClusterStateNodes(tabletServers)
+    tablets := getClusterTablets(dumpEntities.Entities, nodes, c.getTabletPartitions(nodes),
+        ctx.QueryParam("table"), node)
+    // Sorted like the keys of a marshalled map, so that the output is stable
+    sort.Slice(tablets, func(i, j int) bool {
+        return tablets[i].TabletId < tablets[j].TabletId
+    })
+    // The tablets are streamed as a ClusterTabletListResponse, as there can be very many
+    stream := newJsonStream(ctx, http.StatusOK)
+    stream.open(`{"data":{`)
+    for _, tablet := range tablets {
+        stream.field(tablet.TabletId, tablet)
+    }
+    return stream.close("}}")
+}
+
+// GetBalanceScore - Get how evenly the load is spread over the nodes and zones
+func (c *Container) GetBalanceScore(ctx echo.Context) error {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return respondWithError(ctx, tabletServersResponse.Error)
+    }
+    return ctx.JSON(http.StatusOK, models.BalanceResponse{
+        Data: getBalanceScore(tabletServersResponse.Tablets),
+    })
+}
+
+// GetTabletLimits - Get the tablet replicas of each node against recommended limits
+func (c *Container) GetTabletLimits(ctx echo.Context) error {
+    fanOut := newFanOutLimiter()
+    tabletServersFuture := make(chan helpers.TabletServersFuture, 1)
+    clusterConfigFuture := make(chan helpers.ClusterConfigFuture, 1)
+    tablesFuture := make(chan helpers.TablesFuture, 1)
+    fanOut.goCall(func() { helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture) })
+    fanOut.goCall(func() { helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture) })
+    fanOut.goCall(func() { helpers.GetTablesFuture(helpers.HOST, tablesFuture) })
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return respondWithError(ctx, tabletServersResponse.Error)
+    }
+    tabletServers := map[string]helpers.TabletServer{}
+    nodeExporterFutures := map[string]chan helpers.NodeExporterMetricsFuture{}
+    nodeExporterPort := int32(helpers.GetConfig().Upstream.NodeExporterPort)
+    for _, cluster := range tabletServersResponse.Tablets {
+        for address, tabletServer := range cluster {
+            host, err := helpers.GetHostFromAddress(address)
+            if err != nil {
+                host = address
+            }
+            tabletServers[host] = tabletServer
+            nodeExporterFuture := make(chan helpers.NodeExporterMetricsFuture, 1)
+            nodeExporterFutures[host] = nodeExporterFuture
+            fanOut.goCall(func() {
+                helpers.GetNodeExporterMetricsFuture(host, nodeExporterPort, nodeExporterFuture)
+            })
+        }
+    }
+    clusterConfig := <-clusterConfigFuture
+    if clusterConfig.Error != nil {
+        return respondWithError(ctx, clusterConfig.Error)
     }
-    tabletsFuture := make(chan helpers.TabletsFuture)
-    go helpers.GetTabletsFuture(helpers.HOST, tabletsFuture)
-    tabletsList := <-tabletsFuture
-    if tabletsList.Error != nil {
-        return ctx.String(http.StatusInternalServerError, tabletsList.Error.Error())
+    tablesResponse := <-tablesFuture
+    if tablesResponse.Error != nil {
+        return respondWithError(ctx, tablesResponse.Error)
     }
-    for tabletId, tabletInfo := range tabletsList.Tablets {
-        tabletListResponse.Data[tabletId] = models.ClusterTablet{
-            Namespace: tabletInfo.Namespace,
-            TableName: tabletInfo.TableName,
-            TableUuid: tabletInfo.TableUuid,
-            TabletId: tabletId,
-            HasLeader: tabletInfo.HasLeader,
+    replicationFactor := clusterConfig.ClusterConfig.ReplicationInfo.LiveReplicas.NumReplicas
+    // The cluster config only has a replication factor when it was set explicitly
+    if replicationFactor == 0 {
+        replicationFactor = int(math.Min(3, float64(len(tabletServers))))
+    }
+    // Nodes whose node_exporter cannot be reached are reported without a limit
+    resources := map[string]nodeResources{}
+    for host, nodeExporterFuture := range nodeExporterFutures {
+        if nodeExporterMetrics := <-nodeExporterFuture; nodeExporterMetrics.Error == nil {
+            resources[host] = getNodeResources(nodeExporterMetrics.Metrics)
         }
     }
-    return ctx.JSON(http.StatusOK, tabletListResponse)
+    return ctx.JSON(http.StatusOK, models.TabletLimitsResponse{
+        Data: getTabletLimits(tabletServers, resources, replicationFactor,
+            len(tablesResponse.Tables)),
+    })
+}
+
+// SimulateFailure - Get the expected impact of a node, zone or region failure
+func (c *Container) SimulateFailure(ctx echo.Context) error {
+    failureSpec := models.WhatIfFailureSpec{}
+    if err := ctx.Bind(&failureSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    fanOut := newFanOutLimiter()
+    tabletServersFuture := make(chan helpers.TabletServersFuture, 1)
+    mastersFuture := make(chan helpers.MastersFuture, 1)
+    dumpEntitiesFuture := make(chan helpers.DumpEntitiesFuture, 1)
+    fanOut.goCall(func() { helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture) })
+    fanOut.goCall(func() { helpers.GetMastersFuture(helpers.HOST, mastersFuture) })
+    fanOut.goCall(func() { helpers.GetDumpEntitiesFuture(helpers.HOST, dumpEntitiesFuture) })
+    tabletServersResponse := <-tabletServersFuture
+    masters := <-mastersFuture
+    dumpEntities := <-dumpEntitiesFuture
+    for _, err := range []error{tabletServersResponse.Error, masters.Error, dumpEntities.Error} {
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+    }
+    nodes := getClusterStateNodes(tabletServersResponse)
+    if err := validateWhatIfFailureSpec(failureSpec, nodes); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    tabletServers := map[string]helpers.TabletServer{}
+    for _, cluster := range tabletServersResponse.Tablets {
+        for address, tabletServer := range cluster {
+            if host, err := helpers.GetHostFromAddress(address); err == nil {
+                tabletServers[host] = tabletServer
+            }
+        }
+    }
+    tablets := getClusterTablets(dumpEntities.Entities, nodes, map[string]string{}, "", "")
+    return ctx.JSON(http.StatusOK, models.WhatIfFailureResponse{
+        Data: getWhatIfFailure(failureSpec, nodes, tabletServers, masters.Masters, tablets),
+    })
+}
+
+// GetAlerts - Get the alerts raised on the nodes
+func (c *Container) GetAlerts(ctx echo.Context) error {
+    includeSuppressed := false
+    if value := ctx.QueryParam("include_suppressed"); value != "" {
+        var err error
+        if includeSuppressed, err = strconv.ParseBool(value); err != nil {
+            return respondError(ctx, http.StatusBadRequest,
+                "include_suppressed must be true or false")
+        }
+    }
+    return ctx.JSON(http.StatusOK, models.AlertListResponse{
+        Data: c.alerts.list(c.maintenance, includeSuppressed),
+    })
+}
+
+// GetAlertHistory - Get the history of the alerts
+func (c *Container) GetAlertHistory(ctx echo.Context) error {
+    to := time.Now().Unix()
+    if ctx.QueryParam("to") != "" {
+        parsed, err := strconv.ParseInt(ctx.QueryParam("to"), 10, 64)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "to must be a unix timestamp")
+        }
+        to = parsed
+    }
+    from := to - 24*60*60
+    if ctx.QueryParam("from") != "" {
+        parsed, err := strconv.ParseInt(ctx.QueryParam("from"), 10, 64)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "from must be a unix timestamp")
+        }
+        from = parsed
+    }
+    if from > to {
+        return respondError(ctx, http.StatusBadRequest, "from must not be after to")
+    }
+    severity := ctx.QueryParam("severity")
+    if severity != "" && !containsString(ALERT_SEVERITIES, severity) {
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf(
+            "severity must be one of %s, got %q", strings.Join(ALERT_SEVERITIES, ", "), severity))
+    }
+    history, err := c.alerts.history(from, to, severity, ctx.QueryParam("rule_id"))
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.AlertHistoryResponse{
+        Data: history,
+    })
+}
+
+// GetAlertRules - Get list of alert rules
+func (c *Container) GetAlertRules(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, models.AlertRuleListResponse{
+        Data: c.alertRules.list(),
+    })
+}
+
+// CreateAlertRule - Create an alert rule
+func (c *Container) CreateAlertRule(ctx echo.Context) error {
+    ruleSpec := models.AlertRuleSpec{}
+    if err := ctx.Bind(&ruleSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if err := validateAlertRuleSpec(ruleSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    updatedBy := ""
+    if existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session); ok {
+        updatedBy = existing.username
+    }
+    rule, err := c.alertRules.create(ruleSpec, updatedBy)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.alerts.trigger()
+    c.auditLog(ctx, "create_alert_rule", "rule_id", rule.Id, "metric", rule.Metric,
+        "severity", rule.Severity, "threshold", rule.Threshold, "enabled", rule.Enabled)
+    return ctx.JSON(http.StatusOK, models.AlertRuleResponse{
+        Data: rule,
+    })
+}
+
+// UpdateAlertRule - Change an alert rule
+func (c *Container) UpdateAlertRule(ctx echo.Context) error {
+    id := ctx.Param("id")
+    ruleSpec := models.AlertRuleSpec{}
+    if err := ctx.Bind(&ruleSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if err := validateAlertRuleSpec(ruleSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    updatedBy := ""
+    if existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session); ok {
+        updatedBy = existing.username
+    }
+    rule, ok, err := c.alertRules.update(id, ruleSpec, updatedBy)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("alert rule %s not found", id))
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.alerts.trigger()
+    c.auditLog(ctx, "update_alert_rule", "rule_id", id, "metric", rule.Metric,
+        "severity", rule.Severity, "threshold", rule.Threshold, "enabled", rule.Enabled)
+    return ctx.JSON(http.StatusOK, models.AlertRuleResponse{
+        Data: rule,
+    })
+}
+
+// DeleteAlertRule - Delete an alert rule
+func (c *Container) DeleteAlertRule(ctx echo.Context) error {
+    id := ctx.Param("id")
+    ok, err := c.alertRules.delete(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("alert rule %s not found", id))
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.alerts.trigger()
+    c.auditLog(ctx, "delete_alert_rule", "rule_id", id)
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// GetMaintenanceWindows - Get list of maintenance windows
+func (c *Container) GetMaintenanceWindows(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, models.MaintenanceWindowListResponse{
+        Data: c.maintenance.list(),
+    })
+}
+
+// CreateMaintenanceWindow - Schedule a maintenance window
+func (c *Container) CreateMaintenanceWindow(ctx echo.Context) error {
+    windowSpec := models.MaintenanceWindowSpec{}
+    if err := ctx.Bind(&windowSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if err := validateMaintenanceWindowSpec(windowSpec, time.Now().Unix()); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    createdBy := ""
+    if existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session); ok {
+        createdBy = existing.username
+    }
+    window, err := c.maintenance.create(windowSpec, createdBy)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "create_maintenance_window", "window_id", window.Id, "scope", window.Scope,
+        "target", window.Target, "start_time", window.StartTime, "end_time", window.EndTime)
+    return ctx.JSON(http.StatusOK, models.MaintenanceWindowResponse{
+        Data: window,
+    })
+}
+
+// DeleteMaintenanceWindow - Cancel a maintenance window
+func (c *Container) DeleteMaintenanceWindow(ctx echo.Context) error {
+    id := ctx.Param("id")
+    ok, err := c.maintenance.delete(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("maintenance window %s not found", id))
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "delete_maintenance_window", "window_id", id)
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// GetCompactionSchedules - Get list of compaction schedules
+func (c *Container) GetCompactionSchedules(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, models.CompactionScheduleListResponse{
+        Data: c.compactionSchedules.list(),
+    })
+}
+
+// CreateCompactionSchedule - Create a compaction schedule
+func (c *Container) CreateCompactionSchedule(ctx echo.Context) error {
+    scheduleSpec := models.CompactionScheduleSpec{}
+    if err := ctx.Bind(&scheduleSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if err := validateCompactionScheduleSpec(scheduleSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    createdBy := ""
+    if existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session); ok {
+        createdBy = existing.username
+    }
+    schedule, err := c.compactionSchedules.create(scheduleSpec, createdBy)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "create_compaction_schedule", "schedule_id", schedule.Id,
+        "start_time", schedule.StartTime, "duration_minutes", schedule.DurationMinutes,
+        "tables", len(schedule.TableIds), "nodes", len(schedule.Nodes))
+    return ctx.JSON(http.StatusOK, models.CompactionScheduleResponse{
+        Data: schedule,
+    })
+}
+
+// UpdateCompactionSchedule - Change a compaction schedule
+func (c *Container) UpdateCompactionSchedule(ctx echo.Context) error {
+    id := ctx.Param("id")
+    scheduleSpec := models.CompactionScheduleSpec{}
+    if err := ctx.Bind(&scheduleSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if err := validateCompactionScheduleSpec(scheduleSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    schedule, ok, err := c.compactionSchedules.update(id, scheduleSpec)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("compaction schedule %s not found", id))
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "update_compaction_schedule", "schedule_id", id,
+        "start_time", schedule.StartTime, "duration_minutes", schedule.DurationMinutes,
+        "tables", len(schedule.TableIds), "nodes", len(schedule.Nodes),
+        "enabled", schedule.Enabled)
+    return ctx.JSON(http.StatusOK, models.CompactionScheduleResponse{
+        Data: schedule,
+    })
+}
+
+// DeleteCompactionSchedule - Delete a compaction schedule
+func (c *Container) DeleteCompactionSchedule(ctx echo.Context) error {
+    id := ctx.Param("id")
+    ok, err := c.compactionSchedules.delete(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("compaction schedule %s not found", id))
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "delete_compaction_schedule", "schedule_id", id)
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// GetCompactionRuns - Get the runs and skipped windows of a compaction schedule
+func (c *Container) GetCompactionRuns(ctx echo.Context) error {
+    id := ctx.Param("id")
+    runs, ok := c.compactionSchedules.listRuns(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("compaction schedule %s not found", id))
+    }
+    return ctx.JSON(http.StatusOK, models.CompactionRunListResponse{
+        Data: runs,
+    })
+}
+
+// GetMetricsRetention - Get the retention of the metrics and the space they take
+func (c *Container) GetMetricsRetention(ctx echo.Context) error {
+    retention, err := c.getMetricsRetention()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.MetricsRetentionResponse{
+        Data: retention,
+    })
+}
+
+// CleanupMetrics - Delete the samples of the metrics table older than the retention now
+func (c *Container) CleanupMetrics(ctx echo.Context) error {
+    if helpers.GetConfig().Metrics.Retention <= 0 {
+        return respondError(ctx, http.StatusBadRequest,
+            "metrics.retention is 0, samples of the metrics table are kept")
+    }
+    c.metricsCleaner.trigger()
+    c.auditLog(ctx, "cleanup_metrics")
+    return ctx.NoContent(http.StatusAccepted)
 }
 
 // GetVersion - Get YugabyteDB version
@@ -897,18 +1500,148 @@ func (c *Container) GetVersion(ctx echo.Context) error {
     // Get response from tabletServersFuture
     tabletServersResponse := <-tabletServersFuture
     if tabletServersResponse.Error != nil {
-            return ctx.String(http.StatusInternalServerError,
-                    tabletServersResponse.Error.Error())
+            return respondWithError(ctx, tabletServersResponse.Error)
     }
     nodeList := helpers.GetNodesList(tabletServersResponse)
+    fanOut := newFanOutLimiter()
     versionInfoFutures := []chan helpers.VersionInfoFuture{}
     for _, nodeHost := range nodeList {
-        versionInfoFuture := make(chan helpers.VersionInfoFuture)
+        nodeHost := nodeHost
+        versionInfoFuture := make(chan helpers.VersionInfoFuture, 1)
         versionInfoFutures = append(versionInfoFutures, versionInfoFuture)
-        go helpers.GetVersionFuture(nodeHost, versionInfoFuture)
+        fanOut.goCall(func() { helpers.GetVersionFuture(nodeHost, versionInfoFuture) })
     }
     smallestVersion := helpers.GetSmallestVersion(versionInfoFutures)
     return ctx.JSON(http.StatusOK, models.VersionInfo{
         Version: smallestVersion,
     })
 }
+
+// GetVersionCheck - Check the versions of the nodes against the release manifest
+func (c *Container) GetVersionCheck(ctx echo.Context) error {
+    versions, err := getNodeVersions()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    manifest, source, fetchedAt := c.releaseManifests.get()
+    versionCheck := models.VersionCheck{
+        Versions: versions,
+        Findings: getVersionFindings(manifest, versions, time.Now()),
+        ManifestSource: source,
+    }
+    if !fetchedAt.IsZero() {
+        manifestFetchedAt := fetchedAt.Unix()
+        versionCheck.ManifestFetchedAt = &manifestFetchedAt
+    }
+    return ctx.JSON(http.StatusOK, models.VersionCheckResponse{
+        Data: versionCheck,
+    })
+}
+
+// GetTopologyServers - Get the YSQL servers of the cluster for topology-aware load balancing
+func (c *Container) GetTopologyServers(ctx echo.Context) error {
+    serverListResponse := models.TopologyServerListResponse{
+        Data: []models.TopologyServer{},
+    }
+    rows, err := c.Conn.Query(context.Background(), YB_SERVERS_SQL)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer rows.Close()
+    for rows.Next() {
+        server := models.TopologyServer{}
+        err := rows.Scan(&server.Host, &server.Port, &server.NumConnections, &server.NodeType,
+            &server.Cloud, &server.Region, &server.Zone, &server.PublicIp, &server.Uuid)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        serverListResponse.Data = append(serverListResponse.Data, server)
+    }
+    if err := rows.Err(); err != nil {
+        return respondWithError(ctx, err)
+    }
+    sort.Slice(serverListResponse.Data, func(i, j int) bool {
+        return serverListResponse.Data[i].Host < serverListResponse.Data[j].Host
+    })
+    return ctx.JSON(http.StatusOK, serverListResponse)
+}
+
+// GetMasterDetails - Get the flags and metrics of every master
+func (c *Container) GetMasterDetails(ctx echo.Context) error {
+    mastersFuture := make(chan helpers.MastersFuture)
+    go helpers.GetMastersFuture(helpers.HOST, mastersFuture)
+    masters := <-mastersFuture
+    if masters.Error != nil {
+        return respondWithError(ctx, masters.Error)
+    }
+    // The flags are listed without their impact if the master binary is not on this host
+    var flagDocs map[string]helpers.GFlagDoc
+    if entry, err := c.gflagDocs.getForCluster(GFLAG_SERVER_TYPE_MASTER); err == nil {
+        flagDocs = entry.docs
+    } else {
+        c.logger.Debugf("failed to get the documentation of the master flags: %s", err.Error())
+    }
+    return ctx.JSON(http.StatusOK, models.MasterDetailsResponse{
+        Data: getMasterDetails(masters.Masters, flagDocs),
+    })
+}
+
+// GetRootCertificate - Get the root certificate of the cluster
+func (c *Container) GetRootCertificate(ctx echo.Context) error {
+    if !helpers.Secure {
+        return respondError(ctx, http.StatusBadRequest, "the cluster is not secure")
+    }
+    rootCert, err := helpers.ReadRootCertPem()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.CertificateBundleResponse{
+        Data: models.CertificateBundle{
+            RootCert: rootCert,
+        },
+    })
+}
+
+// CreateClientCertificate - Generate a client certificate
+func (c *Container) CreateClientCertificate(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.CertificateGeneration {
+        return respondError(ctx, http.StatusForbidden, "certificate generation is disabled")
+    }
+    if !helpers.Secure {
+        return respondError(ctx, http.StatusBadRequest, "the cluster is not secure")
+    }
+    certSpec := models.ClientCertificateSpec{}
+    if err := ctx.Bind(&certSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if certSpec.Username == "" {
+        return respondError(ctx, http.StatusBadRequest, "username must not be empty")
+    }
+    if certSpec.ValidityDays == 0 {
+        certSpec.ValidityDays = DEFAULT_CLIENT_CERT_VALIDITY_DAYS
+    }
+    if certSpec.ValidityDays < 0 || certSpec.ValidityDays > MAX_CLIENT_CERT_VALIDITY_DAYS {
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf(
+            "validity_days must be between 1 and %d", MAX_CLIENT_CERT_VALIDITY_DAYS))
+    }
+    // Signing client certs needs the root key, which is only given to the server on purpose
+    ca, err := helpers.LoadCertificateAuthority()
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest,
+            "client certificates cannot be generated: "+err.Error())
+    }
+    clientCert, clientKey, err := helpers.GenerateClientCert(ca, certSpec.Username,
+        time.Duration(certSpec.ValidityDays)*24*time.Hour)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "create_client_certificate", "username", certSpec.Username,
+        "validity_days", certSpec.ValidityDays)
+    return ctx.JSON(http.StatusOK, models.CertificateBundleResponse{
+        Data: models.CertificateBundle{
+            RootCert:   ca.CertPem,
+            ClientCert: &clientCert,
+            ClientKey:  &clientKey,
+        },
+    })
+}
@@ -0,0 +1,2112 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "apiserver/cmd/server/tasks"
+    "context"
+    "encoding/hex"
+    "fmt"
+    "io/ioutil"
+    "mime"
+    "net/http"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/jackc/pgx/v4"
+    "github.com/labstack/echo/v4"
+    "github.com/yugabyte/gocql"
+    "golang.org/x/net/websocket"
+)
+
+const YSQL_DATABASES_SQL string = "SELECT datname FROM pg_database " +
+    "WHERE datistemplate = false AND datname != 'system_platform'"
+
+const YSQL_DATABASE_EXISTS_SQL string = "SELECT EXISTS(SELECT 1 FROM pg_database " +
+    "WHERE datname = $1 AND datistemplate = false)"
+
+const YCQL_KEYSPACES_CQL string = "SELECT keyspace_name FROM system_schema.keyspaces"
+
+const YCQL_KEYSPACE_EXISTS_CQL string = "SELECT keyspace_name FROM system_schema.keyspaces " +
+    "WHERE keyspace_name = ?"
+
+// YSQL extensions that are supported by YugabyteDB and can be installed from the UI
+var INSTALLABLE_EXTENSIONS = map[string]bool{
+    "cube":               true,
+    "earthdistance":      true,
+    "fuzzystrmatch":      true,
+    "hstore":             true,
+    "pg_hint_plan":       true,
+    "pg_stat_statements": true,
+    "pg_trgm":            true,
+    "pgcrypto":           true,
+    "postgres_fdw":       true,
+    "sslinfo":            true,
+    "tablefunc":          true,
+    "uuid-ossp":          true,
+}
+
+// Predefined roles created by initdb are left out of the role listing
+const YSQL_ROLES_SELECT_SQL string = "SELECT r.rolname, r.rolsuper, r.rolcanlogin, " +
+    "r.rolcreaterole, r.rolcreatedb, r.rolconnlimit, ARRAY(SELECT b.rolname " +
+    "FROM pg_auth_members m JOIN pg_roles b ON m.roleid = b.oid WHERE m.member = r.oid) " +
+    "FROM pg_roles r"
+
+// Leaves out the roles that are created by the system
+const YSQL_USER_ROLES_SQL string = "r.rolname !~ '^pg_' " +
+    "AND r.rolname NOT IN ('yb_extension', 'yb_fdw', 'yb_db_admin')"
+
+const YSQL_ROLES_SQL string = YSQL_ROLES_SELECT_SQL + " WHERE " + YSQL_USER_ROLES_SQL
+
+const YCQL_ROLES_CQL string = "SELECT role, is_superuser, can_login, member_of " +
+    "FROM system_auth.roles"
+
+const YSQL_ROLE_FILTER_SQL string = " AND r.rolname = $1"
+
+const YCQL_ROLE_FILTER_CQL string = " WHERE role = ?"
+
+// The role name and password are quoted by the server rather than by string concatenation
+const YSQL_ALTER_ROLE_PASSWORD_SQL string = "SELECT format('ALTER ROLE %I WITH PASSWORD %L', " +
+    "$1::text, $2::text)"
+
+const YSQL_EXPIRE_PASSWORD_SQL string = " VALID UNTIL 'now'"
+
+const YSQL_EXTENSIONS_SQL string = "SELECT name, default_version, installed_version, " +
+    "COALESCE(comment, '') FROM pg_available_extensions"
+
+const YSQL_EXTENSION_FILTER_SQL string = " WHERE name = $1"
+
+const YSQL_SEQUENCES_SQL string = "SELECT schemaname, sequencename, sequenceowner, " +
+    "data_type::text, start_value, min_value, max_value, increment_by, cycle, cache_size, " +
+    "last_value FROM pg_sequences"
+
+const YSQL_TABLE_INFO_SQL string = "SELECT c.relkind::text, quote_ident(n.nspname) || '.' || " +
+    "quote_ident(c.relname), COALESCE(array_to_string(c.reloptions, ', '), '') FROM pg_class c " +
+    "JOIN pg_namespace n ON n.oid = c.relnamespace WHERE c.oid = $1"
+
+const YSQL_TABLE_COLUMNS_SQL string = "SELECT quote_ident(a.attname), " +
+    "format_type(a.atttypid, a.atttypmod), a.attnotnull, " +
+    "COALESCE(pg_get_expr(d.adbin, d.adrelid), '') FROM pg_attribute a LEFT JOIN pg_attrdef d " +
+    "ON d.adrelid = a.attrelid AND d.adnum = a.attnum " +
+    "WHERE a.attrelid = $1 AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum"
+
+const YSQL_TABLE_CONSTRAINTS_SQL string = "SELECT quote_ident(conname), " +
+    "pg_get_constraintdef(oid) FROM pg_constraint WHERE conrelid = $1 " +
+    "ORDER BY CASE contype WHEN 'p' THEN 0 ELSE 1 END, conname"
+
+// Indexes that back a constraint are created by the constraint itself
+const YSQL_TABLE_INDEXES_SQL string = "SELECT indexrelid FROM pg_index WHERE indrelid = $1 " +
+    "AND indexrelid NOT IN (SELECT conindid FROM pg_constraint WHERE conrelid = $1) " +
+    "ORDER BY indexrelid"
+
+const YSQL_INDEX_DEF_SQL string = "SELECT pg_get_indexdef($1)"
+
+const YSQL_TABLE_PROPERTIES_SQL string = "SELECT p.num_tablets, p.num_hash_key_columns, " +
+    "p.is_colocated, COALESCE(g.grpname, '') FROM yb_table_properties($1) p " +
+    "LEFT JOIN pg_yb_tablegroup g ON g.oid = p.tablegroup_oid"
+
+const YSQL_RANGE_SPLIT_CLAUSE_SQL string = "SELECT yb_get_range_split_clause($1)::text"
+
+const YCQL_TABLE_PROPERTIES_CQL string = "SELECT default_time_to_live, tablets, transactions " +
+    "FROM system_schema.tables WHERE keyspace_name = ? AND table_name = ?"
+
+const YCQL_TABLE_COLUMNS_CQL string = "SELECT column_name, kind, position, type, " +
+    "clustering_order FROM system_schema.columns WHERE keyspace_name = ? AND table_name = ?"
+
+const YSQL_TABLE_COLUMN_NAMES_SQL string = "SELECT attname FROM pg_attribute " +
+    "WHERE attrelid = $1 AND attnum > 0 AND NOT attisdropped ORDER BY attnum"
+
+const YCQL_TABLE_COLUMN_TYPES_CQL string = "SELECT column_name, type FROM system_schema.columns " +
+    "WHERE keyspace_name = ? AND table_name = ?"
+
+// Name of the cursor that YSQL table exports are read through
+const YSQL_EXPORT_CURSOR = "table_export"
+
+const YCQL_INDEXES_CQL string = "SELECT index_name, table_name, options, is_unique, tablets, " +
+    "transactions FROM system_schema.indexes WHERE keyspace_name = ?"
+
+const YSQL_GRANTS_SQL string = "SELECT grantor, grantee, table_schema, table_name, " +
+    "privilege_type, is_grantable FROM information_schema.role_table_grants " +
+    "WHERE table_schema NOT IN ('pg_catalog', 'information_schema')"
+
+const YCQL_ROLE_MEMBERSHIP_CQL string = "SELECT role, member_of FROM system_auth.roles"
+
+const YCQL_GRANTS_CQL string = "SELECT role, resource, permissions " +
+    "FROM system_auth.role_permissions"
+
+// YCQL keyspaces that are created by the system and should not be shown to users
+var SYSTEM_KEYSPACES = map[string]bool{
+    "system":        true,
+    "system_schema": true,
+    "system_auth":   true,
+}
+
+// GetClusterNamespaces - Get list of YSQL databases and YCQL keyspaces
+func (c *Container) GetClusterNamespaces(ctx echo.Context) error {
+    namespaceListResponse := models.ClusterNamespaceListResponse{
+        Data: []models.ClusterNamespace{},
+    }
+    tablesFuture := make(chan helpers.TablesFuture)
+    go helpers.GetTablesFuture(helpers.HOST, tablesFuture)
+
+    api := ctx.QueryParam("api")
+    // Namespaces without any tables do not show up in the master's /tables page, so we get
+    // the full list of namespaces from the catalog.
+    ysqlNamespaces := map[string]*models.ClusterNamespace{}
+    if api == "" || api == "YSQL" {
+        rows, err := c.Conn.Query(context.Background(), YSQL_DATABASES_SQL)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        for rows.Next() {
+            var name string
+            if err := rows.Scan(&name); err != nil {
+                rows.Close()
+                return respondWithError(ctx, err)
+            }
+            ysqlNamespaces[name] = &models.ClusterNamespace{
+                Name: name,
+                Type: models.YBAPIENUM_YSQL,
+            }
+        }
+        rows.Close()
+        if err := rows.Err(); err != nil {
+            return respondWithError(ctx, err)
+        }
+    }
+    ycqlNamespaces := map[string]*models.ClusterNamespace{}
+    if api == "" || api == "YCQL" {
+        session, err := c.getYcqlSession()
+        if err != nil {
+            return respondError(ctx, http.StatusServiceUnavailable, err.Error())
+        }
+        iter := session.Query(YCQL_KEYSPACES_CQL).Iter()
+        var name string
+        for iter.Scan(&name) {
+            if !SYSTEM_KEYSPACES[name] {
+                ycqlNamespaces[name] = &models.ClusterNamespace{
+                    Name: name,
+                    Type: models.YBAPIENUM_YCQL,
+                }
+            }
+        }
+        if err := iter.Close(); err != nil {
+            return respondWithError(ctx, err)
+        }
+    }
+
+    tablesList := <-tablesFuture
+    if tablesList.Error != nil {
+        return respondWithError(ctx, tablesList.Error)
+    }
+    for _, table := range tablesList.Tables {
+        namespaces := ycqlNamespaces
+        if table.IsYsql {
+            namespaces = ysqlNamespaces
+        }
+        if namespace, ok := namespaces[table.Keyspace]; ok {
+            namespace.NumTables++
+            namespace.SizeBytes += table.SizeBytes
+        }
+    }
+    for name, namespace := range ysqlNamespaces {
+        namespace.IsColocated = tablesList.ColocatedKeyspaces[name]
+        namespaceListResponse.Data = append(namespaceListResponse.Data, *namespace)
+    }
+    for _, namespace := range ycqlNamespaces {
+        namespaceListResponse.Data = append(namespaceListResponse.Data, *namespace)
+    }
+    sort.Slice(namespaceListResponse.Data, func(i, j int) bool {
+        if namespaceListResponse.Data[i].Type != namespaceListResponse.Data[j].Type {
+            return namespaceListResponse.Data[i].Type > namespaceListResponse.Data[j].Type
+        }
+        return namespaceListResponse.Data[i].Name < namespaceListResponse.Data[j].Name
+    })
+    return ctx.JSON(http.StatusOK, namespaceListResponse)
+}
+
+// Scans a row of YSQL_ROLES_SQL into a role
+func scanYsqlRole(row pgx.Row) (models.DatabaseRole, error) {
+    role := models.DatabaseRole{
+        Type: models.YBAPIENUM_YSQL,
+    }
+    err := row.Scan(&role.Name, &role.IsSuperuser, &role.CanLogin, &role.CanCreateRole,
+        &role.CanCreateDb, &role.ConnectionLimit, &role.MemberOf)
+    return role, err
+}
+
+// Fills in the attributes of a role scanned from YCQL_ROLES_CQL
+func completeYcqlRole(role models.DatabaseRole) models.DatabaseRole {
+    // YCQL roles have no connection limit or create privileges attributes
+    role.Type = models.YBAPIENUM_YCQL
+    role.ConnectionLimit = -1
+    if role.MemberOf == nil {
+        role.MemberOf = []string{}
+    }
+    return role
+}
+
+// GetClusterUsers - Get list of YSQL and YCQL roles
+func (c *Container) GetClusterUsers(ctx echo.Context) error {
+    roleListResponse := models.DatabaseRoleListResponse{
+        Data: []models.DatabaseRole{},
+    }
+    api := ctx.QueryParam("api")
+    if api == "" || api == "YSQL" {
+        rows, err := c.Conn.Query(context.Background(), YSQL_ROLES_SQL)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        for rows.Next() {
+            role, err := scanYsqlRole(rows)
+            if err != nil {
+                rows.Close()
+                return respondWithError(ctx, err)
+            }
+            roleListResponse.Data = append(roleListResponse.Data, role)
+        }
+        rows.Close()
+        if err := rows.Err(); err != nil {
+            return respondWithError(ctx, err)
+        }
+    }
+    if api == "" || api == "YCQL" {
+        session, err := c.getYcqlSession()
+        if err != nil {
+            return respondError(ctx, http.StatusServiceUnavailable, err.Error())
+        }
+        iter := session.Query(YCQL_ROLES_CQL).Iter()
+        role := models.DatabaseRole{}
+        for iter.Scan(&role.Name, &role.IsSuperuser, &role.CanLogin, &role.MemberOf) {
+            roleListResponse.Data = append(roleListResponse.Data, completeYcqlRole(role))
+            role = models.DatabaseRole{}
+        }
+        if err := iter.Close(); err != nil {
+            return respondWithError(ctx, err)
+        }
+    }
+    sort.Slice(roleListResponse.Data, func(i, j int) bool {
+        if roleListResponse.Data[i].Type != roleListResponse.Data[j].Type {
+            return roleListResponse.Data[i].Type > roleListResponse.Data[j].Type
+        }
+        return roleListResponse.Data[i].Name < roleListResponse.Data[j].Name
+    })
+    return ctx.JSON(http.StatusOK, roleListResponse)
+}
+
+// Checks that a YSQL database exists, returning a not found error if it does not
+func (c *Container) checkYsqlDatabase(dbName string) error {
+    var exists bool
+    err := c.Conn.QueryRow(context.Background(), YSQL_DATABASE_EXISTS_SQL, dbName).Scan(&exists)
+    if err != nil {
+        return err
+    }
+    if !exists {
+        return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("database %s not found", dbName))
+    }
+    return nil
+}
+
+// Checks that a YCQL keyspace exists, returning a not found error if it does not
+func (c *Container) checkYcqlKeyspace(keyspace string) error {
+    session, err := c.getYcqlSession()
+    if err != nil {
+        return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+    }
+    var name string
+    err = session.Query(YCQL_KEYSPACE_EXISTS_CQL, keyspace).Scan(&name)
+    if err == gocql.ErrNotFound {
+        return echo.NewHTTPError(http.StatusNotFound,
+            fmt.Sprintf("keyspace %s not found", keyspace))
+    }
+    return err
+}
+
+// Gets the YSQL connection for the given database. Connections to the default database are
+// taken from the pool, others are opened on demand, and either must be given back by the caller
+// using the returned function. Databases that do not exist are not connected to, and are not
+// found errors.
+func (c *Container) getYsqlConn(dbName string) (*pgx.Conn, func(), error) {
+    if dbName == "" || dbName == helpers.DbName {
+        poolConn, err := c.Conn.Acquire(context.Background())
+        if err != nil {
+            return nil, nil, err
+        }
+        return poolConn.Conn(), poolConn.Release, nil
+    }
+    if err := c.checkYsqlDatabase(dbName); err != nil {
+        return nil, nil, err
+    }
+    conn, err := pgx.Connect(context.Background(), helpers.GetYsqlConnectionUrl(dbName))
+    if err != nil {
+        return nil, nil, err
+    }
+    return conn, func() { conn.Close(context.Background()) }, nil
+}
+
+// Gets the YSQL grants matching the role and table filters
+func getYsqlGrants(conn *pgx.Conn, dbName string, role string, table string) (
+    []models.DatabaseGrant, error) {
+    grants := []models.DatabaseGrant{}
+    query := YSQL_GRANTS_SQL
+    args := []interface{}{}
+    if role != "" {
+        // Grants to PUBLIC and to roles that the role is a member of also apply
+        args = append(args, role)
+        query += fmt.Sprintf(" AND (grantee = 'PUBLIC' OR pg_has_role($%d, grantee, 'MEMBER'))",
+            len(args))
+    }
+    if table != "" {
+        schemaName, tableName := "public", table
+        if parts := strings.SplitN(table, ".", 2); len(parts) == 2 {
+            schemaName, tableName = parts[0], parts[1]
+        }
+        args = append(args, schemaName, tableName)
+        query += fmt.Sprintf(" AND table_schema = $%d AND table_name = $%d",
+            len(args)-1, len(args))
+    }
+    rows, err := conn.Query(context.Background(), query, args...)
+    if err != nil {
+        return grants, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        grant := models.DatabaseGrant{
+            Type:      models.YBAPIENUM_YSQL,
+            Namespace: dbName,
+        }
+        var isGrantable string
+        err := rows.Scan(&grant.Grantor, &grant.Grantee, &grant.Schema, &grant.ObjectName,
+            &grant.Privilege, &isGrantable)
+        if err != nil {
+            return grants, err
+        }
+        grant.IsGrantable = isGrantable == "YES"
+        grants = append(grants, grant)
+    }
+    return grants, rows.Err()
+}
+
+// Gets the YCQL grants matching the role and table filters
+func getYcqlGrants(session *gocql.Session, role string, table string) (
+    []models.DatabaseGrant, error) {
+    grants := []models.DatabaseGrant{}
+    // YCQL roles inherit the permissions of the roles they are members of
+    roles := map[string]bool{}
+    if role != "" {
+        memberOf := map[string][]string{}
+        iter := session.Query(YCQL_ROLE_MEMBERSHIP_CQL).Iter()
+        var name string
+        var parents []string
+        for iter.Scan(&name, &parents) {
+            memberOf[name] = parents
+        }
+        if err := iter.Close(); err != nil {
+            return grants, err
+        }
+        pending := []string{role}
+        for len(pending) > 0 {
+            current := pending[0]
+            pending = pending[1:]
+            if !roles[current] {
+                roles[current] = true
+                pending = append(pending, memberOf[current]...)
+            }
+        }
+    }
+    iter := session.Query(YCQL_GRANTS_CQL).Iter()
+    var grantee, resource string
+    var permissions []string
+    for iter.Scan(&grantee, &resource, &permissions) {
+        if role != "" && !roles[grantee] {
+            continue
+        }
+        // Data resources are of the form data, data/<keyspace> or data/<keyspace>/<table>
+        parts := strings.Split(resource, "/")
+        if parts[0] != "data" {
+            if table != "" {
+                continue
+            }
+            parts = []string{resource}
+        }
+        namespace, objectName := "", ""
+        if len(parts) > 1 {
+            namespace = parts[1]
+        }
+        if len(parts) > 2 {
+            objectName = parts[2]
+        }
+        if table != "" {
+            keyspaceName, tableName := "", table
+            if tableParts := strings.SplitN(table, ".", 2); len(tableParts) == 2 {
+                keyspaceName, tableName = tableParts[0], tableParts[1]
+            }
+            if (namespace != "" && keyspaceName != "" && namespace != keyspaceName) ||
+                (objectName != "" && objectName != tableName) {
+                continue
+            }
+        }
+        for _, permission := range permissions {
+            grants = append(grants, models.DatabaseGrant{
+                Grantee:    grantee,
+                Type:       models.YBAPIENUM_YCQL,
+                Namespace:  namespace,
+                ObjectName: objectName,
+                Privilege:  permission,
+            })
+        }
+    }
+    return grants, iter.Close()
+}
+
+// GetClusterGrants - Get effective grants for a role or table
+func (c *Container) GetClusterGrants(ctx echo.Context) error {
+    grantListResponse := models.DatabaseGrantListResponse{
+        Data: []models.DatabaseGrant{},
+    }
+    api := ctx.QueryParam("api")
+    role := ctx.QueryParam("role")
+    table := ctx.QueryParam("table")
+    if api == "" || api == "YSQL" {
+        dbName := ctx.QueryParam("database")
+        if dbName == "" {
+            dbName = helpers.DbName
+        }
+        conn, closeConn, err := c.getYsqlConn(dbName)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        grants, err := getYsqlGrants(conn, dbName, role, table)
+        closeConn()
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        grantListResponse.Data = append(grantListResponse.Data, grants...)
+    }
+    if api == "" || api == "YCQL" {
+        session, err := c.getYcqlSession()
+        if err != nil {
+            return respondError(ctx, http.StatusServiceUnavailable, err.Error())
+        }
+        grants, err := getYcqlGrants(session, role, table)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        grantListResponse.Data = append(grantListResponse.Data, grants...)
+    }
+    return ctx.JSON(http.StatusOK, grantListResponse)
+}
+
+// Quotes a YCQL identifier, doubling any embedded double quotes
+func quoteCqlIdentifier(identifier string) string {
+    return "\"" + strings.ReplaceAll(identifier, "\"", "\"\"") + "\""
+}
+
+// Quotes a YCQL string literal, doubling any embedded single quotes
+func quoteCqlLiteral(literal string) string {
+    return "'" + strings.ReplaceAll(literal, "'", "''") + "'"
+}
+
+// Removes the password from an error message, since YCQL errors quote the failed statement
+func redactPassword(err error, password string) string {
+    return strings.ReplaceAll(err.Error(), password, "********")
+}
+
+// ChangeUserPassword - Change the password of a YSQL or YCQL role
+func (c *Container) ChangeUserPassword(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.UserManagement {
+        return respondError(ctx, http.StatusForbidden, "user management is disabled")
+    }
+    name := ctx.Param("name")
+    passwordSpec := models.DatabaseUserPasswordSpec{}
+    if err := ctx.Bind(&passwordSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if passwordSpec.Password == "" {
+        return respondError(ctx, http.StatusBadRequest, "password must not be empty")
+    }
+    roleResponse := models.DatabaseRoleResponse{}
+    switch passwordSpec.Api {
+    case models.YBAPIENUM_YSQL:
+        // Changing our own password would break the connection used by this server
+        if name == helpers.DbYsqlUser {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("cannot change the password of role %s used by this server", name))
+        }
+        role, err := scanYsqlRole(c.Conn.QueryRow(context.Background(),
+            YSQL_ROLES_SQL+YSQL_ROLE_FILTER_SQL, name))
+        if err == pgx.ErrNoRows {
+            return respondError(ctx, http.StatusNotFound, fmt.Sprintf("role %s not found", name))
+        } else if err != nil {
+            return respondWithError(ctx, err)
+        }
+        var statement string
+        err = c.Conn.QueryRow(context.Background(), YSQL_ALTER_ROLE_PASSWORD_SQL, name,
+            passwordSpec.Password).Scan(&statement)
+        if err != nil {
+            return respondError(ctx, http.StatusInternalServerError,
+                redactPassword(err, passwordSpec.Password))
+        }
+        if passwordSpec.ExpirePassword {
+            statement += YSQL_EXPIRE_PASSWORD_SQL
+        }
+        if _, err := c.Conn.Exec(context.Background(), statement); err != nil {
+            return respondError(ctx, http.StatusInternalServerError,
+                redactPassword(err, passwordSpec.Password))
+        }
+        roleResponse.Data = role
+    case models.YBAPIENUM_YCQL:
+        if passwordSpec.ExpirePassword {
+            return respondError(ctx, http.StatusBadRequest,
+                "YCQL roles do not support password expiry")
+        }
+        if name == helpers.DbYcqlUser {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("cannot change the password of role %s used by this server", name))
+        }
+        session, err := c.getYcqlSession()
+        if err != nil {
+            return respondError(ctx, http.StatusServiceUnavailable, err.Error())
+        }
+        role := models.DatabaseRole{}
+        err = session.Query(YCQL_ROLES_CQL+YCQL_ROLE_FILTER_CQL, name).Scan(&role.Name,
+            &role.IsSuperuser, &role.CanLogin, &role.MemberOf)
+        if err == gocql.ErrNotFound {
+            return respondError(ctx, http.StatusNotFound, fmt.Sprintf("role %s not found", name))
+        } else if err != nil {
+            return respondWithError(ctx, err)
+        }
+        statement := fmt.Sprintf("ALTER ROLE %s WITH PASSWORD = %s", quoteCqlIdentifier(name),
+            quoteCqlLiteral(passwordSpec.Password))
+        if err := session.Query(statement).Exec(); err != nil {
+            return respondError(ctx, http.StatusInternalServerError,
+                redactPassword(err, passwordSpec.Password))
+        }
+        roleResponse.Data = completeYcqlRole(role)
+    default:
+        return respondError(ctx, http.StatusBadRequest, "api must be one of YSQL or YCQL")
+    }
+    c.auditLog(ctx, "change_password", "api", passwordSpec.Api, "role", name,
+        "expire_password", passwordSpec.ExpirePassword)
+    return ctx.JSON(http.StatusOK, roleResponse)
+}
+
+// Scans a row of YSQL_EXTENSIONS_SQL into an extension
+func scanYsqlExtension(row pgx.Row) (models.DatabaseExtension, error) {
+    extension := models.DatabaseExtension{}
+    err := row.Scan(&extension.Name, &extension.DefaultVersion, &extension.InstalledVersion,
+        &extension.Comment)
+    extension.IsInstallable = INSTALLABLE_EXTENSIONS[extension.Name]
+    return extension, err
+}
+
+// GetDatabaseExtensions - Get list of YSQL extensions
+func (c *Container) GetDatabaseExtensions(ctx echo.Context) error {
+    extensionListResponse := models.DatabaseExtensionListResponse{
+        Data: []models.DatabaseExtension{},
+    }
+    conn, closeConn, err := c.getYsqlConn(ctx.QueryParam("database"))
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer closeConn()
+    rows, err := conn.Query(context.Background(), YSQL_EXTENSIONS_SQL)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer rows.Close()
+    for rows.Next() {
+        extension, err := scanYsqlExtension(rows)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        extensionListResponse.Data = append(extensionListResponse.Data, extension)
+    }
+    if err := rows.Err(); err != nil {
+        return respondWithError(ctx, err)
+    }
+    sort.Slice(extensionListResponse.Data, func(i, j int) bool {
+        return extensionListResponse.Data[i].Name < extensionListResponse.Data[j].Name
+    })
+    return ctx.JSON(http.StatusOK, extensionListResponse)
+}
+
+// GetPgCompatibility - Get the PostgreSQL version, extensions and unsupported features
+func (c *Container) GetPgCompatibility(ctx echo.Context) error {
+    dbName := ctx.QueryParam("database")
+    if dbName == "" {
+        dbName = helpers.DbName
+    }
+    conn, closeConn, err := c.getYsqlConn(dbName)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer closeConn()
+    var serverVersion string
+    err = conn.QueryRow(context.Background(), YSQL_SERVER_VERSION_SQL).Scan(&serverVersion)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    pgVersion, pgMajorVersion, ybVersion := parseYsqlServerVersion(serverVersion)
+    extensions := []models.InstalledExtension{}
+    rows, err := conn.Query(context.Background(), YSQL_INSTALLED_EXTENSIONS_SQL)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer rows.Close()
+    for rows.Next() {
+        extension := models.InstalledExtension{}
+        if err := rows.Scan(&extension.Name, &extension.Version); err != nil {
+            return respondWithError(ctx, err)
+        }
+        extensions = append(extensions, extension)
+    }
+    if err := rows.Err(); err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.PgCompatibilityResponse{
+        Data: models.PgCompatibility{
+            ServerVersion: serverVersion,
+            PgVersion: pgVersion,
+            YbVersion: ybVersion,
+            Database: dbName,
+            Extensions: extensions,
+            UnsupportedFeatures: getUnsupportedPgFeatures(pgMajorVersion, ybVersion),
+        },
+    })
+}
+
+// CreateDatabaseExtension - Install a YSQL extension
+func (c *Container) CreateDatabaseExtension(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.ExtensionInstall {
+        return respondError(ctx, http.StatusForbidden, "extension installation is disabled")
+    }
+    extensionSpec := models.DatabaseExtensionSpec{}
+    if err := ctx.Bind(&extensionSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if !INSTALLABLE_EXTENSIONS[extensionSpec.Name] {
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("extension %s cannot be installed from the UI", extensionSpec.Name))
+    }
+    dbName := extensionSpec.Database
+    if dbName == "" {
+        dbName = helpers.DbName
+    }
+    conn, closeConn, err := c.getYsqlConn(dbName)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer closeConn()
+    // Make sure the extension files are shipped with this installation before creating it
+    _, err = scanYsqlExtension(conn.QueryRow(context.Background(),
+        YSQL_EXTENSIONS_SQL+YSQL_EXTENSION_FILTER_SQL, extensionSpec.Name))
+    if err == pgx.ErrNoRows {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("extension %s is not available", extensionSpec.Name))
+    } else if err != nil {
+        return respondWithError(ctx, err)
+    }
+    statement := "CREATE EXTENSION IF NOT EXISTS " +
+        pgx.Identifier{extensionSpec.Name}.Sanitize()
+    if _, err := conn.Exec(context.Background(), statement); err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "create_extension", "database", dbName, "extension", extensionSpec.Name)
+    extension, err := scanYsqlExtension(conn.QueryRow(context.Background(),
+        YSQL_EXTENSIONS_SQL+YSQL_EXTENSION_FILTER_SQL, extensionSpec.Name))
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.DatabaseExtensionResponse{
+        Data: extension,
+    })
+}
+
+// Gets the percentage of the range of a sequence that has been used
+func getSequencePercentUsed(sequence models.DatabaseSequence) float64 {
+    if sequence.LastValue == nil || sequence.MaxValue == sequence.MinValue {
+        return 0
+    }
+    // Convert to float first, since the range of a bigint sequence overflows an int64
+    rangeSize := float64(sequence.MaxValue) - float64(sequence.MinValue)
+    used := float64(*sequence.LastValue) - float64(sequence.MinValue)
+    if sequence.IncrementBy < 0 {
+        used = float64(sequence.MaxValue) - float64(*sequence.LastValue)
+    }
+    return 100 * used / rangeSize
+}
+
+// GetDatabaseSequences - Get list of YSQL sequences
+func (c *Container) GetDatabaseSequences(ctx echo.Context) error {
+    sequenceListResponse := models.DatabaseSequenceListResponse{
+        Data: []models.DatabaseSequence{},
+    }
+    conn, closeConn, err := c.getYsqlConn(ctx.QueryParam("database"))
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer closeConn()
+    rows, err := conn.Query(context.Background(), YSQL_SEQUENCES_SQL)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer rows.Close()
+    for rows.Next() {
+        sequence := models.DatabaseSequence{}
+        err := rows.Scan(&sequence.Schema, &sequence.Name, &sequence.Owner, &sequence.DataType,
+            &sequence.StartValue, &sequence.MinValue, &sequence.MaxValue, &sequence.IncrementBy,
+            &sequence.Cycle, &sequence.CacheSize, &sequence.LastValue)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        sequence.PercentUsed = getSequencePercentUsed(sequence)
+        sequence.IsNearOverflow = !sequence.Cycle &&
+            sequence.PercentUsed >= helpers.GetConfig().Thresholds.SequenceOverflowPercent
+        sequenceListResponse.Data = append(sequenceListResponse.Data, sequence)
+    }
+    if err := rows.Err(); err != nil {
+        return respondWithError(ctx, err)
+    }
+    sort.Slice(sequenceListResponse.Data, func(i, j int) bool {
+        if sequenceListResponse.Data[i].Schema != sequenceListResponse.Data[j].Schema {
+            return sequenceListResponse.Data[i].Schema < sequenceListResponse.Data[j].Schema
+        }
+        return sequenceListResponse.Data[i].Name < sequenceListResponse.Data[j].Name
+    })
+    return ctx.JSON(http.StatusOK, sequenceListResponse)
+}
+
+// GetCatalogTables - Get the tables, views and materialized views in the YSQL catalog
+func (c *Container) GetCatalogTables(ctx echo.Context) error {
+    params, err := c.getCatalogParams(ctx.QueryParam)
+    if err == nil {
+        err = validateCatalogEnum("kind", ctx.QueryParam("kind"), CATALOG_TABLE_KINDS)
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    conn, closeConn, err := c.getYsqlConn(params.database)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer closeConn()
+    tables, hasMore, err := getCatalogTables(conn, params, ctx.QueryParam("kind"))
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.CatalogTableListResponse{
+        Data: tables,
+        HasMore: hasMore,
+    })
+}
+
+// GetCatalogIndexes - Get the indexes in the YSQL catalog
+func (c *Container) GetCatalogIndexes(ctx echo.Context) error {
+    params, err := c.getCatalogParams(ctx.QueryParam)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    conn, closeConn, err := c.getYsqlConn(params.database)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer closeConn()
+    indexes, hasMore, err := getCatalogIndexes(conn, params)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.CatalogIndexListResponse{
+        Data: indexes,
+        HasMore: hasMore,
+    })
+}
+
+// GetCatalogConstraints - Get the constraints of the tables in the YSQL catalog
+func (c *Container) GetCatalogConstraints(ctx echo.Context) error {
+    params, err := c.getCatalogParams(ctx.QueryParam)
+    if err == nil {
+        err = validateCatalogEnum("type", ctx.QueryParam("type"), CATALOG_CONSTRAINT_TYPES)
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    conn, closeConn, err := c.getYsqlConn(params.database)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer closeConn()
+    constraints, hasMore, err := getCatalogConstraints(conn, params, ctx.QueryParam("type"))
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.CatalogConstraintListResponse{
+        Data: constraints,
+        HasMore: hasMore,
+    })
+}
+
+// GetCatalogRoles - Get the roles in the YSQL catalog
+func (c *Container) GetCatalogRoles(ctx echo.Context) error {
+    params, err := c.getCatalogParams(ctx.QueryParam)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    conn, closeConn, err := c.getYsqlConn("")
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer closeConn()
+    roles, hasMore, err := getCatalogRoles(conn, params)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.CatalogRoleListResponse{
+        Data: roles,
+        HasMore: hasMore,
+    })
+}
+
+// Gets the YugabyteDB specific clauses of a YSQL table or index, as ysql_dump would add them
+func getYsqlSplitClauses(conn *pgx.Conn, oid uint32) (string, error) {
+    var numTablets, numHashKeyColumns int64
+    var isColocated bool
+    var tablegroup string
+    err := conn.QueryRow(context.Background(), YSQL_TABLE_PROPERTIES_SQL, oid).Scan(&numTablets,
+        &numHashKeyColumns, &isColocated, &tablegroup)
+    if err != nil {
+        return "", err
+    }
+    clauses := ""
+    if !isColocated {
+        if numHashKeyColumns > 0 {
+            clauses += fmt.Sprintf("\nSPLIT INTO %d TABLETS", numTablets)
+        } else if numTablets > 1 {
+            var splitClause string
+            err := conn.QueryRow(context.Background(), YSQL_RANGE_SPLIT_CLAUSE_SQL, oid).Scan(
+                &splitClause)
+            if err != nil {
+                return "", err
+            }
+            clauses += "\n" + splitClause
+        }
+    }
+    // Tables in colocated databases belong to the implicit default tablegroup
+    if tablegroup != "" && tablegroup != "default" {
+        clauses += "\nTABLEGROUP " + pgx.Identifier{tablegroup}.Sanitize()
+    }
+    return clauses, nil
+}
+
+// Gets the CREATE INDEX statement of a YSQL index
+func getYsqlIndexDdl(conn *pgx.Conn, oid uint32) (string, error) {
+    var statement string
+    err := conn.QueryRow(context.Background(), YSQL_INDEX_DEF_SQL, oid).Scan(&statement)
+    if err != nil {
+        return "", err
+    }
+    clauses, err := getYsqlSplitClauses(conn, oid)
+    if err != nil {
+        return "", err
+    }
+    return statement + clauses + ";", nil
+}
+
+// Gets the CREATE statements of a YSQL table and its indexes
+func getYsqlTableDdl(conn *pgx.Conn, oid uint32) ([]string, error) {
+    statements := []string{}
+    var relkind, qualifiedName, reloptions string
+    err := conn.QueryRow(context.Background(), YSQL_TABLE_INFO_SQL, oid).Scan(&relkind,
+        &qualifiedName, &reloptions)
+    if err != nil {
+        return statements, err
+    }
+    if relkind == "i" {
+        statement, err := getYsqlIndexDdl(conn, oid)
+        return append(statements, statement), err
+    }
+    if relkind != "r" {
+        return statements, fmt.Errorf("DDL generation is not supported for relkind %s", relkind)
+    }
+
+    definitions := []string{}
+    rows, err := conn.Query(context.Background(), YSQL_TABLE_COLUMNS_SQL, oid)
+    if err != nil {
+        return statements, err
+    }
+    for rows.Next() {
+        var name, dataType, defaultValue string
+        var notNull bool
+        if err := rows.Scan(&name, &dataType, &notNull, &defaultValue); err != nil {
+            rows.Close()
+            return statements, err
+        }
+        definition := name + " " + dataType
+        if defaultValue != "" {
+            definition += " DEFAULT " + defaultValue
+        }
+        if notNull {
+            definition += " NOT NULL"
+        }
+        definitions = append(definitions, definition)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return statements, err
+    }
+    rows, err = conn.Query(context.Background(), YSQL_TABLE_CONSTRAINTS_SQL, oid)
+    if err != nil {
+        return statements, err
+    }
+    for rows.Next() {
+        var name, definition string
+        if err := rows.Scan(&name, &definition); err != nil {
+            rows.Close()
+            return statements, err
+        }
+        definitions = append(definitions, "CONSTRAINT "+name+" "+definition)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return statements, err
+    }
+
+    statement := "CREATE TABLE " + qualifiedName + " (\n    " +
+        strings.Join(definitions, ",\n    ") + "\n)"
+    if reloptions != "" {
+        statement += "\nWITH (" + reloptions + ")"
+    }
+    clauses, err := getYsqlSplitClauses(conn, oid)
+    if err != nil {
+        return statements, err
+    }
+    statements = append(statements, statement+clauses+";")
+
+    indexOids := []uint32{}
+    rows, err = conn.Query(context.Background(), YSQL_TABLE_INDEXES_SQL, oid)
+    if err != nil {
+        return statements, err
+    }
+    for rows.Next() {
+        var indexOid uint32
+        if err := rows.Scan(&indexOid); err != nil {
+            rows.Close()
+            return statements, err
+        }
+        indexOids = append(indexOids, indexOid)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return statements, err
+    }
+    for _, indexOid := range indexOids {
+        statement, err := getYsqlIndexDdl(conn, indexOid)
+        if err != nil {
+            return statements, err
+        }
+        statements = append(statements, statement)
+    }
+    return statements, nil
+}
+
+// Quotes a YCQL identifier only if it would not be preserved as is without quotes
+func formatCqlIdentifier(identifier string) string {
+    for i, r := range identifier {
+        if !(r >= 'a' && r <= 'z' || r == '_' || i > 0 && r >= '0' && r <= '9') {
+            return quoteCqlIdentifier(identifier)
+        }
+    }
+    return identifier
+}
+
+// Gets the YCQL WITH clause properties of a table or index
+func getYcqlTableProperties(tablets *int32, transactions map[string]string) []string {
+    properties := []string{}
+    if tablets != nil {
+        properties = append(properties, fmt.Sprintf("tablets = %d", *tablets))
+    }
+    if consistencyLevel, ok := transactions["consistency_level"]; ok {
+        properties = append(properties, fmt.Sprintf(
+            "transactions = { 'enabled' : %s, 'consistency_level' : '%s' }",
+            transactions["enabled"], consistencyLevel))
+    } else if transactions["enabled"] == "true" {
+        properties = append(properties, "transactions = { 'enabled' : true }")
+    }
+    return properties
+}
+
+// Gets the CREATE statements of a YCQL table and its indexes. If the name is that of an index,
+// only the statement for the index is returned.
+func getYcqlTableDdl(session *gocql.Session, keyspace string, name string) ([]string, error) {
+    statements := []string{}
+    qualifiedName := formatCqlIdentifier(keyspace) + "." + formatCqlIdentifier(name)
+    var defaultTimeToLive int32
+    var tablets *int32
+    var transactions map[string]string
+    err := session.Query(YCQL_TABLE_PROPERTIES_CQL, keyspace, name).Scan(&defaultTimeToLive,
+        &tablets, &transactions)
+    if err != nil && err != gocql.ErrNotFound {
+        return statements, err
+    }
+    isIndex := err == gocql.ErrNotFound
+
+    if !isIndex {
+        type column struct {
+            name            string
+            kind            string
+            position        int
+            dataType        string
+            clusteringOrder string
+        }
+        columns := []column{}
+        iter := session.Query(YCQL_TABLE_COLUMNS_CQL, keyspace, name).Iter()
+        col := column{}
+        for iter.Scan(&col.name, &col.kind, &col.position, &col.dataType, &col.clusteringOrder) {
+            columns = append(columns, col)
+        }
+        if err := iter.Close(); err != nil {
+            return statements, err
+        }
+        // Key columns come first ordered by their position within the key, the rest keep the
+        // order in which they were returned
+        keyRank := map[string]int{"partition_key": 0, "clustering": 1}
+        rank := func(col column) int {
+            if r, ok := keyRank[col.kind]; ok {
+                return r
+            }
+            return len(keyRank)
+        }
+        sort.SliceStable(columns, func(i, j int) bool {
+            if rank(columns[i]) != rank(columns[j]) {
+                return rank(columns[i]) < rank(columns[j])
+            }
+            return rank(columns[i]) < len(keyRank) && columns[i].position < columns[j].position
+        })
+        definitions := []string{}
+        partitionKey, clusteringKey, clusteringOrder := []string{}, []string{}, []string{}
+        for _, col := range columns {
+            definition := formatCqlIdentifier(col.name) + " " + col.dataType
+            if col.kind == "static" {
+                definition += " STATIC"
+            }
+            definitions = append(definitions, definition)
+            switch col.kind {
+            case "partition_key":
+                partitionKey = append(partitionKey, formatCqlIdentifier(col.name))
+            case "clustering":
+                clusteringKey = append(clusteringKey, formatCqlIdentifier(col.name))
+                if col.clusteringOrder != "none" {
+                    clusteringOrder = append(clusteringOrder, formatCqlIdentifier(col.name)+" "+
+                        strings.ToUpper(col.clusteringOrder))
+                }
+            }
+        }
+        primaryKey := "(" + strings.Join(partitionKey, ", ") + ")"
+        if len(clusteringKey) > 0 {
+            primaryKey += ", " + strings.Join(clusteringKey, ", ")
+        }
+        definitions = append(definitions, "PRIMARY KEY ("+primaryKey+")")
+        statement := "CREATE TABLE " + qualifiedName + " (\n    " +
+            strings.Join(definitions, ",\n    ") + "\n)"
+        properties := []string{}
+        if len(clusteringOrder) > 0 {
+            properties = append(properties,
+                "CLUSTERING ORDER BY ("+strings.Join(clusteringOrder, ", ")+")")
+        }
+        if defaultTimeToLive > 0 {
+            properties = append(properties,
+                fmt.Sprintf("default_time_to_live = %d", defaultTimeToLive))
+        }
+        properties = append(properties, getYcqlTableProperties(tablets, transactions)...)
+        if len(properties) > 0 {
+            statement += "\nWITH " + strings.Join(properties, "\nAND ")
+        }
+        statements = append(statements, statement+";")
+    }
+
+    iter := session.Query(YCQL_INDEXES_CQL, keyspace).Iter()
+    var indexName, tableName string
+    var options map[string]string
+    var isUnique bool
+    var indexTablets *int32
+    var indexTransactions map[string]string
+    for iter.Scan(&indexName, &tableName, &options, &isUnique, &indexTablets,
+        &indexTransactions) {
+        if (isIndex && indexName != name) || (!isIndex && tableName != name) {
+            continue
+        }
+        statement := "CREATE "
+        if isUnique {
+            statement += "UNIQUE "
+        }
+        statement += "INDEX " + formatCqlIdentifier(indexName) + " ON " +
+            formatCqlIdentifier(keyspace) + "." + formatCqlIdentifier(tableName) +
+            " (" + options["target"] + ")"
+        if include, ok := options["include"]; ok {
+            statement += "\nINCLUDE (" + include + ")"
+        }
+        properties := getYcqlTableProperties(indexTablets, indexTransactions)
+        if len(properties) > 0 {
+            statement += "\nWITH " + strings.Join(properties, "\nAND ")
+        }
+        if predicate, ok := options["predicate"]; ok {
+            statement += "\nWHERE " + predicate
+        }
+        statements = append(statements, statement+";")
+    }
+    if err := iter.Close(); err != nil {
+        return statements, err
+    }
+    return statements, nil
+}
+
+// Gets a table by id, or nil if there is no such table
+func getTable(id string) (*helpers.Table, error) {
+    tablesFuture := make(chan helpers.TablesFuture)
+    go helpers.GetTablesFuture(helpers.HOST, tablesFuture)
+    tablesList := <-tablesFuture
+    if tablesList.Error != nil {
+        return nil, tablesList.Error
+    }
+    for i := range tablesList.Tables {
+        if tablesList.Tables[i].Uuid == id {
+            return &tablesList.Tables[i], nil
+        }
+    }
+    return nil, nil
+}
+
+// GetTableDdl - Get the DDL of a table
+func (c *Container) GetTableDdl(ctx echo.Context) error {
+    id := ctx.Param("id")
+    table, err := getTable(id)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if table == nil {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("table %s not found", id))
+    }
+    tableDdl := models.TableDdl{
+        Uuid:     table.Uuid,
+        Name:     table.Name,
+        Keyspace: table.Keyspace,
+    }
+    if table.IsYsql {
+        tableDdl.Type = models.YBAPIENUM_YSQL
+        oid, err := strconv.ParseUint(table.YsqlOid, 10, 32)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        conn, closeConn, err := c.getYsqlConn(table.Keyspace)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        defer closeConn()
+        tableDdl.Statements, err = getYsqlTableDdl(conn, uint32(oid))
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+    } else {
+        tableDdl.Type = models.YBAPIENUM_YCQL
+        session, err := c.getYcqlSession()
+        if err != nil {
+            return respondError(ctx, http.StatusServiceUnavailable, err.Error())
+        }
+        statements, err := getYcqlTableDdl(session, table.Keyspace, table.Name)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        tableDdl.Statements = statements
+    }
+    return ctx.JSON(http.StatusOK, models.TableDdlResponse{
+        Data: tableDdl,
+    })
+}
+
+// GetTableMetrics - Get the read and write ops of a table over time
+func (c *Container) GetTableMetrics(ctx echo.Context) error {
+    id := ctx.Param("id")
+    metricsParam := []string{"READ_OPS_PER_SEC", "WRITE_OPS_PER_SEC"}
+    if ctx.QueryParam("metrics") != "" {
+        metricsParam = strings.Split(ctx.QueryParam("metrics"), ",")
+    }
+    for _, metric := range metricsParam {
+        if _, ok := API_TABLE_OPS_METRICS[metric]; !ok {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("unknown table metric %s", metric))
+        }
+    }
+    endTime := time.Now().Unix()
+    if ctx.QueryParam("end_time") != "" {
+        parsed, err := strconv.ParseInt(ctx.QueryParam("end_time"), 10, 64)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "end_time must be a unix timestamp")
+        }
+        endTime = parsed
+    }
+    startTime := endTime - 60*60
+    if ctx.QueryParam("start_time") != "" {
+        parsed, err := strconv.ParseInt(ctx.QueryParam("start_time"), 10, 64)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest,
+                "start_time must be a unix timestamp")
+        }
+        startTime = parsed
+    }
+    if startTime >= endTime {
+        return respondError(ctx, http.StatusBadRequest, "start_time must be before end_time")
+    }
+    // Only scraped into the fallback store, yugabyted does not record them
+    if !helpers.GetConfig().Metrics.Fallback {
+        return respondError(ctx, http.StatusServiceUnavailable,
+            "table metrics need metrics.fallback to be on")
+    }
+    table, err := getTable(id)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if table == nil {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("table %s not found", id))
+    }
+    metricResponse := models.MetricResponse{
+        Data: []models.MetricData{},
+        StartTimestamp: startTime,
+        EndTimestamp: endTime,
+        Resolution: METRICS_RESOLUTION_RAW,
+    }
+    for _, metric := range metricsParam {
+        values := c.fallbackMetrics.tableOpsValues(API_TABLE_OPS_METRICS[metric], table.Uuid,
+            startTime, endTime)
+        metricResponse.Data = append(metricResponse.Data, models.MetricData{
+            Name: metric,
+            Values: reduceGranularity(startTime, endTime, values, GRANULARITY_NUM_INTERVALS,
+                true),
+        })
+    }
+    return ctx.JSON(http.StatusOK, metricResponse)
+}
+
+// Gets the names of the columns of a YSQL table, in order
+func getYsqlColumnNames(conn *pgx.Conn, oid uint32) ([]string, error) {
+    names := []string{}
+    rows, err := conn.Query(context.Background(), YSQL_TABLE_COLUMN_NAMES_SQL, oid)
+    if err != nil {
+        return names, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var name string
+        if err := rows.Scan(&name); err != nil {
+            return names, err
+        }
+        names = append(names, name)
+    }
+    return names, rows.Err()
+}
+
+// Gets the names of the columns of a YCQL table, and the type of each column by name
+func getYcqlColumnTypes(session *gocql.Session, keyspace string,
+    name string) ([]string, map[string]string, error) {
+    names := []string{}
+    types := map[string]string{}
+    iter := session.Query(YCQL_TABLE_COLUMN_TYPES_CQL, keyspace, name).Iter()
+    var column, columnType string
+    for iter.Scan(&column, &columnType) {
+        names = append(names, column)
+        types[column] = columnType
+    }
+    return names, types, iter.Close()
+}
+
+// Gets the requested columns in order, or all columns if none are requested, checking that
+// they are columns of the table
+func getTableColumns(tableColumns []string, requested []string) ([]string, error) {
+    if len(requested) == 0 {
+        return tableColumns, nil
+    }
+    for _, column := range requested {
+        found := false
+        for _, tableColumn := range tableColumns {
+            found = found || column == tableColumn
+        }
+        if !found {
+            return nil, fmt.Errorf("column %s not found", column)
+        }
+    }
+    return requested, nil
+}
+
+// Writes a value read from YCQL as it would be written in a CQL statement where that differs
+// from the default formatting
+func formatCqlExportValue(value interface{}) string {
+    switch typedValue := value.(type) {
+    case nil:
+        return ""
+    case []byte:
+        return "0x" + hex.EncodeToString(typedValue)
+    case time.Time:
+        if typedValue.IsZero() {
+            return ""
+        }
+        return typedValue.UTC().Format(time.RFC3339Nano)
+    default:
+        return fmt.Sprint(typedValue)
+    }
+}
+
+// Fetches the next page of a YSQL export cursor. NULLs are exported as empty values.
+func fetchYsqlExportPage(tx pgx.Tx, numColumns int, pageSize int) ([][]string, error) {
+    page := [][]string{}
+    // Queries are sent with the extended protocol, which refuses more than one statement
+    rows, err := tx.Query(context.Background(),
+        fmt.Sprintf("FETCH %d FROM %s", pageSize, YSQL_EXPORT_CURSOR))
+    if err != nil {
+        return page, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        values := make([]*string, numColumns)
+        destinations := make([]interface{}, numColumns)
+        for i := range values {
+            destinations[i] = &values[i]
+        }
+        if err := rows.Scan(destinations...); err != nil {
+            return page, err
+        }
+        row := make([]string, numColumns)
+        for i, value := range values {
+            if value != nil {
+                row[i] = *value
+            }
+        }
+        page = append(page, row)
+    }
+    return page, rows.Err()
+}
+
+// Streams the rows of a YSQL table through a cursor, a page at a time. The cursor is read in
+// a read only transaction on a connection of its own, so that the filter cannot change any
+// data and the export does not hold up other requests.
+func (c *Container) exportYsqlTable(ctx echo.Context, table *helpers.Table,
+    exportSpec models.TableExportSpec, limit int64) error {
+    oid, err := strconv.ParseUint(table.YsqlOid, 10, 32)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    conn, err := pgx.Connect(context.Background(), helpers.GetYsqlConnectionUrl(table.Keyspace))
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer conn.Close(context.Background())
+    var relkind, qualifiedName, reloptions string
+    err = conn.QueryRow(context.Background(), YSQL_TABLE_INFO_SQL, oid).Scan(&relkind,
+        &qualifiedName, &reloptions)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    tableColumns, err := getYsqlColumnNames(conn, uint32(oid))
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    columns, err := getTableColumns(tableColumns, exportSpec.Columns)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    selects := []string{}
+    for _, column := range columns {
+        selects = append(selects, pgx.Identifier{column}.Sanitize()+"::text")
+    }
+    terms, err := parseExportFilter(exportSpec.Where, tableColumns)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid where: "+err.Error())
+    }
+    query := fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR SELECT %s FROM %s",
+        YSQL_EXPORT_CURSOR, strings.Join(selects, ", "), qualifiedName)
+    // The simple protocol quotes the values of the filter into the statement as literals
+    args := []interface{}{pgx.QuerySimpleProtocol(true)}
+    if len(terms) > 0 {
+        condition, conditionArgs := getYsqlExportCondition(terms, 1)
+        query += " WHERE (" + condition + ")\n"
+        args = append(args, conditionArgs...)
+    }
+    query += fmt.Sprintf(" LIMIT %d", limit)
+    tx, err := conn.BeginTx(context.Background(), pgx.TxOptions{AccessMode: pgx.ReadOnly})
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer tx.Rollback(context.Background())
+    declareRows, err := tx.Query(context.Background(), query, args...)
+    if err == nil {
+        declareRows.Close()
+        err = declareRows.Err()
+    }
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    // The first page is read before the stream starts, so that errors in the filter that only
+    // show when it is evaluated can still be reported with an error status
+    pageSize := helpers.GetConfig().TableExport.PageSize
+    page, err := fetchYsqlExportPage(tx, len(columns), pageSize)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    stream := newCsvStream(ctx, exportSpec.Format, http.StatusOK)
+    stream.row(columns...)
+    // The rows are counted as well as limited by the query, so that the limit holds whatever
+    // the filter is
+    exported := int64(0)
+    for {
+        for _, row := range page {
+            if exported == limit {
+                break
+            }
+            stream.row(row...)
+            exported++
+        }
+        if len(page) < pageSize || exported == limit {
+            break
+        }
+        page, err = fetchYsqlExportPage(tx, len(columns), pageSize)
+        if err != nil {
+            c.logger.Errorf("failed to export table %s: %s", table.Uuid, err.Error())
+            stream.close()
+            return err
+        }
+    }
+    return stream.close()
+}
+
+// Streams the rows of a YCQL table, which the driver pages through
+func (c *Container) exportYcqlTable(ctx echo.Context, table *helpers.Table,
+    exportSpec models.TableExportSpec, limit int64) error {
+    session, err := c.getYcqlSession()
+    if err != nil {
+        return respondError(ctx, http.StatusServiceUnavailable, err.Error())
+    }
+    tableColumns, columnTypes, err := getYcqlColumnTypes(session, table.Keyspace, table.Name)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    columns, err := getTableColumns(tableColumns, exportSpec.Columns)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    terms, err := parseExportFilter(exportSpec.Where, tableColumns)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid where: "+err.Error())
+    }
+    selects := "*"
+    if len(exportSpec.Columns) > 0 {
+        quotedColumns := []string{}
+        for _, column := range columns {
+            quotedColumns = append(quotedColumns, quoteCqlIdentifier(column))
+        }
+        selects = strings.Join(quotedColumns, ", ")
+    }
+    query := fmt.Sprintf("SELECT %s FROM %s.%s", selects, quoteCqlIdentifier(table.Keyspace),
+        quoteCqlIdentifier(table.Name))
+    args := []interface{}{}
+    if len(terms) > 0 {
+        var condition string
+        condition, args, err = getYcqlExportCondition(terms, columnTypes)
+        if err != nil {
+            return respondError(ctx, http.StatusBadRequest, "invalid where: "+err.Error())
+        }
+        query += " WHERE " + condition
+    }
+    query += fmt.Sprintf(" LIMIT %d", limit)
+    iter := session.Query(query, args...).PageSize(helpers.GetConfig().TableExport.PageSize).Iter()
+    row := map[string]interface{}{}
+    // The first row is read before the stream starts, so that errors in the query can still be
+    // reported with an error status
+    hasRow := iter.MapScan(row)
+    if !hasRow {
+        if err := iter.Close(); err != nil {
+            return respondError(ctx, http.StatusBadRequest, err.Error())
+        }
+    }
+    // Without a column selection, the columns are in the order the table returns them
+    columns = []string{}
+    for _, column := range iter.Columns() {
+        columns = append(columns, column.Name)
+    }
+    stream := newCsvStream(ctx, exportSpec.Format, http.StatusOK)
+    stream.row(columns...)
+    // The rows are counted as well as limited by the query, so that the limit holds whatever
+    // the filter is
+    for exported := int64(0); hasRow && exported < limit; hasRow = iter.MapScan(row) {
+        exported++
+        values := make([]string, len(columns))
+        for i, column := range columns {
+            values[i] = formatCqlExportValue(row[column])
+        }
+        stream.row(values...)
+        row = map[string]interface{}{}
+    }
+    if err := iter.Close(); err != nil {
+        c.logger.Errorf("failed to export table %s: %s", table.Uuid, err.Error())
+        stream.close()
+        return err
+    }
+    return stream.close()
+}
+
+// ExportTable - Export the rows of a table
+func (c *Container) ExportTable(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.TableExport {
+        return respondError(ctx, http.StatusForbidden, "table export is disabled")
+    }
+    exportSpec := models.TableExportSpec{}
+    if err := ctx.Bind(&exportSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    switch exportSpec.Format {
+    case "":
+        exportSpec.Format = EXPORT_FORMAT_CSV
+    case EXPORT_FORMAT_CSV, EXPORT_FORMAT_TSV:
+    default:
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("format must be csv or tsv, got %s", exportSpec.Format))
+    }
+    maxRows := helpers.GetConfig().TableExport.MaxRows
+    limit := maxRows
+    if exportSpec.Limit != nil {
+        if *exportSpec.Limit < 1 || *exportSpec.Limit > maxRows {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("limit must be between 1 and %d, got %d", maxRows, *exportSpec.Limit))
+        }
+        limit = *exportSpec.Limit
+    }
+    id := ctx.Param("id")
+    table, err := getTable(id)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if table == nil {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("table %s not found", id))
+    }
+    c.auditLog(ctx, "export_table", "table", table.Keyspace+"."+table.Name,
+        "columns", exportSpec.Columns, "where", exportSpec.Where, "limit", limit)
+    // Table names can have quotes, or characters that need the encoded form of the filename
+    ctx.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment",
+        map[string]string{"filename": table.Name + "." + exportSpec.Format}))
+    if table.IsYsql {
+        return c.exportYsqlTable(ctx, table, exportSpec, limit)
+    }
+    return c.exportYcqlTable(ctx, table, exportSpec, limit)
+}
+
+// ImportTable - Import rows into a table
+func (c *Container) ImportTable(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.TableImport {
+        return respondError(ctx, http.StatusForbidden, "table import is disabled")
+    }
+    importConfig := helpers.GetConfig().TableImport
+    format := ctx.FormValue("format")
+    switch format {
+    case "":
+        format = EXPORT_FORMAT_CSV
+    case EXPORT_FORMAT_CSV, EXPORT_FORMAT_TSV:
+    default:
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("format must be csv or tsv, got %s", format))
+    }
+    fileHeader, err := ctx.FormFile("file")
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest,
+            "the rows must be uploaded as the file field")
+    }
+    if fileHeader.Size > importConfig.MaxUploadBytes {
+        return respondError(ctx, http.StatusRequestEntityTooLarge,
+            fmt.Sprintf("the file must be at most %d bytes", importConfig.MaxUploadBytes))
+    }
+    upload, err := fileHeader.Open()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    data, err := ioutil.ReadAll(upload)
+    upload.Close()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    file, err := parseImportFile(data, format)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    if len(file.rows) == 0 {
+        return respondError(ctx, http.StatusBadRequest, "the file has no rows below the header")
+    }
+    id := ctx.Param("id")
+    table, err := getTable(id)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if table == nil {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("table %s not found", id))
+    }
+
+    var importer tableImporter
+    if table.IsYsql {
+        oid, err := strconv.ParseUint(table.YsqlOid, 10, 32)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        // The import gets a connection of its own, as it outlives the request
+        conn, err := pgx.Connect(context.Background(),
+            helpers.GetYsqlConnectionUrl(table.Keyspace))
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        var relkind, qualifiedName, reloptions string
+        err = conn.QueryRow(context.Background(), YSQL_TABLE_INFO_SQL, oid).Scan(&relkind,
+            &qualifiedName, &reloptions)
+        if err != nil {
+            conn.Close(context.Background())
+            return respondWithError(ctx, err)
+        }
+        tableColumns, err := getYsqlColumnNames(conn, uint32(oid))
+        if err != nil {
+            conn.Close(context.Background())
+            return respondWithError(ctx, err)
+        }
+        if _, err := getTableColumns(tableColumns, file.columns); err != nil {
+            conn.Close(context.Background())
+            return respondError(ctx, http.StatusBadRequest, err.Error())
+        }
+        importer = ysqlTableImporter{
+            conn: conn,
+            qualifiedName: qualifiedName,
+            columns: file.columns,
+        }
+    } else {
+        session, err := c.getYcqlSession()
+        if err != nil {
+            return respondError(ctx, http.StatusServiceUnavailable, err.Error())
+        }
+        tableColumns, columnTypes, err := getYcqlColumnTypes(session, table.Keyspace, table.Name)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        if _, err := getTableColumns(tableColumns, file.columns); err != nil {
+            return respondError(ctx, http.StatusBadRequest, err.Error())
+        }
+        types := []string{}
+        for _, column := range file.columns {
+            types = append(types, columnTypes[column])
+        }
+        importer = ycqlTableImporter{
+            session: session,
+            qualifiedName: quoteCqlIdentifier(table.Keyspace) + "." +
+                quoteCqlIdentifier(table.Name),
+            columns: file.columns,
+            types: types,
+        }
+    }
+
+    task, err := c.tasks.Submit("import_table", func(task *tasks.Task) error {
+        task.Progress("importing %d rows into %s.%s", len(file.rows), table.Keyspace,
+            table.Name)
+        return runTableImport(task, importer, file, importConfig.BatchSize,
+            importConfig.MaxRowErrors)
+    })
+    if err != nil {
+        importer.close()
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "import_table", "table", table.Keyspace+"."+table.Name,
+        "columns", file.columns, "rows", len(file.rows), "task_id", task.Id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// GetDatabaseDumps - Get list of database dumps
+func (c *Container) GetDatabaseDumps(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, models.DatabaseDumpListResponse{
+        Data: c.databaseDumps.list(),
+    })
+}
+
+// CreateDatabaseDump - Dump a YSQL database
+func (c *Container) CreateDatabaseDump(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.DatabaseDump {
+        return respondError(ctx, http.StatusForbidden, "database dump is disabled")
+    }
+    dumpSpec := models.DatabaseDumpSpec{}
+    if err := ctx.Bind(&dumpSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    if dumpSpec.Database == "" {
+        return respondError(ctx, http.StatusBadRequest, "database is required")
+    }
+    var exists bool
+    err := c.Conn.QueryRow(context.Background(), YSQL_DATABASE_EXISTS_SQL,
+        dumpSpec.Database).Scan(&exists)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !exists {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("database %s not found", dumpSpec.Database))
+    }
+
+    task, err := c.tasks.Submit("dump_database", func(task *tasks.Task) error {
+        id := task.Info().Id
+        path, err := c.databaseDumps.getPath(id)
+        if err != nil {
+            return err
+        }
+        args := []string{"--file", path, "--compress", "6"}
+        if dumpSpec.SchemaOnly {
+            args = append(args, "--schema-only")
+        }
+        task.Progress("dumping database %s", dumpSpec.Database)
+        if _, err := helpers.RunYsqlDump(dumpSpec.Database, args...); err != nil {
+            os.Remove(path)
+            return err
+        }
+        info, err := os.Stat(path)
+        if err != nil {
+            return err
+        }
+        c.databaseDumps.add(models.DatabaseDump{
+            Id: id,
+            Database: dumpSpec.Database,
+            SchemaOnly: dumpSpec.SchemaOnly,
+            SizeBytes: info.Size(),
+            Timestamp: time.Now().Unix(),
+        }, path)
+        task.Progress("wrote %d bytes", info.Size())
+        return nil
+    })
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "dump_database", "database", dumpSpec.Database,
+        "schema_only", dumpSpec.SchemaOnly, "task_id", task.Id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// DownloadDatabaseDump - Download a database dump
+func (c *Container) DownloadDatabaseDump(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.DatabaseDump {
+        return respondError(ctx, http.StatusForbidden, "database dump is disabled")
+    }
+    // A dump has every row of the database, which a read only token cannot otherwise read
+    if isReadOnlyApiToken(ctx) {
+        return respondError(ctx, http.StatusForbidden,
+            fmt.Sprintf("the API token needs the %s scope", API_TOKEN_SCOPE_WRITE))
+    }
+    id := ctx.Param("id")
+    dump, path, ok := c.databaseDumps.get(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("dump %s not found", id))
+    }
+    c.auditLog(ctx, "download_database_dump", "database", dump.Database, "dump_id", id)
+    return ctx.Attachment(path, fmt.Sprintf("%s-%s%s", dump.Database,
+        time.Unix(dump.Timestamp, 0).UTC().Format("20060102T150405Z"), DATABASE_DUMP_EXTENSION))
+}
+
+// CloneDatabase - Create a writable copy of a YSQL database
+func (c *Container) CloneDatabase(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.DatabaseClone {
+        return respondError(ctx, http.StatusForbidden, "database clone is disabled")
+    }
+    source := ctx.Param("name")
+    cloneSpec := models.DatabaseCloneSpec{}
+    if err := ctx.Bind(&cloneSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if !YB_ADMIN_NAME_REGEX.MatchString(cloneSpec.Target) || len(cloneSpec.Target) > 63 {
+        return respondError(ctx, http.StatusBadRequest, "target must be a database name of at "+
+            "most 63 letters, digits, underscores and dollar signs")
+    }
+    if cloneSpec.CloneTime < 0 || cloneSpec.CloneTime > time.Now().Unix() {
+        return respondError(ctx, http.StatusBadRequest,
+            "clone_time must be a UNIX timestamp in the past, or 0 for now")
+    }
+    for _, database := range []string{source, cloneSpec.Target} {
+        var exists bool
+        err := c.Conn.QueryRow(context.Background(), YSQL_DATABASE_EXISTS_SQL,
+            database).Scan(&exists)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        if database == source && !exists {
+            return respondError(ctx, http.StatusNotFound,
+                fmt.Sprintf("database %s not found", source))
+        }
+        if database == cloneSpec.Target && exists {
+            return respondError(ctx, http.StatusConflict,
+                fmt.Sprintf("database %s already exists", cloneSpec.Target))
+        }
+    }
+    version, err := getSmallestNodeVersion()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    instant := version != "" && helpers.CompareVersions(version, INSTANT_CLONE_MIN_VERSION) >= 0
+    if !instant && cloneSpec.CloneTime != 0 {
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf("cloning as of a past time "+
+            "needs version %s or later, the cluster runs %s", INSTANT_CLONE_MIN_VERSION,
+            version))
+    }
+    task, err := c.tasks.Submit("clone_database", func(task *tasks.Task) error {
+        deadline := time.Now().Add(helpers.GetConfig().Timeouts.DatabaseClone)
+        var err error
+        if instant {
+            err = cloneDatabaseInstantly(task, source, cloneSpec.Target, cloneSpec.CloneTime,
+                deadline)
+        } else {
+            err = c.cloneDatabaseWithSnapshot(task, source, cloneSpec.Target, deadline)
+        }
+        if err != nil {
+            return err
+        }
+        task.Progress("database %s is a clone of %s", cloneSpec.Target, source)
+        return nil
+    })
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "clone_database", "database", source, "target", cloneSpec.Target,
+        "clone_time", cloneSpec.CloneTime, "instant", instant, "task_id", task.Id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// GetSampleDatasets - Get list of the sample datasets
+func (c *Container) GetSampleDatasets(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, models.SampleDatasetListResponse{
+        Data: getSampleDatasets(),
+    })
+}
+
+// LoadSampleData - Load a sample dataset into a YSQL database
+func (c *Container) LoadSampleData(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.SampleData {
+        return respondError(ctx, http.StatusForbidden, "sample data is disabled")
+    }
+    sampleDataSpec := models.SampleDataSpec{}
+    if err := ctx.Bind(&sampleDataSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    dataset, ok := getSampleDataset(sampleDataSpec.Dataset)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("sample dataset %s not found", sampleDataSpec.Dataset))
+    }
+    if !YB_ADMIN_NAME_REGEX.MatchString(sampleDataSpec.Database) ||
+        len(sampleDataSpec.Database) > 63 {
+        return respondError(ctx, http.StatusBadRequest, "database must be a database name of "+
+            "at most 63 letters, digits, underscores and dollar signs")
+    }
+    var exists bool
+    err := c.Conn.QueryRow(context.Background(), YSQL_DATABASE_EXISTS_SQL,
+        sampleDataSpec.Database).Scan(&exists)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    task, err := c.tasks.Submit("load_sample_data", func(task *tasks.Task) error {
+        return loadSampleDataset(task, dataset, sampleDataSpec.Database, !exists)
+    })
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "load_sample_data", "dataset", dataset.name,
+        "database", sampleDataSpec.Database, "create_database", !exists, "task_id", task.Id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// CreateCdcStream - Create a CDCSDK stream for the changes of a YSQL database
+func (c *Container) CreateCdcStream(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.CdcStreams {
+        return respondError(ctx, http.StatusForbidden, "CDC streams are disabled")
+    }
+    streamSpec := models.CdcStreamSpec{}
+    if err := ctx.Bind(&streamSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    if err := validateCdcStreamSpec(&streamSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    var exists bool
+    err := c.Conn.QueryRow(context.Background(), YSQL_DATABASE_EXISTS_SQL,
+        streamSpec.Database).Scan(&exists)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !exists {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("database %s not found", streamSpec.Database))
+    }
+    streamId, err := createCdcStream(streamSpec)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "create_cdc_stream", "database", streamSpec.Database,
+        "stream_id", streamId)
+    return ctx.JSON(http.StatusOK, models.CdcStreamResponse{
+        Data: models.CdcStream{
+            StreamId: streamId,
+            Database: streamSpec.Database,
+            CheckpointType: streamSpec.CheckpointType,
+            RecordType: streamSpec.RecordType,
+        },
+    })
+}
+
+// DeleteCdcStream - Delete a CDCSDK stream
+func (c *Container) DeleteCdcStream(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.CdcStreams {
+        return respondError(ctx, http.StatusForbidden, "CDC streams are disabled")
+    }
+    id := ctx.Param("id")
+    if !CDC_STREAM_ID_REGEX.MatchString(id) {
+        return respondError(ctx, http.StatusBadRequest, "invalid stream id")
+    }
+    if _, err := helpers.RunYbAdmin("delete_change_data_stream", id); err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "delete_cdc_stream", "stream_id", id)
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// GetCdcConnectorConfig - Get the config of a Debezium connector that reads a CDCSDK stream
+func (c *Container) GetCdcConnectorConfig(ctx echo.Context) error {
+    id := ctx.Param("id")
+    if !CDC_STREAM_ID_REGEX.MatchString(id) {
+        return respondError(ctx, http.StatusBadRequest, "invalid stream id")
+    }
+    database := ctx.QueryParam("database")
+    if database == "" {
+        return respondError(ctx, http.StatusBadRequest, "database is required")
+    }
+    format := ctx.QueryParam("format")
+    if format == "" {
+        format = CDC_CONNECTOR_FORMAT_KAFKA_CONNECT
+    }
+    if format != CDC_CONNECTOR_FORMAT_KAFKA_CONNECT &&
+        format != CDC_CONNECTOR_FORMAT_DEBEZIUM_SERVER {
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("format must be %s or %s", CDC_CONNECTOR_FORMAT_KAFKA_CONNECT,
+                CDC_CONNECTOR_FORMAT_DEBEZIUM_SERVER))
+    }
+    name := ctx.QueryParam("name")
+    if name == "" {
+        name = "yb-" + database
+    }
+    if !CDC_CONNECTOR_NAME_REGEX.MatchString(name) {
+        return respondError(ctx, http.StatusBadRequest,
+            "name must be up to 100 letters, digits and the characters _.-")
+    }
+    tables := ctx.QueryParam("tables")
+    if tables == "" {
+        tables = CDC_CONNECTOR_DEFAULT_TABLES
+    }
+    target, err := getCdcConnectorTarget()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.CdcConnectorConfigResponse{
+        Data: getCdcConnectorConfig(format, name, id, database, tables, target),
+    })
+}
+
+// GetCdcHealth - Get the status of the Debezium connectors next to the CDC streams they read
+func (c *Container) GetCdcHealth(ctx echo.Context) error {
+    connectorsFuture := make(chan helpers.KafkaConnectConnectorsFuture, 1)
+    if helpers.GetConfig().KafkaConnect.Url != "" {
+        go helpers.GetKafkaConnectConnectorsFuture(connectorsFuture)
+    } else {
+        connectorsFuture <- helpers.KafkaConnectConnectorsFuture{
+            Connectors: map[string]helpers.KafkaConnectConnector{},
+        }
+    }
+    session, err := c.getYcqlSession()
+    if err != nil {
+        return respondError(ctx, http.StatusServiceUnavailable, err.Error())
+    }
+    streams, err := getCdcStreamHealth(session, time.Now())
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    connectors := <-connectorsFuture
+    if connectors.Error != nil {
+        return respondError(ctx, http.StatusBadGateway, connectors.Error.Error())
+    }
+    return ctx.JSON(http.StatusOK, models.CdcHealthResponse{
+        Data: getCdcHealth(connectors.Connectors, streams),
+    })
+}
+
+// OpenShell - Open a ysqlsh or ycqlsh shell over a WebSocket
+func (c *Container) OpenShell(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.Shell {
+        return respondError(ctx, http.StatusForbidden, "the shell is disabled")
+    }
+    // The shell can change anything, even though it is opened with a GET request
+    if isReadOnlyApiToken(ctx) {
+        return respondError(ctx, http.StatusForbidden,
+            fmt.Sprintf("the API token needs the %s scope", API_TOKEN_SCOPE_WRITE))
+    }
+    api := models.YbApiEnum(ctx.QueryParam("api"))
+    database := ctx.QueryParam("database")
+    switch api {
+    case models.YBAPIENUM_YSQL:
+        if database == "" {
+            database = helpers.DbName
+        }
+    case models.YBAPIENUM_YCQL:
+    default:
+        return respondError(ctx, http.StatusBadRequest, "api must be one of YSQL or YCQL")
+    }
+    // The shells take a database that is a connection string as where to connect, and would
+    // send the password there, so only the names of existing databases are passed on
+    if api == models.YBAPIENUM_YSQL {
+        if err := c.checkYsqlDatabase(database); err != nil {
+            return respondWithError(ctx, err)
+        }
+    } else if database != "" {
+        if err := c.checkYcqlKeyspace(database); err != nil {
+            return respondWithError(ctx, err)
+        }
+    }
+    if !c.shells.acquire() {
+        return respondError(ctx, http.StatusTooManyRequests,
+            fmt.Sprintf("%d shells are open already", helpers.GetConfig().Shell.MaxSessions))
+    }
+    defer c.shells.release()
+    cmd, cleanup, err := helpers.NewShellCommand(api == models.YBAPIENUM_YSQL, database)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer cleanup()
+    websocket.Server{
+        Handshake: checkShellOrigin,
+        Handler: func(ws *websocket.Conn) {
+            c.auditLog(ctx, "open_shell", "api", api, "database", database)
+            reason, err := runShell(ws, cmd, func(command string) {
+                c.auditLog(ctx, "shell_command", "api", api, "database", database,
+                    "command", command)
+            })
+            if err != nil {
+                c.logger.Errorf("failed to run the %s shell: %s", api, err.Error())
+                websocket.JSON.Send(ws, shellMessage{Type: SHELL_MESSAGE_EXIT,
+                    Data: fmt.Sprintf("failed to start the shell: %s", err.Error())})
+                return
+            }
+            c.auditLog(ctx, "close_shell", "api", api, "database", database, "reason", reason)
+        },
+    }.ServeHTTP(ctx.Response(), ctx.Request())
+    return nil
+}
+
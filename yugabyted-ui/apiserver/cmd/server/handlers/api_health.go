@@ -0,0 +1,123 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "context"
+    "net"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/jackc/pgx/v4"
+    "github.com/yugabyte/gocql"
+)
+
+const API_HEALTH_YSQL_QUERY = "SELECT 1"
+
+// Connects to the YSQL layer of a node and runs a trivial query. The latency is the round trip
+// of the query alone, without connecting.
+func probeYsql(host string, timeout time.Duration) models.ApiLayerHealth {
+    probeCtx, cancel := context.WithTimeout(context.Background(), timeout)
+    defer cancel()
+    start := time.Now()
+    conn, err := pgx.Connect(probeCtx, helpers.GetYsqlConnectionUrlForHost(host, helpers.DbName))
+    if err != nil {
+        return getApiLayerHealth(0, 0, err)
+    }
+    defer conn.Close(context.Background())
+    connectLatency := time.Since(start)
+    start = time.Now()
+    var result int
+    err = conn.QueryRow(probeCtx, API_HEALTH_YSQL_QUERY).Scan(&result)
+    return getApiLayerHealth(connectLatency, time.Since(start), err)
+}
+
+// Connects to the YCQL layer of a node, and no other node, and runs a trivial query. The
+// latency is the round trip of the query alone, without connecting.
+func probeYcql(cluster *gocql.ClusterConfig, host string,
+    timeout time.Duration) models.ApiLayerHealth {
+    hostPort := net.JoinHostPort(host, strconv.Itoa(helpers.GetConfig().Upstream.YcqlPort))
+    nodeCluster := gocql.NewCluster(hostPort)
+    nodeCluster.Authenticator = cluster.Authenticator
+    nodeCluster.SslOpts = cluster.SslOpts
+    nodeCluster.Consistency = gocql.One
+    nodeCluster.Timeout = timeout
+    nodeCluster.ConnectTimeout = timeout
+    nodeCluster.NumConns = 1
+    // Otherwise the driver discovers the other nodes and may run the query on one of them
+    nodeCluster.DisableInitialHostLookup = true
+    nodeCluster.Events.DisableTopologyEvents = true
+    nodeCluster.Events.DisableNodeStatusEvents = true
+    nodeCluster.Events.DisableSchemaEvents = true
+    start := time.Now()
+    session, err := nodeCluster.CreateSession()
+    if err != nil {
+        return getApiLayerHealth(0, 0, err)
+    }
+    defer session.Close()
+    connectLatency := time.Since(start)
+    start = time.Now()
+    err = session.Query(YCQL_HEALTH_CHECK_CQL).Exec()
+    return getApiLayerHealth(connectLatency, time.Since(start), err)
+}
+
+func getApiLayerHealth(connectLatency time.Duration, queryLatency time.Duration,
+    err error) models.ApiLayerHealth {
+    if err != nil {
+        message := err.Error()
+        return models.ApiLayerHealth{Healthy: false, Error: &message}
+    }
+    connectLatencyMs := float64(connectLatency.Microseconds()) / 1000
+    queryLatencyMs := float64(queryLatency.Microseconds()) / 1000
+    return models.ApiLayerHealth{
+        Healthy: true,
+        ConnectLatencyMs: &connectLatencyMs,
+        LatencyMs: &queryLatencyMs,
+    }
+}
+
+// Probes the YSQL and YCQL layers of every tserver, all at once, sorted by host
+func (c *Container) getApiHealth() (models.ApiHealth, error) {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServers := <-tabletServersFuture
+    if tabletServers.Error != nil {
+        return models.ApiHealth{}, tabletServers.Error
+    }
+    timeout := helpers.GetConfig().Timeouts.ApiHealthProbe
+    fanOut := newFanOutLimiter()
+    nodes := getClusterStateNodes(tabletServers)
+    ysqlFutures := make([]chan models.ApiLayerHealth, len(nodes))
+    ycqlFutures := make([]chan models.ApiLayerHealth, len(nodes))
+    for index, node := range nodes {
+        host := node.Name
+        ysqlFuture := make(chan models.ApiLayerHealth, 1)
+        ycqlFuture := make(chan models.ApiLayerHealth, 1)
+        ysqlFutures[index] = ysqlFuture
+        ycqlFutures[index] = ycqlFuture
+        fanOut.goCall(func() { ysqlFuture <- probeYsql(host, timeout) })
+        fanOut.goCall(func() { ycqlFuture <- probeYcql(c.ycql.cluster, host, timeout) })
+    }
+    apiHealth := models.ApiHealth{
+        Healthy: true,
+        Nodes: []models.NodeApiHealth{},
+    }
+    for index, node := range nodes {
+        nodeApiHealth := models.NodeApiHealth{
+            Node: node.Name,
+            Status: node.Status,
+            Region: node.Region,
+            Ysql: <-ysqlFutures[index],
+            Ycql: <-ycqlFutures[index],
+        }
+        if !nodeApiHealth.Ysql.Healthy || !nodeApiHealth.Ycql.Healthy {
+            apiHealth.Healthy = false
+        }
+        apiHealth.Nodes = append(apiHealth.Nodes, nodeApiHealth)
+    }
+    sort.Slice(apiHealth.Nodes, func(i, j int) bool {
+        return apiHealth.Nodes[i].Node < apiHealth.Nodes[j].Node
+    })
+    return apiHealth, nil
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/models"
+    "context"
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/jackc/pgx/v4"
+    "github.com/labstack/echo/v4"
+)
+
+// Whether Voyager has recorded any migration in the yugabyted database
+func (c *Container) hasVoyagerMetadata() (bool, error) {
+    exists := false
+    err := c.Conn.QueryRow(context.Background(), VOYAGER_METADATA_EXISTS_SQL).Scan(&exists)
+    return exists, err
+}
+
+// Gets the report of the latest completed run of a phase of a migration. Returns false if the
+// phase has not completed.
+func (c *Container) getVoyagerPayload(migrationUuid string, phase int32) (string, bool, error) {
+    exists, err := c.hasVoyagerMetadata()
+    if err != nil || !exists {
+        return "", false, err
+    }
+    payload := ""
+    err = c.Conn.QueryRow(context.Background(), VOYAGER_PAYLOAD_SQL, migrationUuid,
+        phase).Scan(&payload)
+    if err == pgx.ErrNoRows {
+        return "", false, nil
+    }
+    return payload, err == nil, err
+}
+
+// GetMigrations - Get list of the migrations run with YugabyteDB Voyager
+func (c *Container) GetMigrations(ctx echo.Context) error {
+    migrationListResponse := models.MigrationListResponse{
+        Data: []models.Migration{},
+    }
+    exists, err := c.hasVoyagerMetadata()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !exists {
+        return ctx.JSON(http.StatusOK, migrationListResponse)
+    }
+    rows, err := c.Conn.Query(context.Background(), VOYAGER_MIGRATIONS_SQL)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer rows.Close()
+    for rows.Next() {
+        migration := models.Migration{}
+        var phase int32
+        err := rows.Scan(&migration.MigrationUuid, &phase, &migration.InvocationSequence,
+            &migration.DatabaseName, &migration.SchemaName, &migration.SourceDbType,
+            &migration.SourceDbVersion, &migration.SourceDbHost, &migration.SourceDbPort,
+            &migration.Status, &migration.StartedAt, &migration.UpdatedAt)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        migration.Phase = VOYAGER_PHASES[phase]
+        if migration.Phase == "" {
+            migration.Phase = fmt.Sprintf("phase %d", phase)
+        }
+        migrationListResponse.Data = append(migrationListResponse.Data, migration)
+    }
+    if err := rows.Err(); err != nil {
+        return respondWithError(ctx, err)
+    }
+    // Most recently active first
+    sort.SliceStable(migrationListResponse.Data, func(i, j int) bool {
+        return migrationListResponse.Data[i].UpdatedAt > migrationListResponse.Data[j].UpdatedAt
+    })
+    return ctx.JSON(http.StatusOK, migrationListResponse)
+}
+
+// GetMigrationAssessment - Get the assessment of a migration
+func (c *Container) GetMigrationAssessment(ctx echo.Context) error {
+    migrationUuid := ctx.Param("uuid")
+    if !MIGRATION_UUID_REGEX.MatchString(migrationUuid) {
+        return respondError(ctx, http.StatusBadRequest, "invalid migration uuid")
+    }
+    payload, ok, err := c.getVoyagerPayload(migrationUuid, VOYAGER_PHASE_ASSESS_MIGRATION)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !ok {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("migration %s has not been assessed", migrationUuid))
+    }
+    assessment, err := parseMigrationAssessment(payload)
+    if err != nil {
+        return respondError(ctx, http.StatusInternalServerError,
+            fmt.Sprintf("invalid assessment report: %s", err.Error()))
+    }
+    return ctx.JSON(http.StatusOK, models.MigrationAssessmentResponse{
+        Data: assessment,
+    })
+}
+
+// GetMigrationSchemaAnalysis - Get the schema analysis of a migration
+func (c *Container) GetMigrationSchemaAnalysis(ctx echo.Context) error {
+    migrationUuid := ctx.Param("uuid")
+    if !MIGRATION_UUID_REGEX.MatchString(migrationUuid) {
+        return respondError(ctx, http.StatusBadRequest, "invalid migration uuid")
+    }
+    payload, ok, err := c.getVoyagerPayload(migrationUuid, VOYAGER_PHASE_ANALYZE_SCHEMA)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !ok {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("the schema of migration %s has not been analyzed", migrationUuid))
+    }
+    analysis, err := parseMigrationSchemaAnalysis(payload)
+    if err != nil {
+        return respondError(ctx, http.StatusInternalServerError,
+            fmt.Sprintf("invalid schema analysis report: %s", err.Error()))
+    }
+    return ctx.JSON(http.StatusOK, models.MigrationSchemaAnalysisResponse{
+        Data: analysis,
+    })
+}
+
+// GetMigrationProgress - Get the progress of the data import of a migration
+func (c *Container) GetMigrationProgress(ctx echo.Context) error {
+    migrationUuid := ctx.Param("uuid")
+    if !MIGRATION_UUID_REGEX.MatchString(migrationUuid) {
+        return respondError(ctx, http.StatusBadRequest, "invalid migration uuid")
+    }
+    exists := false
+    err := c.Conn.QueryRow(context.Background(), VOYAGER_TABLE_METRICS_EXISTS_SQL).Scan(&exists)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !exists {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("migration %s has not imported any data", migrationUuid))
+    }
+    hasErrorRows := false
+    err = c.Conn.QueryRow(context.Background(), VOYAGER_ERROR_ROWS_EXISTS_SQL).Scan(&hasErrorRows)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    errorRowsColumn := ""
+    if hasErrorRows {
+        errorRowsColumn = ", COALESCE(" + VOYAGER_ERROR_ROWS_COLUMN + ", 0)"
+    }
+    rows, err := c.Conn.Query(context.Background(),
+        fmt.Sprintf(VOYAGER_TABLE_PROGRESS_SQL, errorRowsColumn), migrationUuid,
+        VOYAGER_PHASE_IMPORT_DATA)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    defer rows.Close()
+    tables := []models.MigrationTableProgress{}
+    for rows.Next() {
+        table := models.MigrationTableProgress{}
+        var status int32
+        destinations := []interface{}{&table.SchemaName, &table.TableName, &status,
+            &table.RowsImported, &table.TotalRows, &table.UpdatedAt}
+        if hasErrorRows {
+            table.ErrorRows = new(int64)
+            destinations = append(destinations, table.ErrorRows)
+        }
+        if err := rows.Scan(destinations...); err != nil {
+            return respondWithError(ctx, err)
+        }
+        table.Status = getVoyagerTableStatus(status)
+        tables = append(tables, table)
+    }
+    if err := rows.Err(); err != nil {
+        return respondWithError(ctx, err)
+    }
+    if len(tables) == 0 {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("migration %s has not imported any data", migrationUuid))
+    }
+    var startedAt *int64
+    err = c.Conn.QueryRow(context.Background(), VOYAGER_PHASE_STARTED_AT_SQL, migrationUuid,
+        VOYAGER_PHASE_IMPORT_DATA).Scan(&startedAt)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    progress := getMigrationProgress(tables, startedAt, time.Now().Unix())
+    progress.MigrationUuid = migrationUuid
+    return ctx.JSON(http.StatusOK, models.MigrationProgressResponse{
+        Data: progress,
+    })
+}
+
+// GetSchemaMigrations - Get the history of the schema migration tools in the YSQL databases
+func (c *Container) GetSchemaMigrations(ctx echo.Context) error {
+    limit := SCHEMA_MIGRATIONS_DEFAULT_LIMIT
+    if limitParam := ctx.QueryParam("limit"); limitParam != "" {
+        parsed, err := strconv.Atoi(limitParam)
+        if err != nil || parsed < 0 || parsed > SCHEMA_MIGRATIONS_MAX_LIMIT {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("limit must be between 0 and %d", SCHEMA_MIGRATIONS_MAX_LIMIT))
+        }
+        limit = parsed
+    }
+    databases := []string{}
+    if database := ctx.QueryParam("database"); database != "" {
+        var exists bool
+        err := c.Conn.QueryRow(context.Background(), YSQL_DATABASE_EXISTS_SQL,
+            database).Scan(&exists)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        if !exists {
+            return respondError(ctx, http.StatusNotFound,
+                fmt.Sprintf("database %s not found", database))
+        }
+        databases = append(databases, database)
+    } else {
+        rows, err := c.Conn.Query(context.Background(), YSQL_DATABASES_SQL)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        for rows.Next() {
+            var name string
+            if err := rows.Scan(&name); err != nil {
+                rows.Close()
+                return respondWithError(ctx, err)
+            }
+            databases = append(databases, name)
+        }
+        rows.Close()
+        if err := rows.Err(); err != nil {
+            return respondWithError(ctx, err)
+        }
+    }
+    return ctx.JSON(http.StatusOK, models.SchemaMigrationsResponse{
+        Data: getSchemaMigrations(databases, limit),
+    })
+}
@@ -0,0 +1,1252 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "apiserver/cmd/server/tasks"
+    "errors"
+    "fmt"
+    "math"
+    "net"
+    "net/http"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+
+    "github.com/labstack/echo/v4"
+)
+
+// yugabyted keeps its data, generated certs and certs under this directory by default
+const DEFAULT_YUGABYTED_BASE_DIR string = "~/var"
+
+const PREFLIGHT_DIAL_TIMEOUT = 3 * time.Second
+
+// How often a new tserver is checked for having registered with the masters, which it has
+// timeouts.node_registration to do
+const ADD_NODE_POLL_INTERVAL = 5 * time.Second
+
+// How often the data of a removed tserver is checked for having moved to other tservers,
+// which it has timeouts.node_load_move to do
+const REMOVE_NODE_POLL_INTERVAL = 10 * time.Second
+
+const PROCESS_ACTION_START = "start"
+const PROCESS_ACTION_STOP = "stop"
+const PROCESS_ACTION_RESTART = "restart"
+
+// How long yugabyted gets to restart a stopped process before it is started here
+const PROCESS_RESPAWN_TIMEOUT = 15 * time.Second
+
+type preflightPort struct {
+    name string
+    port int
+}
+
+// Gets the ports that other nodes and clients connect to on a node. New nodes are expected to
+// use the same ports as this one.
+func getPreflightPorts() []preflightPort {
+    upstream := helpers.GetConfig().Upstream
+    return []preflightPort{
+        {"master_rpc", upstream.MasterRpcPort},
+        {"tserver_rpc", upstream.TserverRpcPort},
+        {"master_webserver", upstream.MasterHttpPort},
+        {"tserver_webserver", upstream.TserverHttpPort},
+        {"ysql", helpers.GetConfig().Database.YsqlPort},
+        {"ycql", upstream.YcqlPort},
+    }
+}
+
+// The generated commands are meant to be pasted into a shell, so only plain values are accepted
+var NODE_ADDRESS_REGEX = regexp.MustCompile(`^[A-Za-z0-9.:\-]+$`)
+var CLOUD_LOCATION_REGEX = regexp.MustCompile(`^[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+$`)
+var BASE_DIR_REGEX = regexp.MustCompile(`^[A-Za-z0-9_./~\-]+$`)
+
+// Gets the arguments to yugabyted that start a new node and join it to this cluster
+func getYugabytedStartArgs(address string, cloudLocation string, baseDir string) []string {
+    args := []string{"start", "--advertise_address=" + address, "--join=" + helpers.HOST}
+    if baseDir != "" {
+        args = append(args, "--base_dir="+baseDir)
+    }
+    if cloudLocation != "" {
+        args = append(args, "--cloud_location="+cloudLocation)
+    }
+    if helpers.Secure {
+        args = append(args, "--secure")
+    }
+    return args
+}
+
+// GetNodeJoinCommand - Get the command to join a new node to the cluster
+func (c *Container) GetNodeJoinCommand(ctx echo.Context) error {
+    address := helpers.NormalizeHost(ctx.QueryParam("advertise_address"))
+    if !NODE_ADDRESS_REGEX.MatchString(address) {
+        return respondError(ctx, http.StatusBadRequest, "invalid advertise_address")
+    }
+    cloudLocation := ctx.QueryParam("cloud_location")
+    if cloudLocation != "" && !CLOUD_LOCATION_REGEX.MatchString(cloudLocation) {
+        return respondError(ctx, http.StatusBadRequest,
+            "cloud_location must be of the form cloud.region.zone")
+    }
+    baseDir := ctx.QueryParam("base_dir")
+    if baseDir != "" && !BASE_DIR_REGEX.MatchString(baseDir) {
+        return respondError(ctx, http.StatusBadRequest, "invalid base_dir")
+    }
+
+    joinCommand := models.NodeJoinCommand{
+        JoinAddress:       helpers.HOST,
+        IsSecure:          helpers.Secure,
+        CertSetupCommands: []string{},
+    }
+    if helpers.Secure {
+        baseDirFlag := ""
+        certsBaseDir := DEFAULT_YUGABYTED_BASE_DIR
+        if baseDir != "" {
+            baseDirFlag = " --base_dir=" + baseDir
+            certsBaseDir = baseDir
+        }
+        // The new node needs server certs signed by the root CA that was generated on this node
+        joinCommand.CertSetupCommands = append(joinCommand.CertSetupCommands,
+            fmt.Sprintf("yugabyted cert generate_server_certs --hostnames=%s%s", address,
+                baseDirFlag),
+            fmt.Sprintf("ssh %s mkdir -p %s/certs", address, certsBaseDir),
+            fmt.Sprintf("scp %s/generated_certs/%s/* %s:%s/certs/", certsBaseDir, address,
+                helpers.BracketHost(address), certsBaseDir))
+    }
+    joinCommand.Command = "yugabyted " +
+        strings.Join(getYugabytedStartArgs(address, cloudLocation, baseDir), " ")
+    return ctx.JSON(http.StatusOK, models.NodeJoinCommandResponse{
+        Data: joinCommand,
+    })
+}
+
+// Checks that a port on the host can be reached. Since nothing listens on a prospective node
+// yet, a refused connection means the port is reachable, while a timeout means it is blocked.
+func checkPreflightPort(host string, port preflightPort, future chan models.PreflightCheck) {
+    check := models.PreflightCheck{
+        Name: "port_" + port.name,
+    }
+    address := net.JoinHostPort(host, strconv.Itoa(port.port))
+    conn, err := net.DialTimeout("tcp", address, PREFLIGHT_DIAL_TIMEOUT)
+    if err == nil {
+        conn.Close()
+        check.Status = models.PREFLIGHTCHECKSTATUSENUM_WARN
+        check.Detail = fmt.Sprintf("port %d is reachable but already in use", port.port)
+    } else if errors.Is(err, syscall.ECONNREFUSED) {
+        check.Status = models.PREFLIGHTCHECKSTATUSENUM_PASS
+        check.Detail = fmt.Sprintf("port %d is reachable", port.port)
+    } else {
+        check.Status = models.PREFLIGHTCHECKSTATUSENUM_FAIL
+        check.Detail = fmt.Sprintf("port %d is not reachable: %s", port.port, err.Error())
+    }
+    future <- check
+}
+
+// Counts the cores of a host from its node_exporter metrics. node_cpu_seconds_total has one
+// sample per cpu and mode, so the idle samples are counted.
+func countCpuCores(metrics map[string][]helpers.NodeExporterSample) int {
+    cpuCores := 0
+    for _, sample := range metrics["node_cpu_seconds_total"] {
+        if sample.Label("mode") == "idle" {
+            cpuCores++
+        }
+    }
+    return cpuCores
+}
+
+// Gets the checks that need data from the host itself, from its node_exporter metrics
+func getNodeExporterPreflightChecks(
+    metrics map[string][]helpers.NodeExporterSample) []models.PreflightCheck {
+    thresholds := helpers.GetConfig().Thresholds
+    checks := []models.PreflightCheck{}
+    getValue := func(name string) (float64, bool) {
+        if samples, ok := metrics[name]; ok && len(samples) > 0 {
+            return samples[0].Value, true
+        }
+        return 0, false
+    }
+    missingCheck := func(name string, metric string) models.PreflightCheck {
+        return models.PreflightCheck{
+            Name:   name,
+            Status: models.PREFLIGHTCHECKSTATUSENUM_SKIPPED,
+            Detail: fmt.Sprintf("node_exporter does not report %s", metric),
+        }
+    }
+
+    if syncStatus, ok := getValue("node_timex_sync_status"); !ok {
+        checks = append(checks, missingCheck("clock_sync", "node_timex_sync_status"))
+    } else {
+        offset, _ := getValue("node_timex_offset_seconds")
+        check := models.PreflightCheck{
+            Name:   "clock_sync",
+            Status: models.PREFLIGHTCHECKSTATUSENUM_PASS,
+            Detail: fmt.Sprintf("clock is synchronized with offset %.6fs", offset),
+        }
+        if syncStatus != 1 {
+            check.Status = models.PREFLIGHTCHECKSTATUSENUM_FAIL
+            check.Detail = "clock is not synchronized, enable NTP or chrony"
+        } else if math.Abs(offset) >= thresholds.PreflightMaxClockOffset.Seconds() {
+            check.Status = models.PREFLIGHTCHECKSTATUSENUM_FAIL
+            check.Detail = fmt.Sprintf("clock offset %.6fs exceeds %s", offset,
+                thresholds.PreflightMaxClockOffset)
+        }
+        checks = append(checks, check)
+    }
+
+    // node_exporter runs with the default limits of the host, so its own limit is a good proxy
+    if maxFds, ok := getValue("process_max_fds"); !ok {
+        checks = append(checks, missingCheck("open_files_limit", "process_max_fds"))
+    } else {
+        check := models.PreflightCheck{
+            Name:   "open_files_limit",
+            Status: models.PREFLIGHTCHECKSTATUSENUM_PASS,
+            Detail: fmt.Sprintf("open files limit is %.0f", maxFds),
+        }
+        if maxFds < float64(thresholds.PreflightMinOpenFiles) {
+            check.Status = models.PREFLIGHTCHECKSTATUSENUM_WARN
+            check.Detail = fmt.Sprintf("open files limit is %.0f, at least %d is recommended",
+                maxFds, thresholds.PreflightMinOpenFiles)
+        }
+        checks = append(checks, check)
+    }
+
+    cpuCores := countCpuCores(metrics)
+    if cpuCores == 0 {
+        checks = append(checks, missingCheck("cpu_cores", "node_cpu_seconds_total"))
+    } else {
+        check := models.PreflightCheck{
+            Name:   "cpu_cores",
+            Status: models.PREFLIGHTCHECKSTATUSENUM_PASS,
+            Detail: fmt.Sprintf("host has %d cores", cpuCores),
+        }
+        if cpuCores < thresholds.PreflightMinCpuCores {
+            check.Status = models.PREFLIGHTCHECKSTATUSENUM_FAIL
+            check.Detail = fmt.Sprintf("host has %d cores, at least %d are required", cpuCores,
+                thresholds.PreflightMinCpuCores)
+        }
+        checks = append(checks, check)
+    }
+
+    if memoryBytes, ok := getValue("node_memory_MemTotal_bytes"); !ok {
+        checks = append(checks, missingCheck("memory", "node_memory_MemTotal_bytes"))
+    } else {
+        check := models.PreflightCheck{
+            Name:   "memory",
+            Status: models.PREFLIGHTCHECKSTATUSENUM_PASS,
+            Detail: fmt.Sprintf("host has %.1f GB of memory", memoryBytes/helpers.BYTES_IN_GB),
+        }
+        if memoryBytes < float64(thresholds.PreflightMinMemoryGb*helpers.BYTES_IN_GB) {
+            check.Status = models.PREFLIGHTCHECKSTATUSENUM_FAIL
+            check.Detail = fmt.Sprintf("host has %.1f GB of memory, at least %d GB is required",
+                memoryBytes/helpers.BYTES_IN_GB, thresholds.PreflightMinMemoryGb)
+        }
+        checks = append(checks, check)
+    }
+    return checks
+}
+
+// Gets the check that the release running on the cluster can be downloaded onto the host
+func getVersionPreflightCheck() models.PreflightCheck {
+    check := models.PreflightCheck{
+        Name: "version_availability",
+    }
+    versionFuture := make(chan helpers.VersionInfoFuture)
+    go helpers.GetVersionFuture(helpers.HOST, versionFuture)
+    versionInfo := <-versionFuture
+    if versionInfo.Error != nil {
+        check.Status = models.PREFLIGHTCHECKSTATUSENUM_SKIPPED
+        check.Detail = "could not get the cluster version: " + versionInfo.Error.Error()
+        return check
+    }
+    releaseFuture := make(chan helpers.ReleaseAvailabilityFuture)
+    go helpers.GetReleaseAvailabilityFuture(versionInfo.VersionInfo, releaseFuture)
+    release := <-releaseFuture
+    if release.Error != nil {
+        check.Status = models.PREFLIGHTCHECKSTATUSENUM_WARN
+        check.Detail = fmt.Sprintf("could not check %s: %s", release.Url, release.Error.Error())
+    } else if !release.IsAvailable {
+        check.Status = models.PREFLIGHTCHECKSTATUSENUM_FAIL
+        check.Detail = fmt.Sprintf("version %s is not available at %s",
+            versionInfo.VersionInfo.VersionNumber, release.Url)
+    } else {
+        check.Status = models.PREFLIGHTCHECKSTATUSENUM_PASS
+        check.Detail = fmt.Sprintf("version %s is available at %s",
+            versionInfo.VersionInfo.VersionNumber, release.Url)
+    }
+    return check
+}
+
+// RunPreflightChecks - Run preflight checks against a prospective node
+func (c *Container) RunPreflightChecks(ctx echo.Context) error {
+    preflightSpec := models.PreflightSpec{}
+    if err := ctx.Bind(&preflightSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    preflightSpec.Host = helpers.NormalizeHost(preflightSpec.Host)
+    if !NODE_ADDRESS_REGEX.MatchString(preflightSpec.Host) {
+        return respondError(ctx, http.StatusBadRequest, "invalid host")
+    }
+    if preflightSpec.NodeExporterPort == 0 {
+        preflightSpec.NodeExporterPort =
+            int32(helpers.GetConfig().Upstream.NodeExporterPort)
+    }
+
+    portFutures := []chan models.PreflightCheck{}
+    for _, port := range getPreflightPorts() {
+        portFuture := make(chan models.PreflightCheck)
+        portFutures = append(portFutures, portFuture)
+        go checkPreflightPort(preflightSpec.Host, port, portFuture)
+    }
+    nodeExporterFuture := make(chan helpers.NodeExporterMetricsFuture)
+    go helpers.GetNodeExporterMetricsFuture(preflightSpec.Host, preflightSpec.NodeExporterPort,
+        nodeExporterFuture)
+    versionCheckFuture := make(chan models.PreflightCheck)
+    go func() {
+        versionCheckFuture <- getVersionPreflightCheck()
+    }()
+
+    report := models.PreflightReport{
+        Host:   preflightSpec.Host,
+        Checks: []models.PreflightCheck{},
+    }
+    for _, portFuture := range portFutures {
+        report.Checks = append(report.Checks, <-portFuture)
+    }
+    nodeExporterMetrics := <-nodeExporterFuture
+    if nodeExporterMetrics.Error != nil {
+        for _, name := range []string{"clock_sync", "open_files_limit", "cpu_cores", "memory"} {
+            report.Checks = append(report.Checks, models.PreflightCheck{
+                Name:   name,
+                Status: models.PREFLIGHTCHECKSTATUSENUM_SKIPPED,
+                Detail: "node_exporter is not reachable: " + nodeExporterMetrics.Error.Error(),
+            })
+        }
+    } else {
+        report.Checks = append(report.Checks,
+            getNodeExporterPreflightChecks(nodeExporterMetrics.Metrics)...)
+    }
+    report.Checks = append(report.Checks, <-versionCheckFuture)
+
+    report.Passed = true
+    for _, check := range report.Checks {
+        if check.Status == models.PREFLIGHTCHECKSTATUSENUM_FAIL {
+            report.Passed = false
+        }
+    }
+    return ctx.JSON(http.StatusOK, models.PreflightReportResponse{
+        Data: report,
+    })
+}
+
+// Gets the hosts of the tservers in the cluster
+func getTserverHosts() (map[string]bool, error) {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServers := <-tabletServersFuture
+    if tabletServers.Error != nil {
+        return nil, tabletServers.Error
+    }
+    hosts := map[string]bool{}
+    for _, host := range helpers.GetNodesList(tabletServers) {
+        hosts[host] = true
+    }
+    return hosts, nil
+}
+
+// Gets the hosts of the masters in the cluster
+func getMasterHosts() (map[string]bool, error) {
+    mastersFuture := make(chan helpers.MastersFuture)
+    go helpers.GetMastersFuture(helpers.HOST, mastersFuture)
+    masters := <-mastersFuture
+    if masters.Error != nil {
+        return nil, masters.Error
+    }
+    hosts := map[string]bool{}
+    for _, master := range masters.Masters {
+        for _, address := range master.Registration.PrivateRpcAddresses {
+            hosts[helpers.NormalizeHost(address.Host)] = true
+        }
+    }
+    return hosts, nil
+}
+
+// Gets the hosts of the masters or of the tservers in the cluster, the only hosts that the
+// handlers of a node make requests to
+func getProcessHosts(process string) (map[string]bool, error) {
+    if process == helpers.MASTER_PROCESS {
+        return getMasterHosts()
+    }
+    return getTserverHosts()
+}
+
+// A master or tserver process of a node
+type serverProcess struct {
+    host string
+    process string
+}
+
+// Gets the masters and tservers of the cluster, or only those of one process, sorted by host
+func getServerProcesses(process string) ([]serverProcess, error) {
+    processes := []serverProcess{}
+    for _, candidate := range []string{helpers.MASTER_PROCESS, helpers.TSERVER_PROCESS} {
+        if process != "" && process != candidate {
+            continue
+        }
+        getHosts := getTserverHosts
+        if candidate == helpers.MASTER_PROCESS {
+            getHosts = getMasterHosts
+        }
+        hosts, err := getHosts()
+        if err != nil {
+            return nil, err
+        }
+        sortedHosts := []string{}
+        for host := range hosts {
+            sortedHosts = append(sortedHosts, host)
+        }
+        sort.Strings(sortedHosts)
+        for _, host := range sortedHosts {
+            processes = append(processes, serverProcess{host: host, process: candidate})
+        }
+    }
+    return processes, nil
+}
+
+// AddNode - Add a node to the cluster
+func (c *Container) AddNode(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.NodeManagement {
+        return respondError(ctx, http.StatusForbidden, "node management is disabled")
+    }
+    nodeSpec := models.NodeSpec{}
+    if err := ctx.Bind(&nodeSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    nodeSpec.AdvertiseAddress = helpers.NormalizeHost(nodeSpec.AdvertiseAddress)
+    if !NODE_ADDRESS_REGEX.MatchString(nodeSpec.AdvertiseAddress) {
+        return respondError(ctx, http.StatusBadRequest, "invalid advertise_address")
+    }
+    if nodeSpec.CloudLocation != "" && !CLOUD_LOCATION_REGEX.MatchString(nodeSpec.CloudLocation) {
+        return respondError(ctx, http.StatusBadRequest,
+            "cloud_location must be of the form cloud.region.zone")
+    }
+    // Each node on a host needs its own base directory
+    if !BASE_DIR_REGEX.MatchString(nodeSpec.BaseDir) {
+        return respondError(ctx, http.StatusBadRequest, "invalid base_dir")
+    }
+    if helpers.Secure {
+        return respondError(ctx, http.StatusBadRequest, "nodes of a secure cluster need "+
+            "certificates from this node, use the join command to add them")
+    }
+    isLocal, err := helpers.IsLocalAddress(nodeSpec.AdvertiseAddress)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    if !isLocal {
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf("%s is not an address of this "+
+            "host, use the join command to add it", nodeSpec.AdvertiseAddress))
+    }
+    tserverHosts, err := getTserverHosts()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if tserverHosts[nodeSpec.AdvertiseAddress] {
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("%s is already part of the cluster", nodeSpec.AdvertiseAddress))
+    }
+
+    // On this host, every port of the new node must be free
+    portFutures := []chan models.PreflightCheck{}
+    for _, port := range getPreflightPorts() {
+        portFuture := make(chan models.PreflightCheck)
+        portFutures = append(portFutures, portFuture)
+        go checkPreflightPort(nodeSpec.AdvertiseAddress, port, portFuture)
+    }
+    failedChecks := []string{}
+    for _, portFuture := range portFutures {
+        if check := <-portFuture; check.Status != models.PREFLIGHTCHECKSTATUSENUM_PASS {
+            failedChecks = append(failedChecks, check.Detail)
+        }
+    }
+    if len(failedChecks) > 0 {
+        return respondError(ctx, http.StatusBadRequest,
+            "preflight checks failed: "+strings.Join(failedChecks, "; "))
+    }
+
+    address := nodeSpec.AdvertiseAddress
+    args := getYugabytedStartArgs(address, nodeSpec.CloudLocation, nodeSpec.BaseDir)
+    task, err := c.tasks.Submit("add_node", func(task *tasks.Task) error {
+        task.Progress("starting node %s", address)
+        if _, err := helpers.RunYugabyted(args...); err != nil {
+            return err
+        }
+        task.Progress("waiting for the tserver on %s to register", address)
+        timeout := helpers.GetConfig().Timeouts.NodeRegistration
+        deadline := time.Now().Add(timeout)
+        for time.Now().Before(deadline) {
+            tserverHosts, err := getTserverHosts()
+            if err == nil && tserverHosts[address] {
+                task.Progress("node %s joined the cluster", address)
+                return nil
+            }
+            time.Sleep(ADD_NODE_POLL_INTERVAL)
+        }
+        return fmt.Errorf("the tserver on %s did not register within %s", address, timeout)
+    })
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "add_node", "address", address, "base_dir", nodeSpec.BaseDir,
+        "cloud_location", nodeSpec.CloudLocation, "task_id", task.Id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// RemoveNode - Remove a node from the cluster
+func (c *Container) RemoveNode(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.NodeManagement {
+        return respondError(ctx, http.StatusForbidden, "node management is disabled")
+    }
+    address := helpers.NormalizeHost(ctx.Param("address"))
+    if !NODE_ADDRESS_REGEX.MatchString(address) {
+        return respondError(ctx, http.StatusBadRequest, "invalid address")
+    }
+    // The processes of the node are stopped once its data moved, which is only possible on
+    // this host
+    baseDir := ctx.QueryParam("base_dir")
+    if baseDir == "" {
+        return respondError(ctx, http.StatusBadRequest, "base_dir is required to stop the "+
+            "node, nodes on other hosts are removed by stopping them there")
+    }
+    if !BASE_DIR_REGEX.MatchString(baseDir) {
+        return respondError(ctx, http.StatusBadRequest, "invalid base_dir")
+    }
+    if address == helpers.HOST {
+        return respondError(ctx, http.StatusBadRequest,
+            "cannot remove the node that this server is connected to")
+    }
+    tserverHosts, err := getTserverHosts()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !tserverHosts[address] {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("node %s not found", address))
+    }
+    masterHosts, err := getMasterHosts()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if masterHosts[address] {
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf("node %s runs a master, "+
+            "removing it would shrink the master quorum", address))
+    }
+    isLocal, err := helpers.IsLocalAddress(address)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    if !isLocal {
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf("%s is not an address "+
+            "of this host, its processes must be stopped on it", address))
+    }
+    // The data can only move off the node while enough other tservers are alive to hold a
+    // replica of every tablet
+    liveness, err := getTserverLiveness()
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    replicationFactor, err := getReplicationFactor(len(liveness))
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    othersAlive := 0
+    for host, alive := range liveness {
+        if host != address && alive {
+            othersAlive++
+        }
+    }
+    if othersAlive < replicationFactor {
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf("removing node %s would "+
+            "leave %d alive tservers, fewer than the replication factor of %d", address,
+            othersAlive, replicationFactor))
+    }
+
+    task, err := c.tasks.Submit("remove_node", func(task *tasks.Task) error {
+        tserverAddress := net.JoinHostPort(address,
+            strconv.Itoa(helpers.GetConfig().Upstream.TserverRpcPort))
+        task.Progress("blacklisting tserver %s", tserverAddress)
+        if _, err := helpers.RunYbAdmin("change_blacklist", "ADD", tserverAddress); err != nil {
+            return err
+        }
+        err := moveDataAndStopNode(task, address, baseDir)
+        if err == nil {
+            return nil
+        }
+        // The node stays in the cluster, so it takes data again
+        task.Progress("removing tserver %s from the blacklist", tserverAddress)
+        if _, rollbackErr := helpers.RunYbAdmin("change_blacklist", "REMOVE",
+            tserverAddress); rollbackErr != nil {
+            return fmt.Errorf("%w, and removing tserver %s from the blacklist failed: %s", err,
+                tserverAddress, rollbackErr.Error())
+        }
+        return err
+    })
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "remove_node", "address", address, "base_dir", baseDir,
+        "task_id", task.Id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// Waits for the data to move off a blacklisted node, then stops its processes
+func moveDataAndStopNode(task *tasks.Task, address string, baseDir string) error {
+    timeout := helpers.GetConfig().Timeouts.NodeLoadMove
+    deadline := time.Now().Add(timeout)
+    lastPercent := -1.0
+    for {
+        percent, err := helpers.GetLoadMoveCompletion()
+        if err != nil {
+            return err
+        }
+        if percent != lastPercent {
+            task.Progress("moved %.1f%% of the data off %s", percent, address)
+            lastPercent = percent
+        }
+        if percent >= 100 {
+            break
+        }
+        if time.Now().After(deadline) {
+            return fmt.Errorf("data was not moved off %s within %s", address, timeout)
+        }
+        time.Sleep(REMOVE_NODE_POLL_INTERVAL)
+    }
+    task.Progress("stopping node %s", address)
+    if _, err := helpers.RunYugabyted("stop", "--base_dir="+baseDir); err != nil {
+        return err
+    }
+    task.Progress("node %s was removed from the cluster", address)
+    return nil
+}
+
+// Gets the liveness of the tservers in the cluster, by host
+func getTserverLiveness() (map[string]bool, error) {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServers := <-tabletServersFuture
+    if tabletServers.Error != nil {
+        return nil, tabletServers.Error
+    }
+    liveness := map[string]bool{}
+    for _, obj := range tabletServers.Tablets {
+        for hostport, tabletServer := range obj {
+            if host, err := helpers.GetHostFromAddress(hostport); err == nil {
+                liveness[host] = tabletServer.Status == "ALIVE"
+            }
+        }
+    }
+    return liveness, nil
+}
+
+// Gets the liveness of the masters in the cluster, by host
+func getMasterLiveness() (map[string]bool, error) {
+    mastersFuture := make(chan helpers.MastersFuture)
+    go helpers.GetMastersFuture(helpers.HOST, mastersFuture)
+    masters := <-mastersFuture
+    if masters.Error != nil {
+        return nil, masters.Error
+    }
+    liveness := map[string]bool{}
+    for _, master := range masters.Masters {
+        for _, address := range master.Registration.PrivateRpcAddresses {
+            liveness[helpers.NormalizeHost(address.Host)] = master.Error == nil
+        }
+    }
+    return liveness, nil
+}
+
+func getProcessLiveness(process string) (map[string]bool, error) {
+    if process == helpers.MASTER_PROCESS {
+        return getMasterLiveness()
+    }
+    return getTserverLiveness()
+}
+
+// Gets the replication factor of the primary cluster. The cluster config only has one when it
+// was set explicitly, otherwise the default one of the nodes is assumed.
+func getLiveReplicationFactor(clusterConfig helpers.ClusterConfigStruct, tservers int) int {
+    replicationFactor := clusterConfig.ReplicationInfo.LiveReplicas.NumReplicas
+    if replicationFactor == 0 {
+        replicationFactor = int(math.Min(3, float64(tservers)))
+    }
+    return replicationFactor
+}
+
+func getReplicationFactor(tservers int) (int, error) {
+    clusterConfigFuture := make(chan helpers.ClusterConfigFuture)
+    go helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture)
+    clusterConfig := <-clusterConfigFuture
+    if clusterConfig.Error != nil {
+        return 0, clusterConfig.Error
+    }
+    return getLiveReplicationFactor(clusterConfig.ClusterConfig, tservers), nil
+}
+
+// Checks that taking the process of the node down keeps the cluster available. Returns why it
+// would not.
+func getProcessDownRisk(process string, name string, liveness map[string]bool) (string, error) {
+    othersDown := 0
+    for host, alive := range liveness {
+        if host != name && !alive {
+            othersDown++
+        }
+    }
+    if process == helpers.MASTER_PROCESS {
+        majority := len(liveness)/2 + 1
+        if len(liveness)-othersDown-1 < majority {
+            return fmt.Sprintf("the masters would lose their quorum, %d of %d masters are "+
+                "already down", othersDown, len(liveness)), nil
+        }
+        return "", nil
+    }
+
+    clusterConfigFuture := make(chan helpers.ClusterConfigFuture)
+    go helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture)
+    healthCheckFuture := make(chan helpers.HealthCheckFuture)
+    go helpers.GetHealthCheckFuture(helpers.HOST, healthCheckFuture)
+    clusterConfig := <-clusterConfigFuture
+    healthCheck := <-healthCheckFuture
+    if clusterConfig.Error != nil {
+        return "", clusterConfig.Error
+    }
+    if healthCheck.Error != nil {
+        return "", healthCheck.Error
+    }
+    replicationFactor := getLiveReplicationFactor(clusterConfig.ClusterConfig, len(liveness))
+    faultTolerance := (replicationFactor - 1) / 2
+    if othersDown+1 > faultTolerance {
+        return fmt.Sprintf("tablets would become unavailable, the cluster tolerates %d "+
+            "tservers down and %d are already down", faultTolerance, othersDown), nil
+    }
+    if len(healthCheck.HealthCheck.UnderReplicatedTablets) > 0 {
+        return fmt.Sprintf("%d tablets are under-replicated",
+            len(healthCheck.HealthCheck.UnderReplicatedTablets)), nil
+    }
+    return "", nil
+}
+
+// Waits until the masters see the process of the node as alive again
+func waitForProcessAlive(process string, name string) error {
+    timeout := helpers.GetConfig().Timeouts.ProcessAlive
+    deadline := time.Now().Add(timeout)
+    for time.Now().Before(deadline) {
+        liveness, err := getProcessLiveness(process)
+        if err == nil && liveness[name] {
+            return nil
+        }
+        time.Sleep(ADD_NODE_POLL_INTERVAL)
+    }
+    return fmt.Errorf("the %s on %s did not come back within %s", process, name, timeout)
+}
+
+// ManageServerProcess - Start, stop or restart a server process of a node
+func (c *Container) ManageServerProcess(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.NodeManagement {
+        return respondError(ctx, http.StatusForbidden, "node management is disabled")
+    }
+    name := helpers.NormalizeHost(ctx.Param("name"))
+    process := ctx.Param("process")
+    action := ctx.Param("action")
+    if !NODE_ADDRESS_REGEX.MatchString(name) {
+        return respondError(ctx, http.StatusBadRequest, "invalid name")
+    }
+    if process != helpers.MASTER_PROCESS && process != helpers.TSERVER_PROCESS {
+        return respondError(ctx, http.StatusBadRequest, "process must be master or tserver")
+    }
+    if action != PROCESS_ACTION_START && action != PROCESS_ACTION_STOP &&
+        action != PROCESS_ACTION_RESTART {
+        return respondError(ctx, http.StatusBadRequest, "action must be start, stop or restart")
+    }
+    processActionSpec := models.ProcessActionSpec{}
+    // The body is optional, the first request has no token yet
+    if ctx.Request().ContentLength != 0 {
+        if err := ctx.Bind(&processActionSpec); err != nil {
+            return respondError(ctx, http.StatusBadRequest, "invalid request body")
+        }
+    }
+    if action == PROCESS_ACTION_STOP && name == helpers.HOST {
+        return respondError(ctx, http.StatusBadRequest,
+            "cannot stop a process of the node that this server is connected to")
+    }
+    isLocal, err := helpers.IsLocalAddress(name)
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    if !isLocal {
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf("%s is not an address of this "+
+            "host, its processes must be managed on it", name))
+    }
+    liveness, err := getProcessLiveness(process)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if _, ok := liveness[name]; !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("no %s of the cluster runs on %s",
+            process, name))
+    }
+    running, err := helpers.IsLocalProcessRunning(process, name)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if action == PROCESS_ACTION_START && running {
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("the %s on %s is already running", process, name))
+    }
+    if action != PROCESS_ACTION_START {
+        if !running {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("the %s on %s is not running", process, name))
+        }
+        risk, err := getProcessDownRisk(process, name, liveness)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        if risk != "" {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("cannot %s the %s on %s: %s", action, process, name, risk))
+        }
+    }
+
+    description := fmt.Sprintf("%s the %s on %s", action, process, name)
+    if !c.confirmations.consume(processActionSpec.ConfirmationToken, description) {
+        token, expiresAt, err := c.confirmations.issue(description)
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        return ctx.JSON(http.StatusConflict, models.ConfirmationRequiredResponse{
+            Data: models.ConfirmationRequired{
+                ConfirmationToken: token,
+                ExpireTimestamp:   expiresAt.Unix(),
+                Description:       description,
+            },
+        })
+    }
+
+    task, err := c.tasks.Submit(action+"_"+process, func(task *tasks.Task) error {
+        switch action {
+        case PROCESS_ACTION_STOP:
+            task.Progress("stopping the %s on %s", process, name)
+            if err := helpers.StopLocalProcess(process, name); err != nil {
+                return err
+            }
+            task.Progress("the %s on %s was stopped", process, name)
+            return nil
+        case PROCESS_ACTION_START:
+            task.Progress("starting the %s on %s", process, name)
+            if err := helpers.StartLocalProcess(process, name); err != nil {
+                return err
+            }
+        case PROCESS_ACTION_RESTART:
+            task.Progress("restarting the %s on %s", process, name)
+            if err := helpers.RestartLocalProcess(process, name,
+                PROCESS_RESPAWN_TIMEOUT); err != nil {
+                return err
+            }
+        }
+        task.Progress("waiting for the %s on %s to come back", process, name)
+        if err := waitForProcessAlive(process, name); err != nil {
+            return err
+        }
+        task.Progress("the %s on %s is alive", process, name)
+        return nil
+    })
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, action+"_process", "name", name, "process", process, "task_id", task.Id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// GetNodeRpcz - Get the RPCs in progress on a node
+func (c *Container) GetNodeRpcz(ctx echo.Context) error {
+    name := helpers.NormalizeHost(ctx.Param("name"))
+    if !NODE_ADDRESS_REGEX.MatchString(name) {
+        return respondError(ctx, http.StatusBadRequest, "invalid name")
+    }
+    process := ctx.QueryParam("process")
+    if process == "" {
+        process = helpers.TSERVER_PROCESS
+    }
+    if process != helpers.MASTER_PROCESS && process != helpers.TSERVER_PROCESS {
+        return respondError(ctx, http.StatusBadRequest, "process must be master or tserver")
+    }
+    filter, err := getRpczFilter(ctx.QueryParam("direction"), ctx.QueryParam("min_elapsed_ms"))
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    hosts, err := getProcessHosts(process)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !hosts[name] {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("no %s found on %s", process,
+            name))
+    }
+    future := make(chan helpers.RpczFuture)
+    go helpers.GetRpczFuture(name, process == helpers.MASTER_PROCESS, future)
+    response := <-future
+    if response.Error != nil {
+        return respondWithError(ctx, fmt.Errorf("failed to get the rpcz of the %s on %s: %w",
+            process, name, response.Error))
+    }
+    calls := getRpcCalls(serverProcess{host: name, process: process}, response.Rpcz, filter)
+    return ctx.JSON(http.StatusOK, models.RpczResponse{
+        Data: newRpcz(calls, 0),
+    })
+}
+
+// GetClusterRpcz - Get the RPCs in progress on all nodes
+func (c *Container) GetClusterRpcz(ctx echo.Context) error {
+    process := ctx.QueryParam("process")
+    if process != "" && process != helpers.MASTER_PROCESS && process != helpers.TSERVER_PROCESS {
+        return respondError(ctx, http.StatusBadRequest, "process must be master or tserver")
+    }
+    filter, err := getRpczFilter(ctx.QueryParam("direction"), ctx.QueryParam("min_elapsed_ms"))
+    if err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    processes, err := getServerProcesses(process)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.RpczResponse{
+        Data: getRpcz(processes, filter),
+    })
+}
+
+// GetNodeThreadz - Get the threads of a node grouped by stack
+func (c *Container) GetNodeThreadz(ctx echo.Context) error {
+    name := helpers.NormalizeHost(ctx.Param("name"))
+    if !NODE_ADDRESS_REGEX.MatchString(name) {
+        return respondError(ctx, http.StatusBadRequest, "invalid name")
+    }
+    process := ctx.QueryParam("process")
+    if process == "" {
+        process = helpers.TSERVER_PROCESS
+    }
+    if process != helpers.MASTER_PROCESS && process != helpers.TSERVER_PROCESS {
+        return respondError(ctx, http.StatusBadRequest, "process must be master or tserver")
+    }
+    hosts, err := getProcessHosts(process)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !hosts[name] {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("no %s found on %s", process,
+            name))
+    }
+    future := make(chan helpers.ThreadzFuture)
+    go helpers.GetThreadzFuture(name, process == helpers.MASTER_PROCESS, future)
+    response := <-future
+    if response.Error != nil {
+        return respondWithError(ctx, fmt.Errorf("failed to get the threadz of the %s on %s: %w",
+            process, name, response.Error))
+    }
+    threads := map[serverProcess][]helpers.ThreadzThread{
+        {host: name, process: process}: response.Threads,
+    }
+    return ctx.JSON(http.StatusOK, models.ThreadzResponse{
+        Data: newThreadz(threads, 0),
+    })
+}
+
+// GetNodeVersion - Get the versions and builds of the server processes of a node
+func (c *Container) GetNodeVersion(ctx echo.Context) error {
+    name := helpers.NormalizeHost(ctx.Param("name"))
+    if !NODE_ADDRESS_REGEX.MatchString(name) {
+        return respondError(ctx, http.StatusBadRequest, "invalid name")
+    }
+    fanOut := newFanOutLimiter()
+    tabletServersFuture := make(chan helpers.TabletServersFuture, 1)
+    mastersFuture := make(chan helpers.MastersFuture, 1)
+    nodeExporterFuture := make(chan helpers.NodeExporterMetricsFuture, 1)
+    fanOut.goCall(func() { helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture) })
+    fanOut.goCall(func() { helpers.GetMastersFuture(helpers.HOST, mastersFuture) })
+    fanOut.goCall(func() {
+        helpers.GetNodeExporterMetricsFuture(name,
+            int32(helpers.GetConfig().Upstream.NodeExporterPort), nodeExporterFuture)
+    })
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return respondWithError(ctx, tabletServersResponse.Error)
+    }
+    mastersResponse := <-mastersFuture
+    if mastersResponse.Error != nil {
+        return respondWithError(ctx, mastersResponse.Error)
+    }
+    // Only the server processes that the node runs are asked for their version
+    hasMaster, hasTserver := false, false
+    for _, master := range mastersResponse.Masters {
+        for _, address := range master.Registration.PrivateRpcAddresses {
+            hasMaster = hasMaster || helpers.NormalizeHost(address.Host) == name
+        }
+    }
+    for _, cluster := range tabletServersResponse.Tablets {
+        for address := range cluster {
+            host, err := helpers.GetHostFromAddress(address)
+            hasTserver = hasTserver || (err == nil && helpers.NormalizeHost(host) == name)
+        }
+    }
+    if !hasMaster && !hasTserver {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("node %s not found", name))
+    }
+    masterVersionFuture := make(chan helpers.VersionInfoFuture, 1)
+    tserverVersionFuture := make(chan helpers.VersionInfoFuture, 1)
+    if hasMaster {
+        fanOut.goCall(func() {
+            helpers.GetServerVersionFuture(name, true, masterVersionFuture)
+        })
+    }
+    if hasTserver {
+        fanOut.goCall(func() {
+            helpers.GetServerVersionFuture(name, false, tserverVersionFuture)
+        })
+    }
+    nodeVersion := models.NodeVersion{
+        Name: name,
+        Architecture: getNodeArchitecture(<-nodeExporterFuture),
+        Servers: []models.NodeServerVersion{},
+    }
+    if hasMaster {
+        nodeVersion.Servers = append(nodeVersion.Servers,
+            getNodeServerVersion(helpers.MASTER_PROCESS, <-masterVersionFuture))
+    }
+    if hasTserver {
+        nodeVersion.Servers = append(nodeVersion.Servers,
+            getNodeServerVersion(helpers.TSERVER_PROCESS, <-tserverVersionFuture))
+    }
+    return ctx.JSON(http.StatusOK, models.NodeVersionResponse{
+        Data: nodeVersion,
+    })
+}
+
+// GetClusterThreadz - Get the threads of all nodes grouped by stack
+func (c *Container) GetClusterThreadz(ctx echo.Context) error {
+    process := ctx.QueryParam("process")
+    if process != "" && process != helpers.MASTER_PROCESS && process != helpers.TSERVER_PROCESS {
+        return respondError(ctx, http.StatusBadRequest, "process must be master or tserver")
+    }
+    processes, err := getServerProcesses(process)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    return ctx.JSON(http.StatusOK, models.ThreadzResponse{
+        Data: getThreadz(processes),
+    })
+}
+
+// SetNodeMaintenance - Put a node in maintenance or take it out
+func (c *Container) SetNodeMaintenance(ctx echo.Context) error {
+    name := helpers.NormalizeHost(ctx.Param("name"))
+    if !NODE_ADDRESS_REGEX.MatchString(name) {
+        return respondError(ctx, http.StatusBadRequest, "invalid name")
+    }
+    nodeMaintenanceSpec := models.NodeMaintenanceSpec{}
+    if err := ctx.Bind(&nodeMaintenanceSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if nodeMaintenanceSpec.Enabled && (nodeMaintenanceSpec.DurationMinutes < 1 ||
+        nodeMaintenanceSpec.DurationMinutes > NODE_MAINTENANCE_MAX_DURATION_MINUTES) {
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("duration_minutes must be between 1 and %d",
+                NODE_MAINTENANCE_MAX_DURATION_MINUTES))
+    }
+    if err := validateMetadataText("reason", nodeMaintenanceSpec.Reason,
+        MAINTENANCE_MAX_REASON_LENGTH); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return respondWithError(ctx, tabletServersResponse.Error)
+    }
+    zone, found := "", false
+    for _, cluster := range tabletServersResponse.Tablets {
+        for address, tabletServer := range cluster {
+            host, err := helpers.GetHostFromAddress(address)
+            if err == nil && helpers.NormalizeHost(host) == name {
+                zone, found = getMaintenanceZone(tabletServer), true
+            }
+        }
+    }
+    if !found {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("node %s not found", name))
+    }
+    if nodeMaintenanceSpec.Enabled {
+        createdBy := ""
+        if existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session); ok {
+            createdBy = existing.username
+        }
+        endTime := time.Now().Add(
+            time.Duration(nodeMaintenanceSpec.DurationMinutes) * time.Minute).Unix()
+        if _, err := c.maintenance.setNodeMaintenance(name, endTime,
+            nodeMaintenanceSpec.Reason, createdBy); err != nil {
+            return respondWithError(ctx, err)
+        }
+    } else if err := c.maintenance.endNodeMaintenance(name); err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "set_node_maintenance", "node", name, "enabled",
+        nodeMaintenanceSpec.Enabled, "duration_minutes", nodeMaintenanceSpec.DurationMinutes)
+    // The node can still be covered by the windows of its zone or of the cluster
+    maintenanceUntil := c.maintenance.coveredUntil(name, zone, time.Now().Unix())
+    return ctx.JSON(http.StatusOK, models.NodeMaintenanceResponse{
+        Data: models.NodeMaintenance{
+            Name: name,
+            UnderMaintenance: maintenanceUntil > 0,
+            MaintenanceUntil: maintenanceUntil,
+        },
+    })
+}
+
+// GetProfiles - Get list of collected profiles
+func (c *Container) GetProfiles(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, models.ProfileListResponse{
+        Data: c.profiles.list(),
+    })
+}
+
+// CreateProfile - Collect a CPU or heap profile from a node
+func (c *Container) CreateProfile(ctx echo.Context) error {
+    if !helpers.GetConfig().Features.Profiling {
+        return respondError(ctx, http.StatusForbidden, "profiling is disabled")
+    }
+    profileSpec := models.ProfileSpec{}
+    if err := ctx.Bind(&profileSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    name := helpers.NormalizeHost(profileSpec.Node)
+    if !NODE_ADDRESS_REGEX.MatchString(name) {
+        return respondError(ctx, http.StatusBadRequest, "invalid node")
+    }
+    if profileSpec.Process == "" {
+        profileSpec.Process = helpers.TSERVER_PROCESS
+    }
+    if profileSpec.Process != helpers.MASTER_PROCESS &&
+        profileSpec.Process != helpers.TSERVER_PROCESS {
+        return respondError(ctx, http.StatusBadRequest, "process must be master or tserver")
+    }
+    maxSeconds := helpers.GetConfig().Profiles.MaxSeconds
+    switch profileSpec.Kind {
+    case helpers.PROFILE_KIND_CPU:
+        if profileSpec.Seconds == 0 {
+            profileSpec.Seconds = PROFILE_DEFAULT_SECONDS
+        }
+        if profileSpec.Seconds < 1 || int(profileSpec.Seconds) > maxSeconds {
+            return respondError(ctx, http.StatusBadRequest,
+                fmt.Sprintf("seconds must be between 1 and %d", maxSeconds))
+        }
+    case helpers.PROFILE_KIND_HEAP:
+        profileSpec.Seconds = 0
+    default:
+        return respondError(ctx, http.StatusBadRequest, "kind must be cpu or heap")
+    }
+    hosts, err := getProcessHosts(profileSpec.Process)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    if !hosts[name] {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("no %s found on %s", profileSpec.Process, name))
+    }
+
+    task, err := c.tasks.Submit("collect_profile", func(task *tasks.Task) error {
+        id := task.Info().Id
+        path, err := c.profiles.getPath(id)
+        if err != nil {
+            return err
+        }
+        task.Progress("collecting a %s profile of the %s on %s", profileSpec.Kind,
+            profileSpec.Process, name)
+        isMaster := profileSpec.Process == helpers.MASTER_PROCESS
+        size, err := helpers.DownloadProfile(name, isMaster, profileSpec.Kind,
+            int(profileSpec.Seconds), path)
+        if err != nil {
+            return err
+        }
+        task.Progress("collected %d bytes", size)
+        // The profile can still be downloaded if no flame graph can be made of it
+        hasFlameGraph := false
+        if profileSpec.Kind == helpers.PROFILE_KIND_CPU {
+            if err := foldCpuProfileFile(name, isMaster, path); err != nil {
+                task.Progress("failed to make a flame graph of the profile: %s", err.Error())
+            } else {
+                hasFlameGraph = true
+            }
+        }
+        c.profiles.add(models.Profile{
+            Id: id,
+            Node: name,
+            Process: profileSpec.Process,
+            Kind: profileSpec.Kind,
+            Seconds: profileSpec.Seconds,
+            SizeBytes: size,
+            HasFlameGraph: hasFlameGraph,
+            Timestamp: time.Now().Unix(),
+        }, path)
+        return nil
+    })
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "collect_profile", "node", name, "process", profileSpec.Process,
+        "kind", profileSpec.Kind, "seconds", profileSpec.Seconds, "task_id", task.Id)
+    return ctx.JSON(http.StatusAccepted, models.TaskResponse{
+        Data: task,
+    })
+}
+
+// DownloadProfile - Download a profile
+func (c *Container) DownloadProfile(ctx echo.Context) error {
+    id := ctx.Param("id")
+    profile, path, ok := c.profiles.get(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("profile %s not found", id))
+    }
+    return ctx.Attachment(path, fmt.Sprintf("%s-%s-%s-%s%s", profile.Node, profile.Process,
+        profile.Kind, time.Unix(profile.Timestamp, 0).UTC().Format("20060102T150405Z"),
+        PROFILE_EXTENSION))
+}
+
+// GetProfileFlameGraph - Get the flame graph of a CPU profile
+func (c *Container) GetProfileFlameGraph(ctx echo.Context) error {
+    id := ctx.Param("id")
+    profile, path, ok := c.profiles.get(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("profile %s not found", id))
+    }
+    if !profile.HasFlameGraph {
+        return respondError(ctx, http.StatusNotFound,
+            fmt.Sprintf("profile %s has no flame graph", id))
+    }
+    switch ctx.QueryParam("format") {
+    case "", "json":
+        flameGraph, err := readFlameGraph(getFoldedStacksPath(path))
+        if err != nil {
+            return respondWithError(ctx, err)
+        }
+        return ctx.JSON(http.StatusOK, models.FlameGraphResponse{
+            Data: flameGraph,
+        })
+    case "folded":
+        ctx.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+        return ctx.File(getFoldedStacksPath(path))
+    default:
+        return respondError(ctx, http.StatusBadRequest, "format must be json or folded")
+    }
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/models"
+    "bytes"
+    "fmt"
+    "html/template"
+    "net/http"
+    "time"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Page of a report, for reading it in a browser or sending it by email
+var reportHtmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+    "time": func(timestamp int64) string {
+        return time.Unix(timestamp, 0).UTC().Format(time.RFC1123)
+    },
+    "round": func(value float64) string {
+        return fmt.Sprintf("%.2f", value)
+    },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Kind}} report</title>
+</head>
+<body>
+<h1>{{.Kind}} report</h1>
+<p>{{time .StartTimestamp}} to {{time .EndTimestamp}}</p>
+{{if .Error}}
+<p>The report could not be produced: {{.Error}}</p>
+{{end}}
+{{with .Health}}
+<table>
+<tr><th>Nodes</th><td>{{.NumNodes}}</td></tr>
+<tr><th>Dead nodes</th><td>{{range .DeadNodes}}{{.}} {{else}}None{{end}}</td></tr>
+<tr><th>Under-replicated tablets</th><td>{{.NumUnderReplicatedTablets}}</td></tr>
+<tr><th>Masters</th><td>{{.NumMasters}}</td></tr>
+<tr><th>Unreachable masters</th><td>{{.NumUnreachableMasters}}</td></tr>
+<tr><th>Version</th><td>{{.SoftwareVersion}}</td></tr>
+<tr><th>Average live nodes</th>
+<td>{{with .AverageLiveNodes}}{{round .}}{{else}}Unknown{{end}}</td></tr>
+</table>
+{{end}}
+{{with .TopQueries}}
+<table>
+<tr><th>Query</th><th>Database</th><th>Calls</th><th>Total time (ms)</th>
+<th>Mean time (ms)</th></tr>
+{{range .}}
+<tr><td>{{.Query}}</td><td>{{.Datname}}</td><td>{{.Calls}}</td><td>{{.TotalTime}}</td>
+<td>{{.MeanTime}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{with .CapacityTrend}}
+<table>
+<tr><th>Disk size (GB)</th><td>{{round .DiskSizeGb}}</td></tr>
+<tr><th>Disk used at the start (GB)</th><td>{{round .StartDiskUsedGb}}</td></tr>
+<tr><th>Disk used at the end (GB)</th><td>{{round .EndDiskUsedGb}}</td></tr>
+<tr><th>Growth (GB per day)</th><td>{{round .GrowthGbPerDay}}</td></tr>
+<tr><th>Days until full</th>
+<td>{{with .DaysUntilFull}}{{round .}}{{else}}Not growing{{end}}</td></tr>
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+// GetReports - Get list of reports
+func (c *Container) GetReports(ctx echo.Context) error {
+    kind := models.ReportKindEnum(ctx.QueryParam("kind"))
+    switch kind {
+    case "", models.REPORTKINDENUM_HEALTH, models.REPORTKINDENUM_TOP_QUERIES,
+        models.REPORTKINDENUM_CAPACITY_TREND:
+    default:
+        return respondError(ctx, http.StatusBadRequest, fmt.Sprintf("invalid report kind %s", kind))
+    }
+    return ctx.JSON(http.StatusOK, models.ReportListResponse{
+        Data: c.reports.list(kind),
+    })
+}
+
+// GetReport - Get a report
+func (c *Container) GetReport(ctx echo.Context) error {
+    id := ctx.Param("id")
+    report, ok := c.reports.get(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("report %s not found", id))
+    }
+    switch format := ctx.QueryParam("format"); format {
+    case "", "json":
+        return ctx.JSON(http.StatusOK, models.ReportResponse{
+            Data: report,
+        })
+    case "html":
+        var page bytes.Buffer
+        if err := reportHtmlTemplate.Execute(&page, report); err != nil {
+            return respondWithError(ctx, err)
+        }
+        return ctx.HTMLBlob(http.StatusOK, page.Bytes())
+    default:
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("invalid report format %s", format))
+    }
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/models"
+    "errors"
+    "fmt"
+    "math"
+    "net/http"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/labstack/echo/v4"
+    "golang.org/x/crypto/bcrypt"
+)
+
+func getHitRate(hits int64, lookups int64) float64 {
+    if lookups == 0 {
+        return 0
+    }
+    return float64(hits) / float64(lookups)
+}
+
+// GetResponseCacheStats - Get the hit rate of the response cache
+func (c *Container) GetResponseCacheStats(ctx echo.Context) error {
+    cacheConfig := helpers.GetConfig().Cache
+    entries, routeStats := c.responseCache.getStats()
+    // Cached routes are listed even before their first lookup
+    for route, ttl := range cacheConfig.Ttls {
+        if _, ok := routeStats[route]; !ok && ttl > 0 {
+            routeStats[route] = routeCacheStats{}
+        }
+    }
+    stats := models.ResponseCacheStats{
+        Enabled: cacheConfig.Enabled,
+        Entries: int32(entries),
+        Routes: []models.ResponseCacheRouteStats{},
+    }
+    for route, lookups := range routeStats {
+        stats.Hits += lookups.hits
+        stats.Misses += lookups.misses
+        stats.Bypasses += lookups.bypasses
+        stats.Routes = append(stats.Routes, models.ResponseCacheRouteStats{
+            Path: route,
+            TtlMs: cacheConfig.Ttls[route].Milliseconds(),
+            Hits: lookups.hits,
+            Misses: lookups.misses,
+            Bypasses: lookups.bypasses,
+            HitRate: getHitRate(lookups.hits, lookups.hits+lookups.misses+lookups.bypasses),
+        })
+    }
+    stats.HitRate = getHitRate(stats.Hits, stats.Hits+stats.Misses+stats.Bypasses)
+    sort.Slice(stats.Routes, func(i, j int) bool {
+        return stats.Routes[i].Path < stats.Routes[j].Path
+    })
+    return ctx.JSON(http.StatusOK, models.ResponseCacheStatsResponse{
+        Data: stats,
+    })
+}
+
+// Login - Log in with local credentials
+func (c *Container) Login(ctx echo.Context) error {
+    sessionsConfig := helpers.GetConfig().Sessions
+    if !sessionsConfig.Enabled {
+        return respondError(ctx, http.StatusForbidden, "sessions are disabled")
+    }
+    loginSpec := models.LoginSpec{}
+    if err := ctx.Bind(&loginSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if retryAfter := c.sessions.getLoginRetryAfter(loginSpec.Username); retryAfter > 0 {
+        ctx.Response().Header().Set("Retry-After",
+            strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+        return respondError(ctx, http.StatusTooManyRequests,
+            "too many failed logins, try again later")
+    }
+    passwordHash, ok := sessionsConfig.Users[loginSpec.Username]
+    // Unknown users are checked against a hash as well, so that they take as long to refuse
+    hash := []byte(passwordHash)
+    if !ok {
+        var err error
+        if hash, err = c.sessions.getDummyPasswordHash(sessionsConfig.Users); err != nil {
+            return respondWithError(ctx, err)
+        }
+    }
+    if bcrypt.CompareHashAndPassword(hash, []byte(loginSpec.Password)) != nil || !ok {
+        c.sessions.addFailedLogin(loginSpec.Username)
+        c.auditLog(ctx, "login_failed", "username", loginSpec.Username)
+        return respondError(ctx, http.StatusUnauthorized, "invalid username or password")
+    }
+    c.sessions.clearFailedLogins(loginSpec.Username)
+    newSession, err := c.sessions.create(loginSpec.Username)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    setSessionCookie(ctx, newSession.id, sessionsConfig.AbsoluteTimeout)
+    c.auditLog(ctx, "login", "username", loginSpec.Username)
+    return ctx.JSON(http.StatusOK, models.SessionResponse{
+        Data: getSessionModel(newSession),
+    })
+}
+
+// Logout - End the session
+func (c *Container) Logout(ctx echo.Context) error {
+    if cookie, err := ctx.Cookie(SESSION_COOKIE_NAME); err == nil {
+        if existing, ok := c.sessions.use(cookie.Value); ok {
+            c.auditLog(ctx, "logout", "username", existing.username)
+        }
+        c.sessions.remove(cookie.Value)
+    }
+    setSessionCookie(ctx, "", 0)
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// GetSession - Get the session of the user
+func (c *Container) GetSession(ctx echo.Context) error {
+    existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session)
+    if !ok {
+        return respondError(ctx, http.StatusForbidden, "sessions are disabled")
+    }
+    return ctx.JSON(http.StatusOK, models.SessionResponse{
+        Data: getSessionModel(existing),
+    })
+}
+
+// Longest name of an API token
+const API_TOKEN_MAX_NAME_LENGTH = 100
+
+// API tokens are managed by browser users, so that a leaked token cannot make more tokens
+func isApiTokenRequest(ctx echo.Context) bool {
+    return ctx.Get(API_TOKEN_CONTEXT_KEY) != nil
+}
+
+// GetApiTokens - Get list of API tokens
+func (c *Container) GetApiTokens(ctx echo.Context) error {
+    if isApiTokenRequest(ctx) {
+        return respondError(ctx, http.StatusForbidden, "API tokens cannot manage API tokens")
+    }
+    return ctx.JSON(http.StatusOK, models.ApiTokenListResponse{
+        Data: c.apiTokens.list(),
+    })
+}
+
+// CreateApiToken - Create an API token
+func (c *Container) CreateApiToken(ctx echo.Context) error {
+    if isApiTokenRequest(ctx) {
+        return respondError(ctx, http.StatusForbidden, "API tokens cannot manage API tokens")
+    }
+    tokenSpec := models.ApiTokenSpec{}
+    if err := ctx.Bind(&tokenSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if tokenSpec.Name == "" || len(tokenSpec.Name) > API_TOKEN_MAX_NAME_LENGTH {
+        return respondError(ctx, http.StatusBadRequest,
+            fmt.Sprintf("name must be 1 to %d characters long", API_TOKEN_MAX_NAME_LENGTH))
+    }
+    if err := validateApiTokenScopes(tokenSpec.Scopes); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    createdBy := ""
+    if existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session); ok {
+        createdBy = existing.username
+    }
+    token, err := c.apiTokens.create(tokenSpec.Name, tokenSpec.Scopes, createdBy)
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "create_api_token", "token_id", token.Id, "name", token.Name,
+        "scopes", token.Scopes)
+    return ctx.JSON(http.StatusOK, models.ApiTokenResponse{
+        Data: token,
+    })
+}
+
+// UpdateApiTokenScopes - Change the scopes of an API token
+func (c *Container) UpdateApiTokenScopes(ctx echo.Context) error {
+    if isApiTokenRequest(ctx) {
+        return respondError(ctx, http.StatusForbidden, "API tokens cannot manage API tokens")
+    }
+    id := ctx.Param("id")
+    scopesSpec := models.ApiTokenScopesSpec{}
+    if err := ctx.Bind(&scopesSpec); err != nil {
+        return respondError(ctx, http.StatusBadRequest, "invalid request body")
+    }
+    if err := validateApiTokenScopes(scopesSpec.Scopes); err != nil {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    token, ok, err := c.apiTokens.setScopes(id, scopesSpec.Scopes)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("API token %s not found", id))
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "update_api_token_scopes", "token_id", id, "scopes", token.Scopes)
+    return ctx.JSON(http.StatusOK, models.ApiTokenResponse{
+        Data: token,
+    })
+}
+
+// RevokeApiToken - Revoke an API token
+func (c *Container) RevokeApiToken(ctx echo.Context) error {
+    if isApiTokenRequest(ctx) {
+        return respondError(ctx, http.StatusForbidden, "API tokens cannot manage API tokens")
+    }
+    id := ctx.Param("id")
+    ok, err := c.apiTokens.revoke(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("API token %s not found", id))
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.auditLog(ctx, "revoke_api_token", "token_id", id)
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// BackupLocalStore - Download a backup of the local store
+func (c *Container) BackupLocalStore(ctx echo.Context) error {
+    // The backup holds the hashes of the API tokens, so it is only for users
+    if isApiTokenRequest(ctx) {
+        return respondError(ctx, http.StatusForbidden, "API tokens cannot back up the local store")
+    }
+    c.auditLog(ctx, "backup_local_store")
+    ctx.Response().Header().Set(echo.HeaderContentDisposition,
+        fmt.Sprintf("attachment; filename=\"yugabyted-ui-store-%s.json\"",
+            time.Now().UTC().Format("20060102T150405Z")))
+    ctx.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+    ctx.Response().WriteHeader(http.StatusOK)
+    return localstore.Backup(c.localStore, ctx.Response())
+}
+
+// RestoreLocalStore - Restore the local store from a backup
+func (c *Container) RestoreLocalStore(ctx echo.Context) error {
+    if isApiTokenRequest(ctx) {
+        return respondError(ctx, http.StatusForbidden, "API tokens cannot restore the local store")
+    }
+    err := localstore.Restore(c.localStore, ctx.Request().Body, STORE_MIGRATIONS)
+    if errors.Is(err, localstore.ErrInvalidBackup) {
+        return respondError(ctx, http.StatusBadRequest, err.Error())
+    }
+    if err != nil {
+        return respondWithError(ctx, err)
+    }
+    c.reloadLocalStore()
+    c.auditLog(ctx, "restore_local_store")
+    return ctx.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/models"
+    "fmt"
+    "net/http"
+
+    "github.com/labstack/echo/v4"
+)
+
+// GetTasks - Get list of tasks
+func (c *Container) GetTasks(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, models.TaskListResponse{
+        Data: c.tasks.List(),
+    })
+}
+
+// GetTask - Get a task
+func (c *Container) GetTask(ctx echo.Context) error {
+    id := ctx.Param("id")
+    task, ok := c.tasks.Get(id)
+    if !ok {
+        return respondError(ctx, http.StatusNotFound, fmt.Sprintf("task %s not found", id))
+    }
+    return ctx.JSON(http.StatusOK, models.TaskResponse{
+        Data: task,
+    })
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Tokens with the read scope can only make GET requests, the write scope allows every request
+const API_TOKEN_SCOPE_READ = "read"
+const API_TOKEN_SCOPE_WRITE = "write"
+
+var API_TOKEN_SCOPES = []string{API_TOKEN_SCOPE_READ, API_TOKEN_SCOPE_WRITE}
+
+// Makes the tokens recognizable, e.g. by secret scanners
+const API_TOKEN_PREFIX = "ybui_"
+
+// Key of the API token of the request in the request context
+const API_TOKEN_CONTEXT_KEY = "api_token"
+
+// How stale the last use of a token can be in the store, so that the store is not written on
+// every request
+const API_TOKEN_LAST_USED_PRECISION = time.Minute
+
+// A token as written to the store, with the hash of its secret instead of the secret
+type storedApiToken struct {
+    Id string `json:"id"`
+    Name string `json:"name"`
+    Hash string `json:"hash"`
+    Scopes []string `json:"scopes"`
+    CreatedBy string `json:"created_by"`
+    CreatedAt int64 `json:"created_at"`
+    LastUsedAt int64 `json:"last_used_at"`
+}
+
+type apiTokensFile struct {
+    Tokens []storedApiToken `json:"tokens"`
+}
+
+// Keeps the API tokens in the local store, so that they outlive the server. Tokens are looked
+// up by the hash of their secret.
+type apiTokenStore struct {
+    mutex sync.Mutex
+    tokens map[string]*storedApiToken
+    local localstore.Store
+    logger logger.Logger
+}
+
+func newApiTokenStore(log logger.Logger, local localstore.Store) *apiTokenStore {
+    store := &apiTokenStore{
+        tokens: map[string]*storedApiToken{},
+        local: local,
+        logger: log,
+    }
+    store.load()
+    return store
+}
+
+// Reads the tokens from the local store
+func (store *apiTokenStore) load() {
+    tokens := map[string]*storedApiToken{}
+    if tokensFile, err := store.read(); err != nil {
+        store.logger.Errorf("failed to read the API tokens: %s", err.Error())
+    } else {
+        for index := range tokensFile.Tokens {
+            token := tokensFile.Tokens[index]
+            tokens[token.Hash] = &token
+        }
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    store.tokens = tokens
+}
+
+func (store *apiTokenStore) read() (apiTokensFile, error) {
+    tokensFile := apiTokensFile{}
+    data, ok, err := store.local.Get(STORE_BUCKET_API_TOKENS, STORE_API_TOKENS_KEY)
+    if err != nil || !ok {
+        return tokensFile, err
+    }
+    err = json.Unmarshal(data, &tokensFile)
+    return tokensFile, err
+}
+
+func hashApiToken(secret string) string {
+    hash := sha256.Sum256([]byte(secret))
+    return hex.EncodeToString(hash[:])
+}
+
+// Writes the tokens to the local store. Must be called with the mutex held.
+func (store *apiTokenStore) saveLocked() error {
+    tokensFile := apiTokensFile{Tokens: []storedApiToken{}}
+    for _, token := range store.tokens {
+        tokensFile.Tokens = append(tokensFile.Tokens, *token)
+    }
+    sort.Slice(tokensFile.Tokens, func(i, j int) bool {
+        return tokensFile.Tokens[i].CreatedAt < tokensFile.Tokens[j].CreatedAt
+    })
+    data, err := json.MarshalIndent(tokensFile, "", "  ")
+    if err != nil {
+        return err
+    }
+    return store.local.Put(STORE_BUCKET_API_TOKENS, STORE_API_TOKENS_KEY, data)
+}
+
+func getApiTokenModel(token storedApiToken) models.ApiToken {
+    apiToken := models.ApiToken{
+        Id: token.Id,
+        Name: token.Name,
+        Scopes: append([]string{}, token.Scopes...),
+        CreatedBy: token.CreatedBy,
+        CreatedAt: token.CreatedAt,
+    }
+    if token.LastUsedAt != 0 {
+        lastUsedAt := token.LastUsedAt
+        apiToken.LastUsedAt = &lastUsedAt
+    }
+    return apiToken
+}
+
+func hasApiTokenScope(scopes []string, scope string) bool {
+    for _, candidate := range scopes {
+        if candidate == scope {
+            return true
+        }
+    }
+    return false
+}
+
+// Whether the request was made with an API token without the write scope, which the GET
+// requests that can change or read more than the cluster state refuse
+func isReadOnlyApiToken(ctx echo.Context) bool {
+    token, ok := ctx.Get(API_TOKEN_CONTEXT_KEY).(storedApiToken)
+    return ok && !hasApiTokenScope(token.Scopes, API_TOKEN_SCOPE_WRITE)
+}
+
+// Checks that the scopes are known, without duplicates
+func validateApiTokenScopes(scopes []string) error {
+    if len(scopes) == 0 {
+        return fmt.Errorf("scopes must not be empty")
+    }
+    seen := map[string]bool{}
+    for _, scope := range scopes {
+        if !hasApiTokenScope(API_TOKEN_SCOPES, scope) {
+            return fmt.Errorf("scope must be one of %s, got %q",
+                strings.Join(API_TOKEN_SCOPES, ", "), scope)
+        }
+        if seen[scope] {
+            return fmt.Errorf("scope %s is given more than once", scope)
+        }
+        seen[scope] = true
+    }
+    return nil
+}
+
+// Creates a token, returning it along with its secret, which is not kept
+func (store *apiTokenStore) create(name string, scopes []string,
+    createdBy string) (models.ApiToken, error) {
+    idBytes := make([]byte, 8)
+    secretBytes := make([]byte, 32)
+    if _, err := rand.Read(idBytes); err != nil {
+        return models.ApiToken{}, err
+    }
+    if _, err := rand.Read(secretBytes); err != nil {
+        return models.ApiToken{}, err
+    }
+    secret := API_TOKEN_PREFIX + hex.EncodeToString(secretBytes)
+    token := &storedApiToken{
+        Id: hex.EncodeToString(idBytes),
+        Name: name,
+        Hash: hashApiToken(secret),
+        Scopes: scopes,
+        CreatedBy: createdBy,
+        CreatedAt: time.Now().Unix(),
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    store.tokens[token.Hash] = token
+    if err := store.saveLocked(); err != nil {
+        delete(store.tokens, token.Hash)
+        return models.ApiToken{}, err
+    }
+    apiToken := getApiTokenModel(*token)
+    apiToken.Token = &secret
+    return apiToken, nil
+}
+
+// Gets the tokens, oldest first
+func (store *apiTokenStore) list() []models.ApiToken {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    apiTokens := []models.ApiToken{}
+    for _, token := range store.tokens {
+        apiTokens = append(apiTokens, getApiTokenModel(*token))
+    }
+    sort.Slice(apiTokens, func(i, j int) bool {
+        if apiTokens[i].CreatedAt != apiTokens[j].CreatedAt {
+            return apiTokens[i].CreatedAt < apiTokens[j].CreatedAt
+        }
+        return apiTokens[i].Id < apiTokens[j].Id
+    })
+    return apiTokens
+}
+
+// Must be called with the mutex held
+func (store *apiTokenStore) findLocked(id string) (*storedApiToken, bool) {
+    for _, token := range store.tokens {
+        if token.Id == id {
+            return token, true
+        }
+    }
+    return nil, false
+}
+
+// Replaces the scopes of a token. Returns false if there is no such token.
+func (store *apiTokenStore) setScopes(id string, scopes []string) (models.ApiToken, bool,
+    error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    token, ok := store.findLocked(id)
+    if !ok {
+        return models.ApiToken{}, false, nil
+    }
+    previousScopes := token.Scopes
+    token.Scopes = scopes
+    if err := store.saveLocked(); err != nil {
+        token.Scopes = previousScopes
+        return models.ApiToken{}, true, err
+    }
+    return getApiTokenModel(*token), true, nil
+}
+
+// Revokes a token. Returns false if there is no such token.
+func (store *apiTokenStore) revoke(id string) (bool, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    token, ok := store.findLocked(id)
+    if !ok {
+        return false, nil
+    }
+    delete(store.tokens, token.Hash)
+    if err := store.saveLocked(); err != nil {
+        store.tokens[token.Hash] = token
+        return true, err
+    }
+    return true, nil
+}
+
+// Gets the token with the secret, and records that it was used
+func (store *apiTokenStore) use(secret string) (storedApiToken, bool) {
+    now := time.Now()
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    token, ok := store.tokens[hashApiToken(secret)]
+    if !ok {
+        return storedApiToken{}, false
+    }
+    if now.Sub(time.Unix(token.LastUsedAt, 0)) >= API_TOKEN_LAST_USED_PRECISION {
+        token.LastUsedAt = now.Unix()
+        if err := store.saveLocked(); err != nil {
+            store.logger.Errorf("failed to record the use of API token %s: %s", token.Id,
+                err.Error())
+        }
+    }
+    return *token, true
+}
+
+// Authenticates the API requests that carry a bearer token. Requests without one are left to
+// the session check.
+func (c *Container) AuthenticateApiToken(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+        authorization := ctx.Request().Header.Get(echo.HeaderAuthorization)
+        if !strings.HasPrefix(ctx.Request().URL.Path, "/api/") ||
+            !strings.HasPrefix(authorization, "Bearer ") {
+            return next(ctx)
+        }
+        token, ok := c.apiTokens.use(strings.TrimSpace(strings.TrimPrefix(authorization,
+            "Bearer ")))
+        if !ok {
+            return respondError(ctx, http.StatusUnauthorized, "invalid API token")
+        }
+        method := ctx.Request().Method
+        if method != http.MethodGet && method != http.MethodHead &&
+            !hasApiTokenScope(token.Scopes, API_TOKEN_SCOPE_WRITE) {
+            return respondError(ctx, http.StatusForbidden,
+                fmt.Sprintf("the API token needs the %s scope", API_TOKEN_SCOPE_WRITE))
+        }
+        ctx.Set(API_TOKEN_CONTEXT_KEY, token)
+        return next(ctx)
+    }
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/labstack/echo/v4"
+)
+
+// An operation as kept in the audit log of the local store
+type auditEntry struct {
+    Time int64 `json:"time"`
+    Action string `json:"action"`
+    RemoteIp string `json:"remote_ip"`
+    RequestId string `json:"request_id"`
+    User string `json:"user,omitempty"`
+    ApiToken string `json:"api_token,omitempty"`
+    Details map[string]string `json:"details"`
+}
+
+// Logs an operation that changed the state of the cluster, along with who requested it, and
+// keeps it in the local store. The args are key-value pairs describing the operation and must
+// never contain secrets.
+func (c *Container) auditLog(ctx echo.Context, action string, args ...interface{}) {
+    entry := auditEntry{
+        Time: time.Now().Unix(),
+        Action: action,
+        RemoteIp: ctx.RealIP(),
+        RequestId: ctx.Response().Header().Get(echo.HeaderXRequestID),
+        Details: map[string]string{},
+    }
+    fields := append([]interface{}{
+        "audit", true,
+        "action", action,
+        "remote_ip", entry.RemoteIp,
+        "id", entry.RequestId,
+    }, args...)
+    if existing, ok := ctx.Get(SESSION_CONTEXT_KEY).(session); ok {
+        fields = append(fields, "user", existing.username)
+        entry.User = existing.username
+    }
+    if token, ok := ctx.Get(API_TOKEN_CONTEXT_KEY).(storedApiToken); ok {
+        fields = append(fields, "api_token", token.Id)
+        entry.ApiToken = token.Id
+    }
+    c.logger.With(fields...).Infof("audit")
+    for index := 0; index+1 < len(args); index += 2 {
+        entry.Details[fmt.Sprint(args[index])] = fmt.Sprint(args[index+1])
+    }
+    c.storeAuditEntry(entry)
+}
+
+// Keeps the entry in the audit log of the local store, dropping the oldest entries beyond
+// store.max_audit_entries. Keys start with the time in nanoseconds, so that they sort by time.
+func (c *Container) storeAuditEntry(entry auditEntry) {
+    maxEntries := helpers.GetConfig().Store.MaxAuditEntries
+    if maxEntries == 0 {
+        return
+    }
+    data, err := json.Marshal(entry)
+    if err == nil {
+        key := fmt.Sprintf("%019d-%s", time.Now().UnixNano(), entry.RequestId)
+        err = c.localStore.Put(STORE_BUCKET_AUDIT_LOG, key, data)
+    }
+    if err == nil {
+        err = c.localStore.Trim(STORE_BUCKET_AUDIT_LOG, maxEntries)
+    }
+    if err != nil {
+        c.logger.Errorf("failed to keep the audit log entry of %s: %s", entry.Action,
+            err.Error())
+    }
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "math"
+    "sort"
+)
+
+// What the load of the nodes is compared by
+const BALANCE_DIMENSION_TABLETS = "tablets"
+const BALANCE_DIMENSION_LEADERS = "leaders"
+const BALANCE_DIMENSION_DISK = "disk"
+
+const BALANCE_LEVEL_NODE = "node"
+const BALANCE_LEVEL_ZONE = "zone"
+
+// Number of nodes and zones furthest from the mean that are reported
+const BALANCE_TOP_CONTRIBUTORS = 5
+
+// The load of a node or a zone in one dimension, within its placement
+type balanceEntity struct {
+    name string
+    placement string
+    value float64
+}
+
+// Gets the load of a tserver in a dimension
+func getBalanceValue(tabletServer helpers.TabletServer, dimension string) float64 {
+    switch dimension {
+    case BALANCE_DIMENSION_TABLETS:
+        return float64(tabletServer.UserTabletsTotal)
+    case BALANCE_DIMENSION_LEADERS:
+        return float64(tabletServer.UserTabletsLeaders)
+    }
+    return float64(tabletServer.TotalSstFileSizeBytes)
+}
+
+// Compares the entities to the mean of their placement, as read replica clusters hold other
+// data than the primary cluster. The imbalance is the root mean square of the deviations
+// relative to the mean, and the score is 100 without any imbalance and 0 from an imbalance of
+// 100% on.
+func getDimensionBalance(dimension string, level string, entities []balanceEntity) (
+    models.BalanceDimension, []models.BalanceContributor) {
+    totals := map[string]float64{}
+    counts := map[string]int{}
+    for _, entity := range entities {
+        totals[entity.placement] += entity.value
+        counts[entity.placement]++
+    }
+    contributors := []models.BalanceContributor{}
+    squares := float64(0)
+    for _, entity := range entities {
+        mean := totals[entity.placement] / float64(counts[entity.placement])
+        deviation := float64(0)
+        if mean > 0 {
+            deviation = (entity.value - mean) / mean
+        }
+        squares += deviation * deviation
+        if deviation != 0 {
+            contributors = append(contributors, models.BalanceContributor{
+                Dimension: dimension,
+                Level: level,
+                Name: entity.name,
+                Value: entity.value,
+                Mean: mean,
+                DeviationPercent: 100 * deviation,
+            })
+        }
+    }
+    imbalance := float64(0)
+    if len(entities) > 0 {
+        imbalance = math.Sqrt(squares / float64(len(entities)))
+    }
+    return models.BalanceDimension{
+        Dimension: dimension,
+        Level: level,
+        ImbalancePercent: 100 * imbalance,
+        Score: 100 * math.Max(0, 1-imbalance),
+    }, contributors
+}
+
+// Scores how evenly the tablets, leaders and data are spread over the alive nodes, and over
+// their zones by the average of the nodes of each zone. Zones are left out of placements that
+// have a single zone. The score of the cluster is the average of the scores of each dimension.
+func getBalanceScore(tabletServers map[string]map[string]helpers.TabletServer) models.Balance {
+    balance := models.Balance{
+        Score: 100,
+        Dimensions: []models.BalanceDimension{},
+        TopContributors: []models.BalanceContributor{},
+    }
+    for _, dimension := range []string{BALANCE_DIMENSION_TABLETS, BALANCE_DIMENSION_LEADERS,
+        BALANCE_DIMENSION_DISK} {
+        nodes := []balanceEntity{}
+        zoneTotals := map[balanceEntity]float64{}
+        zoneCounts := map[balanceEntity]int{}
+        zonesByPlacement := map[string]int{}
+        for placement, cluster := range tabletServers {
+            for address, tabletServer := range cluster {
+                if tabletServer.Status != "ALIVE" {
+                    continue
+                }
+                host, err := helpers.GetHostFromAddress(address)
+                if err != nil {
+                    host = address
+                }
+                value := getBalanceValue(tabletServer, dimension)
+                nodes = append(nodes, balanceEntity{host, placement, value})
+                zone := balanceEntity{
+                    name: tabletServer.Cloud + "." + tabletServer.Region + "." +
+                        tabletServer.Zone,
+                    placement: placement,
+                }
+                if zoneCounts[zone] == 0 {
+                    zonesByPlacement[placement]++
+                }
+                zoneTotals[zone] += value
+                zoneCounts[zone]++
+            }
+        }
+        levels := map[string][]balanceEntity{BALANCE_LEVEL_NODE: nodes}
+        for zone, total := range zoneTotals {
+            if zonesByPlacement[zone.placement] > 1 {
+                zone.value = total / float64(zoneCounts[zone])
+                levels[BALANCE_LEVEL_ZONE] = append(levels[BALANCE_LEVEL_ZONE], zone)
+            }
+        }
+        for _, level := range []string{BALANCE_LEVEL_NODE, BALANCE_LEVEL_ZONE} {
+            if len(levels[level]) < 2 {
+                continue
+            }
+            dimensionBalance, contributors := getDimensionBalance(dimension, level,
+                levels[level])
+            balance.Dimensions = append(balance.Dimensions, dimensionBalance)
+            balance.TopContributors = append(balance.TopContributors, contributors...)
+        }
+    }
+    if len(balance.Dimensions) > 0 {
+        total := float64(0)
+        for _, dimension := range balance.Dimensions {
+            total += dimension.Score
+        }
+        balance.Score = total / float64(len(balance.Dimensions))
+    }
+    sort.Slice(balance.TopContributors, func(i, j int) bool {
+        first, second := balance.TopContributors[i], balance.TopContributors[j]
+        if math.Abs(first.DeviationPercent) != math.Abs(second.DeviationPercent) {
+            return math.Abs(first.DeviationPercent) > math.Abs(second.DeviationPercent)
+        }
+        return first.Name < second.Name
+    })
+    if len(balance.TopContributors) > BALANCE_TOP_CONTRIBUTORS {
+        balance.TopContributors = balance.TopContributors[:BALANCE_TOP_CONTRIBUTORS]
+    }
+    return balance
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "context"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/jackc/pgx/v4"
+    "github.com/labstack/echo/v4"
+)
+
+// The kinds of tables and types of constraints, as the catalog browser names them
+const YSQL_CATALOG_TABLE_KIND_SQL string = "CASE c.relkind WHEN 'r' THEN 'table' " +
+    "WHEN 'p' THEN 'partitioned_table' WHEN 'v' THEN 'view' WHEN 'm' THEN 'materialized_view' " +
+    "ELSE 'foreign_table' END"
+
+const YSQL_CATALOG_CONSTRAINT_TYPE_SQL string = "CASE con.contype WHEN 'p' THEN 'primary_key' " +
+    "WHEN 'f' THEN 'foreign_key' WHEN 'u' THEN 'unique' WHEN 'c' THEN 'check' " +
+    "WHEN 'x' THEN 'exclusion' ELSE 'trigger' END"
+
+// The catalog browser only runs the queries below. Filters are added to them as conditions
+// whose values are bound as parameters, never spliced into the SQL.
+const YSQL_CATALOG_TABLES_SQL string = "SELECT n.nspname, c.relname, " +
+    YSQL_CATALOG_TABLE_KIND_SQL + ", pg_get_userbyid(c.relowner), " +
+    "(SELECT count(*) FROM pg_attribute a WHERE a.attrelid = c.oid AND a.attnum > 0 " +
+    "AND NOT a.attisdropped), " +
+    "EXISTS(SELECT 1 FROM pg_constraint p WHERE p.conrelid = c.oid AND p.contype = 'p'), " +
+    "COALESCE(obj_description(c.oid, 'pg_class'), '') FROM pg_class c " +
+    "JOIN pg_namespace n ON n.oid = c.relnamespace WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f')"
+
+const YSQL_CATALOG_INDEXES_SQL string = "SELECT n.nspname, t.relname, ic.relname, am.amname, " +
+    "i.indisunique, i.indisprimary, pg_get_indexdef(i.indexrelid) FROM pg_index i " +
+    "JOIN pg_class ic ON ic.oid = i.indexrelid JOIN pg_class t ON t.oid = i.indrelid " +
+    "JOIN pg_namespace n ON n.oid = t.relnamespace JOIN pg_am am ON am.oid = ic.relam " +
+    "WHERE true"
+
+const YSQL_CATALOG_CONSTRAINTS_SQL string = "SELECT n.nspname, t.relname, con.conname, " +
+    YSQL_CATALOG_CONSTRAINT_TYPE_SQL + ", pg_get_constraintdef(con.oid), " +
+    "CASE WHEN con.confrelid = 0 THEN '' ELSE con.confrelid::regclass::text END " +
+    "FROM pg_constraint con JOIN pg_class t ON t.oid = con.conrelid " +
+    "JOIN pg_namespace n ON n.oid = t.relnamespace WHERE true"
+
+// Leaves out the schemas of the system, unless they are asked for
+const YSQL_CATALOG_USER_SCHEMAS_SQL string = "n.nspname NOT IN ('pg_catalog', " +
+    "'information_schema') AND n.nspname !~ '^pg_toast' AND n.nspname !~ '^pg_temp'"
+
+var CATALOG_TABLE_KINDS = []string{"table", "partitioned_table", "view", "materialized_view",
+    "foreign_table"}
+
+var CATALOG_CONSTRAINT_TYPES = []string{"primary_key", "foreign_key", "unique", "check",
+    "exclusion", "trigger"}
+
+// Number of catalog objects listed by default, and at most
+const CATALOG_DEFAULT_LIMIT = 100
+const CATALOG_MAX_LIMIT = 1000
+
+// A catalog query along with the conditions of its filters and their parameters
+type catalogQuery struct {
+    sql string
+    args []interface{}
+}
+
+// Adds a condition, in which %d is replaced by the number of the parameter bound to the value
+func (query *catalogQuery) where(condition string, value interface{}) {
+    query.args = append(query.args, value)
+    query.sql += " AND " + fmt.Sprintf(condition, len(query.args))
+}
+
+// Adds the filters that the catalog endpoints have in common: include_system, and the
+// case-insensitive substring of the name of the objects
+func (query *catalogQuery) whereCommon(params catalogParams, nameColumn string,
+    userCondition string) {
+    if !params.includeSystem {
+        query.sql += " AND " + userCondition
+    }
+    if params.name != "" {
+        query.where("strpos(lower("+nameColumn+"), lower($%d)) > 0", params.name)
+    }
+}
+
+// Orders the query and gets one row more than the limit, to tell whether there are more
+func (query *catalogQuery) run(conn *pgx.Conn, orderBy string, limit int) (pgx.Rows, error) {
+    query.args = append(query.args, limit+1)
+    return conn.Query(context.Background(),
+        fmt.Sprintf("%s ORDER BY %s LIMIT $%d", query.sql, orderBy, len(query.args)),
+        query.args...)
+}
+
+// The query parameters of the catalog endpoints
+type catalogParams struct {
+    database string
+    schema string
+    table string
+    name string
+    includeSystem bool
+    limit int
+}
+
+// Reads the query parameters of a catalog endpoint, checking that the database exists before
+// anything connects to it. Invalid parameters are bad requests, and unknown databases are not
+// found.
+func (c *Container) getCatalogParams(query func(string) string) (catalogParams, error) {
+    params := catalogParams{
+        database: query("database"),
+        schema: query("schema"),
+        table: query("table"),
+        name: query("name"),
+        limit: CATALOG_DEFAULT_LIMIT,
+    }
+    if value := query("include_system"); value != "" {
+        var err error
+        if params.includeSystem, err = strconv.ParseBool(value); err != nil {
+            return params, echo.NewHTTPError(http.StatusBadRequest,
+                "include_system must be true or false")
+        }
+    }
+    if value := query("limit"); value != "" {
+        limit, err := strconv.Atoi(value)
+        if err != nil || limit < 1 || limit > CATALOG_MAX_LIMIT {
+            return params, echo.NewHTTPError(http.StatusBadRequest,
+                fmt.Sprintf("limit must be between 1 and %d", CATALOG_MAX_LIMIT))
+        }
+        params.limit = limit
+    }
+    if params.database != "" && params.database != helpers.DbName {
+        if err := c.checkYsqlDatabase(params.database); err != nil {
+            return params, err
+        }
+    }
+    return params, nil
+}
+
+// Checks a filter against the values it can take
+func validateCatalogEnum(name string, value string, values []string) error {
+    if value != "" && !containsString(values, value) {
+        return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must be one of %s, got %q",
+            name, strings.Join(values, ", "), value))
+    }
+    return nil
+}
+
+// Gets the tables, views, materialized views and foreign tables of a database, sorted by
+// schema and name. Returns whether more match than the limit.
+func getCatalogTables(conn *pgx.Conn, params catalogParams,
+    kind string) ([]models.CatalogTable, bool, error) {
+    query := catalogQuery{sql: YSQL_CATALOG_TABLES_SQL}
+    query.whereCommon(params, "c.relname", YSQL_CATALOG_USER_SCHEMAS_SQL)
+    if params.schema != "" {
+        query.where("n.nspname = $%d", params.schema)
+    }
+    if kind != "" {
+        query.where(YSQL_CATALOG_TABLE_KIND_SQL+" = $%d", kind)
+    }
+    rows, err := query.run(conn, "n.nspname, c.relname", params.limit)
+    if err != nil {
+        return nil, false, err
+    }
+    defer rows.Close()
+    tables := []models.CatalogTable{}
+    for rows.Next() {
+        table := models.CatalogTable{}
+        err := rows.Scan(&table.Schema, &table.Name, &table.Kind, &table.Owner,
+            &table.NumColumns, &table.HasPrimaryKey, &table.Comment)
+        if err != nil {
+            return nil, false, err
+        }
+        tables = append(tables, table)
+    }
+    if len(tables) > params.limit {
+        return tables[:params.limit], true, rows.Err()
+    }
+    return tables, false, rows.Err()
+}
+
+// Gets the indexes of a database, sorted by schema, table and name. Returns whether more match
+// than the limit.
+func getCatalogIndexes(conn *pgx.Conn,
+    params catalogParams) ([]models.CatalogIndex, bool, error) {
+    query := catalogQuery{sql: YSQL_CATALOG_INDEXES_SQL}
+    query.whereCommon(params, "ic.relname", YSQL_CATALOG_USER_SCHEMAS_SQL)
+    if params.schema != "" {
+        query.where("n.nspname = $%d", params.schema)
+    }
+    if params.table != "" {
+        query.where("t.relname = $%d", params.table)
+    }
+    rows, err := query.run(conn, "n.nspname, t.relname, ic.relname", params.limit)
+    if err != nil {
+        return nil, false, err
+    }
+    defer rows.Close()
+    indexes := []models.CatalogIndex{}
+    for rows.Next() {
+        index := models.CatalogIndex{}
+        err := rows.Scan(&index.Schema, &index.Table, &index.Name, &index.Method,
+            &index.IsUnique, &index.IsPrimary, &index.Definition)
+        if err != nil {
+            return nil, false, err
+        }
+        indexes = append(indexes, index)
+    }
+    if len(indexes) > params.limit {
+        return indexes[:params.limit], true, rows.Err()
+    }
+    return indexes, false, rows.Err()
+}
+
+// Gets the constraints of the tables of a database, sorted by schema, table and name. Returns
+// whether more match than the limit.
+func getCatalogConstraints(conn *pgx.Conn, params catalogParams,
+    constraintType string) ([]models.CatalogConstraint, bool, error) {
+    query := catalogQuery{sql: YSQL_CATALOG_CONSTRAINTS_SQL}
+    query.whereCommon(params, "con.conname", YSQL_CATALOG_USER_SCHEMAS_SQL)
+    if params.schema != "" {
+        query.where("n.nspname = $%d", params.schema)
+    }
+    if params.table != "" {
+        query.where("t.relname = $%d", params.table)
+    }
+    if constraintType != "" {
+        query.where(YSQL_CATALOG_CONSTRAINT_TYPE_SQL+" = $%d", constraintType)
+    }
+    rows, err := query.run(conn, "n.nspname, t.relname, con.conname", params.limit)
+    if err != nil {
+        return nil, false, err
+    }
+    defer rows.Close()
+    constraints := []models.CatalogConstraint{}
+    for rows.Next() {
+        constraint := models.CatalogConstraint{}
+        err := rows.Scan(&constraint.Schema, &constraint.Table, &constraint.Name,
+            &constraint.Type, &constraint.Definition, &constraint.ReferencedTable)
+        if err != nil {
+            return nil, false, err
+        }
+        constraints = append(constraints, constraint)
+    }
+    if len(constraints) > params.limit {
+        return constraints[:params.limit], true, rows.Err()
+    }
+    return constraints, false, rows.Err()
+}
+
+// Gets the YSQL roles, sorted by name. Returns whether more match than the limit.
+func getCatalogRoles(conn *pgx.Conn, params catalogParams) ([]models.DatabaseRole, bool, error) {
+    query := catalogQuery{sql: YSQL_ROLES_SELECT_SQL + " WHERE true"}
+    query.whereCommon(params, "r.rolname", YSQL_USER_ROLES_SQL)
+    rows, err := query.run(conn, "r.rolname", params.limit)
+    if err != nil {
+        return nil, false, err
+    }
+    defer rows.Close()
+    roles := []models.DatabaseRole{}
+    for rows.Next() {
+        role, err := scanYsqlRole(rows)
+        if err != nil {
+            return nil, false, err
+        }
+        roles = append(roles, role)
+    }
+    if len(roles) > params.limit {
+        return roles[:params.limit], true, rows.Err()
+    }
+    return roles, false, rows.Err()
+}
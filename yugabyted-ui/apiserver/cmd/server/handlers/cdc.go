@@ -0,0 +1,252 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "errors"
+    "fmt"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/yugabyte/gocql"
+)
+
+var CDC_STREAM_ID_REGEX = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+var CDC_CONNECTOR_NAME_REGEX = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,100}$`)
+
+// The line with the id of a new stream in the output of create_change_data_stream
+var CDC_STREAM_ID_OUTPUT_REGEX = regexp.MustCompile(`CDC Stream ID: ([0-9a-fA-F]{32})`)
+
+var CDC_CHECKPOINT_TYPES = []string{"EXPLICIT", "IMPLICIT"}
+var CDC_RECORD_TYPES = []string{"CHANGE", "ALL", "FULL_ROW_NEW_IMAGE",
+    "MODIFIED_COLUMNS_OLD_AND_NEW_IMAGES"}
+
+const CDC_CONNECTOR_FORMAT_KAFKA_CONNECT = "kafka_connect"
+const CDC_CONNECTOR_FORMAT_DEBEZIUM_SERVER = "debezium_server"
+
+const CDC_CONNECTOR_CLASS = "io.debezium.connector.yugabytedb.YugabyteDBConnector"
+
+// Tables of the database the connector reads by default, as a Debezium include list
+const CDC_CONNECTOR_DEFAULT_TABLES = `public\..*`
+
+// Stands for values the config cannot be filled in with, such as passwords
+const CDC_CONNECTOR_PLACEHOLDER_PASSWORD = "<password>"
+const CDC_CONNECTOR_PLACEHOLDER_BOOTSTRAP_SERVERS = "<kafka bootstrap servers>"
+
+// The checkpoints of the streams on each tablet, kept by the tservers
+const YCQL_CDC_STATE_CQL string = "SELECT stream_id, last_replication_time " +
+    "FROM system.cdc_state"
+
+func containsString(values []string, value string) bool {
+    for _, candidate := range values {
+        if candidate == value {
+            return true
+        }
+    }
+    return false
+}
+
+// Fills in the defaults of the spec and checks it
+func validateCdcStreamSpec(spec *models.CdcStreamSpec) error {
+    if spec.Database == "" {
+        return errors.New("database is required")
+    }
+    if spec.CheckpointType == "" {
+        spec.CheckpointType = CDC_CHECKPOINT_TYPES[0]
+    }
+    if !containsString(CDC_CHECKPOINT_TYPES, spec.CheckpointType) {
+        return fmt.Errorf("checkpoint_type must be one of %s",
+            strings.Join(CDC_CHECKPOINT_TYPES, ", "))
+    }
+    if spec.RecordType == "" {
+        spec.RecordType = CDC_RECORD_TYPES[0]
+    }
+    if !containsString(CDC_RECORD_TYPES, spec.RecordType) {
+        return fmt.Errorf("record_type must be one of %s", strings.Join(CDC_RECORD_TYPES, ", "))
+    }
+    return nil
+}
+
+// Creates a CDCSDK stream for the changes of a YSQL database, returning its id
+func createCdcStream(spec models.CdcStreamSpec) (string, error) {
+    output, err := helpers.RunYbAdmin("create_change_data_stream", "ysql."+spec.Database,
+        spec.CheckpointType, spec.RecordType)
+    if err != nil {
+        return "", err
+    }
+    match := CDC_STREAM_ID_OUTPUT_REGEX.FindStringSubmatch(output)
+    if match == nil {
+        return "", fmt.Errorf("no stream id in the output of yb-admin: %s",
+            strings.TrimSpace(output))
+    }
+    return match[1], nil
+}
+
+// Where a connector reaches the cluster, as the connector expects them
+type cdcConnectorTarget struct {
+    // The tservers, separated by commas
+    hosts string
+    // The rpc addresses of the masters, separated by commas
+    masterAddresses string
+}
+
+func getCdcConnectorTarget() (cdcConnectorTarget, error) {
+    tserverHosts, err := getTserverHosts()
+    if err != nil {
+        return cdcConnectorTarget{}, err
+    }
+    hosts := []string{}
+    for host := range tserverHosts {
+        hosts = append(hosts, host)
+    }
+    sort.Strings(hosts)
+    masterAddresses, err := helpers.GetMasterAddresses()
+    if err != nil {
+        return cdcConnectorTarget{}, err
+    }
+    return cdcConnectorTarget{
+        hosts: strings.Join(hosts, ","),
+        masterAddresses: masterAddresses,
+    }, nil
+}
+
+// Builds the config of a Debezium YugabyteDB connector that reads a stream, either as the
+// config of a Kafka Connect connector or as the properties of Debezium Server
+func getCdcConnectorConfig(format string, name string, streamId string, database string,
+    tables string, target cdcConnectorTarget) models.CdcConnectorConfig {
+    config := helpers.GetConfig()
+    source := map[string]string{
+        "connector.class": CDC_CONNECTOR_CLASS,
+        "database.hostname": target.hosts,
+        "database.port": strconv.Itoa(config.Database.YsqlPort),
+        "database.master.addresses": target.masterAddresses,
+        "database.user": config.Auth.YsqlUsername,
+        "database.password": CDC_CONNECTOR_PLACEHOLDER_PASSWORD,
+        "database.dbname": database,
+        "database.streamid": streamId,
+        "table.include.list": tables,
+        "topic.prefix": name,
+        // Read by connectors older than Debezium 2, in place of topic.prefix
+        "database.server.name": name,
+        "snapshot.mode": "never",
+    }
+    if config.Tls.Enabled {
+        source["database.sslmode"] = config.Tls.SslMode
+    }
+    if format == CDC_CONNECTOR_FORMAT_KAFKA_CONNECT {
+        return models.CdcConnectorConfig{Format: format, Name: name, Config: source}
+    }
+    properties := map[string]string{
+        "debezium.sink.type": "kafka",
+        "debezium.sink.kafka.producer.bootstrap.servers":
+            CDC_CONNECTOR_PLACEHOLDER_BOOTSTRAP_SERVERS,
+        "debezium.sink.kafka.producer.key.serializer":
+            "org.apache.kafka.common.serialization.StringSerializer",
+        "debezium.sink.kafka.producer.value.serializer":
+            "org.apache.kafka.common.serialization.StringSerializer",
+    }
+    for key, value := range source {
+        properties["debezium.source."+key] = value
+    }
+    return models.CdcConnectorConfig{Format: format, Name: name, Config: properties}
+}
+
+// Gets how far the readers of each stream that has checkpoints are, by stream id
+func getCdcStreamHealth(session *gocql.Session, now time.Time) (
+    map[string]*models.CdcStreamHealth, error) {
+    streams := map[string]*models.CdcStreamHealth{}
+    iter := session.Query(YCQL_CDC_STATE_CQL).Iter()
+    var streamId string
+    var lastReplicationTime time.Time
+    for iter.Scan(&streamId, &lastReplicationTime) {
+        stream, ok := streams[streamId]
+        if !ok {
+            stream = &models.CdcStreamHealth{StreamId: streamId, Connectors: []string{}}
+            streams[streamId] = stream
+        }
+        stream.Tablets++
+        if lastReplicationTime.IsZero() {
+            stream.TabletsNotRead++
+            continue
+        }
+        timestamp := lastReplicationTime.Unix()
+        if stream.OldestReplicationTimestamp == nil ||
+            timestamp < *stream.OldestReplicationTimestamp {
+            lagSeconds := now.Unix() - timestamp
+            stream.OldestReplicationTimestamp = &timestamp
+            stream.LagSeconds = &lagSeconds
+        }
+    }
+    if err := iter.Close(); err != nil {
+        return nil, err
+    }
+    return streams, nil
+}
+
+// Whether a connector of Kafka Connect reads from YugabyteDB, with either the gRPC or the
+// logical replication connector
+func isYugabyteDbConnector(connector helpers.KafkaConnectConnector) bool {
+    return strings.HasPrefix(connector.Info.Config["connector.class"],
+        "io.debezium.connector.yugabytedb.")
+}
+
+func getCdcConnectorHealth(name string,
+    connector helpers.KafkaConnectConnector) models.CdcConnectorHealth {
+    status := connector.Status.Connector
+    tasks := []models.CdcConnectorTask{}
+    for _, task := range connector.Status.Tasks {
+        tasks = append(tasks, models.CdcConnectorTask{
+            Id: int32(task.Id),
+            State: task.State,
+            WorkerId: task.WorkerId,
+            Trace: task.Trace,
+        })
+    }
+    sort.Slice(tasks, func(i, j int) bool {
+        return tasks[i].Id < tasks[j].Id
+    })
+    return models.CdcConnectorHealth{
+        Name: name,
+        State: status.State,
+        WorkerId: status.WorkerId,
+        Trace: status.Trace,
+        Tasks: tasks,
+        Database: connector.Info.Config["database.dbname"],
+        StreamId: connector.Info.Config["database.streamid"],
+    }
+}
+
+// Matches the connectors to the streams they read
+func getCdcHealth(connectors map[string]helpers.KafkaConnectConnector,
+    streams map[string]*models.CdcStreamHealth) models.CdcHealth {
+    health := models.CdcHealth{
+        KafkaConnectEnabled: helpers.GetConfig().KafkaConnect.Url != "",
+        Connectors: []models.CdcConnectorHealth{},
+        Streams: []models.CdcStreamHealth{},
+    }
+    for name, connector := range connectors {
+        if !isYugabyteDbConnector(connector) {
+            continue
+        }
+        connectorHealth := getCdcConnectorHealth(name, connector)
+        if stream, ok := streams[connectorHealth.StreamId]; ok {
+            connectorHealth.StreamFound = true
+            stream.Connectors = append(stream.Connectors, name)
+        }
+        health.Connectors = append(health.Connectors, connectorHealth)
+    }
+    sort.Slice(health.Connectors, func(i, j int) bool {
+        return health.Connectors[i].Name < health.Connectors[j].Name
+    })
+    for _, stream := range streams {
+        sort.Strings(stream.Connectors)
+        health.Streams = append(health.Streams, *stream)
+    }
+    sort.Slice(health.Streams, func(i, j int) bool {
+        return health.Streams[i].StreamId < health.Streams[j].StreamId
+    })
+    return health
+}
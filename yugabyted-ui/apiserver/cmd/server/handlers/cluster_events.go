@@ -0,0 +1,312 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Types of the cluster events
+const CLUSTER_EVENT_NODE_ADDED = "node_added"
+const CLUSTER_EVENT_NODE_REMOVED = "node_removed"
+const CLUSTER_EVENT_NODE_DIED = "node_died"
+const CLUSTER_EVENT_NODE_RECOVERED = "node_recovered"
+const CLUSTER_EVENT_LEADER_CHANGED = "leader_changed"
+const CLUSTER_EVENT_VERSION_CHANGED = "version_changed"
+const CLUSTER_EVENT_GFLAG_CHANGED = "gflag_changed"
+const CLUSTER_EVENT_REPORT_GENERATED = "report_generated"
+
+var CLUSTER_EVENT_TYPES = []string{CLUSTER_EVENT_NODE_ADDED, CLUSTER_EVENT_NODE_REMOVED,
+    CLUSTER_EVENT_NODE_DIED, CLUSTER_EVENT_NODE_RECOVERED, CLUSTER_EVENT_LEADER_CHANGED,
+    CLUSTER_EVENT_VERSION_CHANGED, CLUSTER_EVENT_GFLAG_CHANGED, VERSION_FINDING_MIXED_VERSIONS,
+    VERSION_FINDING_CRITICAL_BUILD, VERSION_FINDING_END_OF_LIFE, CLUSTER_EVENT_REPORT_GENERATED}
+
+// How often the detector checks whether the poll interval was turned on, while it is off
+const CLUSTER_EVENTS_IDLE_INTERVAL = time.Minute
+
+// The parts of the cluster state that events are detected in. The versions and gflags of the
+// nodes that could not be reached are missing, and are not compared.
+type clusterEventState struct {
+    // Status of each tserver, by host
+    nodes map[string]string
+    // Host of the master leader, empty if there is none or the masters could not be listed
+    masterLeader string
+    // Version of each node, by host
+    versions map[string]string
+    // gflags of the tserver of each node, by host
+    gflags map[string]map[string]string
+    // Findings of the version check of the versions
+    versionFindings []models.VersionFinding
+}
+
+// Polls the cluster at every events.poll_interval, records the differences from the previous
+// poll as events in the local store and posts them to the webhooks. The first poll after a
+// start is only compared with the following ones, so changes made while the server was down
+// are not recorded.
+type clusterEventDetector struct {
+    previous *clusterEventState
+    local localstore.Store
+    webhooks *webhookStore
+    releaseManifests *releaseManifestCache
+    logger logger.Logger
+}
+
+func newClusterEventDetector(log logger.Logger, local localstore.Store, webhooks *webhookStore,
+    releaseManifests *releaseManifestCache) *clusterEventDetector {
+    return &clusterEventDetector{
+        local: local,
+        webhooks: webhooks,
+        releaseManifests: releaseManifests,
+        logger: log,
+    }
+}
+
+func (detector *clusterEventDetector) run() {
+    for {
+        interval := helpers.GetConfig().Events.PollInterval
+        if interval <= 0 {
+            detector.previous = nil
+            time.Sleep(CLUSTER_EVENTS_IDLE_INTERVAL)
+            continue
+        }
+        state, err := getClusterEventState()
+        if err != nil {
+            detector.logger.Debugf("failed to poll the cluster for events: %s", err.Error())
+        } else {
+            manifest, _, _ := detector.releaseManifests.get()
+            state.versionFindings = getVersionFindings(manifest, state.versions, time.Now())
+            if detector.previous != nil {
+                detector.record(getClusterEvents(*detector.previous, state,
+                    time.Now().Unix()))
+            }
+            detector.previous = &state
+        }
+        time.Sleep(interval)
+    }
+}
+
+// Gets the tservers and master leader, and the versions and gflags of the alive nodes
+func getClusterEventState() (clusterEventState, error) {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    mastersFuture := make(chan helpers.MastersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    go helpers.GetMastersFuture(helpers.HOST, mastersFuture)
+    tabletServers := <-tabletServersFuture
+    masters := <-mastersFuture
+    if tabletServers.Error != nil {
+        return clusterEventState{}, tabletServers.Error
+    }
+    state := clusterEventState{
+        nodes: map[string]string{},
+        versions: map[string]string{},
+        gflags: map[string]map[string]string{},
+    }
+    for _, node := range getClusterStateNodes(tabletServers) {
+        state.nodes[node.Name] = node.Status
+    }
+    if masters.Error == nil {
+        for _, master := range getClusterStateMasters(masters) {
+            if master.Role == "LEADER" {
+                state.masterLeader = master.Host
+            }
+        }
+    }
+    fanOut := newFanOutLimiter()
+    versionInfoFutures := map[string]chan helpers.VersionInfoFuture{}
+    gFlagsFutures := map[string]chan helpers.GFlagsFuture{}
+    for host, status := range state.nodes {
+        if status != "ALIVE" {
+            continue
+        }
+        host := host
+        versionInfoFuture := make(chan helpers.VersionInfoFuture, 1)
+        versionInfoFutures[host] = versionInfoFuture
+        fanOut.goCall(func() { helpers.GetVersionFuture(host, versionInfoFuture) })
+        gFlagsFuture := make(chan helpers.GFlagsFuture, 1)
+        gFlagsFutures[host] = gFlagsFuture
+        fanOut.goCall(func() { helpers.GetGFlagsFuture(host, false, gFlagsFuture) })
+    }
+    for host, versionInfoFuture := range versionInfoFutures {
+        if versionInfo := <-versionInfoFuture; versionInfo.Error == nil {
+            state.versions[host] = getNodeVersion(versionInfo.VersionInfo)
+        }
+    }
+    for host, gFlagsFuture := range gFlagsFutures {
+        if gFlags := <-gFlagsFuture; gFlags.Error == nil {
+            state.gflags[host] = gFlags.GFlags
+        }
+    }
+    return state, nil
+}
+
+func newClusterEvent(eventType string, node string, now int64, message string,
+    details map[string]string) models.ClusterEvent {
+    return models.ClusterEvent{
+        Type: eventType,
+        Time: now,
+        Node: node,
+        Message: message,
+        Details: details,
+    }
+}
+
+// Compares two polls of the cluster, returning the events sorted by node. Findings of the
+// version check are raised when they were not found by the previous poll.
+func getClusterEvents(previous clusterEventState, current clusterEventState,
+    now int64) []models.ClusterEvent {
+    events := []models.ClusterEvent{}
+    for host, status := range current.nodes {
+        previousStatus, ok := previous.nodes[host]
+        switch {
+        case !ok:
+            events = append(events, newClusterEvent(CLUSTER_EVENT_NODE_ADDED, host, now,
+                fmt.Sprintf("node %s was added", host), map[string]string{"status": status}))
+        case previousStatus == "ALIVE" && status != "ALIVE":
+            events = append(events, newClusterEvent(CLUSTER_EVENT_NODE_DIED, host, now,
+                fmt.Sprintf("node %s died", host), map[string]string{"status": status}))
+        case previousStatus != "ALIVE" && status == "ALIVE":
+            events = append(events, newClusterEvent(CLUSTER_EVENT_NODE_RECOVERED, host, now,
+                fmt.Sprintf("node %s recovered", host), map[string]string{"status": status}))
+        }
+    }
+    for host := range previous.nodes {
+        if _, ok := current.nodes[host]; !ok {
+            events = append(events, newClusterEvent(CLUSTER_EVENT_NODE_REMOVED, host, now,
+                fmt.Sprintf("node %s was removed", host), map[string]string{}))
+        }
+    }
+    if previous.masterLeader != "" && current.masterLeader != "" &&
+        previous.masterLeader != current.masterLeader {
+        events = append(events, newClusterEvent(CLUSTER_EVENT_LEADER_CHANGED,
+            current.masterLeader, now,
+            fmt.Sprintf("the master leader moved from %s to %s", previous.masterLeader,
+                current.masterLeader),
+            map[string]string{"from": previous.masterLeader, "to": current.masterLeader}))
+    }
+    for host, version := range current.versions {
+        if previousVersion, ok := previous.versions[host]; ok && previousVersion != version {
+            events = append(events, newClusterEvent(CLUSTER_EVENT_VERSION_CHANGED, host, now,
+                fmt.Sprintf("node %s went from version %s to %s", host, previousVersion,
+                    version),
+                map[string]string{"from": previousVersion, "to": version}))
+        }
+    }
+    for host, gflags := range current.gflags {
+        previousGflags, ok := previous.gflags[host]
+        if !ok {
+            continue
+        }
+        names := []string{}
+        for name, value := range gflags {
+            if previousValue, ok := previousGflags[name]; !ok || previousValue != value {
+                names = append(names, name)
+            }
+        }
+        for name := range previousGflags {
+            if _, ok := gflags[name]; !ok {
+                names = append(names, name)
+            }
+        }
+        sort.Strings(names)
+        for _, name := range names {
+            events = append(events, newClusterEvent(CLUSTER_EVENT_GFLAG_CHANGED, host, now,
+                fmt.Sprintf("gflag %s of node %s changed from %q to %q", name, host,
+                    previousGflags[name], gflags[name]),
+                map[string]string{
+                    "gflag": name,
+                    "from": previousGflags[name],
+                    "to": gflags[name],
+                }))
+        }
+    }
+    for _, finding := range current.versionFindings {
+        found := false
+        for _, previousFinding := range previous.versionFindings {
+            if previousFinding.Kind == finding.Kind && previousFinding.Version == finding.Version {
+                found = true
+                break
+            }
+        }
+        if !found {
+            events = append(events, newClusterEvent(finding.Kind, finding.Nodes[0], now,
+                finding.Message,
+                map[string]string{
+                    "severity": finding.Severity,
+                    "version": finding.Version,
+                    "nodes": strings.Join(finding.Nodes, ","),
+                }))
+        }
+    }
+    sort.SliceStable(events, func(i, j int) bool {
+        return events[i].Node < events[j].Node
+    })
+    return events
+}
+
+// Keeps the events in the local store, dropping the oldest events beyond events.max_entries,
+// and posts them to the webhooks. Ids start with the time in nanoseconds, so that they sort by
+// time.
+func (detector *clusterEventDetector) record(events []models.ClusterEvent) {
+    if len(events) == 0 {
+        return
+    }
+    nanos := time.Now().UnixNano()
+    for index := range events {
+        events[index].Id = fmt.Sprintf("%019d-%04d", nanos, index)
+        detector.logger.Infof("cluster event: %s", events[index].Message)
+    }
+    detector.webhooks.deliver(events)
+    for _, event := range events {
+        data, err := json.Marshal(event)
+        if err == nil {
+            err = detector.local.Put(STORE_BUCKET_CLUSTER_EVENTS, event.Id, data)
+        }
+        if err != nil {
+            detector.logger.Errorf("failed to keep the cluster events: %s", err.Error())
+            return
+        }
+    }
+    err := detector.local.Trim(STORE_BUCKET_CLUSTER_EVENTS, helpers.GetConfig().Events.MaxEntries)
+    if err != nil {
+        detector.logger.Errorf("failed to trim the cluster events: %s", err.Error())
+    }
+}
+
+// Gets a page of the events between two times, newest first, starting after the event with the
+// id before if it is set. Returns the id to get the next page with, empty on the last page.
+func (detector *clusterEventDetector) list(from int64, to int64, eventType string,
+    before string, limit int) ([]models.ClusterEvent, string, error) {
+    entries, err := detector.local.List(STORE_BUCKET_CLUSTER_EVENTS)
+    if err != nil {
+        return nil, "", err
+    }
+    events := []models.ClusterEvent{}
+    for index := len(entries) - 1; index >= 0; index-- {
+        if before != "" && entries[index].Key >= before {
+            continue
+        }
+        event := models.ClusterEvent{}
+        if err := json.Unmarshal(entries[index].Value, &event); err != nil {
+            detector.logger.Errorf("failed to read cluster event %s: %s", entries[index].Key,
+                err.Error())
+            continue
+        }
+        if event.Time < from {
+            break
+        }
+        if event.Time > to || (eventType != "" && event.Type != eventType) {
+            continue
+        }
+        if len(events) == limit {
+            return events, events[len(events)-1].Id, nil
+        }
+        events = append(events, event)
+    }
+    return events, "", nil
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sync"
+    "unicode"
+    "unicode/utf8"
+)
+
+// Limits on the metadata of a cluster, in characters
+const CLUSTER_METADATA_MAX_NAME_LENGTH = 63
+const CLUSTER_METADATA_MAX_DESCRIPTION_LENGTH = 1024
+const CLUSTER_METADATA_MAX_OWNER_LENGTH = 255
+const CLUSTER_METADATA_MAX_TAGS = 50
+const CLUSTER_METADATA_MAX_TAG_VALUE_LENGTH = 255
+
+var CLUSTER_METADATA_TAG_KEY_REGEX = regexp.MustCompile(`^[A-Za-z0-9_.\-/:]{1,63}$`)
+
+// The metadata of a cluster as written to the file
+type storedClusterMetadata struct {
+    Name string `json:"name"`
+    Description string `json:"description"`
+    Owner string `json:"owner"`
+    Tags map[string]string `json:"tags"`
+    UpdatedBy string `json:"updated_by"`
+    UpdatedAt int64 `json:"updated_at"`
+}
+
+// When a cluster was created, and when its cluster config last changed, as written to the file
+type storedClusterLifecycle struct {
+    CreatedAt int64 `json:"created_at"`
+    ConfigVersion int `json:"config_version"`
+    ConfigUpdatedAt int64 `json:"config_updated_at"`
+}
+
+type clusterMetadataFile struct {
+    // By cluster uuid, so that servers of different clusters on one host can share the file
+    Clusters map[string]storedClusterMetadata `json:"clusters"`
+    Lifecycles map[string]storedClusterLifecycle `json:"lifecycles,omitempty"`
+}
+
+// Keeps the names, descriptions, owners and tags given to clusters in a file, so that they
+// outlive the server, along with when the clusters were created and last changed
+type clusterMetadataStore struct {
+    mutex sync.Mutex
+    clusters map[string]storedClusterMetadata
+    lifecycles map[string]storedClusterLifecycle
+    logger logger.Logger
+}
+
+func newClusterMetadataStore(log logger.Logger) *clusterMetadataStore {
+    store := &clusterMetadataStore{
+        clusters: map[string]storedClusterMetadata{},
+        lifecycles: map[string]storedClusterLifecycle{},
+        logger: log,
+    }
+    data, err := ioutil.ReadFile(helpers.GetConfig().ClusterMetadata.File)
+    if err != nil {
+        if !os.IsNotExist(err) {
+            log.Errorf("failed to read the cluster metadata: %s", err.Error())
+        }
+        return store
+    }
+    metadataFile := clusterMetadataFile{}
+    if err := json.Unmarshal(data, &metadataFile); err != nil {
+        log.Errorf("failed to read the cluster metadata: %s", err.Error())
+        return store
+    }
+    for clusterUuid, metadata := range metadataFile.Clusters {
+        store.clusters[clusterUuid] = metadata
+    }
+    for clusterUuid, lifecycle := range metadataFile.Lifecycles {
+        store.lifecycles[clusterUuid] = lifecycle
+    }
+    return store
+}
+
+// Writes the metadata to a new file that then replaces the old one, so that a crash cannot
+// leave a partly written file. Must be called with the mutex held.
+func (store *clusterMetadataStore) saveLocked() error {
+    data, err := json.MarshalIndent(clusterMetadataFile{
+        Clusters: store.clusters,
+        Lifecycles: store.lifecycles,
+    }, "", "  ")
+    if err != nil {
+        return err
+    }
+    path := helpers.GetConfig().ClusterMetadata.File
+    if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        return err
+    }
+    if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+        return err
+    }
+    return os.Rename(path+".tmp", path)
+}
+
+func getClusterMetadataModel(clusterUuid string,
+    metadata storedClusterMetadata) models.ClusterMetadata {
+    tags := map[string]string{}
+    for key, value := range metadata.Tags {
+        tags[key] = value
+    }
+    return models.ClusterMetadata{
+        ClusterUuid: clusterUuid,
+        Name: metadata.Name,
+        Description: metadata.Description,
+        Owner: metadata.Owner,
+        Tags: tags,
+        UpdatedBy: metadata.UpdatedBy,
+        UpdatedAt: metadata.UpdatedAt,
+    }
+}
+
+// Gets the metadata of a cluster, empty if it was never given any
+func (store *clusterMetadataStore) get(clusterUuid string) models.ClusterMetadata {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    return getClusterMetadataModel(clusterUuid, store.clusters[clusterUuid])
+}
+
+// Replaces the metadata of a cluster. The previous metadata is kept if it cannot be saved.
+func (store *clusterMetadataStore) set(clusterUuid string, spec models.ClusterMetadataSpec,
+    updatedBy string, updatedAt int64) (models.ClusterMetadata, error) {
+    metadata := storedClusterMetadata{
+        Name: spec.Name,
+        Description: spec.Description,
+        Owner: spec.Owner,
+        Tags: map[string]string{},
+        UpdatedBy: updatedBy,
+        UpdatedAt: updatedAt,
+    }
+    for key, value := range spec.Tags {
+        metadata.Tags[key] = value
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    previous, existed := store.clusters[clusterUuid]
+    store.clusters[clusterUuid] = metadata
+    if err := store.saveLocked(); err != nil {
+        if existed {
+            store.clusters[clusterUuid] = previous
+        } else {
+            delete(store.clusters, clusterUuid)
+        }
+        return models.ClusterMetadata{}, err
+    }
+    return getClusterMetadataModel(clusterUuid, metadata), nil
+}
+
+// Gets when a cluster was created and last updated, in seconds. The cluster is created as of the
+// first time it was seen, or of the oldest start time of its masters if that is older, which
+// after the masters restart is still the start time seen before. It is updated when its config
+// version goes up, or its metadata is set. The times are kept in memory if they cannot be
+// saved, to be saved along with the next change.
+func (store *clusterMetadataStore) getLifecycle(clusterUuid string, startedAt int64,
+    configVersion int, now int64) (int64, int64) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    lifecycle, ok := store.lifecycles[clusterUuid]
+    changed := !ok
+    if !ok {
+        lifecycle = storedClusterLifecycle{
+            CreatedAt: startedAt,
+            ConfigVersion: configVersion,
+            ConfigUpdatedAt: startedAt,
+        }
+    }
+    if startedAt < lifecycle.CreatedAt {
+        lifecycle.CreatedAt = startedAt
+        changed = true
+    }
+    if configVersion > lifecycle.ConfigVersion {
+        lifecycle.ConfigVersion = configVersion
+        lifecycle.ConfigUpdatedAt = now
+        changed = true
+    }
+    if changed {
+        store.lifecycles[clusterUuid] = lifecycle
+        if err := store.saveLocked(); err != nil {
+            store.logger.Errorf("failed to save when the cluster was created: %s", err.Error())
+        }
+    }
+    updatedAt := lifecycle.ConfigUpdatedAt
+    if metadata := store.clusters[clusterUuid]; metadata.UpdatedAt > updatedAt {
+        updatedAt = metadata.UpdatedAt
+    }
+    return lifecycle.CreatedAt, updatedAt
+}
+
+// Checks that a text field is short enough and free of control characters, which would garble
+// the pages and logs it shows up in
+func validateMetadataText(field string, value string, maxLength int) error {
+    if utf8.RuneCountInString(value) > maxLength {
+        return fmt.Errorf("%s must be at most %d characters long", field, maxLength)
+    }
+    for _, character := range value {
+        if unicode.IsControl(character) && !(field == "description" && character == '\n') {
+            return fmt.Errorf("%s must not contain control characters", field)
+        }
+    }
+    return nil
+}
+
+func validateClusterMetadataSpec(spec models.ClusterMetadataSpec) error {
+    if err := validateMetadataText("name", spec.Name,
+        CLUSTER_METADATA_MAX_NAME_LENGTH); err != nil {
+        return err
+    }
+    if err := validateMetadataText("description", spec.Description,
+        CLUSTER_METADATA_MAX_DESCRIPTION_LENGTH); err != nil {
+        return err
+    }
+    if err := validateMetadataText("owner", spec.Owner,
+        CLUSTER_METADATA_MAX_OWNER_LENGTH); err != nil {
+        return err
+    }
+    if len(spec.Tags) > CLUSTER_METADATA_MAX_TAGS {
+        return fmt.Errorf("there can be at most %d tags, got %d", CLUSTER_METADATA_MAX_TAGS,
+            len(spec.Tags))
+    }
+    for key, value := range spec.Tags {
+        if !CLUSTER_METADATA_TAG_KEY_REGEX.MatchString(key) {
+            return fmt.Errorf("tag key %q must be 1 to 63 letters, digits or characters _.-/:",
+                key)
+        }
+        if err := validateMetadataText(fmt.Sprintf("the value of tag %s", key), value,
+            CLUSTER_METADATA_MAX_TAG_VALUE_LENGTH); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// The cluster state is only polled while clients asked for changes within this long
+const CLUSTER_STATE_IDLE_TIMEOUT = 2 * time.Minute
+
+const CLUSTER_STATE_NODES = "nodes"
+const CLUSTER_STATE_MASTERS = "masters"
+const CLUSTER_STATE_HEALTH = "health"
+const CLUSTER_STATE_CLUSTER_CONFIG = "cluster_config"
+
+type clusterStateSection struct {
+    value interface{}
+    // JSON of the value, to tell whether it changed
+    encoded string
+    // Version of the state at which the section last changed
+    version int64
+}
+
+// Polls the parts of the cluster state that the UI refreshes, and numbers every change so that
+// clients can wait for the sections that changed since the version they have. Cursors are made
+// of the start time of the server and the version, so that cursors from before a restart are
+// not mistaken for current ones.
+type clusterStateTracker struct {
+    mutex sync.Mutex
+    epoch string
+    version int64
+    sections map[string]*clusterStateSection
+    // Closed and replaced whenever the state changes
+    changed chan struct{}
+    lastRequest time.Time
+    // Wakes the poller up when a request comes in while it is idle
+    wake chan struct{}
+    logger logger.Logger
+}
+
+func newClusterStateTracker(log logger.Logger) *clusterStateTracker {
+    tracker := &clusterStateTracker{
+        epoch: strconv.FormatInt(time.Now().UnixNano(), 36),
+        sections: map[string]*clusterStateSection{},
+        changed: make(chan struct{}),
+        wake: make(chan struct{}, 1),
+        logger: log,
+    }
+    go tracker.pollLoop()
+    return tracker
+}
+
+// Records that a client is waiting for changes, starting the poller if it is idle
+func (tracker *clusterStateTracker) touch() {
+    tracker.mutex.Lock()
+    idle := time.Since(tracker.lastRequest) >= CLUSTER_STATE_IDLE_TIMEOUT
+    tracker.lastRequest = time.Now()
+    tracker.mutex.Unlock()
+    if idle {
+        select {
+        case tracker.wake <- struct{}{}:
+        default:
+        }
+    }
+}
+
+func (tracker *clusterStateTracker) pollLoop() {
+    for {
+        tracker.mutex.Lock()
+        active := time.Since(tracker.lastRequest) < CLUSTER_STATE_IDLE_TIMEOUT
+        tracker.mutex.Unlock()
+        if active {
+            tracker.poll()
+        }
+        select {
+        case <-time.After(helpers.GetConfig().Upstream.NodePollInterval):
+        case <-tracker.wake:
+        }
+    }
+}
+
+// Fetches every section, keeping the previous value of the sections that failed
+func (tracker *clusterStateTracker) poll() {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    mastersFuture := make(chan helpers.MastersFuture)
+    healthCheckFuture := make(chan helpers.HealthCheckFuture)
+    clusterConfigFuture := make(chan helpers.ClusterConfigFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    go helpers.GetMastersFuture(helpers.HOST, mastersFuture)
+    go helpers.GetHealthCheckFuture(helpers.HOST, healthCheckFuture)
+    go helpers.GetClusterConfigFuture(helpers.HOST, clusterConfigFuture)
+    values := map[string]interface{}{}
+    if tabletServers := <-tabletServersFuture; tabletServers.Error == nil {
+        values[CLUSTER_STATE_NODES] = getClusterStateNodes(tabletServers)
+    } else {
+        tracker.logger.Debugf("failed to poll the tservers: %s", tabletServers.Error.Error())
+    }
+    if masters := <-mastersFuture; masters.Error == nil {
+        values[CLUSTER_STATE_MASTERS] = getClusterStateMasters(masters)
+    } else {
+        tracker.logger.Debugf("failed to poll the masters: %s", masters.Error.Error())
+    }
+    if healthCheck := <-healthCheckFuture; healthCheck.Error == nil {
+        values[CLUSTER_STATE_HEALTH] = getClusterStateHealth(healthCheck)
+    } else {
+        tracker.logger.Debugf("failed to poll the health check: %s", healthCheck.Error.Error())
+    }
+    if clusterConfig := <-clusterConfigFuture; clusterConfig.Error == nil {
+        values[CLUSTER_STATE_CLUSTER_CONFIG] = getClusterStateConfig(clusterConfig)
+    } else {
+        tracker.logger.Debugf("failed to poll the cluster config: %s",
+            clusterConfig.Error.Error())
+    }
+    tracker.update(values)
+}
+
+func (tracker *clusterStateTracker) update(values map[string]interface{}) {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    changed := false
+    for name, value := range values {
+        encoded, err := json.Marshal(value)
+        if err != nil {
+            continue
+        }
+        section, ok := tracker.sections[name]
+        if ok && section.encoded == string(encoded) {
+            continue
+        }
+        if !changed {
+            changed = true
+            tracker.version++
+        }
+        tracker.sections[name] = &clusterStateSection{
+            value: value,
+            encoded: string(encoded),
+            version: tracker.version,
+        }
+    }
+    if changed {
+        close(tracker.changed)
+        tracker.changed = make(chan struct{})
+    }
+}
+
+// Gets the sections that changed after the version in the cursor, all of them if the cursor is
+// empty or not from this run of the server. The returned channel is closed on the next change.
+func (tracker *clusterStateTracker) getChanges(
+    cursor string,
+) (models.ClusterStateChanges, bool, chan struct{}) {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    since := int64(-1)
+    if parts := strings.SplitN(cursor, ".", 2); len(parts) == 2 && parts[0] == tracker.epoch {
+        if version, err := strconv.ParseInt(parts[1], 10, 64); err == nil &&
+            version <= tracker.version {
+            since = version
+        }
+    }
+    changes := models.ClusterStateChanges{
+        Cursor: fmt.Sprintf("%s.%d", tracker.epoch, tracker.version),
+    }
+    hasChanges := false
+    for name, section := range tracker.sections {
+        if section.version <= since {
+            continue
+        }
+        hasChanges = true
+        switch value := section.value.(type) {
+        case []models.ClusterStateNode:
+            changes.Nodes = &value
+        case []models.ClusterStateMaster:
+            changes.Masters = &value
+        case models.ClusterStateHealth:
+            changes.Health = &value
+        case models.ClusterStateConfig:
+            changes.ClusterConfig = &value
+        default:
+            tracker.logger.Errorf("unknown cluster state section %s", name)
+        }
+    }
+    return changes, hasChanges, tracker.changed
+}
+
+func getClusterStateNodes(tabletServers helpers.TabletServersFuture) []models.ClusterStateNode {
+    nodes := []models.ClusterStateNode{}
+    for _, cluster := range tabletServers.Tablets {
+        for address, tabletServer := range cluster {
+            host, err := helpers.GetHostFromAddress(address)
+            if err != nil {
+                continue
+            }
+            nodes = append(nodes, models.ClusterStateNode{
+                Name: host,
+                Status: tabletServer.Status,
+                Cloud: tabletServer.Cloud,
+                Region: tabletServer.Region,
+                Zone: tabletServer.Zone,
+            })
+        }
+    }
+    sort.Slice(nodes, func(i, j int) bool {
+        return nodes[i].Name < nodes[j].Name
+    })
+    return nodes
+}
+
+func getClusterStateMasters(masters helpers.MastersFuture) []models.ClusterStateMaster {
+    stateMasters := []models.ClusterStateMaster{}
+    for _, master := range masters.Masters {
+        host := ""
+        if len(master.Registration.PrivateRpcAddresses) > 0 {
+            host = helpers.NormalizeHost(master.Registration.PrivateRpcAddresses[0].Host)
+        }
+        stateMasters = append(stateMasters, models.ClusterStateMaster{
+            Uuid: master.InstanceId.PermanentUuid,
+            Host: host,
+            Role: master.Role,
+            IsReachable: master.Error == nil,
+        })
+    }
+    sort.Slice(stateMasters, func(i, j int) bool {
+        return stateMasters[i].Uuid < stateMasters[j].Uuid
+    })
+    return stateMasters
+}
+
+func getClusterStateHealth(healthCheck helpers.HealthCheckFuture) models.ClusterStateHealth {
+    health := models.ClusterStateHealth{
+        DeadNodes: append([]string{}, healthCheck.HealthCheck.DeadNodes...),
+        UnderReplicatedTablets: append([]string{},
+            healthCheck.HealthCheck.UnderReplicatedTablets...),
+    }
+    sort.Strings(health.DeadNodes)
+    sort.Strings(health.UnderReplicatedTablets)
+    return health
+}
+
+func getClusterStateConfig(clusterConfig helpers.ClusterConfigFuture) models.ClusterStateConfig {
+    config := clusterConfig.ClusterConfig
+    return models.ClusterStateConfig{
+        Version: int32(config.Version),
+        ClusterUuid: config.ClusterUuid,
+        ReplicationFactor: int32(config.ReplicationInfo.LiveReplicas.NumReplicas),
+        EncryptionAtRest: config.EncryptionInfo.EncryptionEnabled,
+    }
+}
@@ -0,0 +1,546 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "apiserver/cmd/server/tasks"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// How often the scheduler checks whether a compaction window has started
+const COMPACTION_CHECK_INTERVAL = time.Minute
+
+const COMPACTION_SCHEDULE_MAX_NAME_LENGTH = 100
+const COMPACTION_SCHEDULE_MAX_DURATION_MINUTES = 24 * 60
+const COMPACTION_SCHEDULE_MAX_TARGET_LENGTH = 255
+
+// States of the run of a window
+const COMPACTION_RUN_STATE_RUNNING = "running"
+const COMPACTION_RUN_STATE_SUCCEEDED = "succeeded"
+const COMPACTION_RUN_STATE_FAILED = "failed"
+// The window ended before all the tables and nodes were compacted
+const COMPACTION_RUN_STATE_INCOMPLETE = "incomplete"
+// Nothing was compacted in the window
+const COMPACTION_RUN_STATE_SKIPPED = "skipped"
+
+// Days of the week a window can start on, in the order of time.Weekday
+var COMPACTION_SCHEDULE_DAYS = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+var errCompactionWindowEnded = errors.New("the window ended before the compactions were done")
+
+// A schedule as written to the file
+type storedCompactionSchedule struct {
+    Id string `json:"id"`
+    Name string `json:"name"`
+    StartTime string `json:"start_time"`
+    DurationMinutes int32 `json:"duration_minutes"`
+    Days []string `json:"days"`
+    TableIds []string `json:"table_ids"`
+    Nodes []string `json:"nodes"`
+    Enabled bool `json:"enabled"`
+    CreatedBy string `json:"created_by"`
+    CreatedAt int64 `json:"created_at"`
+}
+
+type compactionSchedulesFile struct {
+    Schedules []storedCompactionSchedule `json:"schedules"`
+}
+
+// Starts the compactions of a window of a schedule, records the run of the window with addRun
+// and returns it
+type compactionStarter func(schedule storedCompactionSchedule, windowStart time.Time,
+    windowEnd time.Time) models.CompactionRun
+
+// Keeps the compaction schedules in a file, and runs full compactions of their tables and nodes
+// when their windows start. The runs of the windows, including the skipped ones, are only kept
+// in memory. A window that is already open when the server starts or when its schedule is
+// created is run as well.
+type compactionScheduler struct {
+    mutex sync.Mutex
+    schedules map[string]storedCompactionSchedule
+    // Oldest first, by schedule id
+    runs map[string][]models.CompactionRun
+    // Start of the last window handled of each schedule
+    lastWindowStarts map[string]time.Time
+    logger logger.Logger
+}
+
+func newCompactionScheduler(log logger.Logger) *compactionScheduler {
+    scheduler := &compactionScheduler{
+        schedules: map[string]storedCompactionSchedule{},
+        runs: map[string][]models.CompactionRun{},
+        lastWindowStarts: map[string]time.Time{},
+        logger: log,
+    }
+    data, err := ioutil.ReadFile(helpers.GetConfig().Compaction.SchedulesFile)
+    if err != nil {
+        if !os.IsNotExist(err) {
+            log.Errorf("failed to read the compaction schedules: %s", err.Error())
+        }
+        return scheduler
+    }
+    schedulesFile := compactionSchedulesFile{}
+    if err := json.Unmarshal(data, &schedulesFile); err != nil {
+        log.Errorf("failed to read the compaction schedules: %s", err.Error())
+        return scheduler
+    }
+    for _, schedule := range schedulesFile.Schedules {
+        scheduler.schedules[schedule.Id] = schedule
+    }
+    return scheduler
+}
+
+// Writes the schedules to a new file that then replaces the old one, so that a crash cannot
+// leave a partly written file. Must be called with the mutex held.
+func (scheduler *compactionScheduler) saveLocked() error {
+    schedulesFile := compactionSchedulesFile{Schedules: []storedCompactionSchedule{}}
+    for _, schedule := range scheduler.schedules {
+        schedulesFile.Schedules = append(schedulesFile.Schedules, schedule)
+    }
+    sort.Slice(schedulesFile.Schedules, func(i, j int) bool {
+        return schedulesFile.Schedules[i].Id < schedulesFile.Schedules[j].Id
+    })
+    data, err := json.MarshalIndent(schedulesFile, "", "  ")
+    if err != nil {
+        return err
+    }
+    path := helpers.GetConfig().Compaction.SchedulesFile
+    if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        return err
+    }
+    if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+        return err
+    }
+    return os.Rename(path+".tmp", path)
+}
+
+// Parses a time of day given as HH:MM
+func parseCompactionStartTime(startTime string) (time.Duration, error) {
+    parsed, err := time.Parse("15:04", startTime)
+    if err != nil {
+        return 0, fmt.Errorf("start_time must be HH:MM, got %q", startTime)
+    }
+    return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute,
+        nil
+}
+
+func compactionScheduleRunsOn(schedule storedCompactionSchedule, day time.Weekday) bool {
+    return len(schedule.Days) == 0 ||
+        containsString(schedule.Days, COMPACTION_SCHEDULE_DAYS[day])
+}
+
+// Gets the start of the window of a schedule that is open at a time, if there is one. Windows
+// start at their time of day in UTC, and may end on the next day.
+func getCompactionWindowStart(schedule storedCompactionSchedule,
+    now time.Time) (time.Time, bool) {
+    startOffset, err := parseCompactionStartTime(schedule.StartTime)
+    if err != nil {
+        return time.Time{}, false
+    }
+    duration := time.Duration(schedule.DurationMinutes) * time.Minute
+    now = now.UTC()
+    today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+    for _, day := range []time.Time{today, today.AddDate(0, 0, -1)} {
+        windowStart := day.Add(startOffset)
+        if compactionScheduleRunsOn(schedule, windowStart.Weekday()) &&
+            !windowStart.After(now) && now.Before(windowStart.Add(duration)) {
+            return windowStart, true
+        }
+    }
+    return time.Time{}, false
+}
+
+// Gets the start of the next window of a schedule after a time, or 0 if it is disabled
+func getNextCompactionWindowStart(schedule storedCompactionSchedule, now time.Time) int64 {
+    startOffset, err := parseCompactionStartTime(schedule.StartTime)
+    if err != nil || !schedule.Enabled {
+        return 0
+    }
+    now = now.UTC()
+    today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+    for days := 0; days <= 7; days++ {
+        windowStart := today.AddDate(0, 0, days).Add(startOffset)
+        if windowStart.After(now) && compactionScheduleRunsOn(schedule, windowStart.Weekday()) {
+            return windowStart.Unix()
+        }
+    }
+    return 0
+}
+
+func getCompactionScheduleModel(schedule storedCompactionSchedule,
+    now time.Time) models.CompactionSchedule {
+    return models.CompactionSchedule{
+        Id: schedule.Id,
+        Name: schedule.Name,
+        StartTime: schedule.StartTime,
+        DurationMinutes: schedule.DurationMinutes,
+        Days: append([]string{}, schedule.Days...),
+        TableIds: append([]string{}, schedule.TableIds...),
+        Nodes: append([]string{}, schedule.Nodes...),
+        Enabled: schedule.Enabled,
+        CreatedBy: schedule.CreatedBy,
+        CreatedAt: schedule.CreatedAt,
+        NextWindowStart: getNextCompactionWindowStart(schedule, now),
+    }
+}
+
+func validateCompactionScheduleSpec(spec models.CompactionScheduleSpec) error {
+    if spec.Name == "" {
+        return errors.New("name must not be empty")
+    }
+    if err := validateMetadataText("name", spec.Name,
+        COMPACTION_SCHEDULE_MAX_NAME_LENGTH); err != nil {
+        return err
+    }
+    if _, err := parseCompactionStartTime(spec.StartTime); err != nil {
+        return err
+    }
+    if spec.DurationMinutes < 1 ||
+        spec.DurationMinutes > COMPACTION_SCHEDULE_MAX_DURATION_MINUTES {
+        return fmt.Errorf("duration_minutes must be between 1 and %d",
+            COMPACTION_SCHEDULE_MAX_DURATION_MINUTES)
+    }
+    for _, day := range spec.Days {
+        if !containsString(COMPACTION_SCHEDULE_DAYS, day) {
+            return fmt.Errorf("days must be among %s, got %q",
+                strings.Join(COMPACTION_SCHEDULE_DAYS, ", "), day)
+        }
+    }
+    if len(spec.TableIds) == 0 && len(spec.Nodes) == 0 {
+        return errors.New("at least one of table_ids and nodes must be set")
+    }
+    for _, tableId := range spec.TableIds {
+        if tableId == "" {
+            return errors.New("table_ids must not contain empty ids")
+        }
+        if err := validateMetadataText("table_ids", tableId,
+            COMPACTION_SCHEDULE_MAX_TARGET_LENGTH); err != nil {
+            return err
+        }
+    }
+    for _, node := range spec.Nodes {
+        if node == "" {
+            return errors.New("nodes must not contain empty hosts")
+        }
+        if err := validateMetadataText("nodes", node,
+            COMPACTION_SCHEDULE_MAX_TARGET_LENGTH); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func getStoredCompactionSchedule(id string, spec models.CompactionScheduleSpec,
+    createdBy string, createdAt int64) storedCompactionSchedule {
+    return storedCompactionSchedule{
+        Id: id,
+        Name: spec.Name,
+        StartTime: spec.StartTime,
+        DurationMinutes: spec.DurationMinutes,
+        Days: append([]string{}, spec.Days...),
+        TableIds: append([]string{}, spec.TableIds...),
+        Nodes: append([]string{}, spec.Nodes...),
+        Enabled: spec.Enabled == nil || *spec.Enabled,
+        CreatedBy: createdBy,
+        CreatedAt: createdAt,
+    }
+}
+
+// Gets the schedules, in the order they were created
+func (scheduler *compactionScheduler) list() []models.CompactionSchedule {
+    now := time.Now()
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    schedules := []models.CompactionSchedule{}
+    for _, schedule := range scheduler.schedules {
+        schedules = append(schedules, getCompactionScheduleModel(schedule, now))
+    }
+    sort.Slice(schedules, func(i, j int) bool {
+        if schedules[i].CreatedAt != schedules[j].CreatedAt {
+            return schedules[i].CreatedAt < schedules[j].CreatedAt
+        }
+        return schedules[i].Id < schedules[j].Id
+    })
+    return schedules
+}
+
+func (scheduler *compactionScheduler) create(spec models.CompactionScheduleSpec,
+    createdBy string) (models.CompactionSchedule, error) {
+    idBytes := make([]byte, 8)
+    if _, err := rand.Read(idBytes); err != nil {
+        return models.CompactionSchedule{}, err
+    }
+    now := time.Now()
+    schedule := getStoredCompactionSchedule(hex.EncodeToString(idBytes), spec, createdBy,
+        now.Unix())
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    scheduler.schedules[schedule.Id] = schedule
+    if err := scheduler.saveLocked(); err != nil {
+        delete(scheduler.schedules, schedule.Id)
+        return models.CompactionSchedule{}, err
+    }
+    return getCompactionScheduleModel(schedule, now), nil
+}
+
+// Replaces a schedule, keeping its runs. Returns false if there is no such schedule.
+func (scheduler *compactionScheduler) update(id string,
+    spec models.CompactionScheduleSpec) (models.CompactionSchedule, bool, error) {
+    now := time.Now()
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    previous, ok := scheduler.schedules[id]
+    if !ok {
+        return models.CompactionSchedule{}, false, nil
+    }
+    schedule := getStoredCompactionSchedule(id, spec, previous.CreatedBy, previous.CreatedAt)
+    scheduler.schedules[id] = schedule
+    if err := scheduler.saveLocked(); err != nil {
+        scheduler.schedules[id] = previous
+        return models.CompactionSchedule{}, true, err
+    }
+    return getCompactionScheduleModel(schedule, now), true, nil
+}
+
+// Deletes a schedule and its runs. A run that is in progress goes on until it is done. Returns
+// false if there is no such schedule.
+func (scheduler *compactionScheduler) delete(id string) (bool, error) {
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    schedule, ok := scheduler.schedules[id]
+    if !ok {
+        return false, nil
+    }
+    delete(scheduler.schedules, id)
+    if err := scheduler.saveLocked(); err != nil {
+        scheduler.schedules[id] = schedule
+        return true, err
+    }
+    delete(scheduler.runs, id)
+    delete(scheduler.lastWindowStarts, id)
+    return true, nil
+}
+
+// Gets the runs of a schedule, newest first. Returns false if there is no such schedule.
+func (scheduler *compactionScheduler) listRuns(id string) ([]models.CompactionRun, bool) {
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    if _, ok := scheduler.schedules[id]; !ok {
+        return nil, false
+    }
+    runs := []models.CompactionRun{}
+    scheduleRuns := scheduler.runs[id]
+    for index := len(scheduleRuns) - 1; index >= 0; index-- {
+        runs = append(runs, scheduleRuns[index])
+    }
+    return runs, true
+}
+
+func (scheduler *compactionScheduler) run(start compactionStarter) {
+    for {
+        scheduler.check(time.Now(), start)
+        time.Sleep(COMPACTION_CHECK_INTERVAL)
+    }
+}
+
+// Handles the windows that opened since the previous check. A window is skipped if the run of
+// the previous window of its schedule is still going on.
+func (scheduler *compactionScheduler) check(now time.Time, start compactionStarter) {
+    type window struct {
+        schedule storedCompactionSchedule
+        start time.Time
+    }
+    windows := []window{}
+    scheduler.mutex.Lock()
+    for id, schedule := range scheduler.schedules {
+        if !schedule.Enabled {
+            continue
+        }
+        windowStart, ok := getCompactionWindowStart(schedule, now)
+        if !ok || windowStart.Equal(scheduler.lastWindowStarts[id]) {
+            continue
+        }
+        scheduler.lastWindowStarts[id] = windowStart
+        windows = append(windows, window{schedule: schedule, start: windowStart})
+    }
+    scheduler.mutex.Unlock()
+    for _, window := range windows {
+        windowEnd := window.start.Add(
+            time.Duration(window.schedule.DurationMinutes) * time.Minute)
+        var run models.CompactionRun
+        if scheduler.isRunning(window.schedule.Id) {
+            run = getSkippedCompactionRun(window.schedule.Id, window.start, windowEnd,
+                "the run of the previous window is still going on")
+            scheduler.addRun(run)
+        } else {
+            run = start(window.schedule, window.start, windowEnd)
+        }
+        if run.State == COMPACTION_RUN_STATE_SKIPPED {
+            scheduler.logger.Infof("skipped compaction window of schedule %s: %s",
+                window.schedule.Id, run.Reason)
+        } else if run.State == COMPACTION_RUN_STATE_FAILED {
+            scheduler.logger.Errorf("failed to start compaction window of schedule %s: %s",
+                window.schedule.Id, run.Reason)
+        } else {
+            scheduler.logger.Infof("started compaction window of schedule %s in task %s",
+                window.schedule.Id, run.TaskId)
+        }
+    }
+}
+
+func (scheduler *compactionScheduler) isRunning(id string) bool {
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    for _, run := range scheduler.runs[id] {
+        if run.State == COMPACTION_RUN_STATE_RUNNING {
+            return true
+        }
+    }
+    return false
+}
+
+func (scheduler *compactionScheduler) addRun(run models.CompactionRun) {
+    maxRuns := helpers.GetConfig().Compaction.MaxRuns
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    if _, ok := scheduler.schedules[run.ScheduleId]; !ok {
+        return
+    }
+    runs := append(scheduler.runs[run.ScheduleId], run)
+    if len(runs) > maxRuns {
+        runs = append([]models.CompactionRun{}, runs[len(runs)-maxRuns:]...)
+    }
+    scheduler.runs[run.ScheduleId] = runs
+}
+
+// Changes the run of a window, if it is still kept
+func (scheduler *compactionScheduler) updateRun(id string, windowStart int64,
+    change func(run *models.CompactionRun)) {
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    runs := scheduler.runs[id]
+    for index := range runs {
+        if runs[index].WindowStart == windowStart {
+            change(&runs[index])
+        }
+    }
+}
+
+func getSkippedCompactionRun(id string, windowStart time.Time, windowEnd time.Time,
+    reason string) models.CompactionRun {
+    return models.CompactionRun{
+        ScheduleId: id,
+        WindowStart: windowStart.Unix(),
+        WindowEnd: windowEnd.Unix(),
+        State: COMPACTION_RUN_STATE_SKIPPED,
+        Reason: reason,
+    }
+}
+
+// Submits the task that compacts the tables and nodes of a window, unless the cluster is under
+// maintenance
+func (c *Container) startCompactionWindow(schedule storedCompactionSchedule,
+    windowStart time.Time, windowEnd time.Time) models.CompactionRun {
+    now := time.Now()
+    if c.maintenance.coversCluster(now.Unix()) {
+        run := getSkippedCompactionRun(schedule.Id, windowStart, windowEnd,
+            "the cluster is under maintenance")
+        c.compactionSchedules.addRun(run)
+        return run
+    }
+    run := models.CompactionRun{
+        ScheduleId: schedule.Id,
+        WindowStart: windowStart.Unix(),
+        WindowEnd: windowEnd.Unix(),
+        State: COMPACTION_RUN_STATE_RUNNING,
+        StartedAt: now.Unix(),
+    }
+    // The run is only added once the task is submitted, so the task waits for it before it
+    // updates it
+    submitted := make(chan struct{})
+    task, err := c.tasks.Submit("compact", func(task *tasks.Task) error {
+        <-submitted
+        err := c.compactWindow(task, schedule, windowStart.Unix(), windowEnd)
+        state := COMPACTION_RUN_STATE_SUCCEEDED
+        reason := ""
+        if err != nil {
+            state = COMPACTION_RUN_STATE_FAILED
+            reason = err.Error()
+            if errors.Is(err, errCompactionWindowEnded) || !time.Now().Before(windowEnd) {
+                state = COMPACTION_RUN_STATE_INCOMPLETE
+            }
+        }
+        c.compactionSchedules.updateRun(schedule.Id, windowStart.Unix(),
+            func(run *models.CompactionRun) {
+                run.State = state
+                run.Reason = reason
+                run.FinishedAt = time.Now().Unix()
+            })
+        return err
+    })
+    if err != nil {
+        run.State = COMPACTION_RUN_STATE_FAILED
+        run.Reason = err.Error()
+        run.FinishedAt = now.Unix()
+        c.compactionSchedules.addRun(run)
+        return run
+    }
+    run.TaskId = task.Id
+    c.compactionSchedules.addRun(run)
+    close(submitted)
+    return run
+}
+
+// Compacts the tables and then the nodes of a schedule, one at a time. Nothing new is started
+// once the window ends, and each compaction is given until the end of the window.
+func (c *Container) compactWindow(task *tasks.Task, schedule storedCompactionSchedule,
+    windowStart int64, windowEnd time.Time) error {
+    for index, tableId := range schedule.TableIds {
+        remaining := time.Until(windowEnd)
+        if remaining < time.Second {
+            return errCompactionWindowEnded
+        }
+        task.Progress("compacting table %s (%d of %d)", tableId, index+1,
+            len(schedule.TableIds))
+        if _, err := helpers.RunYbAdminWithTimeout(remaining, "compact_table_by_id", tableId,
+            strconv.Itoa(int(remaining.Seconds()))); err != nil {
+            return fmt.Errorf("failed to compact table %s: %s", tableId, err.Error())
+        }
+        c.compactionSchedules.updateRun(schedule.Id, windowStart,
+            func(run *models.CompactionRun) {
+                run.TablesCompacted++
+            })
+    }
+    for index, node := range schedule.Nodes {
+        remaining := time.Until(windowEnd)
+        if remaining < time.Second {
+            return errCompactionWindowEnded
+        }
+        task.Progress("compacting the tablets of node %s (%d of %d)", node, index+1,
+            len(schedule.Nodes))
+        if _, err := helpers.RunYbTsCliWithTimeout(remaining, node, false,
+            "compact_all_tablets"); err != nil {
+            return fmt.Errorf("failed to compact node %s: %s", node, err.Error())
+        }
+        c.compactionSchedules.updateRun(schedule.Id, windowStart,
+            func(run *models.CompactionRun) {
+                run.NodesCompacted++
+            })
+    }
+    task.Progress("compacted %d tables and %d nodes", len(schedule.TableIds),
+        len(schedule.Nodes))
+    return nil
+}
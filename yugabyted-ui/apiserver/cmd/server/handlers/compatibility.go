@@ -0,0 +1,83 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "regexp"
+    "strconv"
+)
+
+const YSQL_SERVER_VERSION_SQL string = "SELECT current_setting('server_version')"
+
+const YSQL_INSTALLED_EXTENSIONS_SQL string = "SELECT extname, extversion FROM pg_extension " +
+    "ORDER BY extname"
+
+// The server version of YSQL, e.g. 11.2-YB-2.20.1.0-b0, made of the PostgreSQL version the
+// cluster is compatible with and the YugabyteDB version
+var YSQL_SERVER_VERSION_REGEX = regexp.MustCompile(`^(([0-9]+)[0-9.]*)-YB-([0-9.]+)`)
+
+// A PostgreSQL feature that YSQL does not support, at least before a version
+type pgFeature struct {
+    name string
+    description string
+    // YugabyteDB version that supports the feature, empty if none does yet
+    supportedFrom string
+    // PostgreSQL major version that introduced the feature, 0 if every supported one has it
+    minPgMajorVersion int
+}
+
+var UNSUPPORTED_PG_FEATURES = []pgFeature{
+    {name: "table_inheritance",
+        description: "Tables cannot inherit from other tables with INHERITS"},
+    {name: "exclusion_constraints",
+        description: "EXCLUDE constraints cannot be created"},
+    {name: "gist_indexes",
+        description: "GiST, SP-GiST and BRIN indexes cannot be created, use lsm or ybgin indexes"},
+    {name: "two_phase_commit",
+        description: "PREPARE TRANSACTION and COMMIT PREPARED are not supported"},
+    {name: "listen_notify",
+        description: "LISTEN and NOTIFY are not supported"},
+    {name: "advisory_locks",
+        description: "pg_advisory_lock and the other advisory lock functions are not supported",
+        supportedFrom: "2.25.0"},
+    {name: "logical_replication_subscriptions",
+        description: "CREATE SUBSCRIPTION is not supported, use CDC streams to read changes"},
+    {name: "unlogged_tables",
+        description: "UNLOGGED is ignored, the tables are replicated like other tables"},
+    {name: "merge",
+        description: "The MERGE statement is not supported",
+        minPgMajorVersion: 15},
+}
+
+// Splits the YSQL server version into the PostgreSQL version, its major version and the
+// YugabyteDB version. The versions are empty if it cannot be parsed.
+func parseYsqlServerVersion(serverVersion string) (string, int, string) {
+    match := YSQL_SERVER_VERSION_REGEX.FindStringSubmatch(serverVersion)
+    if match == nil {
+        return "", 0, ""
+    }
+    pgMajorVersion, _ := strconv.Atoi(match[2])
+    return match[1], pgMajorVersion, match[3]
+}
+
+// Gets the PostgreSQL features that the given versions do not support. All features are
+// reported when the YugabyteDB version is unknown.
+func getUnsupportedPgFeatures(pgMajorVersion int,
+    ybVersion string) []models.UnsupportedPgFeature {
+    features := []models.UnsupportedPgFeature{}
+    for _, feature := range UNSUPPORTED_PG_FEATURES {
+        if feature.minPgMajorVersion > pgMajorVersion {
+            continue
+        }
+        if feature.supportedFrom != "" && ybVersion != "" &&
+            helpers.CompareVersions(ybVersion, feature.supportedFrom) >= 0 {
+            continue
+        }
+        features = append(features, models.UnsupportedPgFeature{
+            Name: feature.name,
+            Description: feature.description,
+            SupportedFrom: feature.supportedFrom,
+        })
+    }
+    return features
+}
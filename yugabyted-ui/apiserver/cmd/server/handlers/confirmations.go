@@ -0,0 +1,58 @@
+package handlers
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "sync"
+    "time"
+)
+
+// How long a confirmation token can be used after it was issued
+const CONFIRMATION_TOKEN_TTL = 1 * time.Minute
+
+type confirmation struct {
+    action string
+    expiresAt time.Time
+}
+
+// Keeps the tokens that confirm dangerous operations. A token is issued for one action and
+// can be used once.
+type confirmationStore struct {
+    mutex sync.Mutex
+    tokens map[string]confirmation
+}
+
+func newConfirmationStore() *confirmationStore {
+    return &confirmationStore{tokens: map[string]confirmation{}}
+}
+
+// Issues a token confirming the action
+func (s *confirmationStore) issue(action string) (string, time.Time, error) {
+    bytes := make([]byte, 16)
+    if _, err := rand.Read(bytes); err != nil {
+        return "", time.Time{}, err
+    }
+    token := hex.EncodeToString(bytes)
+    expiresAt := time.Now().Add(CONFIRMATION_TOKEN_TTL)
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    for existingToken, existing := range s.tokens {
+        if time.Now().After(existing.expiresAt) {
+            delete(s.tokens, existingToken)
+        }
+    }
+    s.tokens[token] = confirmation{action: action, expiresAt: expiresAt}
+    return token, expiresAt, nil
+}
+
+// Checks whether the token confirms the action, and uses it up if so
+func (s *confirmationStore) consume(token string, action string) bool {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    existing, ok := s.tokens[token]
+    if !ok || existing.action != action {
+        return false
+    }
+    delete(s.tokens, token)
+    return time.Now().Before(existing.expiresAt)
+}
@@ -1,21 +1,106 @@
 package handlers
 
 import (
+        "apiserver/cmd/server/localstore"
         "apiserver/cmd/server/logger"
+        "apiserver/cmd/server/tasks"
 
-        "github.com/jackc/pgx/v4"
+        "github.com/jackc/pgx/v4/pgxpool"
         "github.com/yugabyte/gocql"
 )
 
 // Container will hold all dependencies for your application.
 type Container struct {
-        logger  logger.Logger
-        Session *gocql.Session
-        Conn    *pgx.Conn
+        logger              logger.Logger
+        ycql                *ycqlSessionManager
+        Conn                *pgxpool.Pool
+        tasks               *tasks.TaskManager
+        confirmations       *confirmationStore
+        hostToUuid          *hostToUuidCache
+        fallbackMetrics     *fallbackMetrics
+        responseCache       *responseCache
+        clusterState        *clusterStateTracker
+        reports             *reportScheduler
+        databaseDumps       *databaseDumpStore
+        profiles            *profileStore
+        sessions            *sessionStore
+        apiTokens           *apiTokenStore
+        shells              *shellTracker
+        clusterMetadata     *clusterMetadataStore
+        encryptionAtRest    *encryptionAtRestTracker
+        gflagDocs           *gflagDocsCache
+        alertRules          *alertRuleStore
+        alerts              *alertEvaluator
+        maintenance         *maintenanceWindowStore
+        compactionSchedules *compactionScheduler
+        metricsCleaner      *metricsCleaner
+        metricsDownsampler  *metricsDownsampler
+        localStore          localstore.Store
+        clusterEvents       *clusterEventDetector
+        webhooks            *webhookStore
+        releaseManifests    *releaseManifestCache
+        prober              *prober
+        uptime              *uptimeTracker
+        workloads           *workloadTracker
+        ddlActivity         *ddlActivityCollector
+        statsd              *statsdEmitter
+        remoteWriter        *remoteWriter
+        nodeResources       *nodeResourcesCache
 }
 
 // NewContainer returns an empty or an initialized container for your handlers.
-func NewContainer(logger logger.Logger, session *gocql.Session, conn *pgx.Conn) (Container, error) {
-        c := Container{logger, session, conn}
+func NewContainer(logger logger.Logger, cluster *gocql.ClusterConfig, conn *pgxpool.Pool) (Container, error) {
+        localStore, err := openLocalStore(logger)
+        if err != nil {
+                return Container{}, err
+        }
+        hostToUuid := newHostToUuidCache(logger)
+        webhooks := newWebhookStore(logger, localStore)
+        releaseManifests := newReleaseManifestCache(logger)
+        fallbackMetrics := newFallbackMetrics(logger, hostToUuid)
+        clusterEvents := newClusterEventDetector(logger, localStore, webhooks, releaseManifests)
+        alerts := newAlertEvaluator(logger, localStore)
+        c := Container{logger, newYcqlSessionManager(logger, cluster), conn,
+                tasks.NewTaskManager(logger, localStore),
+                newConfirmationStore(), hostToUuid, fallbackMetrics,
+                newResponseCache(), newClusterStateTracker(logger),
+                newReportScheduler(logger, localStore, clusterEvents, alerts),
+                newDatabaseDumpStore(logger), newProfileStore(logger), newSessionStore(),
+                newApiTokenStore(logger, localStore), newShellTracker(),
+                newClusterMetadataStore(logger), newEncryptionAtRestTracker(), newGflagDocsCache(),
+                newAlertRuleStore(logger, localStore), alerts,
+                newMaintenanceWindowStore(logger),
+                newCompactionScheduler(logger), newMetricsCleaner(logger),
+                newMetricsDownsampler(logger), localStore,
+                clusterEvents, webhooks,
+                releaseManifests, newProber(logger, localStore),
+                newUptimeTracker(logger, localStore), newWorkloadTracker(),
+                newDdlActivityCollector(logger, localStore), newStatsdEmitter(logger),
+                newRemoteWriter(logger, fallbackMetrics), newNodeResourcesCache()}
+        go c.reports.run(c.generateReport)
+        go c.alerts.run(c.alertRules, c.maintenance, c.getAlertValues)
+        go c.compactionSchedules.run(c.startCompactionWindow)
+        go c.metricsCleaner.run(c.getYcqlSession)
+        go c.metricsDownsampler.run(c.getYcqlSession, c.getDownsampleSource)
+        go c.clusterEvents.run()
+        go c.prober.run()
+        go c.uptime.run(c.getApiHealth)
+        go c.ddlActivity.run()
+        go c.statsd.run(c.getStatsdGauges)
+        go c.remoteWriter.run()
+        go c.nodeResources.run()
         return c, nil
 }
+
+// getYcqlSession returns the YCQL session, which is created on first use.
+func (c *Container) getYcqlSession() (*gocql.Session, error) {
+        return c.ycql.get()
+}
+
+// Close releases the connections and the local store held by the container.
+func (c *Container) Close() {
+        c.ycql.close()
+        if err := c.localStore.Close(); err != nil {
+                c.logger.Errorf("failed to close the local store: %s", err.Error())
+        }
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+    "encoding/csv"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/labstack/echo/v4"
+)
+
+const EXPORT_FORMAT_JSON = "json"
+const EXPORT_FORMAT_CSV = "csv"
+const EXPORT_FORMAT_TSV = "tsv"
+
+const MIME_TEXT_CSV = "text/csv"
+const MIME_TEXT_TSV = "text/tab-separated-values"
+
+// Number of rows written between flushes of a streamed CSV response
+const CSV_STREAM_FLUSH_ROWS = 1000
+
+// Gets the format a list is requested in, from the format query parameter or else from the
+// Accept header. JSON is the default.
+func getExportFormat(ctx echo.Context) (string, error) {
+    switch format := strings.ToLower(ctx.QueryParam("format")); format {
+    case "":
+    case EXPORT_FORMAT_JSON, EXPORT_FORMAT_CSV, EXPORT_FORMAT_TSV:
+        return format, nil
+    default:
+        return "", fmt.Errorf("format must be json, csv or tsv, got %s", format)
+    }
+    for _, mediaRange := range strings.Split(ctx.Request().Header.Get(echo.HeaderAccept), ",") {
+        switch strings.TrimSpace(strings.SplitN(mediaRange, ";", 2)[0]) {
+        case MIME_TEXT_CSV:
+            return EXPORT_FORMAT_CSV, nil
+        case MIME_TEXT_TSV:
+            return EXPORT_FORMAT_TSV, nil
+        }
+    }
+    return EXPORT_FORMAT_JSON, nil
+}
+
+// Writes a CSV or TSV response row by row. Like a JSON stream, the status goes out with the
+// first write, so errors must be handled before the stream is started.
+type csvStream struct {
+    response *echo.Response
+    writer *csv.Writer
+    count int
+}
+
+func newCsvStream(ctx echo.Context, format string, status int) *csvStream {
+    response := ctx.Response()
+    writer := csv.NewWriter(response)
+    if format == EXPORT_FORMAT_TSV {
+        writer.Comma = '\t'
+        response.Header().Set(echo.HeaderContentType, MIME_TEXT_TSV+"; charset=UTF-8")
+    } else {
+        response.Header().Set(echo.HeaderContentType, MIME_TEXT_CSV+"; charset=UTF-8")
+    }
+    response.WriteHeader(status)
+    return &csvStream{response: response, writer: writer}
+}
+
+func (stream *csvStream) row(fields ...string) {
+    stream.writer.Write(fields)
+    stream.count++
+    if stream.count%CSV_STREAM_FLUSH_ROWS == 0 {
+        stream.flush()
+    }
+}
+
+func (stream *csvStream) flush() {
+    stream.writer.Flush()
+    if flusher, ok := stream.response.Writer.(http.Flusher); ok {
+        flusher.Flush()
+    }
+}
+
+// Writes the rows that are still buffered, returning the first error hit while streaming
+func (stream *csvStream) close() error {
+    stream.flush()
+    return stream.writer.Error()
+}
+
+func formatCsvFloat(value float64) string {
+    return strconv.FormatFloat(value, 'f', -1, 64)
+}
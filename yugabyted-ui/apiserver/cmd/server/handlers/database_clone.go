@@ -0,0 +1,260 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/tasks"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/jackc/pgx/v4"
+)
+
+// Versions from which yb-admin clone_namespace clones a database instantly, sharing its files.
+// Older versions fall back to a snapshot of the database restored into a new one.
+const INSTANT_CLONE_MIN_VERSION = "2.21.1"
+
+// How often the progress of a clone, a snapshot or a restore is checked
+const DATABASE_CLONE_POLL_INTERVAL = 5 * time.Second
+
+var CLONE_STARTED_REGEX = regexp.MustCompile(
+    `(?i)source namespace id:?\s*([0-9a-f]+)\W+seq no:?\s*([0-9]+)`)
+var SNAPSHOT_STARTED_REGEX = regexp.MustCompile(`Started snapshot creation: ([0-9a-f-]+)`)
+var IMPORTED_SNAPSHOT_REGEX = regexp.MustCompile(`(?m)^Snapshot\s+[0-9a-f-]+\s+([0-9a-f-]+)`)
+var RESTORATION_STARTED_REGEX = regexp.MustCompile(`Restoration id: ([0-9a-f-]+)`)
+
+// A clone as printed by yb-admin list_clones
+type namespaceClone struct {
+    AggregateState string `json:"aggregate_state"`
+    AbortMessage string `json:"abort_message"`
+}
+
+// Gets the smallest version of the nodes, empty if it is unknown
+func getSmallestNodeVersion() (string, error) {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return "", tabletServersResponse.Error
+    }
+    fanOut := newFanOutLimiter()
+    versionInfoFutures := []chan helpers.VersionInfoFuture{}
+    for _, nodeHost := range helpers.GetNodesList(tabletServersResponse) {
+        nodeHost := nodeHost
+        versionInfoFuture := make(chan helpers.VersionInfoFuture, 1)
+        versionInfoFutures = append(versionInfoFutures, versionInfoFuture)
+        fanOut.goCall(func() { helpers.GetVersionFuture(nodeHost, versionInfoFuture) })
+    }
+    return helpers.GetSmallestVersion(versionInfoFutures), nil
+}
+
+// Waits until a check reports that an operation is done, for at most the time left until a
+// deadline
+func waitForDatabaseClone(deadline time.Time, what string, isDone func() (bool, error)) error {
+    for {
+        done, err := isDone()
+        if err != nil || done {
+            return err
+        }
+        if time.Now().Add(DATABASE_CLONE_POLL_INTERVAL).After(deadline) {
+            return fmt.Errorf("timed out waiting for %s", what)
+        }
+        time.Sleep(DATABASE_CLONE_POLL_INTERVAL)
+    }
+}
+
+// Clones a database with yb-admin clone_namespace, as of a time or now if the time is zero. The
+// database must be covered by a snapshot schedule.
+func cloneDatabaseInstantly(task *tasks.Task, source string, target string, cloneTime int64,
+    deadline time.Time) error {
+    args := []string{"clone_namespace", "ysql." + source, target}
+    if cloneTime != 0 {
+        args = append(args, strconv.FormatInt(cloneTime*1000000, 10))
+    }
+    task.Progress("cloning database %s into %s", source, target)
+    output, err := helpers.RunYbAdmin(args...)
+    if err != nil {
+        return err
+    }
+    match := CLONE_STARTED_REGEX.FindStringSubmatch(output)
+    if match == nil {
+        return fmt.Errorf("unexpected clone_namespace output: %s", output)
+    }
+    return waitForDatabaseClone(deadline, "the clone", func() (bool, error) {
+        output, err := helpers.RunYbAdmin("list_clones", match[1], match[2])
+        if err != nil {
+            return false, err
+        }
+        clones := []namespaceClone{}
+        if err := json.Unmarshal([]byte(output), &clones); err != nil || len(clones) == 0 {
+            return false, fmt.Errorf("unexpected list_clones output: %s", output)
+        }
+        switch clones[0].AggregateState {
+        case "COMPLETE":
+            return true, nil
+        case "ABORTED":
+            return false, fmt.Errorf("the clone was aborted: %s", clones[0].AbortMessage)
+        }
+        task.Progress("the clone is %s", strings.ToLower(clones[0].AggregateState))
+        return false, nil
+    })
+}
+
+// Clones a database as it is now by creating the target with the schema of the source, then
+// restoring a snapshot of the source into it. The schema is dumped along with the tablets of
+// the tables, so that the snapshot can be imported.
+func (c *Container) cloneDatabaseWithSnapshot(task *tasks.Task, source string, target string,
+    deadline time.Time) error {
+    directory, err := ioutil.TempDir("", "yugabyted-ui-clone")
+    if err != nil {
+        return err
+    }
+    defer os.RemoveAll(directory)
+    schemaPath := filepath.Join(directory, "schema.sql")
+    snapshotPath := filepath.Join(directory, "snapshot")
+    task.Progress("dumping the schema of database %s", source)
+    if _, err := helpers.RunYsqlDump(source, "--schema-only", "--include-yb-metadata",
+        "--serializable-deferrable", "--file", schemaPath); err != nil {
+        return err
+    }
+    task.Progress("taking a snapshot of database %s", source)
+    output, err := helpers.RunYbAdmin("create_database_snapshot", "ysql."+source)
+    if err != nil {
+        return err
+    }
+    match := SNAPSHOT_STARTED_REGEX.FindStringSubmatch(output)
+    if match == nil {
+        return fmt.Errorf("unexpected create_database_snapshot output: %s", output)
+    }
+    snapshotId := match[1]
+    defer func() {
+        if _, err := helpers.RunYbAdmin("delete_snapshot", snapshotId); err != nil {
+            c.logger.Errorf("failed to delete snapshot %s of the clone of %s: %s", snapshotId,
+                source, err.Error())
+        }
+    }()
+    err = waitForDatabaseClone(deadline, "the snapshot", func() (bool, error) {
+        return isSnapshotInState(snapshotId, "COMPLETE")
+    })
+    if err != nil {
+        return err
+    }
+    if _, err := helpers.RunYbAdmin("export_snapshot", snapshotId, snapshotPath); err != nil {
+        return err
+    }
+    task.Progress("creating database %s", target)
+    // The shared connection of the server cannot be used outside of its requests
+    conn, err := pgx.Connect(context.Background(), helpers.GetYsqlConnectionUrl(source))
+    if err != nil {
+        return err
+    }
+    defer conn.Close(context.Background())
+    _, err = conn.Exec(context.Background(), "CREATE DATABASE "+pgx.Identifier{target}.Sanitize())
+    if err != nil {
+        return err
+    }
+    if err := c.restoreDatabaseClone(task, target, schemaPath, snapshotPath,
+        deadline); err != nil {
+        // A database that is half restored is of no use
+        _, dropErr := conn.Exec(context.Background(),
+            "DROP DATABASE "+pgx.Identifier{target}.Sanitize())
+        if dropErr != nil {
+            c.logger.Errorf("failed to drop database %s after its clone failed: %s", target,
+                dropErr.Error())
+        }
+        return err
+    }
+    return nil
+}
+
+// Creates the schema of a clone in its database, then restores the data of the source into it
+func (c *Container) restoreDatabaseClone(task *tasks.Task, target string, schemaPath string,
+    snapshotPath string, deadline time.Time) error {
+    task.Progress("creating the schema of database %s", target)
+    if _, err := helpers.RunYsqlsh(time.Until(deadline), target, "--set", "ON_ERROR_STOP=1",
+        "--quiet", "--file", schemaPath); err != nil {
+        return err
+    }
+    task.Progress("restoring the data into database %s", target)
+    output, err := helpers.RunYbAdmin("import_snapshot", snapshotPath, "ysql."+target)
+    if err != nil {
+        return err
+    }
+    match := IMPORTED_SNAPSHOT_REGEX.FindStringSubmatch(output)
+    if match == nil {
+        return fmt.Errorf("unexpected import_snapshot output: %s", output)
+    }
+    importedId := match[1]
+    defer func() {
+        if _, err := helpers.RunYbAdmin("delete_snapshot", importedId); err != nil {
+            c.logger.Errorf("failed to delete imported snapshot %s of the clone %s: %s",
+                importedId, target, err.Error())
+        }
+    }()
+    output, err = helpers.RunYbAdmin("restore_snapshot", importedId)
+    if err != nil {
+        return err
+    }
+    match = RESTORATION_STARTED_REGEX.FindStringSubmatch(output)
+    if match == nil {
+        return fmt.Errorf("unexpected restore_snapshot output: %s", output)
+    }
+    restorationId := match[1]
+    return waitForDatabaseClone(deadline, "the restore", func() (bool, error) {
+        return isRestorationInState(restorationId, "RESTORED")
+    })
+}
+
+// Gets the states of the snapshots, or of the restorations, printed by yb-admin list_snapshots
+func getSnapshotStates(restorations bool) (map[string]string, error) {
+    output, err := helpers.RunYbAdmin("list_snapshots")
+    if err != nil {
+        return nil, err
+    }
+    states := map[string]string{}
+    inRestorations := false
+    for _, line := range strings.Split(output, "\n") {
+        if strings.HasPrefix(strings.TrimSpace(line), "Restoration UUID") {
+            inRestorations = true
+            continue
+        }
+        fields := strings.Fields(line)
+        if inRestorations == restorations && len(fields) >= 2 &&
+            YB_ADMIN_ID_REGEX.MatchString(fields[0]) {
+            states[fields[0]] = fields[1]
+        }
+    }
+    return states, nil
+}
+
+func isSnapshotInState(snapshotId string, state string) (bool, error) {
+    states, err := getSnapshotStates(false)
+    if err != nil {
+        return false, err
+    }
+    if states[snapshotId] == "FAILED" {
+        return false, fmt.Errorf("snapshot %s failed", snapshotId)
+    }
+    return states[snapshotId] == state, nil
+}
+
+func isRestorationInState(restorationId string, state string) (bool, error) {
+    states, err := getSnapshotStates(true)
+    if err != nil {
+        return false, err
+    }
+    if states[restorationId] == "FAILED" {
+        return false, fmt.Errorf("restoration %s failed", restorationId)
+    }
+    if _, ok := states[restorationId]; !ok {
+        return false, fmt.Errorf("restoration %s not found", restorationId)
+    }
+    return states[restorationId] == state, nil
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+)
+
+// Dumps are plain SQL compressed with gzip, restorable with gunzip and ysqlsh
+const DATABASE_DUMP_EXTENSION = ".sql.gz"
+
+// Keeps track of the dumps written by ysql_dump, of which only the latest are kept
+type databaseDumpStore struct {
+    files *retainedFileStore
+}
+
+func newDatabaseDumpStore(log logger.Logger) *databaseDumpStore {
+    return &databaseDumpStore{
+        files: newRetainedFileStore(log, "database dump",
+            helpers.GetConfig().DatabaseDump.Directory, DATABASE_DUMP_EXTENSION),
+    }
+}
+
+func (store *databaseDumpStore) getPath(id string) (string, error) {
+    return getRetainedFilePath(helpers.GetConfig().DatabaseDump.Directory, id,
+        DATABASE_DUMP_EXTENSION)
+}
+
+// Records a dump that was written, deleting the oldest dumps beyond the configured number
+func (store *databaseDumpStore) add(info models.DatabaseDump, path string) {
+    store.files.add(info.Id, info, info.Timestamp, []string{path},
+        helpers.GetConfig().DatabaseDump.MaxDumps)
+}
+
+// Gets the dumps, newest first
+func (store *databaseDumpStore) list() []models.DatabaseDump {
+    dumps := []models.DatabaseDump{}
+    for _, info := range store.files.list() {
+        dumps = append(dumps, info.(models.DatabaseDump))
+    }
+    return dumps
+}
+
+func (store *databaseDumpStore) get(id string) (models.DatabaseDump, string, bool) {
+    info, path, ok := store.files.get(id)
+    if !ok {
+        return models.DatabaseDump{}, "", false
+    }
+    return info.(models.DatabaseDump), path, true
+}
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "context"
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/jackc/pgx/v4"
+)
+
+// Kinds of the DDL activity: DDL statements that ran on a node since the previous poll, and
+// bumps of the version of the YSQL catalog
+const DDL_ACTIVITY_STATEMENT = "statement"
+const DDL_ACTIVITY_CATALOG_VERSION = "catalog_version"
+
+var DDL_ACTIVITY_KINDS = []string{DDL_ACTIVITY_STATEMENT, DDL_ACTIVITY_CATALOG_VERSION}
+
+// The DDL statements that pg_stat_statements tracked on a node, with the number of times each
+// ran. Utility statements are tracked with their text as is.
+const DDL_STATEMENTS_SQL string = "SELECT d.datname, r.rolname, s.queryid, s.query, s.calls " +
+    "FROM pg_stat_statements s JOIN pg_database d ON s.dbid = d.oid " +
+    "JOIN pg_roles r ON s.userid = r.oid WHERE s.query ~* " +
+    `'^\s*(create|alter|drop|truncate|comment|grant|revoke|reindex|security\s+label)\y'`
+
+// The command of a DDL statement, with the kind of object it creates, alters or drops
+var DDL_COMMAND_REGEX = regexp.MustCompile(`(?i)^\s*((create|alter|drop)` +
+    `(\s+(or\s+replace|unique|temp|temporary|unlogged|materialized))*\s+\w+|` +
+    `truncate|comment|grant|revoke|reindex|security\s+label)`)
+
+const YSQL_CATALOG_VERSION_SQL string = "SELECT yb_catalog_version()"
+
+// How often the collector checks whether the poll interval was turned on, while it is off
+const DDL_ACTIVITY_IDLE_INTERVAL = time.Minute
+
+const DDL_ACTIVITY_DEFAULT_LIMIT = 100
+const DDL_ACTIVITY_MAX_LIMIT = 1000
+
+// A DDL statement tracked by pg_stat_statements on a node
+type ddlStatement struct {
+    database string
+    user string
+    query string
+    calls int64
+}
+
+// The DDL statements of each node, by host and then by database, user and query id, and the
+// version of the YSQL catalog, -1 if it could not be read
+type ddlActivityState struct {
+    statements map[string]map[string]ddlStatement
+    catalogVersion int64
+}
+
+// Polls pg_stat_statements of every node and the version of the YSQL catalog at every
+// ddl_activity.poll_interval, and records the DDL statements that ran and the catalog version
+// bumps since the previous poll in the local store. The first poll of a node only sets the
+// statements it is compared with, and so do polls after its stats were reset.
+type ddlActivityCollector struct {
+    previous *ddlActivityState
+    local localstore.Store
+    logger logger.Logger
+}
+
+func newDdlActivityCollector(log logger.Logger, local localstore.Store) *ddlActivityCollector {
+    return &ddlActivityCollector{
+        local: local,
+        logger: log,
+    }
+}
+
+func (collector *ddlActivityCollector) run() {
+    for {
+        interval := helpers.GetConfig().DdlActivity.PollInterval
+        if interval <= 0 {
+            collector.previous = nil
+            time.Sleep(DDL_ACTIVITY_IDLE_INTERVAL)
+            continue
+        }
+        state, err := collector.getState()
+        if err != nil {
+            collector.logger.Debugf("failed to poll the DDL activity: %s", err.Error())
+        } else {
+            if collector.previous != nil {
+                collector.record(getDdlActivity(*collector.previous, state,
+                    time.Now().Unix()))
+            }
+            collector.previous = &state
+        }
+        time.Sleep(interval)
+    }
+}
+
+// Gets the DDL statements of the alive nodes and the catalog version. The statements of the
+// nodes that cannot be read, and the catalog version if it cannot be read, are carried over
+// from the previous poll.
+func (collector *ddlActivityCollector) getState() (ddlActivityState, error) {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServers := <-tabletServersFuture
+    if tabletServers.Error != nil {
+        return ddlActivityState{}, tabletServers.Error
+    }
+    type statementsFuture struct {
+        statements map[string]ddlStatement
+        err error
+    }
+    fanOut := newFanOutLimiter()
+    futures := map[string]chan statementsFuture{}
+    for _, node := range getClusterStateNodes(tabletServers) {
+        if node.Status != "ALIVE" {
+            continue
+        }
+        host := node.Name
+        future := make(chan statementsFuture, 1)
+        futures[host] = future
+        fanOut.goCall(func() {
+            statements, err := getDdlStatements(host)
+            future <- statementsFuture{statements: statements, err: err}
+        })
+    }
+    state := ddlActivityState{
+        statements: map[string]map[string]ddlStatement{},
+        catalogVersion: -1,
+    }
+    if version, err := getYsqlCatalogVersion(); err == nil {
+        state.catalogVersion = version
+    } else {
+        collector.logger.Debugf("failed to read the catalog version: %s", err.Error())
+        if collector.previous != nil {
+            state.catalogVersion = collector.previous.catalogVersion
+        }
+    }
+    for host, future := range futures {
+        result := <-future
+        if result.err == nil {
+            state.statements[host] = result.statements
+            continue
+        }
+        collector.logger.Debugf("failed to read the DDL statements of node %s: %s", host,
+            result.err.Error())
+        if collector.previous != nil {
+            if statements, ok := collector.previous.statements[host]; ok {
+                state.statements[host] = statements
+            }
+        }
+    }
+    return state, nil
+}
+
+// Reads pg_stat_statements of a node, which takes as long at most as a probe of its YSQL layer
+func getDdlStatements(host string) (map[string]ddlStatement, error) {
+    ctx, cancel := context.WithTimeout(context.Background(),
+        helpers.GetConfig().Timeouts.ApiHealthProbe)
+    defer cancel()
+    conn, err := pgx.Connect(ctx, helpers.GetYsqlConnectionUrlForHost(host, helpers.DbName))
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Close(context.Background())
+    rows, err := conn.Query(ctx, DDL_STATEMENTS_SQL)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    statements := map[string]ddlStatement{}
+    for rows.Next() {
+        statement := ddlStatement{}
+        var queryId int64
+        err := rows.Scan(&statement.database, &statement.user, &queryId, &statement.query,
+            &statement.calls)
+        if err != nil {
+            return nil, err
+        }
+        statements[fmt.Sprintf("%s/%s/%d", statement.database, statement.user, queryId)] =
+            statement
+    }
+    return statements, rows.Err()
+}
+
+func getYsqlCatalogVersion() (int64, error) {
+    ctx, cancel := context.WithTimeout(context.Background(),
+        helpers.GetConfig().Timeouts.ApiHealthProbe)
+    defer cancel()
+    conn, err := pgx.Connect(ctx, helpers.GetYsqlConnectionUrl(helpers.DbName))
+    if err != nil {
+        return 0, err
+    }
+    defer conn.Close(context.Background())
+    var version int64
+    err = conn.QueryRow(ctx, YSQL_CATALOG_VERSION_SQL).Scan(&version)
+    return version, err
+}
+
+// Compares two polls, returning the catalog version bump first, then the statements that ran
+// sorted by node and query
+func getDdlActivity(previous ddlActivityState, current ddlActivityState,
+    now int64) []models.DdlActivity {
+    activity := []models.DdlActivity{}
+    if previous.catalogVersion >= 0 && current.catalogVersion > previous.catalogVersion {
+        previousVersion, version := previous.catalogVersion, current.catalogVersion
+        activity = append(activity, models.DdlActivity{
+            Kind: DDL_ACTIVITY_CATALOG_VERSION,
+            Time: now,
+            PreviousCatalogVersion: &previousVersion,
+            CatalogVersion: &version,
+            Message: fmt.Sprintf("the catalog version went from %d to %d", previousVersion,
+                version),
+        })
+    }
+    statements := []models.DdlActivity{}
+    for host, nodeStatements := range current.statements {
+        previousStatements, ok := previous.statements[host]
+        if !ok || isDdlStatsReset(previousStatements, nodeStatements) {
+            continue
+        }
+        for key, statement := range nodeStatements {
+            calls := statement.calls - previousStatements[key].calls
+            if calls <= 0 {
+                continue
+            }
+            statements = append(statements, models.DdlActivity{
+                Kind: DDL_ACTIVITY_STATEMENT,
+                Time: now,
+                Node: host,
+                Database: statement.database,
+                User: statement.user,
+                Query: statement.query,
+                Calls: calls,
+                Message: fmt.Sprintf("%s ran %s on database %s of node %s",
+                    statement.user, getDdlCommand(statement.query), statement.database, host),
+            })
+        }
+    }
+    sort.Slice(statements, func(i, j int) bool {
+        if statements[i].Node != statements[j].Node {
+            return statements[i].Node < statements[j].Node
+        }
+        return statements[i].Query < statements[j].Query
+    })
+    return append(activity, statements...)
+}
+
+// Whether the stats of a node were reset between two polls, which makes a statement run
+// fewer times than before
+func isDdlStatsReset(previous map[string]ddlStatement, current map[string]ddlStatement) bool {
+    for key, statement := range current {
+        if previousStatement, ok := previous[key]; ok && statement.calls < previousStatement.calls {
+            return true
+        }
+    }
+    return false
+}
+
+// Gets the command of a DDL statement for messages, e.g. CREATE TABLE, from its first words
+func getDdlCommand(query string) string {
+    match := DDL_COMMAND_REGEX.FindString(query)
+    if match == "" {
+        return "a DDL statement"
+    }
+    return strings.ToUpper(strings.Join(strings.Fields(match), " "))
+}
+
+// Keeps the activity in the local store, dropping the oldest beyond ddl_activity.max_entries.
+// Ids start with the time in nanoseconds, so that they sort by time.
+func (collector *ddlActivityCollector) record(activity []models.DdlActivity) {
+    if len(activity) == 0 {
+        return
+    }
+    nanos := time.Now().UnixNano()
+    for index := range activity {
+        activity[index].Id = fmt.Sprintf("%019d-%04d", nanos, index)
+        collector.logger.Infof("DDL activity: %s", activity[index].Message)
+        data, err := json.Marshal(activity[index])
+        if err == nil {
+            err = collector.local.Put(STORE_BUCKET_DDL_ACTIVITY, activity[index].Id, data)
+        }
+        if err != nil {
+            collector.logger.Errorf("failed to keep the DDL activity: %s", err.Error())
+            return
+        }
+    }
+    err := collector.local.Trim(STORE_BUCKET_DDL_ACTIVITY,
+        helpers.GetConfig().DdlActivity.MaxEntries)
+    if err != nil {
+        collector.logger.Errorf("failed to trim the DDL activity: %s", err.Error())
+    }
+}
+
+// Gets a page of the activity between two times, newest first, starting after the entry with
+// the id before if it is set. Returns the id to get the next page with, empty on the last page.
+func (collector *ddlActivityCollector) list(from int64, to int64, kind string, node string,
+    before string, limit int) ([]models.DdlActivity, string, error) {
+    entries, err := collector.local.List(STORE_BUCKET_DDL_ACTIVITY)
+    if err != nil {
+        return nil, "", err
+    }
+    activity := []models.DdlActivity{}
+    for index := len(entries) - 1; index >= 0; index-- {
+        if before != "" && entries[index].Key >= before {
+            continue
+        }
+        entry := models.DdlActivity{}
+        if err := json.Unmarshal(entries[index].Value, &entry); err != nil {
+            collector.logger.Errorf("failed to read DDL activity %s: %s", entries[index].Key,
+                err.Error())
+            continue
+        }
+        if entry.Time < from {
+            break
+        }
+        if entry.Time > to || (kind != "" && entry.Kind != kind) ||
+            (node != "" && entry.Node != node) {
+            continue
+        }
+        if len(activity) == limit {
+            return activity, activity[len(activity)-1].Id, nil
+        }
+        activity = append(activity, entry)
+    }
+    return activity, "", nil
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "apiserver/cmd/server/tasks"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "sync"
+    "time"
+)
+
+var ENCRYPTION_KEY_ID_REGEX = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// How long the masters are given to load a new key into memory
+const ENCRYPTION_KEY_LOAD_TIMEOUT = time.Minute
+const ENCRYPTION_KEY_LOAD_POLL_INTERVAL = 2 * time.Second
+
+// How long the compaction of one table may take when encrypting the data written before
+const ENCRYPTION_COMPACTION_TIMEOUT = time.Hour
+
+// Keeps the progress of the last enablement of encryption at rest, of which one runs at a time
+type encryptionAtRestTracker struct {
+    mutex sync.Mutex
+    running bool
+    progress *models.EncryptionAtRestProgress
+}
+
+func newEncryptionAtRestTracker() *encryptionAtRestTracker {
+    return &encryptionAtRestTracker{}
+}
+
+// Marks an enablement as running, returning false if one already is
+func (tracker *encryptionAtRestTracker) start() bool {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    if tracker.running {
+        return false
+    }
+    tracker.running = true
+    return true
+}
+
+// Sets the progress of the enablement that runs in the given task
+func (tracker *encryptionAtRestTracker) begin(taskId string, keyId string) {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    tracker.progress = &models.EncryptionAtRestProgress{
+        TaskId: taskId,
+        KeyId: keyId,
+        StartTimestamp: time.Now().Unix(),
+    }
+}
+
+func (tracker *encryptionAtRestTracker) update(
+    change func(progress *models.EncryptionAtRestProgress)) {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    if tracker.progress != nil {
+        change(tracker.progress)
+    }
+}
+
+func (tracker *encryptionAtRestTracker) finish() {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    tracker.running = false
+    if tracker.progress != nil && tracker.progress.EndTimestamp == nil {
+        endTimestamp := time.Now().Unix()
+        tracker.progress.EndTimestamp = &endTimestamp
+    }
+}
+
+// Gets a copy of the progress of the last enablement, nil if there was none
+func (tracker *encryptionAtRestTracker) get() *models.EncryptionAtRestProgress {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    if tracker.progress == nil {
+        return nil
+    }
+    progress := *tracker.progress
+    return &progress
+}
+
+func isValidEncryptionKeySize(key []byte) bool {
+    return len(key) == 16 || len(key) == 24 || len(key) == 32
+}
+
+// Checks the spec and reads the key it gives, either inline or from a file on this host
+func getEncryptionKey(spec models.EncryptionAtRestSpec) ([]byte, error) {
+    if spec.KeyId != "" && !ENCRYPTION_KEY_ID_REGEX.MatchString(spec.KeyId) {
+        return nil, errors.New(
+            "key_id must be 1 to 64 letters, digits, underscores or dashes")
+    }
+    if (spec.Key == "") == (spec.KeyFile == "") {
+        return nil, errors.New("exactly one of key and key_file must be given")
+    }
+    var key []byte
+    if spec.Key != "" {
+        decoded, err := base64.StdEncoding.DecodeString(spec.Key)
+        if err != nil {
+            return nil, errors.New("key must be base64 encoded")
+        }
+        key = decoded
+    } else {
+        if !filepath.IsAbs(spec.KeyFile) {
+            return nil, errors.New("key_file must be an absolute path")
+        }
+        data, err := ioutil.ReadFile(spec.KeyFile)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read key_file: %s", err.Error())
+        }
+        key = data
+    }
+    if !isValidEncryptionKeySize(key) {
+        return nil, errors.New("the key must be 16, 24 or 32 bytes long")
+    }
+    return key, nil
+}
+
+// Writes the key to a file only this user can read, which yb-admin sends to the masters
+func writeEncryptionKeyFile(key []byte) (string, error) {
+    keyFile, err := ioutil.TempFile("", "yugabyted-ui-key-")
+    if err != nil {
+        return "", err
+    }
+    defer keyFile.Close()
+    if err := keyFile.Chmod(0600); err != nil {
+        os.Remove(keyFile.Name())
+        return "", err
+    }
+    if _, err := keyFile.Write(key); err != nil {
+        os.Remove(keyFile.Name())
+        return "", err
+    }
+    return keyFile.Name(), nil
+}
+
+// Waits for every master to hold the key in memory, until which yb-admin fails
+func waitForEncryptionKeyInMemory(keyId string) error {
+    deadline := time.Now().Add(ENCRYPTION_KEY_LOAD_TIMEOUT)
+    for {
+        _, err := helpers.RunYbAdmin("all_masters_have_universe_key_in_memory", keyId)
+        if err == nil {
+            return nil
+        }
+        if time.Now().After(deadline) {
+            return fmt.Errorf("the masters did not load the key %s in %s: %w", keyId,
+                ENCRYPTION_KEY_LOAD_TIMEOUT, err)
+        }
+        time.Sleep(ENCRYPTION_KEY_LOAD_POLL_INTERVAL)
+    }
+}
+
+// Adds the key to the masters and makes it the key new data is encrypted with. Data written
+// before is encrypted as its tables are compacted, which is done right away if asked.
+func (c *Container) enableEncryptionAtRest(task *tasks.Task, keyId string, key []byte,
+    encryptExistingData bool) error {
+    keyPath, err := writeEncryptionKeyFile(key)
+    if err != nil {
+        return fmt.Errorf("failed to write the key: %s", err.Error())
+    }
+    defer os.Remove(keyPath)
+    task.Progress("adding the key %s to the masters", keyId)
+    if _, err := helpers.RunYbAdmin("add_universe_key_to_all_masters", keyId,
+        keyPath); err != nil {
+        return err
+    }
+    task.Progress("waiting for the masters to load the key %s", keyId)
+    if err := waitForEncryptionKeyInMemory(keyId); err != nil {
+        return err
+    }
+    task.Progress("encrypting new data with the key %s", keyId)
+    if _, err := helpers.RunYbAdmin("rotate_universe_key_in_memory", keyId); err != nil {
+        return err
+    }
+    if !encryptExistingData {
+        return nil
+    }
+    tablesFuture := make(chan helpers.TablesFuture)
+    go helpers.GetTablesFuture(helpers.HOST, tablesFuture)
+    tablesResponse := <-tablesFuture
+    if tablesResponse.Error != nil {
+        return tablesResponse.Error
+    }
+    bytesTotal := int64(0)
+    for _, table := range tablesResponse.Tables {
+        bytesTotal += table.SizeBytes
+    }
+    c.encryptionAtRest.update(func(progress *models.EncryptionAtRestProgress) {
+        progress.TablesTotal = int32(len(tablesResponse.Tables))
+        progress.BytesTotal = bytesTotal
+    })
+    for index, table := range tablesResponse.Tables {
+        task.Progress("compacting %s.%s (%d of %d)", table.Keyspace, table.Name, index+1,
+            len(tablesResponse.Tables))
+        if _, err := helpers.RunYbAdminWithTimeout(ENCRYPTION_COMPACTION_TIMEOUT,
+            "compact_table_by_id", table.Uuid,
+            strconv.Itoa(int(ENCRYPTION_COMPACTION_TIMEOUT.Seconds()))); err != nil {
+            return err
+        }
+        c.encryptionAtRest.update(func(progress *models.EncryptionAtRestProgress) {
+            progress.TablesEncrypted++
+            progress.BytesEncrypted += table.SizeBytes
+        })
+    }
+    task.Progress("the data of %d tables was encrypted", len(tablesResponse.Tables))
+    return nil
+}
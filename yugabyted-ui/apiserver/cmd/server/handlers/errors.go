@@ -0,0 +1,188 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "context"
+    "errors"
+    "net"
+    "net/http"
+
+    "github.com/jackc/pgconn"
+    "github.com/jackc/pgx/v4"
+    "github.com/labstack/echo/v4"
+    "github.com/yugabyte/gocql"
+)
+
+// Codes of the errors returned by the API, which clients can tell errors apart by without
+// parsing the messages
+const ERROR_CODE_BAD_REQUEST = "bad_request"
+const ERROR_CODE_UNAUTHORIZED = "unauthorized"
+const ERROR_CODE_FORBIDDEN = "forbidden"
+const ERROR_CODE_NOT_FOUND = "not_found"
+const ERROR_CODE_METHOD_NOT_ALLOWED = "method_not_allowed"
+const ERROR_CODE_CONFLICT = "conflict"
+const ERROR_CODE_PAYLOAD_TOO_LARGE = "payload_too_large"
+const ERROR_CODE_UNSUPPORTED_MEDIA_TYPE = "unsupported_media_type"
+const ERROR_CODE_TOO_MANY_REQUESTS = "too_many_requests"
+const ERROR_CODE_INTERNAL = "internal"
+const ERROR_CODE_UNAVAILABLE = "unavailable"
+const ERROR_CODE_TIMEOUT = "timeout"
+
+var ERROR_CODES_BY_STATUS = map[int]string{
+    http.StatusBadRequest: ERROR_CODE_BAD_REQUEST,
+    http.StatusUnauthorized: ERROR_CODE_UNAUTHORIZED,
+    http.StatusForbidden: ERROR_CODE_FORBIDDEN,
+    http.StatusNotFound: ERROR_CODE_NOT_FOUND,
+    http.StatusMethodNotAllowed: ERROR_CODE_METHOD_NOT_ALLOWED,
+    http.StatusConflict: ERROR_CODE_CONFLICT,
+    http.StatusRequestEntityTooLarge: ERROR_CODE_PAYLOAD_TOO_LARGE,
+    http.StatusUnsupportedMediaType: ERROR_CODE_UNSUPPORTED_MEDIA_TYPE,
+    http.StatusTooManyRequests: ERROR_CODE_TOO_MANY_REQUESTS,
+    http.StatusInternalServerError: ERROR_CODE_INTERNAL,
+    http.StatusBadGateway: ERROR_CODE_UNAVAILABLE,
+    http.StatusServiceUnavailable: ERROR_CODE_UNAVAILABLE,
+    http.StatusGatewayTimeout: ERROR_CODE_TIMEOUT,
+}
+
+// Errors with these statuses are expected to go away when the request is made again later
+var RETRYABLE_STATUSES = map[int]bool{
+    http.StatusTooManyRequests: true,
+    http.StatusBadGateway: true,
+    http.StatusServiceUnavailable: true,
+    http.StatusGatewayTimeout: true,
+}
+
+// SQLSTATEs of YSQL errors that are not failures of the server, with the status they map to
+var PG_ERROR_STATUSES = map[string]int{
+    "23505": http.StatusConflict, // unique_violation
+    "40001": http.StatusConflict, // serialization_failure
+    "42501": http.StatusForbidden, // insufficient_privilege
+    "42601": http.StatusBadRequest, // syntax_error
+    "57014": http.StatusGatewayTimeout, // query_canceled, also raised by statement_timeout
+}
+
+// A failed request, as returned by the API
+type apiError struct {
+    status int
+    code string
+    message string
+    details map[string]interface{}
+    retryable bool
+}
+
+func newApiError(status int, message string) apiError {
+    code, ok := ERROR_CODES_BY_STATUS[status]
+    if !ok {
+        code = ERROR_CODE_INTERNAL
+        if status < http.StatusInternalServerError {
+            code = ERROR_CODE_BAD_REQUEST
+        }
+    }
+    return apiError{
+        status: status,
+        code: code,
+        message: message,
+        retryable: RETRYABLE_STATUSES[status],
+    }
+}
+
+// Gets the API error that an error of a helper, a driver or echo stands for. Errors that are
+// not recognized are internal errors.
+func getApiError(err error) apiError {
+    var httpError *echo.HTTPError
+    var pgError *pgconn.PgError
+    var nodeError *helpers.NodeError
+    var netError net.Error
+    switch {
+    case errors.As(err, &httpError):
+        message, ok := httpError.Message.(string)
+        if !ok {
+            message = http.StatusText(httpError.Code)
+        }
+        return newApiError(httpError.Code, message)
+    case errors.As(err, &nodeError):
+        // Nodes that answered with something other than what was asked for are bad gateways
+        status := http.StatusBadGateway
+        if errors.Is(err, helpers.ErrTimeout) {
+            status = http.StatusGatewayTimeout
+        } else if errors.Is(err, helpers.ErrNodeUnreachable) {
+            status = http.StatusServiceUnavailable
+        }
+        apiErr := newApiError(status, err.Error())
+        apiErr.details = map[string]interface{}{"host": nodeError.Host}
+        return apiErr
+    case errors.Is(err, context.DeadlineExceeded):
+        return newApiError(http.StatusGatewayTimeout, err.Error())
+    case errors.Is(err, pgx.ErrNoRows) || errors.Is(err, gocql.ErrNotFound):
+        return newApiError(http.StatusNotFound, err.Error())
+    case errors.Is(err, gocql.ErrNoConnections) || errors.Is(err, gocql.ErrUnavailable):
+        return newApiError(http.StatusServiceUnavailable, err.Error())
+    case errors.As(err, &pgError):
+        status, ok := PG_ERROR_STATUSES[pgError.Code]
+        if !ok {
+            // Class 08 is for connection exceptions
+            status = http.StatusInternalServerError
+            if len(pgError.Code) == 5 && pgError.Code[:2] == "08" {
+                status = http.StatusServiceUnavailable
+            }
+        }
+        apiErr := newApiError(status, err.Error())
+        apiErr.details = map[string]interface{}{"sqlstate": pgError.Code}
+        // Serialization failures succeed when the transaction is run again
+        if pgError.Code == "40001" {
+            apiErr.retryable = true
+        }
+        return apiErr
+    case errors.As(err, &netError):
+        if netError.Timeout() {
+            return newApiError(http.StatusGatewayTimeout, err.Error())
+        }
+        return newApiError(http.StatusServiceUnavailable, err.Error())
+    }
+    return newApiError(http.StatusInternalServerError, err.Error())
+}
+
+// Gets the id of the request, which the request logger and the audit log record as well
+func getRequestId(ctx echo.Context) string {
+    if id := ctx.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+        return id
+    }
+    return ctx.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+func (apiErr apiError) respond(ctx echo.Context) error {
+    if ctx.Request().Method == http.MethodHead {
+        return ctx.NoContent(apiErr.status)
+    }
+    return ctx.JSON(apiErr.status, models.ApiError{
+        Error: models.ApiErrorError{
+            Detail: apiErr.message,
+            Status: int32(apiErr.status),
+            Code: apiErr.code,
+            Details: apiErr.details,
+            Retryable: apiErr.retryable,
+            RequestId: getRequestId(ctx),
+        },
+    })
+}
+
+// Responds with an error of the given status
+func respondError(ctx echo.Context, status int, message string) error {
+    return newApiError(status, message).respond(ctx)
+}
+
+// Responds with an error whose status depends on what failed, e.g. 404 for rows that do not
+// exist or 503 for nodes that cannot be reached
+func respondWithError(ctx echo.Context, err error) error {
+    return getApiError(err).respond(ctx)
+}
+
+// Responds to the requests whose handler or middleware returned an error, e.g. for routes that
+// do not exist or requests that the CSRF check refused
+func HandleError(err error, ctx echo.Context) {
+    if ctx.Response().Committed {
+        return
+    }
+    respondWithError(ctx, err)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+)
+
+// Caps the calls to the nodes that one API request makes at once at
+// upstream.max_concurrent_calls, so that a page load cannot overwhelm small nodes. Calls past
+// the cap wait in their goroutines for another call to finish. The futures of the calls must be
+// buffered, or a finished call waiting for its result to be read would keep its slot from the
+// calls whose results are read first.
+type fanOutLimiter struct {
+    // Nil when there is no cap
+    slots chan struct{}
+}
+
+func newFanOutLimiter() *fanOutLimiter {
+    limiter := &fanOutLimiter{}
+    if limit := helpers.GetConfig().Upstream.MaxConcurrentCalls; limit > 0 {
+        limiter.slots = make(chan struct{}, limit)
+    }
+    return limiter
+}
+
+// Makes the call in a goroutine, once fewer calls than the cap are running
+func (limiter *fanOutLimiter) goCall(call func()) {
+    go func() {
+        if limiter.slots != nil {
+            limiter.slots <- struct{}{}
+            defer func() { <-limiter.slots }()
+        }
+        call()
+    }()
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "math"
+    "sort"
+)
+
+// A node of the primary cluster, and whether a replica of a tablet is placed on it
+type faultToleranceNode struct {
+    host string
+    cloud string
+    region string
+    zone string
+    hasReplica bool
+}
+
+// Whether a field of the cloud info of a placement block matches a node. Blocks leave a field
+// empty, or set it to *, to match any value.
+func matchesPlacementField(blockValue string, nodeValue string) bool {
+    return blockValue == "" || blockValue == "*" || blockValue == nodeValue
+}
+
+func matchesPlacementBlock(block helpers.PlacementBlock, node faultToleranceNode) bool {
+    return matchesPlacementField(block.CloudInfo.PlacementCloud, node.cloud) &&
+        matchesPlacementField(block.CloudInfo.PlacementRegion, node.region) &&
+        matchesPlacementField(block.CloudInfo.PlacementZone, node.zone)
+}
+
+// Places the replicas of a tablet the way the load balancer spreads them: the minimum number
+// of replicas of each placement block first, then the rest on the nodes of the regions and
+// zones with the fewest replicas. Returns the number of replicas that could be placed, which is
+// less than the replication factor when there are too few nodes.
+func placeReplicas(nodes []faultToleranceNode, blocks []helpers.PlacementBlock,
+    replicationFactor int) int {
+    regionReplicas, zoneReplicas := map[string]int{}, map[string]int{}
+    placed := 0
+    place := func(matches func(faultToleranceNode) bool) bool {
+        best := -1
+        for index, node := range nodes {
+            if node.hasReplica || !matches(node) {
+                continue
+            }
+            if best < 0 {
+                best = index
+                continue
+            }
+            region := regionReplicas[node.cloud+"."+node.region]
+            bestRegion := regionReplicas[nodes[best].cloud+"."+nodes[best].region]
+            zone := zoneReplicas[node.cloud+"."+node.region+"."+node.zone]
+            bestZone := zoneReplicas[nodes[best].cloud+"."+nodes[best].region+"."+
+                nodes[best].zone]
+            if region < bestRegion || (region == bestRegion && zone < bestZone) {
+                best = index
+            }
+        }
+        if best < 0 {
+            return false
+        }
+        node := &nodes[best]
+        node.hasReplica = true
+        regionReplicas[node.cloud+"."+node.region]++
+        zoneReplicas[node.cloud+"."+node.region+"."+node.zone]++
+        placed++
+        return true
+    }
+    for _, block := range blocks {
+        block := block
+        for count := 0; count < block.MinNumReplicas && placed < replicationFactor; count++ {
+            if !place(func(node faultToleranceNode) bool {
+                return matchesPlacementBlock(block, node)
+            }) {
+                break
+            }
+        }
+    }
+    for placed < replicationFactor {
+        // Replicas are only placed in the placement blocks, when there are any
+        if !place(func(node faultToleranceNode) bool {
+            if len(blocks) == 0 {
+                return true
+            }
+            for _, block := range blocks {
+                if matchesPlacementBlock(block, node) {
+                    return true
+                }
+            }
+            return false
+        }) {
+            break
+        }
+    }
+    return placed
+}
+
+// Gets how many of the failure domains with the most replicas can fail while a majority of the
+// replication factor is left
+func getFailuresTolerated(domainReplicas map[string]int, placed int,
+    replicationFactor int) int32 {
+    counts := []int{}
+    for _, count := range domainReplicas {
+        counts = append(counts, count)
+    }
+    sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+    quorum := replicationFactor/2 + 1
+    failures := int32(0)
+    for _, count := range counts {
+        if placed-count < quorum {
+            break
+        }
+        placed -= count
+        failures++
+    }
+    return failures
+}
+
+// Derives the fault tolerance of the cluster from its replication factor and placement policy,
+// by placing the replicas of a tablet on the nodes of the primary cluster and counting how many
+// nodes, zones and regions with replicas can fail while the tablet keeps quorum. Without a
+// replication factor in the cluster config, the default one of the nodes is assumed, as for the
+// tablet limits.
+func getFaultTolerance(tabletServers map[string]map[string]helpers.TabletServer,
+    replicationInfo helpers.ReplicationInfoStruct) (models.ClusterFaultTolerance, int,
+    models.ClusterFailuresTolerated) {
+    liveReplicas := replicationInfo.LiveReplicas
+    nodes := []faultToleranceNode{}
+    for placementUuid, cluster := range tabletServers {
+        // Read replica clusters have placements of their own, and do not vote
+        if _, ok := tabletServers[liveReplicas.PlacementUuid]; ok &&
+            placementUuid != liveReplicas.PlacementUuid {
+            continue
+        }
+        for address, tabletServer := range cluster {
+            host, err := helpers.GetHostFromAddress(address)
+            if err != nil {
+                host = address
+            }
+            nodes = append(nodes, faultToleranceNode{
+                host: host,
+                cloud: tabletServer.Cloud,
+                region: tabletServer.Region,
+                zone: tabletServer.Zone,
+            })
+        }
+    }
+    // Sorted so that ties between nodes are broken the same way every time
+    sort.Slice(nodes, func(i, j int) bool {
+        return nodes[i].host < nodes[j].host
+    })
+    replicationFactor := liveReplicas.NumReplicas
+    if replicationFactor == 0 {
+        replicationFactor = int(math.Min(3, float64(len(nodes))))
+    }
+    placed := placeReplicas(nodes, liveReplicas.PlacementBlocks, replicationFactor)
+    nodeReplicas, zoneReplicas := map[string]int{}, map[string]int{}
+    regionReplicas := map[string]int{}
+    for _, node := range nodes {
+        if node.hasReplica {
+            nodeReplicas[node.host]++
+            zoneReplicas[node.cloud+"."+node.region+"."+node.zone]++
+            regionReplicas[node.cloud+"."+node.region]++
+        }
+    }
+    failuresTolerated := models.ClusterFailuresTolerated{
+        Nodes: getFailuresTolerated(nodeReplicas, placed, replicationFactor),
+        Zones: getFailuresTolerated(zoneReplicas, placed, replicationFactor),
+        Regions: getFailuresTolerated(regionReplicas, placed, replicationFactor),
+    }
+    faultTolerance := models.CLUSTERFAULTTOLERANCE_NONE
+    if failuresTolerated.Regions > 0 {
+        faultTolerance = models.CLUSTERFAULTTOLERANCE_REGION
+    } else if failuresTolerated.Zones > 0 {
+        faultTolerance = models.CLUSTERFAULTTOLERANCE_ZONE
+    } else if failuresTolerated.Nodes > 0 {
+        faultTolerance = models.CLUSTERFAULTTOLERANCE_NODE
+    }
+    return faultTolerance, replicationFactor, failuresTolerated
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "fmt"
+    "sort"
+)
+
+// A capability of the cluster that the UI has panels for, available from a version on and, for
+// capabilities in preview, only while a tserver gflag turns it on
+type clusterCapability struct {
+    name string
+    minVersion string
+    // Tserver gflag that must be true on every tserver, empty if none
+    gFlag string
+}
+
+var CLUSTER_CAPABILITIES = []clusterCapability{
+    {name: "pitr", minVersion: "2.14.0"},
+    {name: "xcluster", minVersion: "2.14.0"},
+    {name: "backups", minVersion: "2.18.0"},
+    {name: "ash", minVersion: "2.21.0", gFlag: "ysql_yb_enable_ash"},
+    {name: "instant_clone", minVersion: INSTANT_CLONE_MIN_VERSION},
+}
+
+// Gets whether each capability is available on the cluster, given the smallest version of its
+// nodes and the gflags of its tservers. Capabilities are unavailable when the version is
+// unknown, so that the UI hides panels it cannot tell work.
+func getClusterFeatures(version string, tserverFlags []map[string]string) []models.ClusterFeature {
+    features := []models.ClusterFeature{}
+    for _, capability := range CLUSTER_CAPABILITIES {
+        feature := models.ClusterFeature{Name: capability.name, Available: true}
+        if version == "" {
+            feature.Available = false
+            feature.Reason = "the version of the cluster is unknown"
+        } else if helpers.CompareVersions(version, capability.minVersion) < 0 {
+            feature.Available = false
+            feature.Reason = fmt.Sprintf("needs version %s or later, the cluster runs %s",
+                capability.minVersion, version)
+        } else if capability.gFlag != "" {
+            if len(tserverFlags) == 0 {
+                feature.Available = false
+                feature.Reason = fmt.Sprintf("the %s gflag of the tservers is unknown",
+                    capability.gFlag)
+            }
+            for _, flags := range tserverFlags {
+                if flags[capability.gFlag] != "true" {
+                    feature.Available = false
+                    feature.Reason = fmt.Sprintf("needs the %s gflag to be true on every tserver",
+                        capability.gFlag)
+                    break
+                }
+            }
+        }
+        features = append(features, feature)
+    }
+    return features
+}
+
+// Gets whether each feature of this server that the config can turn off is on, by its key in
+// the features section of the config
+func getConfigFeatures(config helpers.FeaturesConfig) []models.ClusterFeature {
+    enabled := map[string]bool{
+        "node_management": config.NodeManagement,
+        "user_management": config.UserManagement,
+        "extension_install": config.ExtensionInstall,
+        "certificate_generation": config.CertificateGeneration,
+        "table_export": config.TableExport,
+        "table_import": config.TableImport,
+        "database_dump": config.DatabaseDump,
+        "profiling": config.Profiling,
+        "shell": config.Shell,
+        "encryption_at_rest": config.EncryptionAtRest,
+        "cdc_streams": config.CdcStreams,
+        "database_clone": config.DatabaseClone,
+        "workloads": config.Workloads,
+        "sample_data": config.SampleData,
+    }
+    features := []models.ClusterFeature{}
+    for name, isEnabled := range enabled {
+        feature := models.ClusterFeature{Name: name, Available: isEnabled}
+        if !isEnabled {
+            feature.Reason = fmt.Sprintf("turned off by features.%s in the config", name)
+        }
+        features = append(features, feature)
+    }
+    sort.Slice(features, func(i, j int) bool {
+        return features[i].Name < features[j].Name
+    })
+    return features
+}
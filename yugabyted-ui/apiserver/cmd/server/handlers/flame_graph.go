@@ -0,0 +1,225 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "bufio"
+    "encoding/binary"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Folded stacks are kept next to the CPU profile they were made from
+const FOLDED_STACKS_EXTENSION = ".folded"
+
+// A sample of a CPU profile: the number of times a stack was seen, leaf first
+type cpuProfileSample struct {
+    count uint64
+    addresses []uint64
+}
+
+// Parses a CPU profile in the legacy gperftools format that the nodes serve. It is made of
+// little endian machine words: a header of 0, 3, 0, the sampling period and 0, then records of
+// a count, a number of addresses and the addresses, ending with a record of 0, 1, 0. The
+// memory map of the process that follows is not needed.
+func parseCpuProfile(data []byte) ([]cpuProfileSample, error) {
+    if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+        return nil, fmt.Errorf("profiles in the pprof protobuf format are not supported")
+    }
+    // The second word of the header tells the word size
+    wordSize := 0
+    if len(data) >= 8 && binary.LittleEndian.Uint32(data[0:4]) == 0 &&
+        binary.LittleEndian.Uint32(data[4:8]) == 3 {
+        wordSize = 4
+    } else if len(data) >= 16 && binary.LittleEndian.Uint64(data[0:8]) == 0 &&
+        binary.LittleEndian.Uint64(data[8:16]) == 3 {
+        wordSize = 8
+    } else {
+        return nil, fmt.Errorf("not a CPU profile in the legacy format")
+    }
+    // Records follow the header of 5 words
+    offset := 5 * wordSize
+    readWord := func() (uint64, bool) {
+        if offset+wordSize > len(data) {
+            return 0, false
+        }
+        var word uint64
+        if wordSize == 4 {
+            word = uint64(binary.LittleEndian.Uint32(data[offset:]))
+        } else {
+            word = binary.LittleEndian.Uint64(data[offset:])
+        }
+        offset += wordSize
+        return word, true
+    }
+    samples := []cpuProfileSample{}
+    for {
+        count, ok := readWord()
+        if !ok {
+            return nil, fmt.Errorf("the profile is truncated")
+        }
+        numAddresses, ok := readWord()
+        if !ok || numAddresses > uint64((len(data)-offset)/wordSize) {
+            return nil, fmt.Errorf("the profile is truncated")
+        }
+        addresses := make([]uint64, numAddresses)
+        for index := range addresses {
+            addresses[index], _ = readWord()
+        }
+        if count == 0 && numAddresses == 1 && addresses[0] == 0 {
+            return samples, nil
+        }
+        samples = append(samples, cpuProfileSample{count: count, addresses: addresses})
+    }
+}
+
+// Gets the address to resolve for a frame of a stack. The frames after the leaf are return
+// addresses, which point past the call, so the address before them is resolved instead.
+func getFrameAddress(sample cpuProfileSample, index int) uint64 {
+    address := sample.addresses[index]
+    if index > 0 && address > 0 {
+        address--
+    }
+    return address
+}
+
+// Gets the addresses to resolve for the samples
+func getCpuProfileAddresses(samples []cpuProfileSample) []uint64 {
+    seen := map[uint64]bool{}
+    addresses := []uint64{}
+    for _, sample := range samples {
+        for index := range sample.addresses {
+            address := getFrameAddress(sample, index)
+            if !seen[address] {
+                seen[address] = true
+                addresses = append(addresses, address)
+            }
+        }
+    }
+    return addresses
+}
+
+// Folds the samples into stacks of function names, root first and separated by semicolons,
+// with the number of times each stack was seen. Addresses without a name are kept in hex.
+func foldCpuProfile(samples []cpuProfileSample, symbols map[uint64]string) map[string]uint64 {
+    folded := map[string]uint64{}
+    for _, sample := range samples {
+        names := []string{}
+        for index := len(sample.addresses) - 1; index >= 0; index-- {
+            address := getFrameAddress(sample, index)
+            name, ok := symbols[address]
+            if !ok {
+                name = fmt.Sprintf("0x%x", address)
+            }
+            // Semicolons separate the frames of folded stacks
+            names = append(names, strings.ReplaceAll(name, ";", ":"))
+        }
+        folded[strings.Join(names, ";")] += sample.count
+    }
+    return folded
+}
+
+// Gets the path of the folded stacks of a CPU profile
+func getFoldedStacksPath(profilePath string) string {
+    return strings.TrimSuffix(profilePath, PROFILE_EXTENSION) + FOLDED_STACKS_EXTENSION
+}
+
+// Writes folded stacks in the format of the flame graph tools, one stack and count per line
+func writeFoldedStacks(path string, folded map[string]uint64) error {
+    stacks := []string{}
+    for stack := range folded {
+        stacks = append(stacks, stack)
+    }
+    sort.Strings(stacks)
+    var builder strings.Builder
+    for _, stack := range stacks {
+        builder.WriteString(fmt.Sprintf("%s %d\n", stack, folded[stack]))
+    }
+    return ioutil.WriteFile(path, []byte(builder.String()), 0600)
+}
+
+// Folds a CPU profile collected from a master or tserver, naming its functions with the help of
+// the process, which must still be running the binary that was profiled
+func foldCpuProfileFile(hostName string, isMaster bool, profilePath string) error {
+    data, err := ioutil.ReadFile(profilePath)
+    if err != nil {
+        return err
+    }
+    samples, err := parseCpuProfile(data)
+    if err != nil {
+        return err
+    }
+    symbols, err := helpers.SymbolizeAddresses(hostName, isMaster,
+        getCpuProfileAddresses(samples))
+    if err != nil {
+        return err
+    }
+    return writeFoldedStacks(getFoldedStacksPath(profilePath), foldCpuProfile(samples, symbols))
+}
+
+// A frame of a flame graph while it is built, with its children by name
+type flameGraphFrame struct {
+    value int64
+    children map[string]*flameGraphFrame
+}
+
+func (frame *flameGraphFrame) toNode(name string) models.FlameGraphNode {
+    node := models.FlameGraphNode{
+        Name: name,
+        Value: frame.value,
+        Children: []models.FlameGraphNode{},
+    }
+    names := []string{}
+    for childName := range frame.children {
+        names = append(names, childName)
+    }
+    sort.Strings(names)
+    for _, childName := range names {
+        node.Children = append(node.Children, frame.children[childName].toNode(childName))
+    }
+    return node
+}
+
+// Builds the tree of a flame graph from a file of folded stacks. The value of each frame is the
+// number of samples of the stacks that go through it.
+func readFlameGraph(path string) (models.FlameGraphNode, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return models.FlameGraphNode{}, err
+    }
+    defer file.Close()
+    root := &flameGraphFrame{children: map[string]*flameGraphFrame{}}
+    scanner := bufio.NewScanner(file)
+    // Deep stacks make for long lines
+    scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+    for scanner.Scan() {
+        line := scanner.Text()
+        separator := strings.LastIndex(line, " ")
+        if separator < 0 {
+            continue
+        }
+        count, err := strconv.ParseInt(line[separator+1:], 10, 64)
+        if err != nil {
+            return models.FlameGraphNode{}, fmt.Errorf("invalid folded stack: %s", line)
+        }
+        current := root
+        current.value += count
+        for _, name := range strings.Split(line[:separator], ";") {
+            child, ok := current.children[name]
+            if !ok {
+                child = &flameGraphFrame{children: map[string]*flameGraphFrame{}}
+                current.children[name] = child
+            }
+            child.value += count
+            current = child
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return models.FlameGraphNode{}, err
+    }
+    return root.toNode("root"), nil
+}
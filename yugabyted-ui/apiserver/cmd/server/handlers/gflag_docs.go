@@ -0,0 +1,141 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "regexp"
+    "strings"
+    "sync"
+)
+
+var GFLAG_NAME_REGEX = regexp.MustCompile(`^[A-Za-z0-9_]{1,200}$`)
+
+const GFLAG_SERVER_TYPE_MASTER = "master"
+const GFLAG_SERVER_TYPE_TSERVER = "tserver"
+
+// How a change of a gflag with set_flag takes effect
+const GFLAG_IMPACT_RUNTIME = "runtime"
+const GFLAG_IMPACT_RESTART_REQUIRED = "restart_required"
+const GFLAG_IMPACT_AUTO_FLAG = "auto_flag"
+
+// The documentation of the flag cannot be read
+const GFLAG_IMPACT_UNKNOWN = "unknown"
+
+// The source varz reports for the flags set by the AutoFlags of the cluster
+const VARZ_FLAG_TYPE_AUTO = "Auto"
+
+func splitGflagTags(tags string) []string {
+    splitTags := []string{}
+    for _, tag := range strings.Split(tags, ",") {
+        if tag = strings.TrimSpace(tag); tag != "" {
+            splitTags = append(splitTags, tag)
+        }
+    }
+    return splitTags
+}
+
+// Classifies how a change of a flag takes effect. AutoFlags are promoted by the cluster, and a
+// flag without the runtime tag is only read when the server starts, so set_flag changes its
+// value without any effect until a rolling restart.
+func getGflagImpact(doc helpers.GFlagDoc, hasDoc bool, varzType string) string {
+    if varzType == VARZ_FLAG_TYPE_AUTO {
+        return GFLAG_IMPACT_AUTO_FLAG
+    }
+    if !hasDoc {
+        return GFLAG_IMPACT_UNKNOWN
+    }
+    tags := splitGflagTags(doc.Tags)
+    if containsString(tags, "auto") {
+        return GFLAG_IMPACT_AUTO_FLAG
+    }
+    if containsString(tags, "runtime") {
+        return GFLAG_IMPACT_RUNTIME
+    }
+    return GFLAG_IMPACT_RESTART_REQUIRED
+}
+
+// Gets the version the cluster runs, e.g. 2.20.1.0-b97
+func getRunningVersion() (string, error) {
+    versionFuture := make(chan helpers.VersionInfoFuture)
+    go helpers.GetVersionFuture(helpers.HOST, versionFuture)
+    versionInfo := <-versionFuture
+    if versionInfo.Error != nil {
+        return "", versionInfo.Error
+    }
+    return versionInfo.VersionInfo.VersionNumber + "-b" + versionInfo.VersionInfo.BuildNumber,
+        nil
+}
+
+type gflagDocsEntry struct {
+    // The version the cluster ran when the docs were read
+    runningVersion string
+    // The version of the binary the docs were read from
+    binaryVersion string
+    docs map[string]helpers.GFlagDoc
+}
+
+// Keeps the documentation of the flags of the local yb-master and yb-tserver binaries, which
+// is read again once the cluster runs another version
+type gflagDocsCache struct {
+    mutex sync.Mutex
+    // By server type
+    entries map[string]gflagDocsEntry
+}
+
+func newGflagDocsCache() *gflagDocsCache {
+    return &gflagDocsCache{entries: map[string]gflagDocsEntry{}}
+}
+
+func (cache *gflagDocsCache) get(serverType string, runningVersion string) (gflagDocsEntry,
+    error) {
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+    if entry, ok := cache.entries[serverType]; ok && entry.runningVersion == runningVersion {
+        return entry, nil
+    }
+    isMaster := serverType == GFLAG_SERVER_TYPE_MASTER
+    binaryVersion, err := helpers.GetYbServerVersion(isMaster)
+    if err != nil {
+        return gflagDocsEntry{}, err
+    }
+    docs, err := helpers.GetGFlagDocs(isMaster)
+    if err != nil {
+        return gflagDocsEntry{}, err
+    }
+    entry := gflagDocsEntry{
+        runningVersion: runningVersion,
+        binaryVersion: binaryVersion,
+        docs: docs,
+    }
+    cache.entries[serverType] = entry
+    return entry, nil
+}
+
+// Gets the documentation of the flags of a server type for the version the cluster runs
+func (cache *gflagDocsCache) getForCluster(serverType string) (gflagDocsEntry, error) {
+    runningVersion, err := getRunningVersion()
+    if err != nil {
+        return gflagDocsEntry{}, err
+    }
+    return cache.get(serverType, runningVersion)
+}
+
+// Gets the flags among names that set_flag cannot change on a server type, by their impact.
+// Without the documentation of the flags, as when the binary is not on this host, none is.
+func (cache *gflagDocsCache) getNonRuntimeGflags(serverType string,
+    names []string) map[string]string {
+    nonRuntime := map[string]string{}
+    entry, err := cache.getForCluster(serverType)
+    if err != nil {
+        return nonRuntime
+    }
+    for _, name := range names {
+        doc, ok := entry.docs[name]
+        if !ok {
+            continue
+        }
+        if impact := getGflagImpact(doc, true, ""); impact != GFLAG_IMPACT_RUNTIME {
+            nonRuntime[name] = impact
+        }
+    }
+    return nonRuntime
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Caches the map between the hosts and uuids of the tservers, which is parsed from a page of
+// the master. The map is fetched again when it is older than upstream.host_to_uuid_ttl, or
+// as soon as the poller sees that the set of tservers changed.
+type hostToUuidCache struct {
+    mutex sync.Mutex
+    hostToUuid helpers.HostToUuidMap
+    fetchedAt time.Time
+    logger logger.Logger
+}
+
+func newHostToUuidCache(log logger.Logger) *hostToUuidCache {
+    cache := &hostToUuidCache{logger: log}
+    go cache.pollNodes()
+    return cache
+}
+
+// Gets the map from the cache, fetching it if needed
+func (cache *hostToUuidCache) get() (helpers.HostToUuidMap, error) {
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+    if cache.hostToUuid != nil &&
+        time.Since(cache.fetchedAt) < helpers.GetConfig().Upstream.HostToUuidTtl {
+        return cache.hostToUuid, nil
+    }
+    hostToUuid, err := helpers.GetHostToUuidMap(helpers.HOST)
+    if err != nil {
+        return nil, err
+    }
+    cache.hostToUuid = hostToUuid
+    cache.fetchedAt = time.Now()
+    return hostToUuid, nil
+}
+
+func (cache *hostToUuidCache) invalidate() {
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+    cache.hostToUuid = nil
+}
+
+// Invalidates the cache whenever tservers join or leave the cluster
+func (cache *hostToUuidCache) pollNodes() {
+    lastNodes := ""
+    for {
+        tabletServersFuture := make(chan helpers.TabletServersFuture)
+        go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+        tabletServers := <-tabletServersFuture
+        if tabletServers.Error == nil {
+            hosts := helpers.GetNodesList(tabletServers)
+            sort.Strings(hosts)
+            nodes := strings.Join(hosts, ",")
+            if nodes != lastNodes {
+                if lastNodes != "" {
+                    cache.logger.Infof("tservers changed to %s, refreshing their uuids", nodes)
+                }
+                cache.invalidate()
+                lastNodes = nodes
+            }
+        }
+        time.Sleep(helpers.GetConfig().Upstream.NodePollInterval)
+    }
+}
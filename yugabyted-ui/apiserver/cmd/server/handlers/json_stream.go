@@ -0,0 +1,91 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Number of items written between flushes of a streamed response
+const JSON_STREAM_FLUSH_ITEMS = 100
+
+// Writes a JSON response piece by piece, so that long lists are sent as their items are made
+// instead of being collected into a model first. The status goes out with the first write, so
+// errors must be handled before the stream is started.
+type jsonStream struct {
+    response *echo.Response
+    // Number of items written to the list or object that is currently open
+    count int
+    err error
+}
+
+func newJsonStream(ctx echo.Context, status int) *jsonStream {
+    response := ctx.Response()
+    response.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+    response.WriteHeader(status)
+    return &jsonStream{response: response}
+}
+
+// Writes JSON as is, for the parts of the response around the streamed list
+func (stream *jsonStream) raw(text string) {
+    if stream.err != nil {
+        return
+    }
+    _, stream.err = stream.response.Write([]byte(text))
+}
+
+// Writes the opening of a list or object, whose items are then separated by commas
+func (stream *jsonStream) open(text string) {
+    stream.raw(text)
+    stream.count = 0
+}
+
+// Writes an item of the open list
+func (stream *jsonStream) item(value interface{}) {
+    stream.write(nil, value)
+}
+
+// Writes a field of the open object
+func (stream *jsonStream) field(key string, value interface{}) {
+    stream.write(&key, value)
+}
+
+func (stream *jsonStream) write(key *string, value interface{}) {
+    if stream.err != nil {
+        return
+    }
+    encoded, err := json.Marshal(value)
+    if err != nil {
+        stream.err = err
+        return
+    }
+    if stream.count > 0 {
+        stream.raw(",")
+    }
+    if key != nil {
+        encodedKey, _ := json.Marshal(*key)
+        stream.raw(string(encodedKey) + ":")
+    }
+    stream.raw(string(encoded))
+    stream.count++
+    if stream.count%JSON_STREAM_FLUSH_ITEMS == 0 {
+        stream.flush()
+    }
+}
+
+func (stream *jsonStream) flush() {
+    if flusher, ok := stream.response.Writer.(http.Flusher); ok {
+        flusher.Flush()
+    }
+}
+
+// Writes the end of the response, returning the first error hit while streaming. The client
+// has already been sent a success status by then, so it sees the error as truncated JSON.
+func (stream *jsonStream) close(text string) error {
+    stream.raw(text)
+    if stream.err == nil {
+        stream.flush()
+    }
+    return stream.err
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/logger"
+    "io/ioutil"
+    "os"
+)
+
+// Buckets of the local store. The API tokens and the alert rules are kept as one document
+// each, in the layout of the files they were kept in before.
+const STORE_BUCKET_API_TOKENS = "api_tokens"
+const STORE_BUCKET_ALERT_RULES = "alert_rules"
+const STORE_BUCKET_ALERTS = "alerts"
+const STORE_BUCKET_AUDIT_LOG = "audit_log"
+const STORE_BUCKET_ALERT_HISTORY = "alert_history"
+const STORE_BUCKET_CLUSTER_EVENTS = "cluster_events"
+const STORE_BUCKET_WEBHOOKS = "webhooks"
+const STORE_BUCKET_PROBE_RESULTS = "probe_results"
+const STORE_BUCKET_HEALTH_HISTORY = "health_history"
+const STORE_BUCKET_DDL_ACTIVITY = "ddl_activity"
+const STORE_BUCKET_REPORTS = "reports"
+// End of the last period reported of each kind of report, by kind
+const STORE_BUCKET_REPORT_PERIODS = "report_periods"
+
+const STORE_API_TOKENS_KEY = "tokens"
+const STORE_ALERT_RULES_KEY = "rules"
+const STORE_ALERTS_KEY = "alerts"
+
+// Changes to the layout of the local store, oldest first. Versions must only ever be appended,
+// as stores record the latest version applied to them.
+var STORE_MIGRATIONS = []localstore.Migration{
+    {
+        Version: 1,
+        Description: "import the API tokens and the alert rules from their files",
+        Apply: importLegacyStoreFiles,
+        External: true,
+    },
+}
+
+// Copies the files the API tokens and the alert rules were kept in into the store. The files
+// are left in place, so that an older server can still be run.
+func importLegacyStoreFiles(store localstore.Store) error {
+    config := helpers.GetConfig()
+    files := []struct {
+        bucket string
+        key string
+        path string
+    }{
+        {STORE_BUCKET_API_TOKENS, STORE_API_TOKENS_KEY, config.ApiTokens.File},
+        {STORE_BUCKET_ALERT_RULES, STORE_ALERT_RULES_KEY, config.Alerts.RulesFile},
+    }
+    for _, file := range files {
+        data, err := ioutil.ReadFile(file.path)
+        if os.IsNotExist(err) {
+            continue
+        }
+        if err != nil {
+            return err
+        }
+        if err := store.Put(file.bucket, file.key, data); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Opens the local store of the configured backend and brings it up to the latest version
+func openLocalStore(log logger.Logger) (localstore.Store, error) {
+    storeConfig := helpers.GetConfig().Store
+    store, err := localstore.Open(storeConfig.Backend, storeConfig.Path)
+    if err != nil {
+        return nil, err
+    }
+    applied, err := localstore.Migrate(store, STORE_MIGRATIONS)
+    for _, description := range applied {
+        log.Infof("migrated the local store: %s", description)
+    }
+    if err != nil {
+        store.Close()
+        return nil, err
+    }
+    return store, nil
+}
+
+// Reads the state kept in the local store again, after it was restored from a backup
+func (c *Container) reloadLocalStore() {
+    c.apiTokens.load()
+    c.alertRules.load()
+    c.alerts.load()
+    c.tasks.Load()
+    c.webhooks.load()
+    c.reports.load()
+}
@@ -0,0 +1,375 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// What a maintenance window covers. The target of a zone is cloud.region.zone, and the target
+// of a node is its host.
+const MAINTENANCE_SCOPE_CLUSTER = "cluster"
+const MAINTENANCE_SCOPE_ZONE = "zone"
+const MAINTENANCE_SCOPE_NODE = "node"
+
+var MAINTENANCE_SCOPES = []string{MAINTENANCE_SCOPE_CLUSTER, MAINTENANCE_SCOPE_ZONE,
+    MAINTENANCE_SCOPE_NODE}
+
+const MAINTENANCE_MAX_TARGET_LENGTH = 255
+const MAINTENANCE_MAX_REASON_LENGTH = 1024
+// Longest a node can be put in maintenance for, a week
+const NODE_MAINTENANCE_MAX_DURATION_MINUTES = 7 * 24 * 60
+
+// A window as written to the file
+type storedMaintenanceWindow struct {
+    Id string `json:"id"`
+    Scope string `json:"scope"`
+    Target string `json:"target"`
+    StartTime int64 `json:"start_time"`
+    EndTime int64 `json:"end_time"`
+    Reason string `json:"reason"`
+    CreatedBy string `json:"created_by"`
+    CreatedAt int64 `json:"created_at"`
+}
+
+type maintenanceWindowsFile struct {
+    Windows []storedMaintenanceWindow `json:"windows"`
+}
+
+// Keeps the maintenance windows in a file, so that they outlive the server. Windows are
+// dropped once they end.
+type maintenanceWindowStore struct {
+    mutex sync.Mutex
+    windows map[string]storedMaintenanceWindow
+}
+
+func newMaintenanceWindowStore(log logger.Logger) *maintenanceWindowStore {
+    store := &maintenanceWindowStore{windows: map[string]storedMaintenanceWindow{}}
+    data, err := ioutil.ReadFile(helpers.GetConfig().Maintenance.File)
+    if err != nil {
+        if !os.IsNotExist(err) {
+            log.Errorf("failed to read the maintenance windows: %s", err.Error())
+        }
+        return store
+    }
+    windowsFile := maintenanceWindowsFile{}
+    if err := json.Unmarshal(data, &windowsFile); err != nil {
+        log.Errorf("failed to read the maintenance windows: %s", err.Error())
+        return store
+    }
+    for _, window := range windowsFile.Windows {
+        store.windows[window.Id] = window
+    }
+    return store
+}
+
+// Must be called with the mutex held
+func (store *maintenanceWindowStore) dropEndedLocked(now int64) {
+    for id, window := range store.windows {
+        if window.EndTime <= now {
+            delete(store.windows, id)
+        }
+    }
+}
+
+// Writes the windows that have not ended to a new file that then replaces the old one, so that
+// a crash cannot leave a partly written file. Must be called with the mutex held.
+func (store *maintenanceWindowStore) saveLocked() error {
+    store.dropEndedLocked(time.Now().Unix())
+    windowsFile := maintenanceWindowsFile{Windows: []storedMaintenanceWindow{}}
+    for _, window := range store.windows {
+        windowsFile.Windows = append(windowsFile.Windows, window)
+    }
+    sort.Slice(windowsFile.Windows, func(i, j int) bool {
+        return windowsFile.Windows[i].Id < windowsFile.Windows[j].Id
+    })
+    data, err := json.MarshalIndent(windowsFile, "", "  ")
+    if err != nil {
+        return err
+    }
+    path := helpers.GetConfig().Maintenance.File
+    if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        return err
+    }
+    if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+        return err
+    }
+    return os.Rename(path+".tmp", path)
+}
+
+func getMaintenanceWindowModel(window storedMaintenanceWindow,
+    now int64) models.MaintenanceWindow {
+    return models.MaintenanceWindow{
+        Id: window.Id,
+        Scope: window.Scope,
+        Target: window.Target,
+        StartTime: window.StartTime,
+        EndTime: window.EndTime,
+        Reason: window.Reason,
+        CreatedBy: window.CreatedBy,
+        CreatedAt: window.CreatedAt,
+        Active: window.StartTime <= now && now < window.EndTime,
+    }
+}
+
+func validateMaintenanceWindowSpec(spec models.MaintenanceWindowSpec, now int64) error {
+    if !containsString(MAINTENANCE_SCOPES, spec.Scope) {
+        return fmt.Errorf("scope must be one of %s, got %q",
+            strings.Join(MAINTENANCE_SCOPES, ", "), spec.Scope)
+    }
+    if spec.Scope == MAINTENANCE_SCOPE_CLUSTER && spec.Target != "" {
+        return errors.New("target must be empty for the cluster scope")
+    }
+    if spec.Scope != MAINTENANCE_SCOPE_CLUSTER && spec.Target == "" {
+        return fmt.Errorf("target must be set for the %s scope", spec.Scope)
+    }
+    if spec.Scope == MAINTENANCE_SCOPE_ZONE && len(strings.Split(spec.Target, ".")) != 3 {
+        return fmt.Errorf("target of a zone must be cloud.region.zone, got %q", spec.Target)
+    }
+    if err := validateMetadataText("target", spec.Target,
+        MAINTENANCE_MAX_TARGET_LENGTH); err != nil {
+        return err
+    }
+    if err := validateMetadataText("reason", spec.Reason,
+        MAINTENANCE_MAX_REASON_LENGTH); err != nil {
+        return err
+    }
+    if spec.EndTime <= spec.StartTime {
+        return errors.New("end_time must be after start_time")
+    }
+    if spec.EndTime <= now {
+        return errors.New("end_time must be in the future")
+    }
+    return nil
+}
+
+// Gets the windows that have not ended, in the order they start
+func (store *maintenanceWindowStore) list() []models.MaintenanceWindow {
+    now := time.Now().Unix()
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    windows := []models.MaintenanceWindow{}
+    for _, window := range store.windows {
+        if window.EndTime > now {
+            windows = append(windows, getMaintenanceWindowModel(window, now))
+        }
+    }
+    sort.Slice(windows, func(i, j int) bool {
+        if windows[i].StartTime != windows[j].StartTime {
+            return windows[i].StartTime < windows[j].StartTime
+        }
+        return windows[i].Id < windows[j].Id
+    })
+    return windows
+}
+
+func (store *maintenanceWindowStore) create(spec models.MaintenanceWindowSpec,
+    createdBy string) (models.MaintenanceWindow, error) {
+    idBytes := make([]byte, 8)
+    if _, err := rand.Read(idBytes); err != nil {
+        return models.MaintenanceWindow{}, err
+    }
+    now := time.Now().Unix()
+    window := storedMaintenanceWindow{
+        Id: hex.EncodeToString(idBytes),
+        Scope: spec.Scope,
+        Target: spec.Target,
+        StartTime: spec.StartTime,
+        EndTime: spec.EndTime,
+        Reason: spec.Reason,
+        CreatedBy: createdBy,
+        CreatedAt: now,
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    store.windows[window.Id] = window
+    if err := store.saveLocked(); err != nil {
+        delete(store.windows, window.Id)
+        return models.MaintenanceWindow{}, err
+    }
+    return getMaintenanceWindowModel(window, now), nil
+}
+
+// Deletes a window, which ends it if it is active. Returns false if there is no such window.
+func (store *maintenanceWindowStore) delete(id string) (bool, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    window, ok := store.windows[id]
+    if !ok || window.EndTime <= time.Now().Unix() {
+        return false, nil
+    }
+    delete(store.windows, id)
+    if err := store.saveLocked(); err != nil {
+        store.windows[id] = window
+        return true, err
+    }
+    return true, nil
+}
+
+// Whether a node is in an active window, either of its own, of its zone or of the cluster. The
+// zone is cloud.region.zone.
+func (store *maintenanceWindowStore) covers(host string, zone string, now int64) bool {
+    return store.coveredUntil(host, zone, now) > 0
+}
+
+// Gets when the last of the active windows that cover a node ends, or 0 if none covers it
+func (store *maintenanceWindowStore) coveredUntil(host string, zone string, now int64) int64 {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    until := int64(0)
+    for _, window := range store.windows {
+        if window.StartTime > now || now >= window.EndTime {
+            continue
+        }
+        if window.Scope == MAINTENANCE_SCOPE_CLUSTER ||
+            (window.Scope == MAINTENANCE_SCOPE_ZONE && window.Target == zone) ||
+            (window.Scope == MAINTENANCE_SCOPE_NODE &&
+                helpers.NormalizeHost(window.Target) == helpers.NormalizeHost(host)) {
+            if window.EndTime > until {
+                until = window.EndTime
+            }
+        }
+    }
+    return until
+}
+
+// Gets the active windows of a node itself, leaving out those of its zone and of the cluster.
+// Must be called with the mutex held.
+func (store *maintenanceWindowStore) getActiveNodeWindowsLocked(host string,
+    now int64) []storedMaintenanceWindow {
+    windows := []storedMaintenanceWindow{}
+    for _, window := range store.windows {
+        if window.Scope == MAINTENANCE_SCOPE_NODE && window.StartTime <= now &&
+            now < window.EndTime &&
+            helpers.NormalizeHost(window.Target) == helpers.NormalizeHost(host) {
+            windows = append(windows, window)
+        }
+    }
+    return windows
+}
+
+// Puts a node in maintenance from now until a time, with a window that replaces the active
+// windows of the node itself. Returns the new window.
+func (store *maintenanceWindowStore) setNodeMaintenance(host string, endTime int64,
+    reason string, createdBy string) (models.MaintenanceWindow, error) {
+    idBytes := make([]byte, 8)
+    if _, err := rand.Read(idBytes); err != nil {
+        return models.MaintenanceWindow{}, err
+    }
+    now := time.Now().Unix()
+    window := storedMaintenanceWindow{
+        Id: hex.EncodeToString(idBytes),
+        Scope: MAINTENANCE_SCOPE_NODE,
+        Target: host,
+        StartTime: now,
+        EndTime: endTime,
+        Reason: reason,
+        CreatedBy: createdBy,
+        CreatedAt: now,
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    previous := store.getActiveNodeWindowsLocked(host, now)
+    for _, previousWindow := range previous {
+        delete(store.windows, previousWindow.Id)
+    }
+    store.windows[window.Id] = window
+    if err := store.saveLocked(); err != nil {
+        delete(store.windows, window.Id)
+        for _, previousWindow := range previous {
+            store.windows[previousWindow.Id] = previousWindow
+        }
+        return models.MaintenanceWindow{}, err
+    }
+    return getMaintenanceWindowModel(window, now), nil
+}
+
+// Takes a node out of maintenance by deleting the active windows of the node itself. Windows of
+// its zone or of the cluster, and windows that have not started, are left.
+func (store *maintenanceWindowStore) endNodeMaintenance(host string) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    previous := store.getActiveNodeWindowsLocked(host, time.Now().Unix())
+    if len(previous) == 0 {
+        return nil
+    }
+    for _, previousWindow := range previous {
+        delete(store.windows, previousWindow.Id)
+    }
+    if err := store.saveLocked(); err != nil {
+        for _, previousWindow := range previous {
+            store.windows[previousWindow.Id] = previousWindow
+        }
+        return err
+    }
+    return nil
+}
+
+// Whether the whole cluster is in an active window
+func (store *maintenanceWindowStore) coversCluster(now int64) bool {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    for _, window := range store.windows {
+        if window.Scope == MAINTENANCE_SCOPE_CLUSTER && window.StartTime <= now &&
+            now < window.EndTime {
+            return true
+        }
+    }
+    return false
+}
+
+// Gets the UUIDs of the nodes among uuids that are in an active maintenance window
+func (c *Container) getNodesUnderMaintenance(uuids []string, now int64) ([]string, error) {
+    nodes := []string{}
+    if len(uuids) == 0 || len(c.maintenance.list()) == 0 {
+        return nodes, nil
+    }
+    hostToUuid, err := c.hostToUuid.get()
+    if err != nil {
+        return nil, err
+    }
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return nil, tabletServersResponse.Error
+    }
+    zones := map[string]string{}
+    hosts := map[string]string{}
+    for _, cluster := range tabletServersResponse.Tablets {
+        for address, tabletServer := range cluster {
+            host, err := helpers.GetHostFromAddress(address)
+            if err != nil {
+                continue
+            }
+            if uuid, ok := hostToUuid.Get(host); ok {
+                hosts[uuid] = host
+                zones[uuid] = getMaintenanceZone(tabletServer)
+            }
+        }
+    }
+    for _, uuid := range uuids {
+        // Nodes that are not known anymore are only covered by the windows of the cluster
+        if c.maintenance.covers(hosts[uuid], zones[uuid], now) {
+            nodes = append(nodes, uuid)
+        }
+    }
+    return nodes, nil
+}
+
+// Gets the zone of a tserver as it is given as the target of a maintenance window
+func getMaintenanceZone(tabletServer helpers.TabletServer) string {
+    return tabletServer.Cloud + "." + tabletServer.Region + "." + tabletServer.Zone
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "sort"
+)
+
+const MASTER_ROLE_LEADER = "LEADER"
+
+// Type of the metrics entity that covers the server as a whole, rather than a table or tablet
+const SERVER_METRICS_ENTITY_TYPE = "server"
+
+// Gets the metrics of the server entity by name. Histograms are reported as a _count and a
+// _sum, as on the Prometheus endpoint.
+func getServerMetricValues(entities []helpers.ServerMetricsEntity) map[string]float64 {
+    values := map[string]float64{}
+    for _, entity := range entities {
+        if entity.Type != SERVER_METRICS_ENTITY_TYPE {
+            continue
+        }
+        for _, metric := range entity.Metrics {
+            name, ok := metric["name"].(string)
+            if !ok {
+                continue
+            }
+            if value, ok := metric["value"].(float64); ok {
+                values[name] = value
+            } else if count, ok := metric["total_count"].(float64); ok {
+                values[name+"_count"] = count
+                if sum, ok := metric["total_sum"].(float64); ok {
+                    values[name+"_sum"] = sum
+                }
+            }
+        }
+    }
+    return values
+}
+
+// The requests for the flags and metrics of a master
+type masterDetailsFutures struct {
+    varz chan helpers.VarzFuture
+    metrics chan helpers.ServerMetricsFuture
+}
+
+// Fetches the flags and metrics of the masters in parallel and merges them by name. Masters
+// that cannot be reached are listed along with the reason. The impact of changing each flag
+// comes from the documentation of the flags of the master, which may be nil.
+func getMasterDetails(masters []helpers.Master,
+    flagDocs map[string]helpers.GFlagDoc) models.MasterDetails {
+    details := models.MasterDetails{
+        Masters: []models.MasterDetailsServer{},
+        Flags: []models.MasterFlag{},
+        Metrics: []models.MasterMetric{},
+    }
+    fanOut := newFanOutLimiter()
+    futures := []*masterDetailsFutures{}
+    for _, master := range masters {
+        server := models.MasterDetailsServer{
+            Uuid: master.InstanceId.PermanentUuid,
+            Role: master.Role,
+            IsLeader: master.Role == MASTER_ROLE_LEADER,
+            StartTimeUs: master.InstanceId.StartTimeUs,
+        }
+        if len(master.Registration.PrivateRpcAddresses) > 0 {
+            server.Host = helpers.NormalizeHost(master.Registration.PrivateRpcAddresses[0].Host)
+        }
+        if server.IsLeader {
+            details.LeaderUuid = server.Uuid
+        }
+        details.Masters = append(details.Masters, server)
+        if master.Error != nil || server.Host == "" {
+            futures = append(futures, nil)
+            continue
+        }
+        host := server.Host
+        masterFutures := &masterDetailsFutures{
+            varz: make(chan helpers.VarzFuture, 1),
+            metrics: make(chan helpers.ServerMetricsFuture, 1),
+        }
+        futures = append(futures, masterFutures)
+        fanOut.goCall(func() { helpers.GetVarzFuture(host, true, masterFutures.varz) })
+        fanOut.goCall(func() { helpers.GetServerMetricsFuture(host, true, masterFutures.metrics) })
+    }
+    flags := map[string]*models.MasterFlag{}
+    metrics := map[string]*models.MasterMetric{}
+    fetchedCount := 0
+    for index, masterFutures := range futures {
+        server := &details.Masters[index]
+        if masterFutures == nil {
+            message := "the master is unreachable"
+            if masters[index].Error != nil {
+                message = masters[index].Error.Message
+            }
+            server.Error = &message
+            continue
+        }
+        varz := <-masterFutures.varz
+        serverMetrics := <-masterFutures.metrics
+        if varz.Error != nil || serverMetrics.Error != nil {
+            err := varz.Error
+            if err == nil {
+                err = serverMetrics.Error
+            }
+            message := err.Error()
+            server.Error = &message
+            continue
+        }
+        fetchedCount++
+        for _, varzFlag := range varz.Varz.Flags {
+            flag, ok := flags[varzFlag.Name]
+            if !ok {
+                flag = &models.MasterFlag{
+                    Name: varzFlag.Name,
+                    Type: varzFlag.Type,
+                    Values: map[string]string{},
+                }
+                flags[varzFlag.Name] = flag
+            }
+            if server.IsLeader {
+                flag.Type = varzFlag.Type
+            }
+            flag.Values[server.Uuid] = varzFlag.Value
+        }
+        for name, value := range getServerMetricValues(serverMetrics.Entities) {
+            metric, ok := metrics[name]
+            if !ok {
+                metric = &models.MasterMetric{
+                    Name: name,
+                    Values: map[string]float64{},
+                }
+                metrics[name] = metric
+            }
+            metric.Values[server.Uuid] = value
+        }
+    }
+    for _, flag := range flags {
+        distinctValues := map[string]bool{}
+        for _, value := range flag.Values {
+            distinctValues[value] = true
+        }
+        // A flag that some masters do not have differs as well
+        flag.IsUniform = len(flag.Values) == fetchedCount && len(distinctValues) == 1
+        doc, hasDoc := flagDocs[flag.Name]
+        flag.Impact = getGflagImpact(doc, hasDoc, flag.Type)
+        details.Flags = append(details.Flags, *flag)
+    }
+    for _, metric := range metrics {
+        details.Metrics = append(details.Metrics, *metric)
+    }
+    sort.SliceStable(details.Masters, func(i, j int) bool {
+        if details.Masters[i].IsLeader != details.Masters[j].IsLeader {
+            return details.Masters[i].IsLeader
+        }
+        return details.Masters[i].Host < details.Masters[j].Host
+    })
+    sort.Slice(details.Flags, func(i, j int) bool {
+        return details.Flags[i].Name < details.Flags[j].Name
+    })
+    sort.Slice(details.Metrics, func(i, j int) bool {
+        return details.Metrics[i].Name < details.Metrics[j].Name
+    })
+    return details
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/yugabyte/gocql"
+)
+
+// How often the downsampler checks whether a bucket has ended
+const DOWNSAMPLE_CHECK_INTERVAL = time.Minute
+
+// How long after a bucket ends it is rolled up, so that its last samples have been written
+const DOWNSAMPLE_DELAY = time.Minute
+
+// The buckets rolled up at most in one go, when the server starts or the tables were
+// unreachable for a while. Older buckets are left out.
+const DOWNSAMPLE_MAX_BUCKETS = 288
+
+// The resolution of the samples read from the metrics table or the fallback
+const METRICS_RESOLUTION_RAW = "raw"
+
+const DOWNSAMPLE_CREATE_KEYSPACE = "create keyspace if not exists %s"
+const DOWNSAMPLE_CREATE_TABLE = "create table if not exists %s (node text, metric text, " +
+    "ts timestamp, value double, primary key ((node), metric, ts)) " +
+    "with clustering order by (metric asc, ts desc)"
+const DOWNSAMPLE_INSERT = "insert into %s (node, metric, ts, value) values (?, ?, ?, ?) " +
+    "using ttl %d"
+
+// Queries of the rolled up samples, in the same layout as the queries of the metrics table
+const DOWNSAMPLED_QUERY_LIMIT_ONE = "select ts, value from %s where node = ? and metric = ? " +
+    "limit 1"
+const DOWNSAMPLED_QUERY_NODE = "select ts, value from %s where node = ? and metric = ? " +
+    "and ts >= ? and ts < ?"
+const DOWNSAMPLED_QUERY = "select ts, value from %s where metric = ? and ts >= ? and ts < ?"
+
+// A resolution the samples are rolled up to, each sample being the average of the samples of
+// a bucket of the interval. Buckets start at multiples of the interval since the Unix epoch.
+type metricsResolution struct {
+    name string
+    interval time.Duration
+    table string
+}
+
+// Finest first
+var METRICS_RESOLUTIONS = []metricsResolution{
+    {"5m", 5 * time.Minute, "metrics_5m"},
+    {"1h", time.Hour, "metrics_1h"},
+}
+
+func (resolution metricsResolution) qualifiedTable(metricsConfig helpers.MetricsConfig) string {
+    return metricsConfig.DownsampleKeyspace + "." + resolution.table
+}
+
+func (resolution metricsResolution) retention(
+    metricsConfig helpers.MetricsConfig,
+) time.Duration {
+    if resolution.interval == time.Hour {
+        return metricsConfig.Downsample1hRetention
+    }
+    return metricsConfig.Downsample5mRetention
+}
+
+// The metrics that are rolled up, and whether their value is in the details column of the
+// metrics table
+func getDownsampledMetrics(metricsConfig helpers.MetricsConfig) map[string]bool {
+    return map[string]bool{
+        metricsConfig.CpuUsageUserMetric: true,
+        metricsConfig.CpuUsageSystemMetric: true,
+        metricsConfig.TotalDiskMetric: false,
+        metricsConfig.FreeDiskMetric: false,
+        metricsConfig.NodeUpMetric: false,
+        metricsConfig.ReadCountMetric: false,
+        metricsConfig.WriteCountMetric: false,
+        metricsConfig.ReadSumMetric: false,
+        metricsConfig.WriteSumMetric: false,
+    }
+}
+
+// Picks the coarsest resolution whose samples are no further apart than the intervals a range
+// is charted in. Returns false if the range is narrow enough for the raw samples.
+func getMetricsResolution(startTime int64, endTime int64) (metricsResolution, bool) {
+    chartInterval := time.Duration((endTime-startTime)/GRANULARITY_NUM_INTERVALS) * time.Second
+    for index := len(METRICS_RESOLUTIONS) - 1; index >= 0; index-- {
+        if chartInterval >= METRICS_RESOLUTIONS[index].interval {
+            return METRICS_RESOLUTIONS[index], true
+        }
+    }
+    return metricsResolution{}, false
+}
+
+// Reads the rolled up samples of a resolution. The value of every metric is in the value
+// column, so detailsValue is ignored.
+type downsampledMetricsReader struct {
+    session *gocql.Session
+    table string
+}
+
+func (reader downsampledMetricsReader) scan(query *gocql.Query) ([][]float64, error) {
+    var ts int64
+    var value float64
+    values := [][]float64{}
+    iter := query.Iter()
+    for iter.Scan(&ts, &value) {
+        values = append(values, []float64{float64(ts) / 1000, value})
+    }
+    if err := iter.Close(); err != nil {
+        return values, err
+    }
+    sort.Slice(values, func(i, j int) bool {
+        return values[i][0] < values[j][0]
+    })
+    return values, nil
+}
+
+func (reader downsampledMetricsReader) nodeValues(metric string, uuid string, startTime int64,
+    endTime int64, detailsValue bool) ([][]float64, error) {
+    return reader.scan(reader.session.Query(fmt.Sprintf(DOWNSAMPLED_QUERY_NODE, reader.table),
+        uuid, metric, startTime*1000, endTime*1000))
+}
+
+func (reader downsampledMetricsReader) allNodeValues(metric string, startTime int64,
+    endTime int64) ([][]float64, error) {
+    return reader.scan(reader.session.Query(fmt.Sprintf(DOWNSAMPLED_QUERY, reader.table),
+        metric, startTime*1000, endTime*1000))
+}
+
+func (reader downsampledMetricsReader) latestSample(metric string, uuid string,
+    detailsValue bool) ([]float64, error) {
+    values, err := reader.scan(reader.session.Query(
+        fmt.Sprintf(DOWNSAMPLED_QUERY_LIMIT_ONE, reader.table), uuid, metric))
+    if err != nil {
+        return nil, err
+    }
+    if len(values) == 0 {
+        return nil, fmt.Errorf("no samples of %s for node %s", metric, uuid)
+    }
+    return values[0], nil
+}
+
+// Gets the reader of the rolled up samples to chart a range with, and the name of their
+// resolution. Returns false if the range is narrow, downsampling is off or YCQL is unavailable,
+// in which case the raw samples are read.
+func (c *Container) getDownsampledMetricsReader(startTime int64,
+    endTime int64) (metricsReader, string, bool) {
+    metricsConfig := helpers.GetConfig().Metrics
+    if !metricsConfig.Downsample {
+        return nil, "", false
+    }
+    resolution, ok := getMetricsResolution(startTime, endTime)
+    if !ok {
+        return nil, "", false
+    }
+    session, err := c.getYcqlSession()
+    if err != nil {
+        return nil, "", false
+    }
+    return downsampledMetricsReader{session, resolution.qualifiedTable(metricsConfig)},
+        resolution.name, true
+}
+
+// Where the samples to roll up are read from, and the uuids of the nodes to roll up
+type downsampleSource func() (metricsReader, []string, error)
+
+// Gets the reader of the raw samples, which is the fallback while the metrics table is stale
+func (c *Container) getDownsampleSource() (metricsReader, []string, error) {
+    hostToUuid, err := c.hostToUuid.get()
+    if err != nil {
+        return nil, nil, err
+    }
+    reader, err := c.getMetricsReader(hostToUuid)
+    if err != nil {
+        return nil, nil, err
+    }
+    uuids := []string{}
+    for _, uuid := range hostToUuid {
+        uuids = append(uuids, uuid)
+    }
+    return reader, uuids, nil
+}
+
+// Rolls the raw samples of each node up into the tables of each resolution once their buckets
+// end. The rolled up samples expire after the retention of their resolution.
+type metricsDownsampler struct {
+    // End of the last bucket rolled up at each resolution, by name
+    lastBucketEnds map[string]time.Time
+    tablesCreated bool
+    logger logger.Logger
+}
+
+func newMetricsDownsampler(log logger.Logger) *metricsDownsampler {
+    return &metricsDownsampler{
+        lastBucketEnds: map[string]time.Time{},
+        logger: log,
+    }
+}
+
+func (downsampler *metricsDownsampler) run(getSession func() (*gocql.Session, error),
+    getSource downsampleSource) {
+    for {
+        if helpers.GetConfig().Metrics.Downsample {
+            if err := downsampler.check(time.Now(), getSession, getSource); err != nil {
+                downsampler.logger.Debugf("failed to roll up the metrics: %s", err.Error())
+            }
+        }
+        time.Sleep(DOWNSAMPLE_CHECK_INTERVAL)
+    }
+}
+
+// Rolls up the buckets of each resolution that ended since the previous check
+func (downsampler *metricsDownsampler) check(now time.Time,
+    getSession func() (*gocql.Session, error), getSource downsampleSource) error {
+    metricsConfig := helpers.GetConfig().Metrics
+    session, err := getSession()
+    if err != nil {
+        return err
+    }
+    if !downsampler.tablesCreated {
+        if err := createDownsampleTables(session, metricsConfig); err != nil {
+            return err
+        }
+        downsampler.tablesCreated = true
+    }
+    reader, uuids, err := getSource()
+    if err != nil {
+        return err
+    }
+    for _, resolution := range METRICS_RESOLUTIONS {
+        bucketsEnd := getReportPeriodEnd(now.Add(-DOWNSAMPLE_DELAY), resolution.interval)
+        bucketsStart, ok := downsampler.lastBucketEnds[resolution.name]
+        if !ok {
+            bucketsStart = getLastDownsampledBucketEnd(session, resolution, metricsConfig,
+                uuids)
+        }
+        earliestStart := bucketsEnd.Add(-DOWNSAMPLE_MAX_BUCKETS * resolution.interval)
+        if bucketsStart.Before(earliestStart) {
+            bucketsStart = earliestStart
+        }
+        if !bucketsEnd.After(bucketsStart) {
+            continue
+        }
+        if err := rollUpMetrics(session, reader, uuids, metricsConfig, resolution,
+            bucketsStart, bucketsEnd); err != nil {
+            // The tables may have been dropped
+            downsampler.tablesCreated = false
+            return err
+        }
+        downsampler.lastBucketEnds[resolution.name] = bucketsEnd
+    }
+    return nil
+}
+
+func createDownsampleTables(session *gocql.Session, metricsConfig helpers.MetricsConfig) error {
+    err := session.Query(fmt.Sprintf(DOWNSAMPLE_CREATE_KEYSPACE,
+        metricsConfig.DownsampleKeyspace)).Exec()
+    if err != nil {
+        return err
+    }
+    for _, resolution := range METRICS_RESOLUTIONS {
+        err := session.Query(fmt.Sprintf(DOWNSAMPLE_CREATE_TABLE,
+            resolution.qualifiedTable(metricsConfig))).Exec()
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Gets the end of the latest bucket rolled up before the server started, zero if there is none
+func getLastDownsampledBucketEnd(session *gocql.Session, resolution metricsResolution,
+    metricsConfig helpers.MetricsConfig, uuids []string) time.Time {
+    reader := downsampledMetricsReader{session, resolution.qualifiedTable(metricsConfig)}
+    lastBucketEnd := time.Time{}
+    for _, uuid := range uuids {
+        latest, err := reader.latestSample(metricsConfig.NodeUpMetric, uuid, false)
+        if err != nil {
+            continue
+        }
+        bucketEnd := time.Unix(int64(latest[0]), 0).Add(resolution.interval)
+        if bucketEnd.After(lastBucketEnd) {
+            lastBucketEnd = bucketEnd
+        }
+    }
+    return lastBucketEnd
+}
+
+// Writes the average of the raw samples of each bucket between two times, for each metric of
+// each node. Buckets without samples are left out.
+func rollUpMetrics(session *gocql.Session, reader metricsReader, uuids []string,
+    metricsConfig helpers.MetricsConfig, resolution metricsResolution, start time.Time,
+    end time.Time) error {
+    insert := fmt.Sprintf(DOWNSAMPLE_INSERT, resolution.qualifiedTable(metricsConfig),
+        int64(resolution.retention(metricsConfig)/time.Second))
+    intervalSeconds := int64(resolution.interval / time.Second)
+    for metric, detailsValue := range getDownsampledMetrics(metricsConfig) {
+        for _, uuid := range uuids {
+            values, err := reader.nodeValues(metric, uuid, start.Unix(), end.Unix(),
+                detailsValue)
+            if err != nil {
+                return err
+            }
+            sums := map[int64]float64{}
+            counts := map[int64]int{}
+            for _, value := range values {
+                bucketStart := int64(value[0]) - int64(value[0])%intervalSeconds
+                sums[bucketStart] += value[1]
+                counts[bucketStart]++
+            }
+            if len(sums) == 0 {
+                continue
+            }
+            batch := session.NewBatch(gocql.UnloggedBatch)
+            for bucketStart, sum := range sums {
+                batch.Query(insert, uuid, metric, bucketStart*1000,
+                    sum/float64(counts[bucketStart]))
+            }
+            if err := session.ExecuteBatch(batch); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
@@ -0,0 +1,484 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/tsdb"
+    "runtime"
+    "sort"
+    "sync/atomic"
+    "time"
+)
+
+// Cumulative cpu times of a tserver process in milliseconds, as reported by the tserver
+type cpuTimes struct {
+    timestamp int64
+    user float64
+    system float64
+}
+
+// The rates of the statements run through each API, by node, as stored in the fallback store.
+// yugabyted does not write them to the metrics table, so they are always read from the store.
+const YSQL_READ_OPS_METRIC = "ysql_read_ops_per_sec"
+const YSQL_WRITE_OPS_METRIC = "ysql_write_ops_per_sec"
+const YCQL_READ_OPS_METRIC = "ycql_read_ops_per_sec"
+const YCQL_WRITE_OPS_METRIC = "ycql_write_ops_per_sec"
+
+// The statement counters of the YSQL and YCQL servers that each rate is computed from
+var YSQL_OPS_COUNTERS = map[string][]string{
+    YSQL_READ_OPS_METRIC: {"handler_latency_yb_ysqlserver_SQLProcessor_SelectStmt_count"},
+    YSQL_WRITE_OPS_METRIC: {
+        "handler_latency_yb_ysqlserver_SQLProcessor_InsertStmt_count",
+        "handler_latency_yb_ysqlserver_SQLProcessor_UpdateStmt_count",
+        "handler_latency_yb_ysqlserver_SQLProcessor_DeleteStmt_count",
+    },
+}
+var YCQL_OPS_COUNTERS = map[string][]string{
+    YCQL_READ_OPS_METRIC: {"handler_latency_yb_cqlserver_SQLProcessor_SelectStmt_count"},
+    YCQL_WRITE_OPS_METRIC: {
+        "handler_latency_yb_cqlserver_SQLProcessor_InsertStmt_count",
+        "handler_latency_yb_cqlserver_SQLProcessor_UpdateStmt_count",
+        "handler_latency_yb_cqlserver_SQLProcessor_DeleteStmt_count",
+    },
+}
+
+// The rates by the name of the metric the UI requests them as
+var API_OPS_METRICS = map[string]string{
+    "YSQL_READ_OPS_PER_SEC": YSQL_READ_OPS_METRIC,
+    "YSQL_WRITE_OPS_PER_SEC": YSQL_WRITE_OPS_METRIC,
+    "YCQL_READ_OPS_PER_SEC": YCQL_READ_OPS_METRIC,
+    "YCQL_WRITE_OPS_PER_SEC": YCQL_WRITE_OPS_METRIC,
+}
+
+// The rates of the reads and writes served by the tablets of each table, summed over the
+// nodes, as stored in the table store of the fallback under the table id
+const TABLE_READ_OPS_METRIC = "table_read_ops_per_sec"
+const TABLE_WRITE_OPS_METRIC = "table_write_ops_per_sec"
+
+// The tablet counters of the tservers that each rate of a table is computed from. Tservers
+// report them per tablet, or per table when tablet metrics are aggregated, with the table_id
+// label either way.
+var TABLE_OPS_COUNTERS = map[string][]string{
+    TABLE_READ_OPS_METRIC: {"ql_read_latency_count"},
+    TABLE_WRITE_OPS_METRIC: {"write_op_duration_client_propagated_consistency_count"},
+}
+
+// The rates of a table by the name of the metric the UI requests them as
+var API_TABLE_OPS_METRICS = map[string]string{
+    "READ_OPS_PER_SEC": TABLE_READ_OPS_METRIC,
+    "WRITE_OPS_PER_SEC": TABLE_WRITE_OPS_METRIC,
+}
+
+// A rate of a table on a node
+type tableOpsKey struct {
+    tableId string
+    rateMetric string
+}
+
+// A cumulative counter at a scrape
+type counterSample struct {
+    timestamp int64
+    value float64
+}
+
+// Scrapes the tservers directly into an in-memory store, which backs the charts when the YCQL
+// metrics table is missing or stale, e.g. on clusters that only run YSQL. Only the metrics that
+// the UI reads are kept, under the same names as in the metrics table.
+type fallbackMetrics struct {
+    store *tsdb.Store
+    // The ops rates of the tables, by rate and table id in place of the node
+    tableStore *tsdb.Store
+    // The bytes per second replicated between zones, by kind of traffic and pair of zones in
+    // place of the node
+    trafficStore *tsdb.Store
+    hostToUuid *hostToUuidCache
+    logger logger.Logger
+    // The cpu times of each node at the previous scrape, by node uuid
+    previousCpu map[string]cpuTimes
+    // The statement counters of each node at the previous scrape, by node uuid and rate
+    previousOps map[string]map[string]counterSample
+    // The tablet counters of each table on each node at the previous scrape, by node uuid
+    previousTableOps map[string]map[tableOpsKey]counterSample
+    // The bytes each node logged for each tablet at the previous scrape, by node uuid and
+    // tablet id
+    previousLogBytes map[string]map[string]counterSample
+    // The bytes each node sent to xCluster consumers at the previous scrape, by node uuid
+    previousXclusterBytes map[string]counterSample
+    inUse int32
+}
+
+func newFallbackMetrics(log logger.Logger, hostToUuid *hostToUuidCache) *fallbackMetrics {
+    capacity := getFallbackCapacity(helpers.GetConfig().Metrics)
+    fallback := &fallbackMetrics{
+        store: tsdb.NewStore(capacity),
+        tableStore: tsdb.NewStore(capacity),
+        trafficStore: tsdb.NewStore(capacity),
+        hostToUuid: hostToUuid,
+        logger: log,
+        previousCpu: map[string]cpuTimes{},
+        previousOps: map[string]map[string]counterSample{},
+        previousTableOps: map[string]map[tableOpsKey]counterSample{},
+        previousLogBytes: map[string]map[string]counterSample{},
+        previousXclusterBytes: map[string]counterSample{},
+    }
+    go fallback.scrapeLoop()
+    return fallback
+}
+
+// Number of samples of each series that cover the retention
+func getFallbackCapacity(metricsConfig helpers.MetricsConfig) int {
+    return int(metricsConfig.FallbackRetention / metricsConfig.FallbackScrapeInterval)
+}
+
+// Records whether the metrics are served from the store, logging when that changes
+func (fallback *fallbackMetrics) setInUse(inUse bool) {
+    value := int32(0)
+    if inUse {
+        value = 1
+    }
+    if atomic.SwapInt32(&fallback.inUse, value) == value {
+        return
+    }
+    if inUse {
+        fallback.logger.Infof("metrics table is unavailable or stale, " +
+            "serving metrics scraped from the tservers")
+    } else {
+        fallback.logger.Infof("metrics table is up to date again, serving metrics from it")
+    }
+}
+
+func (fallback *fallbackMetrics) scrapeLoop() {
+    for {
+        metricsConfig := helpers.GetConfig().Metrics
+        if metricsConfig.Fallback {
+            fallback.store.SetCapacity(getFallbackCapacity(metricsConfig))
+            fallback.tableStore.SetCapacity(getFallbackCapacity(metricsConfig))
+            fallback.trafficStore.SetCapacity(getFallbackCapacity(metricsConfig))
+            fallback.scrape(metricsConfig)
+        }
+        time.Sleep(metricsConfig.FallbackScrapeInterval)
+    }
+}
+
+func (fallback *fallbackMetrics) scrape(metricsConfig helpers.MetricsConfig) {
+    hostToUuid, err := fallback.hostToUuid.get()
+    if err != nil {
+        fallback.logger.Debugf("skipping metrics scrape, failed to get tserver uuids: %s",
+            err.Error())
+        return
+    }
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        fallback.logger.Debugf("skipping metrics scrape, failed to get tservers: %s",
+            tabletServersResponse.Error.Error())
+        return
+    }
+    dumpEntitiesFuture := make(chan helpers.DumpEntitiesFuture)
+    go helpers.GetDumpEntitiesFuture(helpers.HOST, dumpEntitiesFuture)
+    timestamp := time.Now().UnixMilli()
+    nodes := map[string]bool{}
+    locations := map[string]nodeLocation{}
+    tserverMetricsFutures := map[string]chan helpers.TserverMetricsFuture{}
+    ysqlMetricsFutures := map[string]chan helpers.TserverMetricsFuture{}
+    ycqlMetricsFutures := map[string]chan helpers.TserverMetricsFuture{}
+    for _, cluster := range tabletServersResponse.Tablets {
+        for address, tabletServer := range cluster {
+            host, err := helpers.GetHostFromAddress(address)
+            if err != nil {
+                continue
+            }
+            uuid, ok := hostToUuid.Get(host)
+            if !ok {
+                continue
+            }
+            nodes[uuid] = true
+            locations[uuid] = nodeLocation{tabletServer.Cloud, tabletServer.Region,
+                tabletServer.Zone}
+            isAlive := tabletServer.Status == "ALIVE"
+            nodeUp := float64(0)
+            if isAlive {
+                nodeUp = 1
+            }
+            fallback.store.Append(metricsConfig.NodeUpMetric, uuid, tsdb.Sample{
+                Timestamp: timestamp,
+                Value: nodeUp,
+            })
+            if len(tabletServer.PathMetrics) > 0 {
+                totalDisk, usedDisk := float64(0), float64(0)
+                for _, pathMetrics := range tabletServer.PathMetrics {
+                    totalDisk += float64(pathMetrics.TotalSpaceSize)
+                    usedDisk += float64(pathMetrics.SpaceUsed)
+                }
+                fallback.store.Append(metricsConfig.TotalDiskMetric, uuid, tsdb.Sample{
+                    Timestamp: timestamp,
+                    Value: totalDisk,
+                })
+                fallback.store.Append(metricsConfig.FreeDiskMetric, uuid, tsdb.Sample{
+                    Timestamp: timestamp,
+                    Value: totalDisk - usedDisk,
+                })
+            }
+            if isAlive {
+                tserverMetricsFuture := make(chan helpers.TserverMetricsFuture)
+                tserverMetricsFutures[uuid] = tserverMetricsFuture
+                go helpers.GetTserverMetricsFuture(host, tserverMetricsFuture)
+                ysqlMetricsFuture := make(chan helpers.TserverMetricsFuture)
+                ysqlMetricsFutures[uuid] = ysqlMetricsFuture
+                go helpers.GetApiServerMetricsFuture(host, true, ysqlMetricsFuture)
+                ycqlMetricsFuture := make(chan helpers.TserverMetricsFuture)
+                ycqlMetricsFutures[uuid] = ycqlMetricsFuture
+                go helpers.GetApiServerMetricsFuture(host, false, ycqlMetricsFuture)
+            }
+        }
+    }
+    counters := []string{
+        metricsConfig.ReadCountMetric,
+        metricsConfig.WriteCountMetric,
+        metricsConfig.ReadSumMetric,
+        metricsConfig.WriteSumMetric,
+    }
+    // The rates of each table summed over the nodes, by table id and rate
+    tableOps := map[string]map[string]float64{}
+    replicationRates := map[string]nodeReplicationRates{}
+    scrapedTservers := 0
+    for uuid, tserverMetricsFuture := range tserverMetricsFutures {
+        tserverMetrics := <-tserverMetricsFuture
+        if tserverMetrics.Error != nil {
+            fallback.logger.Debugf("failed to scrape the metrics of tserver %s: %s",
+                uuid, tserverMetrics.Error.Error())
+            continue
+        }
+        for _, counter := range counters {
+            samples, ok := tserverMetrics.Metrics[counter]
+            if !ok {
+                continue
+            }
+            // The counters are reported per server type, and summed like in the metrics table
+            value := float64(0)
+            for _, sample := range samples {
+                value += sample.Value
+            }
+            fallback.store.Append(counter, uuid, tsdb.Sample{
+                Timestamp: timestamp,
+                Value: value,
+            })
+        }
+        fallback.appendCpuUsage(metricsConfig, uuid, timestamp, tserverMetrics.Metrics)
+        fallback.addTableOpsRates(uuid, timestamp, tserverMetrics.Metrics, tableOps)
+        replicationRates[uuid] = fallback.getReplicationRates(uuid, timestamp,
+            tserverMetrics.Metrics)
+        scrapedTservers++
+    }
+    fallback.appendTableOpsRates(metricsConfig, timestamp, tableOps)
+    // Tables and pairs of zones are only known to be gone once every tserver was scraped
+    scrapedAll := scrapedTservers > 0 && scrapedTservers == len(tserverMetricsFutures)
+    if dumpEntities := <-dumpEntitiesFuture; dumpEntities.Error == nil {
+        fallback.appendReplicationTraffic(timestamp, dumpEntities.Entities.Tablets, locations,
+            replicationRates, scrapedAll)
+    } else {
+        fallback.logger.Debugf("failed to get the tablet replicas: %s",
+            dumpEntities.Error.Error())
+    }
+    if scrapedAll {
+        tables := map[string]bool{}
+        for tableId := range tableOps {
+            tables[tableId] = true
+        }
+        fallback.tableStore.Retain(tables)
+    }
+    // YCQL or YSQL may not be enabled on every node, their rates are left out there
+    for uuid, ysqlMetricsFuture := range ysqlMetricsFutures {
+        if ysqlMetrics := <-ysqlMetricsFuture; ysqlMetrics.Error == nil {
+            fallback.appendOpsRates(uuid, timestamp, YSQL_OPS_COUNTERS, ysqlMetrics.Metrics)
+        }
+    }
+    for uuid, ycqlMetricsFuture := range ycqlMetricsFutures {
+        if ycqlMetrics := <-ycqlMetricsFuture; ycqlMetrics.Error == nil {
+            fallback.appendOpsRates(uuid, timestamp, YCQL_OPS_COUNTERS, ycqlMetrics.Metrics)
+        }
+    }
+    fallback.store.Retain(nodes)
+    for uuid := range fallback.previousCpu {
+        if !nodes[uuid] {
+            delete(fallback.previousCpu, uuid)
+        }
+    }
+    for uuid := range fallback.previousOps {
+        if !nodes[uuid] {
+            delete(fallback.previousOps, uuid)
+        }
+    }
+    for uuid := range fallback.previousTableOps {
+        if !nodes[uuid] {
+            delete(fallback.previousTableOps, uuid)
+        }
+    }
+    for uuid := range fallback.previousLogBytes {
+        if !nodes[uuid] {
+            delete(fallback.previousLogBytes, uuid)
+        }
+    }
+    for uuid := range fallback.previousXclusterBytes {
+        if !nodes[uuid] {
+            delete(fallback.previousXclusterBytes, uuid)
+        }
+    }
+}
+
+// Adds the reads and writes per second served on a node since the previous scrape to the
+// rates of each table, from the sums of the counters of the tablets of the table
+func (fallback *fallbackMetrics) addTableOpsRates(uuid string, timestamp int64,
+    metrics map[string][]helpers.NodeExporterSample, tableOps map[string]map[string]float64) {
+    currentOps := map[tableOpsKey]counterSample{}
+    for rateMetric, counterNames := range TABLE_OPS_COUNTERS {
+        for _, counterName := range counterNames {
+            for _, sample := range metrics[counterName] {
+                tableId := sample.Label("table_id")
+                if tableId == "" {
+                    continue
+                }
+                key := tableOpsKey{tableId, rateMetric}
+                current := currentOps[key]
+                current.timestamp = timestamp
+                current.value += sample.Value
+                currentOps[key] = current
+            }
+        }
+    }
+    previousOps := fallback.previousTableOps[uuid]
+    fallback.previousTableOps[uuid] = currentOps
+    for key, current := range currentOps {
+        rates, ok := tableOps[key.tableId]
+        if !ok {
+            rates = map[string]float64{}
+            tableOps[key.tableId] = rates
+        }
+        previous, ok := previousOps[key]
+        // A restarted tserver, or a tablet that moved away, makes the counter go down
+        if !ok || current.timestamp <= previous.timestamp || current.value < previous.value {
+            continue
+        }
+        rates[key.rateMetric] += (current.value - previous.value) * 1000 /
+            float64(current.timestamp-previous.timestamp)
+    }
+}
+
+// Stores the rates of the busiest tables, up to metrics.fallback_max_tables
+func (fallback *fallbackMetrics) appendTableOpsRates(metricsConfig helpers.MetricsConfig,
+    timestamp int64, tableOps map[string]map[string]float64) {
+    tableIds := []string{}
+    totals := map[string]float64{}
+    for tableId, rates := range tableOps {
+        tableIds = append(tableIds, tableId)
+        for _, rate := range rates {
+            totals[tableId] += rate
+        }
+    }
+    sort.Slice(tableIds, func(i, j int) bool {
+        if totals[tableIds[i]] != totals[tableIds[j]] {
+            return totals[tableIds[i]] > totals[tableIds[j]]
+        }
+        return tableIds[i] < tableIds[j]
+    })
+    if len(tableIds) > metricsConfig.FallbackMaxTables {
+        tableIds = tableIds[:metricsConfig.FallbackMaxTables]
+    }
+    for _, tableId := range tableIds {
+        for rateMetric := range TABLE_OPS_COUNTERS {
+            rate, ok := tableOps[tableId][rateMetric]
+            if !ok {
+                continue
+            }
+            fallback.tableStore.Append(rateMetric, tableId, tsdb.Sample{
+                Timestamp: timestamp,
+                Value: rate,
+            })
+        }
+    }
+}
+
+// Gets the rate of ops of a table with startTime <= timestamp < endTime, with timestamps in
+// seconds like the other metrics
+func (fallback *fallbackMetrics) tableOpsValues(rateMetric string, tableId string,
+    startTime int64, endTime int64) [][]float64 {
+    values := [][]float64{}
+    for _, sample := range fallback.tableStore.Query(rateMetric, tableId, startTime*1000,
+        endTime*1000) {
+        values = append(values, []float64{float64(sample.Timestamp) / 1000, sample.Value})
+    }
+    return values
+}
+
+// Stores the statements per second run through an API on a node since the previous scrape,
+// from the sums of the counters of each rate
+func (fallback *fallbackMetrics) appendOpsRates(uuid string, timestamp int64,
+    counters map[string][]string, metrics map[string][]helpers.NodeExporterSample) {
+    previousOps, ok := fallback.previousOps[uuid]
+    if !ok {
+        previousOps = map[string]counterSample{}
+        fallback.previousOps[uuid] = previousOps
+    }
+    for rateMetric, counterNames := range counters {
+        current := counterSample{timestamp: timestamp}
+        for _, counterName := range counterNames {
+            for _, sample := range metrics[counterName] {
+                current.value += sample.Value
+            }
+        }
+        previous, ok := previousOps[rateMetric]
+        previousOps[rateMetric] = current
+        // A restarted server starts counting from zero again
+        if !ok || current.timestamp <= previous.timestamp || current.value < previous.value {
+            continue
+        }
+        fallback.store.Append(rateMetric, uuid, tsdb.Sample{
+            Timestamp: timestamp,
+            Value: (current.value - previous.value) * 1000 /
+                float64(current.timestamp-previous.timestamp),
+        })
+    }
+}
+
+// Gets the latest rate of statements of an API on a node, 0 if there is none yet
+func (fallback *fallbackMetrics) latestOpsRate(rateMetric string, uuid string) float64 {
+    sample, ok := fallback.store.Latest(rateMetric, uuid)
+    if !ok {
+        return 0
+    }
+    return sample.Value
+}
+
+// Stores the cpu usage of the tserver process since the previous scrape as a fraction of all
+// cores, which stands in for the host cpu usage that yugabyted records. The cores of the local
+// host are used for every node, as the tservers do not report theirs.
+func (fallback *fallbackMetrics) appendCpuUsage(metricsConfig helpers.MetricsConfig,
+    uuid string, timestamp int64, metrics map[string][]helpers.NodeExporterSample) {
+    userSamples, hasUser := metrics["cpu_utime"]
+    systemSamples, hasSystem := metrics["cpu_stime"]
+    if !hasUser || !hasSystem || len(userSamples) == 0 || len(systemSamples) == 0 {
+        return
+    }
+    current := cpuTimes{
+        timestamp: timestamp,
+        user: userSamples[0].Value,
+        system: systemSamples[0].Value,
+    }
+    previous, ok := fallback.previousCpu[uuid]
+    fallback.previousCpu[uuid] = current
+    // A restarted tserver starts counting from zero again
+    if !ok || current.timestamp <= previous.timestamp ||
+        current.user < previous.user || current.system < previous.system {
+        return
+    }
+    elapsed := float64(current.timestamp-previous.timestamp) * float64(runtime.NumCPU())
+    fallback.store.Append(metricsConfig.CpuUsageUserMetric, uuid, tsdb.Sample{
+        Timestamp: timestamp,
+        Value: (current.user - previous.user) / elapsed,
+    })
+    fallback.store.Append(metricsConfig.CpuUsageSystemMetric, uuid, tsdb.Sample{
+        Timestamp: timestamp,
+        Value: (current.system - previous.system) / elapsed,
+    })
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/tsdb"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/yugabyte/gocql"
+)
+
+// Reads the samples of the metrics written by yugabyted. Samples are returned as pairs of a
+// timestamp in seconds and a value, oldest first. detailsValue is true if the value of the
+// metric is in the details column instead of the value column of the metrics table.
+type metricsReader interface {
+    nodeValues(metric string, uuid string, startTime int64, endTime int64,
+        detailsValue bool) ([][]float64, error)
+    // Samples of a metric that every node reports the same values of, like the disk usage
+    allNodeValues(metric string, startTime int64, endTime int64) ([][]float64, error)
+    latestSample(metric string, uuid string, detailsValue bool) ([]float64, error)
+}
+
+// Reads the metrics from the YCQL metrics table
+type ycqlMetricsReader struct {
+    session *gocql.Session
+}
+
+func (reader ycqlMetricsReader) scan(query string, detailsValue bool) ([][]float64, error) {
+    var ts int64
+    var value int
+    var details string
+    values := [][]float64{}
+    iter := reader.session.Query(query).Iter()
+    for iter.Scan(&ts, &value, &details) {
+        if detailsValue {
+            detailObj := DetailObj{}
+            json.Unmarshal([]byte(details), &detailObj)
+            values = append(values, []float64{float64(ts) / 1000, detailObj.Value})
+        } else {
+            values = append(values, []float64{float64(ts) / 1000, float64(value)})
+        }
+    }
+    if err := iter.Close(); err != nil {
+        return values, err
+    }
+    sort.Slice(values, func(i, j int) bool {
+        return values[i][0] < values[j][0]
+    })
+    return values, nil
+}
+
+func (reader ycqlMetricsReader) nodeValues(metric string, uuid string, startTime int64,
+    endTime int64, detailsValue bool) ([][]float64, error) {
+    query := fmt.Sprintf(QUERY_FORMAT_NODE, helpers.GetConfig().Metrics.QualifiedTable(),
+        metric, uuid, startTime*1000, endTime*1000)
+    return reader.scan(query, detailsValue)
+}
+
+func (reader ycqlMetricsReader) allNodeValues(metric string, startTime int64,
+    endTime int64) ([][]float64, error) {
+    query := fmt.Sprintf(QUERY_FORMAT, helpers.GetConfig().Metrics.QualifiedTable(),
+        metric, startTime*1000, endTime*1000)
+    return reader.scan(query, false)
+}
+
+func (reader ycqlMetricsReader) latestSample(metric string, uuid string,
+    detailsValue bool) ([]float64, error) {
+    query := fmt.Sprintf(QUERY_LIMIT_ONE, helpers.GetConfig().Metrics.QualifiedTable(),
+        metric, uuid)
+    values, err := reader.scan(query, detailsValue)
+    if err != nil {
+        return nil, err
+    }
+    if len(values) == 0 {
+        return nil, fmt.Errorf("no samples of %s for node %s", metric, uuid)
+    }
+    return values[0], nil
+}
+
+// Reads the metrics from the in-memory store of the tserver metrics. The disk usage is taken
+// from the local node.
+type fallbackMetricsReader struct {
+    store *tsdb.Store
+    localUuid string
+}
+
+func (reader fallbackMetricsReader) nodeValues(metric string, uuid string, startTime int64,
+    endTime int64, detailsValue bool) ([][]float64, error) {
+    values := [][]float64{}
+    for _, sample := range reader.store.Query(metric, uuid, startTime*1000, endTime*1000) {
+        values = append(values, []float64{float64(sample.Timestamp) / 1000, sample.Value})
+    }
+    return values, nil
+}
+
+func (reader fallbackMetricsReader) allNodeValues(metric string, startTime int64,
+    endTime int64) ([][]float64, error) {
+    return reader.nodeValues(metric, reader.localUuid, startTime, endTime, false)
+}
+
+func (reader fallbackMetricsReader) latestSample(metric string, uuid string,
+    detailsValue bool) ([]float64, error) {
+    sample, ok := reader.store.Latest(metric, uuid)
+    if !ok {
+        return nil, fmt.Errorf("no samples of %s for node %s", metric, uuid)
+    }
+    return []float64{float64(sample.Timestamp) / 1000, sample.Value}, nil
+}
+
+// Gets the reader of the metrics table, or of the in-memory fallback when the fallback is
+// enabled and the table cannot be read or has not been written to recently. Whether the table
+// is stale is told by the latest node_up sample of the local node.
+func (c *Container) getMetricsReader(hostToUuid helpers.HostToUuidMap) (metricsReader, error) {
+    metricsConfig := helpers.GetConfig().Metrics
+    localUuid, _ := hostToUuid.Get(helpers.HOST)
+    session, err := c.getYcqlSession()
+    if !metricsConfig.Fallback {
+        if err != nil {
+            return nil, err
+        }
+        return ycqlMetricsReader{session}, nil
+    }
+    if err == nil {
+        reader := ycqlMetricsReader{session}
+        latest, latestErr := reader.latestSample(metricsConfig.NodeUpMetric, localUuid, false)
+        if latestErr == nil &&
+            time.Since(time.UnixMilli(int64(latest[0]*1000))) < metricsConfig.StaleAfter {
+            c.fallbackMetrics.setInUse(false)
+            return reader, nil
+        }
+    }
+    c.fallbackMetrics.setInUse(true)
+    return fallbackMetricsReader{c.fallbackMetrics.store, localUuid}, nil
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/yugabyte/gocql"
+)
+
+// States of the cleanup of the metrics table
+const METRICS_CLEANUP_STATE_DISABLED = "disabled"
+const METRICS_CLEANUP_STATE_PENDING = "pending"
+const METRICS_CLEANUP_STATE_RUNNING = "running"
+const METRICS_CLEANUP_STATE_SUCCEEDED = "succeeded"
+const METRICS_CLEANUP_STATE_FAILED = "failed"
+
+// The metrics table is partitioned by node and clustered by entity_type, entity_id, metric and
+// ts, so a range of ts can only be deleted once the columns before it are all given. The old
+// samples are deleted one node at a time, one series of the node at a time.
+const METRICS_NODES_QUERY = "select distinct node from %s"
+const METRICS_SERIES_QUERY = "select entity_type, entity_id, metric from %s " +
+    "where node = ? and ts < ?"
+const METRICS_CLEANUP_QUERY = "delete from %s where node = ? and entity_type = ? " +
+    "and entity_id = ? and metric = ? and ts < ?"
+
+// A series of samples of the metrics table, the clustering columns before ts
+type metricsSeries struct {
+    entityType string
+    entityId string
+    metric string
+}
+
+// Deletes the samples of the metrics table that are older than metrics.retention every
+// metrics.cleanup_interval, and keeps the status of the latest cleanup in memory
+type metricsCleaner struct {
+    mutex sync.Mutex
+    cleanup models.MetricsCleanup
+    wake chan struct{}
+    logger logger.Logger
+}
+
+func newMetricsCleaner(log logger.Logger) *metricsCleaner {
+    return &metricsCleaner{
+        cleanup: models.MetricsCleanup{State: METRICS_CLEANUP_STATE_PENDING},
+        wake: make(chan struct{}, 1),
+        logger: log,
+    }
+}
+
+// Makes the cleaner run again without waiting for the interval
+func (cleaner *metricsCleaner) trigger() {
+    select {
+    case cleaner.wake <- struct{}{}:
+    default:
+    }
+}
+
+func (cleaner *metricsCleaner) run(getSession func() (*gocql.Session, error)) {
+    for {
+        metricsConfig := helpers.GetConfig().Metrics
+        if metricsConfig.Retention > 0 {
+            cleaner.clean(getSession, metricsConfig)
+        } else {
+            cleaner.mutex.Lock()
+            cleaner.cleanup.State = METRICS_CLEANUP_STATE_DISABLED
+            cleaner.cleanup.NextRunAt = 0
+            cleaner.mutex.Unlock()
+        }
+        select {
+        case <-cleaner.wake:
+        case <-time.After(metricsConfig.CleanupInterval):
+        }
+    }
+}
+
+func (cleaner *metricsCleaner) clean(getSession func() (*gocql.Session, error),
+    metricsConfig helpers.MetricsConfig) {
+    startedAt := time.Now()
+    cutoff := startedAt.Add(-metricsConfig.Retention)
+    cleaner.mutex.Lock()
+    cleaner.cleanup = models.MetricsCleanup{
+        State: METRICS_CLEANUP_STATE_RUNNING,
+        StartedAt: startedAt.Unix(),
+        Cutoff: cutoff.Unix(),
+    }
+    cleaner.mutex.Unlock()
+    nodesCleaned, err := cleanMetricsTable(getSession, metricsConfig.QualifiedTable(), cutoff)
+    cleaner.mutex.Lock()
+    defer cleaner.mutex.Unlock()
+    cleaner.cleanup.NodesCleaned = nodesCleaned
+    cleaner.cleanup.FinishedAt = time.Now().Unix()
+    cleaner.cleanup.NextRunAt = time.Now().Add(metricsConfig.CleanupInterval).Unix()
+    if err != nil {
+        cleaner.cleanup.State = METRICS_CLEANUP_STATE_FAILED
+        cleaner.cleanup.Error = err.Error()
+        cleaner.logger.Errorf("failed to delete the samples of %s older than %s: %s",
+            metricsConfig.QualifiedTable(), cutoff.Format(time.RFC3339), err.Error())
+        return
+    }
+    cleaner.cleanup.State = METRICS_CLEANUP_STATE_SUCCEEDED
+    cleaner.logger.Debugf("deleted the samples of %s older than %s of %d nodes",
+        metricsConfig.QualifiedTable(), cutoff.Format(time.RFC3339), nodesCleaned)
+}
+
+// Deletes the samples of each node taken before a time, and returns the number of nodes whose
+// samples were deleted
+func cleanMetricsTable(getSession func() (*gocql.Session, error), table string,
+    cutoff time.Time) (int32, error) {
+    session, err := getSession()
+    if err != nil {
+        return 0, err
+    }
+    nodes := []string{}
+    var node string
+    iter := session.Query(fmt.Sprintf(METRICS_NODES_QUERY, table)).Iter()
+    for iter.Scan(&node) {
+        nodes = append(nodes, node)
+    }
+    if err := iter.Close(); err != nil {
+        return 0, err
+    }
+    nodesCleaned := int32(0)
+    for _, node := range nodes {
+        seriesList, err := getOldMetricsSeries(session, table, node, cutoff)
+        if err != nil {
+            return nodesCleaned, err
+        }
+        for _, series := range seriesList {
+            err := session.Query(fmt.Sprintf(METRICS_CLEANUP_QUERY, table), node,
+                series.entityType, series.entityId, series.metric, cutoff.UnixMilli()).Exec()
+            if err != nil {
+                return nodesCleaned, err
+            }
+        }
+        nodesCleaned++
+    }
+    return nodesCleaned, nil
+}
+
+// Gets the series of a node that have samples taken before a time. The rows are read a page at
+// a time, and each series is kept once.
+func getOldMetricsSeries(session *gocql.Session, table string, node string,
+    cutoff time.Time) ([]metricsSeries, error) {
+    seen := map[metricsSeries]bool{}
+    seriesList := []metricsSeries{}
+    var series metricsSeries
+    iter := session.Query(fmt.Sprintf(METRICS_SERIES_QUERY, table), node,
+        cutoff.UnixMilli()).Iter()
+    for iter.Scan(&series.entityType, &series.entityId, &series.metric) {
+        if !seen[series] {
+            seen[series] = true
+            seriesList = append(seriesList, series)
+        }
+    }
+    if err := iter.Close(); err != nil {
+        return nil, err
+    }
+    return seriesList, nil
+}
+
+// Gets the status of the latest cleanup
+func (cleaner *metricsCleaner) get() models.MetricsCleanup {
+    cleaner.mutex.Lock()
+    defer cleaner.mutex.Unlock()
+    return cleaner.cleanup
+}
+
+// Gets the retention of the metrics, the status of the cleanup and an estimate of the space
+// taken by the metrics. The metrics table and the tables of the rolled up samples are sized as
+// reported by the master, and the stores of the fallback by the samples they can hold.
+func (c *Container) getMetricsRetention() (models.MetricsRetention, error) {
+    metricsConfig := helpers.GetConfig().Metrics
+    retention := models.MetricsRetention{
+        RetentionSeconds: int64(metricsConfig.Retention / time.Second),
+        CleanupIntervalSeconds: int64(metricsConfig.CleanupInterval / time.Second),
+        FallbackRetentionSeconds: int64(metricsConfig.FallbackRetention / time.Second),
+        Cleanup: c.metricsCleaner.get(),
+    }
+    tablesFuture := make(chan helpers.TablesFuture)
+    go helpers.GetTablesFuture(helpers.HOST, tablesFuture)
+    tablesResponse := <-tablesFuture
+    if tablesResponse.Error != nil {
+        return retention, tablesResponse.Error
+    }
+    for _, table := range tablesResponse.Tables {
+        if !table.IsYsql && table.Keyspace == metricsConfig.Keyspace &&
+            table.Name == metricsConfig.Table {
+            retention.TableSizeBytes += table.SizeBytes
+        }
+        for _, resolution := range METRICS_RESOLUTIONS {
+            if !table.IsYsql && table.Keyspace == metricsConfig.DownsampleKeyspace &&
+                table.Name == resolution.table {
+                retention.DownsampledSizeBytes += table.SizeBytes
+            }
+        }
+    }
+    if metricsConfig.Fallback {
+        retention.FallbackSizeBytes = c.fallbackMetrics.store.SizeBytes() +
+            c.fallbackMetrics.tableStore.SizeBytes() + c.fallbackMetrics.trafficStore.SizeBytes()
+    }
+    retention.EstimatedSizeBytes = retention.TableSizeBytes + retention.DownsampledSizeBytes +
+        retention.FallbackSizeBytes
+    return retention, nil
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "sync"
+    "time"
+)
+
+// Keeps the cores and memory of the tservers, which their node_exporter is asked for every
+// upstream.node_poll_interval with a timeout of upstream.node_exporter_timeout, so that the
+// requests that report them do not wait for slow or unreachable hosts. Hosts whose
+// node_exporter could not be reached in the latest poll are left out, as unknown.
+type nodeResourcesCache struct {
+    mutex sync.Mutex
+    resources map[string]nodeResources
+}
+
+func newNodeResourcesCache() *nodeResourcesCache {
+    return &nodeResourcesCache{resources: map[string]nodeResources{}}
+}
+
+func (cache *nodeResourcesCache) run() {
+    for {
+        cache.poll()
+        time.Sleep(helpers.GetConfig().Upstream.NodePollInterval)
+    }
+}
+
+func (cache *nodeResourcesCache) poll() {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServers := <-tabletServersFuture
+    if tabletServers.Error != nil {
+        return
+    }
+    upstreamConfig := helpers.GetConfig().Upstream
+    fanOut := newFanOutLimiter()
+    nodeExporterFutures := map[string]chan helpers.NodeExporterMetricsFuture{}
+    for _, host := range helpers.GetNodesList(tabletServers) {
+        host := host
+        nodeExporterFuture := make(chan helpers.NodeExporterMetricsFuture, 1)
+        nodeExporterFutures[host] = nodeExporterFuture
+        fanOut.goCall(func() {
+            helpers.GetNodeExporterMetricsWithTimeoutFuture(host,
+                int32(upstreamConfig.NodeExporterPort), upstreamConfig.NodeExporterTimeout,
+                nodeExporterFuture)
+        })
+    }
+    resources := map[string]nodeResources{}
+    for host, nodeExporterFuture := range nodeExporterFutures {
+        if nodeExporterMetrics := <-nodeExporterFuture; nodeExporterMetrics.Error == nil {
+            resources[host] = getNodeResources(nodeExporterMetrics.Metrics)
+        }
+    }
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+    cache.resources = resources
+}
+
+// Gets the resources of the hosts as of the latest poll, by host
+func (cache *nodeResourcesCache) get() map[string]nodeResources {
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+    resources := map[string]nodeResources{}
+    for host, hostResources := range cache.resources {
+        resources[host] = hostResources
+    }
+    return resources
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+)
+
+// The node_exporter metric whose labels describe the kernel and machine of a node
+const NODE_UNAME_INFO_METRIC = "node_uname_info"
+
+// Gets the version and build of a server process, or why they could not be read
+func getNodeServerVersion(process string,
+    versionInfo helpers.VersionInfoFuture) models.NodeServerVersion {
+    if versionInfo.Error != nil {
+        return models.NodeServerVersion{
+            Process: process,
+            Error: versionInfo.Error.Error(),
+        }
+    }
+    info := versionInfo.VersionInfo
+    version := info.VersionNumber
+    if info.BuildNumber != "" {
+        version += "-b" + info.BuildNumber
+    }
+    return models.NodeServerVersion{
+        Process: process,
+        Version: version,
+        VersionNumber: info.VersionNumber,
+        BuildNumber: info.BuildNumber,
+        GitHash: info.GitHash,
+        BuildTimestamp: info.BuildTimestamp,
+        BuildType: info.BuildType,
+        BuildId: info.BuildId,
+        BuildHostname: info.BuildHostname,
+        BuildUsername: info.BuildUsername,
+        BuildCleanRepo: info.BuildCleanRepo,
+    }
+}
+
+// Gets the machine architecture of a node from its node_exporter metrics, empty if they do not
+// have it
+func getNodeArchitecture(metrics helpers.NodeExporterMetricsFuture) string {
+    if metrics.Error != nil {
+        return ""
+    }
+    for _, sample := range metrics.Metrics[NODE_UNAME_INFO_METRIC] {
+        if machine := sample.Label("machine"); machine != "" {
+            return machine
+        }
+    }
+    return ""
+}
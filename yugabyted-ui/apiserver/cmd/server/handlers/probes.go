@@ -0,0 +1,241 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "context"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/jackc/pgx/v4"
+)
+
+// How often the prober checks whether probes were turned on, while they are off
+const PROBES_IDLE_INTERVAL = time.Minute
+
+// Writes a row of its own for each node to the probe table through the node, and reads it back
+// through the same node, so that a probe covers the query layer, the tablet leaders and the
+// replication of the write. The table is created by the first probe.
+type prober struct {
+    mutex sync.Mutex
+    // Whether the probe table was created, by database and table
+    created map[string]bool
+    local localstore.Store
+    logger logger.Logger
+}
+
+func newProber(log logger.Logger, local localstore.Store) *prober {
+    return &prober{
+        created: map[string]bool{},
+        local: local,
+        logger: log,
+    }
+}
+
+func (prober *prober) run() {
+    for {
+        probesConfig := helpers.GetConfig().Probes
+        if probesConfig.Interval <= 0 {
+            time.Sleep(PROBES_IDLE_INTERVAL)
+            continue
+        }
+        nodes, err := getNodes()
+        if err != nil {
+            prober.logger.Debugf("failed to list the nodes to probe: %s", err.Error())
+        } else {
+            prober.record(prober.probeNodes(nodes, probesConfig))
+        }
+        time.Sleep(probesConfig.Interval)
+    }
+}
+
+// Probes the nodes all at once, returning the results sorted by node
+func (prober *prober) probeNodes(nodes []string,
+    probesConfig helpers.ProbesConfig) []models.ProbeResult {
+    fanOut := newFanOutLimiter()
+    futures := make([]chan models.ProbeResult, len(nodes))
+    for index, node := range nodes {
+        node := node
+        future := make(chan models.ProbeResult, 1)
+        futures[index] = future
+        fanOut.goCall(func() { future <- prober.probe(node, probesConfig) })
+    }
+    results := []models.ProbeResult{}
+    for _, future := range futures {
+        results = append(results, <-future)
+    }
+    sort.Slice(results, func(i, j int) bool {
+        return results[i].Node < results[j].Node
+    })
+    return results
+}
+
+func (prober *prober) probe(node string, probesConfig helpers.ProbesConfig) models.ProbeResult {
+    result := models.ProbeResult{
+        Node: node,
+        Time: time.Now().Unix(),
+    }
+    probeCtx, cancel := context.WithTimeout(context.Background(), probesConfig.Timeout)
+    defer cancel()
+    start := time.Now()
+    err := prober.writeAndRead(probeCtx, node, probesConfig)
+    result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+    if err != nil {
+        message := err.Error()
+        result.Error = &message
+    } else {
+        result.Succeeded = true
+    }
+    return result
+}
+
+// Upserts the row of the node with a new value and reads it back, both through the node
+func (prober *prober) writeAndRead(probeCtx context.Context, node string,
+    probesConfig helpers.ProbesConfig) error {
+    conn, err := pgx.Connect(probeCtx,
+        helpers.GetYsqlConnectionUrlForHost(node, probesConfig.Database))
+    if err != nil {
+        return err
+    }
+    defer conn.Close(context.Background())
+    table := pgx.Identifier{probesConfig.Table}.Sanitize()
+    createdKey := probesConfig.Database + "." + probesConfig.Table
+    if !prober.isCreated(createdKey) {
+        _, err := conn.Exec(probeCtx, "CREATE TABLE IF NOT EXISTS "+table+
+            " (node text PRIMARY KEY, value bigint NOT NULL, probed_at timestamptz NOT NULL)")
+        if err != nil {
+            return fmt.Errorf("failed to create the probe table: %w", err)
+        }
+        prober.setCreated(createdKey)
+    }
+    value := time.Now().UnixNano()
+    _, err = conn.Exec(probeCtx, "INSERT INTO "+table+" (node, value, probed_at) "+
+        "VALUES ($1, $2, now()) ON CONFLICT (node) DO UPDATE "+
+        "SET value = EXCLUDED.value, probed_at = EXCLUDED.probed_at", node, value)
+    if err != nil {
+        return fmt.Errorf("failed to write the probe row: %w", err)
+    }
+    var readValue int64
+    err = conn.QueryRow(probeCtx, "SELECT value FROM "+table+" WHERE node = $1", node).
+        Scan(&readValue)
+    if err != nil {
+        return fmt.Errorf("failed to read the probe row: %w", err)
+    }
+    if readValue != value {
+        return fmt.Errorf("read back %d instead of the %d written", readValue, value)
+    }
+    return nil
+}
+
+// Probes of different nodes run at once, so the map is only used through these
+func (prober *prober) isCreated(key string) bool {
+    prober.mutex.Lock()
+    defer prober.mutex.Unlock()
+    return prober.created[key]
+}
+
+func (prober *prober) setCreated(key string) {
+    prober.mutex.Lock()
+    defer prober.mutex.Unlock()
+    prober.created[key] = true
+}
+
+// Keeps the results in the local store, dropping the oldest results beyond
+// probes.max_results. Keys start with the time in nanoseconds, so that they sort by time.
+func (prober *prober) record(results []models.ProbeResult) {
+    nanos := time.Now().UnixNano()
+    for index, result := range results {
+        if !result.Succeeded {
+            prober.logger.Infof("probe of node %s failed: %s", result.Node, *result.Error)
+        }
+        data, err := json.Marshal(result)
+        if err == nil {
+            err = prober.local.Put(STORE_BUCKET_PROBE_RESULTS,
+                fmt.Sprintf("%019d-%04d", nanos, index), data)
+        }
+        if err != nil {
+            prober.logger.Errorf("failed to keep the probe results: %s", err.Error())
+            return
+        }
+    }
+    err := prober.local.Trim(STORE_BUCKET_PROBE_RESULTS, helpers.GetConfig().Probes.MaxResults)
+    if err != nil {
+        prober.logger.Errorf("failed to trim the probe results: %s", err.Error())
+    }
+}
+
+// Gets the results between two times, of one node if it is set, oldest first
+func (prober *prober) list(from int64, to int64, node string) ([]models.ProbeResult, error) {
+    entries, err := prober.local.List(STORE_BUCKET_PROBE_RESULTS)
+    if err != nil {
+        return nil, err
+    }
+    results := []models.ProbeResult{}
+    for _, entry := range entries {
+        result := models.ProbeResult{}
+        if err := json.Unmarshal(entry.Value, &result); err != nil {
+            prober.logger.Errorf("failed to read probe result %s: %s", entry.Key, err.Error())
+            continue
+        }
+        if result.Time < from || result.Time > to || (node != "" && result.Node != node) {
+            continue
+        }
+        results = append(results, result)
+    }
+    return results, nil
+}
+
+// Sums up the results of each node, sorted by node, and of all the nodes together
+func getProbeSummaries(results []models.ProbeResult) ([]models.ProbeSummary,
+    models.ProbeSummary) {
+    resultsByNode := map[string][]models.ProbeResult{}
+    for _, result := range results {
+        resultsByNode[result.Node] = append(resultsByNode[result.Node], result)
+    }
+    summaries := []models.ProbeSummary{}
+    for node, nodeResults := range resultsByNode {
+        summary := getProbeSummary(nodeResults)
+        summary.Node = node
+        summaries = append(summaries, summary)
+    }
+    sort.Slice(summaries, func(i, j int) bool {
+        return summaries[i].Node < summaries[j].Node
+    })
+    return summaries, getProbeSummary(results)
+}
+
+// Sums up results, which must be sorted by time. Latencies are of the successful probes.
+func getProbeSummary(results []models.ProbeResult) models.ProbeSummary {
+    summary := models.ProbeSummary{Probes: int64(len(results))}
+    latencies := []float64{}
+    for _, result := range results {
+        if !result.Succeeded {
+            summary.LastError = result.Error
+            continue
+        }
+        summary.Succeeded++
+        latencies = append(latencies, result.LatencyMs)
+    }
+    if summary.Probes > 0 {
+        summary.SuccessRate = float64(summary.Succeeded) / float64(summary.Probes)
+        lastProbedAt := results[len(results)-1].Time
+        summary.LastProbedAt = &lastProbedAt
+    }
+    if len(latencies) > 0 {
+        sort.Float64s(latencies)
+        total := 0.0
+        for _, latency := range latencies {
+            total += latency
+        }
+        average := total / float64(len(latencies))
+        p99 := latencies[(len(latencies)*99+99)/100-1]
+        summary.AvgLatencyMs = &average
+        summary.P99LatencyMs = &p99
+    }
+    return summary
+}
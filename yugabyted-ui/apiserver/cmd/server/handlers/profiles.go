@@ -0,0 +1,53 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+)
+
+// Profiles are kept in the format the node served them, which go tool pprof reads
+const PROFILE_EXTENSION = ".prof"
+
+// Length of a CPU profile when none is given
+const PROFILE_DEFAULT_SECONDS = 30
+
+// Keeps track of the profiles collected from the nodes, of which only the latest are kept along
+// with their flame graphs
+type profileStore struct {
+    files *retainedFileStore
+}
+
+func newProfileStore(log logger.Logger) *profileStore {
+    return &profileStore{
+        files: newRetainedFileStore(log, "profile", helpers.GetConfig().Profiles.Directory,
+            PROFILE_EXTENSION, FOLDED_STACKS_EXTENSION),
+    }
+}
+
+func (store *profileStore) getPath(id string) (string, error) {
+    return getRetainedFilePath(helpers.GetConfig().Profiles.Directory, id, PROFILE_EXTENSION)
+}
+
+// Records a profile that was written, deleting the oldest profiles beyond the configured number
+func (store *profileStore) add(info models.Profile, path string) {
+    store.files.add(info.Id, info, info.Timestamp, []string{path, getFoldedStacksPath(path)},
+        helpers.GetConfig().Profiles.MaxProfiles)
+}
+
+// Gets the profiles, newest first
+func (store *profileStore) list() []models.Profile {
+    profiles := []models.Profile{}
+    for _, info := range store.files.list() {
+        profiles = append(profiles, info.(models.Profile))
+    }
+    return profiles
+}
+
+func (store *profileStore) get(id string) (models.Profile, string, bool) {
+    info, path, ok := store.files.get(id)
+    if !ok {
+        return models.Profile{}, "", false
+    }
+    return info.(models.Profile), path, true
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/tsdb"
+    "bytes"
+    "encoding/binary"
+    "fmt"
+    "math"
+    "net/http"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/golang/snappy"
+)
+
+// How often the writer checks whether the push was turned on, while it is off
+const REMOTE_WRITE_IDLE_INTERVAL = time.Minute
+
+// The version of the remote_write protocol, sent in the header it is expected in
+const REMOTE_WRITE_VERSION = "0.1.0"
+
+// Name of the series of the bytes per second replicated between zones, which are labeled with
+// the kind of traffic and the pair of zones
+const REMOTE_WRITE_TRAFFIC_METRIC = "replication_traffic_bytes_per_sec"
+
+// Characters that Prometheus does not take in the names of metrics
+var REMOTE_WRITE_INVALID_NAME_REGEX = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+type remoteWriteLabel struct {
+    name string
+    value string
+}
+
+// A series of the fallback store, with the samples that were not pushed yet
+type remoteWriteSeries struct {
+    // The labels of the series joined together, which identifies it between pushes
+    key string
+    labels []remoteWriteLabel
+    samples []tsdb.Sample
+}
+
+// Returned by postRemoteWrite when the endpoint answers with a status other than 2xx
+type remoteWriteStatusError struct {
+    statusCode int
+    status string
+}
+
+func (err remoteWriteStatusError) Error() string {
+    return "remote_write endpoint returned " + err.status
+}
+
+// Whether the request may succeed when sent again. The endpoint refuses requests it cannot take
+// with a 4xx, except 429 when it is overloaded, and those are refused again if sent again.
+func isRemoteWriteRetryable(err error) bool {
+    if statusErr, ok := err.(remoteWriteStatusError); ok {
+        return statusErr.statusCode >= 500 ||
+            statusErr.statusCode == http.StatusTooManyRequests
+    }
+    return true
+}
+
+// Pushes the series that the metrics fallback scrapes from the tservers to the Prometheus
+// remote_write endpoint of remote_write.url at every remote_write.interval. Only the samples
+// scraped since the last push are sent. The samples of a request that the endpoint refuses
+// with a 4xx are dropped. Those of a request that fails otherwise are sent again with the next
+// push, while the store still has them, and the pushes back off until one succeeds.
+type remoteWriter struct {
+    logger logger.Logger
+    fallback *fallbackMetrics
+    // The timestamp of the newest sample pushed of each series, by key of the series
+    pushedUntil map[string]int64
+    // How long to wait before the next push after failures in a row, 0 after a success
+    backoff time.Duration
+}
+
+func newRemoteWriter(log logger.Logger, fallback *fallbackMetrics) *remoteWriter {
+    return &remoteWriter{
+        logger: log,
+        fallback: fallback,
+        pushedUntil: map[string]int64{},
+    }
+}
+
+func (writer *remoteWriter) run() {
+    for {
+        config := helpers.GetConfig()
+        if config.RemoteWrite.Url == "" {
+            time.Sleep(REMOTE_WRITE_IDLE_INTERVAL)
+            continue
+        }
+        delay := config.RemoteWrite.Interval
+        if config.Metrics.Fallback {
+            if err := writer.push(config.RemoteWrite); err != nil {
+                writer.backoff = getRemoteWriteBackoff(writer.backoff, config.RemoteWrite)
+                delay = writer.backoff
+                writer.logger.Errorf("failed to push metrics to remote_write %s, pushing "+
+                    "again in %s: %s", config.RemoteWrite.Url, delay, err.Error())
+            } else {
+                writer.backoff = 0
+            }
+        } else {
+            writer.logger.Debugf("not pushing metrics to remote_write, metrics.fallback is off")
+        }
+        time.Sleep(delay)
+    }
+}
+
+// Gets the wait after another failure, twice the previous one, starting at the interval and
+// up to the max backoff
+func getRemoteWriteBackoff(previous time.Duration,
+    config helpers.RemoteWriteConfig) time.Duration {
+    backoff := 2 * previous
+    if backoff < config.Interval {
+        backoff = config.Interval
+    }
+    if backoff > config.MaxBackoff {
+        backoff = config.MaxBackoff
+    }
+    return backoff
+}
+
+// Pushes the samples that were not pushed yet, in requests of up to max_samples_per_request
+// samples. A series is only split over requests when it has more samples than that. Stops at
+// the first request that may succeed when sent again, and returns its error.
+func (writer *remoteWriter) push(config helpers.RemoteWriteConfig) error {
+    series := writer.getSeries(config)
+    for start := 0; start < len(series); {
+        end, samples := start, 0
+        for end < len(series) &&
+            (end == start || samples+len(series[end].samples) <= config.MaxSamplesPerRequest) {
+            samples += len(series[end].samples)
+            end++
+        }
+        batch := series[start:end]
+        if err := postRemoteWrite(config, encodeRemoteWriteRequest(batch)); err != nil {
+            if isRemoteWriteRetryable(err) {
+                return err
+            }
+            writer.logger.Errorf("dropped %d samples that remote_write %s refused: %s",
+                samples, config.Url, err.Error())
+        }
+        for _, pushed := range batch {
+            writer.pushedUntil[pushed.key] = pushed.samples[len(pushed.samples)-1].Timestamp
+        }
+        start = end
+    }
+    return nil
+}
+
+// Gets the series of the fallback stores with the samples that were not pushed yet, sorted by
+// their key. Series that are no longer in the stores are forgotten.
+func (writer *remoteWriter) getSeries(config helpers.RemoteWriteConfig) []remoteWriteSeries {
+    uuidToHost := map[string]string{}
+    if hostToUuid, err := writer.fallback.hostToUuid.get(); err == nil {
+        for host, uuid := range hostToUuid {
+            uuidToHost[uuid] = host
+        }
+    }
+    endTime := time.Now().UnixMilli() + 1
+    series := []remoteWriteSeries{}
+    seen := map[string]bool{}
+    addSeries := func(store *tsdb.Store, metric string, node string,
+        labels []remoteWriteLabel) {
+        for name, value := range config.ExternalLabels {
+            labels = append(labels, remoteWriteLabel{name, value})
+        }
+        key, labels := getRemoteWriteSeriesKey(labels)
+        seen[key] = true
+        samples := store.Query(metric, node, writer.pushedUntil[key]+1, endTime)
+        if len(samples) > 0 {
+            series = append(series, remoteWriteSeries{key, labels, samples})
+        }
+    }
+    store := writer.fallback.store
+    for _, metric := range store.Metrics() {
+        for _, uuid := range store.Nodes(metric) {
+            labels := []remoteWriteLabel{
+                {"__name__", metric},
+                {"node_uuid", uuid},
+            }
+            if host, ok := uuidToHost[uuid]; ok {
+                labels = append(labels, remoteWriteLabel{"instance", host})
+            }
+            addSeries(store, metric, uuid, labels)
+        }
+    }
+    tableStore := writer.fallback.tableStore
+    for _, metric := range tableStore.Metrics() {
+        for _, tableId := range tableStore.Nodes(metric) {
+            addSeries(tableStore, metric, tableId, []remoteWriteLabel{
+                {"__name__", metric},
+                {"table_id", tableId},
+            })
+        }
+    }
+    trafficStore := writer.fallback.trafficStore
+    for _, kind := range trafficStore.Metrics() {
+        for _, trafficSeries := range trafficStore.Nodes(kind) {
+            source, target, ok := parseTrafficSeries(trafficSeries)
+            if !ok {
+                continue
+            }
+            addSeries(trafficStore, kind, trafficSeries, []remoteWriteLabel{
+                {"__name__", REMOTE_WRITE_TRAFFIC_METRIC},
+                {"kind", kind},
+                {"source_cloud", source.cloud},
+                {"source_region", source.region},
+                {"source_zone", source.zone},
+                {"target_cloud", target.cloud},
+                {"target_region", target.region},
+                {"target_zone", target.zone},
+            })
+        }
+    }
+    for key := range writer.pushedUntil {
+        if !seen[key] {
+            delete(writer.pushedUntil, key)
+        }
+    }
+    sort.Slice(series, func(i, j int) bool {
+        return series[i].key < series[j].key
+    })
+    return series
+}
+
+// Sorts the labels by name as the protocol requires, dropping the empty ones and the external
+// labels that the series already has, and joins them into the key of the series
+func getRemoteWriteSeriesKey(labels []remoteWriteLabel) (string, []remoteWriteLabel) {
+    sorted := []remoteWriteLabel{}
+    seen := map[string]bool{}
+    for _, label := range labels {
+        name := REMOTE_WRITE_INVALID_NAME_REGEX.ReplaceAllString(label.name, "_")
+        if label.value == "" || seen[name] {
+            continue
+        }
+        seen[name] = true
+        value := label.value
+        if name == "__name__" {
+            value = REMOTE_WRITE_INVALID_NAME_REGEX.ReplaceAllString(value, "_")
+        }
+        sorted = append(sorted, remoteWriteLabel{name, value})
+    }
+    sort.Slice(sorted, func(i, j int) bool {
+        return sorted[i].name < sorted[j].name
+    })
+    parts := []string{}
+    for _, label := range sorted {
+        parts = append(parts, fmt.Sprintf("%s=%q", label.name, label.value))
+    }
+    return strings.Join(parts, ","), sorted
+}
+
+func appendProtobufVarint(buffer []byte, value uint64) []byte {
+    for value >= 0x80 {
+        buffer = append(buffer, byte(value)|0x80)
+        value >>= 7
+    }
+    return append(buffer, byte(value))
+}
+
+// Appends a length-delimited field, as strings and embedded messages are encoded
+func appendProtobufBytes(buffer []byte, field uint64, value []byte) []byte {
+    buffer = appendProtobufVarint(buffer, field<<3|2)
+    buffer = appendProtobufVarint(buffer, uint64(len(value)))
+    return append(buffer, value...)
+}
+
+// Encodes the series as the WriteRequest protobuf message of the remote_write protocol, which
+// is small enough to not need the generated code of Prometheus:
+//
+//  WriteRequest { repeated TimeSeries timeseries = 1; }
+//  TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//  Label { string name = 1; string value = 2; }
+//  Sample { double value = 1; int64 timestamp = 2; }
+func encodeRemoteWriteRequest(series []remoteWriteSeries) []byte {
+    request := []byte{}
+    for _, oneSeries := range series {
+        timeSeries := []byte{}
+        for _, label := range oneSeries.labels {
+            encodedLabel := appendProtobufBytes(nil, 1, []byte(label.name))
+            encodedLabel = appendProtobufBytes(encodedLabel, 2, []byte(label.value))
+            timeSeries = appendProtobufBytes(timeSeries, 1, encodedLabel)
+        }
+        for _, sample := range oneSeries.samples {
+            // The value is a fixed 64 bits field, the timestamp a varint field
+            value := make([]byte, 8)
+            binary.LittleEndian.PutUint64(value, math.Float64bits(sample.Value))
+            encodedSample := append(appendProtobufVarint(nil, 1<<3|1), value...)
+            encodedSample = appendProtobufVarint(encodedSample, 2<<3)
+            encodedSample = appendProtobufVarint(encodedSample, uint64(sample.Timestamp))
+            timeSeries = appendProtobufBytes(timeSeries, 2, encodedSample)
+        }
+        request = appendProtobufBytes(request, 1, timeSeries)
+    }
+    return request
+}
+
+// Posts an encoded WriteRequest to the endpoint, compressed with snappy as the protocol
+// requires. Responses other than 2xx are remoteWriteStatusErrors.
+func postRemoteWrite(config helpers.RemoteWriteConfig, body []byte) error {
+    request, err := http.NewRequest(http.MethodPost, config.Url,
+        bytes.NewReader(snappy.Encode(nil, body)))
+    if err != nil {
+        return err
+    }
+    request.Header.Set("Content-Type", "application/x-protobuf")
+    request.Header.Set("Content-Encoding", "snappy")
+    request.Header.Set("X-Prometheus-Remote-Write-Version", REMOTE_WRITE_VERSION)
+    if config.Username != "" {
+        request.SetBasicAuth(config.Username, config.Password)
+    } else if config.BearerToken != "" {
+        request.Header.Set("Authorization", "Bearer "+config.BearerToken)
+    }
+    response, err := helpers.NewHttpClientWithTimeout(config.Timeout).Do(request)
+    if err != nil {
+        return err
+    }
+    defer response.Body.Close()
+    if response.StatusCode < 200 || response.StatusCode >= 300 {
+        return remoteWriteStatusError{statusCode: response.StatusCode, status: response.Status}
+    }
+    return nil
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "apiserver/cmd/server/tsdb"
+    "sort"
+    "strings"
+)
+
+// The kinds of replication traffic, under which their rates are kept in the traffic store of
+// the fallback
+const RAFT_TRAFFIC = "raft"
+const XCLUSTER_TRAFFIC = "xcluster"
+
+// Bytes appended to the log of each tablet. Every replica logs what the leader sends it, so
+// the bytes the leader logs are the bytes it sends each of the other replicas.
+const RAFT_BYTES_COUNTER = "log_bytes_logged"
+
+// Bytes of the changes sent to the consumers of the xCluster streams of each tablet
+const XCLUSTER_BYTES_COUNTER = "rpc_payload_bytes_responded"
+
+const TRAFFIC_LEVEL_REGION = "region"
+const TRAFFIC_LEVEL_ZONE = "zone"
+
+// Separates the locations in the name of a traffic series, which cloud, region and zone names
+// do not contain
+const TRAFFIC_SERIES_SEPARATOR = "\x1f"
+
+// Zones are located by cloud and region too, as their names need not be unique
+type nodeLocation struct {
+    cloud string
+    region string
+    zone string
+}
+
+// Names the series of the traffic from one location to another. xCluster traffic leaves the
+// cluster, so its target is the empty location.
+func getTrafficSeries(source nodeLocation, target nodeLocation) string {
+    return strings.Join([]string{source.cloud, source.region, source.zone, target.cloud,
+        target.region, target.zone}, TRAFFIC_SERIES_SEPARATOR)
+}
+
+func parseTrafficSeries(series string) (nodeLocation, nodeLocation, bool) {
+    parts := strings.Split(series, TRAFFIC_SERIES_SEPARATOR)
+    if len(parts) != 6 {
+        return nodeLocation{}, nodeLocation{}, false
+    }
+    return nodeLocation{parts[0], parts[1], parts[2]},
+        nodeLocation{parts[3], parts[4], parts[5]}, true
+}
+
+// The bytes per second a node logged for each of its tablets and sent to xCluster consumers
+// since the previous scrape
+type nodeReplicationRates struct {
+    tablets map[string]float64
+    xcluster float64
+    hasXcluster bool
+}
+
+// Computes the replication rates of a node from its tablet and stream counters
+func (fallback *fallbackMetrics) getReplicationRates(uuid string, timestamp int64,
+    metrics map[string][]helpers.NodeExporterSample) nodeReplicationRates {
+    rates := nodeReplicationRates{tablets: map[string]float64{}}
+    currentBytes := map[string]counterSample{}
+    for _, sample := range metrics[RAFT_BYTES_COUNTER] {
+        // Tablet metrics that the tserver aggregates by table cannot be told apart
+        if sample.Label("metric_type") != "tablet" || sample.Label("metric_id") == "" {
+            continue
+        }
+        currentBytes[sample.Label("metric_id")] = counterSample{timestamp, sample.Value}
+    }
+    previousBytes := fallback.previousLogBytes[uuid]
+    fallback.previousLogBytes[uuid] = currentBytes
+    for tabletId, current := range currentBytes {
+        previous, ok := previousBytes[tabletId]
+        // A restarted tserver, or a tablet that moved away and back, starts from zero again
+        if !ok || current.timestamp <= previous.timestamp || current.value < previous.value {
+            continue
+        }
+        rates.tablets[tabletId] = (current.value - previous.value) * 1000 /
+            float64(current.timestamp-previous.timestamp)
+    }
+    xclusterSamples, ok := metrics[XCLUSTER_BYTES_COUNTER]
+    if !ok {
+        delete(fallback.previousXclusterBytes, uuid)
+        return rates
+    }
+    current := counterSample{timestamp: timestamp}
+    for _, sample := range xclusterSamples {
+        current.value += sample.Value
+    }
+    previous, ok := fallback.previousXclusterBytes[uuid]
+    fallback.previousXclusterBytes[uuid] = current
+    if ok && current.timestamp > previous.timestamp && current.value >= previous.value {
+        rates.xcluster = (current.value - previous.value) * 1000 /
+            float64(current.timestamp-previous.timestamp)
+        rates.hasXcluster = true
+    }
+    return rates
+}
+
+// Stores the bytes per second replicated between each pair of zones. The bytes each leader
+// logs for a tablet are sent to every replica of the tablet in another zone, and the xCluster
+// bytes of a node leave the cluster from its zone. Pairs that are gone are dropped if every
+// tserver was scraped.
+func (fallback *fallbackMetrics) appendReplicationTraffic(timestamp int64,
+    tablets []helpers.DumpEntitiesTablet, locations map[string]nodeLocation,
+    rates map[string]nodeReplicationRates, scrapedAll bool) {
+    traffic := map[string]map[string]float64{
+        RAFT_TRAFFIC: {},
+        XCLUSTER_TRAFFIC: {},
+    }
+    for _, tablet := range tablets {
+        leaderLocation, hasLocation := locations[tablet.Leader]
+        leaderRates, scraped := rates[tablet.Leader]
+        if !hasLocation || !scraped {
+            continue
+        }
+        for _, replica := range tablet.Replicas {
+            replicaLocation, ok := locations[replica.ServerUuid]
+            if !ok || replica.ServerUuid == tablet.Leader || replicaLocation == leaderLocation {
+                continue
+            }
+            // Pairs of zones are kept while they have replicas, with or without traffic, as
+            // the rates of idle tablets are 0
+            traffic[RAFT_TRAFFIC][getTrafficSeries(leaderLocation, replicaLocation)] +=
+                leaderRates.tablets[tablet.TabletId]
+        }
+    }
+    for uuid, nodeRates := range rates {
+        location, ok := locations[uuid]
+        if !ok || !nodeRates.hasXcluster {
+            continue
+        }
+        traffic[XCLUSTER_TRAFFIC][getTrafficSeries(location, nodeLocation{})] +=
+            nodeRates.xcluster
+    }
+    for kind, kindTraffic := range traffic {
+        for series, rate := range kindTraffic {
+            fallback.trafficStore.Append(kind, series, tsdb.Sample{
+                Timestamp: timestamp,
+                Value: rate,
+            })
+        }
+    }
+    if scrapedAll {
+        pairs := map[string]bool{}
+        for _, kindTraffic := range traffic {
+            for series := range kindTraffic {
+                pairs[series] = true
+            }
+        }
+        fallback.trafficStore.Retain(pairs)
+    }
+}
+
+// Gets the replication traffic of a kind between each pair of regions or zones with
+// startTime <= timestamp < endTime
+func (fallback *fallbackMetrics) getReplicationTraffic(kind string, level string,
+    startTime int64, endTime int64, scrapeIntervalSeconds float64) []models.ReplicationTraffic {
+    type pairValues struct {
+        traffic models.ReplicationTraffic
+        bytes float64
+        seriesValues [][][]float64
+    }
+    pairs := map[string]*pairValues{}
+    for _, series := range fallback.trafficStore.Nodes(kind) {
+        source, target, ok := parseTrafficSeries(series)
+        if !ok {
+            continue
+        }
+        if level == TRAFFIC_LEVEL_REGION {
+            source.zone, target.zone = "", ""
+            // Traffic between the zones of one region does not cross regions
+            if kind == RAFT_TRAFFIC && source == target {
+                continue
+            }
+        }
+        key := getTrafficSeries(source, target)
+        pair, ok := pairs[key]
+        if !ok {
+            pair = &pairValues{traffic: models.ReplicationTraffic{
+                Kind: kind,
+                SourceCloud: source.cloud,
+                SourceRegion: source.region,
+                SourceZone: source.zone,
+                TargetCloud: target.cloud,
+                TargetRegion: target.region,
+                TargetZone: target.zone,
+            }}
+            pairs[key] = pair
+        }
+        values := [][]float64{}
+        for _, sample := range fallback.trafficStore.Query(kind, series, startTime*1000,
+            endTime*1000) {
+            values = append(values, []float64{float64(sample.Timestamp) / 1000, sample.Value})
+            // Each sample is the rate over the scrape interval before it
+            pair.bytes += sample.Value * scrapeIntervalSeconds
+        }
+        if latest, ok := fallback.trafficStore.Latest(kind, series); ok {
+            pair.traffic.BytesPerSec += latest.Value
+        }
+        pair.seriesValues = append(pair.seriesValues, values)
+    }
+    traffic := []models.ReplicationTraffic{}
+    for _, pair := range pairs {
+        seriesValues := reduceGranularityForAllNodes(startTime, endTime, pair.seriesValues,
+            GRANULARITY_NUM_INTERVALS, true)
+        pair.traffic.Values = calculateCombinedMetric(seriesValues, false)
+        pair.traffic.Bytes = int64(pair.bytes)
+        traffic = append(traffic, pair.traffic)
+    }
+    // The busiest pairs first
+    sort.Slice(traffic, func(i, j int) bool {
+        if traffic[i].Bytes != traffic[j].Bytes {
+            return traffic[i].Bytes > traffic[j].Bytes
+        }
+        return getReplicationTrafficSeries(traffic[i]) < getReplicationTrafficSeries(traffic[j])
+    })
+    return traffic
+}
+
+func getReplicationTrafficSeries(traffic models.ReplicationTraffic) string {
+    return getTrafficSeries(
+        nodeLocation{traffic.SourceCloud, traffic.SourceRegion, traffic.SourceZone},
+        nodeLocation{traffic.TargetCloud, traffic.TargetRegion, traffic.TargetZone})
+}
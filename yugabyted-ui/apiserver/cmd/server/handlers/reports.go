@@ -0,0 +1,413 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// How often the scheduler checks whether a report period has ended
+const REPORT_CHECK_INTERVAL = time.Minute
+
+// Name of the Alertmanager alert that a report was produced, which is sent with the info
+// severity so that it can be routed apart from the alerts of the rules
+const REPORT_ALERTNAME = "yugabyted_ui_report"
+
+// Produces a report of a kind for the period between two times
+type reportGenerator func(kind models.ReportKindEnum, start time.Time, end time.Time) models.Report
+
+// Produces the summary reports at the end of each of their periods, keeps the latest ones in
+// the local store and posts them to the webhooks and the Alertmanagers. Periods end at
+// multiples of the interval since the Unix epoch, so that they do not depend on when the server
+// was started. The latest period that ended is reported on the first check of a kind that was
+// never reported, and after a restart if it ended while the server was down.
+type reportScheduler struct {
+    mutex sync.Mutex
+    // Oldest first
+    reports []models.Report
+    // End of the last period reported of each kind
+    lastPeriodEnds map[models.ReportKindEnum]time.Time
+    local localstore.Store
+    events *clusterEventDetector
+    alerts *alertEvaluator
+    logger logger.Logger
+}
+
+func newReportScheduler(log logger.Logger, local localstore.Store, events *clusterEventDetector,
+    alerts *alertEvaluator) *reportScheduler {
+    scheduler := &reportScheduler{
+        reports: []models.Report{},
+        lastPeriodEnds: map[models.ReportKindEnum]time.Time{},
+        local: local,
+        events: events,
+        alerts: alerts,
+        logger: log,
+    }
+    scheduler.load()
+    return scheduler
+}
+
+// Reads the reports and the end of the last period reported of each kind from the local store
+func (scheduler *reportScheduler) load() {
+    reports := []models.Report{}
+    entries, err := scheduler.local.List(STORE_BUCKET_REPORTS)
+    if err != nil {
+        scheduler.logger.Errorf("failed to read the reports: %s", err.Error())
+    }
+    for _, entry := range entries {
+        report := models.Report{}
+        if err := json.Unmarshal(entry.Value, &report); err != nil {
+            scheduler.logger.Errorf("failed to read report %s: %s", entry.Key, err.Error())
+            continue
+        }
+        reports = append(reports, report)
+    }
+    lastPeriodEnds := map[models.ReportKindEnum]time.Time{}
+    entries, err = scheduler.local.List(STORE_BUCKET_REPORT_PERIODS)
+    if err != nil {
+        scheduler.logger.Errorf("failed to read the report periods: %s", err.Error())
+    }
+    for _, entry := range entries {
+        periodEnd, err := strconv.ParseInt(string(entry.Value), 10, 64)
+        if err != nil {
+            scheduler.logger.Errorf("failed to read the report period of %s: %s", entry.Key,
+                err.Error())
+            continue
+        }
+        lastPeriodEnds[models.ReportKindEnum(entry.Key)] = time.Unix(periodEnd, 0)
+    }
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    scheduler.reports = reports
+    scheduler.lastPeriodEnds = lastPeriodEnds
+}
+
+func getReportIntervals(
+    reportsConfig helpers.ReportsConfig,
+) map[models.ReportKindEnum]time.Duration {
+    return map[models.ReportKindEnum]time.Duration{
+        models.REPORTKINDENUM_HEALTH: reportsConfig.HealthInterval,
+        models.REPORTKINDENUM_TOP_QUERIES: reportsConfig.TopQueriesInterval,
+        models.REPORTKINDENUM_CAPACITY_TREND: reportsConfig.CapacityTrendInterval,
+    }
+}
+
+// Gets the end of the latest period of an interval that ended at or before a time
+func getReportPeriodEnd(now time.Time, interval time.Duration) time.Time {
+    seconds := int64(interval / time.Second)
+    return time.Unix(now.Unix()-now.Unix()%seconds, 0)
+}
+
+func (scheduler *reportScheduler) run(generate reportGenerator) {
+    for {
+        scheduler.check(time.Now(), generate)
+        time.Sleep(REPORT_CHECK_INTERVAL)
+    }
+}
+
+// Produces the reports whose period ended since the last one reported
+func (scheduler *reportScheduler) check(now time.Time, generate reportGenerator) {
+    reportsConfig := helpers.GetConfig().Reports
+    for kind, interval := range getReportIntervals(reportsConfig) {
+        if interval <= 0 {
+            continue
+        }
+        periodEnd := getReportPeriodEnd(now, interval)
+        scheduler.mutex.Lock()
+        lastPeriodEnd, ok := scheduler.lastPeriodEnds[kind]
+        scheduler.mutex.Unlock()
+        if ok && !periodEnd.After(lastPeriodEnd) {
+            continue
+        }
+        report := generate(kind, periodEnd.Add(-interval), periodEnd)
+        if report.Error != "" {
+            scheduler.logger.Errorf("failed to produce report %s: %s", report.Id, report.Error)
+        } else {
+            scheduler.logger.Infof("produced report %s", report.Id)
+        }
+        scheduler.add(report, periodEnd, reportsConfig.MaxReports)
+        scheduler.notify(report, reportsConfig)
+    }
+}
+
+// Keeps a report, dropping the oldest reports beyond the limit, and records that its period
+// was reported
+func (scheduler *reportScheduler) add(report models.Report, periodEnd time.Time,
+    maxReports int) {
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    scheduler.lastPeriodEnds[report.Kind] = periodEnd
+    scheduler.reports = append(scheduler.reports, report)
+    if len(scheduler.reports) > maxReports {
+        scheduler.reports = append([]models.Report{},
+            scheduler.reports[len(scheduler.reports)-maxReports:]...)
+    }
+    // Keys start with the time the report was produced, so that they sort like the reports
+    data, err := json.Marshal(report)
+    if err == nil {
+        err = scheduler.local.Put(STORE_BUCKET_REPORTS,
+            fmt.Sprintf("%019d-%s", report.GeneratedTimestamp, report.Id), data)
+    }
+    if err == nil {
+        err = scheduler.local.Trim(STORE_BUCKET_REPORTS, maxReports)
+    }
+    if err == nil {
+        err = scheduler.local.Put(STORE_BUCKET_REPORT_PERIODS, string(report.Kind),
+            []byte(strconv.FormatInt(periodEnd.Unix(), 10)))
+    }
+    if err != nil {
+        scheduler.logger.Errorf("failed to keep report %s: %s", report.Id, err.Error())
+    }
+}
+
+// Records that a report was produced as a cluster event, which posts it to the webhooks
+// subscribed to report_generated, and sends it to the Alertmanagers if reports.alertmanager is
+// on
+func (scheduler *reportScheduler) notify(report models.Report,
+    reportsConfig helpers.ReportsConfig) {
+    now := time.Now()
+    message := fmt.Sprintf("report %s was produced", report.Id)
+    if report.Error != "" {
+        message = fmt.Sprintf("report %s failed: %s", report.Id, report.Error)
+    }
+    details := map[string]string{
+        "report_id": report.Id,
+        "kind": string(report.Kind),
+        "start": strconv.FormatInt(report.StartTimestamp, 10),
+        "end": strconv.FormatInt(report.EndTimestamp, 10),
+    }
+    if report.Error != "" {
+        details["error"] = report.Error
+    }
+    scheduler.events.record([]models.ClusterEvent{
+        newClusterEvent(CLUSTER_EVENT_REPORT_GENERATED, "", now.Unix(), message, details),
+    })
+    if !reportsConfig.Alertmanager {
+        return
+    }
+    labels := map[string]string{}
+    for name, value := range helpers.GetConfig().Alerts.Alertmanager.ExternalLabels {
+        labels[name] = value
+    }
+    labels["alertname"] = REPORT_ALERTNAME
+    labels["severity"] = "info"
+    labels["report_id"] = report.Id
+    labels["kind"] = string(report.Kind)
+    scheduler.alerts.sendToAlertmanagers([]alertmanagerAlert{{
+        Labels: labels,
+        Annotations: map[string]string{"summary": message},
+        StartsAt: now.UTC().Format(time.RFC3339),
+        EndsAt: now.Add(ALERTMANAGER_VALIDITY_INTERVALS *
+            helpers.GetConfig().Alerts.EvaluationInterval).UTC().Format(time.RFC3339),
+    }})
+}
+
+// Gets the reports, newest first, of one kind or of all kinds if the kind is empty
+func (scheduler *reportScheduler) list(kind models.ReportKindEnum) []models.Report {
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    reports := []models.Report{}
+    for index := len(scheduler.reports) - 1; index >= 0; index-- {
+        if kind == "" || scheduler.reports[index].Kind == kind {
+            reports = append(reports, scheduler.reports[index])
+        }
+    }
+    return reports
+}
+
+func (scheduler *reportScheduler) get(id string) (models.Report, bool) {
+    scheduler.mutex.Lock()
+    defer scheduler.mutex.Unlock()
+    for _, report := range scheduler.reports {
+        if report.Id == id {
+            return report, true
+        }
+    }
+    return models.Report{}, false
+}
+
+// Produces a report, recording in it why it could not be produced if it failed
+func (c *Container) generateReport(kind models.ReportKindEnum, start time.Time,
+    end time.Time) models.Report {
+    report := models.Report{
+        Id: fmt.Sprintf("%s-%d", strings.ToLower(string(kind)), end.Unix()),
+        Kind: kind,
+        StartTimestamp: start.Unix(),
+        EndTimestamp: end.Unix(),
+    }
+    var err error
+    switch kind {
+    case models.REPORTKINDENUM_HEALTH:
+        var health models.ReportHealth
+        health, err = c.getHealthReport(start, end)
+        report.Health = &health
+    case models.REPORTKINDENUM_TOP_QUERIES:
+        var topQueries []models.SlowQueryResponseYsqlQueryItem
+        topQueries, err = c.getTopQueriesReport()
+        report.TopQueries = &topQueries
+    case models.REPORTKINDENUM_CAPACITY_TREND:
+        var capacityTrend models.ReportCapacityTrend
+        capacityTrend, err = c.getCapacityTrendReport(start, end)
+        report.CapacityTrend = &capacityTrend
+    default:
+        err = fmt.Errorf("unknown report kind %s", kind)
+    }
+    if err != nil {
+        report.Error = err.Error()
+        report.Health = nil
+        report.TopQueries = nil
+        report.CapacityTrend = nil
+    }
+    report.GeneratedTimestamp = time.Now().Unix()
+    return report
+}
+
+// Gets the health of the cluster now, and the average number of live nodes over the period
+func (c *Container) getHealthReport(start time.Time, end time.Time) (models.ReportHealth, error) {
+    snapshot, err := getClusterSnapshot(end)
+    if err != nil {
+        return models.ReportHealth{}, err
+    }
+    health := models.ReportHealth{
+        NumNodes: int32(len(snapshot.Nodes)),
+        DeadNodes: []string{},
+        NumMasters: int32(len(snapshot.Masters)),
+    }
+    if snapshot.Health != nil {
+        health.DeadNodes = snapshot.Health.DeadNodes
+        health.NumUnderReplicatedTablets = int32(len(snapshot.Health.UnderReplicatedTablets))
+    }
+    for _, master := range snapshot.Masters {
+        if !master.IsReachable {
+            health.NumUnreachableMasters++
+        }
+    }
+    for _, node := range snapshot.Nodes {
+        if node.Version != "" && (health.SoftwareVersion == "" ||
+            helpers.CompareVersions(health.SoftwareVersion, node.Version) > 0) {
+            health.SoftwareVersion = node.Version
+        }
+    }
+    // The live nodes are left unknown rather than failing the report if there are no metrics
+    hostToUuid, err := c.hostToUuid.get()
+    if err != nil {
+        return health, nil
+    }
+    reader, err := c.getMetricsReader(hostToUuid)
+    if err != nil {
+        return health, nil
+    }
+    nodeUpMetric := helpers.GetConfig().Metrics.NodeUpMetric
+    liveNodes := float64(0)
+    hasSamples := false
+    for _, node := range snapshot.Nodes {
+        uuid, ok := hostToUuid.Get(node.Name)
+        if !ok {
+            continue
+        }
+        values, err := reader.nodeValues(nodeUpMetric, uuid, start.Unix(), end.Unix(), false)
+        if err != nil || len(values) == 0 {
+            continue
+        }
+        sum := float64(0)
+        for _, value := range values {
+            sum += value[1]
+        }
+        liveNodes += sum / float64(len(values))
+        hasSamples = true
+    }
+    if hasSamples {
+        health.AverageLiveNodes = &liveNodes
+    }
+    return health, nil
+}
+
+// Gets the queries that took the most time in total. pg_stat_statements only keeps cumulative
+// stats, so they cover the time since the stats were last reset rather than the period.
+func (c *Container) getTopQueriesReport() ([]models.SlowQueryResponseYsqlQueryItem, error) {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return nil, tabletServersResponse.Error
+    }
+    nodes := []string{}
+    for _, cluster := range tabletServersResponse.Tablets {
+        for address := range cluster {
+            host, err := helpers.GetHostFromAddress(address)
+            if err == nil {
+                nodes = append(nodes, host)
+            }
+        }
+    }
+    queryMap, errorCount := c.getAggregatedSlowQueries(nodes)
+    if len(nodes) > 0 && int(errorCount) == len(nodes) {
+        return nil, fmt.Errorf("failed to get the queries of all %d nodes", len(nodes))
+    }
+    queries := []models.SlowQueryResponseYsqlQueryItem{}
+    for _, query := range queryMap {
+        queries = append(queries, *query)
+    }
+    sort.Slice(queries, func(i, j int) bool {
+        return queries[i].TotalTime > queries[j].TotalTime
+    })
+    if count := helpers.GetConfig().Reports.TopQueriesCount; len(queries) > count {
+        queries = queries[:count]
+    }
+    return queries, nil
+}
+
+// Gets how the disk usage grew over the period, from the first and last samples in it
+func (c *Container) getCapacityTrendReport(start time.Time,
+    end time.Time) (models.ReportCapacityTrend, error) {
+    hostToUuid, err := c.hostToUuid.get()
+    if err != nil {
+        return models.ReportCapacityTrend{}, err
+    }
+    reader, err := c.getMetricsReader(hostToUuid)
+    if err != nil {
+        return models.ReportCapacityTrend{}, err
+    }
+    metricsConfig := helpers.GetConfig().Metrics
+    totalValues, err := reader.allNodeValues(metricsConfig.TotalDiskMetric, start.Unix(),
+        end.Unix())
+    if err != nil {
+        return models.ReportCapacityTrend{}, err
+    }
+    freeValues, err := reader.allNodeValues(metricsConfig.FreeDiskMetric, start.Unix(),
+        end.Unix())
+    if err != nil {
+        return models.ReportCapacityTrend{}, err
+    }
+    // Like the disk usage chart, the free and total disk samples are assumed to be paired
+    if len(totalValues) < 2 || len(freeValues) < 2 {
+        return models.ReportCapacityTrend{},
+            fmt.Errorf("not enough disk usage samples in the period")
+    }
+    first, last := 0, len(totalValues)-1
+    if len(freeValues) < len(totalValues) {
+        last = len(freeValues) - 1
+    }
+    trend := models.ReportCapacityTrend{
+        DiskSizeGb: totalValues[last][1] / helpers.BYTES_IN_GB,
+        StartDiskUsedGb: (totalValues[first][1] - freeValues[first][1]) / helpers.BYTES_IN_GB,
+        EndDiskUsedGb: (totalValues[last][1] - freeValues[last][1]) / helpers.BYTES_IN_GB,
+    }
+    days := (totalValues[last][0] - totalValues[first][0]) / (24 * 60 * 60)
+    if days > 0 {
+        trend.GrowthGbPerDay = (trend.EndDiskUsedGb - trend.StartDiskUsedGb) / days
+    }
+    if trend.GrowthGbPerDay > 0 {
+        daysUntilFull := (trend.DiskSizeGb - trend.EndDiskUsedGb) / trend.GrowthGbPerDay
+        trend.DaysUntilFull = &daysUntilFull
+    }
+    return trend, nil
+}
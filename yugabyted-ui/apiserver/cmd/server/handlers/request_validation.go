@@ -0,0 +1,142 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "fmt"
+    "mime"
+    "net/http"
+    "sort"
+    "strings"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Query parameters of each API route, by method and route. Routes that are not listed take
+// none.
+var API_QUERY_PARAMS = map[string][]string{
+    "GET /api/gflags/:name/doc": {"server_type"},
+    "GET /api/cluster/changes": {"since", "wait"},
+    "GET /api/events": {"cursor", "from", "limit", "to", "type"},
+    "GET /api/ddl-activity": {"cursor", "from", "kind", "limit", "node", "to"},
+    "GET /api/callhome/preview": {"collection_level"},
+    "GET /api/yb-admin/:command": {"arg"},
+    "GET /api/restore/preview": {"restore_time", "schedule_id", "snapshot_id"},
+    "GET /api/metrics": {"end_time", "format", "metrics", "node_name", "region", "start_time"},
+    "GET /api/replication-traffic": {"end_time", "kind", "level", "start_time"},
+    "GET /api/tables": {"api"},
+    "GET /api/live_queries": {"api"},
+    "GET /api/slow_queries": {"format"},
+    "GET /api/alerts": {"include_suppressed"},
+    "GET /api/alerts/history": {"from", "rule_id", "severity", "to"},
+    "GET /api/namespaces": {"api"},
+    "GET /api/users": {"api"},
+    "GET /api/grants": {"api", "database", "role", "table"},
+    "GET /api/extensions": {"database"},
+    "GET /api/compatibility": {"database"},
+    "GET /api/sequences": {"database"},
+    "GET /api/tablets": {"node", "table"},
+    "GET /api/catalog/tables": {"database", "include_system", "kind", "limit", "name", "schema"},
+    "GET /api/catalog/indexes": {"database", "include_system", "limit", "name", "schema",
+        "table"},
+    "GET /api/catalog/constraints": {"database", "include_system", "limit", "name", "schema",
+        "table", "type"},
+    "GET /api/catalog/roles": {"include_system", "limit", "name"},
+    "GET /api/tables/:id/metrics": {"end_time", "metrics", "start_time"},
+    "GET /api/cdc/streams/:id/connector-config": {"database", "format", "name", "tables"},
+    "GET /api/shell": {"api", "database"},
+    "GET /api/nodes/join-command": {"advertise_address", "base_dir", "cloud_location"},
+    "DELETE /api/nodes/:address": {"base_dir"},
+    "GET /api/nodes/:name/rpcz": {"direction", "min_elapsed_ms", "process"},
+    "GET /api/rpcz": {"direction", "min_elapsed_ms", "process"},
+    "GET /api/nodes/:name/threadz": {"process"},
+    "GET /api/threadz": {"process"},
+    "GET /api/profiles/:id/flamegraph": {"format"},
+    "GET /api/reports": {"kind"},
+    "GET /api/reports/:id": {"format"},
+    "GET /api/probes": {"from", "limit", "node", "to"},
+    "GET /api/schema-migrations": {"database", "limit"},
+}
+
+// Query parameters that can be given more than once
+var REPEATED_QUERY_PARAMS = []string{"arg"}
+
+// Routes whose bodies are uploads, with the media types they take. Uploads can be up to
+// requests.max_upload_bytes, the bodies of other routes must be JSON of up to
+// requests.max_body_bytes.
+var API_UPLOAD_ROUTES = map[string][]string{
+    "POST /api/tables/:id/import": {echo.MIMEMultipartForm},
+    "POST /api/store/restore": {echo.MIMEApplicationJSON},
+}
+
+// Refuses the API requests whose bodies are too large or not of a media type their route
+// takes, and in requests.strict_query_params mode the requests with query parameters their
+// route does not take. Bodies are cut off at the limit when they do not declare their length.
+func ValidateRequest(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+        if !strings.HasPrefix(ctx.Path(), "/api/") {
+            return next(ctx)
+        }
+        requestsConfig := helpers.GetConfig().Requests
+        request := ctx.Request()
+        route := request.Method + " " + ctx.Path()
+        if requestsConfig.StrictQueryParams {
+            if apiErr, ok := getQueryParamsError(ctx, route); ok {
+                return apiErr.respond(ctx)
+            }
+        }
+        if request.Body == nil || request.Body == http.NoBody || request.ContentLength == 0 {
+            return next(ctx)
+        }
+        maxBytes := requestsConfig.MaxBodyBytes
+        mediaTypes, ok := API_UPLOAD_ROUTES[route]
+        if ok {
+            maxBytes = requestsConfig.MaxUploadBytes
+        } else {
+            mediaTypes = []string{echo.MIMEApplicationJSON}
+        }
+        if request.ContentLength > maxBytes {
+            apiErr := newApiError(http.StatusRequestEntityTooLarge,
+                fmt.Sprintf("the body must be at most %d bytes", maxBytes))
+            apiErr.details = map[string]interface{}{"max_bytes": maxBytes}
+            return apiErr.respond(ctx)
+        }
+        mediaType, _, err := mime.ParseMediaType(request.Header.Get(echo.HeaderContentType))
+        if err != nil || !containsString(mediaTypes, mediaType) {
+            apiErr := newApiError(http.StatusUnsupportedMediaType,
+                fmt.Sprintf("the body must be %s, got %q", strings.Join(mediaTypes, " or "),
+                    request.Header.Get(echo.HeaderContentType)))
+            apiErr.details = map[string]interface{}{"media_types": mediaTypes}
+            return apiErr.respond(ctx)
+        }
+        request.Body = http.MaxBytesReader(ctx.Response(), request.Body, maxBytes)
+        return next(ctx)
+    }
+}
+
+// Gets the error of a request with a query parameter its route does not take, or with a
+// parameter given more than once that can only be given once. Returns false if there is none.
+func getQueryParamsError(ctx echo.Context, route string) (apiError, bool) {
+    params := ctx.QueryParams()
+    names := []string{}
+    for name := range params {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        message := ""
+        if !containsString(API_QUERY_PARAMS[route], name) {
+            message = fmt.Sprintf("unknown query parameter %s", name)
+            if len(API_QUERY_PARAMS[route]) > 0 {
+                message += ", expected one of " + strings.Join(API_QUERY_PARAMS[route], ", ")
+            }
+        } else if len(params[name]) > 1 && !containsString(REPEATED_QUERY_PARAMS, name) {
+            message = fmt.Sprintf("query parameter %s must be given at most once", name)
+        }
+        if message != "" {
+            apiErr := newApiError(http.StatusBadRequest, message)
+            apiErr.details = map[string]interface{}{"parameter": name}
+            return apiErr, true
+        }
+    }
+    return apiError{}, false
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "bytes"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Tells whether a response came from the cache: HIT, MISS or BYPASS
+const CACHE_STATUS_HEADER = "X-Cache"
+
+type cachedResponse struct {
+    status int
+    // The headers the handler set, e.g. the content type and the X-Error-Count of the lists
+    header http.Header
+    body []byte
+    expiresAt time.Time
+}
+
+type routeCacheStats struct {
+    hits int64
+    misses int64
+    bypasses int64
+}
+
+// Caches the responses of the GET routes for the TTL configured for each route, so that UI
+// sessions polling the same pages do not each hit the masters and tservers. Responses are keyed
+// by path, query parameters and Accept header.
+type responseCache struct {
+    mutex sync.Mutex
+    entries map[string]*cachedResponse
+    // Lookups of each route by route path
+    stats map[string]*routeCacheStats
+}
+
+func newResponseCache() *responseCache {
+    return &responseCache{
+        entries: map[string]*cachedResponse{},
+        stats: map[string]*routeCacheStats{},
+    }
+}
+
+// Gets the cached response of a request unless it is expired or bypassed, counting the lookup
+// in the stats of the route
+func (cache *responseCache) lookup(route string, key string, bypass bool) (*cachedResponse, bool) {
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+    stats, ok := cache.stats[route]
+    if !ok {
+        stats = &routeCacheStats{}
+        cache.stats[route] = stats
+    }
+    if bypass {
+        stats.bypasses++
+        return nil, false
+    }
+    entry, ok := cache.entries[key]
+    if !ok || time.Now().After(entry.expiresAt) {
+        stats.misses++
+        return nil, false
+    }
+    stats.hits++
+    return entry, true
+}
+
+// Stores a response, evicting the expired responses and then the ones closest to expiring
+// when the cache is full
+func (cache *responseCache) store(key string, entry *cachedResponse, maxEntries int) {
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+    if _, ok := cache.entries[key]; !ok && len(cache.entries) >= maxEntries {
+        now := time.Now()
+        for existingKey, existing := range cache.entries {
+            if now.After(existing.expiresAt) {
+                delete(cache.entries, existingKey)
+            }
+        }
+        for len(cache.entries) >= maxEntries {
+            oldestKey := ""
+            var oldest *cachedResponse
+            for existingKey, existing := range cache.entries {
+                if oldest == nil || existing.expiresAt.Before(oldest.expiresAt) {
+                    oldestKey, oldest = existingKey, existing
+                }
+            }
+            delete(cache.entries, oldestKey)
+        }
+    }
+    cache.entries[key] = entry
+}
+
+func (cache *responseCache) clear() {
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+    cache.entries = map[string]*cachedResponse{}
+}
+
+// Gets the number of cached responses, and a copy of the lookup stats by route path
+func (cache *responseCache) getStats() (int, map[string]routeCacheStats) {
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+    stats := map[string]routeCacheStats{}
+    for route, routeStats := range cache.stats {
+        stats[route] = *routeStats
+    }
+    return len(cache.entries), stats
+}
+
+// Passes a response through while keeping a copy of its body, up to a limit
+type responseRecorder struct {
+    http.ResponseWriter
+    body bytes.Buffer
+    limit int
+    overflowed bool
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+    if !recorder.overflowed {
+        if recorder.body.Len()+len(data) > recorder.limit {
+            recorder.overflowed = true
+            recorder.body.Reset()
+        } else {
+            recorder.body.Write(data)
+        }
+    }
+    return recorder.ResponseWriter.Write(data)
+}
+
+// Streamed responses flush as they go
+func (recorder *responseRecorder) Flush() {
+    if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+        flusher.Flush()
+    }
+}
+
+// Gets the headers that were set after the previous ones, leaving out the cookies of the
+// response, which are not shared between clients
+func getHandlerHeader(previous http.Header, current http.Header) http.Header {
+    header := http.Header{}
+    for name, values := range current {
+        if name == "Set-Cookie" || name == CACHE_STATUS_HEADER {
+            continue
+        }
+        if previousValues, ok := previous[name]; ok &&
+            strings.Join(previousValues, "\n") == strings.Join(values, "\n") {
+            continue
+        }
+        header[name] = append([]string{}, values...)
+    }
+    return header
+}
+
+// Whether the client asked for a fresh response, which then replaces the cached one
+func isCacheBypassed(request *http.Request) bool {
+    for _, directive := range strings.Split(request.Header.Get("Cache-Control"), ",") {
+        directive = strings.ToLower(strings.TrimSpace(directive))
+        if directive == "no-cache" || directive == "no-store" {
+            return true
+        }
+    }
+    return request.Header.Get("Pragma") == "no-cache"
+}
+
+// CacheResponses - Middleware that serves GET requests from the response cache. Successful
+// requests with other methods clear the cache, as they can change what the pages show.
+func (c *Container) CacheResponses(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+        request := ctx.Request()
+        cacheConfig := helpers.GetConfig().Cache
+        if request.Method != http.MethodGet {
+            err := next(ctx)
+            if err == nil && ctx.Response().Status < http.StatusBadRequest {
+                c.responseCache.clear()
+            }
+            return err
+        }
+        route := ctx.Path()
+        ttl := cacheConfig.Ttls[route]
+        if !cacheConfig.Enabled || ttl <= 0 {
+            return next(ctx)
+        }
+        // Lists can be requested as CSV through the Accept header, so it is part of the key
+        key := request.URL.Path + "?" + ctx.QueryParams().Encode() + "#" +
+            request.Header.Get(echo.HeaderAccept)
+        bypass := isCacheBypassed(request)
+        if entry, ok := c.responseCache.lookup(route, key, bypass); ok {
+            header := ctx.Response().Header()
+            for name, values := range entry.header {
+                header[name] = append([]string{}, values...)
+            }
+            header.Set(CACHE_STATUS_HEADER, "HIT")
+            return ctx.Blob(entry.status, header.Get(echo.HeaderContentType), entry.body)
+        }
+        if bypass {
+            ctx.Response().Header().Set(CACHE_STATUS_HEADER, "BYPASS")
+        } else {
+            ctx.Response().Header().Set(CACHE_STATUS_HEADER, "MISS")
+        }
+        response := ctx.Response()
+        // The headers of the middleware that run again on a hit are not cached
+        previousHeader := response.Header().Clone()
+        recorder := &responseRecorder{
+            ResponseWriter: response.Writer,
+            limit: cacheConfig.MaxEntryBytes,
+        }
+        response.Writer = recorder
+        err := next(ctx)
+        response.Writer = recorder.ResponseWriter
+        if err == nil && response.Status == http.StatusOK && !recorder.overflowed {
+            c.responseCache.store(key, &cachedResponse{
+                status: response.Status,
+                header: getHandlerHeader(previousHeader, response.Header()),
+                body: recorder.body.Bytes(),
+                expiresAt: time.Now().Add(ttl),
+            }, cacheConfig.MaxEntries)
+        }
+        return err
+    }
+}
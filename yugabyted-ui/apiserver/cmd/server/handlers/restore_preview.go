@@ -0,0 +1,256 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// What a restore would do to an object
+const RESTORE_ACTION_OVERWRITE = "overwrite"
+const RESTORE_ACTION_RESTORE = "restore"
+const RESTORE_ACTION_DROP = "drop"
+
+const RESTORE_OBJECT_DATABASE = "database"
+const RESTORE_OBJECT_TABLE = "table"
+const RESTORE_OBJECT_INDEX = "index"
+
+// yb-admin prints the times of the snapshots of a schedule in the local time of the host
+const SNAPSHOT_TIME_LAYOUT = "2006-01-02 15:04:05.999999"
+
+// An object of a snapshot, as printed by yb-admin list_snapshots SHOW_DETAILS
+type snapshotEntry struct {
+    Type string `json:"type"`
+    Id string `json:"id"`
+    Data struct {
+        Name string `json:"name"`
+        DatabaseType string `json:"database_type"`
+        TableType string `json:"table_type"`
+        NamespaceId string `json:"namespace_id"`
+        NamespaceName string `json:"namespace_name"`
+        IndexedTableId string `json:"indexed_table_id"`
+    } `json:"data"`
+}
+
+type snapshotSchedules struct {
+    Schedules []struct {
+        Id string `json:"id"`
+        Options struct {
+            Filter string `json:"filter"`
+        } `json:"options"`
+        Snapshots []struct {
+            Id string `json:"id"`
+            SnapshotTime string `json:"snapshot_time"`
+        } `json:"snapshots"`
+    } `json:"schedules"`
+}
+
+// Gets the objects of a snapshot from the output of yb-admin list_snapshots SHOW_DETAILS, which
+// prints each snapshot on a line followed by one JSON line per object. Returns false if there is
+// no such snapshot.
+func parseSnapshotEntries(output string, snapshotId string) ([]snapshotEntry, bool, error) {
+    entries := []snapshotEntry{}
+    found, current := false, false
+    for _, line := range strings.Split(output, "\n") {
+        line = strings.TrimSpace(line)
+        // The restorations are listed after the snapshots
+        if strings.HasPrefix(line, "Restoration UUID") {
+            break
+        }
+        if strings.HasPrefix(line, "{") {
+            if !current {
+                continue
+            }
+            entry := snapshotEntry{}
+            if err := json.Unmarshal([]byte(line), &entry); err != nil {
+                return nil, false, fmt.Errorf("unexpected list_snapshots output: %s", line)
+            }
+            entries = append(entries, entry)
+            continue
+        }
+        fields := strings.Fields(line)
+        if len(fields) > 0 && YB_ADMIN_ID_REGEX.MatchString(fields[0]) {
+            current = strings.EqualFold(strings.ReplaceAll(fields[0], "-", ""),
+                strings.ReplaceAll(snapshotId, "-", ""))
+            found = found || current
+        }
+    }
+    return entries, found, nil
+}
+
+// The latest snapshot of a schedule taken at or before a time, along with the namespaces of the
+// schedule given as api.name, for instance ysql.yugabyte
+type scheduleSnapshot struct {
+    namespaces []string
+    // Empty if the schedule has no snapshot at or before the time
+    snapshotId string
+    snapshotTime time.Time
+}
+
+// Gets the latest snapshot of a schedule taken at or before a time. Returns false if there is
+// no such schedule.
+func getScheduleSnapshot(scheduleId string, restoreTime time.Time) (scheduleSnapshot, bool,
+    error) {
+    output, err := helpers.RunYbAdmin("list_snapshot_schedules", scheduleId)
+    if err != nil {
+        return scheduleSnapshot{}, false, err
+    }
+    schedules := snapshotSchedules{}
+    if err := json.Unmarshal([]byte(output), &schedules); err != nil {
+        return scheduleSnapshot{}, false,
+            fmt.Errorf("unexpected list_snapshot_schedules output: %s", output)
+    }
+    for _, schedule := range schedules.Schedules {
+        snapshot := scheduleSnapshot{namespaces: []string{}}
+        for _, namespace := range strings.Split(schedule.Options.Filter, ",") {
+            if namespace = strings.TrimSpace(namespace); namespace != "" {
+                snapshot.namespaces = append(snapshot.namespaces, namespace)
+            }
+        }
+        for _, scheduled := range schedule.Snapshots {
+            taken, err := time.ParseInLocation(SNAPSHOT_TIME_LAYOUT, scheduled.SnapshotTime,
+                time.Local)
+            if err != nil {
+                return scheduleSnapshot{}, true,
+                    fmt.Errorf("unexpected snapshot time %q", scheduled.SnapshotTime)
+            }
+            if !taken.After(restoreTime) && taken.After(snapshot.snapshotTime) {
+                snapshot.snapshotId, snapshot.snapshotTime = scheduled.Id, taken
+            }
+        }
+        return snapshot, true, nil
+    }
+    return scheduleSnapshot{}, false, nil
+}
+
+func getRestoreApi(isYsql bool) string {
+    if isYsql {
+        return "YSQL"
+    }
+    return "YCQL"
+}
+
+// Lists what restoring a snapshot would do. The objects of the snapshot are overwritten if they
+// exist now and restored otherwise. For a point in time restore, the objects that exist now in
+// the namespaces of the schedule but not in its snapshot are dropped. The estimated size is the
+// current size of the tables and indexes that would be overwritten or dropped. Returns false if
+// there is no such snapshot.
+func getRestorePreview(snapshotId string,
+    namespaces []string) (models.RestorePreview, bool, error) {
+    preview := models.RestorePreview{
+        SnapshotId: snapshotId,
+        Objects: []models.RestoreObject{},
+    }
+    output, err := helpers.RunYbAdmin("list_snapshots", "SHOW_DETAILS")
+    if err != nil {
+        return preview, false, err
+    }
+    entries, found, err := parseSnapshotEntries(output, snapshotId)
+    if err != nil || !found {
+        return preview, found, err
+    }
+    tablesFuture := make(chan helpers.TablesFuture)
+    go helpers.GetTablesFuture(helpers.HOST, tablesFuture)
+    tablesResponse := <-tablesFuture
+    if tablesResponse.Error != nil {
+        return preview, true, tablesResponse.Error
+    }
+    currentTables := map[string]helpers.Table{}
+    for _, table := range tablesResponse.Tables {
+        currentTables[table.Uuid] = table
+    }
+    namespaceNames := map[string]string{}
+    namespaceApis := map[string]bool{}
+    for _, entry := range entries {
+        if entry.Type == "NAMESPACE" {
+            namespaceNames[entry.Id] = entry.Data.Name
+            namespaceApis[entry.Id] = entry.Data.DatabaseType == "YQL_DATABASE_PGSQL"
+        }
+    }
+    inSnapshot := map[string]bool{}
+    overwrittenDatabases := map[string]bool{}
+    for _, entry := range entries {
+        if entry.Type != "TABLE" {
+            continue
+        }
+        inSnapshot[entry.Id] = true
+        object := models.RestoreObject{
+            Id: entry.Id,
+            Kind: RESTORE_OBJECT_TABLE,
+            Api: getRestoreApi(entry.Data.TableType == "PGSQL_TABLE_TYPE"),
+            Database: entry.Data.NamespaceName,
+            Name: entry.Data.Name,
+            Action: RESTORE_ACTION_RESTORE,
+        }
+        if object.Database == "" {
+            object.Database = namespaceNames[entry.Data.NamespaceId]
+        }
+        if entry.Data.IndexedTableId != "" {
+            object.Kind = RESTORE_OBJECT_INDEX
+        }
+        if table, ok := currentTables[entry.Id]; ok {
+            object.Action = RESTORE_ACTION_OVERWRITE
+            object.SizeBytes = table.SizeBytes
+            overwrittenDatabases[entry.Data.NamespaceId] = true
+        }
+        preview.Objects = append(preview.Objects, object)
+    }
+    for id, name := range namespaceNames {
+        object := models.RestoreObject{
+            Id: id,
+            Kind: RESTORE_OBJECT_DATABASE,
+            Api: getRestoreApi(namespaceApis[id]),
+            Database: name,
+            Name: name,
+            Action: RESTORE_ACTION_RESTORE,
+        }
+        if overwrittenDatabases[id] {
+            object.Action = RESTORE_ACTION_OVERWRITE
+        }
+        preview.Objects = append(preview.Objects, object)
+    }
+    for _, table := range tablesResponse.Tables {
+        namespace := strings.ToLower(getRestoreApi(table.IsYsql)) + "." + table.Keyspace
+        if inSnapshot[table.Uuid] || !containsString(namespaces, namespace) {
+            continue
+        }
+        object := models.RestoreObject{
+            Id: table.Uuid,
+            Kind: RESTORE_OBJECT_TABLE,
+            Api: getRestoreApi(table.IsYsql),
+            Database: table.Keyspace,
+            Name: table.Name,
+            Action: RESTORE_ACTION_DROP,
+            SizeBytes: table.SizeBytes,
+        }
+        if table.IsIndex {
+            object.Kind = RESTORE_OBJECT_INDEX
+        }
+        preview.Objects = append(preview.Objects, object)
+    }
+    for _, object := range preview.Objects {
+        preview.EstimatedSizeBytes += object.SizeBytes
+    }
+    // Databases first, then their tables and indexes by name
+    sort.Slice(preview.Objects, func(i, j int) bool {
+        left, right := preview.Objects[i], preview.Objects[j]
+        if left.Api != right.Api {
+            return left.Api < right.Api
+        }
+        if left.Database != right.Database {
+            return left.Database < right.Database
+        }
+        if (left.Kind == RESTORE_OBJECT_DATABASE) != (right.Kind == RESTORE_OBJECT_DATABASE) {
+            return left.Kind == RESTORE_OBJECT_DATABASE
+        }
+        if left.Name != right.Name {
+            return left.Name < right.Name
+        }
+        return left.Id < right.Id
+    })
+    return preview, true, nil
+}
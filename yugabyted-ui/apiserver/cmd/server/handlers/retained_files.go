@@ -0,0 +1,113 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/logger"
+    "os"
+    "path/filepath"
+    "sort"
+    "sync"
+)
+
+// The files the server writes are named with this prefix, so that only they are deleted when
+// it starts, and not the other files of their directory
+const RETAINED_FILE_PREFIX = "yugabyted-ui-"
+
+type retainedFile struct {
+    info interface{}
+    timestamp int64
+    // The file, then the files made from it, which are deleted along with it
+    paths []string
+}
+
+// Keeps track of the files of a kind that the server writes, e.g. the database dumps, by the id
+// of the task that wrote them. Only the latest files are kept, older ones are deleted.
+type retainedFileStore struct {
+    mutex sync.Mutex
+    // What the files are, for the logs
+    kind string
+    files map[string]*retainedFile
+    logger logger.Logger
+}
+
+// Creates a store of the files of a kind, deleting the files with its extensions that a
+// previous run of the server left in the directory, as they cannot be looked up anymore
+func newRetainedFileStore(log logger.Logger, kind string, directory string,
+    extensions ...string) *retainedFileStore {
+    for _, extension := range extensions {
+        paths, _ := filepath.Glob(filepath.Join(directory, RETAINED_FILE_PREFIX+"*"+extension))
+        for _, path := range paths {
+            if err := os.Remove(path); err != nil {
+                log.Errorf("failed to delete old %s %s: %s", kind, path, err.Error())
+            }
+        }
+    }
+    return &retainedFileStore{
+        kind: kind,
+        files: map[string]*retainedFile{},
+        logger: log,
+    }
+}
+
+// Gets the path the file of an id is written to, creating the directory if needed
+func getRetainedFilePath(directory string, id string, extension string) (string, error) {
+    if err := os.MkdirAll(directory, 0700); err != nil {
+        return "", err
+    }
+    return filepath.Join(directory, RETAINED_FILE_PREFIX+id+extension), nil
+}
+
+// Records the files that were written, deleting the oldest beyond the max
+func (store *retainedFileStore) add(id string, info interface{}, timestamp int64,
+    paths []string, max int) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    store.files[id] = &retainedFile{info: info, timestamp: timestamp, paths: paths}
+    ids := store.listLocked()
+    for index := max; index < len(ids); index++ {
+        for _, path := range store.files[ids[index]].paths {
+            if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+                store.logger.Errorf("failed to delete %s %s: %s", store.kind, ids[index],
+                    err.Error())
+            }
+        }
+        delete(store.files, ids[index])
+    }
+}
+
+// Gets the ids, newest first. Must be called with the mutex held.
+func (store *retainedFileStore) listLocked() []string {
+    ids := []string{}
+    for id := range store.files {
+        ids = append(ids, id)
+    }
+    sort.Slice(ids, func(i, j int) bool {
+        first, second := store.files[ids[i]], store.files[ids[j]]
+        if first.timestamp != second.timestamp {
+            return first.timestamp > second.timestamp
+        }
+        return ids[i] < ids[j]
+    })
+    return ids
+}
+
+// Gets the infos of the files, newest first
+func (store *retainedFileStore) list() []interface{} {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    infos := []interface{}{}
+    for _, id := range store.listLocked() {
+        infos = append(infos, store.files[id].info)
+    }
+    return infos
+}
+
+// Gets the info and the path of the file of an id
+func (store *retainedFileStore) get(id string) (interface{}, string, bool) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    file, ok := store.files[id]
+    if !ok {
+        return nil, "", false
+    }
+    return file.info, file.paths[0], true
+}
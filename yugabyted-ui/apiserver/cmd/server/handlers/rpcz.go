@@ -0,0 +1,136 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "fmt"
+    "sort"
+    "strconv"
+)
+
+const RPC_DIRECTION_INBOUND = "inbound"
+const RPC_DIRECTION_OUTBOUND = "outbound"
+
+// Filters on the RPCs in progress
+type rpczFilter struct {
+    // inbound, outbound or empty for both
+    direction string
+    minElapsedMillis int64
+}
+
+func getRpczFilter(direction string, minElapsedMs string) (rpczFilter, error) {
+    filter := rpczFilter{direction: direction}
+    if direction != "" && direction != RPC_DIRECTION_INBOUND &&
+        direction != RPC_DIRECTION_OUTBOUND {
+        return filter, fmt.Errorf("direction must be inbound or outbound")
+    }
+    if minElapsedMs != "" {
+        value, err := strconv.ParseInt(minElapsedMs, 10, 64)
+        if err != nil || value < 0 {
+            return filter, fmt.Errorf("min_elapsed_ms must be a non-negative integer")
+        }
+        filter.minElapsedMillis = value
+    }
+    return filter, nil
+}
+
+func appendRpcCalls(calls []models.RpcCall, process serverProcess, direction string,
+    connections []helpers.RpczConnection, filter rpczFilter) []models.RpcCall {
+    if filter.direction != "" && filter.direction != direction {
+        return calls
+    }
+    for _, connection := range connections {
+        for _, call := range connection.CallsInFlight {
+            if call.ElapsedMillis < filter.minElapsedMillis {
+                continue
+            }
+            calls = append(calls, models.RpcCall{
+                Node: process.host,
+                Process: process.process,
+                Direction: direction,
+                RemoteAddress: connection.RemoteIp,
+                ConnectionState: connection.State,
+                ServiceName: call.Header.RemoteMethod.ServiceName,
+                MethodName: call.Header.RemoteMethod.MethodName,
+                CallId: call.Header.CallId,
+                ElapsedMillis: call.ElapsedMillis,
+                TimeoutMillis: call.Header.TimeoutMillis,
+                State: call.State,
+            })
+        }
+    }
+    return calls
+}
+
+// Gets the calls of a master or tserver that pass the filter
+func getRpcCalls(process serverProcess, rpcz helpers.Rpcz, filter rpczFilter) []models.RpcCall {
+    calls := appendRpcCalls([]models.RpcCall{}, process, RPC_DIRECTION_INBOUND,
+        rpcz.InboundConnections, filter)
+    return appendRpcCalls(calls, process, RPC_DIRECTION_OUTBOUND, rpcz.OutboundConnections,
+        filter)
+}
+
+// Sorts the calls, longest running first, and groups them by method
+func newRpcz(calls []models.RpcCall, errorCount int32) models.Rpcz {
+    rpcz := models.Rpcz{
+        Calls: calls,
+        Methods: []models.RpcMethodSummary{},
+        ErrorCount: errorCount,
+    }
+    sort.SliceStable(rpcz.Calls, func(i, j int) bool {
+        return rpcz.Calls[i].ElapsedMillis > rpcz.Calls[j].ElapsedMillis
+    })
+    methods := map[string]*models.RpcMethodSummary{}
+    for _, call := range rpcz.Calls {
+        key := call.ServiceName + "." + call.MethodName
+        method, ok := methods[key]
+        if !ok {
+            method = &models.RpcMethodSummary{
+                ServiceName: call.ServiceName,
+                MethodName: call.MethodName,
+            }
+            methods[key] = method
+        }
+        method.Count++
+        if call.ElapsedMillis > method.MaxElapsedMillis {
+            method.MaxElapsedMillis = call.ElapsedMillis
+        }
+    }
+    for _, method := range methods {
+        rpcz.Methods = append(rpcz.Methods, *method)
+    }
+    sort.Slice(rpcz.Methods, func(i, j int) bool {
+        if rpcz.Methods[i].Count != rpcz.Methods[j].Count {
+            return rpcz.Methods[i].Count > rpcz.Methods[j].Count
+        }
+        if rpcz.Methods[i].ServiceName != rpcz.Methods[j].ServiceName {
+            return rpcz.Methods[i].ServiceName < rpcz.Methods[j].ServiceName
+        }
+        return rpcz.Methods[i].MethodName < rpcz.Methods[j].MethodName
+    })
+    return rpcz
+}
+
+// Gets the RPCs in progress on the processes in parallel, counting the processes that could not
+// be reached rather than failing
+func getRpcz(processes []serverProcess, filter rpczFilter) models.Rpcz {
+    fanOut := newFanOutLimiter()
+    futures := []chan helpers.RpczFuture{}
+    for _, process := range processes {
+        host, isMaster := process.host, process.process == helpers.MASTER_PROCESS
+        future := make(chan helpers.RpczFuture, 1)
+        futures = append(futures, future)
+        fanOut.goCall(func() { helpers.GetRpczFuture(host, isMaster, future) })
+    }
+    calls := []models.RpcCall{}
+    errorCount := int32(0)
+    for index, future := range futures {
+        response := <-future
+        if response.Error != nil {
+            errorCount++
+            continue
+        }
+        calls = append(calls, getRpcCalls(processes[index], response.Rpcz, filter)...)
+    }
+    return newRpcz(calls, errorCount)
+}
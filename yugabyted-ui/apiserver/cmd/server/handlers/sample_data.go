@@ -0,0 +1,131 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "apiserver/cmd/server/tasks"
+    "context"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "time"
+
+    "github.com/jackc/pgx/v4"
+)
+
+// A sample dataset shipped in the share directory of the YugabyteDB release, loaded by running
+// its files in order
+type sampleDataset struct {
+    name string
+    description string
+    files []string
+}
+
+var SAMPLE_DATASETS = []sampleDataset{
+    {
+        name: "northwind",
+        description: "Customers, orders, products and suppliers of a trading company",
+        files: []string{"northwind_ddl.sql", "northwind_data.sql"},
+    },
+    {
+        name: "chinook",
+        description: "A digital media store, with artists, albums, tracks, invoices and " +
+            "customers",
+        files: []string{"chinook_ddl.sql", "chinook_genres_artists_albums.sql",
+            "chinook_songs.sql"},
+    },
+    {
+        name: "pgexercises",
+        description: "Members, facilities and bookings of a country club, in schema cd",
+        files: []string{"clubdata_ddl.sql", "clubdata_data.sql"},
+    },
+    {
+        name: "sportsdb",
+        description: "Sports teams, players, events and their statistics",
+        files: []string{"sportsdb_tables.sql", "sportsdb_inserts.sql",
+            "sportsdb_constraints.sql", "sportsdb_fks.sql", "sportsdb_indexes.sql"},
+    },
+}
+
+// Gets the directory of the sample datasets, by default the share directory of the release
+// that ysqlsh is part of
+func getSampleDataDirectory() (string, error) {
+    toolsConfig := helpers.GetConfig().Tools
+    if toolsConfig.SampleDataDirectory != "" {
+        return toolsConfig.SampleDataDirectory, nil
+    }
+    ysqlshPath, err := exec.LookPath(toolsConfig.YsqlshPath)
+    if err != nil {
+        return "", fmt.Errorf("failed to find the sample datasets next to ysqlsh: %w", err)
+    }
+    if resolved, err := filepath.EvalSymlinks(ysqlshPath); err == nil {
+        ysqlshPath = resolved
+    }
+    return filepath.Join(filepath.Dir(filepath.Dir(ysqlshPath)), "share"), nil
+}
+
+func getSampleDataset(name string) (sampleDataset, bool) {
+    for _, dataset := range SAMPLE_DATASETS {
+        if dataset.name == name {
+            return dataset, true
+        }
+    }
+    return sampleDataset{}, false
+}
+
+// Gets the sample datasets, and whether all of their files are in the directory
+func getSampleDatasets() []models.SampleDataset {
+    directory, err := getSampleDataDirectory()
+    datasets := []models.SampleDataset{}
+    for _, dataset := range SAMPLE_DATASETS {
+        available := err == nil
+        for _, file := range dataset.files {
+            if available {
+                _, statErr := os.Stat(filepath.Join(directory, file))
+                available = statErr == nil
+            }
+        }
+        datasets = append(datasets, models.SampleDataset{
+            Name: dataset.name,
+            Description: dataset.description,
+            Files: append([]string{}, dataset.files...),
+            Available: available,
+        })
+    }
+    return datasets
+}
+
+// Loads a sample dataset into a database, creating the database if it does not exist. The
+// files stop at their first error, so a dataset whose tables already exist is not loaded again.
+func loadSampleDataset(task *tasks.Task, dataset sampleDataset, database string,
+    createDatabase bool) error {
+    deadline := time.Now().Add(helpers.GetConfig().Timeouts.SampleDataLoad)
+    directory, err := getSampleDataDirectory()
+    if err != nil {
+        return err
+    }
+    if createDatabase {
+        task.Progress("creating database %s", database)
+        // The shared connection of the server cannot be used outside of its requests
+        conn, err := pgx.Connect(context.Background(), helpers.GetYsqlConnectionUrl(helpers.DbName))
+        if err != nil {
+            return err
+        }
+        defer conn.Close(context.Background())
+        _, err = conn.Exec(context.Background(),
+            "CREATE DATABASE "+pgx.Identifier{database}.Sanitize())
+        if err != nil {
+            return err
+        }
+    }
+    for index, file := range dataset.files {
+        task.Progress("running %s (%d of %d)", file, index+1, len(dataset.files))
+        if _, err := helpers.RunYsqlsh(time.Until(deadline), database, "--set",
+            "ON_ERROR_STOP=1", "--quiet", "--file", filepath.Join(directory, file)); err != nil {
+            return err
+        }
+    }
+    task.Progress("loaded sample dataset %s into database %s", dataset.name, database)
+    return nil
+}
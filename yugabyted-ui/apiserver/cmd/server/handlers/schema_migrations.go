@@ -0,0 +1,193 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "context"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/jackc/pgx/v4"
+)
+
+// Tools whose history tables are read, by the name of their table. schema_migrations is shared
+// by several tools, e.g. Rails, golang-migrate and Ecto, which keep different columns.
+const SCHEMA_MIGRATION_TOOL_FLYWAY = "flyway"
+const SCHEMA_MIGRATION_TOOL_LIQUIBASE = "liquibase"
+const SCHEMA_MIGRATION_TOOL_SCHEMA_MIGRATIONS = "schema_migrations"
+
+var SCHEMA_MIGRATION_TABLES = map[string]string{
+    "flyway_schema_history": SCHEMA_MIGRATION_TOOL_FLYWAY,
+    "databasechangelog": SCHEMA_MIGRATION_TOOL_LIQUIBASE,
+    "schema_migrations": SCHEMA_MIGRATION_TOOL_SCHEMA_MIGRATIONS,
+}
+
+const SCHEMA_MIGRATION_TABLES_SQL string = "SELECT table_schema, table_name, " +
+    "ARRAY(SELECT column_name::text FROM information_schema.columns c " +
+    "WHERE c.table_schema = t.table_schema AND c.table_name = t.table_name) " +
+    "FROM information_schema.tables t WHERE table_type = 'BASE TABLE' AND table_name IN " +
+    "('flyway_schema_history', 'databasechangelog', 'schema_migrations') " +
+    "AND table_schema NOT IN ('pg_catalog', 'information_schema')"
+
+// Number of migrations listed of each history table by default, and at most
+const SCHEMA_MIGRATIONS_DEFAULT_LIMIT = 50
+const SCHEMA_MIGRATIONS_MAX_LIMIT = 1000
+
+// Gets the newest migrations of a history table first, along with the number of migrations in
+// it and when the latest one was applied, if the table records it
+func getSchemaMigrationHistory(conn *pgx.Conn, database string, schema string, table string,
+    columns []string, limit int) (models.SchemaMigrationHistory, error) {
+    history := models.SchemaMigrationHistory{
+        Database: database,
+        Schema: schema,
+        Table: table,
+        Tool: SCHEMA_MIGRATION_TABLES[table],
+        Migrations: []models.SchemaMigration{},
+    }
+    qualifiedTable := pgx.Identifier{schema, table}.Sanitize()
+    err := conn.QueryRow(context.Background(), "SELECT count(*) FROM "+qualifiedTable).
+        Scan(&history.Total)
+    if err != nil {
+        return history, err
+    }
+    var query string
+    switch {
+    case history.Tool == SCHEMA_MIGRATION_TOOL_FLYWAY:
+        query = "SELECT coalesce(version, ''), description, script, installed_by, " +
+            "installed_on, execution_time, success FROM " + qualifiedTable +
+            " ORDER BY installed_rank DESC LIMIT $1"
+    case history.Tool == SCHEMA_MIGRATION_TOOL_LIQUIBASE:
+        query = "SELECT id, coalesce(description, ''), filename, author, dateexecuted, " +
+            "NULL::int, exectype != 'FAILED' FROM " + qualifiedTable +
+            " ORDER BY orderexecuted DESC LIMIT $1"
+    case containsString(columns, "dirty"):
+        // golang-migrate keeps the current version alone, dirty if its migration failed
+        query = "SELECT version::text, '', '', '', NULL::timestamptz, NULL::int, NOT dirty " +
+            "FROM " + qualifiedTable + " ORDER BY version DESC LIMIT $1"
+    case containsString(columns, "inserted_at"):
+        query = "SELECT version::text, '', '', '', inserted_at::timestamptz, NULL::int, " +
+            "NULL::bool FROM " + qualifiedTable + " ORDER BY version DESC LIMIT $1"
+    default:
+        query = "SELECT version::text, '', '', '', NULL::timestamptz, NULL::int, NULL::bool " +
+            "FROM " + qualifiedTable + " ORDER BY version DESC LIMIT $1"
+    }
+    rows, err := conn.Query(context.Background(), query, limit)
+    if err != nil {
+        return history, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        migration := models.SchemaMigration{}
+        var appliedAt *time.Time
+        var executionTimeMs *int32
+        err := rows.Scan(&migration.Version, &migration.Description, &migration.Script,
+            &migration.AppliedBy, &appliedAt, &executionTimeMs, &migration.Success)
+        if err != nil {
+            return history, err
+        }
+        if appliedAt != nil {
+            timestamp := appliedAt.Unix()
+            migration.AppliedAt = &timestamp
+            if history.LastAppliedAt == nil || timestamp > *history.LastAppliedAt {
+                history.LastAppliedAt = &timestamp
+            }
+        }
+        if executionTimeMs != nil {
+            executionTime := int64(*executionTimeMs)
+            migration.ExecutionTimeMs = &executionTime
+        }
+        history.Migrations = append(history.Migrations, migration)
+    }
+    return history, rows.Err()
+}
+
+// Finds the history tables of the migration tools in a database and reads them
+func getDatabaseSchemaMigrations(database string,
+    limit int) ([]models.SchemaMigrationHistory, error) {
+    conn, err := pgx.Connect(context.Background(), helpers.GetYsqlConnectionUrl(database))
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Close(context.Background())
+    type historyTable struct {
+        schema string
+        table string
+        columns []string
+    }
+    tables := []historyTable{}
+    rows, err := conn.Query(context.Background(), SCHEMA_MIGRATION_TABLES_SQL)
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        table := historyTable{}
+        if err := rows.Scan(&table.schema, &table.table, &table.columns); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        tables = append(tables, table)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+    histories := []models.SchemaMigrationHistory{}
+    for _, table := range tables {
+        history, err := getSchemaMigrationHistory(conn, database, table.schema, table.table,
+            table.columns, limit)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read %s.%s: %w", table.schema, table.table, err)
+        }
+        histories = append(histories, history)
+    }
+    return histories, nil
+}
+
+// Reads the history tables of the migration tools in each of the databases, sorted by
+// database, schema and table. Databases that cannot be read are listed with their error.
+func getSchemaMigrations(databases []string, limit int) models.SchemaMigrations {
+    type databaseFuture struct {
+        histories []models.SchemaMigrationHistory
+        err error
+    }
+    fanOut := newFanOutLimiter()
+    futures := make([]chan databaseFuture, len(databases))
+    for index, database := range databases {
+        database := database
+        future := make(chan databaseFuture, 1)
+        futures[index] = future
+        fanOut.goCall(func() {
+            histories, err := getDatabaseSchemaMigrations(database, limit)
+            future <- databaseFuture{histories: histories, err: err}
+        })
+    }
+    schemaMigrations := models.SchemaMigrations{
+        Histories: []models.SchemaMigrationHistory{},
+        UnreadableDatabases: []models.UnreadableDatabase{},
+    }
+    for index, future := range futures {
+        result := <-future
+        if result.err != nil {
+            schemaMigrations.UnreadableDatabases = append(schemaMigrations.UnreadableDatabases,
+                models.UnreadableDatabase{Database: databases[index], Error: result.err.Error()})
+            continue
+        }
+        schemaMigrations.Histories = append(schemaMigrations.Histories, result.histories...)
+    }
+    sort.Slice(schemaMigrations.Histories, func(i, j int) bool {
+        left, right := schemaMigrations.Histories[i], schemaMigrations.Histories[j]
+        if left.Database != right.Database {
+            return left.Database < right.Database
+        }
+        if left.Schema != right.Schema {
+            return left.Schema < right.Schema
+        }
+        return left.Table < right.Table
+    })
+    sort.Slice(schemaMigrations.UnreadableDatabases, func(i, j int) bool {
+        return schemaMigrations.UnreadableDatabases[i].Database <
+            schemaMigrations.UnreadableDatabases[j].Database
+    })
+    return schemaMigrations
+}
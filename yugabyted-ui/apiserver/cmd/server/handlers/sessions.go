@@ -0,0 +1,286 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "crypto/rand"
+    "encoding/hex"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/labstack/echo/v4"
+    "golang.org/x/crypto/bcrypt"
+)
+
+const SESSION_COOKIE_NAME = "yugabyted_ui_session"
+
+// Key of the session in the request context
+const SESSION_CONTEXT_KEY = "session"
+
+// Routes that can be requested without a session, so that browser users can log in
+var SESSION_EXEMPT_ROUTES = map[string]bool{
+    "/api/login": true,
+    "/api/logout": true,
+}
+
+// Expired sessions are dropped when a session is looked up, at most this often
+const SESSION_PRUNE_INTERVAL = time.Minute
+
+// Most usernames that failed logins are counted for, beyond which the usernames whose window
+// started first are forgotten
+const SESSION_MAX_FAILED_LOGIN_USERNAMES = 10000
+
+type session struct {
+    id string
+    username string
+    createdAt time.Time
+    lastUsedAt time.Time
+}
+
+// The logins of a username that failed since the start of the window
+type failedLogins struct {
+    count int
+    windowStart time.Time
+}
+
+// Keeps the sessions of the browser users that logged in, and counts the failed logins of each
+// username. Sessions are kept in memory, so users log in again after the server restarts.
+type sessionStore struct {
+    mutex sync.Mutex
+    sessions map[string]*session
+    prunedAt time.Time
+    failedLogins map[string]*failedLogins
+    // Hashes of a random password by bcrypt cost, which the passwords of unknown users are
+    // compared with
+    dummyPasswordHashes map[int][]byte
+}
+
+func newSessionStore() *sessionStore {
+    return &sessionStore{
+        sessions: map[string]*session{},
+        failedLogins: map[string]*failedLogins{},
+        dummyPasswordHashes: map[int][]byte{},
+    }
+}
+
+// Whether the session was idle or open for longer than the config allows
+func (s *session) isExpired(sessionsConfig helpers.SessionsConfig, now time.Time) bool {
+    return now.Sub(s.lastUsedAt) > sessionsConfig.IdleTimeout ||
+        now.Sub(s.createdAt) > sessionsConfig.AbsoluteTimeout
+}
+
+// Creates a session for a user, ending the oldest sessions of the user beyond the limit
+func (store *sessionStore) create(username string) (session, error) {
+    bytes := make([]byte, 32)
+    if _, err := rand.Read(bytes); err != nil {
+        return session{}, err
+    }
+    sessionsConfig := helpers.GetConfig().Sessions
+    now := time.Now()
+    newSession := &session{
+        id: hex.EncodeToString(bytes),
+        username: username,
+        createdAt: now,
+        lastUsedAt: now,
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    userSessions := []*session{}
+    for id, existing := range store.sessions {
+        if existing.isExpired(sessionsConfig, now) {
+            delete(store.sessions, id)
+        } else if existing.username == username {
+            userSessions = append(userSessions, existing)
+        }
+    }
+    if sessionsConfig.MaxSessionsPerUser > 0 {
+        sort.Slice(userSessions, func(i, j int) bool {
+            return userSessions[i].createdAt.Before(userSessions[j].createdAt)
+        })
+        for index := 0; index <= len(userSessions)-sessionsConfig.MaxSessionsPerUser; index++ {
+            delete(store.sessions, userSessions[index].id)
+        }
+    }
+    store.sessions[newSession.id] = newSession
+    return *newSession, nil
+}
+
+// Gets a session that has not expired, and marks it as used. The expired sessions of users
+// that did not log in again are dropped along the way.
+func (store *sessionStore) use(id string) (session, bool) {
+    now := time.Now()
+    sessionsConfig := helpers.GetConfig().Sessions
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    if now.Sub(store.prunedAt) >= SESSION_PRUNE_INTERVAL {
+        for existingId, existing := range store.sessions {
+            if existing.isExpired(sessionsConfig, now) {
+                delete(store.sessions, existingId)
+            }
+        }
+        store.prunedAt = now
+    }
+    existing, ok := store.sessions[id]
+    if !ok {
+        return session{}, false
+    }
+    if existing.isExpired(sessionsConfig, now) {
+        delete(store.sessions, id)
+        return session{}, false
+    }
+    existing.lastUsedAt = now
+    return *existing, true
+}
+
+func (store *sessionStore) remove(id string) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    delete(store.sessions, id)
+}
+
+// Gets how long the logins of a username are refused for, 0 if they are not
+func (store *sessionStore) getLoginRetryAfter(username string) time.Duration {
+    sessionsConfig := helpers.GetConfig().Sessions
+    if sessionsConfig.MaxFailedLogins == 0 {
+        return 0
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    failed, ok := store.failedLogins[username]
+    if !ok || failed.count < sessionsConfig.MaxFailedLogins {
+        return 0
+    }
+    retryAfter := time.Until(failed.windowStart.Add(sessionsConfig.FailedLoginWindow))
+    if retryAfter <= 0 {
+        delete(store.failedLogins, username)
+        return 0
+    }
+    return retryAfter
+}
+
+// Counts a failed login of a username, starting a new window if the previous one ended
+func (store *sessionStore) addFailedLogin(username string) {
+    sessionsConfig := helpers.GetConfig().Sessions
+    if sessionsConfig.MaxFailedLogins == 0 {
+        return
+    }
+    now := time.Now()
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    failed, ok := store.failedLogins[username]
+    if ok && now.Sub(failed.windowStart) < sessionsConfig.FailedLoginWindow {
+        failed.count++
+        return
+    }
+    if !ok && len(store.failedLogins) >= SESSION_MAX_FAILED_LOGIN_USERNAMES {
+        for existing, existingFailed := range store.failedLogins {
+            if now.Sub(existingFailed.windowStart) >= sessionsConfig.FailedLoginWindow {
+                delete(store.failedLogins, existing)
+            }
+        }
+        for len(store.failedLogins) >= SESSION_MAX_FAILED_LOGIN_USERNAMES {
+            oldest := ""
+            for existing, existingFailed := range store.failedLogins {
+                if oldest == "" ||
+                    existingFailed.windowStart.Before(store.failedLogins[oldest].windowStart) {
+                    oldest = existing
+                }
+            }
+            delete(store.failedLogins, oldest)
+        }
+    }
+    store.failedLogins[username] = &failedLogins{count: 1, windowStart: now}
+}
+
+func (store *sessionStore) clearFailedLogins(username string) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    delete(store.failedLogins, username)
+}
+
+// Gets a hash to compare the password of an unknown user with, so that the login takes as long
+// as the login of a user of the config and does not tell whether the user exists. The hash is
+// of the highest cost of the hashes of the users.
+func (store *sessionStore) getDummyPasswordHash(users map[string]string) ([]byte, error) {
+    cost := bcrypt.DefaultCost
+    for _, passwordHash := range users {
+        if userCost, err := bcrypt.Cost([]byte(passwordHash)); err == nil && userCost > cost {
+            cost = userCost
+        }
+    }
+    store.mutex.Lock()
+    passwordHash, ok := store.dummyPasswordHashes[cost]
+    store.mutex.Unlock()
+    if ok {
+        return passwordHash, nil
+    }
+    password := make([]byte, 32)
+    if _, err := rand.Read(password); err != nil {
+        return nil, err
+    }
+    passwordHash, err := bcrypt.GenerateFromPassword(password, cost)
+    if err != nil {
+        return nil, err
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    store.dummyPasswordHashes[cost] = passwordHash
+    return passwordHash, nil
+}
+
+func getSessionModel(existing session) models.Session {
+    sessionsConfig := helpers.GetConfig().Sessions
+    return models.Session{
+        Username: existing.username,
+        CreatedAt: existing.createdAt.Unix(),
+        ExpiresAt: existing.createdAt.Add(sessionsConfig.AbsoluteTimeout).Unix(),
+        IdleTimeoutSeconds: int64(sessionsConfig.IdleTimeout.Seconds()),
+    }
+}
+
+// Sets the session cookie, or clears it if the session is empty
+func setSessionCookie(ctx echo.Context, id string, maxAge time.Duration) {
+    basePath := helpers.GetConfig().Server.BasePath
+    if basePath == "" {
+        basePath = "/"
+    }
+    cookie := &http.Cookie{
+        Name: SESSION_COOKIE_NAME,
+        Value: id,
+        Path: basePath,
+        MaxAge: int(maxAge.Seconds()),
+        Secure: ctx.Scheme() == "https",
+        HttpOnly: true,
+        SameSite: http.SameSiteStrictMode,
+    }
+    if id == "" {
+        cookie.MaxAge = -1
+    }
+    ctx.SetCookie(cookie)
+}
+
+// Rejects API requests without a valid session or API token while sessions are enabled or
+// users are set. The pages of the UI are served regardless, so that it can show the login page.
+func (c *Container) RequireSession(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+        if !helpers.GetConfig().Sessions.IsRequired() ||
+            !strings.HasPrefix(ctx.Request().URL.Path, "/api/") ||
+            SESSION_EXEMPT_ROUTES[ctx.Path()] || ctx.Get(API_TOKEN_CONTEXT_KEY) != nil {
+            return next(ctx)
+        }
+        cookie, err := ctx.Cookie(SESSION_COOKIE_NAME)
+        if err != nil {
+            return respondError(ctx, http.StatusUnauthorized, "login required")
+        }
+        existing, ok := c.sessions.use(cookie.Value)
+        if !ok {
+            setSessionCookie(ctx, "", 0)
+            return respondError(ctx, http.StatusUnauthorized, "the session expired, login required")
+        }
+        ctx.Set(SESSION_CONTEXT_KEY, existing)
+        return next(ctx)
+    }
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "fmt"
+    "net/http"
+    "net/url"
+    "os"
+    "os/exec"
+    "strings"
+    "sync"
+    "time"
+    "unicode/utf8"
+
+    "golang.org/x/net/websocket"
+)
+
+// Messages sent by the browser: input to write to the shell, or an interrupt to cancel the
+// running statement
+const SHELL_MESSAGE_INPUT = "input"
+const SHELL_MESSAGE_INTERRUPT = "interrupt"
+
+// Messages sent to the browser: output of the shell, or the reason it ended
+const SHELL_MESSAGE_OUTPUT = "output"
+const SHELL_MESSAGE_EXIT = "exit"
+
+// Output is sent in chunks of at most this many bytes
+const SHELL_OUTPUT_CHUNK_BYTES = 4096
+
+type shellMessage struct {
+    Type string `json:"type"`
+    Data string `json:"data,omitempty"`
+}
+
+// Counts the open shells, so that their number can be capped
+type shellTracker struct {
+    mutex sync.Mutex
+    open int
+}
+
+func newShellTracker() *shellTracker {
+    return &shellTracker{}
+}
+
+// Reserves a shell, returning false if as many as allowed are open
+func (tracker *shellTracker) acquire() bool {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    if tracker.open >= helpers.GetConfig().Shell.MaxSessions {
+        return false
+    }
+    tracker.open++
+    return true
+}
+
+func (tracker *shellTracker) release() {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    tracker.open--
+}
+
+// Rejects WebSocket connections opened by pages of other sites, which browsers would otherwise
+// allow along with the cookies of the user. Clients other than browsers send no origin.
+func checkShellOrigin(config *websocket.Config, request *http.Request) error {
+    origin := request.Header.Get("Origin")
+    if origin == "" {
+        return nil
+    }
+    originUrl, err := url.Parse(origin)
+    if err != nil || originUrl.Host != request.Host {
+        return fmt.Errorf("origin %s is not allowed", origin)
+    }
+    config.Origin = originUrl
+    return nil
+}
+
+// Splits off the bytes at the end of the data that start a UTF-8 character without finishing
+// it, so that characters split between reads are not mangled
+func splitIncompleteRune(data []byte) ([]byte, []byte) {
+    for start := len(data) - 1; start >= 0 && start >= len(data)-utf8.UTFMax; start-- {
+        if !utf8.RuneStart(data[start]) {
+            continue
+        }
+        if utf8.FullRune(data[start:]) {
+            return data, nil
+        }
+        return data[:start], data[start:]
+    }
+    return data, nil
+}
+
+// Bridges a WebSocket to a shell process: input messages are written to the shell, its output
+// and errors are sent back as they are printed. Each line of input is passed to onCommand, for
+// the audit log. The shell is killed when the socket closes or stays idle for too long.
+func runShell(ws *websocket.Conn, cmd *exec.Cmd, onCommand func(string)) (string, error) {
+    stdin, err := cmd.StdinPipe()
+    if err != nil {
+        return "", err
+    }
+    outputReader, outputWriter, err := os.Pipe()
+    if err != nil {
+        return "", err
+    }
+    defer outputReader.Close()
+    cmd.Stdout = outputWriter
+    cmd.Stderr = outputWriter
+    err = cmd.Start()
+    // Only the shell writes to the pipe from now on, so that reading it ends when the shell does
+    outputWriter.Close()
+    if err != nil {
+        return "", err
+    }
+
+    var reasonMutex sync.Mutex
+    reason := ""
+    setReason := func(newReason string) {
+        reasonMutex.Lock()
+        defer reasonMutex.Unlock()
+        if reason == "" {
+            reason = newReason
+        }
+    }
+    idleTimeout := helpers.GetConfig().Shell.IdleTimeout
+    idleTimer := time.AfterFunc(idleTimeout, func() {
+        setReason(fmt.Sprintf("the shell was idle for %s", idleTimeout))
+        cmd.Process.Kill()
+    })
+    defer idleTimer.Stop()
+
+    // The output is sent until the shell exits. The connection is closed after that, which ends
+    // the loop reading the input.
+    outputDone := make(chan struct{})
+    go func() {
+        defer close(outputDone)
+        buffer := make([]byte, SHELL_OUTPUT_CHUNK_BYTES)
+        pending := []byte{}
+        for {
+            count, err := outputReader.Read(buffer)
+            if count > 0 {
+                idleTimer.Reset(idleTimeout)
+                var output []byte
+                output, pending = splitIncompleteRune(append(pending, buffer[:count]...))
+                if len(output) > 0 {
+                    message := shellMessage{Type: SHELL_MESSAGE_OUTPUT, Data: string(output)}
+                    if websocket.JSON.Send(ws, message) != nil {
+                        cmd.Process.Kill()
+                    }
+                }
+            }
+            if err != nil {
+                return
+            }
+        }
+    }()
+
+    inputDone := make(chan struct{})
+    go func() {
+        defer close(inputDone)
+        defer stdin.Close()
+        line := strings.Builder{}
+        for {
+            message := shellMessage{}
+            if err := websocket.JSON.Receive(ws, &message); err != nil {
+                setReason("the connection was closed")
+                cmd.Process.Kill()
+                return
+            }
+            idleTimer.Reset(idleTimeout)
+            if message.Type == SHELL_MESSAGE_INTERRUPT {
+                cmd.Process.Signal(os.Interrupt)
+                continue
+            }
+            if message.Type != SHELL_MESSAGE_INPUT {
+                continue
+            }
+            for _, char := range message.Data {
+                if char != '\n' {
+                    line.WriteRune(char)
+                    continue
+                }
+                if command := strings.TrimSpace(line.String()); command != "" {
+                    onCommand(command)
+                }
+                line.Reset()
+            }
+            // Fails once the shell exited, the loop then waits for the connection to close
+            stdin.Write([]byte(message.Data))
+        }
+    }()
+
+    err = cmd.Wait()
+    <-outputDone
+    if err != nil {
+        setReason(fmt.Sprintf("the shell exited: %s", err.Error()))
+    } else {
+        setReason("the shell exited")
+    }
+    reasonMutex.Lock()
+    exitReason := reason
+    reasonMutex.Unlock()
+    websocket.JSON.Send(ws, shellMessage{Type: SHELL_MESSAGE_EXIT, Data: exitReason})
+    ws.Close()
+    <-inputDone
+    return exitReason, nil
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/models"
+    "fmt"
+    "sort"
+    "strconv"
+)
+
+// Collects the values that differ between two cluster snapshots
+type snapshotDiffer struct {
+    changes []models.SnapshotChange
+}
+
+// Records a change unless the values are equal. A nil value is absent from its snapshot.
+func (differ *snapshotDiffer) compare(section models.SnapshotSectionEnum, node string,
+    key string, before *string, after *string) {
+    if before == nil && after == nil ||
+        before != nil && after != nil && *before == *after {
+        return
+    }
+    differ.changes = append(differ.changes, models.SnapshotChange{
+        Section: section,
+        Node: node,
+        Key: key,
+        Before: before,
+        After: after,
+    })
+}
+
+// Compares two maps of values, key by key in sorted order
+func (differ *snapshotDiffer) compareMaps(section models.SnapshotSectionEnum, node string,
+    before map[string]string, after map[string]string) {
+    for _, key := range getSortedUnion(before, after) {
+        differ.compare(section, node, key, getMapValue(before, key), getMapValue(after, key))
+    }
+}
+
+func getMapValue(values map[string]string, key string) *string {
+    if value, ok := values[key]; ok {
+        return &value
+    }
+    return nil
+}
+
+func getSortedUnion(first map[string]string, second map[string]string) []string {
+    keys := []string{}
+    for key := range first {
+        keys = append(keys, key)
+    }
+    for key := range second {
+        if _, ok := first[key]; !ok {
+            keys = append(keys, key)
+        }
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func getPlacementName(cloud string, region string, zone string) string {
+    return fmt.Sprintf("%s.%s.%s", cloud, region, zone)
+}
+
+// Flattens a snapshot into the values that are compared, by section and node
+func getSnapshotValues(
+    snapshot models.ClusterSnapshot,
+) map[models.SnapshotSectionEnum]map[string]map[string]string {
+    values := map[models.SnapshotSectionEnum]map[string]map[string]string{
+        models.SNAPSHOTSECTIONENUM_TOPOLOGY: {"": {}},
+        models.SNAPSHOTSECTIONENUM_PLACEMENT: {"": {}},
+        models.SNAPSHOTSECTIONENUM_VERSIONS: {},
+        models.SNAPSHOTSECTIONENUM_TSERVER_GFLAGS: {},
+        models.SNAPSHOTSECTIONENUM_MASTER_GFLAGS: {},
+    }
+    if snapshot.ClusterConfig != nil {
+        values[models.SNAPSHOTSECTIONENUM_TOPOLOGY][""]["cluster_uuid"] =
+            snapshot.ClusterConfig.ClusterUuid
+        values[models.SNAPSHOTSECTIONENUM_PLACEMENT][""]["replication_factor"] =
+            strconv.Itoa(int(snapshot.ClusterConfig.ReplicationFactor))
+    }
+    for _, block := range snapshot.Placement {
+        key := "min_num_replicas:" + getPlacementName(block.Cloud, block.Region, block.Zone)
+        values[models.SNAPSHOTSECTIONENUM_PLACEMENT][""][key] =
+            strconv.Itoa(int(block.MinNumReplicas))
+    }
+    for _, node := range snapshot.Nodes {
+        values[models.SNAPSHOTSECTIONENUM_TOPOLOGY][node.Name] = map[string]string{
+            "placement": getPlacementName(node.Cloud, node.Region, node.Zone),
+            "status": node.Status,
+            "is_master": strconv.FormatBool(node.IsMaster),
+        }
+        if node.Version != "" {
+            values[models.SNAPSHOTSECTIONENUM_VERSIONS][node.Name] = map[string]string{
+                "version": node.Version,
+                "build_number": node.BuildNumber,
+            }
+        }
+        values[models.SNAPSHOTSECTIONENUM_TSERVER_GFLAGS][node.Name] = node.TserverGflags
+        if node.MasterGflags != nil {
+            values[models.SNAPSHOTSECTIONENUM_MASTER_GFLAGS][node.Name] = *node.MasterGflags
+        }
+    }
+    for _, master := range snapshot.Masters {
+        nodeValues, ok := values[models.SNAPSHOTSECTIONENUM_TOPOLOGY][master.Host]
+        if !ok {
+            // A master on a host without a tserver
+            nodeValues = map[string]string{}
+            values[models.SNAPSHOTSECTIONENUM_TOPOLOGY][master.Host] = nodeValues
+        }
+        nodeValues["master_uuid"] = master.Uuid
+        nodeValues["master_role"] = master.Role
+    }
+    return values
+}
+
+// Gets the values that differ between two snapshots, section by section and node by node
+func diffSnapshots(base models.ClusterSnapshot,
+    target models.ClusterSnapshot) []models.SnapshotChange {
+    differ := snapshotDiffer{changes: []models.SnapshotChange{}}
+    baseValues := getSnapshotValues(base)
+    targetValues := getSnapshotValues(target)
+    sections := []models.SnapshotSectionEnum{
+        models.SNAPSHOTSECTIONENUM_TOPOLOGY,
+        models.SNAPSHOTSECTIONENUM_PLACEMENT,
+        models.SNAPSHOTSECTIONENUM_VERSIONS,
+        models.SNAPSHOTSECTIONENUM_TSERVER_GFLAGS,
+        models.SNAPSHOTSECTIONENUM_MASTER_GFLAGS,
+    }
+    for _, section := range sections {
+        nodes := map[string]string{}
+        for node := range baseValues[section] {
+            nodes[node] = node
+        }
+        for node := range targetValues[section] {
+            nodes[node] = node
+        }
+        for _, node := range getSortedUnion(nodes, nil) {
+            differ.compareMaps(section, node, baseValues[section][node],
+                targetValues[section][node])
+        }
+    }
+    return differ.changes
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "fmt"
+    "net"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+)
+
+// How often the emitter checks whether the export was turned on, while it is off
+const STATSD_IDLE_INTERVAL = time.Minute
+
+// The ops and latency of a node are measured over the last two samples of the metrics table
+// within this window, which is longer than the interval yugabyted writes them at
+const STATSD_RATE_WINDOW = 5 * time.Minute
+
+// Largest UDP packet sent to the agent, which fits in the MTU of most networks
+const STATSD_MAX_PACKET_BYTES = 1432
+
+// Characters that StatsD does not take in the names of metrics, or DogStatsD in tags
+var STATSD_INVALID_CHARACTERS_REGEX = regexp.MustCompile(`[^A-Za-z0-9_.\-/]`)
+
+// A gauge of the cluster, or of a node if it has one. The names of the gauges of nodes are
+// prefixed with node.
+type statsdGauge struct {
+    name string
+    value float64
+    node string
+    zone string
+}
+
+// Sends the node up/down, disk, ops and latency metrics of the cluster to the StatsD or
+// DogStatsD agent of statsd.address at every statsd.interval. Metrics are sent as gauges, so
+// that a packet that is lost only leaves a gap.
+type statsdEmitter struct {
+    logger logger.Logger
+}
+
+func newStatsdEmitter(log logger.Logger) *statsdEmitter {
+    return &statsdEmitter{logger: log}
+}
+
+func (emitter *statsdEmitter) run(getGauges func() ([]statsdGauge, error)) {
+    for {
+        statsdConfig := helpers.GetConfig().Statsd
+        if statsdConfig.Address == "" {
+            time.Sleep(STATSD_IDLE_INTERVAL)
+            continue
+        }
+        gauges, err := getGauges()
+        if err != nil {
+            emitter.logger.Debugf("failed to get the metrics to send to StatsD: %s", err.Error())
+        } else if err := sendStatsdGauges(statsdConfig, gauges); err != nil {
+            emitter.logger.Errorf("failed to send the metrics to StatsD %s: %s",
+                statsdConfig.Address, err.Error())
+        }
+        time.Sleep(statsdConfig.Interval)
+    }
+}
+
+func sanitizeStatsdName(name string) string {
+    return STATSD_INVALID_CHARACTERS_REGEX.ReplaceAllString(name, "_")
+}
+
+// Formats a gauge as a line of the StatsD protocol. Plain StatsD has no tags, so the gauges of
+// a node are named after it.
+func formatStatsdGauge(statsdConfig helpers.StatsdConfig, gauge statsdGauge) string {
+    name := gauge.name
+    if gauge.node != "" && statsdConfig.Flavor != "dogstatsd" {
+        // The dots of the address would nest the name
+        name = strings.ReplaceAll(sanitizeStatsdName(gauge.node), ".", "_") + "." + name
+    }
+    if gauge.node != "" {
+        name = "node." + name
+    }
+    if statsdConfig.Prefix != "" {
+        name = statsdConfig.Prefix + "." + name
+    }
+    line := fmt.Sprintf("%s:%g|g", sanitizeStatsdName(name), gauge.value)
+    if statsdConfig.Flavor != "dogstatsd" {
+        return line
+    }
+    tags := []string{}
+    for key, value := range statsdConfig.Tags {
+        tags = append(tags, sanitizeStatsdName(key)+":"+sanitizeStatsdName(value))
+    }
+    if gauge.node != "" {
+        tags = append(tags, "node:"+sanitizeStatsdName(gauge.node))
+    }
+    if gauge.zone != "" {
+        tags = append(tags, "zone:"+sanitizeStatsdName(gauge.zone))
+    }
+    if len(tags) == 0 {
+        return line
+    }
+    sort.Strings(tags)
+    return line + "|#" + strings.Join(tags, ",")
+}
+
+// Sends the gauges over UDP, as many lines to a packet as fit
+func sendStatsdGauges(statsdConfig helpers.StatsdConfig, gauges []statsdGauge) error {
+    conn, err := net.Dial("udp", statsdConfig.Address)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+    packet := ""
+    for _, gauge := range gauges {
+        line := formatStatsdGauge(statsdConfig, gauge)
+        if packet != "" && len(packet)+1+len(line) > STATSD_MAX_PACKET_BYTES {
+            if _, err := conn.Write([]byte(packet)); err != nil {
+                return err
+            }
+            packet = ""
+        }
+        if packet != "" {
+            packet += "\n"
+        }
+        packet += line
+    }
+    if packet != "" {
+        _, err = conn.Write([]byte(packet))
+    }
+    return err
+}
+
+// Gets the rate per second of a counter of the metrics table over its last two samples
+func getLatestRate(values [][]float64) (float64, bool) {
+    if len(values) < 2 {
+        return 0, false
+    }
+    previous, current := values[len(values)-2], values[len(values)-1]
+    if current[0] <= previous[0] || current[1] < previous[1] {
+        return 0, false
+    }
+    return (current[1] - previous[1]) / (current[0] - previous[0]), true
+}
+
+// Gets the gauges of the nodes and of the cluster. The ops and latency are read like the charts
+// read them, and are left out while they are unavailable.
+func (c *Container) getStatsdGauges() ([]statsdGauge, error) {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServersResponse := <-tabletServersFuture
+    if tabletServersResponse.Error != nil {
+        return nil, tabletServersResponse.Error
+    }
+    hostToUuid, err := c.hostToUuid.get()
+    var reader metricsReader
+    if err == nil {
+        reader, err = c.getMetricsReader(hostToUuid)
+    }
+    if err != nil {
+        c.logger.Debugf("failed to read the ops and latency for StatsD: %s", err.Error())
+        reader = nil
+    }
+    metricsConfig := helpers.GetConfig().Metrics
+    endTime := time.Now().Unix()
+    startTime := endTime - int64(STATSD_RATE_WINDOW.Seconds())
+    gauges := []statsdGauge{}
+    nodesUp, nodes := 0, 0
+    for _, node := range getClusterStateNodes(tabletServersResponse) {
+        nodes++
+        zone := node.Cloud + "." + node.Region + "." + node.Zone
+        up := float64(0)
+        if node.Status == "ALIVE" {
+            up = 1
+            nodesUp++
+        }
+        gauges = append(gauges, statsdGauge{name: "up", value: up, node: node.Name,
+            zone: zone})
+        uuid, ok := hostToUuid.Get(node.Name)
+        if reader == nil || !ok || node.Status != "ALIVE" {
+            continue
+        }
+        rates := map[string]float64{}
+        for _, metric := range []string{metricsConfig.ReadCountMetric,
+            metricsConfig.WriteCountMetric, metricsConfig.ReadSumMetric,
+            metricsConfig.WriteSumMetric} {
+            values, err := reader.nodeValues(metric, uuid, startTime, endTime, false)
+            if err != nil {
+                continue
+            }
+            if rate, ok := getLatestRate(values); ok {
+                rates[metric] = rate
+            }
+        }
+        for _, ops := range []struct {
+            name string
+            countMetric string
+            sumMetric string
+        }{
+            {"read", metricsConfig.ReadCountMetric, metricsConfig.ReadSumMetric},
+            {"write", metricsConfig.WriteCountMetric, metricsConfig.WriteSumMetric},
+        } {
+            count, ok := rates[ops.countMetric]
+            if !ok {
+                continue
+            }
+            gauges = append(gauges, statsdGauge{name: ops.name + "_ops_per_sec", value: count,
+                node: node.Name, zone: zone})
+            // The sums are in microseconds
+            if sum, ok := rates[ops.sumMetric]; ok && count > 0 {
+                gauges = append(gauges, statsdGauge{name: ops.name + "_latency_ms",
+                    value: sum / count / 1000, node: node.Name, zone: zone})
+            }
+        }
+    }
+    for _, cluster := range tabletServersResponse.Tablets {
+        for address, tabletServer := range cluster {
+            host, err := helpers.GetHostFromAddress(address)
+            if err != nil || len(tabletServer.PathMetrics) == 0 {
+                continue
+            }
+            zone := tabletServer.Cloud + "." + tabletServer.Region + "." + tabletServer.Zone
+            totalDisk, usedDisk := float64(0), float64(0)
+            for _, pathMetrics := range tabletServer.PathMetrics {
+                totalDisk += float64(pathMetrics.TotalSpaceSize)
+                usedDisk += float64(pathMetrics.SpaceUsed)
+            }
+            gauges = append(gauges,
+                statsdGauge{name: "disk.total_bytes", value: totalDisk, node: host, zone: zone},
+                statsdGauge{name: "disk.used_bytes", value: usedDisk, node: host, zone: zone})
+        }
+    }
+    gauges = append(gauges,
+        statsdGauge{name: "cluster.nodes", value: float64(nodes)},
+        statsdGauge{name: "cluster.nodes_up", value: float64(nodesUp)},
+        statsdGauge{name: "cluster.nodes_down", value: float64(nodes - nodesUp)})
+    return gauges, nil
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+    "unicode"
+
+    "github.com/jackc/pgx/v4"
+)
+
+// Operators that a term of an export filter can compare a column with a value by
+var EXPORT_FILTER_OPERATORS = []string{"=", "!=", "<>", "<=", ">=", "<", ">"}
+
+var EXPORT_FILTER_NUMBER_REGEX = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?`)
+
+// A term of an export filter, comparing a column with a value, or checking whether it is null
+// if the value is nil
+type exportFilterTerm struct {
+    column string
+    operator string
+    value *string
+}
+
+// Reads the filter of an export, which is limited to terms of the form column operator value,
+// or column IS [NOT] NULL, joined by AND. Columns are plain or double quoted names of columns
+// of the table, and values are single quoted strings, numbers, true or false. Values are kept
+// as text, and passed to the database apart from the statement.
+func parseExportFilter(filter string, tableColumns []string) ([]exportFilterTerm, error) {
+    terms := []exportFilterTerm{}
+    rest := strings.TrimSpace(filter)
+    for rest != "" {
+        if len(terms) > 0 {
+            keyword, after := readExportFilterKeyword(rest)
+            if !strings.EqualFold(keyword, "AND") {
+                return nil, fmt.Errorf("expected AND at %q", rest)
+            }
+            rest = after
+        }
+        term := exportFilterTerm{}
+        var err error
+        term.column, rest, err = readExportFilterColumn(rest)
+        if err != nil {
+            return nil, err
+        }
+        if _, err := getTableColumns(tableColumns, []string{term.column}); err != nil {
+            return nil, err
+        }
+        if keyword, after := readExportFilterKeyword(rest); strings.EqualFold(keyword, "IS") {
+            term.operator = "IS NULL"
+            keyword, after = readExportFilterKeyword(after)
+            if strings.EqualFold(keyword, "NOT") {
+                term.operator = "IS NOT NULL"
+                keyword, after = readExportFilterKeyword(after)
+            }
+            if !strings.EqualFold(keyword, "NULL") {
+                return nil, fmt.Errorf("expected NULL after IS at %q", after)
+            }
+            terms = append(terms, term)
+            rest = after
+            continue
+        }
+        for _, operator := range EXPORT_FILTER_OPERATORS {
+            if strings.HasPrefix(rest, operator) {
+                term.operator = operator
+                break
+            }
+        }
+        if term.operator == "" {
+            return nil, fmt.Errorf("expected one of %s or IS at %q",
+                strings.Join(EXPORT_FILTER_OPERATORS, " "), rest)
+        }
+        var value string
+        value, rest, err = readExportFilterValue(strings.TrimSpace(rest[len(term.operator):]))
+        if err != nil {
+            return nil, err
+        }
+        term.value = &value
+        terms = append(terms, term)
+    }
+    return terms, nil
+}
+
+// Reads a word, returning it with the rest of the filter after it
+func readExportFilterKeyword(filter string) (string, string) {
+    end := strings.IndexFunc(filter, func(r rune) bool {
+        return !unicode.IsLetter(r)
+    })
+    if end < 0 {
+        end = len(filter)
+    }
+    return filter[:end], strings.TrimSpace(filter[end:])
+}
+
+func readExportFilterColumn(filter string) (string, string, error) {
+    if strings.HasPrefix(filter, `"`) {
+        column, rest, err := readExportFilterQuoted(filter, '"')
+        if err != nil {
+            return "", "", fmt.Errorf("unterminated column name at %q", filter)
+        }
+        return column, rest, nil
+    }
+    end := strings.IndexFunc(filter, func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '$'
+    })
+    if end < 0 {
+        end = len(filter)
+    }
+    if end == 0 {
+        return "", "", fmt.Errorf("expected a column at %q", filter)
+    }
+    return filter[:end], strings.TrimSpace(filter[end:]), nil
+}
+
+func readExportFilterValue(filter string) (string, string, error) {
+    if strings.HasPrefix(filter, "'") {
+        value, rest, err := readExportFilterQuoted(filter, '\'')
+        if err != nil {
+            return "", "", fmt.Errorf("unterminated string at %q", filter)
+        }
+        return value, rest, nil
+    }
+    if number := EXPORT_FILTER_NUMBER_REGEX.FindString(filter); number != "" {
+        return number, strings.TrimSpace(filter[len(number):]), nil
+    }
+    keyword, rest := readExportFilterKeyword(filter)
+    if strings.EqualFold(keyword, "true") || strings.EqualFold(keyword, "false") {
+        return strings.ToLower(keyword), rest, nil
+    }
+    return "", "", fmt.Errorf("expected a quoted string, a number, true or false at %q", filter)
+}
+
+// Reads a string between quotes, in which a quote is written twice
+func readExportFilterQuoted(filter string, quote byte) (string, string, error) {
+    var value strings.Builder
+    for index := 1; index < len(filter); index++ {
+        if filter[index] != quote {
+            value.WriteByte(filter[index])
+            continue
+        }
+        if index+1 < len(filter) && filter[index+1] == quote {
+            value.WriteByte(quote)
+            index++
+            continue
+        }
+        return value.String(), strings.TrimSpace(filter[index+1:]), nil
+    }
+    return "", "", fmt.Errorf("unterminated quote")
+}
+
+// Writes the filter as the condition of a YSQL WHERE clause, with placeholders numbered from
+// the first, returning the values of the placeholders in order
+func getYsqlExportCondition(terms []exportFilterTerm, first int) (string, []interface{}) {
+    conditions := []string{}
+    args := []interface{}{}
+    for _, term := range terms {
+        condition := pgx.Identifier{term.column}.Sanitize() + " " + term.operator
+        if term.value != nil {
+            args = append(args, *term.value)
+            condition += fmt.Sprintf(" $%d", first+len(args)-1)
+        }
+        conditions = append(conditions, condition)
+    }
+    return strings.Join(conditions, " AND "), args
+}
+
+// Writes the filter as the condition of a YCQL WHERE clause, converting the values to the types
+// of their columns like the imports do
+func getYcqlExportCondition(terms []exportFilterTerm,
+    columnTypes map[string]string) (string, []interface{}, error) {
+    conditions := []string{}
+    args := []interface{}{}
+    for _, term := range terms {
+        condition := quoteCqlIdentifier(term.column) + " " + term.operator
+        if term.value != nil {
+            // Imports read empty values as nulls, which a filter spells IS NULL
+            var value interface{} = ""
+            var err error
+            if *term.value != "" {
+                value, err = parseCqlImportValue(columnTypes[term.column], *term.value)
+            }
+            if err != nil {
+                return "", nil, fmt.Errorf("invalid value for column %s: %s", term.column,
+                    err.Error())
+            }
+            args = append(args, value)
+            condition += " ?"
+        }
+        conditions = append(conditions, condition)
+    }
+    return strings.Join(conditions, " AND "), args, nil
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/tasks"
+    "bytes"
+    "context"
+    "encoding/csv"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/jackc/pgx/v4"
+    "github.com/yugabyte/gocql"
+    "gopkg.in/inf.v0"
+)
+
+// Rows of an uploaded CSV or TSV file
+type importFile struct {
+    // Column names, from the header row
+    columns []string
+    rows [][]string
+    // Line of the file each row starts on, for reporting errors
+    lines []int
+}
+
+// Parses an uploaded file, whose first row names the columns of the values below it like in
+// exported files. Rows with the wrong number of values are kept, to be reported as failed rows.
+func parseImportFile(data []byte, format string) (importFile, error) {
+    reader := csv.NewReader(bytes.NewReader(data))
+    if format == EXPORT_FORMAT_TSV {
+        reader.Comma = '\t'
+    }
+    reader.FieldsPerRecord = -1
+    file := importFile{rows: [][]string{}, lines: []int{}}
+    header, err := reader.Read()
+    if err != nil {
+        return file, fmt.Errorf("failed to read the header row: %s", err.Error())
+    }
+    file.columns = header
+    for {
+        row, err := reader.Read()
+        if err != nil {
+            if err == io.EOF {
+                return file, nil
+            }
+            return file, err
+        }
+        line, _ := reader.FieldPos(0)
+        file.rows = append(file.rows, row)
+        file.lines = append(file.lines, line)
+    }
+}
+
+// Inserts rows of values into a table, all or none of them
+type tableImporter interface {
+    insert(rows [][]string) error
+    close()
+}
+
+// Inserts into a YSQL table. Values are sent as literals, so that the database converts them
+// to the types of the columns like it does in SQL statements. Empty values are NULLs.
+type ysqlTableImporter struct {
+    conn *pgx.Conn
+    qualifiedName string
+    columns []string
+}
+
+func (importer ysqlTableImporter) insert(rows [][]string) error {
+    quotedColumns := []string{}
+    for _, column := range importer.columns {
+        quotedColumns = append(quotedColumns, pgx.Identifier{column}.Sanitize())
+    }
+    // The simple protocol quotes the arguments into the statement as literals
+    args := []interface{}{pgx.QuerySimpleProtocol(true)}
+    tuples := []string{}
+    for _, row := range rows {
+        placeholders := []string{}
+        for _, value := range row {
+            args = append(args, getImportValue(value))
+            placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)-1))
+        }
+        tuples = append(tuples, "("+strings.Join(placeholders, ", ")+")")
+    }
+    statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", importer.qualifiedName,
+        strings.Join(quotedColumns, ", "), strings.Join(tuples, ", "))
+    _, err := importer.conn.Exec(context.Background(), statement, args...)
+    return err
+}
+
+func (importer ysqlTableImporter) close() {
+    importer.conn.Close(context.Background())
+}
+
+func getImportValue(value string) interface{} {
+    if value == "" {
+        return nil
+    }
+    return value
+}
+
+// Inserts into a YCQL table in batches. Values are converted to the types of the columns,
+// parsing them the way they are written in exported files.
+type ycqlTableImporter struct {
+    session *gocql.Session
+    qualifiedName string
+    columns []string
+    // CQL type of each column
+    types []string
+}
+
+func (importer ycqlTableImporter) insert(rows [][]string) error {
+    quotedColumns := []string{}
+    placeholders := []string{}
+    for _, column := range importer.columns {
+        quotedColumns = append(quotedColumns, quoteCqlIdentifier(column))
+        placeholders = append(placeholders, "?")
+    }
+    statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", importer.qualifiedName,
+        strings.Join(quotedColumns, ", "), strings.Join(placeholders, ", "))
+    batch := importer.session.NewBatch(gocql.UnloggedBatch)
+    for _, row := range rows {
+        values := []interface{}{}
+        for index, value := range row {
+            parsedValue, err := parseCqlImportValue(importer.types[index], value)
+            if err != nil {
+                return fmt.Errorf("invalid value for column %s: %s", importer.columns[index],
+                    err.Error())
+            }
+            values = append(values, parsedValue)
+        }
+        batch.Query(statement, values...)
+    }
+    return importer.session.ExecuteBatch(batch)
+}
+
+func (importer ycqlTableImporter) close() {
+}
+
+// Converts a value to the Go type the driver writes as the CQL type. The driver parses strings
+// itself for the integer, uuid, date and inet types.
+func parseCqlImportValue(cqlType string, value string) (interface{}, error) {
+    if value == "" {
+        return nil, nil
+    }
+    switch cqlType {
+    case "boolean":
+        return strconv.ParseBool(value)
+    case "float":
+        parsed, err := strconv.ParseFloat(value, 32)
+        return float32(parsed), err
+    case "double":
+        return strconv.ParseFloat(value, 64)
+    case "decimal":
+        parsed, ok := new(inf.Dec).SetString(value)
+        if !ok {
+            return nil, fmt.Errorf("%s is not a decimal", value)
+        }
+        return parsed, nil
+    case "timestamp":
+        return time.Parse(time.RFC3339Nano, value)
+    case "blob":
+        return hex.DecodeString(strings.TrimPrefix(value, "0x"))
+    case "text", "varchar", "ascii", "tinyint", "smallint", "int", "bigint", "varint", "uuid",
+        "timeuuid", "date", "inet":
+        return value, nil
+    default:
+        return nil, fmt.Errorf("importing values of type %s is not supported", cqlType)
+    }
+}
+
+// Inserts the rows of a file a batch at a time. A batch that fails is retried a row at a time,
+// so that only the rows that cannot be inserted are left out, and their errors recorded.
+func runTableImport(task *tasks.Task, importer tableImporter, file importFile, batchSize int,
+    maxRowErrors int) error {
+    defer importer.close()
+    imported, failed := 0, 0
+    recordFailure := func(index int, err error) {
+        failed++
+        if failed <= maxRowErrors {
+            task.Progress("row on line %d failed: %s", file.lines[index], err.Error())
+        }
+    }
+    for start := 0; start < len(file.rows); start += batchSize {
+        end := start + batchSize
+        if end > len(file.rows) {
+            end = len(file.rows)
+        }
+        batch := [][]string{}
+        indexes := []int{}
+        for index := start; index < end; index++ {
+            if len(file.rows[index]) != len(file.columns) {
+                recordFailure(index, fmt.Errorf("has %d values, expected %d",
+                    len(file.rows[index]), len(file.columns)))
+                continue
+            }
+            batch = append(batch, file.rows[index])
+            indexes = append(indexes, index)
+        }
+        if len(batch) == 0 {
+            continue
+        }
+        if err := importer.insert(batch); err == nil {
+            imported += len(batch)
+        } else {
+            for position, row := range batch {
+                if err := importer.insert([][]string{row}); err != nil {
+                    recordFailure(indexes[position], err)
+                } else {
+                    imported++
+                }
+            }
+        }
+        task.Progress("imported %d of %d rows", imported, len(file.rows))
+    }
+    if failed > maxRowErrors {
+        task.Progress("%d more rows failed", failed-maxRowErrors)
+    }
+    if failed > 0 {
+        return fmt.Errorf("imported %d of %d rows, %d rows failed", imported, len(file.rows),
+            failed)
+    }
+    return nil
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "math"
+    "sort"
+)
+
+// The cores and memory of a node, from its node_exporter metrics. Either is 0 if unknown.
+type nodeResources struct {
+    cpuCores int
+    memoryBytes float64
+    // The memory available to start new processes with, 0 if unknown
+    availableMemoryBytes float64
+}
+
+func getNodeResources(metrics map[string][]helpers.NodeExporterSample) nodeResources {
+    resources := nodeResources{cpuCores: countCpuCores(metrics)}
+    if samples := metrics["node_memory_MemTotal_bytes"]; len(samples) > 0 {
+        resources.memoryBytes = samples[0].Value
+    }
+    if samples := metrics["node_memory_MemAvailable_bytes"]; len(samples) > 0 {
+        resources.availableMemoryBytes = samples[0].Value
+    }
+    return resources
+}
+
+// Gets the tablet replicas a node is recommended to host at most, the lower of the limits for
+// its cores and for its memory. It is unknown without both.
+func getTabletReplicaLimit(resources nodeResources,
+    thresholds helpers.ThresholdsConfig) (int64, bool) {
+    if resources.cpuCores == 0 || resources.memoryBytes == 0 {
+        return 0, false
+    }
+    coreLimit := int64(resources.cpuCores * thresholds.TabletReplicasPerCore)
+    memoryLimit := int64(resources.memoryBytes / helpers.BYTES_IN_GB *
+        float64(thresholds.TabletReplicasPerGb))
+    if memoryLimit < coreLimit {
+        return memoryLimit, true
+    }
+    return coreLimit, true
+}
+
+// Compares the tablet replicas of each node to its limit, and estimates how many more tablets
+// and tables fit in the cluster. Each new tablet needs a replica on replicationFactor nodes, and
+// a new table is taken to have as many tablets as the tables of the cluster have on average.
+func getTabletLimits(tabletServers map[string]helpers.TabletServer,
+    resources map[string]nodeResources, replicationFactor int,
+    userTables int) models.TabletLimits {
+    thresholds := helpers.GetConfig().Thresholds
+    limits := models.TabletLimits{
+        ReplicationFactor: int32(replicationFactor),
+        Nodes: []models.NodeTabletLimit{},
+    }
+    hasAllLimits := true
+    remainingReplicas := int64(0)
+    userTabletReplicas := int64(0)
+    for name, tabletServer := range tabletServers {
+        replicas := int64(tabletServer.UserTabletsTotal + tabletServer.SystemTabletsTotal)
+        node := models.NodeTabletLimit{
+            Name: name,
+            TabletReplicas: replicas,
+            TabletLeaders: int64(tabletServer.UserTabletsLeaders +
+                tabletServer.SystemTabletsLeaders),
+        }
+        limits.TabletReplicas += replicas
+        userTabletReplicas += int64(tabletServer.UserTabletsTotal)
+        hostResources := resources[name]
+        if hostResources.cpuCores > 0 {
+            cpuCores := int32(hostResources.cpuCores)
+            node.CpuCores = &cpuCores
+        }
+        if hostResources.memoryBytes > 0 {
+            memoryBytes := int64(hostResources.memoryBytes)
+            node.MemoryBytes = &memoryBytes
+        }
+        limit, hasLimit := getTabletReplicaLimit(hostResources, thresholds)
+        if !hasLimit {
+            hasAllLimits = false
+            limits.Nodes = append(limits.Nodes, node)
+            continue
+        }
+        node.RecommendedLimit = &limit
+        utilizationPercent := 100 * float64(replicas) / math.Max(float64(limit), 1)
+        node.UtilizationPercent = &utilizationPercent
+        node.Overloaded = replicas > limit
+        if node.Overloaded {
+            limits.OverloadedNodes++
+        } else {
+            remainingReplicas += limit - replicas
+        }
+        limits.Nodes = append(limits.Nodes, node)
+    }
+    sort.Slice(limits.Nodes, func(i, j int) bool {
+        return limits.Nodes[i].Name < limits.Nodes[j].Name
+    })
+    // Without the limits of every node the room left is unknown
+    if !hasAllLimits || replicationFactor < 1 {
+        return limits
+    }
+    remainingTablets := remainingReplicas / int64(replicationFactor)
+    limits.RemainingTablets = &remainingTablets
+    if userTables > 0 {
+        tabletsPerTable := float64(userTabletReplicas) / float64(replicationFactor) /
+            float64(userTables)
+        limits.TabletsPerTable = &tabletsPerTable
+        if tabletsPerTable > 0 {
+            remainingTables := int64(float64(remainingTablets) / tabletsPerTable)
+            limits.RemainingTables = &remainingTables
+        }
+    }
+    return limits
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "sort"
+)
+
+// Roles of the replicas of a tablet
+const TABLET_PEER_LEADER = "LEADER"
+const TABLET_PEER_FOLLOWER = "FOLLOWER"
+
+// Gets the key ranges of the tablets, by tablet id, from the tablet listings of the alive nodes.
+// A tablet is listed by every node with a replica of it, so a node that cannot be read only
+// leaves out the key ranges of the tablets that have no replica elsewhere.
+func (c *Container) getTabletPartitions(nodes []models.ClusterStateNode) map[string]string {
+    fanOut := newFanOutLimiter()
+    futures := map[string]chan helpers.TabletsFuture{}
+    for _, node := range nodes {
+        if node.Status != "ALIVE" {
+            continue
+        }
+        host := node.Name
+        future := make(chan helpers.TabletsFuture, 1)
+        futures[host] = future
+        fanOut.goCall(func() {
+            helpers.GetTabletsFuture(host, future)
+        })
+    }
+    partitions := map[string]string{}
+    for host, future := range futures {
+        tablets := <-future
+        if tablets.Error != nil {
+            c.logger.Debugf("failed to get the tablets of node %s: %s", host,
+                tablets.Error.Error())
+            continue
+        }
+        for tabletId, tablet := range tablets.Tablets {
+            if tablet.Partition != "" {
+                partitions[tabletId] = tablet.Partition
+            }
+        }
+    }
+    return partitions
+}
+
+// Merges the tablets that the master lists with the placement of their replicas and their key
+// ranges. Only the tablets of the table with the uuid or name, and with a replica on the node
+// with the host, are kept if they are set.
+func getClusterTablets(entities helpers.DumpEntities, nodes []models.ClusterStateNode,
+    partitions map[string]string, table string, node string) []models.ClusterTablet {
+    keyspaces := map[string]string{}
+    for _, keyspace := range entities.Keyspaces {
+        keyspaces[keyspace.KeyspaceId] = keyspace.KeyspaceName
+    }
+    tables := map[string]helpers.DumpEntitiesTable{}
+    for _, entityTable := range entities.Tables {
+        tables[entityTable.TableId] = entityTable
+    }
+    nodesByHost := map[string]models.ClusterStateNode{}
+    for _, stateNode := range nodes {
+        nodesByHost[stateNode.Name] = stateNode
+    }
+    clusterTablets := []models.ClusterTablet{}
+    for _, tablet := range entities.Tablets {
+        entityTable := tables[tablet.TableId]
+        if table != "" && tablet.TableId != table && entityTable.TableName != table {
+            continue
+        }
+        clusterTablet := models.ClusterTablet{
+            Namespace: keyspaces[entityTable.KeyspaceId],
+            TableName: entityTable.TableName,
+            TableUuid: tablet.TableId,
+            TabletId: tablet.TabletId,
+            HasLeader: tablet.Leader != "",
+            State: tablet.State,
+            Partition: partitions[tablet.TabletId],
+            Peers: []models.ClusterTabletPeer{},
+        }
+        onNode := node == ""
+        for _, replica := range tablet.Replicas {
+            host, err := helpers.GetHostFromAddress(replica.Addr)
+            if err != nil {
+                host = replica.Addr
+            }
+            peer := models.ClusterTabletPeer{
+                ServerUuid: replica.ServerUuid,
+                Host: host,
+                Role: TABLET_PEER_FOLLOWER,
+                Type: replica.Type,
+                Cloud: nodesByHost[host].Cloud,
+                Region: nodesByHost[host].Region,
+                Zone: nodesByHost[host].Zone,
+            }
+            if replica.ServerUuid == tablet.Leader {
+                peer.Role = TABLET_PEER_LEADER
+                clusterTablet.Leader = host
+            }
+            onNode = onNode || host == node
+            clusterTablet.Peers = append(clusterTablet.Peers, peer)
+        }
+        if !onNode {
+            continue
+        }
+        sort.Slice(clusterTablet.Peers, func(i, j int) bool {
+            left, right := clusterTablet.Peers[i], clusterTablet.Peers[j]
+            if left.Role != right.Role {
+                return left.Role == TABLET_PEER_LEADER
+            }
+            return left.Host < right.Host
+        })
+        clusterTablets = append(clusterTablets, clusterTablet)
+    }
+    return clusterTablets
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "sort"
+)
+
+// Groups the threads of processes by stack. A pool whose threads are all stuck on the same stack
+// shows as one group with as many threads as the pool.
+func newThreadz(threads map[serverProcess][]helpers.ThreadzThread,
+    errorCount int32) models.Threadz {
+    threadz := models.Threadz{
+        Stacks: []models.ThreadStack{},
+        ErrorCount: errorCount,
+    }
+    stacks := map[string]*models.ThreadStack{}
+    nodes := map[string]map[string]bool{}
+    for process, processThreads := range threads {
+        for _, thread := range processThreads {
+            stack, ok := stacks[thread.Stack]
+            if !ok {
+                stack = &models.ThreadStack{
+                    Stack: thread.Stack,
+                    ThreadNames: []string{},
+                    Nodes: []string{},
+                }
+                stacks[thread.Stack] = stack
+                nodes[thread.Stack] = map[string]bool{}
+            }
+            stack.Count++
+            stack.ThreadNames = append(stack.ThreadNames, thread.Name)
+            if !nodes[thread.Stack][process.host] {
+                nodes[thread.Stack][process.host] = true
+                stack.Nodes = append(stack.Nodes, process.host)
+            }
+            threadz.NumThreads++
+        }
+    }
+    for _, stack := range stacks {
+        sort.Strings(stack.ThreadNames)
+        sort.Strings(stack.Nodes)
+        threadz.Stacks = append(threadz.Stacks, *stack)
+    }
+    sort.Slice(threadz.Stacks, func(i, j int) bool {
+        if threadz.Stacks[i].Count != threadz.Stacks[j].Count {
+            return threadz.Stacks[i].Count > threadz.Stacks[j].Count
+        }
+        return threadz.Stacks[i].Stack < threadz.Stacks[j].Stack
+    })
+    return threadz
+}
+
+// Gets the threads of the processes in parallel, counting the processes that could not be
+// reached rather than failing
+func getThreadz(processes []serverProcess) models.Threadz {
+    fanOut := newFanOutLimiter()
+    futures := []chan helpers.ThreadzFuture{}
+    for _, process := range processes {
+        host, isMaster := process.host, process.process == helpers.MASTER_PROCESS
+        future := make(chan helpers.ThreadzFuture, 1)
+        futures = append(futures, future)
+        fanOut.goCall(func() { helpers.GetThreadzFuture(host, isMaster, future) })
+    }
+    threads := map[serverProcess][]helpers.ThreadzThread{}
+    errorCount := int32(0)
+    for index, future := range futures {
+        response := <-future
+        if response.Error != nil {
+            errorCount++
+            continue
+        }
+        threads[processes[index]] = response.Threads
+    }
+    return newThreadz(threads, errorCount)
+}
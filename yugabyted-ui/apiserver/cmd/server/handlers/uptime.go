@@ -0,0 +1,237 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "time"
+)
+
+// APIs the uptime is measured of
+const UPTIME_API_YSQL = "ysql"
+const UPTIME_API_YCQL = "ycql"
+
+// How often the tracker checks whether sampling was turned on, while it is off
+const UPTIME_IDLE_INTERVAL = time.Minute
+
+// Windows the uptime is measured over, ending now
+var UPTIME_WINDOWS = []struct {
+    name string
+    duration time.Duration
+}{
+    {"24h", 24 * time.Hour},
+    {"7d", 7 * 24 * time.Hour},
+    {"30d", 30 * 24 * time.Hour},
+}
+
+// The health of the layers of a node at a check
+type healthSampleNode struct {
+    Node string `json:"node"`
+    Region string `json:"region"`
+    Ysql bool `json:"ysql"`
+    Ycql bool `json:"ycql"`
+}
+
+// A check of the health of every node, as written to the store
+type healthSample struct {
+    Time int64 `json:"time"`
+    Nodes []healthSampleNode `json:"nodes"`
+}
+
+// Checks the health of the YSQL and YCQL layers of every node at every uptime.sample_interval
+// and keeps the results in the local store, to measure the uptime of the APIs from
+type uptimeTracker struct {
+    local localstore.Store
+    logger logger.Logger
+}
+
+func newUptimeTracker(log logger.Logger, local localstore.Store) *uptimeTracker {
+    return &uptimeTracker{
+        local: local,
+        logger: log,
+    }
+}
+
+func (tracker *uptimeTracker) run(getApiHealth func() (models.ApiHealth, error)) {
+    for {
+        interval := helpers.GetConfig().Uptime.SampleInterval
+        if interval <= 0 {
+            time.Sleep(UPTIME_IDLE_INTERVAL)
+            continue
+        }
+        start := time.Now()
+        apiHealth, err := getApiHealth()
+        if err != nil {
+            tracker.logger.Debugf("failed to check the health of the nodes: %s", err.Error())
+        } else {
+            tracker.record(start.Unix(), apiHealth)
+        }
+        time.Sleep(interval)
+    }
+}
+
+// Keeps a check in the local store, dropping the oldest checks beyond uptime.max_samples
+func (tracker *uptimeTracker) record(now int64, apiHealth models.ApiHealth) {
+    sample := healthSample{Time: now, Nodes: []healthSampleNode{}}
+    for _, node := range apiHealth.Nodes {
+        sample.Nodes = append(sample.Nodes, healthSampleNode{
+            Node: node.Node,
+            Region: node.Region,
+            Ysql: node.Ysql.Healthy,
+            Ycql: node.Ycql.Healthy,
+        })
+    }
+    data, err := json.Marshal(sample)
+    if err == nil {
+        err = tracker.local.Put(STORE_BUCKET_HEALTH_HISTORY,
+            fmt.Sprintf("%019d-%04d", time.Now().UnixNano(), 0), data)
+    }
+    if err != nil {
+        tracker.logger.Errorf("failed to keep the health of the nodes: %s", err.Error())
+        return
+    }
+    err = tracker.local.Trim(STORE_BUCKET_HEALTH_HISTORY, helpers.GetConfig().Uptime.MaxSamples)
+    if err != nil {
+        tracker.logger.Errorf("failed to trim the health history: %s", err.Error())
+    }
+}
+
+// Gets the checks since a time, oldest first
+func (tracker *uptimeTracker) list(from int64) ([]healthSample, error) {
+    entries, err := tracker.local.List(STORE_BUCKET_HEALTH_HISTORY)
+    if err != nil {
+        return nil, err
+    }
+    samples := []healthSample{}
+    for _, entry := range entries {
+        sample := healthSample{}
+        if err := json.Unmarshal(entry.Value, &sample); err != nil {
+            tracker.logger.Errorf("failed to read health check %s: %s", entry.Key, err.Error())
+            continue
+        }
+        if sample.Time >= from {
+            samples = append(samples, sample)
+        }
+    }
+    return samples, nil
+}
+
+// Counts the checks of an API, and the ones that succeeded
+type uptimeCounter struct {
+    checks int64
+    succeeded int64
+}
+
+func (counter *uptimeCounter) add(succeeded bool) {
+    counter.checks++
+    if succeeded {
+        counter.succeeded++
+    }
+}
+
+func (counter uptimeCounter) getModel(api string) models.UptimeApi {
+    uptimeApi := models.UptimeApi{
+        Api: api,
+        Checks: counter.checks,
+        Succeeded: counter.succeeded,
+    }
+    if counter.checks > 0 {
+        availability := 100 * float64(counter.succeeded) / float64(counter.checks)
+        uptimeApi.AvailabilityPercent = &availability
+    }
+    return uptimeApi
+}
+
+// Measures the availability of each API over a window, overall and by region, as the share of
+// the checks of the API on every node that succeeded. The YSQL checks are the health checks and
+// the probes, the YCQL checks are the health checks. Probes of nodes missing from the health
+// history are only counted overall, as their region is not known.
+func getUptimeWindow(name string, from int64, to int64, samples []healthSample,
+    probeResults []models.ProbeResult) models.UptimeWindow {
+    ysql, ycql := uptimeCounter{}, uptimeCounter{}
+    ysqlByRegion := map[string]*uptimeCounter{}
+    ycqlByRegion := map[string]*uptimeCounter{}
+    getRegionCounters := func(region string) (*uptimeCounter, *uptimeCounter) {
+        if _, ok := ysqlByRegion[region]; !ok {
+            ysqlByRegion[region] = &uptimeCounter{}
+            ycqlByRegion[region] = &uptimeCounter{}
+        }
+        return ysqlByRegion[region], ycqlByRegion[region]
+    }
+    // Region of each node at its latest check, for the probes
+    regions := map[string]string{}
+    for _, sample := range samples {
+        for _, node := range sample.Nodes {
+            regions[node.Node] = node.Region
+            if sample.Time < from || sample.Time > to {
+                continue
+            }
+            ysql.add(node.Ysql)
+            ycql.add(node.Ycql)
+            regionYsql, regionYcql := getRegionCounters(node.Region)
+            regionYsql.add(node.Ysql)
+            regionYcql.add(node.Ycql)
+        }
+    }
+    for _, result := range probeResults {
+        if result.Time < from || result.Time > to {
+            continue
+        }
+        ysql.add(result.Succeeded)
+        if region, ok := regions[result.Node]; ok {
+            regionYsql, _ := getRegionCounters(region)
+            regionYsql.add(result.Succeeded)
+        }
+    }
+    window := models.UptimeWindow{
+        Window: name,
+        From: from,
+        To: to,
+        Apis: []models.UptimeApi{
+            ysql.getModel(UPTIME_API_YSQL),
+            ycql.getModel(UPTIME_API_YCQL),
+        },
+        Regions: []models.UptimeRegion{},
+    }
+    for region := range ysqlByRegion {
+        window.Regions = append(window.Regions, models.UptimeRegion{
+            Region: region,
+            Apis: []models.UptimeApi{
+                ysqlByRegion[region].getModel(UPTIME_API_YSQL),
+                ycqlByRegion[region].getModel(UPTIME_API_YCQL),
+            },
+        })
+    }
+    sort.Slice(window.Regions, func(i, j int) bool {
+        return window.Regions[i].Region < window.Regions[j].Region
+    })
+    return window
+}
+
+// Measures the uptime of the APIs over each of the windows, ending now
+func (c *Container) getUptime(now time.Time) (models.Uptime, error) {
+    to := now.Unix()
+    from := to - int64(UPTIME_WINDOWS[len(UPTIME_WINDOWS)-1].duration.Seconds())
+    samples, err := c.uptime.list(from)
+    if err != nil {
+        return models.Uptime{}, err
+    }
+    probeResults, err := c.prober.list(from, to, "")
+    if err != nil {
+        return models.Uptime{}, err
+    }
+    uptime := models.Uptime{Windows: []models.UptimeWindow{}}
+    if len(samples) > 0 {
+        since := samples[0].Time
+        uptime.SampledSince = &since
+    }
+    for _, window := range UPTIME_WINDOWS {
+        uptime.Windows = append(uptime.Windows, getUptimeWindow(window.name,
+            to-int64(window.duration.Seconds()), to, samples, probeResults))
+    }
+    return uptime, nil
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Kinds of the findings of the version check, which are also the types of the cluster events
+// raised when they are first found
+const VERSION_FINDING_MIXED_VERSIONS = "mixed_versions"
+const VERSION_FINDING_CRITICAL_BUILD = "critical_build"
+const VERSION_FINDING_END_OF_LIFE = "end_of_life"
+
+// Source of the version check when versions.manifest_url is empty or cannot be fetched
+const RELEASE_MANIFEST_BUNDLED = "bundled"
+
+// A release series, e.g. 2.20 or 2024.1
+type releaseSeries struct {
+    Series string `json:"series"`
+    // Whether the series is past its end of life, for series whose date is not listed
+    EndOfLife bool `json:"end_of_life"`
+    // Date the series reaches its end of life as YYYY-MM-DD, empty if not announced
+    EndOfLifeDate string `json:"end_of_life_date"`
+}
+
+// A build that should not be run, e.g. as it has a known data loss bug
+type criticalBuild struct {
+    // Version with the build number, e.g. 2.20.1.0-b97, or without it to cover every build
+    Version string `json:"version"`
+    Reason string `json:"reason"`
+}
+
+// The releases the versions of the nodes are checked against, in the layout of the manifest
+// fetched from versions.manifest_url
+type releaseManifest struct {
+    Series []releaseSeries `json:"series"`
+    CriticalBuilds []criticalBuild `json:"critical_builds"`
+}
+
+// The series known to be past their end of life when this server was released. Later end of
+// life dates and critical builds need a fetched manifest.
+var BUNDLED_RELEASE_MANIFEST = releaseManifest{
+    Series: []releaseSeries{
+        {Series: "2.0", EndOfLife: true},
+        {Series: "2.1", EndOfLife: true},
+        {Series: "2.2", EndOfLife: true},
+        {Series: "2.3", EndOfLife: true},
+        {Series: "2.4", EndOfLife: true},
+        {Series: "2.5", EndOfLife: true},
+        {Series: "2.6", EndOfLife: true},
+        {Series: "2.7", EndOfLife: true},
+        {Series: "2.8", EndOfLife: true},
+        {Series: "2.9", EndOfLife: true},
+        {Series: "2.11", EndOfLife: true},
+        {Series: "2.12", EndOfLife: true},
+        {Series: "2.13", EndOfLife: true},
+        {Series: "2.14", EndOfLife: true},
+        {Series: "2.15", EndOfLife: true},
+        {Series: "2.16", EndOfLife: true},
+        {Series: "2.17", EndOfLife: true},
+        {Series: "2.18", EndOfLife: true},
+        {Series: "2.19", EndOfLife: true},
+    },
+    CriticalBuilds: []criticalBuild{},
+}
+
+// Keeps the manifest fetched from versions.manifest_url, fetching it again every
+// versions.manifest_refresh_interval. The bundled manifest is used until a fetch succeeds.
+type releaseManifestCache struct {
+    mutex sync.Mutex
+    manifest releaseManifest
+    source string
+    fetchedAt time.Time
+    // Time of the last attempt, so that a failing URL is not fetched on every check
+    attemptedAt time.Time
+    logger logger.Logger
+}
+
+func newReleaseManifestCache(log logger.Logger) *releaseManifestCache {
+    return &releaseManifestCache{
+        manifest: BUNDLED_RELEASE_MANIFEST,
+        source: RELEASE_MANIFEST_BUNDLED,
+        logger: log,
+    }
+}
+
+// Gets the manifest, its source and when it was fetched, zero for the bundled one
+func (cache *releaseManifestCache) get() (releaseManifest, string, time.Time) {
+    versionsConfig := helpers.GetConfig().Versions
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+    if versionsConfig.ManifestUrl == "" {
+        return BUNDLED_RELEASE_MANIFEST, RELEASE_MANIFEST_BUNDLED, time.Time{}
+    }
+    if cache.source != versionsConfig.ManifestUrl ||
+        time.Since(cache.attemptedAt) >= versionsConfig.ManifestRefreshInterval {
+        cache.attemptedAt = time.Now()
+        manifest, err := fetchReleaseManifest(versionsConfig.ManifestUrl)
+        if err == nil {
+            cache.manifest = manifest
+            cache.source = versionsConfig.ManifestUrl
+            cache.fetchedAt = cache.attemptedAt
+        } else {
+            cache.logger.Errorf("failed to fetch the release manifest from %s: %s",
+                versionsConfig.ManifestUrl, err.Error())
+        }
+    }
+    if cache.source != versionsConfig.ManifestUrl {
+        return BUNDLED_RELEASE_MANIFEST, RELEASE_MANIFEST_BUNDLED, time.Time{}
+    }
+    return cache.manifest, cache.source, cache.fetchedAt
+}
+
+func fetchReleaseManifest(manifestUrl string) (releaseManifest, error) {
+    manifest := releaseManifest{}
+    resp, err := helpers.NewHttpClient().Get(manifestUrl)
+    if err != nil {
+        return manifest, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return manifest, fmt.Errorf("the manifest URL returned %s", resp.Status)
+    }
+    body, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        return manifest, err
+    }
+    if err := json.Unmarshal(body, &manifest); err != nil {
+        return manifest, fmt.Errorf("invalid manifest: %s", err.Error())
+    }
+    return manifest, nil
+}
+
+// Gets the series of a version with its build number, e.g. 2.20 for 2.20.1.0-b97
+func getReleaseSeries(version string) string {
+    parts := strings.SplitN(strings.SplitN(version, "-", 2)[0], ".", 3)
+    if len(parts) < 2 {
+        return parts[0]
+    }
+    return parts[0] + "." + parts[1]
+}
+
+// Checks the versions of the nodes, by host, as version-bBUILD. Returns mixed versions, nodes
+// on critical builds and nodes on series past their end of life, severe findings first.
+func getVersionFindings(manifest releaseManifest, versions map[string]string,
+    now time.Time) []models.VersionFinding {
+    findings := []models.VersionFinding{}
+    nodesByVersion := map[string][]string{}
+    for host, version := range versions {
+        nodesByVersion[version] = append(nodesByVersion[version], host)
+    }
+    allVersions := []string{}
+    for version, nodes := range nodesByVersion {
+        sort.Strings(nodes)
+        allVersions = append(allVersions, version)
+    }
+    sort.Strings(allVersions)
+    if len(allVersions) > 1 {
+        nodes := []string{}
+        for _, version := range allVersions {
+            nodes = append(nodes, nodesByVersion[version]...)
+        }
+        findings = append(findings, models.VersionFinding{
+            Kind: VERSION_FINDING_MIXED_VERSIONS,
+            Severity: ALERT_SEVERITY_WARNING,
+            Version: strings.Join(allVersions, ", "),
+            Nodes: nodes,
+            Message: fmt.Sprintf("the nodes run %d different versions: %s", len(allVersions),
+                strings.Join(allVersions, ", ")),
+        })
+    }
+    for _, version := range allVersions {
+        for _, build := range manifest.CriticalBuilds {
+            if build.Version != version && build.Version != strings.SplitN(version, "-", 2)[0] {
+                continue
+            }
+            findings = append(findings, models.VersionFinding{
+                Kind: VERSION_FINDING_CRITICAL_BUILD,
+                Severity: ALERT_SEVERITY_SEVERE,
+                Version: version,
+                Nodes: nodesByVersion[version],
+                Message: fmt.Sprintf("version %s is a critical build: %s", version,
+                    build.Reason),
+            })
+            break
+        }
+        series := getReleaseSeries(version)
+        for _, releaseSeries := range manifest.Series {
+            if releaseSeries.Series != series {
+                continue
+            }
+            endOfLife := releaseSeries.EndOfLife
+            if date, err := time.Parse("2006-01-02", releaseSeries.EndOfLifeDate); err == nil &&
+                !now.Before(date) {
+                endOfLife = true
+            }
+            if endOfLife {
+                message := fmt.Sprintf("release series %s of version %s is past its end of life",
+                    series, version)
+                if releaseSeries.EndOfLifeDate != "" {
+                    message += " since " + releaseSeries.EndOfLifeDate
+                }
+                findings = append(findings, models.VersionFinding{
+                    Kind: VERSION_FINDING_END_OF_LIFE,
+                    Severity: ALERT_SEVERITY_WARNING,
+                    Version: version,
+                    Nodes: nodesByVersion[version],
+                    Message: message,
+                })
+            }
+            break
+        }
+    }
+    sort.SliceStable(findings, func(i, j int) bool {
+        return findings[i].Severity == ALERT_SEVERITY_SEVERE &&
+            findings[j].Severity != ALERT_SEVERITY_SEVERE
+    })
+    return findings
+}
+
+// Gets the versions of the alive nodes, by host. Nodes that cannot be reached are left out.
+func getNodeVersions() (map[string]string, error) {
+    tabletServersFuture := make(chan helpers.TabletServersFuture)
+    go helpers.GetTabletServersFuture(helpers.HOST, tabletServersFuture)
+    tabletServers := <-tabletServersFuture
+    if tabletServers.Error != nil {
+        return nil, tabletServers.Error
+    }
+    fanOut := newFanOutLimiter()
+    versionInfoFutures := map[string]chan helpers.VersionInfoFuture{}
+    for _, node := range getClusterStateNodes(tabletServers) {
+        if node.Status != "ALIVE" {
+            continue
+        }
+        host := node.Name
+        versionInfoFuture := make(chan helpers.VersionInfoFuture, 1)
+        versionInfoFutures[host] = versionInfoFuture
+        fanOut.goCall(func() { helpers.GetVersionFuture(host, versionInfoFuture) })
+    }
+    versions := map[string]string{}
+    for host, versionInfoFuture := range versionInfoFutures {
+        if versionInfo := <-versionInfoFuture; versionInfo.Error == nil {
+            versions[host] = getNodeVersion(versionInfo.VersionInfo)
+        }
+    }
+    return versions, nil
+}
+
+// Gets the version of a node with its build number, e.g. 2.20.1.0-b97
+func getNodeVersion(versionInfo helpers.VersionInfoStruct) string {
+    return versionInfo.VersionNumber + "-b" + versionInfo.BuildNumber
+}
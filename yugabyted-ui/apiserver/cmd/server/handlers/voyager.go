@@ -0,0 +1,290 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "regexp"
+)
+
+// YugabyteDB Voyager records every run of its commands in these tables of the yugabyted
+// database when a migration is run with --control-plane-type yugabyted
+const VOYAGER_METADATA_TABLE = "ybvoyager_visualizer.ybvoyager_visualizer_metadata"
+
+const VOYAGER_METADATA_EXISTS_SQL = "SELECT to_regclass('" + VOYAGER_METADATA_TABLE +
+    "') IS NOT NULL"
+
+// The latest run of each migration, along with when the first one started
+const VOYAGER_MIGRATIONS_SQL = "SELECT DISTINCT ON (migration_uuid) migration_uuid::text, " +
+    "migration_phase, invocation_sequence, COALESCE(database_name, ''), " +
+    "COALESCE(schema_name, ''), COALESCE(db_type, ''), COALESCE(db_version, ''), " +
+    "COALESCE(host_ip, ''), COALESCE(port, 0), COALESCE(status, ''), " +
+    "EXTRACT(EPOCH FROM MIN(invocation_timestamp) OVER (PARTITION BY migration_uuid))::bigint, " +
+    "EXTRACT(EPOCH FROM invocation_timestamp)::bigint FROM " + VOYAGER_METADATA_TABLE +
+    " ORDER BY migration_uuid, invocation_sequence DESC"
+
+// The report of the latest completed run of a phase of a migration
+const VOYAGER_PAYLOAD_SQL = "SELECT payload FROM " + VOYAGER_METADATA_TABLE +
+    " WHERE migration_uuid::text = $1 AND migration_phase = $2 AND status = '" +
+    VOYAGER_STATUS_COMPLETED + "' ORDER BY invocation_sequence DESC LIMIT 1"
+
+const VOYAGER_STATUS_COMPLETED = "COMPLETED"
+
+// Voyager records the rows migrated so far of each table in this table while data is exported
+// or imported
+const VOYAGER_TABLE_METRICS_TABLE = "ybvoyager_visualizer.ybvoyager_visualizer_table_metrics"
+
+const VOYAGER_TABLE_METRICS_EXISTS_SQL = "SELECT to_regclass('" + VOYAGER_TABLE_METRICS_TABLE +
+    "') IS NOT NULL"
+
+// Only recent versions of Voyager count the rows that failed to import
+const VOYAGER_ERROR_ROWS_COLUMN = "count_errored_rows"
+
+const VOYAGER_ERROR_ROWS_EXISTS_SQL = "SELECT EXISTS (SELECT 1 FROM information_schema.columns " +
+    "WHERE table_schema = 'ybvoyager_visualizer' " +
+    "AND table_name = 'ybvoyager_visualizer_table_metrics' " +
+    "AND column_name = '" + VOYAGER_ERROR_ROWS_COLUMN + "')"
+
+// The progress of each table in a phase of a migration. The columns that follow the timestamp
+// are given by the caller.
+const VOYAGER_TABLE_PROGRESS_SQL = "SELECT COALESCE(schema_name, ''), table_name, " +
+    "COALESCE(status, 0), COALESCE(count_live_rows, 0), COALESCE(count_total_rows, 0), " +
+    "EXTRACT(EPOCH FROM invocation_timestamp)::bigint%s FROM " + VOYAGER_TABLE_METRICS_TABLE +
+    " WHERE migration_uuid::text = $1 AND migration_phase = $2 ORDER BY schema_name, table_name"
+
+// When the first run of a phase of a migration started
+const VOYAGER_PHASE_STARTED_AT_SQL = "SELECT EXTRACT(EPOCH FROM MIN(invocation_timestamp))" +
+    "::bigint FROM " + VOYAGER_METADATA_TABLE +
+    " WHERE migration_uuid::text = $1 AND migration_phase = $2"
+
+// The statuses of the tables of a migration as Voyager numbers them
+var VOYAGER_TABLE_STATUSES = map[int32]string{
+    0: "NOT STARTED",
+    1: "IN PROGRESS",
+    2: "DONE",
+    3: "COMPLETED",
+}
+
+// The phases of a migration as Voyager numbers them, by the command that runs them
+const VOYAGER_PHASE_ASSESS_MIGRATION = 1
+const VOYAGER_PHASE_ANALYZE_SCHEMA = 3
+const VOYAGER_PHASE_IMPORT_DATA = 6
+
+var VOYAGER_PHASES = map[int32]string{
+    VOYAGER_PHASE_ASSESS_MIGRATION: "assess-migration",
+    2: "export-schema",
+    VOYAGER_PHASE_ANALYZE_SCHEMA: "analyze-schema",
+    4: "export-data",
+    5: "import-schema",
+    VOYAGER_PHASE_IMPORT_DATA: "import-data",
+    7: "finalize-schema",
+}
+
+var MIGRATION_UUID_REGEX = regexp.MustCompile(
+    `^[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}$`)
+
+type voyagerDatabaseObject struct {
+    ObjectType string `json:"ObjectType"`
+    TotalCount int32 `json:"TotalCount"`
+    InvalidCount int32 `json:"InvalidCount"`
+}
+
+type voyagerSchemaSummary struct {
+    DatabaseObjects []voyagerDatabaseObject `json:"DatabaseObjects"`
+}
+
+type voyagerSizingRecommendation struct {
+    ColocatedTables []string `json:"ColocatedTables"`
+    ShardedTables []string `json:"ShardedTables"`
+    NumNodes float64 `json:"NumNodes"`
+    VCPUsPerInstance float64 `json:"VCPUsPerInstance"`
+    MemoryPerInstance float64 `json:"MemoryPerInstance"`
+    EstimatedTimeInMinForImport float64 `json:"EstimatedTimeInMinForImport"`
+    ParallelVoyagerJobs float64 `json:"ParallelVoyagerJobs"`
+}
+
+type voyagerSizing struct {
+    SizingRecommendation voyagerSizingRecommendation `json:"SizingRecommendation"`
+    FailureReasoning string `json:"FailureReasoning"`
+}
+
+type voyagerUnsupportedFeature struct {
+    FeatureName string `json:"FeatureName"`
+    Objects []struct {
+        ObjectName string `json:"ObjectName"`
+    } `json:"Objects"`
+}
+
+type voyagerUnsupportedDataType struct {
+    SchemaName string `json:"SchemaName"`
+    TableName string `json:"TableName"`
+    ColumnName string `json:"ColumnName"`
+    DataType string `json:"DataType"`
+}
+
+// The JSON report of assess-migration
+type voyagerAssessmentReport struct {
+    VoyagerVersion string `json:"VoyagerVersion"`
+    MigrationComplexity string `json:"MigrationComplexity"`
+    SchemaSummary voyagerSchemaSummary `json:"SchemaSummary"`
+    Sizing *voyagerSizing `json:"Sizing"`
+    UnsupportedFeatures []voyagerUnsupportedFeature `json:"UnsupportedFeatures"`
+    UnsupportedDataTypes []voyagerUnsupportedDataType `json:"UnsupportedDataTypes"`
+}
+
+type voyagerSchemaIssue struct {
+    IssueType string `json:"IssueType"`
+    ObjectType string `json:"ObjectType"`
+    ObjectName string `json:"ObjectName"`
+    Reason string `json:"Reason"`
+    SqlStatement string `json:"SqlStatement"`
+    Suggestion string `json:"Suggestion"`
+    GH string `json:"GH"`
+}
+
+// The JSON report of analyze-schema
+type voyagerSchemaReport struct {
+    VoyagerVersion string `json:"VoyagerVersion"`
+    Summary voyagerSchemaSummary `json:"Summary"`
+    Issues []voyagerSchemaIssue `json:"Issues"`
+}
+
+func getMigrationObjectSummaries(summary voyagerSchemaSummary) []models.MigrationObjectSummary {
+    objects := []models.MigrationObjectSummary{}
+    for _, object := range summary.DatabaseObjects {
+        objects = append(objects, models.MigrationObjectSummary{
+            ObjectType: object.ObjectType,
+            TotalCount: object.TotalCount,
+            InvalidCount: object.InvalidCount,
+        })
+    }
+    return objects
+}
+
+// Parses the payload of assess-migration. Newer versions of Voyager wrap the report along
+// with other details, older ones send the report as is.
+func parseMigrationAssessment(payload string) (models.MigrationAssessment, error) {
+    wrapper := struct {
+        AssessmentJsonReport *voyagerAssessmentReport `json:"AssessmentJsonReport"`
+    }{}
+    if err := json.Unmarshal([]byte(payload), &wrapper); err != nil {
+        return models.MigrationAssessment{}, err
+    }
+    report := voyagerAssessmentReport{}
+    if wrapper.AssessmentJsonReport != nil {
+        report = *wrapper.AssessmentJsonReport
+    } else if err := json.Unmarshal([]byte(payload), &report); err != nil {
+        return models.MigrationAssessment{}, err
+    }
+    assessment := models.MigrationAssessment{
+        VoyagerVersion: report.VoyagerVersion,
+        Complexity: report.MigrationComplexity,
+        Objects: getMigrationObjectSummaries(report.SchemaSummary),
+        UnsupportedFeatures: []models.MigrationUnsupportedFeature{},
+        UnsupportedDataTypes: []models.MigrationUnsupportedDataType{},
+    }
+    for _, feature := range report.UnsupportedFeatures {
+        // Voyager lists every feature it checks for, including the ones that no object uses
+        if len(feature.Objects) == 0 {
+            continue
+        }
+        unsupportedFeature := models.MigrationUnsupportedFeature{
+            FeatureName: feature.FeatureName,
+            Objects: []string{},
+        }
+        for _, object := range feature.Objects {
+            unsupportedFeature.Objects = append(unsupportedFeature.Objects, object.ObjectName)
+        }
+        assessment.UnsupportedFeatures = append(assessment.UnsupportedFeatures,
+            unsupportedFeature)
+    }
+    for _, dataType := range report.UnsupportedDataTypes {
+        assessment.UnsupportedDataTypes = append(assessment.UnsupportedDataTypes,
+            models.MigrationUnsupportedDataType{
+                SchemaName: dataType.SchemaName,
+                TableName: dataType.TableName,
+                ColumnName: dataType.ColumnName,
+                DataType: dataType.DataType,
+            })
+    }
+    if report.Sizing != nil {
+        recommendation := report.Sizing.SizingRecommendation
+        sizing := models.MigrationSizing{
+            NumNodes: int32(recommendation.NumNodes),
+            VcpusPerInstance: int32(recommendation.VCPUsPerInstance),
+            MemoryPerInstanceGb: int32(recommendation.MemoryPerInstance),
+            ColocatedTables: append([]string{}, recommendation.ColocatedTables...),
+            ShardedTables: append([]string{}, recommendation.ShardedTables...),
+            EstimatedImportMinutes: recommendation.EstimatedTimeInMinForImport,
+            ParallelImportJobs: int32(recommendation.ParallelVoyagerJobs),
+            FailureReasoning: report.Sizing.FailureReasoning,
+        }
+        assessment.Sizing = &sizing
+    }
+    return assessment, nil
+}
+
+// Parses the payload of analyze-schema
+func parseMigrationSchemaAnalysis(payload string) (models.MigrationSchemaAnalysis, error) {
+    report := voyagerSchemaReport{}
+    if err := json.Unmarshal([]byte(payload), &report); err != nil {
+        return models.MigrationSchemaAnalysis{}, err
+    }
+    analysis := models.MigrationSchemaAnalysis{
+        VoyagerVersion: report.VoyagerVersion,
+        Objects: getMigrationObjectSummaries(report.Summary),
+        Issues: []models.MigrationSchemaIssue{},
+    }
+    for _, issue := range report.Issues {
+        analysis.Issues = append(analysis.Issues, models.MigrationSchemaIssue{
+            IssueType: issue.IssueType,
+            ObjectType: issue.ObjectType,
+            ObjectName: issue.ObjectName,
+            Reason: issue.Reason,
+            SqlStatement: issue.SqlStatement,
+            Suggestion: issue.Suggestion,
+            GithubIssue: issue.GH,
+        })
+    }
+    return analysis, nil
+}
+
+func getVoyagerTableStatus(status int32) string {
+    if name, ok := VOYAGER_TABLE_STATUSES[status]; ok {
+        return name
+    }
+    return fmt.Sprintf("status %d", status)
+}
+
+// Sums up the progress of the tables of a migration. The rate is the average since the phase
+// started, and the time left assumes that the rest of the rows go at the same rate.
+func getMigrationProgress(tables []models.MigrationTableProgress, startedAt *int64,
+    now int64) models.MigrationProgress {
+    progress := models.MigrationProgress{
+        StartedAt: startedAt,
+        Tables: tables,
+    }
+    for _, table := range tables {
+        progress.RowsImported += table.RowsImported
+        progress.TotalRows += table.TotalRows
+        if table.ErrorRows != nil {
+            if progress.ErrorRows == nil {
+                progress.ErrorRows = new(int64)
+            }
+            *progress.ErrorRows += *table.ErrorRows
+        }
+    }
+    if startedAt != nil && now > *startedAt {
+        progress.RowsPerSecond = float64(progress.RowsImported) / float64(now-*startedAt)
+    }
+    // The total is an estimate, so more rows than it can be imported
+    remainingRows := progress.TotalRows - progress.RowsImported
+    if remainingRows <= 0 {
+        etaSeconds := int64(0)
+        progress.EtaSeconds = &etaSeconds
+    } else if progress.RowsPerSecond > 0 {
+        etaSeconds := int64(float64(remainingRows) / progress.RowsPerSecond)
+        progress.EtaSeconds = &etaSeconds
+    }
+    return progress
+}
@@ -0,0 +1,364 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "bytes"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Headers of the webhook requests. The signature is the HMAC-SHA256 of the body with the secret
+// of the webhook, as sha256=<hex>.
+const WEBHOOK_EVENT_HEADER = "X-Yugabyted-Ui-Event"
+const WEBHOOK_DELIVERY_HEADER = "X-Yugabyted-Ui-Delivery"
+const WEBHOOK_SIGNATURE_HEADER = "X-Yugabyted-Ui-Signature"
+
+const WEBHOOK_MAX_URL_LENGTH = 2048
+
+// A webhook as written to the store, with its secret
+type storedWebhook struct {
+    Id string `json:"id"`
+    Url string `json:"url"`
+    Secret string `json:"secret"`
+    EventTypes []string `json:"event_types"`
+    CreatedBy string `json:"created_by"`
+    CreatedAt int64 `json:"created_at"`
+}
+
+// The body of the webhook requests
+type webhookPayload struct {
+    WebhookId string `json:"webhook_id"`
+    Event models.ClusterEvent `json:"event"`
+}
+
+// The events waiting to be posted to a webhook, which one worker posts in order
+type webhookQueue struct {
+    events chan models.ClusterEvent
+    // Closed when the webhook is deleted, which stops the worker
+    stop chan struct{}
+    // Number of events dropped as the queue was full
+    dropped int64
+}
+
+// Keeps the webhooks in the local store, one entry each, and posts the cluster events of their
+// types to them. The latest delivery of each webhook is only kept in memory.
+type webhookStore struct {
+    mutex sync.Mutex
+    webhooks map[string]storedWebhook
+    deliveries map[string]models.WebhookDelivery
+    queues map[string]*webhookQueue
+    local localstore.Store
+    logger logger.Logger
+}
+
+func newWebhookStore(log logger.Logger, local localstore.Store) *webhookStore {
+    store := &webhookStore{
+        webhooks: map[string]storedWebhook{},
+        deliveries: map[string]models.WebhookDelivery{},
+        queues: map[string]*webhookQueue{},
+        local: local,
+        logger: log,
+    }
+    store.load()
+    return store
+}
+
+// Reads the webhooks from the local store
+func (store *webhookStore) load() {
+    webhooks := map[string]storedWebhook{}
+    entries, err := store.local.List(STORE_BUCKET_WEBHOOKS)
+    if err != nil {
+        store.logger.Errorf("failed to read the webhooks: %s", err.Error())
+    }
+    for _, entry := range entries {
+        webhook := storedWebhook{}
+        if err := json.Unmarshal(entry.Value, &webhook); err != nil {
+            store.logger.Errorf("failed to read webhook %s: %s", entry.Key, err.Error())
+            continue
+        }
+        webhooks[webhook.Id] = webhook
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    store.webhooks = webhooks
+    for id := range store.queues {
+        if _, ok := webhooks[id]; !ok {
+            store.stopQueueLocked(id)
+        }
+    }
+}
+
+func (store *webhookStore) put(webhook storedWebhook) error {
+    data, err := json.Marshal(webhook)
+    if err != nil {
+        return err
+    }
+    return store.local.Put(STORE_BUCKET_WEBHOOKS, webhook.Id, data)
+}
+
+// Checks the URL and the event types of a webhook. The secret may only be empty when an
+// existing webhook is replaced, which keeps its secret.
+func validateWebhookSpec(spec models.WebhookSpec, secretRequired bool) error {
+    if len(spec.Url) > WEBHOOK_MAX_URL_LENGTH {
+        return fmt.Errorf("url must be at most %d characters", WEBHOOK_MAX_URL_LENGTH)
+    }
+    parsedUrl, err := url.Parse(spec.Url)
+    if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") ||
+        parsedUrl.Host == "" {
+        return fmt.Errorf("url must be an http or https URL, got %q", spec.Url)
+    }
+    if secretRequired && spec.Secret == "" {
+        return errors.New("secret must not be empty")
+    }
+    if len(spec.EventTypes) == 0 {
+        return errors.New("event_types must not be empty")
+    }
+    seen := map[string]bool{}
+    for _, eventType := range spec.EventTypes {
+        if !containsString(CLUSTER_EVENT_TYPES, eventType) {
+            return fmt.Errorf("event type must be one of %s, got %q",
+                strings.Join(CLUSTER_EVENT_TYPES, ", "), eventType)
+        }
+        if seen[eventType] {
+            return fmt.Errorf("event type %s is given more than once", eventType)
+        }
+        seen[eventType] = true
+    }
+    return nil
+}
+
+// Must be called with the mutex held
+func (store *webhookStore) getModelLocked(webhook storedWebhook) models.Webhook {
+    model := models.Webhook{
+        Id: webhook.Id,
+        Url: webhook.Url,
+        EventTypes: append([]string{}, webhook.EventTypes...),
+        CreatedBy: webhook.CreatedBy,
+        CreatedAt: webhook.CreatedAt,
+    }
+    if delivery, ok := store.deliveries[webhook.Id]; ok {
+        model.LastDelivery = &delivery
+    }
+    if queue, ok := store.queues[webhook.Id]; ok {
+        model.DroppedEvents = queue.dropped
+    }
+    return model
+}
+
+// Gets the webhooks, oldest first. Their secrets are left out.
+func (store *webhookStore) list() []models.Webhook {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    webhooks := []models.Webhook{}
+    for _, webhook := range store.webhooks {
+        webhooks = append(webhooks, store.getModelLocked(webhook))
+    }
+    sort.Slice(webhooks, func(i, j int) bool {
+        if webhooks[i].CreatedAt != webhooks[j].CreatedAt {
+            return webhooks[i].CreatedAt < webhooks[j].CreatedAt
+        }
+        return webhooks[i].Id < webhooks[j].Id
+    })
+    return webhooks
+}
+
+func (store *webhookStore) create(spec models.WebhookSpec,
+    createdBy string) (models.Webhook, error) {
+    idBytes := make([]byte, 8)
+    if _, err := rand.Read(idBytes); err != nil {
+        return models.Webhook{}, err
+    }
+    webhook := storedWebhook{
+        Id: hex.EncodeToString(idBytes),
+        Url: spec.Url,
+        Secret: spec.Secret,
+        EventTypes: spec.EventTypes,
+        CreatedBy: createdBy,
+        CreatedAt: time.Now().Unix(),
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    if err := store.put(webhook); err != nil {
+        return models.Webhook{}, err
+    }
+    store.webhooks[webhook.Id] = webhook
+    return store.getModelLocked(webhook), nil
+}
+
+// Replaces the URL and event types of a webhook, and its secret unless the spec has none.
+// Returns false if there is no such webhook.
+func (store *webhookStore) update(id string, spec models.WebhookSpec) (models.Webhook, bool,
+    error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    webhook, ok := store.webhooks[id]
+    if !ok {
+        return models.Webhook{}, false, nil
+    }
+    webhook.Url = spec.Url
+    webhook.EventTypes = spec.EventTypes
+    if spec.Secret != "" {
+        webhook.Secret = spec.Secret
+    }
+    if err := store.put(webhook); err != nil {
+        return models.Webhook{}, true, err
+    }
+    store.webhooks[id] = webhook
+    return store.getModelLocked(webhook), true, nil
+}
+
+// Deletes a webhook. Returns false if there is no such webhook.
+func (store *webhookStore) delete(id string) (bool, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    if _, ok := store.webhooks[id]; !ok {
+        return false, nil
+    }
+    if err := store.local.Delete(STORE_BUCKET_WEBHOOKS, id); err != nil {
+        return true, err
+    }
+    delete(store.webhooks, id)
+    delete(store.deliveries, id)
+    store.stopQueueLocked(id)
+    return true, nil
+}
+
+// Stops the worker of a webhook, dropping the events left in its queue. Must be called with the
+// mutex held.
+func (store *webhookStore) stopQueueLocked(id string) {
+    if queue, ok := store.queues[id]; ok {
+        close(queue.stop)
+        delete(store.queues, id)
+    }
+}
+
+// Queues the events for the webhooks subscribed to their types, starting the worker of a
+// webhook on its first event. The events of each webhook are posted one at a time, in order,
+// and those that do not fit in its queue of webhooks.queue_size events are dropped.
+func (store *webhookStore) deliver(events []models.ClusterEvent) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    for _, webhook := range store.webhooks {
+        for _, event := range events {
+            if !containsString(webhook.EventTypes, event.Type) {
+                continue
+            }
+            queue, ok := store.queues[webhook.Id]
+            if !ok {
+                queue = &webhookQueue{
+                    events: make(chan models.ClusterEvent,
+                        helpers.GetConfig().Webhooks.QueueSize),
+                    stop: make(chan struct{}),
+                }
+                store.queues[webhook.Id] = queue
+                go store.runQueue(webhook.Id, queue)
+            }
+            select {
+            case queue.events <- event:
+            default:
+                queue.dropped++
+                store.logger.Errorf("dropped event %s of webhook %s as its queue is full",
+                    event.Id, webhook.Id)
+            }
+        }
+    }
+}
+
+// Posts the queued events of a webhook until it is deleted. Each event is posted to the
+// webhook as it is when the event is taken from the queue.
+func (store *webhookStore) runQueue(id string, queue *webhookQueue) {
+    for {
+        select {
+        case <-queue.stop:
+            return
+        case event := <-queue.events:
+            store.mutex.Lock()
+            webhook, ok := store.webhooks[id]
+            store.mutex.Unlock()
+            if !ok {
+                return
+            }
+            store.deliverEvent(webhook, event, queue.stop)
+        }
+    }
+}
+
+// Posts an event to a webhook, trying again after webhooks.retry_delay until it is accepted,
+// webhooks.max_attempts were made or the webhook is deleted
+func (store *webhookStore) deliverEvent(webhook storedWebhook, event models.ClusterEvent,
+    stop <-chan struct{}) {
+    webhooksConfig := helpers.GetConfig().Webhooks
+    body, err := json.Marshal(webhookPayload{WebhookId: webhook.Id, Event: event})
+    if err != nil {
+        store.logger.Errorf("failed to encode event %s: %s", event.Id, err.Error())
+        return
+    }
+    delivery := models.WebhookDelivery{EventId: event.Id}
+    for delivery.Attempts < int32(webhooksConfig.MaxAttempts) {
+        if delivery.Attempts > 0 {
+            select {
+            case <-stop:
+                return
+            case <-time.After(webhooksConfig.RetryDelay):
+            }
+        }
+        delivery.Attempts++
+        delivery.AttemptedAt = time.Now().Unix()
+        statusCode, err := postWebhook(webhook, event, body, webhooksConfig.Timeout)
+        delivery.StatusCode = statusCode
+        if err == nil {
+            delivery.Succeeded = true
+            delivery.Error = ""
+            break
+        }
+        delivery.Error = err.Error()
+    }
+    if !delivery.Succeeded {
+        store.logger.Errorf("failed to post event %s to webhook %s: %s", event.Id, webhook.Id,
+            delivery.Error)
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    if _, ok := store.webhooks[webhook.Id]; ok {
+        store.deliveries[webhook.Id] = delivery
+    }
+}
+
+// Posts the body of an event to a webhook, returning the status code of the response. Responses
+// other than 2xx are errors.
+func postWebhook(webhook storedWebhook, event models.ClusterEvent, body []byte,
+    timeout time.Duration) (int32, error) {
+    request, err := http.NewRequest(http.MethodPost, webhook.Url, bytes.NewReader(body))
+    if err != nil {
+        return 0, err
+    }
+    mac := hmac.New(sha256.New, []byte(webhook.Secret))
+    mac.Write(body)
+    request.Header.Set("Content-Type", "application/json")
+    request.Header.Set(WEBHOOK_EVENT_HEADER, event.Type)
+    request.Header.Set(WEBHOOK_DELIVERY_HEADER, event.Id)
+    request.Header.Set(WEBHOOK_SIGNATURE_HEADER, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+    response, err := helpers.NewHttpClientWithTimeout(timeout).Do(request)
+    if err != nil {
+        return 0, err
+    }
+    defer response.Body.Close()
+    if response.StatusCode < 200 || response.StatusCode >= 300 {
+        return int32(response.StatusCode), fmt.Errorf("the webhook returned %s", response.Status)
+    }
+    return int32(response.StatusCode), nil
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// Types of the failures that can be simulated, named like the levels of fault tolerance
+var WHATIF_FAILURE_TYPES = []string{
+    string(models.CLUSTERFAULTTOLERANCE_NODE),
+    string(models.CLUSTERFAULTTOLERANCE_ZONE),
+    string(models.CLUSTERFAULTTOLERANCE_REGION),
+}
+
+// Whether a failure takes down a server placed at the host, cloud, region and zone
+func isWhatIfFailed(spec models.WhatIfFailureSpec, host string, cloud string, region string,
+    zone string) bool {
+    switch models.ClusterFaultTolerance(spec.FailureType) {
+    case models.CLUSTERFAULTTOLERANCE_NODE:
+        return host == helpers.NormalizeHost(spec.Node)
+    case models.CLUSTERFAULTTOLERANCE_ZONE:
+        return (spec.Cloud == "" || cloud == spec.Cloud) && region == spec.Region &&
+            zone == spec.Zone
+    case models.CLUSTERFAULTTOLERANCE_REGION:
+        return (spec.Cloud == "" || cloud == spec.Cloud) && region == spec.Region
+    }
+    return false
+}
+
+// Checks that a failure has what its type needs, and takes down at least one node
+func validateWhatIfFailureSpec(spec models.WhatIfFailureSpec,
+    nodes []models.ClusterStateNode) error {
+    switch models.ClusterFaultTolerance(spec.FailureType) {
+    case models.CLUSTERFAULTTOLERANCE_NODE:
+        if spec.Node == "" {
+            return errors.New("node is required for a NODE failure")
+        }
+    case models.CLUSTERFAULTTOLERANCE_ZONE:
+        if spec.Region == "" || spec.Zone == "" {
+            return errors.New("region and zone are required for a ZONE failure")
+        }
+    case models.CLUSTERFAULTTOLERANCE_REGION:
+        if spec.Region == "" {
+            return errors.New("region is required for a REGION failure")
+        }
+    default:
+        return fmt.Errorf("failure_type must be one of %s, got %q",
+            strings.Join(WHATIF_FAILURE_TYPES, ", "), spec.FailureType)
+    }
+    for _, node := range nodes {
+        if isWhatIfFailed(spec, node.Name, node.Cloud, node.Region, node.Zone) {
+            return nil
+        }
+    }
+    return errors.New("the failure does not take down any node of the cluster")
+}
+
+// Simulates a failure on the current placement of the tablets and masters. Nodes that are
+// already down stay down. Tablets keep quorum while more than half of their voters are left,
+// and the leaders of the tablets that keep quorum but lose their leader are expected to move
+// to any of the voters left with the same chance.
+func getWhatIfFailure(spec models.WhatIfFailureSpec, nodes []models.ClusterStateNode,
+    tabletServers map[string]helpers.TabletServer, masters []helpers.Master,
+    tablets []models.ClusterTablet) models.WhatIfFailure {
+    whatIf := models.WhatIfFailure{
+        FailureType: spec.FailureType,
+        FailedNodes: []string{},
+        DownNodes: []string{},
+        TablesLosingQuorum: []models.WhatIfTable{},
+        Nodes: []models.WhatIfNode{},
+    }
+    down := map[string]bool{}
+    whatIfNodes := map[string]*models.WhatIfNode{}
+    for _, node := range nodes {
+        whatIfNode := &models.WhatIfNode{
+            Host: node.Name,
+            Status: node.Status,
+            Cloud: node.Cloud,
+            Region: node.Region,
+            Zone: node.Zone,
+            Fails: isWhatIfFailed(spec, node.Name, node.Cloud, node.Region, node.Zone),
+        }
+        whatIfNodes[node.Name] = whatIfNode
+        if whatIfNode.Fails {
+            whatIf.FailedNodes = append(whatIf.FailedNodes, node.Name)
+        } else if node.Status != "ALIVE" {
+            whatIf.DownNodes = append(whatIf.DownNodes, node.Name)
+        }
+        down[node.Name] = whatIfNode.Fails || node.Status != "ALIVE"
+    }
+    capacity := &whatIf.Capacity
+    for host, tabletServer := range tabletServers {
+        if !down[host] {
+            capacity.NodesLeft++
+            for _, pathMetrics := range tabletServer.PathMetrics {
+                capacity.TotalDiskBytes += int64(pathMetrics.TotalSpaceSize)
+                capacity.FreeDiskBytes += int64(pathMetrics.TotalSpaceSize) -
+                    int64(pathMetrics.SpaceUsed)
+            }
+        } else {
+            capacity.BytesToReReplicate += int64(tabletServer.TotalSstFileSizeBytes)
+        }
+    }
+    tables := map[string]*models.WhatIfTable{}
+    for _, tablet := range tablets {
+        whatIf.Tablets++
+        voters, votersLeft, replicasDown := 0, []string{}, 0
+        for _, peer := range tablet.Peers {
+            if whatIfNode, ok := whatIfNodes[peer.Host]; ok {
+                whatIfNode.Replicas++
+                if peer.Role == TABLET_PEER_LEADER {
+                    whatIfNode.Leaders++
+                }
+            }
+            if down[peer.Host] {
+                replicasDown++
+            }
+            // Observers of read replica clusters do not vote
+            if peer.Type == "OBSERVER" {
+                continue
+            }
+            voters++
+            if !down[peer.Host] {
+                votersLeft = append(votersLeft, peer.Host)
+            }
+        }
+        if voters > 0 && 2*len(votersLeft) <= voters {
+            whatIf.TabletsLosingQuorum++
+            table, ok := tables[tablet.TableUuid]
+            if !ok {
+                table = &models.WhatIfTable{
+                    Namespace: tablet.Namespace,
+                    TableName: tablet.TableName,
+                    TableUuid: tablet.TableUuid,
+                    TabletIds: []string{},
+                }
+                tables[tablet.TableUuid] = table
+            }
+            table.TabletIds = append(table.TabletIds, tablet.TabletId)
+            continue
+        }
+        if replicasDown > 0 {
+            whatIf.TabletsUnderReplicated++
+            capacity.ReplicasToReReplicate += int64(replicasDown)
+        }
+        if tablet.Leader != "" && !down[tablet.Leader] {
+            if whatIfNode, ok := whatIfNodes[tablet.Leader]; ok {
+                whatIfNode.ExpectedLeaders++
+            }
+            continue
+        }
+        whatIf.LeadersToMove++
+        for _, host := range votersLeft {
+            if whatIfNode, ok := whatIfNodes[host]; ok {
+                whatIfNode.ExpectedLeaders += 1 / float64(len(votersLeft))
+            }
+        }
+    }
+    capacity.FitsOnDisk = capacity.BytesToReReplicate <= capacity.FreeDiskBytes
+    for _, table := range tables {
+        sort.Strings(table.TabletIds)
+        whatIf.TablesLosingQuorum = append(whatIf.TablesLosingQuorum, *table)
+    }
+    sort.Slice(whatIf.TablesLosingQuorum, func(i, j int) bool {
+        left, right := whatIf.TablesLosingQuorum[i], whatIf.TablesLosingQuorum[j]
+        if left.Namespace != right.Namespace {
+            return left.Namespace < right.Namespace
+        }
+        return left.TableName < right.TableName
+    })
+    for _, node := range nodes {
+        whatIf.Nodes = append(whatIf.Nodes, *whatIfNodes[node.Name])
+    }
+    // Masters that cannot be reached are counted as down already
+    for _, master := range masters {
+        whatIf.Masters++
+        host := ""
+        if len(master.Registration.PrivateRpcAddresses) > 0 {
+            host = helpers.NormalizeHost(master.Registration.PrivateRpcAddresses[0].Host)
+        }
+        cloudInfo := master.Registration.CloudInfo
+        if master.Error == nil && !isWhatIfFailed(spec, host, cloudInfo.PlacementCloud,
+            cloudInfo.PlacementRegion, cloudInfo.PlacementZone) {
+            whatIf.MastersLeft++
+        }
+    }
+    whatIf.MasterQuorumLost = whatIf.Masters > 0 && 2*whatIf.MastersLeft <= whatIf.Masters
+    return whatIf
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/models"
+    "apiserver/cmd/server/tasks"
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/jackc/pgx/v4"
+    "github.com/yugabyte/gocql"
+)
+
+// Kinds of the sample workloads: reads and writes of a YCQL table by key, or of a YSQL table by
+// primary key
+const WORKLOAD_KEY_VALUE = "key_value"
+const WORKLOAD_SQL = "sql"
+
+var WORKLOAD_KINDS = []string{WORKLOAD_KEY_VALUE, WORKLOAD_SQL}
+
+// How often a running workload records its progress in its task
+const WORKLOAD_PROGRESS_INTERVAL = 30 * time.Second
+
+// A workload that was started, with the counts of its operations so far
+type runningWorkload struct {
+    spec models.WorkloadSpec
+    taskId string
+    startedBy string
+    startedAt time.Time
+    // Cancels the workload when it is stopped
+    ctx context.Context
+    cancel context.CancelFunc
+    operations int64
+    failures int64
+    latencyNanos int64
+    // The error of the latest operation that failed
+    lastError atomic.Value
+}
+
+func (workload *runningWorkload) add(latency time.Duration, err error) {
+    atomic.AddInt64(&workload.operations, 1)
+    atomic.AddInt64(&workload.latencyNanos, int64(latency))
+    if err != nil {
+        atomic.AddInt64(&workload.failures, 1)
+        workload.lastError.Store(err.Error())
+    }
+}
+
+// Must be called with the mutex of the tracker held, or once the task id is set
+func (workload *runningWorkload) getModel() models.Workload {
+    model := models.Workload{
+        TaskId: workload.taskId,
+        Workload: workload.spec.Workload,
+        Threads: workload.spec.Threads,
+        DurationSeconds: workload.spec.DurationSeconds,
+        ReadPercent: workload.spec.ReadPercent,
+        StartedBy: workload.startedBy,
+        StartedAt: workload.startedAt.Unix(),
+        Operations: atomic.LoadInt64(&workload.operations),
+        Failures: atomic.LoadInt64(&workload.failures),
+    }
+    if elapsed := time.Since(workload.startedAt).Seconds(); elapsed > 0 {
+        model.OpsPerSecond = float64(model.Operations) / elapsed
+    }
+    if model.Operations > 0 {
+        latencyMs := float64(atomic.LoadInt64(&workload.latencyNanos)) /
+            float64(model.Operations) / float64(time.Millisecond)
+        model.AvgLatencyMs = &latencyMs
+    }
+    if lastError, ok := workload.lastError.Load().(string); ok {
+        model.LastError = &lastError
+    }
+    return model
+}
+
+// Keeps the workloads while they run, so that they can be listed and stopped
+type workloadTracker struct {
+    mutex sync.Mutex
+    workloads []*runningWorkload
+}
+
+func newWorkloadTracker() *workloadTracker {
+    return &workloadTracker{workloads: []*runningWorkload{}}
+}
+
+// Adds a workload unless workloads.max_running are already running
+func (tracker *workloadTracker) add(workload *runningWorkload) bool {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    if len(tracker.workloads) >= helpers.GetConfig().Workloads.MaxRunning {
+        return false
+    }
+    tracker.workloads = append(tracker.workloads, workload)
+    return true
+}
+
+func (tracker *workloadTracker) remove(workload *runningWorkload) {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    for index, running := range tracker.workloads {
+        if running == workload {
+            tracker.workloads = append(tracker.workloads[:index],
+                tracker.workloads[index+1:]...)
+            return
+        }
+    }
+}
+
+func (tracker *workloadTracker) setTaskId(workload *runningWorkload, taskId string) {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    workload.taskId = taskId
+}
+
+// Gets the running workloads, oldest first
+func (tracker *workloadTracker) list() []models.Workload {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    workloads := []models.Workload{}
+    for _, workload := range tracker.workloads {
+        workloads = append(workloads, workload.getModel())
+    }
+    return workloads
+}
+
+// Stops the workload of a task. Returns false if no running workload has the task.
+func (tracker *workloadTracker) stop(taskId string) bool {
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+    for _, workload := range tracker.workloads {
+        if workload.taskId == taskId {
+            workload.cancel()
+            return true
+        }
+    }
+    return false
+}
+
+// Checks a workload against the limits of the config
+func validateWorkloadSpec(spec models.WorkloadSpec) error {
+    workloadsConfig := helpers.GetConfig().Workloads
+    if !containsString(WORKLOAD_KINDS, spec.Workload) {
+        return fmt.Errorf("workload must be %s or %s, got %q", WORKLOAD_KEY_VALUE, WORKLOAD_SQL,
+            spec.Workload)
+    }
+    if spec.Threads < 1 || int(spec.Threads) > workloadsConfig.MaxThreads {
+        return fmt.Errorf("threads must be between 1 and %d", workloadsConfig.MaxThreads)
+    }
+    maxSeconds := int64(workloadsConfig.MaxDuration.Seconds())
+    if spec.DurationSeconds < 1 || spec.DurationSeconds > maxSeconds {
+        return fmt.Errorf("duration_seconds must be between 1 and %d", maxSeconds)
+    }
+    if spec.ReadPercent < 0 || spec.ReadPercent > 100 {
+        return errors.New("read_percent must be between 0 and 100")
+    }
+    return nil
+}
+
+// An operation of a workload: a read or a write of the row with the key
+type workloadOperation func(ctx context.Context, read bool, key int64, value string) error
+
+// Runs a workload until its duration is over or it is stopped. Fails if the table cannot be
+// created, or if no operation succeeded.
+func (c *Container) runWorkload(task *tasks.Task, workload *runningWorkload) error {
+    workloadsConfig := helpers.GetConfig().Workloads
+    ctx, cancel := context.WithTimeout(workload.ctx,
+        time.Duration(workload.spec.DurationSeconds)*time.Second)
+    defer cancel()
+    getOperations := getSqlOperations
+    if workload.spec.Workload == WORKLOAD_KEY_VALUE {
+        getOperations = c.getKeyValueOperations
+    }
+    operations, closeOperations, err := getOperations(ctx, task, workloadsConfig,
+        int(workload.spec.Threads))
+    if err != nil {
+        return err
+    }
+    defer closeOperations()
+    task.Progress("running %d threads for %d seconds, %d%% reads", workload.spec.Threads,
+        workload.spec.DurationSeconds, workload.spec.ReadPercent)
+    waitGroup := sync.WaitGroup{}
+    for index, operation := range operations {
+        operation := operation
+        random := rand.New(rand.NewSource(time.Now().UnixNano() + int64(index)))
+        waitGroup.Add(1)
+        go func() {
+            defer waitGroup.Done()
+            for ctx.Err() == nil {
+                read := random.Int31n(100) < workload.spec.ReadPercent
+                key := random.Int63n(workloadsConfig.MaxKeys)
+                start := time.Now()
+                err := operation(ctx, read, key, fmt.Sprintf("%d-%d", key, start.UnixNano()))
+                if ctx.Err() != nil {
+                    // Operations cut short by the end of the workload are not counted
+                    return
+                }
+                workload.add(time.Since(start), err)
+            }
+        }()
+    }
+    done := make(chan struct{})
+    go func() {
+        waitGroup.Wait()
+        close(done)
+    }()
+    ticker := time.NewTicker(WORKLOAD_PROGRESS_INTERVAL)
+    defer ticker.Stop()
+    for running := true; running; {
+        select {
+        case <-ticker.C:
+            model := workload.getModel()
+            task.Progress("%d operations, %d failed, %.1f operations per second",
+                model.Operations, model.Failures, model.OpsPerSecond)
+        case <-done:
+            running = false
+        }
+    }
+    model := workload.getModel()
+    if workload.ctx.Err() != nil {
+        task.Progress("stopped")
+    }
+    task.Progress("ran %d operations, %d failed, %.1f operations per second", model.Operations,
+        model.Failures, model.OpsPerSecond)
+    if model.AvgLatencyMs != nil {
+        task.Progress("average latency %.2f ms", *model.AvgLatencyMs)
+    }
+    if model.Operations > 0 && model.Failures == model.Operations {
+        return fmt.Errorf("every operation failed, the latest with: %s", *model.LastError)
+    }
+    return nil
+}
+
+// Creates the YCQL keyspace and table of the key_value workload, and gets an operation for each
+// thread, and a function that closes them. The threads share the YCQL session of the server.
+func (c *Container) getKeyValueOperations(ctx context.Context, task *tasks.Task,
+    workloadsConfig helpers.WorkloadsConfig, threads int) ([]workloadOperation, func(), error) {
+    session, err := c.getYcqlSession()
+    if err != nil {
+        return nil, nil, err
+    }
+    keyspace := quoteCqlIdentifier(workloadsConfig.Keyspace)
+    table := keyspace + "." + quoteCqlIdentifier(workloadsConfig.Table)
+    task.Progress("creating table %s", table)
+    err = session.Query("CREATE KEYSPACE IF NOT EXISTS " + keyspace).WithContext(ctx).Exec()
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to create keyspace %s: %w", keyspace, err)
+    }
+    err = session.Query("CREATE TABLE IF NOT EXISTS " + table +
+        " (k bigint PRIMARY KEY, v text)").WithContext(ctx).Exec()
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to create table %s: %w", table, err)
+    }
+    operation := func(ctx context.Context, read bool, key int64, value string) error {
+        if read {
+            var readValue string
+            err := session.Query("SELECT v FROM "+table+" WHERE k = ?", key).WithContext(ctx).
+                Scan(&readValue)
+            if err == gocql.ErrNotFound {
+                return nil
+            }
+            return err
+        }
+        return session.Query("INSERT INTO "+table+" (k, v) VALUES (?, ?)", key, value).
+            WithContext(ctx).Exec()
+    }
+    operations := []workloadOperation{}
+    for index := 0; index < threads; index++ {
+        operations = append(operations, operation)
+    }
+    return operations, func() {}, nil
+}
+
+// Creates the YSQL table of the sql workload, and gets an operation for each thread, and a
+// function that closes them. Each thread has a connection of its own.
+func getSqlOperations(ctx context.Context, task *tasks.Task,
+    workloadsConfig helpers.WorkloadsConfig, threads int) ([]workloadOperation, func(), error) {
+    conns := []*pgx.Conn{}
+    closeConns := func() {
+        for _, conn := range conns {
+            conn.Close(context.Background())
+        }
+    }
+    connectionUrl := helpers.GetYsqlConnectionUrl(workloadsConfig.Database)
+    for index := 0; index < threads; index++ {
+        conn, err := pgx.Connect(ctx, connectionUrl)
+        if err != nil {
+            closeConns()
+            return nil, nil, err
+        }
+        conns = append(conns, conn)
+    }
+    table := pgx.Identifier{workloadsConfig.Table}.Sanitize()
+    task.Progress("creating table %s in database %s", table, workloadsConfig.Database)
+    _, err := conns[0].Exec(ctx, "CREATE TABLE IF NOT EXISTS "+table+
+        " (k bigint PRIMARY KEY, v text NOT NULL)")
+    if err != nil {
+        closeConns()
+        return nil, nil, fmt.Errorf("failed to create table %s: %w", table, err)
+    }
+    operations := []workloadOperation{}
+    for _, conn := range conns {
+        conn := conn
+        operations = append(operations,
+            func(ctx context.Context, read bool, key int64, value string) error {
+                if read {
+                    var readValue string
+                    err := conn.QueryRow(ctx, "SELECT v FROM "+table+" WHERE k = $1", key).
+                        Scan(&readValue)
+                    if errors.Is(err, pgx.ErrNoRows) {
+                        return nil
+                    }
+                    return err
+                }
+                _, err := conn.Exec(ctx, "INSERT INTO "+table+" (k, v) VALUES ($1, $2) "+
+                    "ON CONFLICT (k) DO UPDATE SET v = EXCLUDED.v", key, value)
+                return err
+            })
+    }
+    return operations, closeConns, nil
+}
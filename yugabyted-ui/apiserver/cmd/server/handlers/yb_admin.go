@@ -0,0 +1,213 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// How the output of a yb-admin command is parsed
+type ybAdminOutputKind int
+
+const (
+    YB_ADMIN_OUTPUT_TEXT ybAdminOutputKind = iota
+    // A header row and rows, with columns separated by tabs
+    YB_ADMIN_OUTPUT_TABLE
+    YB_ADMIN_OUTPUT_JSON
+)
+
+// Arguments cannot start with a dash, so that they cannot be taken for flags
+var YB_ADMIN_NAME_REGEX = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_$]*$`)
+var YB_ADMIN_KEYSPACE_REGEX = regexp.MustCompile(`^((ysql|ycql)\.)?[A-Za-z0-9_][A-Za-z0-9_$]*$`)
+var YB_ADMIN_ID_REGEX = regexp.MustCompile(`^[0-9a-fA-F-]{32,36}$`)
+var YB_ADMIN_COUNT_REGEX = regexp.MustCompile(`^[0-9]+$`)
+
+// Lines that glog writes to stderr, which are mixed in with the output
+var YB_ADMIN_LOG_LINE_REGEX = regexp.MustCompile(`^[IWEF][0-9]{4} `)
+
+type ybAdminArg struct {
+    name string
+    pattern *regexp.Regexp
+    optional bool
+}
+
+type ybAdminCommand struct {
+    description string
+    args []ybAdminArg
+    // Whether the last argument can be given any number of times
+    repeatLast bool
+    output ybAdminOutputKind
+}
+
+// The yb-admin commands that can be run from the UI. They only read the state of the cluster.
+var YB_ADMIN_COMMANDS = map[string]ybAdminCommand{
+    "list_all_masters": {
+        description: "List the masters and their roles",
+        output: YB_ADMIN_OUTPUT_TABLE,
+    },
+    "list_all_tablet_servers": {
+        description: "List the tservers",
+        output: YB_ADMIN_OUTPUT_TABLE,
+    },
+    "list_tablet_servers": {
+        description: "List the tservers that host a tablet",
+        args: []ybAdminArg{{name: "tablet_id", pattern: YB_ADMIN_ID_REGEX}},
+        output: YB_ADMIN_OUTPUT_TABLE,
+    },
+    "list_tables": {
+        description: "List the tables",
+        args: []ybAdminArg{{
+            name: "include_db_type|include_table_id|include_table_type",
+            pattern: regexp.MustCompile(`^(include_db_type|include_table_id|include_table_type)$`),
+            optional: true,
+        }},
+        repeatLast: true,
+        output: YB_ADMIN_OUTPUT_TEXT,
+    },
+    "list_tablets": {
+        description: "List the tablets of a table and their leaders",
+        args: []ybAdminArg{
+            {name: "keyspace", pattern: YB_ADMIN_KEYSPACE_REGEX},
+            {name: "table_name", pattern: YB_ADMIN_NAME_REGEX},
+            {name: "max_tablets", pattern: YB_ADMIN_COUNT_REGEX, optional: true},
+        },
+        output: YB_ADMIN_OUTPUT_TABLE,
+    },
+    "get_universe_config": {
+        description: "Get the cluster config",
+        output: YB_ADMIN_OUTPUT_JSON,
+    },
+    "get_load_move_completion": {
+        description: "Get the progress of moving data off blacklisted tservers",
+        output: YB_ADMIN_OUTPUT_TEXT,
+    },
+    "get_leader_blacklist_completion": {
+        description: "Get the progress of moving leaders off leader blacklisted tservers",
+        output: YB_ADMIN_OUTPUT_TEXT,
+    },
+    "get_is_load_balancer_idle": {
+        description: "Check whether the load balancer is idle",
+        output: YB_ADMIN_OUTPUT_TEXT,
+    },
+    "list_snapshots": {
+        description: "List the snapshots",
+        args: []ybAdminArg{{
+            name: "SHOW_DETAILS|NOT_SHOW_RESTORED",
+            pattern: regexp.MustCompile(`^(SHOW_DETAILS|NOT_SHOW_RESTORED)$`),
+            optional: true,
+        }},
+        repeatLast: true,
+        output: YB_ADMIN_OUTPUT_TEXT,
+    },
+    "list_snapshot_schedules": {
+        description: "List the snapshot schedules, or one of them",
+        args: []ybAdminArg{{name: "schedule_id", pattern: YB_ADMIN_ID_REGEX, optional: true}},
+        output: YB_ADMIN_OUTPUT_JSON,
+    },
+    "ysql_catalog_version": {
+        description: "Get the version of the YSQL catalog",
+        output: YB_ADMIN_OUTPUT_TEXT,
+    },
+}
+
+// Gets the usage of a command, like yb-admin prints it
+func (command ybAdminCommand) usage(name string) string {
+    usage := name
+    for index, arg := range command.args {
+        argUsage := "<" + arg.name + ">"
+        if command.repeatLast && index == len(command.args)-1 {
+            argUsage += "..."
+        }
+        if arg.optional {
+            argUsage = "[" + argUsage + "]"
+        }
+        usage += " " + argUsage
+    }
+    return usage
+}
+
+// Checks that the arguments are the ones the command takes
+func (command ybAdminCommand) validate(name string, args []string) error {
+    for index, value := range args {
+        if index >= len(command.args) && !command.repeatLast {
+            return fmt.Errorf("too many arguments, usage: %s", command.usage(name))
+        }
+        arg := command.args[len(command.args)-1]
+        if index < len(command.args) {
+            arg = command.args[index]
+        }
+        if !arg.pattern.MatchString(value) {
+            return fmt.Errorf("invalid %s: %q", arg.name, value)
+        }
+    }
+    for index := len(args); index < len(command.args); index++ {
+        if !command.args[index].optional {
+            return fmt.Errorf("missing %s, usage: %s", command.args[index].name,
+                command.usage(name))
+        }
+    }
+    return nil
+}
+
+func getYbAdminCommands() []models.YbAdminCommand {
+    commands := []models.YbAdminCommand{}
+    for name, command := range YB_ADMIN_COMMANDS {
+        commands = append(commands, models.YbAdminCommand{
+            Name: name,
+            Usage: command.usage(name),
+            Description: command.description,
+        })
+    }
+    sort.Slice(commands, func(i, j int) bool {
+        return commands[i].Name < commands[j].Name
+    })
+    return commands
+}
+
+// Parses the output of a command into a table or a JSON document, keeping the text as is
+func parseYbAdminOutput(name string, args []string, output string,
+    kind ybAdminOutputKind) (models.YbAdminOutput, error) {
+    parsed := models.YbAdminOutput{
+        Command: name,
+        Args: args,
+        Output: output,
+    }
+    lines := []string{}
+    for _, line := range strings.Split(output, "\n") {
+        if strings.TrimSpace(line) != "" && !YB_ADMIN_LOG_LINE_REGEX.MatchString(line) {
+            lines = append(lines, line)
+        }
+    }
+    switch kind {
+    case YB_ADMIN_OUTPUT_TABLE:
+        rows := [][]string{}
+        for _, line := range lines {
+            columns := strings.Split(line, "\t")
+            for index := range columns {
+                columns[index] = strings.TrimSpace(columns[index])
+            }
+            rows = append(rows, columns)
+        }
+        if len(rows) == 0 {
+            return parsed, fmt.Errorf("unexpected %s output: %s", name, output)
+        }
+        columns, values := rows[0], rows[1:]
+        parsed.Columns = &columns
+        parsed.Rows = &values
+    case YB_ADMIN_OUTPUT_JSON:
+        text := strings.Join(lines, "\n")
+        start := strings.IndexAny(text, "{[")
+        if start < 0 {
+            return parsed, fmt.Errorf("unexpected %s output: %s", name, output)
+        }
+        var document interface{}
+        if err := json.NewDecoder(strings.NewReader(text[start:])).Decode(&document); err != nil {
+            return parsed, fmt.Errorf("failed to parse %s output: %s", name, err.Error())
+        }
+        parsed.Json = document
+    }
+    return parsed, nil
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+    "apiserver/cmd/server/logger"
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/yugabyte/gocql"
+)
+
+// Backoff between attempts to create the YCQL session, doubling up to the maximum
+const YCQL_SESSION_MIN_BACKOFF = 1 * time.Second
+const YCQL_SESSION_MAX_BACKOFF = 1 * time.Minute
+
+// How often the YCQL session is checked, and created if it does not exist yet
+const YCQL_SESSION_HEALTH_CHECK_INTERVAL = 30 * time.Second
+
+const YCQL_HEALTH_CHECK_CQL string = "SELECT now() FROM system.local"
+
+// Creates the YCQL session on first use, so that the server can start before YCQL is up, and
+// creates it again when it stops working
+type ycqlSessionManager struct {
+    mutex sync.Mutex
+    cluster *gocql.ClusterConfig
+    session *gocql.Session
+    lastErr error
+    backoff time.Duration
+    nextAttempt time.Time
+    logger logger.Logger
+}
+
+func newYcqlSessionManager(log logger.Logger, cluster *gocql.ClusterConfig) *ycqlSessionManager {
+    manager := &ycqlSessionManager{cluster: cluster, logger: log}
+    go manager.checkHealth()
+    return manager
+}
+
+// Gets the session, creating it if needed. Failed attempts are not retried before the backoff
+// has passed, so that requests fail fast while YCQL is down.
+func (manager *ycqlSessionManager) get() (*gocql.Session, error) {
+    manager.mutex.Lock()
+    defer manager.mutex.Unlock()
+    if manager.session != nil && !manager.session.Closed() {
+        return manager.session, nil
+    }
+    if time.Now().Before(manager.nextAttempt) {
+        return nil, fmt.Errorf("YCQL is unavailable, retrying in %s: %s",
+            time.Until(manager.nextAttempt).Round(time.Second), manager.lastErr.Error())
+    }
+    session, err := manager.cluster.CreateSession()
+    if err != nil {
+        if manager.backoff == 0 {
+            manager.backoff = YCQL_SESSION_MIN_BACKOFF
+        } else if manager.backoff *= 2; manager.backoff > YCQL_SESSION_MAX_BACKOFF {
+            manager.backoff = YCQL_SESSION_MAX_BACKOFF
+        }
+        manager.nextAttempt = time.Now().Add(manager.backoff)
+        manager.lastErr = err
+        manager.logger.Errorf("Error initializing the gocql session: %s", err.Error())
+        return nil, fmt.Errorf("YCQL is unavailable: %s", err.Error())
+    }
+    if manager.lastErr != nil {
+        manager.logger.Infof("Initialized the gocql session")
+    }
+    manager.session = session
+    manager.lastErr = nil
+    manager.backoff = 0
+    manager.nextAttempt = time.Time{}
+    return session, nil
+}
+
+// Drops the session if it is the given one, so that the next use creates a new one
+func (manager *ycqlSessionManager) reset(session *gocql.Session) {
+    manager.mutex.Lock()
+    defer manager.mutex.Unlock()
+    if manager.session == session {
+        manager.session = nil
+        session.Close()
+    }
+}
+
+// Creates the session in the background once YCQL is up, and replaces it if it stops working
+func (manager *ycqlSessionManager) checkHealth() {
+    for {
+        session, err := manager.get()
+        if err == nil {
+            if err := session.Query(YCQL_HEALTH_CHECK_CQL).Exec(); err != nil {
+                manager.logger.Errorf("gocql session health check failed, recreating it: %s",
+                    err.Error())
+                manager.reset(session)
+            }
+        }
+        time.Sleep(YCQL_SESSION_HEALTH_CHECK_INTERVAL)
+    }
+}
+
+func (manager *ycqlSessionManager) close() {
+    manager.mutex.Lock()
+    defer manager.mutex.Unlock()
+    if manager.session != nil {
+        manager.session.Close()
+        manager.session = nil
+    }
+}
@@ -0,0 +1,39 @@
+package helpers
+
+import (
+    "net"
+    "strconv"
+    "strings"
+)
+
+// Gets the canonical form of a host, so that hosts can be compared. IPv6 literals lose their
+// brackets and are shortened, hostnames are lowercased.
+func NormalizeHost(host string) string {
+    host = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(host), "["), "]")
+    if ip := net.ParseIP(host); ip != nil {
+        return ip.String()
+    }
+    return strings.ToLower(host)
+}
+
+// Gets the normalized host of a host:port address
+func GetHostFromAddress(address string) (string, error) {
+    host, _, err := net.SplitHostPort(address)
+    if err != nil {
+        return "", err
+    }
+    return NormalizeHost(host), nil
+}
+
+// Brackets IPv6 literals, for places that expect a host followed by a colon, like URLs
+func BracketHost(host string) string {
+    if strings.Contains(host, ":") {
+        return "[" + host + "]"
+    }
+    return host
+}
+
+// Builds the url of a YugabyteDB web endpoint. The path includes the leading slash.
+func GetHttpUrl(host string, port int, path string) string {
+    return "http://" + net.JoinHostPort(host, strconv.Itoa(port)) + path
+}
@@ -0,0 +1,120 @@
+package helpers
+
+import (
+    "crypto"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/pem"
+    "errors"
+    "io/ioutil"
+    "math/big"
+    "time"
+)
+
+const CLIENT_KEY_BITS = 2048
+
+type CertificateAuthority struct {
+    Cert *x509.Certificate
+    // The root certificate as read from disk, in PEM format
+    CertPem string
+    Key crypto.Signer
+}
+
+// Reads the PEM encoded root certificate of the cluster
+func ReadRootCertPem() (string, error) {
+    if SslRootCert == "" {
+        return "", errors.New("no root certificate is configured")
+    }
+    certPem, err := ioutil.ReadFile(SslRootCert)
+    if err != nil {
+        return "", err
+    }
+    return string(certPem), nil
+}
+
+// Parses a PEM encoded private key in any of the formats written by openssl
+func parsePrivateKeyPem(keyPem []byte) (crypto.Signer, error) {
+    block, _ := pem.Decode(keyPem)
+    if block == nil {
+        return nil, errors.New("could not decode the root key")
+    }
+    if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+        return key, nil
+    }
+    if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+        return key, nil
+    }
+    key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+    if err != nil {
+        return nil, err
+    }
+    signer, ok := key.(crypto.Signer)
+    if !ok {
+        return nil, errors.New("unsupported root key type")
+    }
+    return signer, nil
+}
+
+// Loads the root certificate and its key, which is only possible if the key was configured
+func LoadCertificateAuthority() (CertificateAuthority, error) {
+    ca := CertificateAuthority{}
+    if SslRootKey == "" {
+        return ca, errors.New("no root key is configured")
+    }
+    certPem, err := ReadRootCertPem()
+    if err != nil {
+        return ca, err
+    }
+    block, _ := pem.Decode([]byte(certPem))
+    if block == nil {
+        return ca, errors.New("could not decode the root certificate")
+    }
+    ca.Cert, err = x509.ParseCertificate(block.Bytes)
+    if err != nil {
+        return ca, err
+    }
+    ca.CertPem = certPem
+    keyPem, err := ioutil.ReadFile(SslRootKey)
+    if err != nil {
+        return ca, err
+    }
+    ca.Key, err = parsePrivateKeyPem(keyPem)
+    return ca, err
+}
+
+// Generates a client certificate for the given user, returning the certificate and key in PEM
+// format
+func GenerateClientCert(ca CertificateAuthority, username string, validity time.Duration) (
+    string, string, error) {
+    key, err := rsa.GenerateKey(rand.Reader, CLIENT_KEY_BITS)
+    if err != nil {
+        return "", "", err
+    }
+    serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+    if err != nil {
+        return "", "", err
+    }
+    now := time.Now()
+    template := &x509.Certificate{
+        SerialNumber: serialNumber,
+        Subject: pkix.Name{
+            CommonName: username,
+        },
+        NotBefore: now.Add(-time.Minute),
+        NotAfter: now.Add(validity),
+        KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+        ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+    }
+    certDer, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &key.PublicKey, ca.Key)
+    if err != nil {
+        return "", "", err
+    }
+    certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer})
+    keyPem := pem.EncodeToMemory(&pem.Block{
+        Type: "RSA PRIVATE KEY",
+        Bytes: x509.MarshalPKCS1PrivateKey(key),
+    })
+    return string(certPem), string(keyPem), nil
+}
@@ -2,10 +2,7 @@ package helpers
 
 import (
     "encoding/json"
-    "fmt"
     "io/ioutil"
-    "net/http"
-    "time"
 )
 
 type PlacementBlock struct {
@@ -16,6 +13,7 @@ type PlacementBlock struct {
 type LiveReplicasStruct struct {
     NumReplicas     int              `json:"num_replicas"`
     PlacementBlocks []PlacementBlock `json:"placement_blocks"`
+    PlacementUuid   string           `json:"placement_uuid"`
 }
 
 type ReplicationInfoStruct struct {
@@ -46,24 +44,23 @@ func GetClusterConfigFuture(nodeHost string, future chan ClusterConfigFuture) {
         ClusterConfig: ClusterConfigStruct{},
         Error: nil,
     }
-    httpClient := &http.Client{
-        Timeout: time.Second * 10,
-    }
-    url := fmt.Sprintf("http://%s:7000/api/v1/cluster-config", nodeHost)
+    url := GetHttpUrl(nodeHost, GetConfig().Upstream.MasterHttpPort, "/api/v1/cluster-config")
+    httpClient := NewUpstreamHttpClient(url)
     resp, err := httpClient.Get(url)
     if err != nil {
-        clusterConfig.Error = err
+        clusterConfig.Error = NewNodeRequestError(url, err)
         future <- clusterConfig
         return
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        clusterConfig.Error = err
+        clusterConfig.Error = NewNodeRequestError(url, err)
         future <- clusterConfig
         return
     }
-    err = json.Unmarshal([]byte(body), &clusterConfig.ClusterConfig)
-    clusterConfig.Error = err
+    if err := json.Unmarshal([]byte(body), &clusterConfig.ClusterConfig); err != nil {
+        clusterConfig.Error = NewNodeParseError(url, err)
+    }
     future <- clusterConfig
 }
@@ -0,0 +1,1650 @@
+package helpers
+
+import (
+    "bytes"
+    "flag"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "net"
+    "net/url"
+    "os"
+    "path/filepath"
+    "reflect"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "golang.org/x/crypto/bcrypt"
+    "gopkg.in/yaml.v3"
+)
+
+// Environment variables named YUGABYTED_UI_<SECTION>_<KEY> override the config file
+const CONFIG_ENV_PREFIX = "YUGABYTED_UI_"
+
+const CONFIG_FILE_ENV = "YUGABYTED_UI_CONFIG_FILE"
+
+// Kept for compatibility, the same as YUGABYTED_UI_SERVER_PORT
+const LEGACY_SERVER_PORT_ENV = "YUGABYTED_UI_PORT"
+
+var SSL_MODES = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Metrics are read from the YCQL metrics table, the only supported backend
+var METRICS_BACKENDS = []string{"ycql"}
+
+// The local store keeps the state of the server in a bolt file, or only in memory
+var STORE_BACKENDS = []string{"bolt", "memory"}
+
+var CQL_IDENTIFIER_REGEX = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var METRIC_NAME_REGEX = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+var YCQL_CONSISTENCY_LEVELS = []string{"any", "one", "two", "three", "quorum", "all",
+    "local_quorum", "each_quorum", "local_one"}
+
+const YCQL_POLICY_ROUND_ROBIN = "round_robin"
+const YCQL_POLICY_TOKEN_AWARE = "token_aware"
+const YCQL_POLICY_DC_AWARE = "dc_aware"
+
+var YCQL_HOST_SELECTION_POLICIES = []string{YCQL_POLICY_ROUND_ROBIN, YCQL_POLICY_TOKEN_AWARE,
+    YCQL_POLICY_DC_AWARE}
+
+var LOG_LEVELS = []string{"debug", "info", "warn", "error"}
+
+type ServerConfig struct {
+    ListenAddress string `yaml:"listen_address"`
+    Port int `yaml:"port"`
+    // URL path prefix, for serving the UI behind a reverse proxy that does not strip it
+    BasePath string `yaml:"base_path"`
+    // How often the config file is checked for changes, 0 to only reload it on SIGHUP
+    ConfigWatchInterval time.Duration `yaml:"config_watch_interval"`
+    // PEM files of the certificate and key to serve HTTPS with, empty to serve plain HTTP
+    TlsCertFile string `yaml:"tls_cert_file"`
+    TlsKeyFile string `yaml:"tls_key_file"`
+    // Whether HTTP/2 is negotiated with the clients that support it over HTTPS
+    Http2 bool `yaml:"http2"`
+    // Whether HTTP/2 is also served over plain HTTP, to clients that start with it or upgrade
+    // to it, e.g. behind a proxy that terminates TLS
+    H2c bool `yaml:"h2c"`
+    // Number of requests a client can have in flight on each HTTP/2 connection
+    Http2MaxConcurrentStreams uint32 `yaml:"http2_max_concurrent_streams"`
+}
+
+// The debug endpoints profile the server itself, on a port of their own so that they are not
+// exposed along with the UI
+type DebugConfig struct {
+    Enabled bool `yaml:"enabled"`
+    ListenAddress string `yaml:"listen_address"`
+    Port int `yaml:"port"`
+}
+
+// Protects the mutating endpoints from cross-site requests, by requiring them to echo a token
+// that the server sets in a cookie, which other sites cannot read
+type CsrfConfig struct {
+    Enabled bool `yaml:"enabled"`
+    // How long the token cookie is kept by the browser
+    CookieMaxAge time.Duration `yaml:"cookie_max_age"`
+}
+
+// Browser users log in with local credentials and get a session cookie, which the API requires
+// while sessions are enabled
+type SessionsConfig struct {
+    // Sessions are also required once users are set. Without either, the API, including the
+    // endpoints that change the cluster or role passwords, is open to anyone who can reach the
+    // server, except for requests with an API token.
+    Enabled bool `yaml:"enabled"`
+    // bcrypt hashes of the passwords of the users that can log in, by username
+    Users map[string]string `yaml:"users"`
+    // A session ends when it was not used for this long
+    IdleTimeout time.Duration `yaml:"idle_timeout"`
+    // A session ends this long after the login, even if it is in use
+    AbsoluteTimeout time.Duration `yaml:"absolute_timeout"`
+    // Logging in once more ends the oldest session of the user, 0 for no limit
+    MaxSessionsPerUser int `yaml:"max_sessions_per_user"`
+    // Logins of a username are refused for the rest of the window once this many failed in
+    // it, 0 for no limit
+    MaxFailedLogins int `yaml:"max_failed_logins"`
+    FailedLoginWindow time.Duration `yaml:"failed_login_window"`
+}
+
+// Automation authenticates with long-lived API tokens instead of a session
+type ApiTokensConfig struct {
+    // Where the tokens were kept before the local store, read once to import them into it.
+    // Only hashes of the tokens are written.
+    File string `yaml:"file"`
+}
+
+// The name, description, owner and tags given to clusters in the UI, by cluster uuid
+type ClusterMetadataConfig struct {
+    File string `yaml:"file"`
+}
+
+type LogConfig struct {
+    Level string `yaml:"level"`
+}
+
+type DatabaseConfig struct {
+    // Advertise address of the local YugabyteDB node, all upstream requests go to it
+    Host string `yaml:"host"`
+    YsqlPort int `yaml:"ysql_port"`
+    Name string `yaml:"name"`
+    // Largest number of YSQL connections to the default database that the handlers share
+    MaxConns int `yaml:"max_conns"`
+}
+
+type AuthConfig struct {
+    YsqlUsername string `yaml:"ysql_username"`
+    YcqlUsername string `yaml:"ycql_username"`
+    Password string `yaml:"password"`
+}
+
+type TlsConfig struct {
+    Enabled bool `yaml:"enabled"`
+    SslMode string `yaml:"ssl_mode"`
+    RootCert string `yaml:"root_cert"`
+    RootKey string `yaml:"root_key"`
+}
+
+type UpstreamConfig struct {
+    MasterHttpPort int `yaml:"master_http_port"`
+    TserverHttpPort int `yaml:"tserver_http_port"`
+    YsqlHttpPort int `yaml:"ysql_http_port"`
+    YcqlHttpPort int `yaml:"ycql_http_port"`
+    MasterRpcPort int `yaml:"master_rpc_port"`
+    TserverRpcPort int `yaml:"tserver_rpc_port"`
+    YcqlPort int `yaml:"ycql_port"`
+    NodeExporterPort int `yaml:"node_exporter_port"`
+    // Timeout of the background polls of the node_exporter of the nodes for their cores and
+    // memory
+    NodeExporterTimeout time.Duration `yaml:"node_exporter_timeout"`
+    // How long resolved node hostnames are cached, 0 to resolve them for every connection
+    DnsCacheTtl time.Duration `yaml:"dns_cache_ttl"`
+    // How long the map between the hosts and uuids of the tservers is cached
+    HostToUuidTtl time.Duration `yaml:"host_to_uuid_ttl"`
+    // How often the tservers are listed to notice nodes joining or leaving, and the
+    // node_exporter of each asked for its cores and memory
+    NodePollInterval time.Duration `yaml:"node_poll_interval"`
+    // Number of calls to the nodes that one API request makes at once, the others wait for one
+    // of them to finish. 0 for no limit.
+    MaxConcurrentCalls int `yaml:"max_concurrent_calls"`
+}
+
+// Proxy for the requests to the web endpoints of the nodes, by default taken from the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+type ProxyConfig struct {
+    HttpProxy string `yaml:"http_proxy"`
+    HttpsProxy string `yaml:"https_proxy"`
+    // Comma separated hosts, domains and CIDRs that are reached directly
+    NoProxy string `yaml:"no_proxy"`
+}
+
+type YcqlConfig struct {
+    // Connections to open to each host
+    NumConns int `yaml:"num_conns"`
+    Consistency string `yaml:"consistency"`
+    ConnectTimeout time.Duration `yaml:"connect_timeout"`
+    // round_robin, token_aware or dc_aware
+    HostSelectionPolicy string `yaml:"host_selection_policy"`
+    // Data center whose hosts are preferred, required by dc_aware and optional for token_aware
+    LocalDc string `yaml:"local_dc"`
+}
+
+type MetricsConfig struct {
+    Backend string `yaml:"backend"`
+    // Where yugabyted writes the metrics, for deployments that relocate or rename the table
+    Keyspace string `yaml:"keyspace"`
+    Table string `yaml:"table"`
+    // Names of the metrics as stored in the metric column of the table
+    CpuUsageUserMetric string `yaml:"cpu_usage_user_metric"`
+    CpuUsageSystemMetric string `yaml:"cpu_usage_system_metric"`
+    TotalDiskMetric string `yaml:"total_disk_metric"`
+    FreeDiskMetric string `yaml:"free_disk_metric"`
+    NodeUpMetric string `yaml:"node_up_metric"`
+    // The count metrics count the total number of accumulated ops, and the sum metrics count
+    // the total amount of time spent on ops
+    ReadCountMetric string `yaml:"read_count_metric"`
+    WriteCountMetric string `yaml:"write_count_metric"`
+    ReadSumMetric string `yaml:"read_sum_metric"`
+    WriteSumMetric string `yaml:"write_sum_metric"`
+    // Scrape the tservers into memory, and serve the metrics from there while the table is
+    // missing or has not been written to for stale_after
+    Fallback bool `yaml:"fallback"`
+    FallbackScrapeInterval time.Duration `yaml:"fallback_scrape_interval"`
+    FallbackRetention time.Duration `yaml:"fallback_retention"`
+    // The read and write ops of the busiest tables are also scraped, which takes memory for
+    // each table kept
+    FallbackMaxTables int `yaml:"fallback_max_tables"`
+    StaleAfter time.Duration `yaml:"stale_after"`
+    // Samples of the metrics table older than the retention are deleted every cleanup_interval,
+    // 0 to keep them
+    Retention time.Duration `yaml:"retention"`
+    CleanupInterval time.Duration `yaml:"cleanup_interval"`
+    // Roll the samples up into averages over 5 minutes and over an hour, kept in the metrics_5m
+    // and metrics_1h tables of downsample_keyspace for their retention. Charts over wide ranges
+    // are drawn from them.
+    Downsample bool `yaml:"downsample"`
+    DownsampleKeyspace string `yaml:"downsample_keyspace"`
+    Downsample5mRetention time.Duration `yaml:"downsample_5m_retention"`
+    Downsample1hRetention time.Duration `yaml:"downsample_1h_retention"`
+}
+
+// Whether API requests need a session or an API token
+func (sessions SessionsConfig) IsRequired() bool {
+    return sessions.Enabled || len(sessions.Users) > 0
+}
+
+// Gets the keyspace qualified name of the metrics table, for use in YCQL queries
+func (metrics MetricsConfig) QualifiedTable() string {
+    return metrics.Keyspace + "." + metrics.Table
+}
+
+type TimeoutsConfig struct {
+    HttpRequest time.Duration `yaml:"http_request"`
+    YcqlRequest time.Duration `yaml:"ycql_request"`
+    YugabytedCommand time.Duration `yaml:"yugabyted_command"`
+    YbAdminCommand time.Duration `yaml:"yb_admin_command"`
+    YbTsCliCommand time.Duration `yaml:"yb_ts_cli_command"`
+    // Runs of yb-master and yb-tserver that print information and exit, such as their flags
+    YbServerCommand time.Duration `yaml:"yb_server_command"`
+    YsqlDumpCommand time.Duration `yaml:"ysql_dump_command"`
+    // How long cloning a database can take, across all the commands it runs
+    DatabaseClone time.Duration `yaml:"database_clone"`
+    // How long each probe of the YSQL and YCQL layers of a node can take, including connecting
+    ApiHealthProbe time.Duration `yaml:"api_health_probe"`
+    // How long loading a sample dataset can take, across all of its files
+    SampleDataLoad time.Duration `yaml:"sample_data_load"`
+    // How long a node that is added can take to register its tserver with the masters
+    NodeRegistration time.Duration `yaml:"node_registration"`
+    // How long the data of a node that is removed can take to move to the other nodes
+    NodeLoadMove time.Duration `yaml:"node_load_move"`
+    // How long a process that is started or restarted can take to be seen as alive by the
+    // masters
+    ProcessAlive time.Duration `yaml:"process_alive"`
+    // Timeouts of the requests to the web endpoints of the nodes, by endpoint path. Endpoints
+    // that are not listed use http_request.
+    Upstream map[string]time.Duration `yaml:"upstream"`
+}
+
+type ToolsConfig struct {
+    YugabytedPath string `yaml:"yugabyted_path"`
+    YbAdminPath string `yaml:"yb_admin_path"`
+    YbTsCliPath string `yaml:"yb_ts_cli_path"`
+    // The binaries of the masters and tservers, of the version the cluster runs
+    YbMasterPath string `yaml:"yb_master_path"`
+    YbTserverPath string `yaml:"yb_tserver_path"`
+    YsqlDumpPath string `yaml:"ysql_dump_path"`
+    YsqlshPath string `yaml:"ysqlsh_path"`
+    YcqlshPath string `yaml:"ycqlsh_path"`
+    // Directory of the sample datasets of the YugabyteDB release, empty for the share directory
+    // next to the directory of ysqlsh
+    SampleDataDirectory string `yaml:"sample_data_directory"`
+}
+
+type ThresholdsConfig struct {
+    // Sequences that have used more than this percentage of their range are flagged
+    SequenceOverflowPercent float64 `yaml:"sequence_overflow_percent"`
+    // Minimum resources needed to run a master and a tserver on a node
+    PreflightMinCpuCores int `yaml:"preflight_min_cpu_cores"`
+    PreflightMinMemoryGb int `yaml:"preflight_min_memory_gb"`
+    // Recommended open files limit for YugabyteDB processes
+    PreflightMinOpenFiles int `yaml:"preflight_min_open_files"`
+    // Clock skew beyond this makes tservers refuse to serve reads, see --max_clock_skew_usec
+    PreflightMaxClockOffset time.Duration `yaml:"preflight_max_clock_offset"`
+    // Tablet replicas a node is recommended to host at most for each of its cores and each GB
+    // of its memory, the lower of which is its limit
+    TabletReplicasPerCore int `yaml:"tablet_replicas_per_core"`
+    TabletReplicasPerGb int `yaml:"tablet_replicas_per_gb"`
+}
+
+// Toggles for the endpoints that change the cluster
+type FeaturesConfig struct {
+    NodeManagement bool `yaml:"node_management"`
+    UserManagement bool `yaml:"user_management"`
+    ExtensionInstall bool `yaml:"extension_install"`
+    CertificateGeneration bool `yaml:"certificate_generation"`
+    // Off by default, as it lets anyone who can reach the UI read every table
+    TableExport bool `yaml:"table_export"`
+    // Off by default, as it lets anyone who can reach the UI write to every table
+    TableImport bool `yaml:"table_import"`
+    // Off by default, as it lets anyone who can reach the UI download every database
+    DatabaseDump bool `yaml:"database_dump"`
+    // Profiling slows the profiled process down while the profile is collected
+    Profiling bool `yaml:"profiling"`
+    // Off by default, as it lets anyone who can reach the UI run any statement, and any
+    // command on the server through the \! meta-command of ysqlsh
+    Shell bool `yaml:"shell"`
+    // Off by default, as the data of the cluster cannot be read without the key once it is
+    // encrypted with it
+    EncryptionAtRest bool `yaml:"encryption_at_rest"`
+    // Streams retain the changes of their database until they are read, which takes up disk
+    CdcStreams bool `yaml:"cdc_streams"`
+    // A clone takes up disk of its own once it or its source database changes
+    DatabaseClone bool `yaml:"database_clone"`
+    // Sample workloads load the cluster while they run, and write to tables of their own
+    Workloads bool `yaml:"workloads"`
+    // Sample datasets are loaded into new tables of the database they are loaded into
+    SampleData bool `yaml:"sample_data"`
+}
+
+type DatabaseDumpConfig struct {
+    // Where dumps are written. Dumps left there by a previous run of the server are deleted,
+    // other files are left alone.
+    Directory string `yaml:"directory"`
+    // Number of dumps kept, the oldest are deleted first
+    MaxDumps int `yaml:"max_dumps"`
+}
+
+// The in-browser shell runs ysqlsh or ycqlsh on the server for each user that opens it
+type ShellConfig struct {
+    // A shell is closed when nothing was typed or printed in it for this long
+    IdleTimeout time.Duration `yaml:"idle_timeout"`
+    // Shells that can be open at once, across all users
+    MaxSessions int `yaml:"max_sessions"`
+}
+
+type ProfilesConfig struct {
+    // Where profiles are written. Profiles left there by a previous run of the server are
+    // deleted, other files are left alone.
+    Directory string `yaml:"directory"`
+    // Number of profiles kept, the oldest are deleted first
+    MaxProfiles int `yaml:"max_profiles"`
+    // Longest CPU profile that can be collected
+    MaxSeconds int `yaml:"max_seconds"`
+}
+
+type TableExportConfig struct {
+    // Largest number of rows a single export can return
+    MaxRows int64 `yaml:"max_rows"`
+    // Number of rows fetched from the database at a time
+    PageSize int `yaml:"page_size"`
+}
+
+type CacheConfig struct {
+    Enabled bool `yaml:"enabled"`
+    MaxEntries int `yaml:"max_entries"`
+    // Larger responses are not cached
+    MaxEntryBytes int `yaml:"max_entry_bytes"`
+    // How long the responses of each GET route are cached, by route path. Routes that are not
+    // listed, or have a TTL of 0, are not cached.
+    Ttls map[string]time.Duration `yaml:"ttls"`
+}
+
+type TableImportConfig struct {
+    // Larger uploads are refused
+    MaxUploadBytes int64 `yaml:"max_upload_bytes"`
+    // Number of rows inserted by each statement
+    BatchSize int `yaml:"batch_size"`
+    // Number of failed rows whose errors are recorded in the task
+    MaxRowErrors int `yaml:"max_row_errors"`
+}
+
+type ReportsConfig struct {
+    // How often each report is produced, 0 to not produce it. Reports cover the periods that
+    // end at multiples of the interval since the Unix epoch.
+    HealthInterval time.Duration `yaml:"health_interval"`
+    TopQueriesInterval time.Duration `yaml:"top_queries_interval"`
+    CapacityTrendInterval time.Duration `yaml:"capacity_trend_interval"`
+    // Number of queries in the top queries report
+    TopQueriesCount int `yaml:"top_queries_count"`
+    // Number of reports kept in the local store, the oldest are dropped first
+    MaxReports int `yaml:"max_reports"`
+    // Whether each report produced is also sent to the Alertmanagers of alerts.alertmanager, as
+    // an alert of the info severity. Reports are posted to the webhooks subscribed to
+    // report_generated either way.
+    Alertmanager bool `yaml:"alertmanager"`
+}
+
+// The Kafka Connect cluster that runs the Debezium connectors reading the CDC streams
+type KafkaConnectConfig struct {
+    // REST URL of Kafka Connect, e.g. http://connect:8083, empty to not integrate with it
+    Url string `yaml:"url"`
+    // Credentials of the REST API, if it requires basic authentication
+    Username string `yaml:"username"`
+    Password string `yaml:"password"`
+}
+
+// The rules that raise alerts on the metrics of the nodes
+type AlertsConfig struct {
+    // Where the rules were kept before the local store, read once to import them into it
+    RulesFile string `yaml:"rules_file"`
+    // How often the rules are checked
+    EvaluationInterval time.Duration `yaml:"evaluation_interval"`
+    // Number of alert history entries kept in the local store, the oldest are dropped first, 0
+    // to not keep any
+    MaxHistoryEntries int `yaml:"max_history_entries"`
+    Alertmanager AlertmanagerConfig `yaml:"alertmanager"`
+}
+
+// The Prometheus Alertmanagers that the alerts are sent to, through their v2 API
+type AlertmanagerConfig struct {
+    // URLs of the Alertmanagers, e.g. http://alertmanager:9093, empty to not send the alerts
+    Urls []string `yaml:"urls"`
+    // Credentials of the Alertmanagers, if they require basic authentication
+    Username string `yaml:"username"`
+    Password string `yaml:"password"`
+    // Timeout of each request to an Alertmanager
+    Timeout time.Duration `yaml:"timeout"`
+    // Labels added to every alert, e.g. to tell the clusters apart in the routes
+    ExternalLabels map[string]string `yaml:"external_labels"`
+}
+
+// Windows during which nodes, zones or the cluster are under maintenance
+type MaintenanceConfig struct {
+    File string `yaml:"file"`
+}
+
+// Full compactions run by the apiserver during off-peak windows
+type CompactionConfig struct {
+    // Where the schedules are kept
+    SchedulesFile string `yaml:"schedules_file"`
+    // Number of runs and skipped windows of each schedule that are kept in memory, the oldest
+    // are dropped first
+    MaxRuns int `yaml:"max_runs"`
+}
+
+// The local store of the API tokens, alert rules, alerts and their history, audit log, tasks,
+// cluster events, webhooks, probe results, health history and DDL activity
+type StoreConfig struct {
+    Backend string `yaml:"backend"`
+    // The file of the bolt backend
+    Path string `yaml:"path"`
+    // Number of audit log entries kept, the oldest are dropped first, 0 to not keep any
+    MaxAuditEntries int `yaml:"max_audit_entries"`
+}
+
+// The URLs the cluster events are posted to
+type WebhooksConfig struct {
+    // Timeout of each request to a webhook
+    Timeout time.Duration `yaml:"timeout"`
+    // Number of times an event is posted to a webhook until it is accepted
+    MaxAttempts int `yaml:"max_attempts"`
+    // How long to wait before posting an event again
+    RetryDelay time.Duration `yaml:"retry_delay"`
+    // Number of events waiting to be posted to each webhook, beyond which new events are
+    // dropped
+    QueueSize int `yaml:"queue_size"`
+}
+
+// The feed of the changes detected in the cluster, e.g. nodes that died or gflags that changed
+type EventsConfig struct {
+    // How often the cluster is checked for changes, 0 to not check it
+    PollInterval time.Duration `yaml:"poll_interval"`
+    // Number of events kept in the local store, the oldest are dropped first
+    MaxEntries int `yaml:"max_entries"`
+}
+
+// The feed of the DDL statements that ran on the nodes and of the bumps of the catalog version
+type DdlActivityConfig struct {
+    // How often the nodes are polled for DDL statements, 0 to not poll them
+    PollInterval time.Duration `yaml:"poll_interval"`
+    // Number of entries kept in the local store, the oldest are dropped first
+    MaxEntries int `yaml:"max_entries"`
+}
+
+// Synthetic probes, which write a row to a table of their own through each node and read it
+// back, to measure the availability of the cluster end to end
+type ProbesConfig struct {
+    // How often the nodes are probed, 0 to not probe them
+    Interval time.Duration `yaml:"interval"`
+    // How long each probe can take, including connecting
+    Timeout time.Duration `yaml:"timeout"`
+    // The YSQL database and table the probes write to. The table is created if it does not
+    // exist.
+    Database string `yaml:"database"`
+    Table string `yaml:"table"`
+    // Number of probe results kept in the local store, the oldest are dropped first
+    MaxResults int `yaml:"max_results"`
+}
+
+// The history of the health of the YSQL and YCQL layers of the nodes, which the uptime is
+// measured from along with the probe results
+type UptimeConfig struct {
+    // How often the health of the nodes is checked, 0 to not check it
+    SampleInterval time.Duration `yaml:"sample_interval"`
+    // Number of health checks kept in the local store, the oldest are dropped first. The
+    // uptime of a window is only measured over the checks that were kept.
+    MaxSamples int `yaml:"max_samples"`
+}
+
+// Sample workloads, which read and write rows of a table of their own to demo and validate the
+// performance of the cluster
+type WorkloadsConfig struct {
+    // Limits on the workloads that can be started
+    MaxThreads int `yaml:"max_threads"`
+    MaxDuration time.Duration `yaml:"max_duration"`
+    // Workloads that can run at once
+    MaxRunning int `yaml:"max_running"`
+    // Number of distinct keys the workloads read and write, which bounds the size of their
+    // tables
+    MaxKeys int64 `yaml:"max_keys"`
+    // The YSQL database and table of the sql workload, and the YCQL keyspace and table of the
+    // key_value workload. They are created if they do not exist.
+    Database string `yaml:"database"`
+    Keyspace string `yaml:"keyspace"`
+    Table string `yaml:"table"`
+}
+
+// Limits on the requests to the API
+type RequestsConfig struct {
+    // Largest JSON body a request can have
+    MaxBodyBytes int64 `yaml:"max_body_bytes"`
+    // Largest body of the uploads, i.e. table imports, whose files are also limited by
+    // table_import.max_upload_bytes, and local store restores
+    MaxUploadBytes int64 `yaml:"max_upload_bytes"`
+    // Whether requests with query parameters that their route does not take are refused
+    StrictQueryParams bool `yaml:"strict_query_params"`
+}
+
+// The release manifest the versions of the nodes are checked against
+type VersionsConfig struct {
+    // URL of a newer manifest than the one bundled with the server, empty to only use the
+    // bundled one
+    ManifestUrl string `yaml:"manifest_url"`
+    // How often the manifest is fetched again
+    ManifestRefreshInterval time.Duration `yaml:"manifest_refresh_interval"`
+}
+
+// Flavors of StatsD that metrics can be exported to. dogstatsd takes the node as a tag, plain
+// statsd in the name of the metrics.
+var STATSD_FLAVORS = []string{"statsd", "dogstatsd"}
+
+// The export of the key metrics of the cluster to a StatsD or DogStatsD agent
+type StatsdConfig struct {
+    // host:port of the agent, which the metrics are sent to over UDP, empty to not export them
+    Address string `yaml:"address"`
+    Flavor string `yaml:"flavor"`
+    // How often the metrics are sent
+    Interval time.Duration `yaml:"interval"`
+    // Prefix of the names of the metrics
+    Prefix string `yaml:"prefix"`
+    // Tags added to every metric, only sent to dogstatsd
+    Tags map[string]string `yaml:"tags"`
+}
+
+// The push of the series scraped by the metrics fallback to a Prometheus remote_write endpoint
+type RemoteWriteConfig struct {
+    // URL of the endpoint, e.g. http://prometheus:9090/api/v1/write, empty to not push
+    Url string `yaml:"url"`
+    // How often the samples scraped since the last push are pushed
+    Interval time.Duration `yaml:"interval"`
+    // Timeout of each request to the endpoint
+    Timeout time.Duration `yaml:"timeout"`
+    // Longest wait before pushing again after the endpoint failed or could not be reached.
+    // The wait starts at the interval and doubles with each failure in a row.
+    MaxBackoff time.Duration `yaml:"max_backoff"`
+    // Credentials of the endpoint, either basic authentication or a bearer token
+    Username string `yaml:"username"`
+    Password string `yaml:"password"`
+    BearerToken string `yaml:"bearer_token"`
+    // Largest number of samples sent in one request, more are split over several requests
+    MaxSamplesPerRequest int `yaml:"max_samples_per_request"`
+    // Labels added to every series, e.g. to tell the clusters apart
+    ExternalLabels map[string]string `yaml:"external_labels"`
+}
+
+type Config struct {
+    Server ServerConfig `yaml:"server"`
+    Log LogConfig `yaml:"log"`
+    Debug DebugConfig `yaml:"debug"`
+    Csrf CsrfConfig `yaml:"csrf"`
+    Sessions SessionsConfig `yaml:"sessions"`
+    ApiTokens ApiTokensConfig `yaml:"api_tokens"`
+    ClusterMetadata ClusterMetadataConfig `yaml:"cluster_metadata"`
+    Database DatabaseConfig `yaml:"database"`
+    Auth AuthConfig `yaml:"auth"`
+    Tls TlsConfig `yaml:"tls"`
+    Upstream UpstreamConfig `yaml:"upstream"`
+    Proxy ProxyConfig `yaml:"proxy"`
+    Ycql YcqlConfig `yaml:"ycql"`
+    Metrics MetricsConfig `yaml:"metrics"`
+    Timeouts TimeoutsConfig `yaml:"timeouts"`
+    Thresholds ThresholdsConfig `yaml:"thresholds"`
+    Tools ToolsConfig `yaml:"tools"`
+    Features FeaturesConfig `yaml:"features"`
+    Cache CacheConfig `yaml:"cache"`
+    Reports ReportsConfig `yaml:"reports"`
+    TableExport TableExportConfig `yaml:"table_export"`
+    TableImport TableImportConfig `yaml:"table_import"`
+    DatabaseDump DatabaseDumpConfig `yaml:"database_dump"`
+    Profiles ProfilesConfig `yaml:"profiles"`
+    Shell ShellConfig `yaml:"shell"`
+    KafkaConnect KafkaConnectConfig `yaml:"kafka_connect"`
+    Alerts AlertsConfig `yaml:"alerts"`
+    Maintenance MaintenanceConfig `yaml:"maintenance"`
+    Compaction CompactionConfig `yaml:"compaction"`
+    Store StoreConfig `yaml:"store"`
+    Events EventsConfig `yaml:"events"`
+    Webhooks WebhooksConfig `yaml:"webhooks"`
+    Versions VersionsConfig `yaml:"versions"`
+    Requests RequestsConfig `yaml:"requests"`
+    Probes ProbesConfig `yaml:"probes"`
+    Uptime UptimeConfig `yaml:"uptime"`
+    Workloads WorkloadsConfig `yaml:"workloads"`
+    DdlActivity DdlActivityConfig `yaml:"ddl_activity"`
+    Statsd StatsdConfig `yaml:"statsd"`
+    RemoteWrite RemoteWriteConfig `yaml:"remote_write"`
+}
+
+var ConfigFile string
+
+// Holds a *Config. A config is never changed once stored, reloading stores a new one.
+var currentConfig atomic.Value
+
+// Serializes reloads, so that a slower one cannot overwrite a newer config
+var configReloadMutex sync.Mutex
+
+// Sections that are only read when the server starts, changing them needs a restart
+var RESTART_CONFIG_SECTIONS = []string{"server", "debug", "csrf", "api_tokens",
+    "cluster_metadata", "database", "auth", "tls", "ycql", "alerts", "maintenance",
+    "compaction", "store"}
+
+func init() {
+    currentConfig.Store(DefaultConfig())
+}
+
+// Command line flags take precedence over the config file and the environment, when set
+var FLAG_CONFIG_OVERRIDES = map[string]func(config *Config){
+    "database_host": func(config *Config) { config.Database.Host = HOST },
+    "database_port": func(config *Config) { config.Database.YsqlPort = PORT },
+    "database_name": func(config *Config) { config.Database.Name = DbName },
+    "ysql_username": func(config *Config) { config.Auth.YsqlUsername = DbYsqlUser },
+    "ycql_username": func(config *Config) { config.Auth.YcqlUsername = DbYcqlUser },
+    "database_password": func(config *Config) { config.Auth.Password = DbPassword },
+    "secure": func(config *Config) { config.Tls.Enabled = Secure },
+    "ssl_mode": func(config *Config) { config.Tls.SslMode = SslMode },
+    "ssl_root certificate": func(config *Config) { config.Tls.RootCert = SslRootCert },
+    "ssl_root_cert": func(config *Config) { config.Tls.RootCert = SslRootCert },
+    "ssl_root_key": func(config *Config) { config.Tls.RootKey = SslRootKey },
+    "yugabyted_path": func(config *Config) { config.Tools.YugabytedPath = YugabytedPath },
+    "yb_admin_path": func(config *Config) { config.Tools.YbAdminPath = YbAdminPath },
+    "bind_address": func(config *Config) { config.Server.ListenAddress = BindAddress },
+    "port": func(config *Config) { config.Server.Port = ServerPort },
+    "base_path": func(config *Config) { config.Server.BasePath = BasePath },
+    "debug": func(config *Config) { config.Debug.Enabled = Debug },
+}
+
+// Tokens and cluster metadata outlive the server, so they are kept in the config directory of
+// the user rather than in the temporary directory
+func getDefaultUserConfigFile(name string) string {
+    directory, err := os.UserConfigDir()
+    if err != nil {
+        directory = os.TempDir()
+    }
+    return filepath.Join(directory, "yugabyted-ui", name)
+}
+
+func DefaultConfig() *Config {
+    return &Config{
+        Server: ServerConfig{
+            // Only local clients can reach the server unless configured otherwise
+            ListenAddress: "127.0.0.1",
+            Port: 15433,
+            ConfigWatchInterval: 10 * time.Second,
+            Http2: true,
+            Http2MaxConcurrentStreams: 250,
+        },
+        Log: LogConfig{
+            Level: "info",
+        },
+        Debug: DebugConfig{
+            Enabled: false,
+            ListenAddress: "127.0.0.1",
+            Port: 15434,
+        },
+        Csrf: CsrfConfig{
+            Enabled: true,
+            CookieMaxAge: 24 * time.Hour,
+        },
+        Sessions: SessionsConfig{
+            Enabled: false,
+            Users: map[string]string{},
+            IdleTimeout: 30 * time.Minute,
+            AbsoluteTimeout: 12 * time.Hour,
+            MaxSessionsPerUser: 5,
+            MaxFailedLogins: 5,
+            FailedLoginWindow: 15 * time.Minute,
+        },
+        ApiTokens: ApiTokensConfig{
+            File: getDefaultUserConfigFile("api_tokens.json"),
+        },
+        ClusterMetadata: ClusterMetadataConfig{
+            File: getDefaultUserConfigFile("cluster_metadata.json"),
+        },
+        Database: DatabaseConfig{
+            Host: "127.0.0.1",
+            YsqlPort: 5433,
+            Name: "yugabyte",
+            MaxConns: 4,
+        },
+        Auth: AuthConfig{
+            YsqlUsername: "yugabyte",
+            YcqlUsername: "cassandra",
+            Password: "yugabyte",
+        },
+        Tls: TlsConfig{
+            Enabled: false,
+            SslMode: "require",
+        },
+        Upstream: UpstreamConfig{
+            MasterHttpPort: 7000,
+            TserverHttpPort: 9000,
+            YsqlHttpPort: 13000,
+            YcqlHttpPort: 12000,
+            MasterRpcPort: 7100,
+            TserverRpcPort: 9100,
+            YcqlPort: 9042,
+            NodeExporterPort: 9300,
+            NodeExporterTimeout: 2 * time.Second,
+            DnsCacheTtl: 30 * time.Second,
+            HostToUuidTtl: 5 * time.Minute,
+            NodePollInterval: 30 * time.Second,
+            MaxConcurrentCalls: 8,
+        },
+        // The defaults of gocql
+        Ycql: YcqlConfig{
+            NumConns: 2,
+            Consistency: "quorum",
+            ConnectTimeout: 600 * time.Millisecond,
+            HostSelectionPolicy: YCQL_POLICY_ROUND_ROBIN,
+        },
+        Metrics: MetricsConfig{
+            Backend: "ycql",
+            Keyspace: "system",
+            Table: "metrics",
+            CpuUsageUserMetric: "cpu_usage_user",
+            CpuUsageSystemMetric: "cpu_usage_system",
+            TotalDiskMetric: "total_disk",
+            FreeDiskMetric: "free_disk",
+            NodeUpMetric: "node_up",
+            ReadCountMetric: "handler_latency_yb_tserver_TabletServerService_Read_count",
+            WriteCountMetric: "handler_latency_yb_tserver_TabletServerService_Write_count",
+            ReadSumMetric: "handler_latency_yb_tserver_TabletServerService_Read_sum",
+            WriteSumMetric: "handler_latency_yb_tserver_TabletServerService_Write_sum",
+            Fallback: true,
+            FallbackScrapeInterval: 30 * time.Second,
+            FallbackRetention: 6 * time.Hour,
+            FallbackMaxTables: 500,
+            StaleAfter: 5 * time.Minute,
+            CleanupInterval: time.Hour,
+            Downsample: true,
+            DownsampleKeyspace: "yugabyted_ui",
+            Downsample5mRetention: 30 * 24 * time.Hour,
+            Downsample1hRetention: 365 * 24 * time.Hour,
+        },
+        Timeouts: TimeoutsConfig{
+            HttpRequest: 10 * time.Second,
+            // The same timeout as the Java driver
+            YcqlRequest: 12 * time.Second,
+            // Starting a node waits for its processes to come up, which can take a while
+            YugabytedCommand: 5 * time.Minute,
+            YbAdminCommand: 1 * time.Minute,
+            YbTsCliCommand: 30 * time.Second,
+            YbServerCommand: 30 * time.Second,
+            YsqlDumpCommand: 1 * time.Hour,
+            DatabaseClone: 1 * time.Hour,
+            ApiHealthProbe: 5 * time.Second,
+            SampleDataLoad: 30 * time.Minute,
+            NodeRegistration: 2 * time.Minute,
+            NodeLoadMove: time.Hour,
+            ProcessAlive: 2 * time.Minute,
+            // Listing tables and tablets renders a page per call that grows with the cluster,
+            // while flags and versions are answered right away
+            Upstream: map[string]time.Duration{
+                "/api/v1/varz": 5 * time.Second,
+                "/api/v1/version": 5 * time.Second,
+                "/varz": 5 * time.Second,
+                "/tables": 30 * time.Second,
+                "/tablets": 30 * time.Second,
+                "/tablet-servers": 30 * time.Second,
+                "/api/v1/tablet-replication": 30 * time.Second,
+                "/dump-entities": 30 * time.Second,
+                "/prometheus-metrics": 20 * time.Second,
+                "/metrics": 20 * time.Second,
+            },
+        },
+        Thresholds: ThresholdsConfig{
+            SequenceOverflowPercent: 90,
+            PreflightMinCpuCores: 2,
+            PreflightMinMemoryGb: 2,
+            PreflightMinOpenFiles: 1048576,
+            PreflightMaxClockOffset: 500 * time.Millisecond,
+            TabletReplicasPerCore: 250,
+            TabletReplicasPerGb: 75,
+        },
+        Tools: ToolsConfig{
+            YugabytedPath: "yugabyted",
+            YbAdminPath: "yb-admin",
+            YbTsCliPath: "yb-ts-cli",
+            YbMasterPath: "yb-master",
+            YbTserverPath: "yb-tserver",
+            YsqlDumpPath: "ysql_dump",
+            YsqlshPath: "ysqlsh",
+            YcqlshPath: "ycqlsh",
+        },
+        Features: FeaturesConfig{
+            NodeManagement: true,
+            UserManagement: true,
+            ExtensionInstall: true,
+            CertificateGeneration: true,
+            TableExport: false,
+            TableImport: false,
+            DatabaseDump: false,
+            Profiling: true,
+            Shell: false,
+            EncryptionAtRest: false,
+            CdcStreams: true,
+            DatabaseClone: true,
+            Workloads: true,
+            SampleData: true,
+        },
+        Cache: CacheConfig{
+            Enabled: true,
+            MaxEntries: 1000,
+            MaxEntryBytes: 4 * 1024 * 1024,
+            Ttls: map[string]time.Duration{
+                "/api/cluster": 10 * time.Second,
+                "/api/metrics": 10 * time.Second,
+                "/api/nodes": 10 * time.Second,
+                "/api/health-check": 10 * time.Second,
+                "/api/topology/servers": 10 * time.Second,
+                "/api/slow_queries": 10 * time.Second,
+                "/api/tables": 30 * time.Second,
+                "/api/tablets": 30 * time.Second,
+                "/api/namespaces": 30 * time.Second,
+                "/api/users": 30 * time.Second,
+                "/api/grants": 30 * time.Second,
+                "/api/sequences": 30 * time.Second,
+                "/api/extensions": 1 * time.Minute,
+                "/api/compatibility": 1 * time.Minute,
+                "/api/features": 1 * time.Minute,
+                "/api/version": 5 * time.Minute,
+            },
+        },
+        Reports: ReportsConfig{
+            HealthInterval: 7 * 24 * time.Hour,
+            TopQueriesInterval: 7 * 24 * time.Hour,
+            CapacityTrendInterval: 7 * 24 * time.Hour,
+            TopQueriesCount: 10,
+            MaxReports: 50,
+            Alertmanager: true,
+        },
+        TableExport: TableExportConfig{
+            MaxRows: 1000000,
+            PageSize: 1000,
+        },
+        TableImport: TableImportConfig{
+            MaxUploadBytes: 16 * 1024 * 1024,
+            BatchSize: 500,
+            MaxRowErrors: 100,
+        },
+        DatabaseDump: DatabaseDumpConfig{
+            Directory: filepath.Join(os.TempDir(), "yugabyted-ui-dumps"),
+            MaxDumps: 5,
+        },
+        Profiles: ProfilesConfig{
+            Directory: filepath.Join(os.TempDir(), "yugabyted-ui-profiles"),
+            MaxProfiles: 10,
+            MaxSeconds: 300,
+        },
+        Shell: ShellConfig{
+            IdleTimeout: 15 * time.Minute,
+            MaxSessions: 5,
+        },
+        KafkaConnect: KafkaConnectConfig{
+            Url: "",
+        },
+        Alerts: AlertsConfig{
+            RulesFile: getDefaultUserConfigFile("alert_rules.json"),
+            EvaluationInterval: time.Minute,
+            MaxHistoryEntries: 10000,
+            Alertmanager: AlertmanagerConfig{
+                Urls: []string{},
+                Timeout: 10 * time.Second,
+                ExternalLabels: map[string]string{},
+            },
+        },
+        Maintenance: MaintenanceConfig{
+            File: getDefaultUserConfigFile("maintenance_windows.json"),
+        },
+        Compaction: CompactionConfig{
+            SchedulesFile: getDefaultUserConfigFile("compaction_schedules.json"),
+            MaxRuns: 50,
+        },
+        Store: StoreConfig{
+            Backend: "bolt",
+            Path: getDefaultUserConfigFile("store.db"),
+            MaxAuditEntries: 10000,
+        },
+        Events: EventsConfig{
+            PollInterval: time.Minute,
+            MaxEntries: 10000,
+        },
+        Webhooks: WebhooksConfig{
+            Timeout: 10 * time.Second,
+            MaxAttempts: 3,
+            RetryDelay: 30 * time.Second,
+            QueueSize: 100,
+        },
+        Versions: VersionsConfig{
+            ManifestRefreshInterval: 24 * time.Hour,
+        },
+        Requests: RequestsConfig{
+            MaxBodyBytes: 1 << 20,
+            MaxUploadBytes: 256 << 20,
+        },
+        Probes: ProbesConfig{
+            Timeout: 5 * time.Second,
+            Database: "yugabyte",
+            Table: "yugabyted_ui_probes",
+            MaxResults: 100000,
+        },
+        Uptime: UptimeConfig{
+            SampleInterval: time.Minute,
+            MaxSamples: 50000,
+        },
+        Workloads: WorkloadsConfig{
+            MaxThreads: 16,
+            MaxDuration: time.Hour,
+            MaxRunning: 1,
+            MaxKeys: 100000,
+            Database: "yugabyte",
+            Keyspace: "yugabyted_ui",
+            Table: "yugabyted_ui_workload",
+        },
+        DdlActivity: DdlActivityConfig{
+            PollInterval: time.Minute,
+            MaxEntries: 10000,
+        },
+        Statsd: StatsdConfig{
+            Address: "",
+            Flavor: "statsd",
+            Interval: 10 * time.Second,
+            Prefix: "yugabyted",
+            Tags: map[string]string{},
+        },
+        RemoteWrite: RemoteWriteConfig{
+            Url: "",
+            Interval: 30 * time.Second,
+            Timeout: 10 * time.Second,
+            MaxBackoff: 5 * time.Minute,
+            MaxSamplesPerRequest: 2000,
+            ExternalLabels: map[string]string{},
+        },
+    }
+}
+
+// Gets the configuration the server runs with
+func GetConfig() *Config {
+    return currentConfig.Load().(*Config)
+}
+
+// Gets the path of the config file, empty if there is none
+func GetConfigFile() string {
+    if ConfigFile != "" {
+        return ConfigFile
+    }
+    return os.Getenv(CONFIG_FILE_ENV)
+}
+
+// Applies YUGABYTED_UI_<SECTION>_<KEY> environment variables to the config
+func applyConfigEnv(config *Config) error {
+    if value, ok := os.LookupEnv(LEGACY_SERVER_PORT_ENV); ok {
+        port, err := strconv.Atoi(value)
+        if err != nil {
+            return fmt.Errorf("%s: %s is not a port", LEGACY_SERVER_PORT_ENV, value)
+        }
+        config.Server.Port = port
+    }
+    sections := reflect.ValueOf(config).Elem()
+    for i := 0; i < sections.NumField(); i++ {
+        section := sections.Field(i)
+        sectionName := sections.Type().Field(i).Tag.Get("yaml")
+        for j := 0; j < section.NumField(); j++ {
+            field := section.Field(j)
+            envName := strings.ToUpper(CONFIG_ENV_PREFIX + sectionName + "_" +
+                section.Type().Field(j).Tag.Get("yaml"))
+            value, ok := os.LookupEnv(envName)
+            if !ok {
+                continue
+            }
+            var err error
+            switch field.Interface().(type) {
+            case string:
+                field.SetString(value)
+            case int:
+                var intValue int
+                intValue, err = strconv.Atoi(value)
+                field.SetInt(int64(intValue))
+            case float64:
+                var floatValue float64
+                floatValue, err = strconv.ParseFloat(value, 64)
+                field.SetFloat(floatValue)
+            case bool:
+                var boolValue bool
+                boolValue, err = strconv.ParseBool(value)
+                field.SetBool(boolValue)
+            case time.Duration:
+                var duration time.Duration
+                duration, err = time.ParseDuration(value)
+                field.SetInt(int64(duration))
+            }
+            if err != nil {
+                return fmt.Errorf("%s: invalid value %q", envName, value)
+            }
+        }
+    }
+    return nil
+}
+
+func containsString(values []string, value string) bool {
+    for _, v := range values {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
+
+// Checks the config, returning every problem found
+func (config *Config) Validate() error {
+    problems := []string{}
+    ports := map[string]int{
+        "server.port": config.Server.Port,
+        "debug.port": config.Debug.Port,
+        "database.ysql_port": config.Database.YsqlPort,
+        "upstream.master_http_port": config.Upstream.MasterHttpPort,
+        "upstream.tserver_http_port": config.Upstream.TserverHttpPort,
+        "upstream.ysql_http_port": config.Upstream.YsqlHttpPort,
+        "upstream.ycql_http_port": config.Upstream.YcqlHttpPort,
+        "upstream.master_rpc_port": config.Upstream.MasterRpcPort,
+        "upstream.tserver_rpc_port": config.Upstream.TserverRpcPort,
+        "upstream.ycql_port": config.Upstream.YcqlPort,
+        "upstream.node_exporter_port": config.Upstream.NodeExporterPort,
+    }
+    for name, port := range ports {
+        if port < 1 || port > 65535 {
+            problems = append(problems, fmt.Sprintf("%s must be between 1 and 65535, got %d",
+                name, port))
+        }
+    }
+    if (config.Server.TlsCertFile == "") != (config.Server.TlsKeyFile == "") {
+        problems = append(problems,
+            "server.tls_cert_file and server.tls_key_file must be set together")
+    }
+    if config.Server.H2c && config.Server.TlsCertFile != "" {
+        problems = append(problems, "server.h2c only applies without server.tls_cert_file")
+    }
+    if config.Server.Http2MaxConcurrentStreams == 0 {
+        problems = append(problems, "server.http2_max_concurrent_streams must be positive")
+    }
+    if config.Debug.Enabled && config.Debug.Port == config.Server.Port {
+        problems = append(problems, "debug.port must differ from server.port")
+    }
+    if config.Csrf.Enabled && config.Csrf.CookieMaxAge < time.Second {
+        problems = append(problems, "csrf.cookie_max_age must be at least 1s")
+    }
+    if config.Sessions.Enabled && len(config.Sessions.Users) == 0 {
+        problems = append(problems, "sessions.users must not be empty when sessions are enabled")
+    }
+    for username, passwordHash := range config.Sessions.Users {
+        if _, err := bcrypt.Cost([]byte(passwordHash)); err != nil {
+            problems = append(problems, fmt.Sprintf(
+                "sessions.users: the password hash of %s is not a bcrypt hash", username))
+        }
+    }
+    if config.Sessions.IdleTimeout <= 0 {
+        problems = append(problems, "sessions.idle_timeout must be positive")
+    }
+    if config.Sessions.AbsoluteTimeout < config.Sessions.IdleTimeout {
+        problems = append(problems,
+            "sessions.absolute_timeout must be at least sessions.idle_timeout")
+    }
+    if config.Sessions.MaxSessionsPerUser < 0 {
+        problems = append(problems, "sessions.max_sessions_per_user must not be negative")
+    }
+    if config.Sessions.MaxFailedLogins < 0 {
+        problems = append(problems, "sessions.max_failed_logins must not be negative")
+    }
+    if config.Sessions.MaxFailedLogins > 0 && config.Sessions.FailedLoginWindow <= 0 {
+        problems = append(problems, "sessions.failed_login_window must be positive")
+    }
+    if config.ApiTokens.File == "" {
+        problems = append(problems, "api_tokens.file must be set")
+    }
+    if config.ClusterMetadata.File == "" {
+        problems = append(problems, "cluster_metadata.file must be set")
+    }
+    if config.Database.Host == "" {
+        problems = append(problems, "database.host must be set")
+    }
+    if config.Database.Name == "" {
+        problems = append(problems, "database.name must be set")
+    }
+    if config.Database.MaxConns < 1 {
+        problems = append(problems, "database.max_conns must be at least 1")
+    }
+    if !containsString(SSL_MODES, config.Tls.SslMode) {
+        problems = append(problems, fmt.Sprintf("tls.ssl_mode must be one of %s, got %q",
+            strings.Join(SSL_MODES, ", "), config.Tls.SslMode))
+    }
+    for name, path := range map[string]string{
+        "tls.root_cert": config.Tls.RootCert,
+        "tls.root_key": config.Tls.RootKey,
+    } {
+        if path == "" {
+            continue
+        }
+        if _, err := os.Stat(path); err != nil {
+            problems = append(problems, fmt.Sprintf("%s: %s", name, err.Error()))
+        }
+    }
+    if config.Server.BasePath != "" && (!strings.HasPrefix(config.Server.BasePath, "/") ||
+        strings.HasSuffix(config.Server.BasePath, "/")) {
+        problems = append(problems, fmt.Sprintf("server.base_path must start with / and not end "+
+            "with /, got %q", config.Server.BasePath))
+    }
+    for name, proxy := range map[string]string{
+        "proxy.http_proxy": config.Proxy.HttpProxy,
+        "proxy.https_proxy": config.Proxy.HttpsProxy,
+    } {
+        if proxy == "" {
+            continue
+        }
+        if proxyUrl, err := url.Parse(proxy); err != nil || proxyUrl.Host == "" {
+            problems = append(problems, fmt.Sprintf("%s must be a url like "+
+                "http://proxy:3128, got %q", name, proxy))
+        }
+    }
+    if config.KafkaConnect.Url != "" {
+        if connectUrl, err := url.Parse(config.KafkaConnect.Url); err != nil ||
+            (connectUrl.Scheme != "http" && connectUrl.Scheme != "https") ||
+            connectUrl.Host == "" {
+            problems = append(problems, fmt.Sprintf("kafka_connect.url must be a url like "+
+                "http://connect:8083, got %q", config.KafkaConnect.Url))
+        }
+    }
+    if config.Alerts.RulesFile == "" {
+        problems = append(problems, "alerts.rules_file must be set")
+    }
+    if config.Alerts.EvaluationInterval <= 0 {
+        problems = append(problems, "alerts.evaluation_interval must be positive")
+    }
+    if config.Alerts.MaxHistoryEntries < 0 {
+        problems = append(problems, "alerts.max_history_entries must not be negative")
+    }
+    for _, alertmanager := range config.Alerts.Alertmanager.Urls {
+        if alertmanagerUrl, err := url.Parse(alertmanager); err != nil ||
+            (alertmanagerUrl.Scheme != "http" && alertmanagerUrl.Scheme != "https") ||
+            alertmanagerUrl.Host == "" {
+            problems = append(problems, fmt.Sprintf("alerts.alertmanager.urls must be urls "+
+                "like http://alertmanager:9093, got %q", alertmanager))
+        }
+    }
+    if config.Alerts.Alertmanager.Timeout <= 0 {
+        problems = append(problems, "alerts.alertmanager.timeout must be positive")
+    }
+    if config.Maintenance.File == "" {
+        problems = append(problems, "maintenance.file must be set")
+    }
+    if config.Compaction.SchedulesFile == "" {
+        problems = append(problems, "compaction.schedules_file must be set")
+    }
+    if config.Compaction.MaxRuns < 1 {
+        problems = append(problems, "compaction.max_runs must be positive")
+    }
+    if !containsString(STORE_BACKENDS, config.Store.Backend) {
+        problems = append(problems, fmt.Sprintf("store.backend must be one of %s, got %q",
+            strings.Join(STORE_BACKENDS, ", "), config.Store.Backend))
+    }
+    if config.Store.Backend == "bolt" && config.Store.Path == "" {
+        problems = append(problems, "store.path must be set for the bolt backend")
+    }
+    if config.Store.MaxAuditEntries < 0 {
+        problems = append(problems, "store.max_audit_entries must not be negative")
+    }
+    if config.Events.PollInterval < 0 {
+        problems = append(problems, "events.poll_interval must not be negative")
+    }
+    if config.Events.MaxEntries <= 0 {
+        problems = append(problems, "events.max_entries must be positive")
+    }
+    if config.Webhooks.Timeout <= 0 {
+        problems = append(problems, "webhooks.timeout must be positive")
+    }
+    if config.Webhooks.MaxAttempts < 1 {
+        problems = append(problems, "webhooks.max_attempts must be at least 1")
+    }
+    if config.Webhooks.RetryDelay < 0 {
+        problems = append(problems, "webhooks.retry_delay must not be negative")
+    }
+    if config.Webhooks.QueueSize < 1 {
+        problems = append(problems, "webhooks.queue_size must be at least 1")
+    }
+    if config.Versions.ManifestUrl != "" {
+        manifestUrl, err := url.Parse(config.Versions.ManifestUrl)
+        if err != nil || (manifestUrl.Scheme != "http" && manifestUrl.Scheme != "https") {
+            problems = append(problems, fmt.Sprintf(
+                "versions.manifest_url must be an http or https URL, got %q",
+                config.Versions.ManifestUrl))
+        }
+    }
+    if config.Versions.ManifestRefreshInterval <= 0 {
+        problems = append(problems, "versions.manifest_refresh_interval must be positive")
+    }
+    if config.Requests.MaxBodyBytes <= 0 {
+        problems = append(problems, "requests.max_body_bytes must be positive")
+    }
+    if config.Requests.MaxUploadBytes < config.Requests.MaxBodyBytes {
+        problems = append(problems,
+            "requests.max_upload_bytes must be at least requests.max_body_bytes")
+    }
+    if config.Probes.Interval < 0 {
+        problems = append(problems, "probes.interval must not be negative")
+    }
+    if config.Probes.Timeout <= 0 {
+        problems = append(problems, "probes.timeout must be positive")
+    }
+    if config.Probes.Database == "" || config.Probes.Table == "" {
+        problems = append(problems, "probes.database and probes.table must be set")
+    }
+    if config.Probes.MaxResults <= 0 {
+        problems = append(problems, "probes.max_results must be positive")
+    }
+    if config.Uptime.SampleInterval < 0 {
+        problems = append(problems, "uptime.sample_interval must not be negative")
+    }
+    if config.Uptime.MaxSamples <= 0 {
+        problems = append(problems, "uptime.max_samples must be positive")
+    }
+    if config.DdlActivity.PollInterval < 0 {
+        problems = append(problems, "ddl_activity.poll_interval must not be negative")
+    }
+    if config.DdlActivity.MaxEntries <= 0 {
+        problems = append(problems, "ddl_activity.max_entries must be positive")
+    }
+    if config.Statsd.Address != "" {
+        if _, _, err := net.SplitHostPort(config.Statsd.Address); err != nil {
+            problems = append(problems, fmt.Sprintf(
+                "statsd.address must be host:port, got %q", config.Statsd.Address))
+        }
+    }
+    if !containsString(STATSD_FLAVORS, config.Statsd.Flavor) {
+        problems = append(problems, fmt.Sprintf("statsd.flavor must be one of %s, got %q",
+            strings.Join(STATSD_FLAVORS, ", "), config.Statsd.Flavor))
+    }
+    if config.Statsd.Interval <= 0 {
+        problems = append(problems, "statsd.interval must be positive")
+    }
+    if config.RemoteWrite.Url != "" {
+        if writeUrl, err := url.Parse(config.RemoteWrite.Url); err != nil ||
+            (writeUrl.Scheme != "http" && writeUrl.Scheme != "https") || writeUrl.Host == "" {
+            problems = append(problems, fmt.Sprintf("remote_write.url must be a url like "+
+                "http://prometheus:9090/api/v1/write, got %q", config.RemoteWrite.Url))
+        }
+    }
+    if config.RemoteWrite.Username != "" && config.RemoteWrite.BearerToken != "" {
+        problems = append(problems,
+            "remote_write.username and remote_write.bearer_token cannot both be set")
+    }
+    if config.RemoteWrite.Interval <= 0 {
+        problems = append(problems, "remote_write.interval must be positive")
+    }
+    if config.RemoteWrite.Timeout <= 0 {
+        problems = append(problems, "remote_write.timeout must be positive")
+    }
+    if config.RemoteWrite.MaxBackoff < config.RemoteWrite.Interval {
+        problems = append(problems, "remote_write.max_backoff must be at least the interval")
+    }
+    if config.RemoteWrite.MaxSamplesPerRequest <= 0 {
+        problems = append(problems, "remote_write.max_samples_per_request must be positive")
+    }
+    if config.Workloads.MaxThreads < 1 {
+        problems = append(problems, "workloads.max_threads must be at least 1")
+    }
+    if config.Workloads.MaxDuration <= 0 {
+        problems = append(problems, "workloads.max_duration must be positive")
+    }
+    if config.Workloads.MaxRunning < 1 {
+        problems = append(problems, "workloads.max_running must be at least 1")
+    }
+    if config.Workloads.MaxKeys <= 0 {
+        problems = append(problems, "workloads.max_keys must be positive")
+    }
+    if config.Workloads.Database == "" || config.Workloads.Keyspace == "" ||
+        config.Workloads.Table == "" {
+        problems = append(problems,
+            "workloads.database, workloads.keyspace and workloads.table must be set")
+    }
+    if config.Upstream.DnsCacheTtl < 0 {
+        problems = append(problems, "upstream.dns_cache_ttl must not be negative")
+    }
+    if config.Upstream.HostToUuidTtl < 0 {
+        problems = append(problems, "upstream.host_to_uuid_ttl must not be negative")
+    }
+    if config.Upstream.NodePollInterval <= 0 {
+        problems = append(problems, "upstream.node_poll_interval must be positive")
+    }
+    if config.Upstream.NodeExporterTimeout <= 0 {
+        problems = append(problems, "upstream.node_exporter_timeout must be positive")
+    }
+    if config.Upstream.MaxConcurrentCalls < 0 {
+        problems = append(problems, "upstream.max_concurrent_calls must not be negative")
+    }
+    if config.Server.ConfigWatchInterval < 0 {
+        problems = append(problems, "server.config_watch_interval must not be negative")
+    }
+    if !containsString(LOG_LEVELS, config.Log.Level) {
+        problems = append(problems, fmt.Sprintf("log.level must be one of %s, got %q",
+            strings.Join(LOG_LEVELS, ", "), config.Log.Level))
+    }
+    if config.Ycql.NumConns < 1 {
+        problems = append(problems, fmt.Sprintf("ycql.num_conns must be at least 1, got %d",
+            config.Ycql.NumConns))
+    }
+    if !containsString(YCQL_CONSISTENCY_LEVELS, strings.ToLower(config.Ycql.Consistency)) {
+        problems = append(problems, fmt.Sprintf("ycql.consistency must be one of %s, got %q",
+            strings.Join(YCQL_CONSISTENCY_LEVELS, ", "), config.Ycql.Consistency))
+    }
+    if config.Ycql.ConnectTimeout <= 0 {
+        problems = append(problems, "ycql.connect_timeout must be positive")
+    }
+    if !containsString(YCQL_HOST_SELECTION_POLICIES, config.Ycql.HostSelectionPolicy) {
+        problems = append(problems, fmt.Sprintf("ycql.host_selection_policy must be one of %s, "+
+            "got %q", strings.Join(YCQL_HOST_SELECTION_POLICIES, ", "),
+            config.Ycql.HostSelectionPolicy))
+    }
+    if config.Ycql.HostSelectionPolicy == YCQL_POLICY_DC_AWARE && config.Ycql.LocalDc == "" {
+        problems = append(problems, "ycql.local_dc must be set for the dc_aware policy")
+    }
+    if !containsString(METRICS_BACKENDS, config.Metrics.Backend) {
+        problems = append(problems, fmt.Sprintf("metrics.backend must be one of %s, got %q",
+            strings.Join(METRICS_BACKENDS, ", "), config.Metrics.Backend))
+    }
+    // These are interpolated into queries, so only plain identifiers and names are allowed
+    identifiers := map[string]string{
+        "metrics.keyspace": config.Metrics.Keyspace,
+        "metrics.table": config.Metrics.Table,
+        "metrics.downsample_keyspace": config.Metrics.DownsampleKeyspace,
+    }
+    for name, identifier := range identifiers {
+        if !CQL_IDENTIFIER_REGEX.MatchString(identifier) {
+            problems = append(problems, fmt.Sprintf("%s must be a plain CQL identifier, got %q",
+                name, identifier))
+        }
+    }
+    metricNames := map[string]string{
+        "metrics.cpu_usage_user_metric": config.Metrics.CpuUsageUserMetric,
+        "metrics.cpu_usage_system_metric": config.Metrics.CpuUsageSystemMetric,
+        "metrics.total_disk_metric": config.Metrics.TotalDiskMetric,
+        "metrics.free_disk_metric": config.Metrics.FreeDiskMetric,
+        "metrics.node_up_metric": config.Metrics.NodeUpMetric,
+        "metrics.read_count_metric": config.Metrics.ReadCountMetric,
+        "metrics.write_count_metric": config.Metrics.WriteCountMetric,
+        "metrics.read_sum_metric": config.Metrics.ReadSumMetric,
+        "metrics.write_sum_metric": config.Metrics.WriteSumMetric,
+    }
+    for name, metricName := range metricNames {
+        if !METRIC_NAME_REGEX.MatchString(metricName) {
+            problems = append(problems, fmt.Sprintf("%s must only contain letters, digits, "+
+                "'_', '.', ':' and '-', got %q", name, metricName))
+        }
+    }
+    if config.Metrics.FallbackScrapeInterval <= 0 {
+        problems = append(problems, "metrics.fallback_scrape_interval must be positive")
+    }
+    if config.Metrics.FallbackRetention < config.Metrics.FallbackScrapeInterval {
+        problems = append(problems, "metrics.fallback_retention must be at least "+
+            "metrics.fallback_scrape_interval")
+    }
+    if config.Metrics.FallbackMaxTables < 0 {
+        problems = append(problems, "metrics.fallback_max_tables must not be negative")
+    }
+    if config.Metrics.StaleAfter <= 0 {
+        problems = append(problems, "metrics.stale_after must be positive")
+    }
+    if config.Metrics.Retention < 0 {
+        problems = append(problems, "metrics.retention must not be negative")
+    }
+    if config.Metrics.CleanupInterval <= 0 {
+        problems = append(problems, "metrics.cleanup_interval must be positive")
+    }
+    if config.Metrics.Downsample5mRetention < 5*time.Minute {
+        problems = append(problems, "metrics.downsample_5m_retention must be at least 5m")
+    }
+    if config.Metrics.Downsample1hRetention < time.Hour {
+        problems = append(problems, "metrics.downsample_1h_retention must be at least 1h")
+    }
+    timeouts := map[string]time.Duration{
+        "timeouts.http_request": config.Timeouts.HttpRequest,
+        "timeouts.ycql_request": config.Timeouts.YcqlRequest,
+        "timeouts.yugabyted_command": config.Timeouts.YugabytedCommand,
+        "timeouts.yb_admin_command": config.Timeouts.YbAdminCommand,
+        "timeouts.yb_ts_cli_command": config.Timeouts.YbTsCliCommand,
+        "timeouts.yb_server_command": config.Timeouts.YbServerCommand,
+        "timeouts.ysql_dump_command": config.Timeouts.YsqlDumpCommand,
+        "timeouts.database_clone": config.Timeouts.DatabaseClone,
+        "timeouts.api_health_probe": config.Timeouts.ApiHealthProbe,
+        "timeouts.sample_data_load": config.Timeouts.SampleDataLoad,
+        "timeouts.node_registration": config.Timeouts.NodeRegistration,
+        "timeouts.node_load_move": config.Timeouts.NodeLoadMove,
+        "timeouts.process_alive": config.Timeouts.ProcessAlive,
+    }
+    for name, timeout := range timeouts {
+        if timeout <= 0 {
+            problems = append(problems, fmt.Sprintf("%s must be positive, got %s",
+                name, timeout))
+        }
+    }
+    if config.Thresholds.SequenceOverflowPercent <= 0 ||
+        config.Thresholds.SequenceOverflowPercent > 100 {
+        problems = append(problems, fmt.Sprintf("thresholds.sequence_overflow_percent must be "+
+            "between 0 and 100, got %g", config.Thresholds.SequenceOverflowPercent))
+    }
+    minimums := map[string]int{
+        "thresholds.preflight_min_cpu_cores": config.Thresholds.PreflightMinCpuCores,
+        "thresholds.preflight_min_memory_gb": config.Thresholds.PreflightMinMemoryGb,
+        "thresholds.preflight_min_open_files": config.Thresholds.PreflightMinOpenFiles,
+    }
+    for name, minimum := range minimums {
+        if minimum < 0 {
+            problems = append(problems, fmt.Sprintf("%s must not be negative, got %d",
+                name, minimum))
+        }
+    }
+    tabletLimits := map[string]int{
+        "thresholds.tablet_replicas_per_core": config.Thresholds.TabletReplicasPerCore,
+        "thresholds.tablet_replicas_per_gb": config.Thresholds.TabletReplicasPerGb,
+    }
+    for name, limit := range tabletLimits {
+        if limit <= 0 {
+            problems = append(problems, fmt.Sprintf("%s must be positive, got %d", name, limit))
+        }
+    }
+    if config.Thresholds.PreflightMaxClockOffset <= 0 {
+        problems = append(problems, "thresholds.preflight_max_clock_offset must be positive")
+    }
+    if config.Cache.MaxEntries < 1 {
+        problems = append(problems, fmt.Sprintf("cache.max_entries must be at least 1, got %d",
+            config.Cache.MaxEntries))
+    }
+    if config.Cache.MaxEntryBytes < 1 {
+        problems = append(problems, fmt.Sprintf("cache.max_entry_bytes must be at least 1, "+
+            "got %d", config.Cache.MaxEntryBytes))
+    }
+    reportIntervals := map[string]time.Duration{
+        "reports.health_interval": config.Reports.HealthInterval,
+        "reports.top_queries_interval": config.Reports.TopQueriesInterval,
+        "reports.capacity_trend_interval": config.Reports.CapacityTrendInterval,
+    }
+    for name, interval := range reportIntervals {
+        if interval != 0 && interval < time.Hour {
+            problems = append(problems, fmt.Sprintf("%s must be 0 or at least 1h, got %s",
+                name, interval))
+        }
+    }
+    if config.Reports.TopQueriesCount < 1 {
+        problems = append(problems, fmt.Sprintf("reports.top_queries_count must be at least 1, "+
+            "got %d", config.Reports.TopQueriesCount))
+    }
+    if config.Reports.MaxReports < 1 {
+        problems = append(problems, fmt.Sprintf("reports.max_reports must be at least 1, got %d",
+            config.Reports.MaxReports))
+    }
+    if config.TableExport.MaxRows < 1 {
+        problems = append(problems, fmt.Sprintf("table_export.max_rows must be at least 1, got %d",
+            config.TableExport.MaxRows))
+    }
+    if config.TableExport.PageSize < 1 {
+        problems = append(problems, fmt.Sprintf("table_export.page_size must be at least 1, "+
+            "got %d", config.TableExport.PageSize))
+    }
+    if config.TableImport.MaxUploadBytes < 1 {
+        problems = append(problems, fmt.Sprintf("table_import.max_upload_bytes must be at "+
+            "least 1, got %d", config.TableImport.MaxUploadBytes))
+    }
+    if config.TableImport.BatchSize < 1 {
+        problems = append(problems, fmt.Sprintf("table_import.batch_size must be at least 1, "+
+            "got %d", config.TableImport.BatchSize))
+    }
+    if config.TableImport.MaxRowErrors < 0 {
+        problems = append(problems, fmt.Sprintf("table_import.max_row_errors must not be "+
+            "negative, got %d", config.TableImport.MaxRowErrors))
+    }
+    if config.DatabaseDump.Directory == "" {
+        problems = append(problems, "database_dump.directory must be set")
+    }
+    if config.DatabaseDump.MaxDumps < 1 {
+        problems = append(problems, fmt.Sprintf("database_dump.max_dumps must be at least 1, "+
+            "got %d", config.DatabaseDump.MaxDumps))
+    }
+    if config.Profiles.Directory == "" {
+        problems = append(problems, "profiles.directory must be set")
+    }
+    if config.Profiles.MaxProfiles < 1 {
+        problems = append(problems, fmt.Sprintf("profiles.max_profiles must be at least 1, "+
+            "got %d", config.Profiles.MaxProfiles))
+    }
+    if config.Profiles.MaxSeconds < 1 {
+        problems = append(problems, fmt.Sprintf("profiles.max_seconds must be at least 1, "+
+            "got %d", config.Profiles.MaxSeconds))
+    }
+    if config.Shell.IdleTimeout <= 0 {
+        problems = append(problems, "shell.idle_timeout must be positive")
+    }
+    if config.Shell.MaxSessions < 1 {
+        problems = append(problems, fmt.Sprintf("shell.max_sessions must be at least 1, got %d",
+            config.Shell.MaxSessions))
+    }
+    for path, timeout := range config.Timeouts.Upstream {
+        if !strings.HasPrefix(path, "/") {
+            problems = append(problems, fmt.Sprintf("timeouts.upstream: %q is not an endpoint "+
+                "path", path))
+        }
+        if timeout <= 0 {
+            problems = append(problems, fmt.Sprintf("timeouts.upstream: the timeout of %s must "+
+                "be positive, got %s", path, timeout))
+        }
+    }
+    for path, ttl := range config.Cache.Ttls {
+        if !strings.HasPrefix(path, "/") {
+            problems = append(problems, fmt.Sprintf("cache.ttls: %q is not a route path", path))
+        }
+        if ttl < 0 {
+            problems = append(problems, fmt.Sprintf("cache.ttls: the TTL of %s must not be "+
+                "negative, got %s", path, ttl))
+        }
+    }
+    if len(problems) > 0 {
+        return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+    }
+    return nil
+}
+
+// Builds the config from the defaults, the config file, the environment and the command line
+// flags, in increasing order of precedence
+func buildConfig() (*Config, error) {
+    config := DefaultConfig()
+    if path := GetConfigFile(); path != "" {
+        contents, err := ioutil.ReadFile(path)
+        if err != nil {
+            return nil, fmt.Errorf("reading config file: %s", err.Error())
+        }
+        decoder := yaml.NewDecoder(bytes.NewReader(contents))
+        // Misspelled keys would otherwise be ignored silently
+        decoder.KnownFields(true)
+        // An empty file is a valid config
+        if err := decoder.Decode(config); err != nil && err != io.EOF {
+            return nil, fmt.Errorf("parsing config file %s: %s", path, err.Error())
+        }
+    }
+    if err := applyConfigEnv(config); err != nil {
+        return nil, err
+    }
+    flag.Visit(func(f *flag.Flag) {
+        if override, ok := FLAG_CONFIG_OVERRIDES[f.Name]; ok {
+            override(config)
+        }
+    })
+    config.Database.Host = NormalizeHost(config.Database.Host)
+    if err := config.Validate(); err != nil {
+        return nil, err
+    }
+    return config, nil
+}
+
+// Builds the config and makes it the one the server runs with
+func LoadConfig() (*Config, error) {
+    configReloadMutex.Lock()
+    defer configReloadMutex.Unlock()
+    config, err := buildConfig()
+    if err != nil {
+        return nil, err
+    }
+
+    // Most of the server reads these directly
+    HOST = config.Database.Host
+    PORT = config.Database.YsqlPort
+    DbName = config.Database.Name
+    DbYsqlUser = config.Auth.YsqlUsername
+    DbYcqlUser = config.Auth.YcqlUsername
+    DbPassword = config.Auth.Password
+    Secure = config.Tls.Enabled
+    SslMode = config.Tls.SslMode
+    SslRootCert = config.Tls.RootCert
+    SslRootKey = config.Tls.RootKey
+    YugabytedPath = config.Tools.YugabytedPath
+    YbAdminPath = config.Tools.YbAdminPath
+    currentConfig.Store(config)
+    return config, nil
+}
+
+// Builds the config again and swaps it in for the current one, if it is valid. Changes to the
+// sections read only at startup are not applied, their names are returned.
+func ReloadConfig() (*Config, []string, error) {
+    configReloadMutex.Lock()
+    defer configReloadMutex.Unlock()
+    config, err := buildConfig()
+    if err != nil {
+        return nil, nil, err
+    }
+    current := GetConfig()
+    ignoredSections := []string{}
+    newSections := reflect.ValueOf(config).Elem()
+    currentSections := reflect.ValueOf(current).Elem()
+    for i := 0; i < newSections.NumField(); i++ {
+        name := newSections.Type().Field(i).Tag.Get("yaml")
+        if !containsString(RESTART_CONFIG_SECTIONS, name) {
+            continue
+        }
+        if !reflect.DeepEqual(newSections.Field(i).Interface(),
+            currentSections.Field(i).Interface()) {
+            ignoredSections = append(ignoredSections, name)
+            newSections.Field(i).Set(currentSections.Field(i))
+        }
+    }
+    currentConfig.Store(config)
+    return config, ignoredSections, nil
+}
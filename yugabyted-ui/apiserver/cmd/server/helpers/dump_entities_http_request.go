@@ -0,0 +1,79 @@
+package helpers
+
+import (
+    "encoding/json"
+    "io/ioutil"
+)
+
+type DumpEntitiesReplica struct {
+    // VOTER, or OBSERVER for the replicas of read replica clusters
+    Type string `json:"type"`
+    ServerUuid string `json:"server_uuid"`
+    Addr string `json:"addr"`
+}
+
+type DumpEntitiesKeyspace struct {
+    KeyspaceId string `json:"keyspace_id"`
+    KeyspaceName string `json:"keyspace_name"`
+    // ysql or ycql
+    KeyspaceType string `json:"keyspace_type"`
+}
+
+type DumpEntitiesTable struct {
+    TableId string `json:"table_id"`
+    KeyspaceId string `json:"keyspace_id"`
+    TableName string `json:"table_name"`
+    State string `json:"state"`
+}
+
+type DumpEntitiesTablet struct {
+    TableId string `json:"table_id"`
+    TabletId string `json:"tablet_id"`
+    State string `json:"state"`
+    Replicas []DumpEntitiesReplica `json:"replicas"`
+    // Uuid of the tserver of the leader, empty while there is none
+    Leader string `json:"leader"`
+}
+
+type DumpEntities struct {
+    Keyspaces []DumpEntitiesKeyspace `json:"keyspaces"`
+    Tables []DumpEntitiesTable `json:"tables"`
+    Tablets []DumpEntitiesTablet `json:"tablets"`
+}
+
+type DumpEntitiesFuture struct {
+    Entities DumpEntities
+    Error error
+}
+
+// Gets the keyspaces, tables and tablets of the cluster, with the tservers of the replicas of
+// the tablets, from the master
+func GetDumpEntitiesFuture(nodeHost string, future chan DumpEntitiesFuture) {
+    dumpEntities := DumpEntitiesFuture{
+        Entities: DumpEntities{
+            Keyspaces: []DumpEntitiesKeyspace{},
+            Tables: []DumpEntitiesTable{},
+            Tablets: []DumpEntitiesTablet{},
+        },
+        Error: nil,
+    }
+    url := GetHttpUrl(nodeHost, GetConfig().Upstream.MasterHttpPort, "/dump-entities")
+    httpClient := NewUpstreamHttpClient(url)
+    resp, err := httpClient.Get(url)
+    if err != nil {
+        dumpEntities.Error = NewNodeRequestError(url, err)
+        future <- dumpEntities
+        return
+    }
+    defer resp.Body.Close()
+    body, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        dumpEntities.Error = NewNodeRequestError(url, err)
+        future <- dumpEntities
+        return
+    }
+    if err := json.Unmarshal(body, &dumpEntities.Entities); err != nil {
+        dumpEntities.Error = NewNodeParseError(url, err)
+    }
+    future <- dumpEntities
+}
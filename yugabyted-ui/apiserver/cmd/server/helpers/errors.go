@@ -0,0 +1,84 @@
+package helpers
+
+import (
+    "context"
+    "errors"
+    "expvar"
+    "fmt"
+    "net"
+    "net/url"
+)
+
+// What went wrong when requesting a node, to be checked with errors.Is
+var ErrNodeUnreachable = errors.New("node unreachable")
+var ErrTimeout = errors.New("timed out")
+var ErrUnexpectedStatus = errors.New("unexpected status")
+var ErrParse = errors.New("invalid response")
+
+// The node answered, but reported an error of its own
+var ErrNodeReported = errors.New("node reported an error")
+
+// An error of a request to the web endpoints of a master or tserver, with the node it went to.
+// It is both the kind of error and the error it wraps for errors.Is and errors.As.
+type NodeError struct {
+    // The address of the node, as host:port
+    Host string
+    Url string
+    Kind error
+    Err error
+}
+
+func (e *NodeError) Error() string {
+    return e.Err.Error()
+}
+
+func (e *NodeError) Unwrap() error {
+    return e.Err
+}
+
+func (e *NodeError) Is(target error) bool {
+    return target == e.Kind
+}
+
+// Number of requests to the web endpoints of the nodes that timed out, by endpoint path. Served
+// on /debug/vars to help tune timeouts.upstream.
+var UpstreamDeadlineHits = expvar.NewMap("upstream_deadline_hits")
+
+func newNodeError(requestUrl string, kind error, err error) error {
+    host := requestUrl
+    if parsedUrl, parseErr := url.Parse(requestUrl); parseErr == nil {
+        host = parsedUrl.Host
+        if kind == ErrTimeout {
+            UpstreamDeadlineHits.Add(parsedUrl.Path, 1)
+        }
+    }
+    return &NodeError{Host: host, Url: requestUrl, Kind: kind, Err: err}
+}
+
+// Wraps an error of sending a request to a node or reading its response, which either timed
+// out or could not reach the node
+func NewNodeRequestError(requestUrl string, err error) error {
+    var netError net.Error
+    if errors.Is(err, context.DeadlineExceeded) ||
+        (errors.As(err, &netError) && netError.Timeout()) {
+        return newNodeError(requestUrl, ErrTimeout, err)
+    }
+    return newNodeError(requestUrl, ErrNodeUnreachable, err)
+}
+
+// Builds the error of a node that answered with another status than 200 OK
+func NewNodeStatusError(requestUrl string, status string) error {
+    return newNodeError(requestUrl, ErrUnexpectedStatus,
+        fmt.Errorf("%s returned %s", requestUrl, status))
+}
+
+// Wraps an error of parsing the response of a node
+func NewNodeParseError(requestUrl string, err error) error {
+    return newNodeError(requestUrl, ErrParse,
+        fmt.Errorf("invalid response from %s: %w", requestUrl, err))
+}
+
+// Builds the error of a node that reported an error in its response
+func NewNodeReportedError(requestUrl string, message string) error {
+    return newNodeError(requestUrl, ErrNodeReported, errors.New(message))
+}
@@ -0,0 +1,72 @@
+package helpers
+
+import (
+    "encoding/xml"
+    "errors"
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+// The version line of yb-master --version and yb-tserver --version
+var YB_SERVER_VERSION_REGEX = regexp.MustCompile(`version (\S+) build (\S+)`)
+
+type GFlagDoc struct {
+    Name string `xml:"name"`
+    Meaning string `xml:"meaning"`
+    Default string `xml:"default"`
+    Type string `xml:"type"`
+    // Separated by commas, e.g. runtime,advanced
+    Tags string `xml:"tags"`
+}
+
+type gFlagDocs struct {
+    Flags []GFlagDoc `xml:"flag"`
+}
+
+func getYbServerPath(isMaster bool) string {
+    if isMaster {
+        return GetConfig().Tools.YbMasterPath
+    }
+    return GetConfig().Tools.YbTserverPath
+}
+
+// Gets the version of the local yb-master or yb-tserver binary, e.g. 2.20.1.0-b97
+func GetYbServerVersion(isMaster bool) (string, error) {
+    output, err := runCommand(GetConfig().Timeouts.YbServerCommand, getYbServerPath(isMaster),
+        "--version")
+    if err != nil {
+        return "", err
+    }
+    match := YB_SERVER_VERSION_REGEX.FindStringSubmatch(output)
+    if match == nil {
+        return "", fmt.Errorf("no version in the output of %s --version",
+            getYbServerPath(isMaster))
+    }
+    return match[1] + "-b" + match[2], nil
+}
+
+// Gets the documentation of every flag of the local yb-master or yb-tserver binary, by flag
+// name, from the XML it dumps them as
+func GetGFlagDocs(isMaster bool) (map[string]GFlagDoc, error) {
+    output, err := runCommand(GetConfig().Timeouts.YbServerCommand, getYbServerPath(isMaster),
+        "--dump_flags_xml")
+    if err != nil {
+        return nil, err
+    }
+    // Log lines may come before the document
+    start := strings.Index(output, "<AllFlags>")
+    if start == -1 {
+        return nil, errors.New("no flags in the output of " + getYbServerPath(isMaster))
+    }
+    docs := gFlagDocs{}
+    if err := xml.Unmarshal([]byte(output[start:]), &docs); err != nil {
+        return nil, fmt.Errorf("failed to parse the flags of %s: %w",
+            getYbServerPath(isMaster), err)
+    }
+    docsByName := map[string]GFlagDoc{}
+    for _, doc := range docs.Flags {
+        docsByName[doc.Name] = doc
+    }
+    return docsByName, nil
+}
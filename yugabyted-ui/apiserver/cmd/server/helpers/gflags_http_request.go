@@ -2,11 +2,8 @@ package helpers
 
 import (
     "bytes"
-    "fmt"
     "io/ioutil"
-    "net/http"
     "regexp"
-    "time"
 )
 
 type GFlagsFuture struct {
@@ -15,28 +12,26 @@ type GFlagsFuture struct {
 }
 
 func GetGFlagsFuture(hostName string, isMaster bool, future chan GFlagsFuture) {
-    port := "9000"
+    port := GetConfig().Upstream.TserverHttpPort
     if isMaster {
-        port = "7000"
+        port = GetConfig().Upstream.MasterHttpPort
     }
     gFlags := GFlagsFuture {
         GFlags: map[string]string{},
         Error: nil,
     }
-    httpClient := &http.Client{
-        Timeout: time.Second * 10,
-    }
-    url := fmt.Sprintf("http://%s:%s/varz?raw=1", hostName, port)
+    url := GetHttpUrl(hostName, port, "/varz?raw=1")
+    httpClient := NewUpstreamHttpClient(url)
     resp, err := httpClient.Get(url)
     if err != nil {
-        gFlags.Error = err
+        gFlags.Error = NewNodeRequestError(url, err)
         future <- gFlags
         return
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        gFlags.Error = err
+        gFlags.Error = NewNodeRequestError(url, err)
         future <- gFlags
         return
     }
@@ -2,11 +2,8 @@ package helpers
 
 import (
     "encoding/json"
-    "errors"
     "fmt"
     "io/ioutil"
-    "net/http"
-    "time"
 )
 
 type HealthCheckStruct struct {
@@ -25,36 +22,35 @@ func GetHealthCheckFuture(nodeHost string, future chan HealthCheckFuture) {
         HealthCheck: HealthCheckStruct{},
         Error: nil,
     }
-    httpClient := &http.Client{
-        Timeout: time.Second * 10,
-    }
-    url := fmt.Sprintf("http://%s:7000/api/v1/health-check", nodeHost)
+    url := GetHttpUrl(nodeHost, GetConfig().Upstream.MasterHttpPort, "/api/v1/health-check")
+    httpClient := NewUpstreamHttpClient(url)
     resp, err := httpClient.Get(url)
     if err != nil {
-        healthCheck.Error = err
+        healthCheck.Error = NewNodeRequestError(url, err)
         future <- healthCheck
         return
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        healthCheck.Error = err
+        healthCheck.Error = NewNodeRequestError(url, err)
         future <- healthCheck
         return
     }
     var result map[string]interface{}
     err = json.Unmarshal([]byte(body), &result)
     if err != nil {
-        healthCheck.Error = err
+        healthCheck.Error = NewNodeParseError(url, err)
         future <- healthCheck
         return
     }
     if val, ok := result["error"]; ok {
-        healthCheck.Error = errors.New(val.(string))
+        healthCheck.Error = NewNodeReportedError(url, fmt.Sprint(val))
         future <- healthCheck
         return
     }
-    err = json.Unmarshal([]byte(body), &healthCheck.HealthCheck)
-    healthCheck.Error = err
+    if err := json.Unmarshal([]byte(body), &healthCheck.HealthCheck); err != nil {
+        healthCheck.Error = NewNodeParseError(url, err)
+    }
     future <- healthCheck
 }
@@ -0,0 +1,48 @@
+package helpers
+
+import (
+    "encoding/json"
+    "errors"
+    "io/ioutil"
+)
+
+type JsonDocumentFuture struct {
+    Document json.RawMessage
+    Error error
+}
+
+// Gets a JSON document served by a master or tserver as is, for passing it on without knowing
+// its layout
+func GetJsonDocumentFuture(hostName string, isMaster bool, path string,
+    future chan JsonDocumentFuture) {
+    jsonDocument := JsonDocumentFuture{
+        Document: nil,
+        Error: nil,
+    }
+    port := GetConfig().Upstream.TserverHttpPort
+    if isMaster {
+        port = GetConfig().Upstream.MasterHttpPort
+    }
+    url := GetHttpUrl(hostName, port, path)
+    httpClient := NewUpstreamHttpClient(url)
+    resp, err := httpClient.Get(url)
+    if err != nil {
+        jsonDocument.Error = NewNodeRequestError(url, err)
+        future <- jsonDocument
+        return
+    }
+    defer resp.Body.Close()
+    body, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        jsonDocument.Error = NewNodeRequestError(url, err)
+        future <- jsonDocument
+        return
+    }
+    if !json.Valid(body) {
+        jsonDocument.Error = NewNodeParseError(url, errors.New("the response is not JSON"))
+        future <- jsonDocument
+        return
+    }
+    jsonDocument.Document = body
+    future <- jsonDocument
+}
@@ -0,0 +1,82 @@
+package helpers
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "strings"
+)
+
+type KafkaConnectState struct {
+    State string `json:"state"`
+    WorkerId string `json:"worker_id"`
+    // Stack trace of the failure, for failed connectors and tasks
+    Trace string `json:"trace"`
+}
+
+type KafkaConnectTaskState struct {
+    KafkaConnectState
+    Id int `json:"id"`
+}
+
+type KafkaConnectConnector struct {
+    Info struct {
+        Name string `json:"name"`
+        Config map[string]string `json:"config"`
+        Type string `json:"type"`
+    } `json:"info"`
+    Status struct {
+        Connector KafkaConnectState `json:"connector"`
+        Tasks []KafkaConnectTaskState `json:"tasks"`
+    } `json:"status"`
+}
+
+type KafkaConnectConnectorsFuture struct {
+    // By connector name
+    Connectors map[string]KafkaConnectConnector
+    Error error
+}
+
+// Gets the connectors of the Kafka Connect cluster of kafka_connect.url, with their config and
+// status
+func GetKafkaConnectConnectorsFuture(future chan KafkaConnectConnectorsFuture) {
+    connectors := KafkaConnectConnectorsFuture{
+        Connectors: map[string]KafkaConnectConnector{},
+        Error: nil,
+    }
+    config := GetConfig().KafkaConnect
+    url := strings.TrimSuffix(config.Url, "/") + "/connectors?expand=info&expand=status"
+    request, err := http.NewRequest(http.MethodGet, url, nil)
+    if err != nil {
+        connectors.Error = err
+        future <- connectors
+        return
+    }
+    if config.Username != "" {
+        request.SetBasicAuth(config.Username, config.Password)
+    }
+    httpClient := NewHttpClient()
+    resp, err := httpClient.Do(request)
+    if err != nil {
+        connectors.Error = fmt.Errorf("failed to reach Kafka Connect: %w", err)
+        future <- connectors
+        return
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        connectors.Error = fmt.Errorf("Kafka Connect returned %s", resp.Status)
+        future <- connectors
+        return
+    }
+    body, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        connectors.Error = fmt.Errorf("failed to reach Kafka Connect: %w", err)
+        future <- connectors
+        return
+    }
+    if err := json.Unmarshal(body, &connectors.Connectors); err != nil {
+        connectors.Error = fmt.Errorf("invalid response from Kafka Connect: %w", err)
+    }
+    future <- connectors
+}
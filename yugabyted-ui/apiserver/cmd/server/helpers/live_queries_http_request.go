@@ -2,12 +2,9 @@ package helpers
 import (
     "apiserver/cmd/server/models"
     "encoding/json"
-    "fmt"
     "io/ioutil"
     "net"
-    "net/http"
     "strings"
-    "time"
 )
 type LiveQueryHttpYsqlResponseConnection struct {
     BackendType    string `json:"backend_type"`
@@ -73,20 +70,18 @@ func GetLiveQueriesYsqlFuture(nodeHost string, future chan LiveQueriesYsqlFuture
         Items: []*models.LiveQueryResponseYsqlQueryItem{},
         Error: nil,
     }
-    httpClient := &http.Client{
-        Timeout: time.Second * 10,
-    }
-    url := fmt.Sprintf("http://%s:13000/rpcz", nodeHost)
+    url := GetHttpUrl(nodeHost, GetConfig().Upstream.YsqlHttpPort, "/rpcz")
+    httpClient := NewUpstreamHttpClient(url)
     resp, err := httpClient.Get(url)
     if err != nil {
-        liveQueries.Error = err
+        liveQueries.Error = NewNodeRequestError(url, err)
         future <- liveQueries
         return
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        liveQueries.Error = err
+        liveQueries.Error = NewNodeRequestError(url, err)
         future <- liveQueries
         return
     }
@@ -126,20 +121,18 @@ func GetLiveQueriesYcqlFuture(nodeHost string, future chan LiveQueriesYcqlFuture
         Items: []*models.LiveQueryResponseYcqlQueryItem{},
         Error: nil,
     }
-    httpClient := &http.Client{
-        Timeout: time.Second * 10,
-    }
-    url := fmt.Sprintf("http://%s:12000/rpcz", nodeHost)
+    url := GetHttpUrl(nodeHost, GetConfig().Upstream.YcqlHttpPort, "/rpcz")
+    httpClient := NewUpstreamHttpClient(url)
     resp, err := httpClient.Get(url)
     if err != nil {
-        liveQueries.Error = err
+        liveQueries.Error = NewNodeRequestError(url, err)
         future <- liveQueries
         return
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        liveQueries.Error = err
+        liveQueries.Error = NewNodeRequestError(url, err)
         future <- liveQueries
         return
     }
@@ -0,0 +1,188 @@
+package helpers
+
+import (
+    "fmt"
+    "io/ioutil"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "syscall"
+    "time"
+)
+
+const MASTER_PROCESS = "master"
+const TSERVER_PROCESS = "tserver"
+
+// How long a process gets to shut down cleanly before it is killed
+const PROCESS_STOP_TIMEOUT = 30 * time.Second
+const PROCESS_POLL_INTERVAL = 500 * time.Millisecond
+
+// Flags whose values tell which address a server process serves
+var PROCESS_ADDRESS_FLAGS = []string{"server_broadcast_addresses", "rpc_bind_addresses"}
+
+// Command lines of the processes stopped by this server, so that they can be started again
+var stoppedProcessCommands = map[string]localProcess{}
+var stoppedProcessCommandsMutex sync.Mutex
+
+type localProcess struct {
+    Pid int
+    Args []string
+    Dir string
+}
+
+func getProcessBinary(process string) string {
+    return "yb-" + process
+}
+
+func getProcessKey(process string, address string) string {
+    return process + "@" + address
+}
+
+// Gets the hosts a server process serves, from its command line flags
+func getProcessHosts(args []string) []string {
+    hosts := []string{}
+    for _, arg := range args[1:] {
+        for _, flag := range PROCESS_ADDRESS_FLAGS {
+            value := ""
+            if strings.HasPrefix(arg, "--"+flag+"=") {
+                value = strings.TrimPrefix(arg, "--"+flag+"=")
+            } else if strings.HasPrefix(arg, "-"+flag+"=") {
+                value = strings.TrimPrefix(arg, "-"+flag+"=")
+            } else {
+                continue
+            }
+            for _, hostPort := range strings.Split(value, ",") {
+                if host, err := GetHostFromAddress(hostPort); err == nil {
+                    hosts = append(hosts, host)
+                } else {
+                    hosts = append(hosts, NormalizeHost(hostPort))
+                }
+            }
+        }
+    }
+    return hosts
+}
+
+// Finds the master or tserver process on this host that serves the address. Returns nil if
+// there is none.
+func findLocalProcess(process string, address string) (*localProcess, error) {
+    procDirs, err := filepath.Glob("/proc/[0-9]*")
+    if err != nil {
+        return nil, err
+    }
+    for _, procDir := range procDirs {
+        pid, err := strconv.Atoi(filepath.Base(procDir))
+        if err != nil {
+            continue
+        }
+        // Processes can exit while we scan, and others' processes may not be readable
+        cmdline, err := ioutil.ReadFile(filepath.Join(procDir, "cmdline"))
+        if err != nil || len(cmdline) == 0 {
+            continue
+        }
+        args := strings.Split(strings.TrimSuffix(string(cmdline), "\x00"), "\x00")
+        if filepath.Base(args[0]) != getProcessBinary(process) {
+            continue
+        }
+        for _, host := range getProcessHosts(args) {
+            if host == address {
+                dir, _ := os.Readlink(filepath.Join(procDir, "cwd"))
+                return &localProcess{Pid: pid, Args: args, Dir: dir}, nil
+            }
+        }
+    }
+    return nil, nil
+}
+
+func isProcessRunning(pid int) bool {
+    // Signal 0 only checks whether the process exists. Zombies are reaped by their parent.
+    return syscall.Kill(pid, 0) == nil
+}
+
+// Checks whether a master or tserver process for the address runs on this host
+func IsLocalProcessRunning(process string, address string) (bool, error) {
+    localProcess, err := findLocalProcess(process, address)
+    return localProcess != nil, err
+}
+
+// Stops the master or tserver process for the address on this host, killing it if it does not
+// shut down in time. Its command line is kept so that StartLocalProcess can start it again.
+func StopLocalProcess(process string, address string) error {
+    localProcess, err := findLocalProcess(process, address)
+    if err != nil {
+        return err
+    }
+    if localProcess == nil {
+        return fmt.Errorf("no %s process for %s runs on this host", process, address)
+    }
+    stoppedProcessCommandsMutex.Lock()
+    stoppedProcessCommands[getProcessKey(process, address)] = *localProcess
+    stoppedProcessCommandsMutex.Unlock()
+
+    if err := syscall.Kill(localProcess.Pid, syscall.SIGTERM); err != nil {
+        return err
+    }
+    deadline := time.Now().Add(PROCESS_STOP_TIMEOUT)
+    for time.Now().Before(deadline) {
+        if !isProcessRunning(localProcess.Pid) {
+            return nil
+        }
+        time.Sleep(PROCESS_POLL_INTERVAL)
+    }
+    if err := syscall.Kill(localProcess.Pid, syscall.SIGKILL); err != nil &&
+        err != syscall.ESRCH {
+        return err
+    }
+    return nil
+}
+
+// Starts the master or tserver process for the address on this host again, with the command
+// line it had when it was stopped by StopLocalProcess
+func StartLocalProcess(process string, address string) error {
+    running, err := IsLocalProcessRunning(process, address)
+    if err != nil {
+        return err
+    }
+    if running {
+        return fmt.Errorf("the %s process for %s is already running", process, address)
+    }
+    stoppedProcessCommandsMutex.Lock()
+    stoppedProcess, ok := stoppedProcessCommands[getProcessKey(process, address)]
+    stoppedProcessCommandsMutex.Unlock()
+    if !ok {
+        return fmt.Errorf("the %s process for %s was not stopped by this server, start it "+
+            "with yugabyted", process, address)
+    }
+    cmd := exec.Command(stoppedProcess.Args[0], stoppedProcess.Args[1:]...)
+    cmd.Dir = stoppedProcess.Dir
+    // Detach the process, so that it outlives this server
+    cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+    if err := cmd.Start(); err != nil {
+        return err
+    }
+    go cmd.Wait()
+    return nil
+}
+
+// Restarts the master or tserver process for the address on this host. yugabyted restarts
+// the processes it monitors by itself, otherwise the process is started again here.
+func RestartLocalProcess(process string, address string, respawnTimeout time.Duration) error {
+    if err := StopLocalProcess(process, address); err != nil {
+        return err
+    }
+    deadline := time.Now().Add(respawnTimeout)
+    for time.Now().Before(deadline) {
+        running, err := IsLocalProcessRunning(process, address)
+        if err != nil {
+            return err
+        }
+        if running {
+            return nil
+        }
+        time.Sleep(PROCESS_POLL_INTERVAL)
+    }
+    return StartLocalProcess(process, address)
+}
@@ -2,10 +2,7 @@ package helpers
 
 import (
     "encoding/json"
-    "fmt"
     "io/ioutil"
-    "net/http"
-    "time"
 )
 
 type InstanceIdStruct struct {
@@ -33,10 +30,16 @@ type RegistrationStruct struct {
     BroadcastAddresses []HostPortAddress `json:"broadcast_addresses"`
 }
 
+type MasterErrorStruct struct {
+    Message string `json:"message"`
+}
+
 type Master struct {
     InstanceId InstanceIdStruct `json:"instance_id"`
     Registration RegistrationStruct `json:"registration"`
     Role string `json:"role"`
+    // Set when the master could not be reached
+    Error *MasterErrorStruct `json:"error"`
 }
 
 type MastersFuture struct {
@@ -49,20 +52,18 @@ func GetMastersFuture(nodeHost string, future chan MastersFuture) {
         Masters: []Master{},
         Error: nil,
     }
-    httpClient := &http.Client{
-        Timeout: time.Second * 10,
-    }
-    url := fmt.Sprintf("http://%s:7000/api/v1/masters", nodeHost)
+    url := GetHttpUrl(nodeHost, GetConfig().Upstream.MasterHttpPort, "/api/v1/masters")
+    httpClient := NewUpstreamHttpClient(url)
     resp, err := httpClient.Get(url)
     if err != nil {
-        masters.Error = err
+        masters.Error = NewNodeRequestError(url, err)
         future <- masters
         return
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        masters.Error = err
+        masters.Error = NewNodeRequestError(url, err)
         future <- masters
         return
     }
@@ -71,6 +72,8 @@ func GetMastersFuture(nodeHost string, future chan MastersFuture) {
         future <- masters
         return
     }
-    masters.Error = err
+    if err != nil {
+        masters.Error = NewNodeParseError(url, err)
+    }
     future <- masters
 }
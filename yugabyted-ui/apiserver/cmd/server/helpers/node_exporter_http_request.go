@@ -0,0 +1,136 @@
+package helpers
+
+import (
+    "bufio"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+type NodeExporterSample struct {
+    // Labels of the sample as written in the exposition format, e.g. {cpu="0",mode="idle"}
+    Labels string
+    Value float64
+}
+
+// Gets the value of a label of the sample, or "" if the sample does not have it
+func (sample NodeExporterSample) Label(name string) string {
+    labels := strings.TrimSuffix(strings.TrimPrefix(sample.Labels, "{"), "}")
+    for labels != "" {
+        equalsIndex := strings.Index(labels, `="`)
+        if equalsIndex == -1 {
+            return ""
+        }
+        labelName := strings.TrimSpace(labels[:equalsIndex])
+        rest := labels[equalsIndex+2:]
+        // Label values escape quotes, backslashes and line feeds with a backslash
+        var value strings.Builder
+        end := -1
+        for i := 0; i < len(rest); i++ {
+            if rest[i] == '\\' && i+1 < len(rest) {
+                i++
+                if rest[i] == 'n' {
+                    value.WriteByte('\n')
+                } else {
+                    value.WriteByte(rest[i])
+                }
+            } else if rest[i] == '"' {
+                end = i
+                break
+            } else {
+                value.WriteByte(rest[i])
+            }
+        }
+        if end == -1 {
+            return ""
+        }
+        if labelName == name {
+            return value.String()
+        }
+        labels = strings.TrimPrefix(rest[end+1:], ",")
+    }
+    return ""
+}
+
+type NodeExporterMetricsFuture struct {
+    // Samples of each metric by metric name
+    Metrics map[string][]NodeExporterSample
+    Error error
+}
+
+// Parses the Prometheus text exposition format, skipping comments and malformed lines
+func parseNodeExporterMetrics(body *bufio.Scanner) map[string][]NodeExporterSample {
+    metrics := map[string][]NodeExporterSample{}
+    for body.Scan() {
+        line := strings.TrimSpace(body.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        fields := strings.Fields(line)
+        if len(fields) < 2 {
+            continue
+        }
+        // The value is the last field unless a timestamp follows it, and label values may
+        // contain spaces, so split on the closing brace of the labels instead.
+        nameAndLabels, valueString := fields[0], fields[1]
+        if braceIndex := strings.LastIndex(line, "}"); braceIndex != -1 {
+            nameAndLabels = line[:braceIndex+1]
+            valueFields := strings.Fields(line[braceIndex+1:])
+            if len(valueFields) < 1 {
+                continue
+            }
+            valueString = valueFields[0]
+        }
+        value, err := strconv.ParseFloat(valueString, 64)
+        if err != nil {
+            continue
+        }
+        name, labels := nameAndLabels, ""
+        if labelIndex := strings.Index(nameAndLabels, "{"); labelIndex != -1 {
+            name, labels = nameAndLabels[:labelIndex], nameAndLabels[labelIndex:]
+        }
+        metrics[name] = append(metrics[name], NodeExporterSample{
+            Labels: labels,
+            Value: value,
+        })
+    }
+    return metrics
+}
+
+func GetNodeExporterMetricsFuture(nodeHost string, port int32,
+    future chan NodeExporterMetricsFuture) {
+    url := GetHttpUrl(nodeHost, int(port), "/metrics")
+    GetNodeExporterMetricsWithTimeoutFuture(nodeHost, port, GetUpstreamTimeout(url), future)
+}
+
+// Gets the metrics of a node_exporter, giving up after a timeout other than the one of its
+// endpoint, e.g. a shorter one for the background polls
+func GetNodeExporterMetricsWithTimeoutFuture(nodeHost string, port int32,
+    timeout time.Duration, future chan NodeExporterMetricsFuture) {
+    nodeExporterMetrics := NodeExporterMetricsFuture{
+        Metrics: map[string][]NodeExporterSample{},
+        Error: nil,
+    }
+    url := GetHttpUrl(nodeHost, int(port), "/metrics")
+    httpClient := NewHttpClientWithTimeout(timeout)
+    resp, err := httpClient.Get(url)
+    if err != nil {
+        nodeExporterMetrics.Error = NewNodeRequestError(url, err)
+        future <- nodeExporterMetrics
+        return
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        nodeExporterMetrics.Error = NewNodeStatusError(url, resp.Status)
+        future <- nodeExporterMetrics
+        return
+    }
+    scanner := bufio.NewScanner(resp.Body)
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+    nodeExporterMetrics.Metrics = parseNodeExporterMetrics(scanner)
+    if err := scanner.Err(); err != nil {
+        nodeExporterMetrics.Error = NewNodeRequestError(url, err)
+    }
+    future <- nodeExporterMetrics
+}
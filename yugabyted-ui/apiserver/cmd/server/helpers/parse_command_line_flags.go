@@ -14,6 +14,13 @@ var (
         DbPassword  string
         SslMode     string
         SslRootCert string
+        SslRootKey  string
+        YugabytedPath string
+        YbAdminPath string
+        BindAddress string
+        ServerPort  int
+        BasePath    string
+        Debug       bool
 )
 
 func init() {
@@ -32,5 +39,22 @@ func init() {
                 "ssl mode for connecting to the database.")
         flag.StringVar(&SslRootCert, "ssl_root certificate", "",
                 "root certificate for connecting to the database.")
+        flag.StringVar(&SslRootCert, "ssl_root_cert", "",
+                "root certificate for connecting to the database.")
+        flag.StringVar(&SslRootKey, "ssl_root_key", "",
+                "private key of the root certificate, used to sign client certificates.")
+        flag.StringVar(&YugabytedPath, "yugabyted_path", "yugabyted",
+                "path to the yugabyted script, used to start and stop nodes.")
+        flag.StringVar(&YbAdminPath, "yb_admin_path", "yb-admin",
+                "path to the yb-admin binary, used to change the cluster configuration.")
+        flag.StringVar(&BindAddress, "bind_address", "127.0.0.1",
+                "address the API server listens on, 0.0.0.0 to listen on all interfaces.")
+        flag.IntVar(&ServerPort, "port", 15433, "port the API server listens on.")
+        flag.StringVar(&BasePath, "base_path", "",
+                "URL path prefix the API server is served under, e.g. behind a reverse proxy.")
+        flag.BoolVar(&Debug, "debug", false,
+                "serve pprof and expvar endpoints of the API server on the debug port.")
+        flag.StringVar(&ConfigFile, "config_file", "",
+                "path to a YAML config file, the flags above override its settings.")
         flag.Parse()
 }
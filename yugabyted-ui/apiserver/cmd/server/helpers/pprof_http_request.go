@@ -0,0 +1,102 @@
+package helpers
+
+import (
+    "fmt"
+    "io"
+    "io/ioutil"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const PROFILE_KIND_CPU = "cpu"
+const PROFILE_KIND_HEAP = "heap"
+
+// Collects a profile from the pprof endpoints of a master or tserver and writes it to a file.
+// CPU profiles are sampled for the given number of seconds, heap profiles are taken at once.
+// Returns the size of the profile.
+func DownloadProfile(hostName string, isMaster bool, kind string, seconds int,
+    path string) (int64, error) {
+    port := GetConfig().Upstream.TserverHttpPort
+    if isMaster {
+        port = GetConfig().Upstream.MasterHttpPort
+    }
+    var url string
+    var timeout time.Duration
+    switch kind {
+    case PROFILE_KIND_CPU:
+        url = GetHttpUrl(hostName, port, fmt.Sprintf("/pprof/profile?seconds=%d", seconds))
+        timeout = GetUpstreamTimeout(url) + time.Duration(seconds)*time.Second
+    case PROFILE_KIND_HEAP:
+        url = GetHttpUrl(hostName, port, "/pprof/heap")
+        timeout = GetUpstreamTimeout(url)
+    default:
+        return 0, fmt.Errorf("unknown profile kind %s", kind)
+    }
+    resp, err := NewHttpClientWithTimeout(timeout).Get(url)
+    if err != nil {
+        return 0, NewNodeRequestError(url, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return 0, NewNodeStatusError(url, resp.Status)
+    }
+    file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+    if err != nil {
+        return 0, err
+    }
+    size, err := io.Copy(file, resp.Body)
+    if closeErr := file.Close(); err == nil {
+        err = closeErr
+    }
+    if err != nil {
+        os.Remove(path)
+        return 0, err
+    }
+    return size, nil
+}
+
+// Gets the names of the functions at addresses in a master or tserver, by the address.
+// Addresses that cannot be resolved are left out.
+func SymbolizeAddresses(hostName string, isMaster bool,
+    addresses []uint64) (map[uint64]string, error) {
+    port := GetConfig().Upstream.TserverHttpPort
+    if isMaster {
+        port = GetConfig().Upstream.MasterHttpPort
+    }
+    hexAddresses := []string{}
+    for _, address := range addresses {
+        hexAddresses = append(hexAddresses, fmt.Sprintf("0x%x", address))
+    }
+    url := GetHttpUrl(hostName, port, "/pprof/symbol")
+    resp, err := NewUpstreamHttpClient(url).Post(url, "text/plain",
+        strings.NewReader(strings.Join(hexAddresses, "+")))
+    if err != nil {
+        return nil, NewNodeRequestError(url, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, NewNodeStatusError(url, resp.Status)
+    }
+    body, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        return nil, NewNodeRequestError(url, err)
+    }
+    // Each line is an address and the name of its function, separated by whitespace
+    symbols := map[uint64]string{}
+    for _, line := range strings.Split(string(body), "\n") {
+        line = strings.TrimSpace(line)
+        separator := strings.IndexAny(line, " \t")
+        if separator < 0 {
+            continue
+        }
+        address, err := strconv.ParseUint(strings.TrimPrefix(line[:separator], "0x"), 16, 64)
+        if err != nil {
+            continue
+        }
+        symbols[address] = strings.TrimSpace(line[separator+1:])
+    }
+    return symbols, nil
+}
@@ -0,0 +1,34 @@
+package helpers
+
+import (
+    "fmt"
+    "net/http"
+)
+
+type ReleaseAvailabilityFuture struct {
+    Url string
+    IsAvailable bool
+    Error error
+}
+
+// Checks whether the Linux release package for the given version can be downloaded
+func GetReleaseAvailabilityFuture(versionInfo VersionInfoStruct,
+    future chan ReleaseAvailabilityFuture) {
+    releaseAvailability := ReleaseAvailabilityFuture{
+        Url: fmt.Sprintf(
+            "https://downloads.yugabyte.com/releases/%s/yugabyte-%s-b%s-linux-x86_64.tar.gz",
+            versionInfo.VersionNumber, versionInfo.VersionNumber, versionInfo.BuildNumber),
+        IsAvailable: false,
+        Error: nil,
+    }
+    httpClient := NewHttpClient()
+    resp, err := httpClient.Head(releaseAvailability.Url)
+    if err != nil {
+        releaseAvailability.Error = err
+        future <- releaseAvailability
+        return
+    }
+    resp.Body.Close()
+    releaseAvailability.IsAvailable = resp.StatusCode == http.StatusOK
+    future <- releaseAvailability
+}
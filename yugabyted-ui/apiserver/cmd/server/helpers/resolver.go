@@ -0,0 +1,163 @@
+package helpers
+
+import (
+    "context"
+    "net"
+    "net/http"
+    "net/url"
+    "sync"
+    "time"
+
+    "golang.org/x/net/http/httpproxy"
+)
+
+type cachedAddresses struct {
+    ips []string
+    expiresAt time.Time
+}
+
+// Resolves node hostnames, caching the results for upstream.dns_cache_ttl. Node hostnames can
+// move to new IPs when nodes restart, so the cached IPs of a host are dropped as soon as none
+// of them can be dialed, and the host is resolved again.
+type Resolver struct {
+    mutex sync.Mutex
+    cache map[string]cachedAddresses
+}
+
+var NodeResolver = &Resolver{cache: map[string]cachedAddresses{}}
+
+// Shared by the clients of all upstream requests, so that connections are reused
+var upstreamTransport = &http.Transport{
+    Proxy: getUpstreamProxy,
+    DialContext: NodeResolver.DialContext,
+    MaxIdleConns: 100,
+    IdleConnTimeout: 90 * time.Second,
+    TLSHandshakeTimeout: 10 * time.Second,
+}
+
+// Gets the proxy for an upstream request. The proxy settings of the config take precedence over
+// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func getUpstreamProxy(request *http.Request) (*url.URL, error) {
+    proxyConfig := httpproxy.FromEnvironment()
+    proxy := GetConfig().Proxy
+    if proxy.HttpProxy != "" {
+        proxyConfig.HTTPProxy = proxy.HttpProxy
+    }
+    if proxy.HttpsProxy != "" {
+        proxyConfig.HTTPSProxy = proxy.HttpsProxy
+    }
+    if proxy.NoProxy != "" {
+        proxyConfig.NoProxy = proxy.NoProxy
+    }
+    return proxyConfig.ProxyFunc()(request.URL)
+}
+
+// Gets a client for requests to the web endpoints of the nodes
+func NewHttpClient() *http.Client {
+    return NewHttpClientWithTimeout(GetConfig().Timeouts.HttpRequest)
+}
+
+// Gets the timeout of the requests to a web endpoint of the nodes, from timeouts.upstream if the
+// path of the endpoint is listed there
+func GetUpstreamTimeout(requestUrl string) time.Duration {
+    timeouts := GetConfig().Timeouts
+    if parsedUrl, err := url.Parse(requestUrl); err == nil {
+        if timeout, ok := timeouts.Upstream[parsedUrl.Path]; ok {
+            return timeout
+        }
+    }
+    return timeouts.HttpRequest
+}
+
+// Gets a client for requests to a web endpoint of the nodes, with the timeout of the endpoint
+func NewUpstreamHttpClient(requestUrl string) *http.Client {
+    return NewHttpClientWithTimeout(GetUpstreamTimeout(requestUrl))
+}
+
+// Gets a client for requests to the web endpoints of the nodes that take longer than usual
+func NewHttpClientWithTimeout(timeout time.Duration) *http.Client {
+    return &http.Client{
+        Transport: upstreamTransport,
+        Timeout: timeout,
+    }
+}
+
+// Gets the IPs of the host, from the cache if they have not expired
+func (r *Resolver) Lookup(ctx context.Context, host string) ([]string, error) {
+    r.mutex.Lock()
+    cached, ok := r.cache[host]
+    r.mutex.Unlock()
+    if ok && time.Now().Before(cached.expiresAt) {
+        return cached.ips, nil
+    }
+    ips, err := net.DefaultResolver.LookupHost(ctx, host)
+    if err != nil {
+        return nil, err
+    }
+    if ttl := GetConfig().Upstream.DnsCacheTtl; ttl > 0 {
+        r.mutex.Lock()
+        r.cache[host] = cachedAddresses{ips: ips, expiresAt: time.Now().Add(ttl)}
+        r.mutex.Unlock()
+    }
+    return ips, nil
+}
+
+// Drops the cached IPs of the host
+func (r *Resolver) Invalidate(host string) {
+    r.mutex.Lock()
+    delete(r.cache, host)
+    r.mutex.Unlock()
+}
+
+func dialFirst(ctx context.Context, network string, ips []string, port string) (
+    net.Conn, error) {
+    dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+    var lastErr error
+    for _, ip := range ips {
+        conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+        if err == nil {
+            return conn, nil
+        }
+        lastErr = err
+    }
+    return nil, lastErr
+}
+
+// Dials the address, resolving its host with the cache. If none of the cached IPs can be
+// dialed, the host is resolved again and the new IPs are tried.
+func (r *Resolver) DialContext(ctx context.Context, network string, address string) (
+    net.Conn, error) {
+    host, port, err := net.SplitHostPort(address)
+    if err != nil {
+        return nil, err
+    }
+    if net.ParseIP(host) != nil {
+        return dialFirst(ctx, network, []string{host}, port)
+    }
+    ips, err := r.Lookup(ctx, host)
+    if err != nil {
+        return nil, err
+    }
+    conn, err := dialFirst(ctx, network, ips, port)
+    if err == nil || ctx.Err() != nil {
+        return conn, err
+    }
+    r.Invalidate(host)
+    freshIps, lookupErr := r.Lookup(ctx, host)
+    if lookupErr != nil || sameStrings(ips, freshIps) {
+        return nil, err
+    }
+    return dialFirst(ctx, network, freshIps, port)
+}
+
+func sameStrings(a []string, b []string) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i] != b[i] {
+            return false
+        }
+    }
+    return true
+}
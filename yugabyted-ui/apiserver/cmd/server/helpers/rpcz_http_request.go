@@ -0,0 +1,70 @@
+package helpers
+
+import (
+    "encoding/json"
+    "io/ioutil"
+)
+
+type RpczRemoteMethod struct {
+    ServiceName string `json:"service_name"`
+    MethodName string `json:"method_name"`
+}
+
+type RpczRequestHeader struct {
+    CallId int64 `json:"call_id"`
+    RemoteMethod RpczRemoteMethod `json:"remote_method"`
+    TimeoutMillis int64 `json:"timeout_millis"`
+}
+
+type RpczCallInProgress struct {
+    Header RpczRequestHeader `json:"header"`
+    ElapsedMillis int64 `json:"elapsed_millis"`
+    State string `json:"state"`
+}
+
+type RpczConnection struct {
+    RemoteIp string `json:"remote_ip"`
+    State string `json:"state"`
+    CallsInFlight []RpczCallInProgress `json:"calls_in_flight"`
+}
+
+type Rpcz struct {
+    InboundConnections []RpczConnection `json:"inbound_connections"`
+    OutboundConnections []RpczConnection `json:"outbound_connections"`
+}
+
+type RpczFuture struct {
+    Rpcz Rpcz
+    Error error
+}
+
+// Gets the calls in progress on the connections of a master or tserver
+func GetRpczFuture(hostName string, isMaster bool, future chan RpczFuture) {
+    rpcz := RpczFuture{
+        Rpcz: Rpcz{},
+        Error: nil,
+    }
+    port := GetConfig().Upstream.TserverHttpPort
+    if isMaster {
+        port = GetConfig().Upstream.MasterHttpPort
+    }
+    url := GetHttpUrl(hostName, port, "/rpcz")
+    httpClient := NewUpstreamHttpClient(url)
+    resp, err := httpClient.Get(url)
+    if err != nil {
+        rpcz.Error = NewNodeRequestError(url, err)
+        future <- rpcz
+        return
+    }
+    defer resp.Body.Close()
+    body, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        rpcz.Error = NewNodeRequestError(url, err)
+        future <- rpcz
+        return
+    }
+    if err := json.Unmarshal([]byte(body), &rpcz.Rpcz); err != nil {
+        rpcz.Error = NewNodeParseError(url, err)
+    }
+    future <- rpcz
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "net/http"
+)
+
+// An entity of a master or tserver that metrics are reported for, e.g. the server, a table or
+// a tablet
+type ServerMetricsEntity struct {
+    Type string `json:"type"`
+    Id string `json:"id"`
+    Attributes map[string]string `json:"attributes"`
+    // Counters and gauges have a value, histograms have a total_count, total_sum and
+    // percentiles. Kept as generic maps since the layout depends on the kind of metric.
+    Metrics []map[string]interface{} `json:"metrics"`
+}
+
+type ServerMetricsFuture struct {
+    Entities []ServerMetricsEntity
+    Error error
+}
+
+// Gets the metrics of a master or tserver from its JSON metrics endpoint
+func GetServerMetricsFuture(hostName string, isMaster bool, future chan ServerMetricsFuture) {
+    serverMetrics := ServerMetricsFuture{
+        Entities: []ServerMetricsEntity{},
+        Error: nil,
+    }
+    port := GetConfig().Upstream.TserverHttpPort
+    if isMaster {
+        port = GetConfig().Upstream.MasterHttpPort
+    }
+    url := GetHttpUrl(hostName, port, "/metrics")
+    httpClient := NewUpstreamHttpClient(url)
+    resp, err := httpClient.Get(url)
+    if err != nil {
+        serverMetrics.Error = NewNodeRequestError(url, err)
+        future <- serverMetrics
+        return
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        serverMetrics.Error = NewNodeStatusError(url, resp.Status)
+        future <- serverMetrics
+        return
+    }
+    body, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        serverMetrics.Error = NewNodeRequestError(url, err)
+        future <- serverMetrics
+        return
+    }
+    if err := json.Unmarshal([]byte(body), &serverMetrics.Entities); err != nil {
+        serverMetrics.Error = NewNodeParseError(url, err)
+    }
+    future <- serverMetrics
+}
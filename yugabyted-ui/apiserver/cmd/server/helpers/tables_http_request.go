@@ -2,23 +2,26 @@ package helpers
 
 import (
     "errors"
-    "fmt"
     "io/ioutil"
-    "net/http"
     "regexp"
     "strconv"
-    "time"
 )
 
 type Table struct {
+    Uuid string
     Keyspace string
     Name string
     SizeBytes int64
     IsYsql bool
+    // OID of the table in its database, empty for YCQL tables
+    YsqlOid string
+    IsIndex bool
 }
 
 type TablesFuture struct {
     Tables []Table
+    // Names of YSQL databases that have a colocation parent table
+    ColocatedKeyspaces map[string]bool
     Error error
 }
 
@@ -77,51 +80,80 @@ func parseTablesFromHtml(body string) ([]Table, error) {
     userTableRowMatches := rowRegex.FindAllString(userTablesHtml, -1)
     indexTableRowMatches := rowRegex.FindAllString(indexTablesHtml, -1)
     dataRegex, err := regexp.Compile(`<td>(.*?)</td><td><a.*?>(.*?)</a></td><td>.*?</td>`+
-        `<td>.*?</td><td>.*?</td><td>(.*?)</td>(.*?Total:\s*(.*?)<li>)?`)
+        `<td>.*?</td><td>(.*?)</td><td>(.*?)</td>(.*?Total:\s*(.*?)<li>)?`)
     if err != nil {
         return tables, err
     }
-    // For each match, group 1 is keyspace, group 2 is table name,
-    // group 3 is the YSQL OID (to distinguish between YSQL and YCQL tables) and
-    // group 5 is total size as a string
-    for _, row := range append(userTableRowMatches, indexTableRowMatches...) {
+    // For each match, group 1 is keyspace, group 2 is table name, group 3 is the table UUID,
+    // group 4 is the YSQL OID (to distinguish between YSQL and YCQL tables) and
+    // group 6 is total size as a string
+    for index, row := range append(userTableRowMatches, indexTableRowMatches...) {
         data := dataRegex.FindStringSubmatch(row)
-        sizeBytes, err := getBytesFromString(data[5])
+        sizeBytes, err := getBytesFromString(data[6])
         if err != nil {
             return tables, err
         }
         tables = append(tables, Table{
+            Uuid: data[3],
             Keyspace: data[1],
             Name: data[2],
             SizeBytes: sizeBytes,
-            IsYsql: data[3] != "",
+            IsYsql: data[4] != "",
+            YsqlOid: data[4],
+            IsIndex: index >= len(userTableRowMatches),
         })
     }
     return tables, nil
 }
 
+// Colocated databases are listed in the Parent tables section of the /tables page
+func parseColocatedKeyspacesFromHtml(body string) (map[string]bool, error) {
+    keyspaces := map[string]bool{}
+    parentTablesRegex, err := regexp.Compile(`(?ms)Parent tables</h2></div>.*?</div>`)
+    if err != nil {
+        return keyspaces, err
+    }
+    rowRegex, err := regexp.Compile(`<tr><td>(.*?)</td>`)
+    if err != nil {
+        return keyspaces, err
+    }
+    parentTablesHtml := parentTablesRegex.FindString(body)
+    for _, row := range rowRegex.FindAllStringSubmatch(parentTablesHtml, -1) {
+        keyspaces[row[1]] = true
+    }
+    return keyspaces, nil
+}
+
 func GetTablesFuture(nodeHost string, future chan TablesFuture) {
     tables := TablesFuture{
         Tables: []Table{},
+        ColocatedKeyspaces: map[string]bool{},
         Error: nil,
     }
-    httpClient := &http.Client{
-        Timeout: time.Second * 10,
-    }
-    url := fmt.Sprintf("http://%s:7000/tables", nodeHost)
+    url := GetHttpUrl(nodeHost, GetConfig().Upstream.MasterHttpPort, "/tables")
+    httpClient := NewUpstreamHttpClient(url)
     resp, err := httpClient.Get(url)
     if err != nil {
-        tables.Error = err
+        tables.Error = NewNodeRequestError(url, err)
         future <- tables
         return
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        tables.Error = err
+        tables.Error = NewNodeRequestError(url, err)
         future <- tables
         return
     }
-    tables.Tables, tables.Error = parseTablesFromHtml(string(body))
+    tables.Tables, err = parseTablesFromHtml(string(body))
+    if err != nil {
+        tables.Error = NewNodeParseError(url, err)
+        future <- tables
+        return
+    }
+    tables.ColocatedKeyspaces, err = parseColocatedKeyspacesFromHtml(string(body))
+    if err != nil {
+        tables.Error = NewNodeParseError(url, err)
+    }
     future <- tables
 }
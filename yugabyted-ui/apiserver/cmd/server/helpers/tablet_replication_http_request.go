@@ -2,10 +2,7 @@ package helpers
 
 import (
     "encoding/json"
-    "fmt"
     "io/ioutil"
-    "net/http"
-    "time"
 )
 
 type TabletReplicationInfo struct {
@@ -23,24 +20,24 @@ func GetTabletReplicationFuture(nodeHost string, future chan TabletReplicationFu
         LeaderlessTablets: []TabletReplicationInfo{},
         Error: nil,
     }
-    httpClient := &http.Client{
-        Timeout: time.Second * 10,
-    }
-    url := fmt.Sprintf("http://%s:7000/api/v1/tablet-replication", nodeHost)
+    url := GetHttpUrl(nodeHost, GetConfig().Upstream.MasterHttpPort,
+        "/api/v1/tablet-replication")
+    httpClient := NewUpstreamHttpClient(url)
     resp, err := httpClient.Get(url)
     if err != nil {
-        leaderlessTablets.Error = err
+        leaderlessTablets.Error = NewNodeRequestError(url, err)
         future <- leaderlessTablets
         return
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        leaderlessTablets.Error = err
+        leaderlessTablets.Error = NewNodeRequestError(url, err)
         future <- leaderlessTablets
         return
     }
-    err = json.Unmarshal([]byte(body), &leaderlessTablets.LeaderlessTablets)
-    leaderlessTablets.Error = err
+    if err := json.Unmarshal([]byte(body), &leaderlessTablets.LeaderlessTablets); err != nil {
+        leaderlessTablets.Error = NewNodeParseError(url, err)
+    }
     future <- leaderlessTablets
 }
@@ -1,13 +1,13 @@
 package helpers
 
 import (
+        "context"
         "encoding/json"
-        "errors"
         "fmt"
         "io/ioutil"
         "net"
-        "net/http"
         "regexp"
+        "strings"
         "time"
 )
 
@@ -52,37 +52,37 @@ func GetTabletServersFuture(nodeHost string, future chan TabletServersFuture) {
                 Tablets: map[string]map[string]TabletServer{},
                 Error:   nil,
         }
-        httpClient := &http.Client{
-                Timeout: time.Second * 10,
-        }
-        url := fmt.Sprintf("http://%s:7000/api/v1/tablet-servers", nodeHost)
+        url := GetHttpUrl(nodeHost, GetConfig().Upstream.MasterHttpPort,
+                "/api/v1/tablet-servers")
+        httpClient := NewUpstreamHttpClient(url)
         resp, err := httpClient.Get(url)
         if err != nil {
-                tabletServers.Error = err
+                tabletServers.Error = NewNodeRequestError(url, err)
                 future <- tabletServers
                 return
         }
         defer resp.Body.Close()
         body, err := ioutil.ReadAll(resp.Body)
         if err != nil {
-                tabletServers.Error = err
+                tabletServers.Error = NewNodeRequestError(url, err)
                 future <- tabletServers
                 return
         }
         var result map[string]interface{}
         err = json.Unmarshal([]byte(body), &result)
         if err != nil {
-                tabletServers.Error = err
+                tabletServers.Error = NewNodeParseError(url, err)
                 future <- tabletServers
                 return
         }
         if val, ok := result["error"]; ok {
-                tabletServers.Error = errors.New(val.(string))
+                tabletServers.Error = NewNodeReportedError(url, fmt.Sprint(val))
                 future <- tabletServers
                 return
         }
-        err = json.Unmarshal([]byte(body), &tabletServers.Tablets)
-        tabletServers.Error = err
+        if err := json.Unmarshal([]byte(body), &tabletServers.Tablets); err != nil {
+                tabletServers.Error = NewNodeParseError(url, err)
+        }
         future <- tabletServers
 }
 
@@ -91,7 +91,7 @@ func GetNodesList(tablets TabletServersFuture) []string {
         hostNames := []string{}
         for _, obj := range tablets.Tablets {
                 for hostport := range obj {
-                        host, _, err := net.SplitHostPort(hostport)
+                        host, err := GetHostFromAddress(hostport)
                         if err == nil {
                                 hostNames = append(hostNames, host)
                         }
@@ -100,22 +100,67 @@ func GetNodesList(tablets TabletServersFuture) []string {
         return hostNames
 }
 
+// Map between the normalized hosts of the tservers and their uuids
+type HostToUuidMap map[string]string
+
+// How long resolving a host may take when looking up its uuid
+const HOST_TO_UUID_LOOKUP_TIMEOUT = 2 * time.Second
+
+// Gets the uuid of the tserver on the host. Hosts are compared in their normalized form first.
+// Otherwise the tserver may have registered under another name for the host, e.g. an FQDN or
+// IP where the host is a short name, so hosts resolving to a common IP match, and so do
+// hostnames whose short names are equal if only one tserver has that short name.
+func (hostToUuidMap HostToUuidMap) Get(host string) (string, bool) {
+        host = NormalizeHost(host)
+        if uuid, ok := hostToUuidMap[host]; ok {
+                return uuid, true
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), HOST_TO_UUID_LOOKUP_TIMEOUT)
+        defer cancel()
+        if hostIps, err := NodeResolver.Lookup(ctx, host); err == nil {
+                for tserverHost, uuid := range hostToUuidMap {
+                        tserverIps, err := NodeResolver.Lookup(ctx, tserverHost)
+                        if err != nil {
+                                continue
+                        }
+                        for _, hostIp := range hostIps {
+                                for _, tserverIp := range tserverIps {
+                                        if net.ParseIP(hostIp).Equal(net.ParseIP(tserverIp)) {
+                                                return uuid, true
+                                        }
+                                }
+                        }
+                }
+        }
+        if net.ParseIP(host) != nil {
+                return "", false
+        }
+        shortName := strings.Split(host, ".")[0]
+        matchedUuid := ""
+        matches := 0
+        for tserverHost, uuid := range hostToUuidMap {
+                if net.ParseIP(tserverHost) == nil && strings.Split(tserverHost, ".")[0] == shortName {
+                        matchedUuid = uuid
+                        matches++
+                }
+        }
+        return matchedUuid, matches == 1
+}
+
 // Helper for getting a map between hostnames and uuids for tservers
 // For now, we hit the /tablet-servers endpoint and parse the html
-func GetHostToUuidMap(nodeHost string) (map[string]string, error) {
-        hostToUuidMap := map[string]string{}
-        httpClient := &http.Client{
-                Timeout: time.Second * 10,
-        }
-        url := fmt.Sprintf("http://%s:7000/tablet-servers", HOST)
+func GetHostToUuidMap(nodeHost string) (HostToUuidMap, error) {
+        hostToUuidMap := HostToUuidMap{}
+        url := GetHttpUrl(HOST, GetConfig().Upstream.MasterHttpPort, "/tablet-servers")
+        httpClient := NewUpstreamHttpClient(url)
         resp, err := httpClient.Get(url)
         if err != nil {
-                return hostToUuidMap, err
+                return hostToUuidMap, NewNodeRequestError(url, err)
         }
         defer resp.Body.Close()
         body, err := ioutil.ReadAll(resp.Body)
         if err != nil {
-                return hostToUuidMap, err
+                return hostToUuidMap, NewNodeRequestError(url, err)
         }
         // Now we parse the html to get the hostnames and uuids
         // This regex will not work if the layout of the page changes. In the future, it would be
@@ -126,9 +171,9 @@ func GetHostToUuidMap(nodeHost string) (map[string]string, error) {
         }
         matches := regex.FindAllSubmatch(body, -1)
         for _, v := range matches {
-                host, _, err := net.SplitHostPort(string(v[1]))
+                host, err := GetHostFromAddress(string(v[1]))
                 if err != nil {
-                        return hostToUuidMap, err
+                        return hostToUuidMap, NewNodeParseError(url, err)
                 }
                 hostToUuidMap[host] = string(v[2])
         }
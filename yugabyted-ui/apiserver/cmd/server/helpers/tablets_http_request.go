@@ -1,18 +1,18 @@
 package helpers
 
 import (
-    "fmt"
+    "html"
     "io/ioutil"
-    "net/http"
     "regexp"
     "strings"
-    "time"
 )
 
 type TabletInfo struct {
     Namespace  string
     TableName  string
     TableUuid  string
+    // Key range of the tablet, e.g. hash_split: [0x0000, 0x5555)
+    Partition  string
     State      string
     HasLeader  bool
 }
@@ -52,6 +52,7 @@ func parseTabletsFromHtml(body string) (map[string]TabletInfo, error) {
         tableName := row[2]
         tableUuid := row[3]
         tabletId := linkRegex.FindStringSubmatch(row[4])[1]
+        partition := html.UnescapeString(row[5])
         state := row[6]
         raftConfig := row[10]
         hasLeader := strings.Contains(raftConfig, "LEADER")
@@ -59,6 +60,7 @@ func parseTabletsFromHtml(body string) (map[string]TabletInfo, error) {
             Namespace: namespace,
             TableName: tableName,
             TableUuid: tableUuid,
+            Partition: partition,
             State: state,
             HasLeader: hasLeader,
         }
@@ -71,23 +73,24 @@ func GetTabletsFuture(nodeHost string, future chan TabletsFuture) {
         Tablets: map[string]TabletInfo{},
         Error: nil,
     }
-    httpClient := &http.Client{
-        Timeout: time.Second * 10,
-    }
-    url := fmt.Sprintf("http://%s:9000/tablets", nodeHost)
+    url := GetHttpUrl(nodeHost, GetConfig().Upstream.TserverHttpPort, "/tablets")
+    httpClient := NewUpstreamHttpClient(url)
     resp, err := httpClient.Get(url)
     if err != nil {
-        tablets.Error = err
+        tablets.Error = NewNodeRequestError(url, err)
         future <- tablets
         return
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        tablets.Error = err
+        tablets.Error = NewNodeRequestError(url, err)
         future <- tablets
         return
     }
-    tablets.Tablets, tablets.Error = parseTabletsFromHtml(string(body))
+    tablets.Tablets, err = parseTabletsFromHtml(string(body))
+    if err != nil {
+        tablets.Error = NewNodeParseError(url, err)
+    }
     future <- tablets
 }
@@ -0,0 +1,97 @@
+package helpers
+
+import (
+    "html"
+    "io/ioutil"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+var THREADZ_ROW_REGEX = regexp.MustCompile(`(?ms)<tr>(.*?)</tr>`)
+var THREADZ_CELL_REGEX = regexp.MustCompile(`(?ms)<td([^>]*)>(.*?)</td>`)
+var THREADZ_ROWSPAN_REGEX = regexp.MustCompile(`rowspan=["']?([0-9]+)`)
+var THREADZ_STACK_REGEX = regexp.MustCompile(`(?ms)<pre>(.*?)</pre>`)
+
+// Line that threadz appends to the stack of a group of threads
+var THREADZ_TOTAL_REGEX = regexp.MustCompile(`(?m)^\s*Total number of threads: [0-9]+\s*$`)
+
+type ThreadzThread struct {
+    Name string
+    // Empty if the stack of the thread was not captured
+    Stack string
+}
+
+type ThreadzFuture struct {
+    Threads []ThreadzThread
+    Error error
+}
+
+// TODO: replace this with a call to a json endpoint so we don't have to parse html
+// Gets the threads of a master or tserver with their stacks. Threads with the same stack share
+// a cell that spans their rows.
+func GetThreadzFuture(hostName string, isMaster bool, future chan ThreadzFuture) {
+    threadz := ThreadzFuture{
+        Threads: []ThreadzThread{},
+        Error: nil,
+    }
+    port := GetConfig().Upstream.TserverHttpPort
+    if isMaster {
+        port = GetConfig().Upstream.MasterHttpPort
+    }
+    url := GetHttpUrl(hostName, port, "/threadz?group=all")
+    httpClient := NewUpstreamHttpClient(url)
+    resp, err := httpClient.Get(url)
+    if err != nil {
+        threadz.Error = NewNodeRequestError(url, err)
+        future <- threadz
+        return
+    }
+    defer resp.Body.Close()
+    body, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        threadz.Error = NewNodeRequestError(url, err)
+        future <- threadz
+        return
+    }
+    threadz.Threads = parseThreadz(string(body))
+    future <- threadz
+}
+
+func parseThreadz(page string) []ThreadzThread {
+    threads := []ThreadzThread{}
+    stack := ""
+    stackRows := 0
+    for _, row := range THREADZ_ROW_REGEX.FindAllStringSubmatch(page, -1) {
+        // The header row has no td cells
+        cells := THREADZ_CELL_REGEX.FindAllStringSubmatch(row[1], -1)
+        if len(cells) == 0 {
+            continue
+        }
+        if stackRows > 0 {
+            stackRows--
+        } else {
+            stack = ""
+        }
+        for _, cell := range cells[1:] {
+            match := THREADZ_STACK_REGEX.FindStringSubmatch(cell[2])
+            if match == nil {
+                continue
+            }
+            // Only whole lines are trimmed, to keep the indentation of the first frame
+            stack = strings.TrimRight(strings.TrimLeft(THREADZ_TOTAL_REGEX.ReplaceAllString(
+                html.UnescapeString(match[1]), ""), "\r\n"), " \t\r\n")
+            stackRows = 0
+            if rowspan := THREADZ_ROWSPAN_REGEX.FindStringSubmatch(cell[1]); rowspan != nil {
+                rows, _ := strconv.Atoi(rowspan[1])
+                // The rows after this one that share the stack
+                stackRows = rows - 1
+            }
+        }
+        threads = append(threads, ThreadzThread{
+            Name: strings.TrimSpace(html.UnescapeString(cells[0][2])),
+            Stack: stack,
+        })
+    }
+    return threads
+}
@@ -0,0 +1,56 @@
+package helpers
+
+import (
+    "bufio"
+    "net/http"
+)
+
+type TserverMetricsFuture struct {
+    // Samples of each metric by metric name
+    Metrics map[string][]NodeExporterSample
+    Error error
+}
+
+// Gets the metrics of a tserver from its Prometheus endpoint, which uses the same exposition
+// format as node_exporter
+func GetTserverMetricsFuture(nodeHost string, future chan TserverMetricsFuture) {
+    getPrometheusMetricsFuture(nodeHost, GetConfig().Upstream.TserverHttpPort, future)
+}
+
+// Gets the metrics of the YSQL or YCQL server of a tserver, such as its statement counters,
+// which they report on their own web endpoints
+func GetApiServerMetricsFuture(nodeHost string, isYsql bool, future chan TserverMetricsFuture) {
+    port := GetConfig().Upstream.YcqlHttpPort
+    if isYsql {
+        port = GetConfig().Upstream.YsqlHttpPort
+    }
+    getPrometheusMetricsFuture(nodeHost, port, future)
+}
+
+func getPrometheusMetricsFuture(nodeHost string, port int, future chan TserverMetricsFuture) {
+    tserverMetrics := TserverMetricsFuture{
+        Metrics: map[string][]NodeExporterSample{},
+        Error: nil,
+    }
+    url := GetHttpUrl(nodeHost, port, "/prometheus-metrics")
+    httpClient := NewUpstreamHttpClient(url)
+    resp, err := httpClient.Get(url)
+    if err != nil {
+        tserverMetrics.Error = NewNodeRequestError(url, err)
+        future <- tserverMetrics
+        return
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        tserverMetrics.Error = NewNodeStatusError(url, resp.Status)
+        future <- tserverMetrics
+        return
+    }
+    scanner := bufio.NewScanner(resp.Body)
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+    tserverMetrics.Metrics = parseNodeExporterMetrics(scanner)
+    if err := scanner.Err(); err != nil {
+        tserverMetrics.Error = NewNodeRequestError(url, err)
+    }
+    future <- tserverMetrics
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "net/http"
+)
+
+type VarzFlag struct {
+    Name string `json:"name"`
+    Value string `json:"value"`
+    // Default, Custom, NodeInfo or Auto
+    Type string `json:"type"`
+}
+
+type Varz struct {
+    Flags []VarzFlag `json:"flags"`
+}
+
+type VarzFuture struct {
+    Varz Varz
+    Error error
+}
+
+// Gets the flags of a master or tserver along with where their values come from
+func GetVarzFuture(hostName string, isMaster bool, future chan VarzFuture) {
+    varz := VarzFuture{
+        Varz: Varz{Flags: []VarzFlag{}},
+        Error: nil,
+    }
+    port := GetConfig().Upstream.TserverHttpPort
+    if isMaster {
+        port = GetConfig().Upstream.MasterHttpPort
+    }
+    url := GetHttpUrl(hostName, port, "/api/v1/varz")
+    httpClient := NewUpstreamHttpClient(url)
+    resp, err := httpClient.Get(url)
+    if err != nil {
+        varz.Error = NewNodeRequestError(url, err)
+        future <- varz
+        return
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        varz.Error = NewNodeStatusError(url, resp.Status)
+        future <- varz
+        return
+    }
+    body, err := ioutil.ReadAll(resp.Body)
+    if err != nil {
+        varz.Error = NewNodeRequestError(url, err)
+        future <- varz
+        return
+    }
+    if err := json.Unmarshal([]byte(body), &varz.Varz); err != nil {
+        varz.Error = NewNodeParseError(url, err)
+    }
+    future <- varz
+}
@@ -2,10 +2,7 @@ package helpers
 
 import (
     "encoding/json"
-    "fmt"
     "io/ioutil"
-    "net/http"
-    "time"
 )
 
 type VersionInfoStruct struct {
@@ -26,27 +23,36 @@ type VersionInfoFuture struct {
 }
 
 func GetVersionFuture(hostName string, future chan VersionInfoFuture) {
+    GetServerVersionFuture(hostName, true, future)
+}
+
+// Gets the version and build of the master or tserver of a node
+func GetServerVersionFuture(hostName string, isMaster bool, future chan VersionInfoFuture) {
     versionInfo := VersionInfoFuture{
         VersionInfo: VersionInfoStruct{},
         Error: nil,
     }
-    httpClient := &http.Client{
-        Timeout: time.Second * 10,
+    port := GetConfig().Upstream.TserverHttpPort
+    if isMaster {
+        port = GetConfig().Upstream.MasterHttpPort
     }
-    url := fmt.Sprintf("http://%s:7000/api/v1/version", hostName)
+    url := GetHttpUrl(hostName, port, "/api/v1/version")
+    httpClient := NewUpstreamHttpClient(url)
     resp, err := httpClient.Get(url)
     if err != nil {
-        versionInfo.Error = err
+        versionInfo.Error = NewNodeRequestError(url, err)
         future <- versionInfo
         return
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        versionInfo.Error = err
+        versionInfo.Error = NewNodeRequestError(url, err)
         future <- versionInfo
         return
     }
-    versionInfo.Error = json.Unmarshal([]byte(body), &versionInfo.VersionInfo)
+    if err := json.Unmarshal([]byte(body), &versionInfo.VersionInfo); err != nil {
+        versionInfo.Error = NewNodeParseError(url, err)
+    }
     future <- versionInfo
 }
@@ -0,0 +1,219 @@
+package helpers
+
+import (
+    "context"
+    "fmt"
+    "io/ioutil"
+    "net"
+    "os"
+    "os/exec"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+var LOAD_MOVE_PERCENT_REGEX = regexp.MustCompile(`Percent complete = ([0-9.]+)`)
+
+func runCommand(timeout time.Duration, name string, args ...string) (string, error) {
+    return runCommandWithEnv(timeout, nil, name, args...)
+}
+
+// Runs a command with extra environment variables, which are kept out of error messages
+func runCommandWithEnv(timeout time.Duration, env []string, name string,
+    args ...string) (string, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), timeout)
+    defer cancel()
+    cmd := exec.CommandContext(ctx, name, args...)
+    if len(env) > 0 {
+        cmd.Env = append(os.Environ(), env...)
+    }
+    output, err := cmd.CombinedOutput()
+    if err != nil {
+        return string(output), fmt.Errorf("%s %s failed: %s: %s", name,
+            strings.Join(args, " "), err.Error(), strings.TrimSpace(string(output)))
+    }
+    return string(output), nil
+}
+
+// Runs yugabyted with the given arguments
+func RunYugabyted(args ...string) (string, error) {
+    return runCommand(GetConfig().Timeouts.YugabytedCommand,
+        GetConfig().Tools.YugabytedPath, args...)
+}
+
+// Gets the rpc addresses of the masters, as yb-admin expects them
+func GetMasterAddresses() (string, error) {
+    mastersFuture := make(chan MastersFuture)
+    go GetMastersFuture(HOST, mastersFuture)
+    masters := <-mastersFuture
+    if masters.Error != nil {
+        return "", masters.Error
+    }
+    addresses := []string{}
+    for _, master := range masters.Masters {
+        for _, address := range master.Registration.PrivateRpcAddresses {
+            addresses = append(addresses,
+                net.JoinHostPort(address.Host, strconv.Itoa(int(address.Port))))
+        }
+    }
+    if len(addresses) == 0 {
+        return "", fmt.Errorf("no masters found")
+    }
+    return strings.Join(addresses, ","), nil
+}
+
+// Runs yb-admin against the masters of the cluster
+func RunYbAdmin(args ...string) (string, error) {
+    return RunYbAdminWithTimeout(GetConfig().Timeouts.YbAdminCommand, args...)
+}
+
+// Runs yb-admin with another timeout than timeouts.yb_admin_command, for commands that wait
+// for long operations such as compactions
+func RunYbAdminWithTimeout(timeout time.Duration, args ...string) (string, error) {
+    masterAddresses, err := GetMasterAddresses()
+    if err != nil {
+        return "", err
+    }
+    return runCommand(timeout, GetConfig().Tools.YbAdminPath,
+        append([]string{"-master_addresses", masterAddresses}, args...)...)
+}
+
+// Runs yb-ts-cli against a master or tserver, which it reaches on its rpc port
+func RunYbTsCli(host string, isMaster bool, args ...string) (string, error) {
+    return RunYbTsCliWithTimeout(GetConfig().Timeouts.YbTsCliCommand, host, isMaster, args...)
+}
+
+// Runs yb-ts-cli with another timeout than timeouts.yb_ts_cli_command, for commands that wait
+// for long operations such as compactions
+func RunYbTsCliWithTimeout(timeout time.Duration, host string, isMaster bool,
+    args ...string) (string, error) {
+    port := GetConfig().Upstream.TserverRpcPort
+    if isMaster {
+        port = GetConfig().Upstream.MasterRpcPort
+    }
+    return runCommand(timeout, GetConfig().Tools.YbTsCliPath,
+        append([]string{"--server_address", net.JoinHostPort(host, strconv.Itoa(port))},
+            args...)...)
+}
+
+// Changes a runtime gflag of a master or tserver. The change is lost when the process restarts.
+func SetGFlag(host string, isMaster bool, name string, value string) error {
+    _, err := RunYbTsCli(host, isMaster, "set_flag", name, value)
+    return err
+}
+
+// Gets the environment of the YSQL tools, which carries the password so that it does not show
+// in the process list
+func getYsqlToolEnv() []string {
+    env := []string{"PGPASSWORD=" + DbPassword}
+    if Secure {
+        env = append(env, "PGSSLMODE="+SslMode)
+        if SslRootCert != "" {
+            env = append(env, "PGSSLROOTCERT="+SslRootCert)
+        }
+    }
+    return env
+}
+
+// Runs ysql_dump against a database, connecting like the server does
+func RunYsqlDump(dbName string, args ...string) (string, error) {
+    return runCommandWithEnv(GetConfig().Timeouts.YsqlDumpCommand, getYsqlToolEnv(),
+        GetConfig().Tools.YsqlDumpPath, append([]string{"--host", HOST,
+            "--port", strconv.Itoa(PORT), "--username", DbYsqlUser, "--dbname", dbName},
+            args...)...)
+}
+
+// Runs ysqlsh against a database without reading a psqlrc, connecting like the server does
+func RunYsqlsh(timeout time.Duration, dbName string, args ...string) (string, error) {
+    return runCommandWithEnv(timeout, getYsqlToolEnv(), GetConfig().Tools.YsqlshPath,
+        append([]string{"--host", HOST, "--port", strconv.Itoa(PORT), "--username",
+            DbYsqlUser, "--dbname", dbName, "--no-psqlrc"}, args...)...)
+}
+
+// Builds a ysqlsh or ycqlsh command that connects like the server does, to the given database
+// or keyspace. The credentials are kept out of the process list: ysqlsh gets the password in
+// the environment, ycqlsh in a cqlshrc file that the returned function deletes.
+func NewShellCommand(isYsql bool, database string) (*exec.Cmd, func(), error) {
+    if isYsql {
+        cmd := exec.Command(GetConfig().Tools.YsqlshPath, "--host", HOST,
+            "--port", strconv.Itoa(PORT), "--username", DbYsqlUser, "--dbname", database,
+            "--no-psqlrc")
+        cmd.Env = append(os.Environ(), "PGPASSWORD="+DbPassword)
+        if Secure {
+            cmd.Env = append(cmd.Env, "PGSSLMODE="+SslMode)
+            if SslRootCert != "" {
+                cmd.Env = append(cmd.Env, "PGSSLROOTCERT="+SslRootCert)
+            }
+        }
+        return cmd, func() {}, nil
+    }
+    cqlshrc, err := ioutil.TempFile("", "yugabyted-ui-cqlshrc")
+    if err != nil {
+        return nil, nil, err
+    }
+    cleanup := func() { os.Remove(cqlshrc.Name()) }
+    _, err = fmt.Fprintf(cqlshrc, "[authentication]\nusername = %s\npassword = %s\n",
+        DbYcqlUser, DbPassword)
+    if closeErr := cqlshrc.Close(); err == nil {
+        err = closeErr
+    }
+    if err != nil {
+        cleanup()
+        return nil, nil, err
+    }
+    // Without --tty, ycqlsh does not prompt when its input is not a terminal
+    args := []string{"--cqlshrc", cqlshrc.Name(), "--tty"}
+    if database != "" {
+        args = append(args, "--keyspace", database)
+    }
+    if Secure {
+        args = append(args, "--ssl")
+    }
+    args = append(args, HOST, strconv.Itoa(GetConfig().Upstream.YcqlPort))
+    cmd := exec.Command(GetConfig().Tools.YcqlshPath, args...)
+    // ycqlsh is written in Python, which would otherwise hold output back while it is piped
+    cmd.Env = append(os.Environ(), "PYTHONUNBUFFERED=1")
+    if Secure && SslRootCert != "" {
+        cmd.Env = append(cmd.Env, "SSL_CERTFILE="+SslRootCert)
+    }
+    return cmd, cleanup, nil
+}
+
+// Gets how much of the data has been moved off blacklisted tservers, as a percentage
+func GetLoadMoveCompletion() (float64, error) {
+    output, err := RunYbAdmin("get_load_move_completion")
+    if err != nil {
+        return 0, err
+    }
+    match := LOAD_MOVE_PERCENT_REGEX.FindStringSubmatch(output)
+    if match == nil {
+        return 0, fmt.Errorf("unexpected get_load_move_completion output: %s", output)
+    }
+    return strconv.ParseFloat(match[1], 64)
+}
+
+// Checks whether the address resolves to this host, so that processes for it can be managed
+// locally
+func IsLocalAddress(address string) (bool, error) {
+    ips, err := net.LookupIP(address)
+    if err != nil {
+        return false, err
+    }
+    interfaceAddresses, err := net.InterfaceAddrs()
+    if err != nil {
+        return false, err
+    }
+    for _, ip := range ips {
+        // The whole loopback range is local, even if only 127.0.0.1 is configured
+        if ip.IsLoopback() {
+            return true, nil
+        }
+        for _, interfaceAddress := range interfaceAddresses {
+            if ipNet, ok := interfaceAddress.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+                return true, nil
+            }
+        }
+    }
+    return false, nil
+}
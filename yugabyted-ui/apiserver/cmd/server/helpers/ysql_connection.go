@@ -0,0 +1,33 @@
+package helpers
+
+import (
+    "net"
+    "net/url"
+    "strconv"
+)
+
+// Builds the pgx connection url for the given YSQL database on the local node
+func GetYsqlConnectionUrl(dbName string) string {
+    return GetYsqlConnectionUrlForHost(HOST, dbName)
+}
+
+// Builds the pgx connection url for the given YSQL database on the node with the given host.
+// The credentials and the database name are escaped, so that a name cannot add parameters
+// to the url, e.g. another host to connect to.
+func GetYsqlConnectionUrlForHost(host string, dbName string) string {
+    connectionUrl := url.URL{
+        Scheme: "postgres",
+        User: url.UserPassword(DbYsqlUser, DbPassword),
+        Host: net.JoinHostPort(host, strconv.Itoa(PORT)),
+        Path: "/" + dbName,
+    }
+    if Secure {
+        secureOptions := url.Values{}
+        secureOptions.Set("sslmode", SslMode)
+        if SslRootCert != "" {
+            secureOptions.Set("sslrootcert", SslRootCert)
+        }
+        connectionUrl.RawQuery = secureOptions.Encode()
+    }
+    return connectionUrl.String()
+}
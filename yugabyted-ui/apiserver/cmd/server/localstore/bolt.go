@@ -0,0 +1,148 @@
+package localstore
+
+import (
+    "os"
+    "path/filepath"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+)
+
+// How long opening the file waits for another server that has it open
+const BOLT_OPEN_TIMEOUT = 5 * time.Second
+
+// Keeps the buckets in a bolt file. Every write is a transaction of its own, synced to disk
+// before it returns.
+type boltStore struct {
+    db *bolt.DB
+}
+
+func openBoltStore(path string) (*boltStore, error) {
+    if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        return nil, err
+    }
+    db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: BOLT_OPEN_TIMEOUT})
+    if err != nil {
+        return nil, err
+    }
+    return &boltStore{db}, nil
+}
+
+func (store *boltStore) Get(bucket string, key string) ([]byte, bool, error) {
+    var value []byte
+    err := store.db.View(func(tx *bolt.Tx) error {
+        if b := tx.Bucket([]byte(bucket)); b != nil {
+            // Values are only valid during the transaction
+            if found := b.Get([]byte(key)); found != nil {
+                value = copyBytes(found)
+            }
+        }
+        return nil
+    })
+    return value, value != nil, err
+}
+
+func (store *boltStore) Put(bucket string, key string, value []byte) error {
+    return store.db.Update(func(tx *bolt.Tx) error {
+        b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+        if err != nil {
+            return err
+        }
+        return b.Put([]byte(key), value)
+    })
+}
+
+func (store *boltStore) Delete(bucket string, key string) error {
+    return store.db.Update(func(tx *bolt.Tx) error {
+        if b := tx.Bucket([]byte(bucket)); b != nil {
+            return b.Delete([]byte(key))
+        }
+        return nil
+    })
+}
+
+func (store *boltStore) List(bucket string) ([]Entry, error) {
+    entries := []Entry{}
+    err := store.db.View(func(tx *bolt.Tx) error {
+        b := tx.Bucket([]byte(bucket))
+        if b == nil {
+            return nil
+        }
+        return b.ForEach(func(key []byte, value []byte) error {
+            entries = append(entries, Entry{string(key), copyBytes(value)})
+            return nil
+        })
+    })
+    return entries, err
+}
+
+func (store *boltStore) Trim(bucket string, max int) error {
+    return store.db.Update(func(tx *bolt.Tx) error {
+        b := tx.Bucket([]byte(bucket))
+        if b == nil {
+            return nil
+        }
+        excess := b.Stats().KeyN - max
+        // Keys are collected first, as deleting moves the cursor
+        keys := [][]byte{}
+        cursor := b.Cursor()
+        for key, _ := cursor.First(); key != nil && len(keys) < excess; key, _ = cursor.Next() {
+            keys = append(keys, copyBytes(key))
+        }
+        for _, key := range keys {
+            if err := b.Delete(key); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (store *boltStore) Dump() (map[string]map[string][]byte, error) {
+    buckets := map[string]map[string][]byte{}
+    err := store.db.View(func(tx *bolt.Tx) error {
+        return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+            bucket := map[string][]byte{}
+            buckets[string(name)] = bucket
+            return b.ForEach(func(key []byte, value []byte) error {
+                bucket[string(key)] = copyBytes(value)
+                return nil
+            })
+        })
+    })
+    return buckets, err
+}
+
+func (store *boltStore) Load(buckets map[string]map[string][]byte) error {
+    return store.db.Update(func(tx *bolt.Tx) error {
+        names := [][]byte{}
+        err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+            names = append(names, copyBytes(name))
+            return nil
+        })
+        if err != nil {
+            return err
+        }
+        for _, name := range names {
+            if err := tx.DeleteBucket(name); err != nil {
+                return err
+            }
+        }
+        for name, bucket := range buckets {
+            b, err := tx.CreateBucket([]byte(name))
+            if err != nil {
+                return err
+            }
+            for key, value := range bucket {
+                if err := b.Put([]byte(key), value); err != nil {
+                    return err
+                }
+            }
+        }
+        return nil
+    })
+}
+
+func (store *boltStore) Close() error {
+    return store.db.Close()
+}
@@ -0,0 +1,107 @@
+package localstore
+
+import (
+    "sort"
+    "sync"
+)
+
+// Keeps the buckets in memory, so that nothing outlives the server
+type memoryStore struct {
+    mutex sync.RWMutex
+    buckets map[string]map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+    return &memoryStore{buckets: map[string]map[string][]byte{}}
+}
+
+func copyBytes(value []byte) []byte {
+    return append([]byte{}, value...)
+}
+
+func (store *memoryStore) Get(bucket string, key string) ([]byte, bool, error) {
+    store.mutex.RLock()
+    defer store.mutex.RUnlock()
+    value, ok := store.buckets[bucket][key]
+    if !ok {
+        return nil, false, nil
+    }
+    return copyBytes(value), true, nil
+}
+
+func (store *memoryStore) Put(bucket string, key string, value []byte) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    if _, ok := store.buckets[bucket]; !ok {
+        store.buckets[bucket] = map[string][]byte{}
+    }
+    store.buckets[bucket][key] = copyBytes(value)
+    return nil
+}
+
+func (store *memoryStore) Delete(bucket string, key string) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    delete(store.buckets[bucket], key)
+    return nil
+}
+
+// Gets the keys of a bucket in order. Must be called with the mutex held.
+func (store *memoryStore) sortedKeysLocked(bucket string) []string {
+    keys := []string{}
+    for key := range store.buckets[bucket] {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func (store *memoryStore) List(bucket string) ([]Entry, error) {
+    store.mutex.RLock()
+    defer store.mutex.RUnlock()
+    entries := []Entry{}
+    for _, key := range store.sortedKeysLocked(bucket) {
+        entries = append(entries, Entry{key, copyBytes(store.buckets[bucket][key])})
+    }
+    return entries, nil
+}
+
+func (store *memoryStore) Trim(bucket string, max int) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    keys := store.sortedKeysLocked(bucket)
+    for index := 0; index < len(keys)-max; index++ {
+        delete(store.buckets[bucket], keys[index])
+    }
+    return nil
+}
+
+func (store *memoryStore) Dump() (map[string]map[string][]byte, error) {
+    store.mutex.RLock()
+    defer store.mutex.RUnlock()
+    buckets := map[string]map[string][]byte{}
+    for name, bucket := range store.buckets {
+        buckets[name] = map[string][]byte{}
+        for key, value := range bucket {
+            buckets[name][key] = copyBytes(value)
+        }
+    }
+    return buckets, nil
+}
+
+func (store *memoryStore) Load(buckets map[string]map[string][]byte) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    store.buckets = map[string]map[string][]byte{}
+    for name, bucket := range buckets {
+        store.buckets[name] = map[string][]byte{}
+        for key, value := range bucket {
+            store.buckets[name][key] = copyBytes(value)
+        }
+    }
+    return nil
+}
+
+func (store *memoryStore) Close() error {
+    return nil
+}
@@ -0,0 +1,168 @@
+package localstore
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "strconv"
+    "time"
+)
+
+// bolt keeps the state in a file, memory only until the server stops
+const BACKEND_BOLT = "bolt"
+const BACKEND_MEMORY = "memory"
+
+// The bucket the schema version of the store is kept in
+const META_BUCKET = "meta"
+const SCHEMA_VERSION_KEY = "schema_version"
+
+// Store keeps the state of the server as values under keys, grouped in buckets. Buckets are
+// created on first write, and reading a bucket that does not exist finds nothing.
+type Store interface {
+    Get(bucket string, key string) ([]byte, bool, error)
+    Put(bucket string, key string, value []byte) error
+    Delete(bucket string, key string) error
+    // Gets the entries of a bucket in key order
+    List(bucket string) ([]Entry, error)
+    // Deletes the first entries of a bucket in key order, keeping the last max ones
+    Trim(bucket string, max int) error
+    // Gets the values of every bucket, by bucket and key
+    Dump() (map[string]map[string][]byte, error)
+    // Replaces the buckets at once
+    Load(buckets map[string]map[string][]byte) error
+    Close() error
+}
+
+type Entry struct {
+    Key string
+    Value []byte
+}
+
+// Opens the store of a backend. The path is only used by the bolt backend.
+func Open(backend string, path string) (Store, error) {
+    switch backend {
+    case BACKEND_BOLT:
+        return openBoltStore(path)
+    case BACKEND_MEMORY:
+        return newMemoryStore(), nil
+    }
+    return nil, fmt.Errorf("unknown store backend %q", backend)
+}
+
+// A change to the layout of the store, e.g. moving state that was kept elsewhere into it
+type Migration struct {
+    Version int
+    Description string
+    Apply func(store Store) error
+    // Whether the migration brings in state from outside the store, e.g. files. Such
+    // migrations are only recorded as applied when a backup is restored, as the backup
+    // already holds the state the store had.
+    External bool
+}
+
+// Gets the version of the latest migration applied to the store, 0 if none was
+func GetSchemaVersion(store Store) (int, error) {
+    value, ok, err := store.Get(META_BUCKET, SCHEMA_VERSION_KEY)
+    if err != nil || !ok {
+        return 0, err
+    }
+    version, err := strconv.Atoi(string(value))
+    if err != nil {
+        return 0, fmt.Errorf("invalid schema version %q", value)
+    }
+    return version, nil
+}
+
+// Applies the migrations newer than the schema version of the store in order of version,
+// recording the version after each. Returns the descriptions of the migrations applied.
+func Migrate(store Store, migrations []Migration) ([]string, error) {
+    return migrate(store, migrations, false)
+}
+
+func migrate(store Store, migrations []Migration, restoring bool) ([]string, error) {
+    applied := []string{}
+    version, err := GetSchemaVersion(store)
+    if err != nil {
+        return applied, err
+    }
+    for _, migration := range migrations {
+        if migration.Version <= version {
+            continue
+        }
+        if !migration.External || !restoring {
+            if err := migration.Apply(store); err != nil {
+                return applied, fmt.Errorf("migration %d failed: %s", migration.Version,
+                    err.Error())
+            }
+        }
+        err := store.Put(META_BUCKET, SCHEMA_VERSION_KEY,
+            []byte(strconv.Itoa(migration.Version)))
+        if err != nil {
+            return applied, err
+        }
+        version = migration.Version
+        applied = append(applied, migration.Description)
+    }
+    return applied, nil
+}
+
+// Returned by Restore, wrapped, when the backup cannot be read or is of a newer schema
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// The contents of a store as written by Backup. Values are base64 encoded.
+type backup struct {
+    SchemaVersion int `json:"schema_version"`
+    CreatedAt int64 `json:"created_at"`
+    Buckets map[string]map[string][]byte `json:"buckets"`
+}
+
+// Writes the contents of the store as JSON, which Restore reads back into a store of any
+// backend
+func Backup(store Store, writer io.Writer) error {
+    version, err := GetSchemaVersion(store)
+    if err != nil {
+        return err
+    }
+    buckets, err := store.Dump()
+    if err != nil {
+        return err
+    }
+    return json.NewEncoder(writer).Encode(backup{
+        SchemaVersion: version,
+        CreatedAt: time.Now().Unix(),
+        Buckets: buckets,
+    })
+}
+
+// Replaces the contents of the store with a backup, then migrates it from the schema version
+// of the backup, skipping the external migrations. Backups of a newer schema than the
+// migrations know of are refused.
+func Restore(store Store, reader io.Reader, migrations []Migration) error {
+    restored := backup{}
+    if err := json.NewDecoder(reader).Decode(&restored); err != nil {
+        return fmt.Errorf("%w: %s", ErrInvalidBackup, err.Error())
+    }
+    latest := 0
+    if len(migrations) > 0 {
+        latest = migrations[len(migrations)-1].Version
+    }
+    if restored.SchemaVersion > latest {
+        return fmt.Errorf("%w: the backup has schema version %d, newer than %d",
+            ErrInvalidBackup, restored.SchemaVersion, latest)
+    }
+    if restored.Buckets == nil {
+        restored.Buckets = map[string]map[string][]byte{}
+    }
+    meta := restored.Buckets[META_BUCKET]
+    if meta == nil {
+        meta = map[string][]byte{}
+        restored.Buckets[META_BUCKET] = meta
+    }
+    meta[SCHEMA_VERSION_KEY] = []byte(strconv.Itoa(restored.SchemaVersion))
+    if err := store.Load(restored.Buckets); err != nil {
+        return err
+    }
+    _, err := migrate(store, migrations, true)
+    return err
+}
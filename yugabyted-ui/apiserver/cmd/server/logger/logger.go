@@ -9,6 +9,9 @@ type Logger interface {
     // Standard function for adding fields for structured logging
     With(args ...interface{}) Logger
 
+    // Changes the minimum level of the messages logged, for this logger and all derived ones
+    SetLevel(level string) error
+
     // Anything that needs to be run before the program exits
     Cleanup()
 }
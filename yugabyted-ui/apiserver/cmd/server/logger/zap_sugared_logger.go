@@ -6,17 +6,20 @@ import (
 
 type ZapSugaredLogger struct {
     logger *zap.SugaredLogger
+    // Shared with the loggers derived with With
+    level zap.AtomicLevel
 }
 
 func NewSugaredLogger() (*ZapSugaredLogger, error) {
-    zapLogger, err := zap.NewProduction()
+    config := zap.NewProductionConfig()
+    zapLogger, err := config.Build()
     if err != nil {
         return nil, err
     }
     sugaredLogger := zapLogger.Sugar().WithOptions(
         zap.AddCallerSkip(1),
     )
-    return &ZapSugaredLogger{sugaredLogger}, nil
+    return &ZapSugaredLogger{sugaredLogger, config.Level}, nil
 }
 
 func (zapLogger *ZapSugaredLogger) Debugf(format string, args ...interface{}) {
@@ -32,7 +35,11 @@ func (zapLogger *ZapSugaredLogger) Errorf(format string, args ...interface{}) {
 }
 
 func (zapLogger *ZapSugaredLogger) With(args ...interface{}) Logger {
-    return &ZapSugaredLogger{zapLogger.logger.With(args...)}
+    return &ZapSugaredLogger{zapLogger.logger.With(args...), zapLogger.level}
+}
+
+func (zapLogger *ZapSugaredLogger) SetLevel(level string) error {
+    return zapLogger.level.UnmarshalText([]byte(level))
 }
 
 func (zapLogger *ZapSugaredLogger) Cleanup() {
@@ -7,23 +7,28 @@ import (
         "apiserver/cmd/server/templates"
         "context"
         "embed"
-        "fmt"
+        "expvar"
         "io/fs"
+        "net"
         "net/http"
+        "net/http/pprof"
         "os"
+        "os/signal"
+        "runtime"
         "strconv"
+        "strings"
+        "syscall"
         "time"
 
         "html/template"
 
-        "github.com/jackc/pgx/v4"
+        "github.com/jackc/pgx/v4/pgxpool"
         "github.com/labstack/echo/v4"
         "github.com/labstack/echo/v4/middleware"
         "github.com/yugabyte/gocql"
+        "golang.org/x/net/http2"
 )
 
-const serverPortEnv string = "YUGABYTED_UI_PORT"
-
 const (
         uiDir     = "ui"
         extension = "/*.html"
@@ -34,13 +39,6 @@ var staticFiles embed.FS
 
 var templatesMap map[string]*template.Template
 
-func getEnv(key, fallback string) string {
-        if value, ok := os.LookupEnv(key); ok {
-                return value
-        }
-        return fallback
-}
-
 func LoadTemplates() error {
 
         if templatesMap == nil {
@@ -81,7 +79,9 @@ func getStaticFiles() http.FileSystem {
 func createGoCqlClient(log logger.Logger) *gocql.ClusterConfig {
 
         // Initialize gocql client
-        cluster := gocql.NewCluster(helpers.HOST)
+        // The port is given with the host, so that IPv6 literals are bracketed
+        cluster := gocql.NewCluster(net.JoinHostPort(helpers.HOST,
+                strconv.Itoa(helpers.GetConfig().Upstream.YcqlPort)))
 
         if helpers.Secure {
                 cluster.Authenticator = gocql.PasswordAuthenticator{
@@ -93,8 +93,23 @@ func createGoCqlClient(log logger.Logger) *gocql.ClusterConfig {
                 }
         }
 
-        // Use the same timeout as the Java driver.
-        cluster.Timeout = 12 * time.Second
+        ycqlConfig := helpers.GetConfig().Ycql
+        cluster.Timeout = helpers.GetConfig().Timeouts.YcqlRequest
+        cluster.ConnectTimeout = ycqlConfig.ConnectTimeout
+        cluster.NumConns = ycqlConfig.NumConns
+        // Validated with the config
+        cluster.Consistency = gocql.ParseConsistency(ycqlConfig.Consistency)
+        switch ycqlConfig.HostSelectionPolicy {
+        case helpers.YCQL_POLICY_TOKEN_AWARE:
+                fallback := gocql.RoundRobinHostPolicy()
+                if ycqlConfig.LocalDc != "" {
+                        fallback = gocql.DCAwareRoundRobinPolicy(ycqlConfig.LocalDc)
+                }
+                cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(fallback)
+        case helpers.YCQL_POLICY_DC_AWARE:
+                cluster.PoolConfig.HostSelectionPolicy =
+                        gocql.DCAwareRoundRobinPolicy(ycqlConfig.LocalDc)
+        }
 
         // Create the session.
         log.Debugf("Initializing gocql client.")
@@ -102,28 +117,167 @@ func createGoCqlClient(log logger.Logger) *gocql.ClusterConfig {
         return cluster
 }
 
-func createPgClient(log logger.Logger) *pgx.Conn {
+// Creates the pool of YSQL connections that the handlers share. Connections are opened on first
+// use, so that the server starts while YSQL is down.
+func createPgClient(log logger.Logger) *pgxpool.Pool {
 
-        var url string
+        url := helpers.GetYsqlConnectionUrl(helpers.DbName)
 
-        url = fmt.Sprintf("postgres://%s:%s@%s:%d/%s",
-                helpers.DbYsqlUser, helpers.DbPassword, helpers.HOST, helpers.PORT, helpers.DbName)
-        if helpers.Secure {
-                secureOptions := fmt.Sprintf("sslmode=%s", helpers.SslMode)
-                if helpers.SslRootCert != "" {
-                        secureOptions = fmt.Sprintf("%s&%s", secureOptions, helpers.SslRootCert)
+        log.Debugf("Initializing pgx pool.")
+        poolConfig, err := pgxpool.ParseConfig(url)
+        if err != nil {
+                log.Errorf("Error initializing the pgx pool.")
+                log.Errorf(err.Error())
+                os.Exit(1)
+        }
+        poolConfig.MaxConns = int32(helpers.GetConfig().Database.MaxConns)
+        poolConfig.LazyConnect = true
+        pool, err := pgxpool.ConnectConfig(context.Background(), poolConfig)
+        if err != nil {
+                log.Errorf("Error initializing the pgx pool.")
+                log.Errorf(err.Error())
+                os.Exit(1)
+        }
+        return pool
+}
+
+// Serves the server under the base path, by removing it from the request paths before routing
+func stripBasePath(basePath string) echo.MiddlewareFunc {
+        return func(next echo.HandlerFunc) echo.HandlerFunc {
+                return func(c echo.Context) error {
+                        url := c.Request().URL
+                        if url.Path != basePath && !strings.HasPrefix(url.Path, basePath+"/") {
+                                return echo.ErrNotFound
+                        }
+                        url.Path = "/" + strings.TrimPrefix(url.Path[len(basePath):], "/")
+                        if url.RawPath != "" {
+                                rawPath := strings.TrimPrefix(url.RawPath, basePath)
+                                url.RawPath = "/" + strings.TrimPrefix(rawPath, "/")
+                        }
+                        return next(c)
+                }
+        }
+}
+
+// Rejects POST, PUT and DELETE requests that do not echo the token of the CSRF cookie in a
+// header. The names are the ones axios uses by default, so the UI sends the token by itself.
+// Requests authenticated with a valid API token are exempt, since a browser does not send the
+// token along with the requests that other sites make.
+func csrfProtection(csrfConfig helpers.CsrfConfig, basePath string) echo.MiddlewareFunc {
+        cookiePath := basePath
+        if cookiePath == "" {
+                cookiePath = "/"
+        }
+        return middleware.CSRFWithConfig(middleware.CSRFConfig{
+                Skipper: func(c echo.Context) bool {
+                        return c.Get(handlers.API_TOKEN_CONTEXT_KEY) != nil
+                },
+                TokenLookup:    "header:X-XSRF-TOKEN",
+                CookieName:     "XSRF-TOKEN",
+                CookiePath:     cookiePath,
+                CookieMaxAge:   int(csrfConfig.CookieMaxAge.Seconds()),
+                CookieSameSite: http.SameSiteStrictMode,
+        })
+}
+
+// Serves the API and the UI over HTTPS when a certificate is set, negotiating HTTP/2 unless
+// server.http2 is off, or over plain HTTP, which also serves HTTP/2 when server.h2c is on.
+// WebSocket upgrades always use HTTP/1.1 connections.
+func startServer(e *echo.Echo, serverConfig helpers.ServerConfig, listenAddress string) error {
+        http2Server := &http2.Server{
+                MaxConcurrentStreams: serverConfig.Http2MaxConcurrentStreams,
+        }
+        if serverConfig.TlsCertFile != "" {
+                e.DisableHTTP2 = !serverConfig.Http2
+                if serverConfig.Http2 {
+                        if err := http2.ConfigureServer(e.TLSServer, http2Server); err != nil {
+                                return err
+                        }
                 }
-                url = fmt.Sprintf("%s?%s", url, secureOptions)
+                return e.StartTLS(listenAddress, serverConfig.TlsCertFile,
+                        serverConfig.TlsKeyFile)
         }
+        if serverConfig.H2c {
+                return e.StartH2CServer(listenAddress, http2Server)
+        }
+        return e.Start(listenAddress)
+}
 
-        log.Debugf("Initializing pgx client.")
-        conn, err := pgx.Connect(context.Background(), url)
+// Serves the pprof and expvar endpoints of the server itself on the debug port, apart from the
+// UI so that they are neither under the base path nor behind the proxies in front of the UI
+func serveDebug(log logger.Logger, debugConfig helpers.DebugConfig) {
+        expvar.Publish("goroutines", expvar.Func(func() interface{} {
+                return runtime.NumGoroutine()
+        }))
+        mux := http.NewServeMux()
+        mux.HandleFunc("/debug/pprof/", pprof.Index)
+        mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+        mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+        mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+        mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+        mux.Handle("/debug/vars", expvar.Handler())
+        debugAddress := net.JoinHostPort(debugConfig.ListenAddress,
+                strconv.Itoa(debugConfig.Port))
+        log.Infof("Serving debug endpoints on %s", debugAddress)
+        if err := http.ListenAndServe(debugAddress, mux); err != nil {
+                log.Errorf("Error serving debug endpoints: %s", err.Error())
+        }
+}
 
+// Applies a reloaded config to the parts of the server that keep their own copy of it
+func applyConfig(log logger.Logger, config *helpers.Config) {
+        if err := log.SetLevel(config.Log.Level); err != nil {
+                log.Errorf("Error setting the log level: %s", err.Error())
+        }
+        if !config.Sessions.IsRequired() {
+                log.Infof("sessions are disabled and no users are set, the API can be used " +
+                        "without logging in")
+        }
+}
+
+func reloadConfig(log logger.Logger) {
+        config, ignoredSections, err := helpers.ReloadConfig()
         if err != nil {
-                log.Errorf("Error initializing the pgx client.")
-                log.Errorf(err.Error())
+                log.Errorf("Error reloading the config, keeping the current one: %s", err.Error())
+                return
+        }
+        applyConfig(log, config)
+        if len(ignoredSections) > 0 {
+                log.Infof("Config reloaded, changes to %s need a restart",
+                        strings.Join(ignoredSections, ", "))
+        } else {
+                log.Infof("Config reloaded")
+        }
+}
+
+// Reloads the config on SIGHUP, and whenever the config file changes
+func watchConfig(log logger.Logger, watchInterval time.Duration) {
+        signals := make(chan os.Signal, 1)
+        signal.Notify(signals, syscall.SIGHUP)
+        var ticks <-chan time.Time
+        path := helpers.GetConfigFile()
+        var lastModTime time.Time
+        if path != "" && watchInterval > 0 {
+                if info, err := os.Stat(path); err == nil {
+                        lastModTime = info.ModTime()
+                }
+                ticks = time.NewTicker(watchInterval).C
+        }
+        for {
+                select {
+                case <-signals:
+                        log.Infof("Received SIGHUP, reloading the config")
+                        reloadConfig(log)
+                case <-ticks:
+                        info, err := os.Stat(path)
+                        if err != nil || info.ModTime().Equal(lastModTime) {
+                                continue
+                        }
+                        lastModTime = info.ModTime()
+                        log.Infof("Config file %s changed, reloading it", path)
+                        reloadConfig(log)
+                }
         }
-        return conn
 }
 
 func main() {
@@ -131,35 +285,52 @@ func main() {
         // Initialize logger
         var log logger.Logger
         var cluster *gocql.ClusterConfig
-        var pgxConn *pgx.Conn
+        var pgxPool *pgxpool.Pool
 
         log, _ = logger.NewSugaredLogger()
         defer log.Cleanup()
         log.Infof("Logger initialized")
 
-        serverPort := getEnv(serverPortEnv, "15433")
+        config, err := helpers.LoadConfig()
+        if err != nil {
+                log.Errorf(err.Error())
+                os.Exit(1)
+        }
+        applyConfig(log, config)
+        go watchConfig(log, config.Server.ConfigWatchInterval)
+        if config.Debug.Enabled {
+                go serveDebug(log, config.Debug)
+        }
 
-        port := ":" + serverPort
+        listenAddress := net.JoinHostPort(config.Server.ListenAddress,
+                strconv.Itoa(config.Server.Port))
 
         LoadTemplates()
 
         e := echo.New()
+        if config.Server.BasePath != "" {
+                e.Pre(stripBasePath(config.Server.BasePath))
+        }
 
         cluster = createGoCqlClient(log)
-        pgxConn = createPgClient(log)
+        pgxPool = createPgClient(log)
+
+        defer pgxPool.Close()
 
-        gocqlSession, err := cluster.CreateSession()
+        c, err := handlers.NewContainer(log, cluster, pgxPool)
         if err != nil {
-                log.Errorf("Error initializing the pgx client.")
                 log.Errorf(err.Error())
+                os.Exit(1)
         }
-        defer gocqlSession.Close()
-        defer pgxConn.Close(context.Background())
+        defer c.Close()
 
-        //todo: handle the error!
-        c, _ := handlers.NewContainer(log, gocqlSession, pgxConn)
+        // Errors are returned as models.ApiError, like the handlers return them
+        e.HTTPErrorHandler = handlers.HandleError
 
         // Middleware
+        // Requests get an id, unless a proxy in front of the server gave them one, which
+        // error responses and the request and audit logs carry
+        e.Use(middleware.RequestID())
         e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
                 LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
                         log.Errorf("[PANIC RECOVER] %v %s\n", err, stack)
@@ -208,19 +379,113 @@ func main() {
                         return nil
                 },
         }))
+        // The CSRF check only skips the requests that AuthenticateApiToken validated a token of,
+        // not every request with an Authorization header, so it must be registered after it
+        e.Use(c.AuthenticateApiToken)
+        e.Use(c.RequireSession)
+        if config.Csrf.Enabled {
+                e.Use(csrfProtection(config.Csrf, config.Server.BasePath))
+        }
+        e.Use(handlers.ValidateRequest)
+        e.Use(c.CacheResponses)
 
         // GetCluster - Get a cluster
         e.GET("/api/cluster", c.GetCluster)
 
+        // GetClusterMetadata - Get the name and details of the cluster
+        e.GET("/api/cluster/metadata", c.GetClusterMetadata)
+
+        // UpdateClusterMetadata - Set the name and details of the cluster
+        e.PUT("/api/cluster/metadata", c.UpdateClusterMetadata)
+
+        // GetEncryptionAtRest - Get the state of encryption at rest
+        e.GET("/api/encryption-at-rest", c.GetEncryptionAtRest)
+
+        // EnableEncryptionAtRest - Encrypt the data of the cluster with a key, or rotate its key
+        e.POST("/api/encryption-at-rest", c.EnableEncryptionAtRest)
+
+        // GetGflagDoc - Get the documentation of a gflag
+        e.GET("/api/gflags/:name/doc", c.GetGflagDoc)
+
+        // GetFeatures - Get the capabilities available on the cluster
+        e.GET("/api/features", c.GetFeatures)
+
+        // GetClusterChanges - Wait for changes of the cluster state
+        e.GET("/api/cluster/changes", c.GetClusterChanges)
+
+        // GetClusterEvents - Get the changes detected in the cluster
+        e.GET("/api/events", c.GetClusterEvents)
+
+        // GetWebhooks - Get list of webhooks
+        e.GET("/api/webhooks", c.GetWebhooks)
+
+        // CreateWebhook - Create a webhook
+        e.POST("/api/webhooks", c.CreateWebhook)
+
+        // UpdateWebhook - Change a webhook
+        e.PUT("/api/webhooks/:id", c.UpdateWebhook)
+
+        // DeleteWebhook - Delete a webhook
+        e.DELETE("/api/webhooks/:id", c.DeleteWebhook)
+
+        // GetDdlActivity - Get the DDL statements that ran on the nodes and the catalog version
+        // bumps
+        e.GET("/api/ddl-activity", c.GetDdlActivity)
+
+        // GetWorkloads - Get list of the running sample workloads
+        e.GET("/api/workloads", c.GetWorkloads)
+
+        // StartWorkload - Start a sample workload
+        e.POST("/api/workloads", c.StartWorkload)
+
+        // StopWorkload - Stop a running sample workload
+        e.POST("/api/workloads/:id/stop", c.StopWorkload)
+
+        // GetClusterSnapshot - Export the current view of the cluster
+        e.GET("/api/cluster/snapshot", c.GetClusterSnapshot)
+
+        // DiffClusterSnapshots - Compare two cluster snapshots
+        e.POST("/api/snapshots/diff", c.DiffClusterSnapshots)
+
+        // GetCallhome - Get the diagnostics reporting settings
+        e.GET("/api/callhome", c.GetCallhome)
+
+        // UpdateCallhome - Change the diagnostics reporting settings
+        e.PUT("/api/callhome", c.UpdateCallhome)
+
+        // GetCallhomePreview - Preview the diagnostics sent by the tserver of this host
+        e.GET("/api/callhome/preview", c.GetCallhomePreview)
+
+        // GetYbAdminCommands - Get list of the yb-admin commands that can be run
+        e.GET("/api/yb-admin", c.GetYbAdminCommands)
+
+        // RunYbAdminCommand - Run a read-only yb-admin command
+        e.GET("/api/yb-admin/:command", c.RunYbAdminCommand)
+
+        // GetRestorePreview - Get what restoring a snapshot or a snapshot schedule would do
+        e.GET("/api/restore/preview", c.GetRestorePreview)
+
         // GetClusterMetric - Get a metric for a cluster
         e.GET("/api/metrics", c.GetClusterMetric)
 
+        // GetReplicationTraffic - Get the bytes replicated between regions or zones
+        e.GET("/api/replication-traffic", c.GetReplicationTraffic)
+
         // GetClusterNodes - Get the nodes for a cluster
         e.GET("/api/nodes", c.GetClusterNodes)
 
         // GetHealthCheck - Get health information about the cluster
         e.GET("/api/health-check", c.GetClusterHealthCheck)
 
+        // GetApiHealth - Probe the YSQL and YCQL layers of every node
+        e.GET("/api/health/apis", c.GetApiHealth)
+
+        // GetProbes - Get the results of the synthetic probes
+        e.GET("/api/probes", c.GetProbes)
+
+        // GetUptime - Get the availability of the APIs over the last day, week and month
+        e.GET("/api/uptime", c.GetUptime)
+
         // GetClusterTables - Get list of DB tables per YB API (YCQL/YSQL)
         e.GET("/api/tables", c.GetClusterTables)
 
@@ -233,9 +498,265 @@ func main() {
         // GetClusterTablets - Get list of tablets
         e.GET("/api/tablets", c.GetClusterTablets)
 
+        // GetTabletLimits - Get the tablet replicas of each node against recommended limits
+        e.GET("/api/tablet-limits", c.GetTabletLimits)
+
+        // GetBalanceScore - Get how evenly the load is spread over the nodes and zones
+        e.GET("/api/balance", c.GetBalanceScore)
+
+        // SimulateFailure - Get the expected impact of a node, zone or region failure
+        e.POST("/api/whatif/failure", c.SimulateFailure)
+
+        // GetAlerts - Get the alerts raised on the nodes
+        e.GET("/api/alerts", c.GetAlerts)
+
+        // GetAlertHistory - Get the history of the alerts
+        e.GET("/api/alerts/history", c.GetAlertHistory)
+
+        // GetAlertRules - Get list of alert rules
+        e.GET("/api/alerts/rules", c.GetAlertRules)
+
+        // CreateAlertRule - Create an alert rule
+        e.POST("/api/alerts/rules", c.CreateAlertRule)
+
+        // UpdateAlertRule - Change an alert rule
+        e.PUT("/api/alerts/rules/:id", c.UpdateAlertRule)
+
+        // DeleteAlertRule - Delete an alert rule
+        e.DELETE("/api/alerts/rules/:id", c.DeleteAlertRule)
+
+        // GetMaintenanceWindows - Get list of maintenance windows
+        e.GET("/api/maintenance-windows", c.GetMaintenanceWindows)
+
+        // CreateMaintenanceWindow - Schedule a maintenance window
+        e.POST("/api/maintenance-windows", c.CreateMaintenanceWindow)
+
+        // DeleteMaintenanceWindow - Cancel a maintenance window
+        e.DELETE("/api/maintenance-windows/:id", c.DeleteMaintenanceWindow)
+
+        // GetCompactionSchedules - Get list of compaction schedules
+        e.GET("/api/compaction-schedules", c.GetCompactionSchedules)
+
+        // CreateCompactionSchedule - Create a compaction schedule
+        e.POST("/api/compaction-schedules", c.CreateCompactionSchedule)
+
+        // UpdateCompactionSchedule - Change a compaction schedule
+        e.PUT("/api/compaction-schedules/:id", c.UpdateCompactionSchedule)
+
+        // DeleteCompactionSchedule - Delete a compaction schedule
+        e.DELETE("/api/compaction-schedules/:id", c.DeleteCompactionSchedule)
+
+        // GetCompactionRuns - Get the runs and skipped windows of a compaction schedule
+        e.GET("/api/compaction-schedules/:id/runs", c.GetCompactionRuns)
+
+        // GetMetricsRetention - Get the retention of the metrics and the space they take
+        e.GET("/api/metrics/retention", c.GetMetricsRetention)
+
+        // CleanupMetrics - Delete the samples of the metrics table older than the retention now
+        e.POST("/api/metrics/retention/cleanup", c.CleanupMetrics)
+
         // GetVersion - Get YugabyteDB version
         e.GET("/api/version", c.GetVersion)
 
+        // GetVersionCheck - Check the versions of the nodes against the release manifest
+        e.GET("/api/version/check", c.GetVersionCheck)
+
+        // GetTopologyServers - Get the YSQL servers of the cluster for topology-aware load balancing
+        e.GET("/api/topology/servers", c.GetTopologyServers)
+
+        // GetMasterDetails - Get the flags and metrics of every master
+        e.GET("/api/masters", c.GetMasterDetails)
+
+        // GetRootCertificate - Get the root certificate of the cluster
+        e.GET("/api/certs", c.GetRootCertificate)
+
+        // CreateClientCertificate - Generate a client certificate
+        e.POST("/api/certs/client", c.CreateClientCertificate)
+
+        // GetClusterNamespaces - Get list of YSQL databases and YCQL keyspaces
+        e.GET("/api/namespaces", c.GetClusterNamespaces)
+
+        // CloneDatabase - Create a writable copy of a YSQL database
+        e.POST("/api/namespaces/:name/clone", c.CloneDatabase)
+
+        // GetSampleDatasets - Get list of the sample datasets
+        e.GET("/api/sample-data", c.GetSampleDatasets)
+
+        // LoadSampleData - Load a sample dataset into a YSQL database
+        e.POST("/api/sample-data", c.LoadSampleData)
+
+        // GetClusterUsers - Get list of YSQL and YCQL roles
+        e.GET("/api/users", c.GetClusterUsers)
+
+        // GetClusterGrants - Get effective grants for a role or table
+        e.GET("/api/grants", c.GetClusterGrants)
+
+        // ChangeUserPassword - Change the password of a YSQL or YCQL role
+        e.POST("/api/users/:name/password", c.ChangeUserPassword)
+
+        // GetDatabaseExtensions - Get list of YSQL extensions
+        e.GET("/api/extensions", c.GetDatabaseExtensions)
+
+        // CreateDatabaseExtension - Install a YSQL extension
+        e.POST("/api/extensions", c.CreateDatabaseExtension)
+
+        // GetPgCompatibility - Get the PostgreSQL version, extensions and unsupported features
+        e.GET("/api/compatibility", c.GetPgCompatibility)
+
+        // GetDatabaseSequences - Get list of YSQL sequences
+        e.GET("/api/sequences", c.GetDatabaseSequences)
+
+        // GetCatalogTables - Get the tables, views and materialized views in the YSQL catalog
+        e.GET("/api/catalog/tables", c.GetCatalogTables)
+
+        // GetCatalogIndexes - Get the indexes in the YSQL catalog
+        e.GET("/api/catalog/indexes", c.GetCatalogIndexes)
+
+        // GetCatalogConstraints - Get the constraints of the tables in the YSQL catalog
+        e.GET("/api/catalog/constraints", c.GetCatalogConstraints)
+
+        // GetCatalogRoles - Get the roles in the YSQL catalog
+        e.GET("/api/catalog/roles", c.GetCatalogRoles)
+
+        // GetTableDdl - Get the DDL of a table
+        e.GET("/api/tables/:id/ddl", c.GetTableDdl)
+
+        // GetTableMetrics - Get the read and write ops of a table over time
+        e.GET("/api/tables/:id/metrics", c.GetTableMetrics)
+
+        // ExportTable - Export the rows of a table
+        e.POST("/api/tables/:id/export", c.ExportTable)
+
+        // ImportTable - Import rows into a table
+        e.POST("/api/tables/:id/import", c.ImportTable)
+
+        // GetDatabaseDumps - Get list of database dumps
+        e.GET("/api/dumps", c.GetDatabaseDumps)
+
+        // CreateDatabaseDump - Dump a YSQL database
+        e.POST("/api/dumps", c.CreateDatabaseDump)
+
+        // DownloadDatabaseDump - Download a database dump
+        e.GET("/api/dumps/:id", c.DownloadDatabaseDump)
+
+        // CreateCdcStream - Create a CDCSDK stream for the changes of a YSQL database
+        e.POST("/api/cdc/streams", c.CreateCdcStream)
+
+        // DeleteCdcStream - Delete a CDCSDK stream
+        e.DELETE("/api/cdc/streams/:id", c.DeleteCdcStream)
+
+        // GetCdcConnectorConfig - Get the config of a Debezium connector that reads a stream
+        e.GET("/api/cdc/streams/:id/connector-config", c.GetCdcConnectorConfig)
+
+        // GetCdcHealth - Get the status of the Debezium connectors and the streams they read
+        e.GET("/api/cdc/health", c.GetCdcHealth)
+
+        // OpenShell - Open a ysqlsh or ycqlsh shell over a WebSocket
+        e.GET("/api/shell", c.OpenShell)
+
+        // GetNodeJoinCommand - Get the command to join a new node to the cluster
+        e.GET("/api/nodes/join-command", c.GetNodeJoinCommand)
+
+        // RunPreflightChecks - Run preflight checks against a prospective node
+        e.POST("/api/preflight", c.RunPreflightChecks)
+
+        // AddNode - Add a node to the cluster
+        e.POST("/api/nodes", c.AddNode)
+
+        // RemoveNode - Remove a node from the cluster
+        e.DELETE("/api/nodes/:address", c.RemoveNode)
+
+        // ManageServerProcess - Start, stop or restart a server process of a node
+        e.POST("/api/nodes/:name/:process/:action", c.ManageServerProcess)
+
+        // GetNodeRpcz - Get the RPCs in progress on a node
+        e.GET("/api/nodes/:name/rpcz", c.GetNodeRpcz)
+
+        // GetClusterRpcz - Get the RPCs in progress on all nodes
+        e.GET("/api/rpcz", c.GetClusterRpcz)
+
+        // GetNodeThreadz - Get the threads of a node grouped by stack
+        e.GET("/api/nodes/:name/threadz", c.GetNodeThreadz)
+
+        // GetNodeVersion - Get the versions and builds of the server processes of a node
+        e.GET("/api/nodes/:name/version", c.GetNodeVersion)
+
+        // SetNodeMaintenance - Put a node in maintenance or take it out
+        e.PUT("/api/nodes/:name/maintenance", c.SetNodeMaintenance)
+
+        // GetClusterThreadz - Get the threads of all nodes grouped by stack
+        e.GET("/api/threadz", c.GetClusterThreadz)
+
+        // GetProfiles - Get list of collected profiles
+        e.GET("/api/profiles", c.GetProfiles)
+
+        // CreateProfile - Collect a CPU or heap profile from a node
+        e.POST("/api/profiles", c.CreateProfile)
+
+        // DownloadProfile - Download a profile
+        e.GET("/api/profiles/:id", c.DownloadProfile)
+
+        // GetProfileFlameGraph - Get the flame graph of a CPU profile
+        e.GET("/api/profiles/:id/flamegraph", c.GetProfileFlameGraph)
+
+        // GetTasks - Get list of tasks
+        e.GET("/api/tasks", c.GetTasks)
+
+        // GetTask - Get a task
+        e.GET("/api/tasks/:id", c.GetTask)
+
+        // GetResponseCacheStats - Get the hit rate of the response cache
+        e.GET("/api/cache", c.GetResponseCacheStats)
+
+        // Login - Log in with local credentials
+        e.POST("/api/login", c.Login)
+
+        // Logout - End the session
+        e.POST("/api/logout", c.Logout)
+
+        // GetSession - Get the session of the user
+        e.GET("/api/session", c.GetSession)
+
+        // GetApiTokens - Get list of API tokens
+        e.GET("/api/tokens", c.GetApiTokens)
+
+        // CreateApiToken - Create an API token
+        e.POST("/api/tokens", c.CreateApiToken)
+
+        // UpdateApiTokenScopes - Change the scopes of an API token
+        e.PUT("/api/tokens/:id", c.UpdateApiTokenScopes)
+
+        // RevokeApiToken - Revoke an API token
+        e.DELETE("/api/tokens/:id", c.RevokeApiToken)
+
+        // BackupLocalStore - Download a backup of the local store
+        e.GET("/api/store/backup", c.BackupLocalStore)
+
+        // RestoreLocalStore - Restore the local store from a backup
+        e.POST("/api/store/restore", c.RestoreLocalStore)
+
+        // GetMigrations - Get list of the migrations run with YugabyteDB Voyager
+        e.GET("/api/migrations", c.GetMigrations)
+
+        // GetMigrationAssessment - Get the assessment of a migration
+        e.GET("/api/migrations/:uuid/assessment", c.GetMigrationAssessment)
+
+        // GetMigrationSchemaAnalysis - Get the schema analysis of a migration
+        e.GET("/api/migrations/:uuid/schema-analysis", c.GetMigrationSchemaAnalysis)
+
+        // GetMigrationProgress - Get the progress of the data import of a migration
+        e.GET("/api/migrations/:uuid/progress", c.GetMigrationProgress)
+
+        // GetSchemaMigrations - Get the history of the schema migration tools in the YSQL
+        // databases
+        e.GET("/api/schema-migrations", c.GetSchemaMigrations)
+
+        // GetReports - Get list of reports
+        e.GET("/api/reports", c.GetReports)
+
+        // GetReport - Get a report
+        e.GET("/api/reports/:id", c.GetReport)
+
         render_htmls := templates.NewTemplate()
 
         // Code for rendering UI Without embedding the files
@@ -251,5 +772,5 @@ func main() {
         e.GET("/", handlers.IndexHandler)
 
         // Start server
-        e.Logger.Fatal(e.Start(port))
+        e.Logger.Fatal(startServer(e, config.Server, listenAddress))
 }
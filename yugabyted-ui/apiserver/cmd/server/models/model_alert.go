@@ -0,0 +1,31 @@
+package models
+
+// Alert - A rule whose threshold a node is above
+type Alert struct {
+
+    RuleId string `json:"rule_id"`
+
+    RuleName string `json:"rule_name"`
+
+    Metric string `json:"metric"`
+
+    // warning or severe
+    Severity string `json:"severity"`
+
+    // Host of the node
+    Node string `json:"node"`
+
+    // Zone of the node as cloud.region.zone
+    Zone string `json:"zone"`
+
+    // Latest value of the metric on the node
+    Value float64 `json:"value"`
+
+    Threshold float64 `json:"threshold"`
+
+    // UNIX timestamp of the first check that found the node above the threshold
+    Since int64 `json:"since"`
+
+    // Whether the node is in an active maintenance window
+    Suppressed bool `json:"suppressed"`
+}
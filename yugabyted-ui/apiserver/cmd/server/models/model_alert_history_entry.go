@@ -0,0 +1,37 @@
+package models
+
+// AlertHistoryEntry - An alert that was raised or resolved
+type AlertHistoryEntry struct {
+
+    // fired or resolved
+    Event string `json:"event"`
+
+    // UNIX timestamp of the check that raised or resolved the alert
+    Time int64 `json:"time"`
+
+    RuleId string `json:"rule_id"`
+
+    RuleName string `json:"rule_name"`
+
+    Metric string `json:"metric"`
+
+    // warning or severe
+    Severity string `json:"severity"`
+
+    // Host of the node
+    Node string `json:"node"`
+
+    // Zone of the node as cloud.region.zone
+    Zone string `json:"zone"`
+
+    // Latest value of the metric on the node
+    Value float64 `json:"value"`
+
+    Threshold float64 `json:"threshold"`
+
+    // UNIX timestamp of the first check that found the node above the threshold
+    Since int64 `json:"since"`
+
+    // Whether the node was in an active maintenance window
+    Suppressed bool `json:"suppressed"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type AlertHistoryResponse struct {
+
+    Data []AlertHistoryEntry `json:"data"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type AlertListResponse struct {
+
+    Data []Alert `json:"data"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// AlertRule - A rule that raises an alert for each node whose metric is above its threshold
+type AlertRule struct {
+
+    Id string `json:"id"`
+
+    Name string `json:"name"`
+
+    // disk_usage_percent, cpu_usage_percent, replication_lag_seconds or clock_skew_ms
+    Metric string `json:"metric"`
+
+    // warning or severe
+    Severity string `json:"severity"`
+
+    // Value of the metric above which the alert is raised, in the unit of the metric
+    Threshold float64 `json:"threshold"`
+
+    Enabled bool `json:"enabled"`
+
+    // User whose session last changed the rule, empty for the built-in rules
+    UpdatedBy string `json:"updated_by"`
+
+    // UNIX timestamp of when the rule was last changed, 0 for the built-in rules
+    UpdatedAt int64 `json:"updated_at"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type AlertRuleListResponse struct {
+
+    Data []AlertRule `json:"data"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type AlertRuleResponse struct {
+
+    Data AlertRule `json:"data"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// AlertRuleSpec - A rule to create, or to replace a rule with
+type AlertRuleSpec struct {
+
+    Name string `json:"name"`
+
+    // disk_usage_percent, cpu_usage_percent, replication_lag_seconds or clock_skew_ms
+    Metric string `json:"metric"`
+
+    // warning or severe
+    Severity string `json:"severity"`
+
+    // Value of the metric above which the alert is raised, in the unit of the metric
+    Threshold float64 `json:"threshold"`
+
+    // Whether the rule is checked, true if null
+    Enabled *bool `json:"enabled"`
+}
@@ -5,6 +5,18 @@ type ApiErrorError struct {
     // Error message
     Detail string `json:"detail"`
 
-    // Error code
+    // HTTP status code
     Status int32 `json:"status"`
+
+    // Error code, e.g. not_found or unavailable
+    Code string `json:"code"`
+
+    // More about the error, depending on the code
+    Details map[string]interface{} `json:"details,omitempty"`
+
+    // Whether the request can succeed if it is made again later
+    Retryable bool `json:"retryable"`
+
+    // ID of the request, as recorded in the request and audit logs
+    RequestId string `json:"request_id,omitempty"`
 }
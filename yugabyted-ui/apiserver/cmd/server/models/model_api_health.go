@@ -0,0 +1,10 @@
+package models
+
+// ApiHealth - The health of the YSQL and YCQL layers of the nodes
+type ApiHealth struct {
+
+    // Whether every layer of every node answered
+    Healthy bool `json:"healthy"`
+
+    Nodes []NodeApiHealth `json:"nodes"`
+}
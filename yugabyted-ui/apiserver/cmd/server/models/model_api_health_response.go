@@ -0,0 +1,6 @@
+package models
+
+type ApiHealthResponse struct {
+
+    Data ApiHealth `json:"data"`
+}
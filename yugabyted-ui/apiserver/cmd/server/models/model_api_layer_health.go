@@ -0,0 +1,16 @@
+package models
+
+// ApiLayerHealth - Whether a query layer of a node answered a trivial query
+type ApiLayerHealth struct {
+
+    Healthy bool `json:"healthy"`
+
+    // Milliseconds it took to connect to the node, missing if it failed
+    ConnectLatencyMs *float64 `json:"connect_latency_ms,omitempty"`
+
+    // Milliseconds of the round trip of the query, missing if it failed
+    LatencyMs *float64 `json:"latency_ms,omitempty"`
+
+    // Why connecting or the query failed
+    Error *string `json:"error,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// ApiToken - A long-lived token that automation calls the API with
+type ApiToken struct {
+
+    Id string `json:"id"`
+
+    // What the token is used for
+    Name string `json:"name"`
+
+    // read allows GET requests, write allows every request
+    Scopes []string `json:"scopes"`
+
+    // User whose session created the token, empty if sessions were disabled
+    CreatedBy string `json:"created_by"`
+
+    // UNIX timestamp of when the token was created
+    CreatedAt int64 `json:"created_at"`
+
+    // UNIX timestamp of when the token was last used, accurate to a minute
+    LastUsedAt *int64 `json:"last_used_at,omitempty"`
+
+    // The token itself, only returned when it is created
+    Token *string `json:"token,omitempty"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type ApiTokenListResponse struct {
+
+    Data []ApiToken `json:"data"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type ApiTokenResponse struct {
+
+    Data ApiToken `json:"data"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// ApiTokenScopesSpec - Scopes to give an API token
+type ApiTokenScopesSpec struct {
+
+    Scopes []string `json:"scopes"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// ApiTokenSpec - API token to create
+type ApiTokenSpec struct {
+
+    // What the token is used for
+    Name string `json:"name"`
+
+    Scopes []string `json:"scopes"`
+}
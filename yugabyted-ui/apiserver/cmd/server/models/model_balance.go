@@ -0,0 +1,13 @@
+package models
+
+// Balance - How evenly the load is spread over the nodes and zones of the cluster
+type Balance struct {
+
+    // 100 when the tablets, leaders and data are spread evenly, lower the less even they are
+    Score float64 `json:"score"`
+
+    Dimensions []BalanceDimension `json:"dimensions"`
+
+    // The nodes and zones furthest from the mean of their dimension
+    TopContributors []BalanceContributor `json:"top_contributors"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// BalanceContributor - A node or zone whose load differs from the mean
+type BalanceContributor struct {
+
+    // tablets, leaders or disk
+    Dimension string `json:"dimension"`
+
+    // node or zone
+    Level string `json:"level"`
+
+    // Host of the node, or cloud.region.zone of the zone
+    Name string `json:"name"`
+
+    // Tablets, leaders or bytes of SST files of the node, or the average of the nodes of the
+    // zone
+    Value float64 `json:"value"`
+
+    // Mean of the nodes or zones of the same placement
+    Mean float64 `json:"mean"`
+
+    // How far the value is above or below the mean, relative to the mean
+    DeviationPercent float64 `json:"deviation_percent"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// BalanceDimension - How evenly one kind of load is spread over the nodes or zones
+type BalanceDimension struct {
+
+    // tablets, leaders or disk
+    Dimension string `json:"dimension"`
+
+    // node or zone
+    Level string `json:"level"`
+
+    // Root mean square of the deviations from the mean, relative to the mean
+    ImbalancePercent float64 `json:"imbalance_percent"`
+
+    // 100 without imbalance, down to 0 at an imbalance of 100%
+    Score float64 `json:"score"`
+}
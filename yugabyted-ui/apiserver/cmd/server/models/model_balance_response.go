@@ -0,0 +1,6 @@
+package models
+
+type BalanceResponse struct {
+
+    Data Balance `json:"data"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// CallhomePreview - What the tserver of this host sends when reporting diagnostics
+type CallhomePreview struct {
+
+    CollectionLevel string `json:"collection_level"`
+
+    Url string `json:"url"`
+
+    // Sections of the diagnostics, by name
+    Payload map[string]interface{} `json:"payload"`
+}
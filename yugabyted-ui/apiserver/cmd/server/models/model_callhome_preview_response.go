@@ -0,0 +1,6 @@
+package models
+
+type CallhomePreviewResponse struct {
+
+    Data CallhomePreview `json:"data"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// CallhomeServer - Diagnostics reporting settings of a master or tserver
+type CallhomeServer struct {
+
+    Host string `json:"host"`
+
+    // master or tserver
+    ServerType string `json:"server_type"`
+
+    Enabled bool `json:"enabled"`
+
+    // How much is sent: low, medium or high
+    CollectionLevel string `json:"collection_level"`
+
+    // Where the diagnostics are sent
+    Url string `json:"url"`
+
+    // How often the diagnostics are sent
+    IntervalSecs int32 `json:"interval_secs"`
+
+    // Why the settings could not be read or changed, empty unless that failed
+    Error string `json:"error"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// CallhomeSettings - Diagnostics reporting settings of the cluster
+type CallhomeSettings struct {
+
+    // Whether any master or tserver sends diagnostics
+    Enabled bool `json:"enabled"`
+
+    Servers []CallhomeServer `json:"servers"`
+
+    // Only set when the settings were changed. Whether the change outlives a restart of the
+    // processes, which it does not as only the runtime gflags are changed.
+    Persisted *bool `json:"persisted,omitempty"`
+
+    // Only set when the settings were changed. What is lost when a process restarts.
+    Warning *string `json:"warning,omitempty"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type CallhomeSettingsResponse struct {
+
+    Data CallhomeSettings `json:"data"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// CallhomeSpec - Diagnostics reporting settings to apply to every master and tserver
+type CallhomeSpec struct {
+
+    Enabled bool `json:"enabled"`
+
+    // How much is sent: low, medium or high. Left unchanged if null.
+    CollectionLevel *string `json:"collection_level"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// CatalogConstraint - A constraint of a table in the YSQL catalog
+type CatalogConstraint struct {
+
+    Schema string `json:"schema"`
+
+    // Table the constraint is on
+    Table string `json:"table"`
+
+    Name string `json:"name"`
+
+    // primary_key, foreign_key, unique, check, exclusion or trigger
+    Type string `json:"type"`
+
+    // Definition of the constraint, as it would appear in ALTER TABLE ADD CONSTRAINT
+    Definition string `json:"definition"`
+
+    // Table that a foreign key references, empty for other constraints
+    ReferencedTable string `json:"referenced_table"`
+}
@@ -0,0 +1,9 @@
+package models
+
+type CatalogConstraintListResponse struct {
+
+    Data []CatalogConstraint `json:"data"`
+
+    // Whether more constraints match the filters than the limit
+    HasMore bool `json:"has_more"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// CatalogIndex - An index in the YSQL catalog
+type CatalogIndex struct {
+
+    Schema string `json:"schema"`
+
+    // Table the index is on
+    Table string `json:"table"`
+
+    Name string `json:"name"`
+
+    // Access method of the index, e.g. lsm or ybgin
+    Method string `json:"method"`
+
+    IsUnique bool `json:"is_unique"`
+
+    IsPrimary bool `json:"is_primary"`
+
+    // CREATE INDEX statement of the index
+    Definition string `json:"definition"`
+}
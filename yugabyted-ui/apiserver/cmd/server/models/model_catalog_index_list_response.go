@@ -0,0 +1,9 @@
+package models
+
+type CatalogIndexListResponse struct {
+
+    Data []CatalogIndex `json:"data"`
+
+    // Whether more indexes match the filters than the limit
+    HasMore bool `json:"has_more"`
+}
@@ -0,0 +1,9 @@
+package models
+
+type CatalogRoleListResponse struct {
+
+    Data []DatabaseRole `json:"data"`
+
+    // Whether more roles match the filters than the limit
+    HasMore bool `json:"has_more"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// CatalogTable - A table, view, materialized view or foreign table in the YSQL catalog
+type CatalogTable struct {
+
+    Schema string `json:"schema"`
+
+    Name string `json:"name"`
+
+    // table, partitioned_table, view, materialized_view or foreign_table
+    Kind string `json:"kind"`
+
+    Owner string `json:"owner"`
+
+    NumColumns int64 `json:"num_columns"`
+
+    HasPrimaryKey bool `json:"has_primary_key"`
+
+    // Comment on the table, empty if there is none
+    Comment string `json:"comment"`
+}
@@ -0,0 +1,9 @@
+package models
+
+type CatalogTableListResponse struct {
+
+    Data []CatalogTable `json:"data"`
+
+    // Whether more tables match the filters than the limit
+    HasMore bool `json:"has_more"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// CdcConnectorConfig - Config of a connector that reads a CDCSDK stream
+type CdcConnectorConfig struct {
+
+    // kafka_connect or debezium_server
+    Format string `json:"format"`
+
+    // Name of the connector, and prefix of the topics it writes to
+    Name string `json:"name"`
+
+    // Properties of the connector. The password is a placeholder to fill in.
+    Config map[string]string `json:"config"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type CdcConnectorConfigResponse struct {
+
+    Data CdcConnectorConfig `json:"data"`
+}
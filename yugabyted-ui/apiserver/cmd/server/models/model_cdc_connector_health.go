@@ -0,0 +1,26 @@
+package models
+
+// CdcConnectorHealth - Status of a Debezium connector that reads a CDC stream
+type CdcConnectorHealth struct {
+
+    Name string `json:"name"`
+
+    // State of the connector in Kafka Connect, e.g. RUNNING, PAUSED or FAILED
+    State string `json:"state"`
+
+    WorkerId string `json:"worker_id"`
+
+    // Stack trace of the failure, empty unless the connector failed
+    Trace string `json:"trace"`
+
+    Tasks []CdcConnectorTask `json:"tasks"`
+
+    Database string `json:"database"`
+
+    // Stream the connector is configured to read
+    StreamId string `json:"stream_id"`
+
+    // Whether the stream has checkpoints in the cluster. A connector whose stream has none
+    // reads a stream that was deleted or has not been read yet.
+    StreamFound bool `json:"stream_found"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// CdcConnectorTask - Status of a task of a connector
+type CdcConnectorTask struct {
+
+    Id int32 `json:"id"`
+
+    State string `json:"state"`
+
+    WorkerId string `json:"worker_id"`
+
+    // Stack trace of the failure, empty unless the task failed
+    Trace string `json:"trace"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// CdcHealth - The Debezium connectors of Kafka Connect next to the CDC streams they read
+type CdcHealth struct {
+
+    // Whether kafka_connect.url is set in the config. Connectors is empty if it is not.
+    KafkaConnectEnabled bool `json:"kafka_connect_enabled"`
+
+    // The connectors that read from YugabyteDB, by name
+    Connectors []CdcConnectorHealth `json:"connectors"`
+
+    // The streams that have checkpoints, by stream id
+    Streams []CdcStreamHealth `json:"streams"`
+}
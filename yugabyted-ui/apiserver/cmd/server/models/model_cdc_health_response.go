@@ -0,0 +1,6 @@
+package models
+
+type CdcHealthResponse struct {
+
+    Data CdcHealth `json:"data"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// CdcStream - A CDCSDK stream of the changes of a YSQL database
+type CdcStream struct {
+
+    StreamId string `json:"stream_id"`
+
+    Database string `json:"database"`
+
+    CheckpointType string `json:"checkpoint_type"`
+
+    RecordType string `json:"record_type"`
+}
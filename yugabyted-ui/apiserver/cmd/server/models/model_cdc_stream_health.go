@@ -0,0 +1,23 @@
+package models
+
+// CdcStreamHealth - How far the readers of a CDC stream are behind, from its checkpoints
+type CdcStreamHealth struct {
+
+    StreamId string `json:"stream_id"`
+
+    // Tablets the stream has a checkpoint for
+    Tablets int32 `json:"tablets"`
+
+    // Tablets whose changes were never read
+    TabletsNotRead int32 `json:"tablets_not_read"`
+
+    // UNIX timestamp of the oldest change read from a tablet, null if none was read
+    OldestReplicationTimestamp *int64 `json:"oldest_replication_timestamp"`
+
+    // Seconds since the oldest change read, null if none was read
+    LagSeconds *int64 `json:"lag_seconds"`
+
+    // Connectors configured to read the stream. Streams that no connector reads keep the
+    // changes of their database until they are deleted.
+    Connectors []string `json:"connectors"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type CdcStreamResponse struct {
+
+    Data CdcStream `json:"data"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// CdcStreamSpec - CDCSDK stream to create for the changes of a YSQL database
+type CdcStreamSpec struct {
+
+    Database string `json:"database"`
+
+    // How the position of the readers is kept, EXPLICIT for the Debezium connector
+    CheckpointType string `json:"checkpoint_type"`
+
+    // Which images of the changed rows are sent
+    RecordType string `json:"record_type"`
+}
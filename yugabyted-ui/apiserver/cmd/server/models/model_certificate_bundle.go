@@ -0,0 +1,14 @@
+package models
+
+// CertificateBundle - PEM encoded certificates for connecting to a secure cluster
+type CertificateBundle struct {
+
+    // Root CA certificate of the cluster
+    RootCert string `json:"root_cert"`
+
+    // Client certificate, if one was generated
+    ClientCert *string `json:"client_cert"`
+
+    // Private key of the client certificate, if one was generated
+    ClientKey *string `json:"client_key"`
+}
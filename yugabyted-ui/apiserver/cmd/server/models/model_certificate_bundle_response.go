@@ -0,0 +1,6 @@
+package models
+
+type CertificateBundleResponse struct {
+
+    Data CertificateBundle `json:"data"`
+}
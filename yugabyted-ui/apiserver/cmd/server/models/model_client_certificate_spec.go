@@ -0,0 +1,11 @@
+package models
+
+// ClientCertificateSpec - Client certificate to generate
+type ClientCertificateSpec struct {
+
+    // Database user that the certificate authenticates, used as its common name
+    Username string `json:"username"`
+
+    // Number of days the certificate is valid for
+    ValidityDays int32 `json:"validity_days"`
+}
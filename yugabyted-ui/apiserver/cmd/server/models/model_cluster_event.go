@@ -0,0 +1,25 @@
+package models
+
+// ClusterEvent - A change detected in the cluster
+type ClusterEvent struct {
+
+    // ID of the event, which sorts by time
+    Id string `json:"id"`
+
+    // node_added, node_removed, node_died, node_recovered, leader_changed, version_changed,
+    // gflag_changed, mixed_versions, critical_build or end_of_life for the findings of the
+    // version check, or report_generated when a summary report was produced
+    Type string `json:"type"`
+
+    // UNIX timestamp of the poll that detected the change
+    Time int64 `json:"time"`
+
+    // Host of the node that changed, of the new master leader for leader_changed, of the first
+    // node of the finding for the findings of the version check, empty for report_generated
+    Node string `json:"node"`
+
+    Message string `json:"message"`
+
+    // Values before and after the change, depending on the type of the event
+    Details map[string]string `json:"details"`
+}
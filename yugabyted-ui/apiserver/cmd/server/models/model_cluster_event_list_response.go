@@ -0,0 +1,9 @@
+package models
+
+type ClusterEventListResponse struct {
+
+    Data []ClusterEvent `json:"data"`
+
+    // Cursor to get the next page of events with, empty on the last page
+    NextCursor string `json:"next_cursor"`
+}
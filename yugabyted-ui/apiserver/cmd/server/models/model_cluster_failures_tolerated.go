@@ -0,0 +1,14 @@
+package models
+
+// ClusterFailuresTolerated - How many failures of each domain the cluster tolerates
+type ClusterFailuresTolerated struct {
+
+    // Nodes that can fail at once while every tablet keeps quorum
+    Nodes int32 `json:"nodes"`
+
+    // Zones that can fail at once while every tablet keeps quorum
+    Zones int32 `json:"zones"`
+
+    // Regions that can fail at once while every tablet keeps quorum
+    Regions int32 `json:"regions"`
+}
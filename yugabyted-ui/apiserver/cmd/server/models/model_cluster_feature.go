@@ -0,0 +1,13 @@
+package models
+
+// ClusterFeature - Whether a capability is available, for the UI to hide the panels of the ones
+// that are not
+type ClusterFeature struct {
+
+    Name string `json:"name"`
+
+    Available bool `json:"available"`
+
+    // Why the capability is not available, empty if it is
+    Reason string `json:"reason"`
+}
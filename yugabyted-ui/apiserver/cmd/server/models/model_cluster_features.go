@@ -0,0 +1,16 @@
+package models
+
+// ClusterFeatures - The capabilities of the cluster and of this server
+type ClusterFeatures struct {
+
+    // Smallest version of the nodes, empty if it is unknown
+    Version string `json:"version"`
+
+    // Capabilities that depend on the version and gflags of the cluster: pitr, xcluster,
+    // backups and ash
+    Cluster []ClusterFeature `json:"cluster"`
+
+    // Features of this server that the config can turn off, by their key in the features
+    // section of the config
+    Server []ClusterFeature `json:"server"`
+}
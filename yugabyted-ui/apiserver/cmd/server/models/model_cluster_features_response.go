@@ -0,0 +1,6 @@
+package models
+
+type ClusterFeaturesResponse struct {
+
+    Data ClusterFeatures `json:"data"`
+}
@@ -8,6 +8,11 @@ type ClusterInfo struct {
 
     FaultTolerance ClusterFaultTolerance `json:"fault_tolerance"`
 
+    // Replication factor of the primary cluster
+    ReplicationFactor int32 `json:"replication_factor"`
+
+    FailuresTolerated ClusterFailuresTolerated `json:"failures_tolerated"`
+
     NodeInfo ClusterNodeInfo `json:"node_info"`
 
     // Describes if the cluster is a production cluster
@@ -0,0 +1,25 @@
+package models
+
+// ClusterMetadata - The name and details given to a cluster to tell it apart from others
+type ClusterMetadata struct {
+
+    // Uuid of the cluster the metadata belongs to
+    ClusterUuid string `json:"cluster_uuid"`
+
+    // Human-friendly name of the cluster
+    Name string `json:"name"`
+
+    Description string `json:"description"`
+
+    // Team or person responsible for the cluster
+    Owner string `json:"owner"`
+
+    // Labels of the cluster, e.g. env: production
+    Tags map[string]string `json:"tags"`
+
+    // User whose session last changed the metadata, empty if sessions were disabled
+    UpdatedBy string `json:"updated_by"`
+
+    // UNIX timestamp of when the metadata was last changed, 0 if it never was
+    UpdatedAt int64 `json:"updated_at"`
+}
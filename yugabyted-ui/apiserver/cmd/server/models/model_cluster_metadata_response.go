@@ -0,0 +1,6 @@
+package models
+
+type ClusterMetadataResponse struct {
+
+    Data ClusterMetadata `json:"data"`
+}
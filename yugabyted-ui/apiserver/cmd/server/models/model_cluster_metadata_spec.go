@@ -0,0 +1,16 @@
+package models
+
+// ClusterMetadataSpec - Name and details to give a cluster, replacing the current ones
+type ClusterMetadataSpec struct {
+
+    // Human-friendly name of the cluster
+    Name string `json:"name"`
+
+    Description string `json:"description"`
+
+    // Team or person responsible for the cluster
+    Owner string `json:"owner"`
+
+    // Labels of the cluster, e.g. env: production
+    Tags map[string]string `json:"tags"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// ClusterNamespace - Model representing a YSQL database or YCQL keyspace
+type ClusterNamespace struct {
+
+    Name string `json:"name"`
+
+    Type YbApiEnum `json:"type"`
+
+    // Number of tables and indexes in the namespace
+    NumTables int32 `json:"num_tables"`
+
+    // Total on-disk size of the tables in the namespace
+    SizeBytes int64 `json:"size_bytes"`
+
+    // Whether the YSQL database is colocated
+    IsColocated bool `json:"is_colocated"`
+}
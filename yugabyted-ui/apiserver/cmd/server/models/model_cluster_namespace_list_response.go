@@ -0,0 +1,6 @@
+package models
+
+type ClusterNamespaceListResponse struct {
+
+    Data []ClusterNamespace `json:"data"`
+}
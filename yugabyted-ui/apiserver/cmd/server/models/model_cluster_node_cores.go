@@ -0,0 +1,9 @@
+package models
+
+// ClusterNodeCores - The number of CPU cores of a node
+type ClusterNodeCores struct {
+
+    Name string `json:"name"`
+
+    NumCores int32 `json:"num_cores"`
+}
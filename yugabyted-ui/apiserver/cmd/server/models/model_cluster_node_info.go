@@ -3,7 +3,7 @@ package models
 // ClusterNodeInfo - Node level information
 type ClusterNodeInfo struct {
 
-    // The total amount of RAM (MB) used by all nodes
+    // The total amount of RAM (MB) used by the tservers of all nodes
     MemoryMb float64 `json:"memory_mb"`
 
     // The total size of disk (GB)
@@ -15,6 +15,25 @@ type ClusterNodeInfo struct {
     // The average CPU usage over all nodes
     CpuUsage float64 `json:"cpu_usage"`
 
-    // The number of CPU cores per node
+    // The number of CPU cores per node, the fewest of any node when they differ, 0 if no
+    // node_exporter can be reached
     NumCores int32 `json:"num_cores"`
+
+    // The number of CPU cores of all nodes, 0 if no node_exporter can be reached
+    TotalCores int32 `json:"total_cores"`
+
+    // The number of CPU cores of each node whose node_exporter can be reached
+    NodeCores []ClusterNodeCores `json:"node_cores"`
+
+    // The memory (MB) of all nodes whose node_exporter can be reached
+    TotalMemoryMb float64 `json:"total_memory_mb"`
+
+    // The memory (MB) in use on all nodes whose node_exporter can be reached
+    UsedMemoryMb float64 `json:"used_memory_mb"`
+
+    // The memory (MB) available on all nodes whose node_exporter can be reached
+    AvailableMemoryMb float64 `json:"available_memory_mb"`
+
+    // The memory of each node whose node_exporter can be reached
+    NodeMemory []ClusterNodeMemory `json:"node_memory"`
 }
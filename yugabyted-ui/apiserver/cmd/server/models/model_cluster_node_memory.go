@@ -0,0 +1,16 @@
+package models
+
+// ClusterNodeMemory - The memory of a node
+type ClusterNodeMemory struct {
+
+    Name string `json:"name"`
+
+    // The memory of the node (MB)
+    TotalMb float64 `json:"total_mb"`
+
+    // The memory in use (MB), which leaves out the page cache that can be reclaimed
+    UsedMb float64 `json:"used_mb"`
+
+    // The memory available to start new processes with (MB)
+    AvailableMb float64 `json:"available_mb"`
+}
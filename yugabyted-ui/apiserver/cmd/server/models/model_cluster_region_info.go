@@ -3,5 +3,12 @@ package models
 // ClusterRegionInfo - Cluster region info list
 type ClusterRegionInfo struct {
 
+    // Cloud of the region, as the nodes report it. Regions with the same name in different
+    // clouds are listed apart.
+    Cloud string `json:"cloud"`
+
     PlacementInfo PlacementInfo `json:"placement_info"`
+
+    // The zones of the region, sorted by name
+    Zones []ClusterZoneInfo `json:"zones"`
 }
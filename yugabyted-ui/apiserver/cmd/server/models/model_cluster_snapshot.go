@@ -0,0 +1,26 @@
+package models
+
+// ClusterSnapshot - The current view of the cluster in a single document
+type ClusterSnapshot struct {
+
+    // Version of the layout of this document, incremented on incompatible changes
+    SchemaVersion int32 `json:"schema_version"`
+
+    // UNIX timestamp at which the snapshot was taken
+    Timestamp int64 `json:"timestamp"`
+
+    // Null if the cluster config could not be fetched
+    ClusterConfig *ClusterStateConfig `json:"cluster_config"`
+
+    Placement []ClusterSnapshotPlacementBlock `json:"placement"`
+
+    Nodes []ClusterSnapshotNode `json:"nodes"`
+
+    Masters []ClusterStateMaster `json:"masters"`
+
+    // Null if the health check could not be fetched
+    Health *ClusterStateHealth `json:"health"`
+
+    // Parts of the cluster view that could not be fetched
+    Errors []string `json:"errors"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// ClusterSnapshotNode - A node of the cluster with its version and gflags
+type ClusterSnapshotNode struct {
+
+    // Host of the node
+    Name string `json:"name"`
+
+    // ALIVE or DEAD, as reported by the master leader
+    Status string `json:"status"`
+
+    Cloud string `json:"cloud"`
+
+    Region string `json:"region"`
+
+    Zone string `json:"zone"`
+
+    // YugabyteDB version of the node, empty if it could not be fetched
+    Version string `json:"version"`
+
+    BuildNumber string `json:"build_number"`
+
+    // Whether a master runs on the node
+    IsMaster bool `json:"is_master"`
+
+    TserverGflags map[string]string `json:"tserver_gflags"`
+
+    // Null if no master runs on the node
+    MasterGflags *map[string]string `json:"master_gflags"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// ClusterSnapshotPlacementBlock - Where the replicas of the cluster are required to be placed
+type ClusterSnapshotPlacementBlock struct {
+
+    Cloud string `json:"cloud"`
+
+    Region string `json:"region"`
+
+    Zone string `json:"zone"`
+
+    MinNumReplicas int32 `json:"min_num_replicas"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type ClusterSnapshotResponse struct {
+
+    Data ClusterSnapshot `json:"data"`
+}
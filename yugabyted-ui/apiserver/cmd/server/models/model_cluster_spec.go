@@ -6,6 +6,14 @@ type ClusterSpec struct {
     // The name of the cluster
     Name string `json:"name"`
 
+    Description string `json:"description"`
+
+    // Team or person responsible for the cluster
+    Owner string `json:"owner"`
+
+    // Labels of the cluster, e.g. env: production
+    Tags map[string]string `json:"tags"`
+
     CloudInfo CloudInfo `json:"cloud_info"`
 
     ClusterInfo ClusterInfo `json:"cluster_info"`
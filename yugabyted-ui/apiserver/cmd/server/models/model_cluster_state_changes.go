@@ -0,0 +1,17 @@
+package models
+
+// ClusterStateChanges - Sections of the cluster state that changed since a cursor
+type ClusterStateChanges struct {
+
+    // Cursor to pass as since to get the next changes
+    Cursor string `json:"cursor"`
+
+    // Sections are present only if they changed
+    Nodes *[]ClusterStateNode `json:"nodes,omitempty"`
+
+    Masters *[]ClusterStateMaster `json:"masters,omitempty"`
+
+    Health *ClusterStateHealth `json:"health,omitempty"`
+
+    ClusterConfig *ClusterStateConfig `json:"cluster_config,omitempty"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type ClusterStateChangesResponse struct {
+
+    Data ClusterStateChanges `json:"data"`
+}
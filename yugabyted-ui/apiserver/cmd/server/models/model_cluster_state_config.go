@@ -0,0 +1,14 @@
+package models
+
+// ClusterStateConfig - Cluster config kept by the masters
+type ClusterStateConfig struct {
+
+    // Incremented by the masters on every change of the config
+    Version int32 `json:"version"`
+
+    ClusterUuid string `json:"cluster_uuid"`
+
+    ReplicationFactor int32 `json:"replication_factor"`
+
+    EncryptionAtRest bool `json:"encryption_at_rest"`
+}
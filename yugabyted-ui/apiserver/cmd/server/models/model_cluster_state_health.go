@@ -0,0 +1,11 @@
+package models
+
+// ClusterStateHealth - Dead nodes and under-replicated tablets of the cluster
+type ClusterStateHealth struct {
+
+    // UUIDs of dead nodes
+    DeadNodes []string `json:"dead_nodes"`
+
+    // UUIDs of under-replicated tablets
+    UnderReplicatedTablets []string `json:"under_replicated_tablets"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// ClusterStateMaster - Role and reachability of a master
+type ClusterStateMaster struct {
+
+    Uuid string `json:"uuid"`
+
+    // Host of the master's RPC address
+    Host string `json:"host"`
+
+    // LEADER, FOLLOWER, or empty if unknown
+    Role string `json:"role"`
+
+    IsReachable bool `json:"is_reachable"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// ClusterStateNode - Registration and liveness of a tserver
+type ClusterStateNode struct {
+
+    // Host of the node
+    Name string `json:"name"`
+
+    // ALIVE or DEAD, as reported by the master leader
+    Status string `json:"status"`
+
+    Cloud string `json:"cloud"`
+
+    Region string `json:"region"`
+
+    Zone string `json:"zone"`
+}
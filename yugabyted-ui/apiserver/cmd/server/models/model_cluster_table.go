@@ -3,6 +3,8 @@ package models
 // ClusterTable - Model representing a DB table
 type ClusterTable struct {
 
+    Uuid string `json:"uuid"`
+
     Name string `json:"name"`
 
     Keyspace string `json:"keyspace"`
@@ -12,4 +12,17 @@ type ClusterTablet struct {
     TabletId string `json:"tablet_id"`
 
     HasLeader bool `json:"has_leader"`
+
+    // State of the tablet as the master reports it, e.g. RUNNING
+    State string `json:"state"`
+
+    // Key range of the tablet as the tservers report it, e.g. hash_split: [0x0000, 0x5555),
+    // empty if none of the nodes of its replicas could be read
+    Partition string `json:"partition"`
+
+    // Host of the node of the leader, empty while there is none
+    Leader string `json:"leader"`
+
+    // Replicas of the tablet, the leader first
+    Peers []ClusterTabletPeer `json:"peers"`
 }
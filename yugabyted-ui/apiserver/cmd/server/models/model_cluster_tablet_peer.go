@@ -0,0 +1,22 @@
+package models
+
+// ClusterTabletPeer - A replica of a tablet and where it is placed
+type ClusterTabletPeer struct {
+
+    ServerUuid string `json:"server_uuid"`
+
+    // Host of the node of the replica
+    Host string `json:"host"`
+
+    // LEADER or FOLLOWER
+    Role string `json:"role"`
+
+    // VOTER, or OBSERVER for the replicas of read replica clusters
+    Type string `json:"type"`
+
+    Cloud string `json:"cloud"`
+
+    Region string `json:"region"`
+
+    Zone string `json:"zone"`
+}
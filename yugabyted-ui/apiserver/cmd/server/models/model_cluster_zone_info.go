@@ -0,0 +1,10 @@
+package models
+
+// ClusterZoneInfo - A zone of a region of the cluster
+type ClusterZoneInfo struct {
+
+    Zone string `json:"zone"`
+
+    // How many nodes are in the zone
+    NumNodes int32 `json:"num_nodes"`
+}
@@ -0,0 +1,33 @@
+package models
+
+// CompactionRun - What was done in a window of a compaction schedule
+type CompactionRun struct {
+
+    ScheduleId string `json:"schedule_id"`
+
+    // UNIX timestamp of when the window started
+    WindowStart int64 `json:"window_start"`
+
+    // UNIX timestamp of when the window ends
+    WindowEnd int64 `json:"window_end"`
+
+    // running, succeeded, failed, incomplete if the window ended before all the compactions
+    // were done, or skipped if nothing was compacted
+    State string `json:"state"`
+
+    // Task that runs the compactions, empty if the window was skipped
+    TaskId string `json:"task_id"`
+
+    // Why the window was skipped, or why the run failed or is incomplete
+    Reason string `json:"reason"`
+
+    TablesCompacted int32 `json:"tables_compacted"`
+
+    NodesCompacted int32 `json:"nodes_compacted"`
+
+    // UNIX timestamp of when the compactions started, 0 if the window was skipped
+    StartedAt int64 `json:"started_at"`
+
+    // UNIX timestamp of when the compactions ended, 0 if they are still running
+    FinishedAt int64 `json:"finished_at"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type CompactionRunListResponse struct {
+
+    Data []CompactionRun `json:"data"`
+}
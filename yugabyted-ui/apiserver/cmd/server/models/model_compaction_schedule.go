@@ -0,0 +1,37 @@
+package models
+
+// CompactionSchedule - Off-peak windows during which full compactions of tables and nodes are
+// run
+type CompactionSchedule struct {
+
+    Id string `json:"id"`
+
+    Name string `json:"name"`
+
+    // Time of day in UTC at which the windows start, as HH:MM
+    StartTime string `json:"start_time"`
+
+    // Length of the windows
+    DurationMinutes int32 `json:"duration_minutes"`
+
+    // Days of the week on which the windows start, among sun, mon, tue, wed, thu, fri and sat,
+    // every day if empty
+    Days []string `json:"days"`
+
+    // UUIDs of the tables to compact
+    TableIds []string `json:"table_ids"`
+
+    // Hosts of the tservers whose tablets are all compacted
+    Nodes []string `json:"nodes"`
+
+    Enabled bool `json:"enabled"`
+
+    // User whose session created the schedule, empty if sessions were disabled
+    CreatedBy string `json:"created_by"`
+
+    // UNIX timestamp of when the schedule was created
+    CreatedAt int64 `json:"created_at"`
+
+    // UNIX timestamp of when the next window starts, 0 if the schedule is disabled
+    NextWindowStart int64 `json:"next_window_start"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type CompactionScheduleListResponse struct {
+
+    Data []CompactionSchedule `json:"data"`
+}
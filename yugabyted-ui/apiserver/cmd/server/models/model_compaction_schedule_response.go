@@ -0,0 +1,6 @@
+package models
+
+type CompactionScheduleResponse struct {
+
+    Data CompactionSchedule `json:"data"`
+}
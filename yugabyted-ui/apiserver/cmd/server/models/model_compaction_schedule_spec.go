@@ -0,0 +1,26 @@
+package models
+
+// CompactionScheduleSpec - A compaction schedule to create, or to replace a schedule with
+type CompactionScheduleSpec struct {
+
+    Name string `json:"name"`
+
+    // Time of day in UTC at which the windows start, as HH:MM
+    StartTime string `json:"start_time"`
+
+    // Length of the windows, at most a day
+    DurationMinutes int32 `json:"duration_minutes"`
+
+    // Days of the week on which the windows start, among sun, mon, tue, wed, thu, fri and sat,
+    // every day if empty
+    Days []string `json:"days"`
+
+    // UUIDs of the tables to compact
+    TableIds []string `json:"table_ids"`
+
+    // Hosts of the tservers whose tablets are all compacted
+    Nodes []string `json:"nodes"`
+
+    // Whether the windows are run, true if null
+    Enabled *bool `json:"enabled"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// ConfirmationRequired - Token that must be sent back to confirm an action
+type ConfirmationRequired struct {
+
+    ConfirmationToken string `json:"confirmation_token"`
+
+    // UNIX timestamp after which the token can no longer be used
+    ExpireTimestamp int64 `json:"expire_timestamp"`
+
+    // What the action will do
+    Description string `json:"description"`
+}
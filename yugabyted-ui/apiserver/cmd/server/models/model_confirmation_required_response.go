@@ -0,0 +1,6 @@
+package models
+
+type ConfirmationRequiredResponse struct {
+
+    Data ConfirmationRequired `json:"data"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// DatabaseCloneSpec - Writable copy of a YSQL database to create
+type DatabaseCloneSpec struct {
+
+    // Name of the new database
+    Target string `json:"target"`
+
+    // UNIX timestamp of the point in time to clone the database as of, 0 for now. Only
+    // versions that clone instantly can clone as of a past time.
+    CloneTime int64 `json:"clone_time"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// DatabaseDump - A dump of a YSQL database written by ysql_dump
+type DatabaseDump struct {
+
+    // ID of the dump, the ID of the task that wrote it
+    Id string `json:"id"`
+
+    // YSQL database that was dumped
+    Database string `json:"database"`
+
+    // Whether only the schema was dumped, without the rows
+    SchemaOnly bool `json:"schema_only"`
+
+    // Size of the compressed dump in bytes
+    SizeBytes int64 `json:"size_bytes"`
+
+    // Unix timestamp of when the dump finished
+    Timestamp int64 `json:"timestamp"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type DatabaseDumpListResponse struct {
+
+    Data []DatabaseDump `json:"data"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// DatabaseDumpSpec - YSQL database to dump
+type DatabaseDumpSpec struct {
+
+    // Name of the YSQL database
+    Database string `json:"database"`
+
+    // Only dump the schema, without the rows
+    SchemaOnly bool `json:"schema_only"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// DatabaseExtension - Model representing a YSQL extension
+type DatabaseExtension struct {
+
+    Name string `json:"name"`
+
+    // Version that is installed when no version is given
+    DefaultVersion string `json:"default_version"`
+
+    // Installed version, or null if the extension is not installed
+    InstalledVersion *string `json:"installed_version"`
+
+    Comment string `json:"comment"`
+
+    // Whether the extension can be installed from the UI
+    IsInstallable bool `json:"is_installable"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type DatabaseExtensionListResponse struct {
+
+    Data []DatabaseExtension `json:"data"`
+}
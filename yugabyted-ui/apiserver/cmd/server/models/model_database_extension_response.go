@@ -0,0 +1,6 @@
+package models
+
+type DatabaseExtensionResponse struct {
+
+    Data DatabaseExtension `json:"data"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// DatabaseExtensionSpec - YSQL extension to install
+type DatabaseExtensionSpec struct {
+
+    // YSQL database to install the extension in, which must exist, yugabyte by default
+    Database string `json:"database"`
+
+    // Name of the extension
+    Name string `json:"name"`
+}
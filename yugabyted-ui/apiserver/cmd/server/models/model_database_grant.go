@@ -0,0 +1,27 @@
+package models
+
+// DatabaseGrant - Model representing a privilege granted to a role
+type DatabaseGrant struct {
+
+    // Role the privilege is granted to
+    Grantee string `json:"grantee"`
+
+    // Role that granted the privilege (YSQL only)
+    Grantor string `json:"grantor"`
+
+    Type YbApiEnum `json:"type"`
+
+    // YSQL database or YCQL keyspace of the object, empty for cluster-wide grants
+    Namespace string `json:"namespace"`
+
+    // Schema of the object (YSQL only)
+    Schema string `json:"schema"`
+
+    // Name of the table, empty for keyspace-wide grants
+    ObjectName string `json:"object_name"`
+
+    Privilege string `json:"privilege"`
+
+    // Whether the grantee can grant the privilege to others (YSQL only)
+    IsGrantable bool `json:"is_grantable"`
+}
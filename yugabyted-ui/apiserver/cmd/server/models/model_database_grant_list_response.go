@@ -0,0 +1,6 @@
+package models
+
+type DatabaseGrantListResponse struct {
+
+    Data []DatabaseGrant `json:"data"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// DatabaseRole - Model representing a YSQL or YCQL role
+type DatabaseRole struct {
+
+    Name string `json:"name"`
+
+    Type YbApiEnum `json:"type"`
+
+    IsSuperuser bool `json:"is_superuser"`
+
+    CanLogin bool `json:"can_login"`
+
+    // Whether the role can create other roles (YSQL only)
+    CanCreateRole bool `json:"can_create_role"`
+
+    // Whether the role can create databases (YSQL only)
+    CanCreateDb bool `json:"can_create_db"`
+
+    // Maximum number of concurrent connections, -1 means no limit (YSQL only)
+    ConnectionLimit int32 `json:"connection_limit"`
+
+    // Roles that this role is a member of
+    MemberOf []string `json:"member_of"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type DatabaseRoleListResponse struct {
+
+    Data []DatabaseRole `json:"data"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type DatabaseRoleResponse struct {
+
+    Data DatabaseRole `json:"data"`
+}
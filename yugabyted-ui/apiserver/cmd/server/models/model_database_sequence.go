@@ -0,0 +1,36 @@
+package models
+
+// DatabaseSequence - Model representing a YSQL sequence
+type DatabaseSequence struct {
+
+    Schema string `json:"schema"`
+
+    Name string `json:"name"`
+
+    Owner string `json:"owner"`
+
+    DataType string `json:"data_type"`
+
+    StartValue int64 `json:"start_value"`
+
+    MinValue int64 `json:"min_value"`
+
+    MaxValue int64 `json:"max_value"`
+
+    IncrementBy int64 `json:"increment_by"`
+
+    // Whether the sequence wraps around when it reaches its limit
+    Cycle bool `json:"cycle"`
+
+    // Number of values each session preallocates from the sequence
+    CacheSize int64 `json:"cache_size"`
+
+    // Last value handed out by the sequence, or null if it has not been used yet
+    LastValue *int64 `json:"last_value"`
+
+    // Percentage of the range of the sequence that has been used
+    PercentUsed float64 `json:"percent_used"`
+
+    // Whether the sequence is close to exhausting its range and does not cycle
+    IsNearOverflow bool `json:"is_near_overflow"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type DatabaseSequenceListResponse struct {
+
+    Data []DatabaseSequence `json:"data"`
+}
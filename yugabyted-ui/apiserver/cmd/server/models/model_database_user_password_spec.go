@@ -0,0 +1,13 @@
+package models
+
+// DatabaseUserPasswordSpec - New password for a YSQL or YCQL role
+type DatabaseUserPasswordSpec struct {
+
+    Api YbApiEnum `json:"api"`
+
+    // The new password for the role
+    Password string `json:"password"`
+
+    // Mark the new password as already expired, so that it must be reset before the role can log in with it (YSQL only)
+    ExpirePassword bool `json:"expire_password"`
+}
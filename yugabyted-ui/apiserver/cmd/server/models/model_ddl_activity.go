@@ -0,0 +1,37 @@
+package models
+
+// DdlActivity - A DDL statement that ran on a node, or a bump of the version of the YSQL catalog
+type DdlActivity struct {
+
+    // Id of the entry, which sorts by time
+    Id string `json:"id"`
+
+    // statement or catalog_version
+    Kind string `json:"kind"`
+
+    // UNIX timestamp of the poll the activity was detected at
+    Time int64 `json:"time"`
+
+    // Host of the node the statement ran on, missing for catalog version bumps
+    Node string `json:"node,omitempty"`
+
+    // Database the statement ran in
+    Database string `json:"database,omitempty"`
+
+    // User who ran the statement
+    User string `json:"user,omitempty"`
+
+    // The statement, as pg_stat_statements tracked it
+    Query string `json:"query,omitempty"`
+
+    // Number of times the statement ran since the previous poll
+    Calls int64 `json:"calls,omitempty"`
+
+    // Catalog version at the previous poll, for catalog version bumps
+    PreviousCatalogVersion *int64 `json:"previous_catalog_version,omitempty"`
+
+    // Catalog version at this poll, for catalog version bumps
+    CatalogVersion *int64 `json:"catalog_version,omitempty"`
+
+    Message string `json:"message"`
+}
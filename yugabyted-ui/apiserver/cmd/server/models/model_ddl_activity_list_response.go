@@ -0,0 +1,9 @@
+package models
+
+type DdlActivityListResponse struct {
+
+    Data []DdlActivity `json:"data"`
+
+    // Cursor to get the next page of the activity with, empty on the last page
+    NextCursor string `json:"next_cursor"`
+}
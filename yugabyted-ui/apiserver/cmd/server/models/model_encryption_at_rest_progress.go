@@ -0,0 +1,26 @@
+package models
+
+// EncryptionAtRestProgress - How far the data written before encryption was enabled has been
+// encrypted, by compacting the tables one by one
+type EncryptionAtRestProgress struct {
+
+    // Task that enables encryption and compacts the tables
+    TaskId string `json:"task_id"`
+
+    KeyId string `json:"key_id"`
+
+    TablesTotal int32 `json:"tables_total"`
+
+    TablesEncrypted int32 `json:"tables_encrypted"`
+
+    // Size of the tables when the task started
+    BytesTotal int64 `json:"bytes_total"`
+
+    BytesEncrypted int64 `json:"bytes_encrypted"`
+
+    // UNIX timestamp of when the task started
+    StartTimestamp int64 `json:"start_timestamp"`
+
+    // UNIX timestamp of when the task finished, null while it runs
+    EndTimestamp *int64 `json:"end_timestamp"`
+}
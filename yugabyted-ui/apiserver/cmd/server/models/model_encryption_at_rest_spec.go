@@ -0,0 +1,21 @@
+package models
+
+// EncryptionAtRestSpec - Key to encrypt the data of the cluster with, given either inline or as
+// a file on this host
+type EncryptionAtRestSpec struct {
+
+    // Id to register the key under, generated if empty
+    KeyId string `json:"key_id"`
+
+    // Base64 encoded AES key of 16, 24 or 32 bytes
+    Key string `json:"key"`
+
+    // Absolute path of a file on this host holding the raw key, e.g. written by a KMS agent
+    KeyFile string `json:"key_file"`
+
+    // Whether to compact every table so that the data written before is encrypted as well
+    EncryptExistingData bool `json:"encrypt_existing_data"`
+
+    // Token returned by the previous request for the same action
+    ConfirmationToken string `json:"confirmation_token"`
+}
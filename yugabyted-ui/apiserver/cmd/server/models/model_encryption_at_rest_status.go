@@ -0,0 +1,17 @@
+package models
+
+// EncryptionAtRestStatus - Whether the data of the cluster is encrypted, and with which key
+type EncryptionAtRestStatus struct {
+
+    Enabled bool `json:"enabled"`
+
+    // Id of the key that new data is encrypted with, empty if encryption is disabled
+    KeyId string `json:"key_id"`
+
+    // Whether the masters hold the key in memory. Masters forget the key when they restart,
+    // and it must be added again.
+    KeyInMemory bool `json:"key_in_memory"`
+
+    // Progress of the last enablement run by this server, null if there was none
+    Progress *EncryptionAtRestProgress `json:"progress"`
+}
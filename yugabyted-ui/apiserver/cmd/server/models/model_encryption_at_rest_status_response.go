@@ -0,0 +1,6 @@
+package models
+
+type EncryptionAtRestStatusResponse struct {
+
+    Data EncryptionAtRestStatus `json:"data"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// FlameGraphNode - A frame of a flame graph
+type FlameGraphNode struct {
+
+    // Name of the function
+    Name string `json:"name"`
+
+    // Number of samples of the stacks that go through the frame
+    Value int64 `json:"value"`
+
+    // Frames called from this one, sorted by name
+    Children []FlameGraphNode `json:"children"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type FlameGraphResponse struct {
+
+    Data FlameGraphNode `json:"data"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// GflagDoc - The documentation of a gflag
+type GflagDoc struct {
+
+    Name string `json:"name"`
+
+    // master or tserver
+    ServerType string `json:"server_type"`
+
+    Description string `json:"description"`
+
+    // Type of the value, e.g. bool, int32 or string
+    Type string `json:"type"`
+
+    DefaultValue string `json:"default_value"`
+
+    // e.g. runtime, advanced, auto or stable
+    Tags []string `json:"tags"`
+
+    // How a change of the flag takes effect: runtime, restart_required or auto_flag
+    Impact string `json:"impact"`
+
+    // Version of the binary the documentation was read from
+    Version string `json:"version"`
+
+    // Version the cluster runs. The documentation may not match it if it differs from version.
+    RunningVersion string `json:"running_version"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type GflagDocResponse struct {
+
+    Data GflagDoc `json:"data"`
+}
@@ -2,7 +2,7 @@ package models
 
 type HealthCheckInfo struct {
 
-    // UUIDs of dead nodes
+    // UUIDs of dead nodes, leaving out those in an active maintenance window
     DeadNodes []string `json:"dead_nodes"`
 
     MostRecentUptime int64 `json:"most_recent_uptime"`
@@ -12,4 +12,11 @@ type HealthCheckInfo struct {
 
     // UUIDs of leaderless tablets
     LeaderlessTablets []string `json:"leaderless_tablets"`
+
+    // Whether the cluster is in an active maintenance window
+    UnderMaintenance bool `json:"under_maintenance"`
+
+    // UUIDs of the dead nodes in an active maintenance window, whose failure is expected and
+    // which are not counted in dead_nodes
+    DeadNodesUnderMaintenance []string `json:"dead_nodes_under_maintenance"`
 }
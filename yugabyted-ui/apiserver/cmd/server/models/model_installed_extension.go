@@ -0,0 +1,9 @@
+package models
+
+// InstalledExtension - An extension created in a YSQL database
+type InstalledExtension struct {
+
+    Name string `json:"name"`
+
+    Version string `json:"version"`
+}
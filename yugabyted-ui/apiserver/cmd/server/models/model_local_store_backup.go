@@ -0,0 +1,14 @@
+package models
+
+// LocalStoreBackup - Contents of the local store, by bucket and key
+type LocalStoreBackup struct {
+
+    // Latest migration applied to the store
+    SchemaVersion int32 `json:"schema_version"`
+
+    // Unix timestamp the backup was taken at
+    CreatedAt int64 `json:"created_at"`
+
+    // Values of each bucket by key, base64 encoded
+    Buckets map[string]map[string]string `json:"buckets"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// LoginSpec - Local credentials of a user
+type LoginSpec struct {
+
+    Username string `json:"username"`
+
+    Password string `json:"password"`
+}
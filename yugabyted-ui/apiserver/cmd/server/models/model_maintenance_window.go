@@ -0,0 +1,31 @@
+package models
+
+// MaintenanceWindow - A time range during which a node, a zone or the cluster is under
+// maintenance
+type MaintenanceWindow struct {
+
+    Id string `json:"id"`
+
+    // cluster, zone or node
+    Scope string `json:"scope"`
+
+    // cloud.region.zone for a zone, the host for a node, and empty for the cluster
+    Target string `json:"target"`
+
+    // UNIX timestamp of when the window starts
+    StartTime int64 `json:"start_time"`
+
+    // UNIX timestamp of when the window ends
+    EndTime int64 `json:"end_time"`
+
+    Reason string `json:"reason"`
+
+    // User whose session created the window, empty if sessions were disabled
+    CreatedBy string `json:"created_by"`
+
+    // UNIX timestamp of when the window was created
+    CreatedAt int64 `json:"created_at"`
+
+    // Whether the window has started
+    Active bool `json:"active"`
+}
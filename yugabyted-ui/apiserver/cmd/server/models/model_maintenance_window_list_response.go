@@ -0,0 +1,6 @@
+package models
+
+type MaintenanceWindowListResponse struct {
+
+    Data []MaintenanceWindow `json:"data"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type MaintenanceWindowResponse struct {
+
+    Data MaintenanceWindow `json:"data"`
+}
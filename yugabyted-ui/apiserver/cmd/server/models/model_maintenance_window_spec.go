@@ -0,0 +1,19 @@
+package models
+
+// MaintenanceWindowSpec - A maintenance window to schedule
+type MaintenanceWindowSpec struct {
+
+    // cluster, zone or node
+    Scope string `json:"scope"`
+
+    // cloud.region.zone for a zone, the host for a node, and empty for the cluster
+    Target string `json:"target"`
+
+    // UNIX timestamp of when the window starts
+    StartTime int64 `json:"start_time"`
+
+    // UNIX timestamp of when the window ends, which must be in the future
+    EndTime int64 `json:"end_time"`
+
+    Reason string `json:"reason"`
+}
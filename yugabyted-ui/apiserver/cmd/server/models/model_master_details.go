@@ -0,0 +1,15 @@
+package models
+
+// MasterDetails - Flags and metrics of every master of the cluster
+type MasterDetails struct {
+
+    // Masters of the cluster, the leader first
+    Masters []MasterDetailsServer `json:"masters"`
+
+    // UUID of the leader master, empty if there is none
+    LeaderUuid string `json:"leader_uuid"`
+
+    Flags []MasterFlag `json:"flags"`
+
+    Metrics []MasterMetric `json:"metrics"`
+}
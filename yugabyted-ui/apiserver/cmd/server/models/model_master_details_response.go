@@ -0,0 +1,6 @@
+package models
+
+type MasterDetailsResponse struct {
+
+    Data MasterDetails `json:"data"`
+}
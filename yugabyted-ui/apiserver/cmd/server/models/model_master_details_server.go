@@ -0,0 +1,21 @@
+package models
+
+// MasterDetailsServer - A master and whether its flags and metrics could be fetched
+type MasterDetailsServer struct {
+
+    Uuid string `json:"uuid"`
+
+    // Host of the master's RPC address
+    Host string `json:"host"`
+
+    // LEADER, FOLLOWER, or empty if unknown
+    Role string `json:"role"`
+
+    IsLeader bool `json:"is_leader"`
+
+    // UNIX timestamp in microseconds of when the master started
+    StartTimeUs int64 `json:"start_time_us"`
+
+    // Why the flags or metrics of the master could not be fetched
+    Error *string `json:"error,omitempty"`
+}
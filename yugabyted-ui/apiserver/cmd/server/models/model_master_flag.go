@@ -0,0 +1,20 @@
+package models
+
+// MasterFlag - A flag and its value on each master
+type MasterFlag struct {
+
+    Name string `json:"name"`
+
+    // Where the value comes from on the leader: Default, Custom, NodeInfo or Auto
+    Type string `json:"type"`
+
+    // Value of the flag by master UUID
+    Values map[string]string `json:"values"`
+
+    // Whether every master has the same value
+    IsUniform bool `json:"is_uniform"`
+
+    // How a change of the flag takes effect: runtime, restart_required, auto_flag, or unknown
+    // without the documentation of the flags
+    Impact string `json:"impact"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// MasterMetric - A server metric and its value on each master
+type MasterMetric struct {
+
+    Name string `json:"name"`
+
+    // Value of the metric by master UUID
+    Values map[string]float64 `json:"values"`
+}
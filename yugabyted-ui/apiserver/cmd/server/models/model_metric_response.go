@@ -9,4 +9,7 @@ type MetricResponse struct {
 
     // End of range of results
     EndTimestamp int64 `json:"end_timestamp"`
+
+    // raw, or the interval the samples were rolled up over for wide ranges
+    Resolution string `json:"resolution"`
 }
@@ -0,0 +1,27 @@
+package models
+
+// MetricsCleanup - The latest cleanup of the samples of the metrics table
+type MetricsCleanup struct {
+
+    // disabled if metrics.retention is 0, pending until the first cleanup, running, succeeded
+    // or failed
+    State string `json:"state"`
+
+    // UNIX timestamp of when the cleanup started
+    StartedAt int64 `json:"started_at"`
+
+    // UNIX timestamp of when the cleanup ended, 0 if it is still running
+    FinishedAt int64 `json:"finished_at"`
+
+    // UNIX timestamp of the time the samples were deleted up to
+    Cutoff int64 `json:"cutoff"`
+
+    // Number of nodes whose old samples were deleted
+    NodesCleaned int32 `json:"nodes_cleaned"`
+
+    // UNIX timestamp of when the next cleanup runs, 0 if cleanups are disabled
+    NextRunAt int64 `json:"next_run_at"`
+
+    // Why the cleanup failed
+    Error string `json:"error"`
+}
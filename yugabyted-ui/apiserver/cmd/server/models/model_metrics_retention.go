@@ -0,0 +1,26 @@
+package models
+
+// MetricsRetention - How long the metrics are kept, and an estimate of the space they take
+type MetricsRetention struct {
+
+    // How long the samples of the metrics table are kept, 0 if they are not deleted
+    RetentionSeconds int64 `json:"retention_seconds"`
+
+    CleanupIntervalSeconds int64 `json:"cleanup_interval_seconds"`
+
+    // How long the samples scraped into memory are kept
+    FallbackRetentionSeconds int64 `json:"fallback_retention_seconds"`
+
+    Cleanup MetricsCleanup `json:"cleanup"`
+
+    // Size of the metrics table as listed by the master, 0 if the master does not list it
+    TableSizeBytes int64 `json:"table_size_bytes"`
+
+    // Size of the tables of the samples rolled up over 5 minutes and over an hour
+    DownsampledSizeBytes int64 `json:"downsampled_size_bytes"`
+
+    // Memory taken by the samples scraped into memory, 0 if metrics.fallback is off
+    FallbackSizeBytes int64 `json:"fallback_size_bytes"`
+
+    EstimatedSizeBytes int64 `json:"estimated_size_bytes"`
+}
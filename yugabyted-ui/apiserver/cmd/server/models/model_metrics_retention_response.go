@@ -0,0 +1,6 @@
+package models
+
+type MetricsRetentionResponse struct {
+
+    Data MetricsRetention `json:"data"`
+}
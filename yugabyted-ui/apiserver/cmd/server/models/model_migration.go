@@ -0,0 +1,37 @@
+package models
+
+// Migration - A migration to YugabyteDB run with YugabyteDB Voyager, as of its latest run
+type Migration struct {
+
+    MigrationUuid string `json:"migration_uuid"`
+
+    // Database that is migrated
+    DatabaseName string `json:"database_name"`
+
+    // Schemas that are migrated
+    SchemaName string `json:"schema_name"`
+
+    // postgresql, mysql or oracle
+    SourceDbType string `json:"source_db_type"`
+
+    SourceDbVersion string `json:"source_db_version"`
+
+    SourceDbHost string `json:"source_db_host"`
+
+    SourceDbPort int32 `json:"source_db_port"`
+
+    // Command of the latest run, e.g. export-data
+    Phase string `json:"phase"`
+
+    // Number of the latest run
+    InvocationSequence int32 `json:"invocation_sequence"`
+
+    // Status of the latest run, e.g. IN PROGRESS, COMPLETED or ERROR
+    Status string `json:"status"`
+
+    // UNIX timestamp of the first run
+    StartedAt int64 `json:"started_at"`
+
+    // UNIX timestamp of the latest run
+    UpdatedAt int64 `json:"updated_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// MigrationAssessment - How hard migrating the source database is, and the cluster to migrate it to
+type MigrationAssessment struct {
+
+    VoyagerVersion string `json:"voyager_version"`
+
+    // LOW, MEDIUM or HIGH
+    Complexity string `json:"complexity"`
+
+    Objects []MigrationObjectSummary `json:"objects"`
+
+    UnsupportedFeatures []MigrationUnsupportedFeature `json:"unsupported_features"`
+
+    UnsupportedDataTypes []MigrationUnsupportedDataType `json:"unsupported_data_types"`
+
+    // Missing if Voyager could not size the cluster
+    Sizing *MigrationSizing `json:"sizing,omitempty"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type MigrationAssessmentResponse struct {
+
+    Data MigrationAssessment `json:"data"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type MigrationListResponse struct {
+
+    Data []Migration `json:"data"`
+}
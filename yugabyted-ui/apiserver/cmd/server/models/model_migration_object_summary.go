@@ -0,0 +1,13 @@
+package models
+
+// MigrationObjectSummary - How many objects of a type the source database has
+type MigrationObjectSummary struct {
+
+    // e.g. TABLE, INDEX or FUNCTION
+    ObjectType string `json:"object_type"`
+
+    TotalCount int32 `json:"total_count"`
+
+    // Number of the objects that cannot be migrated as is
+    InvalidCount int32 `json:"invalid_count"`
+}
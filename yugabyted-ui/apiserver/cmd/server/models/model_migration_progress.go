@@ -0,0 +1,25 @@
+package models
+
+// MigrationProgress - How much of the data of a migration has been imported
+type MigrationProgress struct {
+
+    MigrationUuid string `json:"migration_uuid"`
+
+    // UNIX timestamp of when the import of the data started, absent if it has not
+    StartedAt *int64 `json:"started_at,omitempty"`
+
+    RowsImported int64 `json:"rows_imported"`
+
+    TotalRows int64 `json:"total_rows"`
+
+    // Number of rows that failed to import, not reported by older versions of Voyager
+    ErrorRows *int64 `json:"error_rows,omitempty"`
+
+    // Average number of rows imported per second since the import started
+    RowsPerSecond float64 `json:"rows_per_second"`
+
+    // Estimated number of seconds until all the rows are imported, absent if unknown
+    EtaSeconds *int64 `json:"eta_seconds,omitempty"`
+
+    Tables []MigrationTableProgress `json:"tables"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type MigrationProgressResponse struct {
+
+    Data MigrationProgress `json:"data"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// MigrationSchemaAnalysis - The issues found in the schema exported from the source database
+type MigrationSchemaAnalysis struct {
+
+    VoyagerVersion string `json:"voyager_version"`
+
+    Objects []MigrationObjectSummary `json:"objects"`
+
+    Issues []MigrationSchemaIssue `json:"issues"`
+}
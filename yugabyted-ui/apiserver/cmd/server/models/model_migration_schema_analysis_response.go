@@ -0,0 +1,6 @@
+package models
+
+type MigrationSchemaAnalysisResponse struct {
+
+    Data MigrationSchemaAnalysis `json:"data"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// MigrationSchemaIssue - An object of the exported schema that needs changes for YugabyteDB
+type MigrationSchemaIssue struct {
+
+    // e.g. unsupported_features or migration_caveats
+    IssueType string `json:"issue_type"`
+
+    ObjectType string `json:"object_type"`
+
+    ObjectName string `json:"object_name"`
+
+    Reason string `json:"reason"`
+
+    SqlStatement string `json:"sql_statement"`
+
+    // How to work around the issue
+    Suggestion string `json:"suggestion"`
+
+    // Link to the GitHub issue tracking support for it
+    GithubIssue string `json:"github_issue"`
+}
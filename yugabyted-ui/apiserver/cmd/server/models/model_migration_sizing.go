@@ -0,0 +1,25 @@
+package models
+
+// MigrationSizing - Cluster recommended for the migrated database
+type MigrationSizing struct {
+
+    NumNodes int32 `json:"num_nodes"`
+
+    VcpusPerInstance int32 `json:"vcpus_per_instance"`
+
+    MemoryPerInstanceGb int32 `json:"memory_per_instance_gb"`
+
+    // Tables that are small enough to be colocated
+    ColocatedTables []string `json:"colocated_tables"`
+
+    ShardedTables []string `json:"sharded_tables"`
+
+    // How long importing the data is expected to take
+    EstimatedImportMinutes float64 `json:"estimated_import_minutes"`
+
+    // Number of parallel jobs recommended for importing the data
+    ParallelImportJobs int32 `json:"parallel_import_jobs"`
+
+    // Why no sizing could be recommended, empty if one was
+    FailureReasoning string `json:"failure_reasoning"`
+}
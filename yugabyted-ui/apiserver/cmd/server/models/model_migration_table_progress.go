@@ -0,0 +1,23 @@
+package models
+
+// MigrationTableProgress - How much of the data of a table has been imported
+type MigrationTableProgress struct {
+
+    SchemaName string `json:"schema_name"`
+
+    TableName string `json:"table_name"`
+
+    // e.g. NOT STARTED, IN PROGRESS or DONE
+    Status string `json:"status"`
+
+    RowsImported int64 `json:"rows_imported"`
+
+    // Number of rows of the table in the source database, as estimated by Voyager
+    TotalRows int64 `json:"total_rows"`
+
+    // Number of rows that failed to import, not reported by older versions of Voyager
+    ErrorRows *int64 `json:"error_rows,omitempty"`
+
+    // UNIX timestamp of the latest update of the progress
+    UpdatedAt int64 `json:"updated_at"`
+}
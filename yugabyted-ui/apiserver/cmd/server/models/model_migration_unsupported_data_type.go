@@ -0,0 +1,13 @@
+package models
+
+// MigrationUnsupportedDataType - A column of a type that YugabyteDB does not support
+type MigrationUnsupportedDataType struct {
+
+    SchemaName string `json:"schema_name"`
+
+    TableName string `json:"table_name"`
+
+    ColumnName string `json:"column_name"`
+
+    DataType string `json:"data_type"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// MigrationUnsupportedFeature - A feature of the source database that YugabyteDB does not support
+type MigrationUnsupportedFeature struct {
+
+    FeatureName string `json:"feature_name"`
+
+    // Objects that use the feature
+    Objects []string `json:"objects"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// NodeApiHealth - The health of the YSQL and YCQL layers of a node
+type NodeApiHealth struct {
+
+    // Host of the node
+    Node string `json:"node"`
+
+    // Status of the tserver, e.g. ALIVE or DEAD
+    Status string `json:"status"`
+
+    // Region of the node
+    Region string `json:"region"`
+
+    Ysql ApiLayerHealth `json:"ysql"`
+
+    Ycql ApiLayerHealth `json:"ycql"`
+}
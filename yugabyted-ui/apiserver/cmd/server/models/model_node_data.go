@@ -16,4 +16,12 @@ type NodeData struct {
     CloudInfo NodeDataCloudInfo `json:"cloud_info"`
 
     SoftwareVersion string `json:"software_version"`
+
+    // Whether the node is in an active maintenance window, either of its own, of its zone or of
+    // the cluster
+    UnderMaintenance bool `json:"under_maintenance"`
+
+    // UNIX timestamp of when the last of the active windows that cover the node ends, 0 if it
+    // is not in maintenance
+    MaintenanceUntil int64 `json:"maintenance_until"`
 }
@@ -11,4 +11,16 @@ type NodeDataMetrics struct {
     ReadOpsPerSec float64 `json:"read_ops_per_sec"`
 
     WriteOpsPerSec float64 `json:"write_ops_per_sec"`
+
+    // SELECT statements per second run through YSQL
+    YsqlReadOpsPerSec float64 `json:"ysql_read_ops_per_sec"`
+
+    // INSERT, UPDATE and DELETE statements per second run through YSQL
+    YsqlWriteOpsPerSec float64 `json:"ysql_write_ops_per_sec"`
+
+    // SELECT statements per second run through YCQL
+    YcqlReadOpsPerSec float64 `json:"ycql_read_ops_per_sec"`
+
+    // INSERT, UPDATE and DELETE statements per second run through YCQL
+    YcqlWriteOpsPerSec float64 `json:"ycql_write_ops_per_sec"`
 }
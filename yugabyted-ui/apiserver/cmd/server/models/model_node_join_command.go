@@ -0,0 +1,17 @@
+package models
+
+// NodeJoinCommand - Commands that add a new node to the cluster
+type NodeJoinCommand struct {
+
+    // Address of the node in the cluster that the new node joins through
+    JoinAddress string `json:"join_address"`
+
+    // Whether the cluster uses encryption in transit and authentication
+    IsSecure bool `json:"is_secure"`
+
+    // Commands to run before starting the new node, to give it certificates signed by the cluster root CA
+    CertSetupCommands []string `json:"cert_setup_commands"`
+
+    // Command to run on the new node to start it and join the cluster
+    Command string `json:"command"`
+}
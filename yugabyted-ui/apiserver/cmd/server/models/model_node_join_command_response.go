@@ -0,0 +1,6 @@
+package models
+
+type NodeJoinCommandResponse struct {
+
+    Data NodeJoinCommand `json:"data"`
+}
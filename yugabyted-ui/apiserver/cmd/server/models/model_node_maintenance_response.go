@@ -0,0 +1,6 @@
+package models
+
+type NodeMaintenanceResponse struct {
+
+    Data NodeMaintenance `json:"data"`
+}
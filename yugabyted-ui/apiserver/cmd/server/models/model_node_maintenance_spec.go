@@ -0,0 +1,14 @@
+package models
+
+// NodeMaintenanceSpec - Whether to put a node in maintenance or to take it out
+type NodeMaintenanceSpec struct {
+
+    // true to put the node in maintenance, false to take it out
+    Enabled bool `json:"enabled"`
+
+    // How long the node stays in maintenance before it is taken out on its own, required when
+    // enabled
+    DurationMinutes int32 `json:"duration_minutes"`
+
+    Reason string `json:"reason"`
+}
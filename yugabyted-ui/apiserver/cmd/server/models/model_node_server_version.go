@@ -0,0 +1,35 @@
+package models
+
+// NodeServerVersion - The version and build of a server process of a node
+type NodeServerVersion struct {
+
+    // master or tserver
+    Process string `json:"process"`
+
+    // The version number along with the build number, e.g. 2.20.1.0-b97
+    Version string `json:"version"`
+
+    VersionNumber string `json:"version_number"`
+
+    BuildNumber string `json:"build_number"`
+
+    // Hash of the commit the server was built from
+    GitHash string `json:"git_hash"`
+
+    BuildTimestamp string `json:"build_timestamp"`
+
+    // e.g. release or debug
+    BuildType string `json:"build_type"`
+
+    BuildId string `json:"build_id"`
+
+    BuildHostname string `json:"build_hostname"`
+
+    BuildUsername string `json:"build_username"`
+
+    // Whether the server was built from a clean checkout
+    BuildCleanRepo bool `json:"build_clean_repo"`
+
+    // Why the version could not be read, empty if it was
+    Error string `json:"error"`
+}
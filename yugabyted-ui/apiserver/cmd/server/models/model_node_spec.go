@@ -0,0 +1,14 @@
+package models
+
+// NodeSpec - New node to start on this host
+type NodeSpec struct {
+
+    // Address of the new node, which must belong to this host
+    AdvertiseAddress string `json:"advertise_address"`
+
+    // Cloud location of the new node, as cloud.region.zone
+    CloudLocation string `json:"cloud_location"`
+
+    // yugabyted base directory of the new node
+    BaseDir string `json:"base_dir"`
+}
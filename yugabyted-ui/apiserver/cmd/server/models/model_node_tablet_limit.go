@@ -0,0 +1,28 @@
+package models
+
+// NodeTabletLimit - The tablet replicas of a node next to the limit recommended for it
+type NodeTabletLimit struct {
+
+    Name string `json:"name"`
+
+    // Cores of the host, null if node_exporter cannot be reached
+    CpuCores *int32 `json:"cpu_cores"`
+
+    // Memory of the host, null if node_exporter cannot be reached
+    MemoryBytes *int64 `json:"memory_bytes"`
+
+    // Replicas of user and system tablets on the node
+    TabletReplicas int64 `json:"tablet_replicas"`
+
+    TabletLeaders int64 `json:"tablet_leaders"`
+
+    // Most tablet replicas recommended for the cores and memory of the node, null if they are
+    // unknown
+    RecommendedLimit *int64 `json:"recommended_limit"`
+
+    // Tablet replicas as a percentage of the recommended limit, null if it is unknown
+    UtilizationPercent *float64 `json:"utilization_percent"`
+
+    // Whether the node hosts more tablet replicas than recommended
+    Overloaded bool `json:"overloaded"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// NodeVersion - The versions and builds of the server processes of a node
+type NodeVersion struct {
+
+    Name string `json:"name"`
+
+    // Machine architecture of the node, e.g. x86_64, empty if node_exporter cannot be reached
+    Architecture string `json:"architecture"`
+
+    // The master, if the node runs one, then the tserver
+    Servers []NodeServerVersion `json:"servers"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// NodeVersionResponse - The versions and builds of the server processes of a node
+type NodeVersionResponse struct {
+
+    Data NodeVersion `json:"data"`
+}
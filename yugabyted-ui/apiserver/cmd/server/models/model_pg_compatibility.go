@@ -0,0 +1,22 @@
+package models
+
+// PgCompatibility - How compatible the cluster is with PostgreSQL
+type PgCompatibility struct {
+
+    // Server version reported by YSQL, e.g. 11.2-YB-2.20.1.0-b0
+    ServerVersion string `json:"server_version"`
+
+    // PostgreSQL version the cluster is wire and feature compatible with, e.g. 11.2
+    PgVersion string `json:"pg_version"`
+
+    // YugabyteDB version of the node this server is connected to
+    YbVersion string `json:"yb_version"`
+
+    // Database the extensions were read from
+    Database string `json:"database"`
+
+    Extensions []InstalledExtension `json:"extensions"`
+
+    // PostgreSQL features that the version of the cluster does not support
+    UnsupportedFeatures []UnsupportedPgFeature `json:"unsupported_features"`
+}
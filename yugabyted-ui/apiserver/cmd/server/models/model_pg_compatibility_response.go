@@ -0,0 +1,6 @@
+package models
+
+type PgCompatibilityResponse struct {
+
+    Data PgCompatibility `json:"data"`
+}
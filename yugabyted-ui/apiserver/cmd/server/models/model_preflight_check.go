@@ -0,0 +1,11 @@
+package models
+
+// PreflightCheck - Result of a single preflight check
+type PreflightCheck struct {
+
+    Name string `json:"name"`
+
+    Status PreflightCheckStatusEnum `json:"status"`
+
+    Detail string `json:"detail"`
+}
@@ -0,0 +1,11 @@
+package models
+// PreflightCheckStatusEnum : Result of a preflight check
+type PreflightCheckStatusEnum string
+
+// List of PreflightCheckStatusEnum
+const (
+    PREFLIGHTCHECKSTATUSENUM_PASS PreflightCheckStatusEnum = "PASS"
+    PREFLIGHTCHECKSTATUSENUM_WARN PreflightCheckStatusEnum = "WARN"
+    PREFLIGHTCHECKSTATUSENUM_FAIL PreflightCheckStatusEnum = "FAIL"
+    PREFLIGHTCHECKSTATUSENUM_SKIPPED PreflightCheckStatusEnum = "SKIPPED"
+)
@@ -0,0 +1,12 @@
+package models
+
+// PreflightReport - Results of the preflight checks against a host
+type PreflightReport struct {
+
+    Host string `json:"host"`
+
+    // Whether none of the checks failed
+    Passed bool `json:"passed"`
+
+    Checks []PreflightCheck `json:"checks"`
+}
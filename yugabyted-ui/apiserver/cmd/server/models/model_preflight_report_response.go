@@ -0,0 +1,6 @@
+package models
+
+type PreflightReportResponse struct {
+
+    Data PreflightReport `json:"data"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// PreflightSpec - Host to run preflight checks against
+type PreflightSpec struct {
+
+    // Address of the prospective node
+    Host string `json:"host"`
+
+    // Port on which node_exporter listens on the host
+    NodeExporterPort int32 `json:"node_exporter_port"`
+}
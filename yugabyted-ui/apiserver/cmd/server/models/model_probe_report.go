@@ -0,0 +1,19 @@
+package models
+
+// ProbeReport - The results of the synthetic probes over a time range
+type ProbeReport struct {
+
+    // UNIX timestamp of the start of the range
+    From int64 `json:"from"`
+
+    // UNIX timestamp of the end of the range
+    To int64 `json:"to"`
+
+    Overall ProbeSummary `json:"overall"`
+
+    // Summary of each node, sorted by node
+    Nodes []ProbeSummary `json:"nodes"`
+
+    // The latest results, newest first
+    Results []ProbeResult `json:"results"`
+}
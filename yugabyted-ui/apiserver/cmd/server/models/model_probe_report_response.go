@@ -0,0 +1,6 @@
+package models
+
+type ProbeReportResponse struct {
+
+    Data ProbeReport `json:"data"`
+}
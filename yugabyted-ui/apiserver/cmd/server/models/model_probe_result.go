@@ -0,0 +1,19 @@
+package models
+
+// ProbeResult - A write and read back of a row through a node
+type ProbeResult struct {
+
+    // Host of the node
+    Node string `json:"node"`
+
+    // UNIX timestamp of when the probe started
+    Time int64 `json:"time"`
+
+    Succeeded bool `json:"succeeded"`
+
+    // Milliseconds the probe took, including connecting to the node
+    LatencyMs float64 `json:"latency_ms"`
+
+    // Why the probe failed
+    Error *string `json:"error,omitempty"`
+}
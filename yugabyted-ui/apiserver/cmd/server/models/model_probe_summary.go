@@ -0,0 +1,29 @@
+package models
+
+// ProbeSummary - The results of the probes of a node, or of every node
+type ProbeSummary struct {
+
+    // Host of the node, missing for the summary of every node
+    Node string `json:"node,omitempty"`
+
+    // Number of probes
+    Probes int64 `json:"probes"`
+
+    // Number of probes that succeeded
+    Succeeded int64 `json:"succeeded"`
+
+    // Fraction of the probes that succeeded, 0 if there were none
+    SuccessRate float64 `json:"success_rate"`
+
+    // Average latency of the probes that succeeded, missing if none did
+    AvgLatencyMs *float64 `json:"avg_latency_ms,omitempty"`
+
+    // 99th percentile latency of the probes that succeeded, missing if none did
+    P99LatencyMs *float64 `json:"p99_latency_ms,omitempty"`
+
+    // UNIX timestamp of the latest probe, missing if there were none
+    LastProbedAt *int64 `json:"last_probed_at,omitempty"`
+
+    // Error of the latest probe that failed
+    LastError *string `json:"last_error,omitempty"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// ProcessActionSpec - Confirmation of an action on a server process
+type ProcessActionSpec struct {
+
+    // Token returned by the previous request for the same action
+    ConfirmationToken string `json:"confirmation_token"`
+}
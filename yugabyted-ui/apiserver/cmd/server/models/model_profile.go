@@ -0,0 +1,30 @@
+package models
+
+// Profile - A profile collected from a master or tserver
+type Profile struct {
+
+    // ID of the profile, the ID of the task that collected it
+    Id string `json:"id"`
+
+    // Host of the master or tserver
+    Node string `json:"node"`
+
+    // master or tserver
+    Process string `json:"process"`
+
+    // cpu or heap
+    Kind string `json:"kind"`
+
+    // How long the CPU profile was sampled for, 0 for heap profiles
+    Seconds int32 `json:"seconds"`
+
+    // Size of the profile in bytes
+    SizeBytes int64 `json:"size_bytes"`
+
+    // Whether a flame graph can be made of the profile. Only CPU profiles whose functions could
+    // be named have one.
+    HasFlameGraph bool `json:"has_flame_graph"`
+
+    // Unix timestamp of when the profile was collected
+    Timestamp int64 `json:"timestamp"`
+}
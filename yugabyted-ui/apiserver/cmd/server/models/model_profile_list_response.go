@@ -0,0 +1,6 @@
+package models
+
+type ProfileListResponse struct {
+
+    Data []Profile `json:"data"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// ProfileSpec - Profile to collect
+type ProfileSpec struct {
+
+    // Address of the node
+    Node string `json:"node"`
+
+    // master or tserver
+    Process string `json:"process"`
+
+    // cpu or heap
+    Kind string `json:"kind"`
+
+    // How long to sample a CPU profile for
+    Seconds int32 `json:"seconds"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// ReplicationTraffic - Bytes replicated from one region or zone to another
+type ReplicationTraffic struct {
+
+    // raft for the replication between the replicas of the tablets, xcluster for the changes
+    // sent to xCluster consumers
+    Kind string `json:"kind"`
+
+    SourceCloud string `json:"source_cloud"`
+
+    SourceRegion string `json:"source_region"`
+
+    // Empty when the traffic is given by region
+    SourceZone string `json:"source_zone"`
+
+    // Empty for xcluster traffic, which leaves the cluster
+    TargetCloud string `json:"target_cloud"`
+
+    // Empty for xcluster traffic, which leaves the cluster
+    TargetRegion string `json:"target_region"`
+
+    // Empty when the traffic is given by region, and for xcluster traffic
+    TargetZone string `json:"target_zone"`
+
+    // Bytes per second at the latest scrape
+    BytesPerSec float64 `json:"bytes_per_sec"`
+
+    // Estimated bytes replicated over the range
+    Bytes int64 `json:"bytes"`
+
+    // Array of (timestamp, bytes per second) tuples
+    Values [][]float64 `json:"values"`
+}
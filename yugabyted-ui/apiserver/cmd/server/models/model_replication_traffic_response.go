@@ -0,0 +1,12 @@
+package models
+
+type ReplicationTrafficResponse struct {
+
+    Data []ReplicationTraffic `json:"data"`
+
+    // Start of range of results
+    StartTimestamp int64 `json:"start_timestamp"`
+
+    // End of range of results
+    EndTimestamp int64 `json:"end_timestamp"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// Report - Summary of the cluster over a period
+type Report struct {
+
+    Id string `json:"id"`
+
+    Kind ReportKindEnum `json:"kind"`
+
+    // UNIX timestamp of the start of the period
+    StartTimestamp int64 `json:"start_timestamp"`
+
+    // UNIX timestamp of the end of the period
+    EndTimestamp int64 `json:"end_timestamp"`
+
+    // UNIX timestamp at which the report was produced
+    GeneratedTimestamp int64 `json:"generated_timestamp"`
+
+    // Why the report could not be produced, empty unless it failed
+    Error string `json:"error"`
+
+    // Set for HEALTH reports
+    Health *ReportHealth `json:"health"`
+
+    // Set for TOP_QUERIES reports. The stats are cumulative since they were last reset, not
+    // limited to the period.
+    TopQueries *[]SlowQueryResponseYsqlQueryItem `json:"top_queries"`
+
+    // Set for CAPACITY_TREND reports
+    CapacityTrend *ReportCapacityTrend `json:"capacity_trend"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// ReportCapacityTrend - Disk usage of a node over a report period
+type ReportCapacityTrend struct {
+
+    DiskSizeGb float64 `json:"disk_size_gb"`
+
+    StartDiskUsedGb float64 `json:"start_disk_used_gb"`
+
+    EndDiskUsedGb float64 `json:"end_disk_used_gb"`
+
+    GrowthGbPerDay float64 `json:"growth_gb_per_day"`
+
+    // Days until the disk is full at the growth of the period, null if usage is not growing
+    DaysUntilFull *float64 `json:"days_until_full"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// ReportHealth - Health of the cluster at the end of a report period
+type ReportHealth struct {
+
+    NumNodes int32 `json:"num_nodes"`
+
+    // UUIDs of dead nodes
+    DeadNodes []string `json:"dead_nodes"`
+
+    NumUnderReplicatedTablets int32 `json:"num_under_replicated_tablets"`
+
+    NumMasters int32 `json:"num_masters"`
+
+    NumUnreachableMasters int32 `json:"num_unreachable_masters"`
+
+    // Smallest YugabyteDB version of the nodes
+    SoftwareVersion string `json:"software_version"`
+
+    // Average number of live nodes over the period, null if there are no metrics for it
+    AverageLiveNodes *float64 `json:"average_live_nodes"`
+}
@@ -0,0 +1,10 @@
+package models
+// ReportKindEnum : What a report summarizes
+type ReportKindEnum string
+
+// List of ReportKindEnum
+const (
+    REPORTKINDENUM_HEALTH ReportKindEnum = "HEALTH"
+    REPORTKINDENUM_TOP_QUERIES ReportKindEnum = "TOP_QUERIES"
+    REPORTKINDENUM_CAPACITY_TREND ReportKindEnum = "CAPACITY_TREND"
+)
@@ -0,0 +1,6 @@
+package models
+
+type ReportListResponse struct {
+
+    Data []Report `json:"data"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type ReportResponse struct {
+
+    Data Report `json:"data"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// ResponseCacheRouteStats - Lookups of the cached responses of a route
+type ResponseCacheRouteStats struct {
+
+    // Path of the route, as registered with the server
+    Path string `json:"path"`
+
+    // How long the responses of the route are cached, in milliseconds
+    TtlMs int64 `json:"ttl_ms"`
+
+    Hits int64 `json:"hits"`
+
+    Misses int64 `json:"misses"`
+
+    // Lookups skipped because the client asked for a fresh response
+    Bypasses int64 `json:"bypasses"`
+
+    // Fraction of the lookups that were served from the cache
+    HitRate float64 `json:"hit_rate"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// ResponseCacheStats - Usage of the response cache of the API server
+type ResponseCacheStats struct {
+
+    Enabled bool `json:"enabled"`
+
+    // Number of responses in the cache
+    Entries int32 `json:"entries"`
+
+    Hits int64 `json:"hits"`
+
+    Misses int64 `json:"misses"`
+
+    Bypasses int64 `json:"bypasses"`
+
+    // Fraction of the lookups that were served from the cache
+    HitRate float64 `json:"hit_rate"`
+
+    Routes []ResponseCacheRouteStats `json:"routes"`
+}
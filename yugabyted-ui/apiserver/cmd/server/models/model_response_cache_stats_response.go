@@ -0,0 +1,6 @@
+package models
+
+type ResponseCacheStatsResponse struct {
+
+    Data ResponseCacheStats `json:"data"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// RestoreObject - A database, table or index that a restore would change
+type RestoreObject struct {
+
+    Id string `json:"id"`
+
+    // database, table or index
+    Kind string `json:"kind"`
+
+    // YSQL or YCQL
+    Api string `json:"api"`
+
+    // Database or keyspace of the object, its own name for a database
+    Database string `json:"database"`
+
+    Name string `json:"name"`
+
+    // overwrite if the object exists now, restore if it does not, or drop if it exists now but
+    // not in the snapshot of a point in time restore
+    Action string `json:"action"`
+
+    // Current size of the object, 0 if it does not exist now
+    SizeBytes int64 `json:"size_bytes"`
+}
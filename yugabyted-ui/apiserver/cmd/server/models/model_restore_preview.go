@@ -0,0 +1,25 @@
+package models
+
+// RestorePreview - What restoring a snapshot, or a snapshot schedule to a point in time, would
+// do
+type RestorePreview struct {
+
+    // Snapshot that would be restored, for a point in time restore the latest snapshot of the
+    // schedule taken at or before the restore time
+    SnapshotId string `json:"snapshot_id"`
+
+    // Snapshot schedule of a point in time restore, empty for the restore of a snapshot
+    ScheduleId string `json:"schedule_id"`
+
+    // UNIX timestamp of the point in time to restore to, 0 for the restore of a snapshot
+    RestoreTime int64 `json:"restore_time"`
+
+    // UNIX timestamp of when the snapshot of a point in time restore was taken, which the
+    // objects are listed as of, 0 for the restore of a snapshot
+    SnapshotTime int64 `json:"snapshot_time"`
+
+    Objects []RestoreObject `json:"objects"`
+
+    // Current size of the tables and indexes that would be overwritten or dropped
+    EstimatedSizeBytes int64 `json:"estimated_size_bytes"`
+}
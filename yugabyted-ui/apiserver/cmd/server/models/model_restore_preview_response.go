@@ -0,0 +1,6 @@
+package models
+
+type RestorePreviewResponse struct {
+
+    Data RestorePreview `json:"data"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// RpcCall - An RPC in progress on a master or tserver
+type RpcCall struct {
+
+    // Host of the master or tserver
+    Node string `json:"node"`
+
+    // master or tserver
+    Process string `json:"process"`
+
+    // inbound for calls that the process serves, outbound for calls that it made
+    Direction string `json:"direction"`
+
+    // Address of the other end of the connection
+    RemoteAddress string `json:"remote_address"`
+
+    ConnectionState string `json:"connection_state"`
+
+    ServiceName string `json:"service_name"`
+
+    MethodName string `json:"method_name"`
+
+    CallId int64 `json:"call_id"`
+
+    // How long the call has been in progress
+    ElapsedMillis int64 `json:"elapsed_millis"`
+
+    // Timeout of the call, 0 if it has none
+    TimeoutMillis int64 `json:"timeout_millis"`
+
+    // State of the call, only known for outbound calls
+    State string `json:"state"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// RpcMethodSummary - The calls in progress to an RPC method
+type RpcMethodSummary struct {
+
+    ServiceName string `json:"service_name"`
+
+    MethodName string `json:"method_name"`
+
+    // Number of calls in progress
+    Count int32 `json:"count"`
+
+    // How long the oldest call has been in progress
+    MaxElapsedMillis int64 `json:"max_elapsed_millis"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// Rpcz - RPCs in progress, longest running first
+type Rpcz struct {
+
+    Calls []RpcCall `json:"calls"`
+
+    // Calls grouped by method, the methods with the most calls first
+    Methods []RpcMethodSummary `json:"methods"`
+
+    // Number of processes whose calls could not be fetched
+    ErrorCount int32 `json:"error_count"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type RpczResponse struct {
+
+    Data Rpcz `json:"data"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// SampleDataSpec - Sample dataset to load, and the database to load it into
+type SampleDataSpec struct {
+
+    // Name of the dataset
+    Dataset string `json:"dataset"`
+
+    // Name of the YSQL database, which is created if it does not exist
+    Database string `json:"database"`
+}
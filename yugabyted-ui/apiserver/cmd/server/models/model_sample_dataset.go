@@ -0,0 +1,15 @@
+package models
+
+// SampleDataset - A sample dataset that can be loaded into a database
+type SampleDataset struct {
+
+    Name string `json:"name"`
+
+    Description string `json:"description"`
+
+    // Files of the dataset in the share directory of the release, run in order
+    Files []string `json:"files"`
+
+    // Whether every file of the dataset was found
+    Available bool `json:"available"`
+}
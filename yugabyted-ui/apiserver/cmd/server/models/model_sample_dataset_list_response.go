@@ -0,0 +1,6 @@
+package models
+
+type SampleDatasetListResponse struct {
+
+    Data []SampleDataset `json:"data"`
+}
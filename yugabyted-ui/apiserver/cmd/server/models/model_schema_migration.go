@@ -0,0 +1,25 @@
+package models
+
+// SchemaMigration - A migration applied by a schema migration tool
+type SchemaMigration struct {
+
+    // Version of the migration, or its id for Liquibase. Empty for repeatable Flyway migrations.
+    Version string `json:"version"`
+
+    Description string `json:"description"`
+
+    // Script or changelog file of the migration
+    Script string `json:"script"`
+
+    // Database user who applied the migration
+    AppliedBy string `json:"applied_by"`
+
+    // UNIX timestamp of when the migration was applied, missing if the tool does not record it
+    AppliedAt *int64 `json:"applied_at,omitempty"`
+
+    // How long the migration took, missing if the tool does not record it
+    ExecutionTimeMs *int64 `json:"execution_time_ms,omitempty"`
+
+    // Whether the migration succeeded, missing if the tool does not record it
+    Success *bool `json:"success,omitempty"`
+}
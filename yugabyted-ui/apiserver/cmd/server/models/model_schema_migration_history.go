@@ -0,0 +1,25 @@
+package models
+
+// SchemaMigrationHistory - The history table of a schema migration tool in a database
+type SchemaMigrationHistory struct {
+
+    Database string `json:"database"`
+
+    Schema string `json:"schema"`
+
+    Table string `json:"table"`
+
+    // flyway, liquibase, or schema_migrations for the tools that keep that table, e.g. Rails,
+    // golang-migrate and Ecto
+    Tool string `json:"tool"`
+
+    // Number of migrations in the table
+    Total int64 `json:"total"`
+
+    // UNIX timestamp of when the latest migration listed was applied, missing if the tool does
+    // not record it
+    LastAppliedAt *int64 `json:"last_applied_at,omitempty"`
+
+    // The latest migrations, newest first
+    Migrations []SchemaMigration `json:"migrations"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// SchemaMigrations - The history tables of the schema migration tools in the databases
+type SchemaMigrations struct {
+
+    // Sorted by database, schema and table
+    Histories []SchemaMigrationHistory `json:"histories"`
+
+    // Databases whose tables could not be read, e.g. as the user of the server cannot connect
+    UnreadableDatabases []UnreadableDatabase `json:"unreadable_databases"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type SchemaMigrationsResponse struct {
+
+    Data SchemaMigrations `json:"data"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Session - The session of a browser user
+type Session struct {
+
+    Username string `json:"username"`
+
+    // UNIX timestamp of the login
+    CreatedAt int64 `json:"created_at"`
+
+    // UNIX timestamp of when the session ends even if it is in use
+    ExpiresAt int64 `json:"expires_at"`
+
+    // How long the session can stay unused before it ends, in seconds
+    IdleTimeoutSeconds int64 `json:"idle_timeout_seconds"`
+}
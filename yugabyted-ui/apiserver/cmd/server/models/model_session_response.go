@@ -0,0 +1,6 @@
+package models
+
+type SessionResponse struct {
+
+    Data Session `json:"data"`
+}
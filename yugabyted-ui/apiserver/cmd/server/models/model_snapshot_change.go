@@ -0,0 +1,19 @@
+package models
+
+// SnapshotChange - A value that differs between two cluster snapshots
+type SnapshotChange struct {
+
+    Section SnapshotSectionEnum `json:"section"`
+
+    // Host of the node the value belongs to, empty for values of the whole cluster
+    Node string `json:"node"`
+
+    // Name of the value, e.g. the name of a gflag
+    Key string `json:"key"`
+
+    // Value in the base snapshot, null if it is absent there
+    Before *string `json:"before"`
+
+    // Value in the target snapshot, null if it is absent there
+    After *string `json:"after"`
+}
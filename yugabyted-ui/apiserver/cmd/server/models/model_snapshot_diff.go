@@ -0,0 +1,16 @@
+package models
+
+// SnapshotDiff - What changed between two cluster snapshots
+type SnapshotDiff struct {
+
+    // UNIX timestamp of the base snapshot
+    BaseTimestamp int64 `json:"base_timestamp"`
+
+    // UNIX timestamp of the target snapshot
+    TargetTimestamp int64 `json:"target_timestamp"`
+
+    // Whether the target is the live cluster rather than an exported snapshot
+    TargetIsLive bool `json:"target_is_live"`
+
+    Changes []SnapshotChange `json:"changes"`
+}
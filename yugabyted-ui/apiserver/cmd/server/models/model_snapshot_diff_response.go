@@ -0,0 +1,6 @@
+package models
+
+type SnapshotDiffResponse struct {
+
+    Data SnapshotDiff `json:"data"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// SnapshotDiffSpec - Two cluster snapshots to compare, bare or as exported with their data
+// field
+type SnapshotDiffSpec struct {
+
+    // The data of an exported cluster snapshot
+    Base ClusterSnapshot `json:"base"`
+
+    // The data of another exported cluster snapshot, or null to compare with the live cluster
+    Target *ClusterSnapshot `json:"target"`
+}
@@ -0,0 +1,12 @@
+package models
+// SnapshotSectionEnum : Part of a cluster snapshot
+type SnapshotSectionEnum string
+
+// List of SnapshotSectionEnum
+const (
+    SNAPSHOTSECTIONENUM_TOPOLOGY SnapshotSectionEnum = "TOPOLOGY"
+    SNAPSHOTSECTIONENUM_PLACEMENT SnapshotSectionEnum = "PLACEMENT"
+    SNAPSHOTSECTIONENUM_VERSIONS SnapshotSectionEnum = "VERSIONS"
+    SNAPSHOTSECTIONENUM_TSERVER_GFLAGS SnapshotSectionEnum = "TSERVER_GFLAGS"
+    SNAPSHOTSECTIONENUM_MASTER_GFLAGS SnapshotSectionEnum = "MASTER_GFLAGS"
+)
@@ -0,0 +1,16 @@
+package models
+
+// TableDdl - Statements that recreate a table or index
+type TableDdl struct {
+
+    Uuid string `json:"uuid"`
+
+    Name string `json:"name"`
+
+    Keyspace string `json:"keyspace"`
+
+    Type YbApiEnum `json:"type"`
+
+    // CREATE statements for the table and its indexes
+    Statements []string `json:"statements"`
+}
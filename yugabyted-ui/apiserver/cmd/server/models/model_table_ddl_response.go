@@ -0,0 +1,6 @@
+package models
+
+type TableDdlResponse struct {
+
+    Data TableDdl `json:"data"`
+}
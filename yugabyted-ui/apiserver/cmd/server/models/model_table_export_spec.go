@@ -0,0 +1,19 @@
+package models
+
+// TableExportSpec - Rows of a table to export
+type TableExportSpec struct {
+
+    // Columns to export, in order. All columns if empty.
+    Columns []string `json:"columns"`
+
+    // Filter on the rows, as terms of the form column operator value, or column IS [NOT] NULL,
+    // joined by AND. Operators are =, !=, <>, <, <=, > and >=, and values are single quoted
+    // strings, numbers, true or false.
+    Where string `json:"where"`
+
+    // Largest number of rows to export, the configured maximum if null
+    Limit *int64 `json:"limit"`
+
+    // csv or tsv
+    Format string `json:"format"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// TabletLimits - Tablet replicas per node against recommended limits, and the room left
+type TabletLimits struct {
+
+    ReplicationFactor int32 `json:"replication_factor"`
+
+    // Tablet replicas on all the nodes
+    TabletReplicas int64 `json:"tablet_replicas"`
+
+    // Nodes that host more tablet replicas than recommended
+    OverloadedNodes int32 `json:"overloaded_nodes"`
+
+    // Estimated tablets that can still be created before the nodes reach their limits, null
+    // if the limit of a node is unknown
+    RemainingTablets *int64 `json:"remaining_tablets"`
+
+    // Average tablets of the user tables and indexes, null if there are none
+    TabletsPerTable *float64 `json:"tablets_per_table"`
+
+    // Estimated tables that can still be created with tablets_per_table tablets each, null if
+    // remaining_tablets or tablets_per_table is
+    RemainingTables *int64 `json:"remaining_tables"`
+
+    Nodes []NodeTabletLimit `json:"nodes"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type TabletLimitsResponse struct {
+
+    Data TabletLimits `json:"data"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// Task - Model representing a long running operation
+type Task struct {
+
+    Id string `json:"id"`
+
+    Name string `json:"name"`
+
+    State TaskStateEnum `json:"state"`
+
+    // Progress messages of the task, oldest first
+    Steps []string `json:"steps"`
+
+    // Why the task failed, empty unless it failed
+    Error string `json:"error"`
+
+    // UNIX timestamp at which the task started
+    StartTimestamp int64 `json:"start_timestamp"`
+
+    // UNIX timestamp at which the task finished, or null if it is running
+    EndTimestamp *int64 `json:"end_timestamp"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type TaskListResponse struct {
+
+    Data []Task `json:"data"`
+}
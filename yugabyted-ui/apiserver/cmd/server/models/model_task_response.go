@@ -0,0 +1,6 @@
+package models
+
+type TaskResponse struct {
+
+    Data Task `json:"data"`
+}
@@ -0,0 +1,10 @@
+package models
+// TaskStateEnum : State of a task
+type TaskStateEnum string
+
+// List of TaskStateEnum
+const (
+    TASKSTATEENUM_RUNNING TaskStateEnum = "RUNNING"
+    TASKSTATEENUM_SUCCEEDED TaskStateEnum = "SUCCEEDED"
+    TASKSTATEENUM_FAILED TaskStateEnum = "FAILED"
+)
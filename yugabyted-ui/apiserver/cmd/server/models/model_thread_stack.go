@@ -0,0 +1,17 @@
+package models
+
+// ThreadStack - Threads that have the same stack
+type ThreadStack struct {
+
+    // Stack of the threads, empty for the threads whose stack was not captured
+    Stack string `json:"stack"`
+
+    // Number of threads with the stack
+    Count int32 `json:"count"`
+
+    // Names of the threads, sorted
+    ThreadNames []string `json:"thread_names"`
+
+    // Nodes the threads run on, sorted
+    Nodes []string `json:"nodes"`
+}
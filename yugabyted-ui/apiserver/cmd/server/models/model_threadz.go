@@ -0,0 +1,14 @@
+package models
+
+// Threadz - Threads of masters and tservers grouped by stack
+type Threadz struct {
+
+    // Stacks, the ones shared by the most threads first
+    Stacks []ThreadStack `json:"stacks"`
+
+    // Number of threads
+    NumThreads int32 `json:"num_threads"`
+
+    // Number of processes whose threads could not be fetched
+    ErrorCount int32 `json:"error_count"`
+}
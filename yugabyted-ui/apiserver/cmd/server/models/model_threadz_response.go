@@ -0,0 +1,6 @@
+package models
+
+type ThreadzResponse struct {
+
+    Data Threadz `json:"data"`
+}
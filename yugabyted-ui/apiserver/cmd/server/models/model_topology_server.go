@@ -0,0 +1,26 @@
+package models
+
+// TopologyServer - Model representing a YSQL server as seen by smart drivers
+type TopologyServer struct {
+
+    Host string `json:"host"`
+
+    Port int64 `json:"port"`
+
+    // Number of client connections to the server
+    NumConnections int64 `json:"num_connections"`
+
+    // Whether the server is in a primary or read replica cluster
+    NodeType string `json:"node_type"`
+
+    Cloud string `json:"cloud"`
+
+    Region string `json:"region"`
+
+    Zone string `json:"zone"`
+
+    PublicIp string `json:"public_ip"`
+
+    // UUID of the tablet server
+    Uuid string `json:"uuid"`
+}
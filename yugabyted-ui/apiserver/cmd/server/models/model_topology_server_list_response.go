@@ -0,0 +1,6 @@
+package models
+
+type TopologyServerListResponse struct {
+
+    Data []TopologyServer `json:"data"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// UnreadableDatabase - A database that could not be read
+type UnreadableDatabase struct {
+
+    Database string `json:"database"`
+
+    Error string `json:"error"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// UnsupportedPgFeature - A PostgreSQL feature that the cluster does not support
+type UnsupportedPgFeature struct {
+
+    Name string `json:"name"`
+
+    Description string `json:"description"`
+
+    // YugabyteDB version that supports the feature, empty if none does yet
+    SupportedFrom string `json:"supported_from"`
+}
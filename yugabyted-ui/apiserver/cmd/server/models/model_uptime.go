@@ -0,0 +1,11 @@
+package models
+
+// Uptime - The measured availability of the APIs over the last day, week and month
+type Uptime struct {
+
+    // UNIX timestamp of the oldest health check kept, missing if there are none. Windows that
+    // start before it are only measured from it.
+    SampledSince *int64 `json:"sampled_since,omitempty"`
+
+    Windows []UptimeWindow `json:"windows"`
+}
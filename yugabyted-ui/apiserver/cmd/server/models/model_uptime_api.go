@@ -0,0 +1,17 @@
+package models
+
+// UptimeApi - The availability of an API over a window
+type UptimeApi struct {
+
+    // The API, ysql or ycql
+    Api string `json:"api"`
+
+    // Number of checks of the API on the nodes
+    Checks int64 `json:"checks"`
+
+    // Number of checks that succeeded
+    Succeeded int64 `json:"succeeded"`
+
+    // Percentage of the checks that succeeded, missing if there were none
+    AvailabilityPercent *float64 `json:"availability_percent,omitempty"`
+}
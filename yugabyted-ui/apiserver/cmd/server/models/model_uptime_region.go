@@ -0,0 +1,9 @@
+package models
+
+// UptimeRegion - The availability of the APIs on the nodes of a region over a window
+type UptimeRegion struct {
+
+    Region string `json:"region"`
+
+    Apis []UptimeApi `json:"apis"`
+}
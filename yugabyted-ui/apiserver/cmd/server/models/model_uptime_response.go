@@ -0,0 +1,6 @@
+package models
+
+type UptimeResponse struct {
+
+    Data Uptime `json:"data"`
+}
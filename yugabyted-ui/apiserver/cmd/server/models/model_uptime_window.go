@@ -0,0 +1,20 @@
+package models
+
+// UptimeWindow - The availability of the APIs over a window
+type UptimeWindow struct {
+
+    // Name of the window, 24h, 7d or 30d
+    Window string `json:"window"`
+
+    // UNIX timestamp of the start of the window
+    From int64 `json:"from"`
+
+    // UNIX timestamp of the end of the window
+    To int64 `json:"to"`
+
+    // Availability of each API on every node
+    Apis []UptimeApi `json:"apis"`
+
+    // Availability of each API by region, sorted by region
+    Regions []UptimeRegion `json:"regions"`
+}
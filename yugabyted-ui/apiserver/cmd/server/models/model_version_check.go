@@ -0,0 +1,17 @@
+package models
+
+// VersionCheck - The versions of the nodes and the problems found with them
+type VersionCheck struct {
+
+    // Version of each alive node with its build number, by host
+    Versions map[string]string `json:"versions"`
+
+    // Severe findings first
+    Findings []VersionFinding `json:"findings"`
+
+    // bundled, or the URL of the fetched release manifest
+    ManifestSource string `json:"manifest_source"`
+
+    // UNIX timestamp of when the release manifest was fetched, missing for the bundled one
+    ManifestFetchedAt *int64 `json:"manifest_fetched_at,omitempty"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type VersionCheckResponse struct {
+
+    Data VersionCheck `json:"data"`
+}
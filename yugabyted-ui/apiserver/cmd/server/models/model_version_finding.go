@@ -0,0 +1,20 @@
+package models
+
+// VersionFinding - A problem found by checking the versions of the nodes against the release
+// manifest
+type VersionFinding struct {
+
+    // mixed_versions, critical_build or end_of_life
+    Kind string `json:"kind"`
+
+    // warning, or severe for critical builds
+    Severity string `json:"severity"`
+
+    // Version with its build number, every version of the nodes for mixed_versions
+    Version string `json:"version"`
+
+    // Hosts of the nodes running the version
+    Nodes []string `json:"nodes"`
+
+    Message string `json:"message"`
+}
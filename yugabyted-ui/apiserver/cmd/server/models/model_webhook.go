@@ -0,0 +1,23 @@
+package models
+
+// Webhook - A URL the cluster events of some types are posted to. Its secret is not returned.
+type Webhook struct {
+
+    Id string `json:"id"`
+
+    Url string `json:"url"`
+
+    EventTypes []string `json:"event_types"`
+
+    // User who created the webhook, empty when authentication is off
+    CreatedBy string `json:"created_by"`
+
+    // UNIX timestamp of the creation of the webhook
+    CreatedAt int64 `json:"created_at"`
+
+    LastDelivery *WebhookDelivery `json:"last_delivery,omitempty"`
+
+    // Number of events that were not posted as the queue of the webhook was full, since the
+    // server started
+    DroppedEvents int64 `json:"dropped_events"`
+}
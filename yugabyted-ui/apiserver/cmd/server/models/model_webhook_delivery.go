@@ -0,0 +1,22 @@
+package models
+
+// WebhookDelivery - The latest posting of an event to a webhook
+type WebhookDelivery struct {
+
+    // ID of the cluster event
+    EventId string `json:"event_id"`
+
+    // UNIX timestamp of the last attempt
+    AttemptedAt int64 `json:"attempted_at"`
+
+    Attempts int32 `json:"attempts"`
+
+    // Status code of the last response, 0 if the webhook could not be reached
+    StatusCode int32 `json:"status_code"`
+
+    // Whether the webhook accepted the event with a 2xx response
+    Succeeded bool `json:"succeeded"`
+
+    // Why the last attempt failed
+    Error string `json:"error"`
+}
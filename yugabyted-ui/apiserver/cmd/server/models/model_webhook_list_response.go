@@ -0,0 +1,6 @@
+package models
+
+type WebhookListResponse struct {
+
+    Data []Webhook `json:"data"`
+}
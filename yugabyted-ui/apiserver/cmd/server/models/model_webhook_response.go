@@ -0,0 +1,6 @@
+package models
+
+type WebhookResponse struct {
+
+    Data Webhook `json:"data"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// WebhookSpec - Webhook to create, or to replace a webhook with
+type WebhookSpec struct {
+
+    // http or https URL the events are posted to
+    Url string `json:"url"`
+
+    // Key the payloads are signed with, kept when a webhook is replaced without one
+    Secret string `json:"secret"`
+
+    // Types of the cluster events posted to the webhook
+    EventTypes []string `json:"event_types"`
+}
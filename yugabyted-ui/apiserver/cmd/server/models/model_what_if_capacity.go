@@ -0,0 +1,23 @@
+package models
+
+// WhatIfCapacity - The capacity left after a failure, and what it has to take over
+type WhatIfCapacity struct {
+
+    // Nodes that are not down after the failure
+    NodesLeft int32 `json:"nodes_left"`
+
+    // Disk space of the nodes left
+    TotalDiskBytes int64 `json:"total_disk_bytes"`
+
+    // Free disk space of the nodes left
+    FreeDiskBytes int64 `json:"free_disk_bytes"`
+
+    // Replicas on the nodes that are down, of the tablets that keep quorum
+    ReplicasToReReplicate int64 `json:"replicas_to_re_replicate"`
+
+    // Size of the SST files on the nodes that are down, which the nodes left have to take over
+    BytesToReReplicate int64 `json:"bytes_to_re_replicate"`
+
+    // Whether the free disk space of the nodes left fits the data to re-replicate
+    FitsOnDisk bool `json:"fits_on_disk"`
+}
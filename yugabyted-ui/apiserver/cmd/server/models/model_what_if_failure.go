@@ -0,0 +1,40 @@
+package models
+
+// WhatIfFailure - The expected impact of a failure on the current placement
+type WhatIfFailure struct {
+
+    FailureType string `json:"failure_type"`
+
+    // Hosts of the nodes that the failure takes down
+    FailedNodes []string `json:"failed_nodes"`
+
+    // Hosts of the nodes that are down already, which stay down
+    DownNodes []string `json:"down_nodes"`
+
+    Tablets int64 `json:"tablets"`
+
+    // Tablets left with half of their voters or fewer, which become unavailable
+    TabletsLosingQuorum int64 `json:"tablets_losing_quorum"`
+
+    // Tablets that keep quorum but lose replicas
+    TabletsUnderReplicated int64 `json:"tablets_under_replicated"`
+
+    // Tablets that keep quorum but have their leader down, which elect a new leader
+    LeadersToMove int64 `json:"leaders_to_move"`
+
+    // Tables with tablets losing quorum
+    TablesLosingQuorum []WhatIfTable `json:"tables_losing_quorum"`
+
+    Nodes []WhatIfNode `json:"nodes"`
+
+    Masters int32 `json:"masters"`
+
+    // Masters that are reachable and not taken down by the failure
+    MastersLeft int32 `json:"masters_left"`
+
+    // Whether the masters left are half of the masters or fewer, which stops DDL, leader
+    // elections of new tablets and failover of the tablets
+    MasterQuorumLost bool `json:"master_quorum_lost"`
+
+    Capacity WhatIfCapacity `json:"capacity"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type WhatIfFailureResponse struct {
+
+    Data WhatIfFailure `json:"data"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// WhatIfFailureSpec - A hypothetical failure of a node, a zone or a region
+type WhatIfFailureSpec struct {
+
+    // NODE, ZONE or REGION
+    FailureType string `json:"failure_type"`
+
+    // Host of the node that fails, for NODE failures
+    Node string `json:"node"`
+
+    // Cloud of the zone or region that fails, any cloud if empty
+    Cloud string `json:"cloud"`
+
+    // Region that fails, or the region of the zone that fails
+    Region string `json:"region"`
+
+    // Zone that fails, for ZONE failures
+    Zone string `json:"zone"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// WhatIfNode - A node along with its tablet leaders before and after a failure
+type WhatIfNode struct {
+
+    Host string `json:"host"`
+
+    // ALIVE or DEAD, as reported by the master leader before the failure
+    Status string `json:"status"`
+
+    Cloud string `json:"cloud"`
+
+    Region string `json:"region"`
+
+    Zone string `json:"zone"`
+
+    // Whether the failure takes the node down
+    Fails bool `json:"fails"`
+
+    // Tablet replicas on the node
+    Replicas int64 `json:"replicas"`
+
+    // Tablet leaders on the node before the failure
+    Leaders int64 `json:"leaders"`
+
+    // Tablet leaders expected on the node after the failure, counting the leaders that move to
+    // it by their chance to
+    ExpectedLeaders float64 `json:"expected_leaders"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// WhatIfTable - A table with tablets that lose quorum in a failure
+type WhatIfTable struct {
+
+    Namespace string `json:"namespace"`
+
+    TableName string `json:"table_name"`
+
+    TableUuid string `json:"table_uuid"`
+
+    // Tablets of the table that lose quorum
+    TabletIds []string `json:"tablet_ids"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// Workload - A running sample workload, with the counts of its operations so far
+type Workload struct {
+
+    // ID of the task that runs the workload, which it is stopped with
+    TaskId string `json:"task_id"`
+
+    // key_value or sql
+    Workload string `json:"workload"`
+
+    Threads int32 `json:"threads"`
+
+    DurationSeconds int64 `json:"duration_seconds"`
+
+    ReadPercent int32 `json:"read_percent"`
+
+    // User who started the workload, empty when authentication is off
+    StartedBy string `json:"started_by"`
+
+    // UNIX timestamp of when the workload started
+    StartedAt int64 `json:"started_at"`
+
+    // Number of operations run so far
+    Operations int64 `json:"operations"`
+
+    // Number of operations that failed
+    Failures int64 `json:"failures"`
+
+    // Operations per second since the workload started
+    OpsPerSecond float64 `json:"ops_per_second"`
+
+    // Average latency of the operations, missing if none ran
+    AvgLatencyMs *float64 `json:"avg_latency_ms,omitempty"`
+
+    // Error of the latest operation that failed
+    LastError *string `json:"last_error,omitempty"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type WorkloadListResponse struct {
+
+    Data []Workload `json:"data"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// WorkloadSpec - Sample workload to run against the cluster
+type WorkloadSpec struct {
+
+    // key_value to read and write a YCQL table by key, or sql to read and write a YSQL table by
+    // primary key
+    Workload string `json:"workload"`
+
+    // Number of threads, each running one operation at a time
+    Threads int32 `json:"threads"`
+
+    // How long the workload runs unless it is stopped
+    DurationSeconds int64 `json:"duration_seconds"`
+
+    // Percentage of the operations that are reads, 0 to only write
+    ReadPercent int32 `json:"read_percent"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// YbAdminCommand - A yb-admin command that can be run from the UI
+type YbAdminCommand struct {
+
+    // Name of the command
+    Name string `json:"name"`
+
+    // Arguments of the command, with the optional ones in brackets
+    Usage string `json:"usage"`
+
+    Description string `json:"description"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type YbAdminCommandListResponse struct {
+
+    Data []YbAdminCommand `json:"data"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// YbAdminOutput - Output of a yb-admin command
+type YbAdminOutput struct {
+
+    Command string `json:"command"`
+
+    Args []string `json:"args"`
+
+    // Output of the command as printed
+    Output string `json:"output"`
+
+    // Header of the output of commands that print a table
+    Columns *[]string `json:"columns,omitempty"`
+
+    // Rows of the output of commands that print a table
+    Rows *[][]string `json:"rows,omitempty"`
+
+    // Output of commands that print a JSON document, parsed
+    Json interface{} `json:"json,omitempty"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type YbAdminOutputResponse struct {
+
+    Data YbAdminOutput `json:"data"`
+}
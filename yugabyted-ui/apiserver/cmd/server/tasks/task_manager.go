@@ -0,0 +1,212 @@
+package tasks
+
+import (
+    "apiserver/cmd/server/helpers"
+    "apiserver/cmd/server/localstore"
+    "apiserver/cmd/server/logger"
+    "apiserver/cmd/server/models"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+)
+
+// Number of finished tasks that are kept around for clients to look up
+const MAX_FINISHED_TASKS = 100
+
+// Bucket of the local store the tasks are kept in, by id
+const STORE_BUCKET = "tasks"
+
+// Error of the tasks that were running when the server stopped
+const INTERRUPTED_TASK_ERROR = "interrupted by a restart of the server"
+
+type Task struct {
+    mutex sync.Mutex
+    info models.Task
+    logger logger.Logger
+}
+
+// Records a progress message for the task
+func (task *Task) Progress(format string, args ...interface{}) {
+    task.logger.Infof(format, args...)
+    task.mutex.Lock()
+    defer task.mutex.Unlock()
+    task.info.Steps = append(task.info.Steps, fmt.Sprintf(format, args...))
+}
+
+// Gets a copy of the current state of the task
+func (task *Task) Info() models.Task {
+    task.mutex.Lock()
+    defer task.mutex.Unlock()
+    info := task.info
+    info.Steps = append([]string{}, task.info.Steps...)
+    return info
+}
+
+func (task *Task) finish(err error) {
+    task.mutex.Lock()
+    defer task.mutex.Unlock()
+    endTimestamp := time.Now().Unix()
+    task.info.EndTimestamp = &endTimestamp
+    if err != nil {
+        task.info.State = models.TASKSTATEENUM_FAILED
+        task.info.Error = err.Error()
+        task.logger.Errorf("task failed: %s", err.Error())
+    } else {
+        task.info.State = models.TASKSTATEENUM_SUCCEEDED
+        task.logger.Infof("task succeeded")
+    }
+}
+
+func (task *Task) isFinished() bool {
+    task.mutex.Lock()
+    defer task.mutex.Unlock()
+    return task.info.State != models.TASKSTATEENUM_RUNNING
+}
+
+// TaskManager runs long running operations in the background and keeps track of their progress.
+// The tasks are kept in the local store when they start and finish, so that they can still be
+// looked up after a restart.
+type TaskManager struct {
+    mutex sync.Mutex
+    tasks map[string]*Task
+    local localstore.Store
+    logger logger.Logger
+}
+
+func NewTaskManager(log logger.Logger, local localstore.Store) *TaskManager {
+    manager := &TaskManager{
+        tasks: map[string]*Task{},
+        local: local,
+        logger: log,
+    }
+    manager.Load()
+    return manager
+}
+
+// Reads the tasks from the local store, keeping the tasks that run in this server. Tasks that
+// are marked as running in the store but not here were interrupted, and are marked as failed.
+func (manager *TaskManager) Load() {
+    entries, err := manager.local.List(STORE_BUCKET)
+    if err != nil {
+        manager.logger.Errorf("failed to read the tasks: %s", err.Error())
+        return
+    }
+    manager.mutex.Lock()
+    defer manager.mutex.Unlock()
+    tasks := map[string]*Task{}
+    for id, task := range manager.tasks {
+        if !task.isFinished() {
+            tasks[id] = task
+        }
+    }
+    for _, entry := range entries {
+        info := models.Task{}
+        if err := json.Unmarshal(entry.Value, &info); err != nil {
+            manager.logger.Errorf("failed to read task %s: %s", entry.Key, err.Error())
+            continue
+        }
+        if _, ok := tasks[info.Id]; ok {
+            continue
+        }
+        task := &Task{
+            info: info,
+            logger: manager.logger.With("task_id", info.Id, "task", info.Name),
+        }
+        if info.State == models.TASKSTATEENUM_RUNNING {
+            task.finish(fmt.Errorf(INTERRUPTED_TASK_ERROR))
+            manager.save(task)
+        }
+        tasks[info.Id] = task
+    }
+    manager.tasks = tasks
+    manager.pruneFinishedTasks()
+}
+
+// Writes the current state of the task to the local store
+func (manager *TaskManager) save(task *Task) {
+    info := task.Info()
+    data, err := json.Marshal(info)
+    if err == nil {
+        err = manager.local.Put(STORE_BUCKET, info.Id, data)
+    }
+    if err != nil {
+        manager.logger.Errorf("failed to write task %s: %s", info.Id, err.Error())
+    }
+}
+
+// Starts running the function in the background as a new task
+func (manager *TaskManager) Submit(name string, run func(task *Task) error) (models.Task, error) {
+    id, err := helpers.Random128BitString()
+    if err != nil {
+        return models.Task{}, err
+    }
+    task := &Task{
+        info: models.Task{
+            Id: id,
+            Name: name,
+            State: models.TASKSTATEENUM_RUNNING,
+            Steps: []string{},
+            StartTimestamp: time.Now().Unix(),
+        },
+        logger: manager.logger.With("task_id", id, "task", name),
+    }
+    manager.save(task)
+    manager.mutex.Lock()
+    manager.tasks[id] = task
+    manager.pruneFinishedTasks()
+    manager.mutex.Unlock()
+    go func() {
+        task.finish(run(task))
+        manager.save(task)
+    }()
+    return task.Info(), nil
+}
+
+// Gets the task with the given id
+func (manager *TaskManager) Get(id string) (models.Task, bool) {
+    manager.mutex.Lock()
+    defer manager.mutex.Unlock()
+    task, ok := manager.tasks[id]
+    if !ok {
+        return models.Task{}, false
+    }
+    return task.Info(), true
+}
+
+// Gets all tasks, most recently started first
+func (manager *TaskManager) List() []models.Task {
+    manager.mutex.Lock()
+    defer manager.mutex.Unlock()
+    tasks := []models.Task{}
+    for _, task := range manager.tasks {
+        tasks = append(tasks, task.Info())
+    }
+    sort.Slice(tasks, func(i, j int) bool {
+        return tasks[i].StartTimestamp > tasks[j].StartTimestamp
+    })
+    return tasks
+}
+
+// Drops the oldest finished tasks beyond MAX_FINISHED_TASKS. Must be called with the mutex held.
+func (manager *TaskManager) pruneFinishedTasks() {
+    finished := []models.Task{}
+    for _, task := range manager.tasks {
+        if task.isFinished() {
+            finished = append(finished, task.Info())
+        }
+    }
+    if len(finished) <= MAX_FINISHED_TASKS {
+        return
+    }
+    sort.Slice(finished, func(i, j int) bool {
+        return *finished[i].EndTimestamp < *finished[j].EndTimestamp
+    })
+    for _, task := range finished[:len(finished)-MAX_FINISHED_TASKS] {
+        delete(manager.tasks, task.Id)
+        if err := manager.local.Delete(STORE_BUCKET, task.Id); err != nil {
+            manager.logger.Errorf("failed to delete task %s: %s", task.Id, err.Error())
+        }
+    }
+}
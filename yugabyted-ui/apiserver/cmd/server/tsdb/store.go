@@ -0,0 +1,182 @@
+package tsdb
+
+import (
+    "sync"
+    "unsafe"
+)
+
+type Sample struct {
+    // Unix timestamp in milliseconds
+    Timestamp int64
+    Value float64
+}
+
+type seriesKey struct {
+    metric string
+    node string
+}
+
+// Fixed size buffer of samples in increasing timestamp order, overwriting the oldest sample
+// once full
+type ring struct {
+    samples []Sample
+    start int
+    length int
+}
+
+func newRing(capacity int) *ring {
+    return &ring{samples: make([]Sample, capacity)}
+}
+
+func (r *ring) at(index int) Sample {
+    return r.samples[(r.start+index)%len(r.samples)]
+}
+
+func (r *ring) append(sample Sample) {
+    if r.length < len(r.samples) {
+        r.samples[(r.start+r.length)%len(r.samples)] = sample
+        r.length++
+        return
+    }
+    r.samples[r.start] = sample
+    r.start = (r.start + 1) % len(r.samples)
+}
+
+// Copies the newest samples into a buffer of the new capacity
+func (r *ring) resize(capacity int) {
+    resized := newRing(capacity)
+    first := 0
+    if r.length > capacity {
+        first = r.length - capacity
+    }
+    for i := first; i < r.length; i++ {
+        resized.append(r.at(i))
+    }
+    *r = *resized
+}
+
+// Store keeps the most recent samples of each metric of each node in memory
+type Store struct {
+    mutex sync.RWMutex
+    capacity int
+    series map[seriesKey]*ring
+}
+
+// Creates a store that keeps up to capacity samples of each series
+func NewStore(capacity int) *Store {
+    if capacity < 1 {
+        capacity = 1
+    }
+    return &Store{
+        capacity: capacity,
+        series: map[seriesKey]*ring{},
+    }
+}
+
+// Changes the number of samples kept of each series, dropping the oldest ones if needed
+func (store *Store) SetCapacity(capacity int) {
+    if capacity < 1 {
+        capacity = 1
+    }
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    if capacity == store.capacity {
+        return
+    }
+    store.capacity = capacity
+    for _, series := range store.series {
+        series.resize(capacity)
+    }
+}
+
+// Adds a sample to a series. Samples that are not newer than the latest one are dropped.
+func (store *Store) Append(metric string, node string, sample Sample) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    key := seriesKey{metric, node}
+    series, ok := store.series[key]
+    if !ok {
+        series = newRing(store.capacity)
+        store.series[key] = series
+    }
+    if series.length > 0 && series.at(series.length-1).Timestamp >= sample.Timestamp {
+        return
+    }
+    series.append(sample)
+}
+
+// Gets the samples of a series with startTime <= timestamp < endTime, oldest first
+func (store *Store) Query(metric string, node string, startTime int64, endTime int64) []Sample {
+    store.mutex.RLock()
+    defer store.mutex.RUnlock()
+    samples := []Sample{}
+    series, ok := store.series[seriesKey{metric, node}]
+    if !ok {
+        return samples
+    }
+    for i := 0; i < series.length; i++ {
+        sample := series.at(i)
+        if sample.Timestamp >= startTime && sample.Timestamp < endTime {
+            samples = append(samples, sample)
+        }
+    }
+    return samples
+}
+
+// Gets the newest sample of a series
+func (store *Store) Latest(metric string, node string) (Sample, bool) {
+    store.mutex.RLock()
+    defer store.mutex.RUnlock()
+    series, ok := store.series[seriesKey{metric, node}]
+    if !ok || series.length == 0 {
+        return Sample{}, false
+    }
+    return series.at(series.length - 1), true
+}
+
+// Drops the series of the nodes that are not in the given set
+func (store *Store) Retain(nodes map[string]bool) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    for key := range store.series {
+        if !nodes[key.node] {
+            delete(store.series, key)
+        }
+    }
+}
+
+// Gets the nodes that have a series of the metric
+func (store *Store) Nodes(metric string) []string {
+    store.mutex.RLock()
+    defer store.mutex.RUnlock()
+    nodes := []string{}
+    for key := range store.series {
+        if key.metric == metric {
+            nodes = append(nodes, key.node)
+        }
+    }
+    return nodes
+}
+
+// Gets the metrics that have a series of any node
+func (store *Store) Metrics() []string {
+    store.mutex.RLock()
+    defer store.mutex.RUnlock()
+    seen := map[string]bool{}
+    metrics := []string{}
+    for key := range store.series {
+        if !seen[key.metric] {
+            seen[key.metric] = true
+            metrics = append(metrics, key.metric)
+        }
+    }
+    return metrics
+}
+
+// Estimates the bytes taken by the samples, counting the whole buffer of each series as it is
+// allocated when the series is created
+func (store *Store) SizeBytes() int64 {
+    store.mutex.RLock()
+    defer store.mutex.RUnlock()
+    return int64(len(store.series)) * int64(store.capacity) * int64(unsafe.Sizeof(Sample{}))
+}
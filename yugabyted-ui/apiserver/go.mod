@@ -3,24 +3,30 @@ module apiserver
 go 1.18
 
 require (
+    github.com/golang/snappy v0.0.3
+    github.com/jackc/pgconn v1.12.1
     github.com/jackc/pgx/v4 v4.16.1
     github.com/labstack/echo/v4 v4.7.2
     github.com/yugabyte/gocql v0.0.0-20220204171058-0bd8e6cb12d0
+    go.etcd.io/bbolt v1.3.6
     go.uber.org/zap v1.23.0
+    golang.org/x/crypto v0.0.0-20210817164053-32db794688a5
+    golang.org/x/net v0.0.0-20211015210444-4f30a5c0130f
+    gopkg.in/inf.v0 v0.9.1
+    gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
     github.com/gocql/gocql v1.1.0 // indirect
     github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
-    github.com/golang/snappy v0.0.3 // indirect
     github.com/hailocab/go-hostpool v0.0.0-20160125115350-e80d13ce29ed // indirect
     github.com/jackc/chunkreader/v2 v2.0.1 // indirect
-    github.com/jackc/pgconn v1.12.1 // indirect
     github.com/jackc/pgio v1.0.0 // indirect
     github.com/jackc/pgpassfile v1.0.0 // indirect
     github.com/jackc/pgproto3/v2 v2.3.0 // indirect
     github.com/jackc/pgservicefile v0.0.0-20200714003250-2b9c44734f2b // indirect
     github.com/jackc/pgtype v1.11.0 // indirect
+    github.com/jackc/puddle v1.2.1 // indirect
     github.com/labstack/gommon v0.3.1 // indirect
     github.com/mattn/go-colorable v0.1.11 // indirect
     github.com/mattn/go-isatty v0.0.14 // indirect
@@ -28,10 +34,7 @@ require (
     github.com/valyala/fasttemplate v1.2.1 // indirect
     go.uber.org/atomic v1.7.0 // indirect
     go.uber.org/multierr v1.6.0 // indirect
-    golang.org/x/crypto v0.0.0-20210817164053-32db794688a5 // indirect
-    golang.org/x/net v0.0.0-20211015210444-4f30a5c0130f // indirect
     golang.org/x/sys v0.0.0-20211103235746-7861aae1554b // indirect
     golang.org/x/text v0.3.7 // indirect
     golang.org/x/time v0.0.0-20201208040808-7e3f01d25324 // indirect
-    gopkg.in/inf.v0 v0.9.1 // indirect
 )